@@ -0,0 +1,98 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+)
+
+const (
+	// AGC target loudness range in dBFS of frame RMS.
+	minAGCTargetDb     = -40
+	maxAGCTargetDb     = -6
+	defaultAGCTargetDb = -20
+	// AGC gain bounds (-18 dB to +18 dB), so background noise isn't blown
+	// up to speech level and a shout isn't squashed to nothing.
+	agcMinGain = 0.125
+	agcMaxGain = 8
+	// agcSilenceRMS is the level below which a frame is treated as a pause
+	// and leaves the gain alone, so silence doesn't pump it up.
+	agcSilenceRMS = 0.005
+	// agcAttack and agcRelease are how far each frame moves the gain toward
+	// the target: fast when the input is too loud, slow when too quiet.
+	// At 20 ms frames the gain falls within a few frames and rises over a
+	// second or so.
+	agcAttack  = 0.3
+	agcRelease = 0.05
+	// agcPeakLimit is the highest sample the gain may produce, so a loud
+	// onset right after a quiet stretch is not clipped.
+	agcPeakLimit = 0.98
+)
+
+// SetAGCTarget sets the loudness automatic gain control steers the
+// microphone toward, in dBFS of frame RMS, clamped to [-40, -6]. It has no
+// effect while AGC is off; see SetAGC.
+func (ae *AudioEngine) SetAGCTarget(dbfs float64) {
+	dbfs = max(minAGCTargetDb, min(maxAGCTargetDb, dbfs))
+	ae.agcTargetDb.Store(math.Float64bits(dbfs))
+	slog.Debug("agc target updated", "target_db", dbfs)
+}
+
+// AGCTarget returns the AGC target loudness in dBFS.
+func (ae *AudioEngine) AGCTarget() float64 {
+	return math.Float64frombits(ae.agcTargetDb.Load())
+}
+
+// SetAGCLevel sets the AGC target from the older 0-100 level, spread
+// evenly over the dBFS target range: 0 is the quietest target and 100 the
+// loudest.
+func (ae *AudioEngine) SetAGCLevel(level int) {
+	level = max(0, min(100, level))
+	ae.SetAGCTarget(minAGCTargetDb + float64(level)*(maxAGCTargetDb-minAGCTargetDb)/100)
+}
+
+// agcFrame applies automatic gain control to one captured frame with level
+// rms, scaling buf in place. It returns the frame's level after the gain.
+func (ae *AudioEngine) agcFrame(g *agc, buf []float32, rms float32) float32 {
+	if !ae.autoGainControlEnabled.Load() {
+		return rms
+	}
+	target := float32(math.Pow(10, ae.AGCTarget()/20))
+	return g.apply(buf, rms, target)
+}
+
+// agc holds the capture gain automatic gain control has settled on.
+type agc struct {
+	gain float32 // 0 means not yet started, treated as unity
+}
+
+// apply moves the gain toward the one that brings rms to target and scales
+// buf by it, limited so no sample passes agcPeakLimit.
+func (g *agc) apply(buf []float32, rms, target float32) float32 {
+	if g.gain == 0 {
+		g.gain = 1
+	}
+	if rms >= agcSilenceRMS {
+		want := max(agcMinGain, min(agcMaxGain, target/rms))
+		rate := float32(agcRelease)
+		if want < g.gain {
+			rate = agcAttack
+		}
+		g.gain += (want - g.gain) * rate
+	}
+
+	gain := g.gain
+	var peak float32
+	for _, s := range buf {
+		peak = max(peak, s, -s)
+	}
+	if peak*gain > agcPeakLimit {
+		gain = agcPeakLimit / peak
+	}
+	if gain == 1 {
+		return rms
+	}
+	for i := range buf {
+		buf[i] *= gain
+	}
+	return rms * gain
+}
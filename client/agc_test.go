@@ -0,0 +1,79 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAGCAmplifiesQuietInputTowardTarget(t *testing.T) {
+	ae := NewAudioEngine()
+	ae.SetAGC(true)
+	ae.SetAGCTarget(-20)
+	target := float32(math.Pow(10, -20.0/20))
+	var g agc
+
+	// -32 dBFS speech, four times too quiet.
+	const quiet = 0.025
+	var prev float32
+	for i := 0; i < 100; i++ {
+		buf := gateTestFrame(quiet)
+		rms := ae.agcFrame(&g, buf, frameRMS(buf))
+		if rms < prev {
+			t.Fatalf("frame %d: level fell from %v to %v while ramping up", i, prev, rms)
+		}
+		prev = rms
+	}
+	if math.Abs(float64(prev-target)) > 0.01 {
+		t.Errorf("after 100 frames level = %v, want about %v", prev, target)
+	}
+}
+
+func TestAGCDoesNotClipLoudInput(t *testing.T) {
+	ae := NewAudioEngine()
+	ae.SetAGC(true)
+	ae.SetAGCTarget(-6)
+	var g agc
+
+	// Ramp the gain up on a quiet stretch, then hit it with a near
+	// full-scale frame before the attack can pull the gain down.
+	for i := 0; i < 100; i++ {
+		buf := gateTestFrame(0.01)
+		ae.agcFrame(&g, buf, frameRMS(buf))
+	}
+	for i := 0; i < 10; i++ {
+		buf := gateTestFrame(0.9)
+		ae.agcFrame(&g, buf, frameRMS(buf))
+		for j, s := range buf {
+			if s > agcPeakLimit || s < -agcPeakLimit {
+				t.Fatalf("frame %d sample %d = %v, exceeds %v", i, j, s, agcPeakLimit)
+			}
+		}
+	}
+}
+
+func TestAGCDisabledLeavesFrameAlone(t *testing.T) {
+	ae := NewAudioEngine()
+	ae.SetAGC(false)
+	var g agc
+	buf := gateTestFrame(0.01)
+	if rms := ae.agcFrame(&g, buf, frameRMS(buf)); rms != 0.01 || buf[0] != 0.01 {
+		t.Errorf("disabled AGC changed the frame: rms %v, sample %v", rms, buf[0])
+	}
+}
+
+func TestSetAGCLevelMapsOntoTargetRange(t *testing.T) {
+	ae := NewAudioEngine()
+	if got := ae.AGCTarget(); got != defaultAGCTargetDb {
+		t.Errorf("default target = %v, want %v", got, defaultAGCTargetDb)
+	}
+	for level, want := range map[int]float64{0: -40, 50: -23, 100: -6, -5: -40, 200: -6} {
+		ae.SetAGCLevel(level)
+		if got := ae.AGCTarget(); math.Abs(got-want) > 1e-9 {
+			t.Errorf("SetAGCLevel(%d): target = %v, want %v", level, got, want)
+		}
+	}
+	ae.SetAGCTarget(-80)
+	if got := ae.AGCTarget(); got != minAGCTargetDb {
+		t.Errorf("SetAGCTarget(-80) = %v, want clamped to %v", got, minAGCTargetDb)
+	}
+}
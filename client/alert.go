@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// In-call alert kinds accepted by PlayAlert.
+const (
+	AlertMention = "mention"
+	AlertDM      = "dm"
+)
+
+const (
+	// alertChannelBuf bounds queued alert audio (~1 s); alerts are short and
+	// a burst of mentions should not stack up behind each other.
+	alertChannelBuf = 50
+
+	// alertVolume is the peak amplitude of alert tones. It sits a little
+	// above notifVolume because it has to cut through live speech.
+	alertVolume = 0.22
+
+	// While an alert plays, call audio is ducked to alertDuckGain. Once it
+	// ends, the gain recovers by alertDuckRelease per frame (~100 ms total).
+	alertDuckGain    = 0.3
+	alertDuckRelease = 0.15
+)
+
+// PlayAlert mixes a short alert into the call audio, briefly ducking the
+// other participants so it is heard over the conversation. Unlike
+// PlayNotification it is silent while deafened as well as in do-not-disturb,
+// and does nothing when no call is running.
+func (ae *AudioEngine) PlayAlert(kind string) error {
+	frames, err := generateAlertFrames(kind)
+	if err != nil {
+		return err
+	}
+	if ae.doNotDisturb.Load() || ae.deafened.Load() || !ae.running.Load() {
+		return nil
+	}
+	go func() {
+		stopCh := ae.stopCh
+		for _, frame := range frames {
+			select {
+			case <-stopCh:
+				return
+			case ae.alertCh <- frame:
+			default:
+				// Channel full — skip frame rather than block.
+			}
+		}
+	}()
+	return nil
+}
+
+// generateAlertFrames returns the PCM frames for an alert kind.
+func generateAlertFrames(kind string) ([][]float32, error) {
+	var tones [][2]int // {Hz, ms}
+	switch kind {
+	case AlertMention:
+		tones = [][2]int{{988, 70}, {1319, 90}} // B5 → E6
+	case AlertDM:
+		tones = [][2]int{{1319, 60}, {988, 60}, {1319, 90}} // E6 → B5 → E6
+	default:
+		return nil, fmt.Errorf("unknown alert kind %q", kind)
+	}
+	var frames [][]float32
+	for _, t := range tones {
+		frames = append(frames, generateSineTone(float64(t[0]), t[1])...)
+	}
+	// generateSineTone is scaled for notifications; rescale to alertVolume.
+	for _, f := range frames {
+		for i := range f {
+			f[i] *= alertVolume / notifVolume
+		}
+	}
+	return frames, nil
+}
+
+// nextDuckGain returns the call-audio gain for the next frame: alertDuckGain
+// while an alert frame is playing, then a linear release back to unity.
+func nextDuckGain(current float32, alerting bool) float32 {
+	if alerting {
+		return alertDuckGain
+	}
+	return float32(math.Min(1, float64(current+alertDuckRelease)))
+}
+
+// applyGainRamp scales buf by a gain moving linearly from `from` to `to`
+// across the frame, so ducking never introduces a step discontinuity.
+func applyGainRamp(buf []float32, from, to float32) {
+	if from == 1 && to == 1 {
+		return
+	}
+	n := float32(len(buf))
+	for i := range buf {
+		g := from + (to-from)*float32(i+1)/n
+		buf[i] *= g
+	}
+}
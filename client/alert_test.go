@@ -0,0 +1,116 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGenerateAlertFrames(t *testing.T) {
+	for _, kind := range []string{AlertMention, AlertDM} {
+		frames, err := generateAlertFrames(kind)
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		if len(frames) == 0 {
+			t.Fatalf("%s: no frames", kind)
+		}
+		var peak float32
+		for _, f := range frames {
+			if len(f) != FrameSize {
+				t.Fatalf("%s: frame length %d, want %d", kind, len(f), FrameSize)
+			}
+			for _, s := range f {
+				if s > peak {
+					peak = s
+				}
+			}
+		}
+		if peak > alertVolume+1e-4 || peak < alertVolume/2 {
+			t.Errorf("%s: peak %.3f, want about %.2f", kind, peak, alertVolume)
+		}
+	}
+	if _, err := generateAlertFrames("siren"); err == nil {
+		t.Error("expected error for unknown alert kind")
+	}
+}
+
+func TestNextDuckGain(t *testing.T) {
+	g := nextDuckGain(1, true)
+	if g != alertDuckGain {
+		t.Fatalf("alerting gain = %v, want %v", g, alertDuckGain)
+	}
+	// Release is gradual and settles at unity.
+	steps := 0
+	for g < 1 {
+		next := nextDuckGain(g, false)
+		if next <= g {
+			t.Fatalf("gain did not increase: %v → %v", g, next)
+		}
+		g = next
+		steps++
+	}
+	if g != 1 || steps < 2 {
+		t.Errorf("release ended at %v after %d frames", g, steps)
+	}
+}
+
+func TestApplyGainRamp(t *testing.T) {
+	buf := make([]float32, 4)
+	for i := range buf {
+		buf[i] = 1
+	}
+	applyGainRamp(buf, 1, 0.2)
+	for i := 1; i < len(buf); i++ {
+		if buf[i] >= buf[i-1] {
+			t.Fatalf("ramp not decreasing: %v", buf)
+		}
+	}
+	if d := buf[3] - 0.2; d > 1e-6 || d < -1e-6 {
+		t.Errorf("ramp should end at the target gain, got %v", buf[3])
+	}
+
+	unity := []float32{0.5, -0.5}
+	applyGainRamp(unity, 1, 1)
+	if unity[0] != 0.5 || unity[1] != -0.5 {
+		t.Errorf("unity gain changed samples: %v", unity)
+	}
+}
+
+func TestPlayAlertRespectsDNDAndDeafen(t *testing.T) {
+	ae := NewAudioEngine()
+	ae.running.Store(true)
+
+	expectNone := func(name string) {
+		t.Helper()
+		select {
+		case <-ae.alertCh:
+			t.Fatalf("alert frame queued while %s", name)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	ae.SetDoNotDisturb(true)
+	if err := ae.PlayAlert(AlertMention); err != nil {
+		t.Fatalf("PlayAlert: %v", err)
+	}
+	expectNone("do-not-disturb is on")
+	ae.SetDoNotDisturb(false)
+
+	ae.SetDeafened(true)
+	_ = ae.PlayAlert(AlertMention)
+	expectNone("deafened")
+	ae.SetDeafened(false)
+
+	if err := ae.PlayAlert(AlertDM); err != nil {
+		t.Fatalf("PlayAlert: %v", err)
+	}
+	select {
+	case <-ae.alertCh:
+	case <-time.After(time.Second):
+		t.Fatal("alert not queued")
+	}
+
+	if err := ae.PlayAlert("siren"); err == nil {
+		t.Error("expected error for unknown alert kind")
+	}
+}
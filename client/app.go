@@ -1,23 +1,26 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"client/internal/config"
+
 	"github.com/gordonklaus/portaudio"
 	wailsrt "github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -37,6 +40,61 @@ type App struct {
 	// Metrics cache: updated every 5 s by adaptBitrateLoop; read by GetMetrics.
 	metricsMu     sync.Mutex
 	cachedMetrics Metrics
+
+	// uploadLimitKbps caps file upload bandwidth; 0 means unlimited.
+	uploadLimitKbps atomic.Int32
+
+	// adaptiveBitrate lets adaptBitrateLoop move the Opus bitrate within the
+	// engine's bitrate range. manualBitrateKbps is the user's chosen rate,
+	// restored when adaptation is switched off.
+	adaptiveBitrate   atomic.Bool
+	manualBitrateKbps atomic.Int32
+
+	// onsetRedundancy is applied to every new session's transport; see
+	// SetOnsetRedundancy.
+	onsetRedundancy atomic.Bool
+
+	// dropOldest is applied to every new session's transport; see
+	// SetDropPolicy.
+	dropOldest atomic.Bool
+
+	// monitorVolume is the playback gain for monitored channels, as
+	// float64 bits; see SetMonitorVolume.
+	monitorVolume atomic.Uint64
+
+	// activeChannel is the voice channel we are in (0 = none); it gets
+	// "all" notifications by default. channelNotify holds saved
+	// per-channel levels keyed by channelNotifyKey.
+	activeChannel atomic.Int64
+	// channels is the session's latest channel list (guarded by mu); it
+	// supplies the bitrate cap for the voice channel we join.
+	channels      []ChannelInfo
+	notifyMu      sync.Mutex
+	channelNotify map[string]string
+	// inCallAlerts mixes mention alerts into call audio (AudioEngine.PlayAlert).
+	inCallAlerts atomic.Bool
+	// serverMutedMic is set when a moderator's mute turned our microphone
+	// off, so it can be turned back on when the mute lifts.
+	serverMutedMic atomic.Bool
+	// doNotDisturb mirrors our own "dnd" status as the server reports it;
+	// it silences join and leave sounds.
+	doNotDisturb atomic.Bool
+
+	// autoJoinVoice holds the configured channel to join once a new
+	// session's channel list arrives.
+	autoJoinVoice autoJoinState
+
+	// blocks holds the users blocked on each server; see BlockUser.
+	blocks blockState
+	// notifyMuted holds the users whose join and leave sounds are muted
+	// on each server; see MuteUserNotifications. joinLeaveSilenced mutes
+	// them for everyone; see SetJoinLeaveSounds.
+	notifyMuted       blockState
+	joinLeaveSilenced atomic.Bool
+
+	// pttHotkey drives push-to-talk from a global key while bken is in
+	// the background.
+	pttHotkey pttHotkey
 }
 
 var (
@@ -56,10 +114,12 @@ type BuildInfo struct {
 
 // NewApp creates a new App.
 func NewApp() *App {
-	return &App{
+	a := &App{
 		audio:     NewAudioEngine(),
 		transport: NewTransport(),
 	}
+	a.SetMonitorVolume(config.Default().MonitorVolume)
+	return a
 }
 
 func (a *App) normalizedAddr(addr string) (string, error) {
@@ -105,7 +165,11 @@ func (a *App) startup(ctx context.Context) {
 
 // shutdown is called when the Wails app is closing.
 func (a *App) shutdown(_ context.Context) {
+	_ = a.pttHotkey.set("", nil)
 	a.Disconnect()
+	if err := a.audio.StopRecording(); err != nil {
+		slog.Error("finish local recording", "err", err)
+	}
 	portaudio.Terminate()
 }
 
@@ -137,6 +201,12 @@ func (a *App) GetAutoLogin() AutoLogin {
 
 // GetBuildInfo returns application build/runtime details for diagnostics.
 func (a *App) GetBuildInfo() BuildInfo {
+	return currentBuildInfo()
+}
+
+// currentBuildInfo collects build details from link-time variables and the
+// embedded Go build info.
+func currentBuildInfo() BuildInfo {
 	info := BuildInfo{
 		Commit:    buildCommit,
 		BuildTime: buildTime,
@@ -178,14 +248,26 @@ func (a *App) GetOutputDevices() []AudioDevice {
 	return a.audio.ListOutputDevices()
 }
 
-// SetInputDevice sets the active audio input device by index.
-func (a *App) SetInputDevice(id int) {
-	a.audio.SetInputDevice(id)
+// SetInputDevice sets the active audio input device by index, switching
+// mid-call if voice is running. It returns an error message, or "" on
+// success; on failure the previous device stays in use.
+func (a *App) SetInputDevice(id int) string {
+	if err := a.audio.SetInputDevice(id); err != nil {
+		slog.Error("set input device", "id", id, "err", err)
+		return err.Error()
+	}
+	return ""
 }
 
-// SetOutputDevice sets the active audio output device by index.
-func (a *App) SetOutputDevice(id int) {
-	a.audio.SetOutputDevice(id)
+// SetOutputDevice sets the active audio output device by index, switching
+// mid-call if voice is running. It returns an error message, or "" on
+// success; on failure the previous device stays in use.
+func (a *App) SetOutputDevice(id int) string {
+	if err := a.audio.SetOutputDevice(id); err != nil {
+		slog.Error("set output device", "id", id, "err", err)
+		return err.Error()
+	}
+	return ""
 }
 
 // SetVolume sets playback volume in the range [0.0, 1.0].
@@ -194,16 +276,96 @@ func (a *App) SetVolume(vol float64) {
 	a.audio.SetVolume(vol)
 }
 
-// SetAudioBitrate sets the Opus target bitrate in kbps.
+// SetAudioBitrate sets the Opus target bitrate in kbps. While adaptive
+// bitrate is on the rate is clamped to the adaptive range; the unclamped
+// value is what disabling adaptation restores.
 func (a *App) SetAudioBitrate(kbps int) {
+	a.manualBitrateKbps.Store(int32(kbps))
+	if a.adaptiveBitrate.Load() {
+		kbps = a.clampToAdaptiveRange(kbps)
+	}
 	a.audio.SetBitrate(kbps)
 }
 
+// clampToAdaptiveRange returns kbps limited to the adaptive bitrate range.
+func (a *App) clampToAdaptiveRange(kbps int) int {
+	floor, ceiling := a.audio.adaptiveBitrateRange()
+	return max(floor, min(ceiling, kbps))
+}
+
+// applyAdaptiveRange moves the current bitrate into the adaptive range if
+// adaptive bitrate is on, so a new range takes effect without waiting for
+// the next quality tick.
+func (a *App) applyAdaptiveRange() {
+	if !a.adaptiveBitrate.Load() {
+		return
+	}
+	cur := a.audio.CurrentBitrate()
+	if next := a.clampToAdaptiveRange(cur); next != cur {
+		a.audio.SetBitrate(next)
+	}
+}
+
 // GetAudioBitrate returns the current Opus target bitrate in kbps.
 func (a *App) GetAudioBitrate() int {
 	return a.audio.CurrentBitrate()
 }
 
+// SetAdaptiveBitrate enables or disables quality-driven bitrate adaptation.
+// Disabling it restores the bitrate last set with SetAudioBitrate.
+func (a *App) SetAdaptiveBitrate(enabled bool) {
+	a.adaptiveBitrate.Store(enabled)
+	if !enabled {
+		if kbps := int(a.manualBitrateKbps.Load()); kbps > 0 {
+			a.audio.SetBitrate(kbps)
+		}
+		return
+	}
+	a.applyAdaptiveRange()
+}
+
+// IsAdaptiveBitrate reports whether adaptive bitrate is enabled.
+func (a *App) IsAdaptiveBitrate() bool {
+	return a.adaptiveBitrate.Load()
+}
+
+// SetBitrateRange sets the floor and ceiling (kbps) for adaptive bitrate.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SetBitrateRange(floorKbps, ceilingKbps int) string {
+	if err := a.audio.SetBitrateRange(floorKbps, ceilingKbps); err != nil {
+		return err.Error()
+	}
+	a.applyAdaptiveRange()
+	return ""
+}
+
+// GetBitrateFloor returns the adaptive bitrate floor in kbps.
+func (a *App) GetBitrateFloor() int {
+	floor, _ := a.audio.BitrateRange()
+	return floor
+}
+
+// GetBitrateCeiling returns the adaptive bitrate ceiling in kbps.
+func (a *App) GetBitrateCeiling() int {
+	_, ceiling := a.audio.BitrateRange()
+	return ceiling
+}
+
+// SetMaxPacketBytes caps each encoded Opus frame at n bytes (0 = no cap),
+// lowering the bitrate if needed so frames never fragment on low-MTU links.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SetMaxPacketBytes(n int) string {
+	if err := a.audio.SetMaxPacketBytes(n); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// GetMaxPacketBytes returns the Opus frame size cap in bytes (0 = no cap).
+func (a *App) GetMaxPacketBytes() int {
+	return a.audio.MaxPacketBytes()
+}
+
 // SetAEC enables or disables echo-cancellation preference.
 func (a *App) SetAEC(enabled bool) {
 	a.audio.SetAEC(enabled)
@@ -214,16 +376,179 @@ func (a *App) SetAGC(enabled bool) {
 	a.audio.SetAGC(enabled)
 }
 
+// SetAGCTarget sets the loudness, in dBFS (-40 to -6), automatic gain
+// control steers the microphone toward.
+func (a *App) SetAGCTarget(dbfs float64) {
+	a.audio.SetAGCTarget(dbfs)
+}
+
+// SetAGCLevel sets the AGC target from a 0-100 level, as older settings
+// stored it; see AudioEngine.SetAGCLevel.
+func (a *App) SetAGCLevel(level int) {
+	a.audio.SetAGCLevel(level)
+}
+
+// SetFEC enables or disables Opus forward error correction, which lets a
+// single lost voice packet be recovered at the cost of some extra bitrate.
+func (a *App) SetFEC(enabled bool) {
+	a.audio.SetFEC(enabled)
+}
+
+// SetDTX enables or disables discontinuous transmission, which stops
+// sending voice packets while the microphone is silent.
+func (a *App) SetDTX(enabled bool) {
+	a.audio.SetDTX(enabled)
+}
+
+// SetOnsetRedundancy turns on or off sending the first few frames of each
+// talk spurt twice, so a lost first packet after silence does not clip the
+// start of a word. It costs a little bandwidth at every onset.
+func (a *App) SetOnsetRedundancy(enabled bool) {
+	a.onsetRedundancy.Store(enabled)
+	a.mu.RLock()
+	tr := a.transport
+	a.mu.RUnlock()
+	if tr != nil {
+		tr.SetOnsetRedundancy(enabled)
+	}
+	slog.Debug("onset redundancy updated", "enabled", enabled)
+}
+
+// SetNoiseGate configures the capture noise gate: while enabled, frames
+// quieter than db (dBFS) are silenced before they are encoded.
+func (a *App) SetNoiseGate(db float64, enabled bool) {
+	a.audio.SetNoiseGate(db, enabled)
+}
+
+// SetAutoLevel turns per-speaker auto-leveling on or off. While on,
+// quiet and loud speakers are brought toward the same loudness, and each
+// user's volume setting applies on top as a trim.
+func (a *App) SetAutoLevel(enabled bool) {
+	a.audio.SetAutoLevel(enabled)
+}
+
+// SetSidetone turns microphone monitoring on or off: while enabled, the
+// captured audio is played back locally at gain (0-1). Nothing extra is
+// sent to peers.
+func (a *App) SetSidetone(enabled bool, gain float64) {
+	a.audio.SetSidetone(enabled, gain)
+}
+
+// SetJitterBufferMs sets how much audio is held back per speaker before
+// playback. Higher values smooth out jittery links at the cost of latency.
+func (a *App) SetJitterBufferMs(ms int) {
+	a.audio.SetJitterBufferMs(ms)
+}
+
+// SetStereo switches between mono (the default) and stereo capture. While
+// in voice the audio engine is restarted so capture picks up the new
+// channel count, and peers are renegotiated for the new track.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SetStereo(enabled bool) string {
+	if a.audio.Stereo() == enabled {
+		return ""
+	}
+	a.audio.SetStereo(enabled)
+	a.mu.RLock()
+	tr := a.transport
+	a.mu.RUnlock()
+	if tr != nil {
+		tr.SetStereo(enabled)
+	}
+	if !a.connected.Load() {
+		return ""
+	}
+	if err := a.restartAudio(); err != nil {
+		slog.Error("restart audio for stereo", "enabled", enabled, "err", err)
+		return err.Error()
+	}
+	return ""
+}
+
+// SetFrameSize sets how many milliseconds of audio each encoded packet
+// carries (10, 20, 40 or 60) and saves it to the config. While in voice the
+// audio engine is restarted so capture picks up the new frame size.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SetFrameSize(ms int) string {
+	prev := a.audio.CaptureFrameMs()
+	if err := a.audio.SetCaptureFrameMs(ms); err != nil {
+		return err.Error()
+	}
+	cfg := LoadConfig()
+	cfg.CaptureFrameMs = ms
+	if err := SaveConfig(cfg); err != nil {
+		slog.Error("save frame size failed", "ms", ms, "err", err)
+		return err.Error()
+	}
+	if ms == prev || !a.connected.Load() {
+		return ""
+	}
+	if err := a.restartAudio(); err != nil {
+		slog.Error("restart audio for frame size", "ms", ms, "err", err)
+		return err.Error()
+	}
+	return ""
+}
+
+// restartAudio stops and restarts the audio engine in voice, so settings
+// that only apply at start take effect. If audio cannot start again we
+// leave voice cleanly, since we cannot stay in it without audio.
+func (a *App) restartAudio() error {
+	a.audio.Stop()
+	if err := a.audio.Start(); err != nil {
+		_ = a.DisconnectVoice()
+		return err
+	}
+	go a.sendLoop()
+	go a.adaptBitrateLoop(a.audio.Done())
+	return nil
+}
+
 // SetNoiseSuppression enables or disables noise suppression.
 func (a *App) SetNoiseSuppression(enabled bool) {
 	a.audio.SetNoiseSuppression(enabled)
 }
 
+// SetSignalAutoDetect enables or disables automatic speech/music detection,
+// which retunes the Opus encoder for the kind of audio being captured.
+func (a *App) SetSignalAutoDetect(enabled bool) {
+	a.audio.SetSignalAutoDetect(enabled)
+}
+
+// SetSignalType sets the manual Opus signal type ("voice" or "music") used
+// while auto-detection is off.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SetSignalType(signal string) string {
+	if err := a.audio.SetSignalType(signal); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// GetSignalType returns the signal type the encoder is currently tuned for.
+func (a *App) GetSignalType() string {
+	return a.audio.SignalType()
+}
+
 // SetNotificationVolume sets the notification/soundboard volume (0.0-1.0).
 func (a *App) SetNotificationVolume(vol float64) {
 	a.audio.SetNotificationVolume(float32(vol))
 }
 
+// SetDoNotDisturb suppresses or restores notification sounds and emits
+// "dnd:changed" so every view reflects the new state.
+func (a *App) SetDoNotDisturb(enabled bool) {
+	a.audio.SetDoNotDisturb(enabled)
+	if a.ctx != nil {
+		wailsrt.EventsEmit(a.ctx, "dnd:changed", map[string]any{"enabled": enabled})
+	}
+}
+
+// IsDoNotDisturb reports whether notification sounds are suppressed.
+func (a *App) IsDoNotDisturb() bool {
+	return a.audio.IsDoNotDisturb()
+}
+
 // GetNotificationVolume returns the notification volume (0.0-1.0).
 func (a *App) GetNotificationVolume() float64 {
 	return float64(a.audio.NotificationVolume())
@@ -244,6 +569,26 @@ func (a *App) StartTest() string {
 	return ""
 }
 
+// LatencyResult is the outcome of MeasureAudioLatency.
+type LatencyResult struct {
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// MeasureAudioLatency sends a test tone through the running loopback test
+// and reports how long it took to reach playback, in milliseconds. A large
+// value points at an oversized jitter buffer or frame size.
+func (a *App) MeasureAudioLatency() LatencyResult {
+	d, err := a.audio.MeasureLatency()
+	if err != nil {
+		slog.Warn("measure audio latency", "err", err)
+		return LatencyResult{Error: err.Error()}
+	}
+	ms := float64(d) / float64(time.Millisecond)
+	slog.Info("audio loopback latency", "ms", ms)
+	return LatencyResult{LatencyMs: ms}
+}
+
 // StopTest stops the audio loopback test.
 func (a *App) StopTest() {
 	a.audio.StopTest()
@@ -262,11 +607,12 @@ func (a *App) SetMuted(muted bool) {
 	tr := a.transport
 	a.mu.RUnlock()
 	if tr != nil {
-		_ = tr.SendVoiceFlags(muted, a.audio.IsDeafened())
+		_ = tr.SendVoiceFlags(a.audio.IsMuted(), a.audio.IsDeafened())
 	}
 }
 
-// SetDeafened enables or disables audio playback.
+// SetDeafened enables or disables audio playback. Deafening also stops
+// transmitting; undeafening restores whatever mute state was set before.
 func (a *App) SetDeafened(deafened bool) {
 	slog.Debug("SetDeafened", "deafened", deafened)
 	a.audio.SetDeafened(deafened)
@@ -289,6 +635,19 @@ func (a *App) SetPTTMode(enabled bool) {
 	a.audio.SetPTTMode(enabled)
 }
 
+// SetPTTHotkey registers keyName (a KeyboardEvent.code such as "Backquote")
+// as a global push-to-talk key, so PTT works while the window isn't
+// focused. An empty name removes it. Returns an error message string or ""
+// on success (Wails JS binding convention).
+func (a *App) SetPTTHotkey(keyName string) string {
+	slog.Debug("SetPTTHotkey", "key", keyName)
+	if err := a.pttHotkey.set(keyName, a.audio.SetPTTActive); err != nil {
+		slog.Warn("register ptt hotkey", "key", keyName, "err", err)
+		return err.Error()
+	}
+	return ""
+}
+
 // PTTKeyDown signals that the push-to-talk key was pressed. Audio capture
 // begins transmitting immediately. No-op when PTT mode is disabled.
 func (a *App) PTTKeyDown() {
@@ -301,6 +660,13 @@ func (a *App) PTTKeyUp() {
 	a.audio.SetPTTActive(false)
 }
 
+// A dropped control connection is re-dialled up to reconnectAttempts times,
+// backing off from reconnectBaseDelay, before the session is reported lost.
+const (
+	reconnectAttempts  = 5
+	reconnectBaseDelay = time.Second
+)
+
 // Connect establishes a control session with the server.
 // If already connected to the same address, this is a no-op.
 // If connected to a different address, disconnects first.
@@ -339,7 +705,17 @@ func (a *App) Connect(addr, username string) string {
 
 	a.wireSessionCallbacks(normalizedAddr, tr)
 
+	// Armed before connecting: the snapshot and channel list can arrive
+	// before Connect returns.
+	if id := LoadConfig().AutoJoinVoice[normalizedAddr]; id > 0 {
+		a.autoJoinVoice.arm(id)
+	}
+	tr.SetReconnect(reconnectAttempts, reconnectBaseDelay)
+	tr.SetStereo(a.audio.Stereo())
+	tr.SetOnsetRedundancy(a.onsetRedundancy.Load())
+	_ = tr.SetDropPolicy(a.dropPolicy())
 	if err := tr.Connect(context.Background(), normalizedAddr, username); err != nil {
+		a.autoJoinVoice.disarm()
 		return err.Error()
 	}
 
@@ -359,7 +735,37 @@ func (a *App) Connect(addr, username string) string {
 // wireSessionCallbacks registers transport callbacks and tags each event with
 // its server address.
 func (a *App) wireSessionCallbacks(serverAddr string, tr Transporter) {
+	// The server's own join and leave sounds (string URLs), set from its
+	// snapshot; empty plays the bundled sound.
+	var joinSound, leaveSound atomic.Value
+	tr.SetOnJoinSounds(func(joinURL, leaveURL string) {
+		joinSound.Store(joinURL)
+		leaveSound.Store(leaveURL)
+		for _, url := range []string{joinURL, leaveURL} {
+			if url == "" {
+				continue
+			}
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), soundFetchTimeout)
+				defer cancel()
+				if err := a.audio.LoadSound(ctx, url); err != nil {
+					slog.Warn("load server sound, using the bundled sound", "addr", serverAddr, "url", url, "err", err)
+				}
+			}()
+		}
+	})
 	tr.SetOnUserList(func(users []UserInfo) {
+		a.autoJoinVoice.observeUsers(users)
+		for _, u := range users {
+			if u.ID == tr.MyID() {
+				a.doNotDisturb.Store(u.Status == "dnd")
+				continue
+			}
+			a.notifyMuted.observe(serverAddr, u.ID, u.Username)
+			if a.blocks.observe(serverAddr, u.ID, u.Username) {
+				tr.BlockUser(u.ID)
+			}
+		}
 		slog.Debug("emit user:list", "addr", serverAddr)
 		wailsrt.EventsEmit(a.ctx, "user:list", map[string]any{
 			"server_addr": serverAddr,
@@ -372,21 +778,29 @@ func (a *App) wireSessionCallbacks(serverAddr string, tr Transporter) {
 		})
 	})
 	tr.SetOnUserJoined(func(id uint16, name string) {
+		a.notifyMuted.observe(serverAddr, id, name)
+		if a.blocks.observe(serverAddr, id, name) {
+			tr.BlockUser(id)
+		}
 		slog.Debug("emit user:joined", "addr", serverAddr, "id", id, "username", name)
 		wailsrt.EventsEmit(a.ctx, "user:joined", map[string]any{
 			"server_addr": serverAddr,
 			"id":          id,
 			"username":    name,
 		})
-		a.audio.PlayNotification(SoundUserJoined)
+		url, _ := joinSound.Load().(string)
+		a.playJoinLeaveSound(serverAddr, id, url, SoundUserJoined)
 	})
 	tr.SetOnUserLeft(func(id uint16) {
+		a.blocks.forget(id)
 		slog.Debug("emit user:left", "addr", serverAddr, "id", id)
 		wailsrt.EventsEmit(a.ctx, "user:left", map[string]any{
 			"server_addr": serverAddr,
 			"id":          id,
 		})
-		a.audio.PlayNotification(SoundUserLeft)
+		url, _ := leaveSound.Load().(string)
+		a.playJoinLeaveSound(serverAddr, id, url, SoundUserLeft)
+		a.notifyMuted.forget(id)
 	})
 	tr.SetOnAudioReceived(func(userID uint16) {
 		slog.Debug("emit audio:speaking", "addr", serverAddr, "user_id", userID)
@@ -395,6 +809,14 @@ func (a *App) wireSessionCallbacks(serverAddr string, tr Transporter) {
 			"id":          int(userID),
 		})
 	})
+	tr.SetOnSpeaking(func(userID uint16, speaking bool) {
+		slog.Debug("emit voice:speaking_state", "addr", serverAddr, "user_id", userID, "speaking", speaking)
+		wailsrt.EventsEmit(a.ctx, "voice:speaking_state", map[string]any{
+			"server_addr": serverAddr,
+			"user_id":     int(userID),
+			"speaking":    speaking,
+		})
+	})
 	tr.SetOnDisconnected(func(reason string) {
 		a.mu.Lock()
 		if a.transport == tr {
@@ -420,6 +842,14 @@ func (a *App) wireSessionCallbacks(serverAddr string, tr Transporter) {
 		})
 		slog.Info("connection lost", "addr", serverAddr, "reason", reason)
 	})
+	tr.SetOnReconnecting(func(attempt int) {
+		slog.Debug("emit connection:reconnecting", "addr", serverAddr, "attempt", attempt)
+		wailsrt.EventsEmit(a.ctx, "connection:reconnecting", map[string]any{
+			"server_addr": serverAddr,
+			"attempt":     attempt,
+			"max":         reconnectAttempts,
+		})
+	})
 	tr.SetOnChatMessage(func(msgID uint64, senderID uint16, username, message string, ts int64, fileID string, fileName string, fileSize int64, mentions []uint16) {
 		payload := map[string]any{
 			"server_addr": serverAddr,
@@ -443,6 +873,7 @@ func (a *App) wireSessionCallbacks(serverAddr string, tr Transporter) {
 			}
 			payload["mentions"] = intMentions
 		}
+		a.notifyChat(payload, serverAddr, 0, senderID, tr.MyID(), mentions)
 		slog.Debug("emit chat:message", "addr", serverAddr, "msg_id", msgID, "sender_id", senderID)
 		wailsrt.EventsEmit(a.ctx, "chat:message", payload)
 	})
@@ -469,6 +900,7 @@ func (a *App) wireSessionCallbacks(serverAddr string, tr Transporter) {
 			}
 			payload["mentions"] = intMentions
 		}
+		a.notifyChat(payload, serverAddr, channelID, senderID, tr.MyID(), mentions)
 		slog.Debug("emit chat:message", "addr", serverAddr, "msg_id", msgID, "sender_id", senderID)
 		wailsrt.EventsEmit(a.ctx, "chat:message", payload)
 	})
@@ -492,6 +924,14 @@ func (a *App) wireSessionCallbacks(serverAddr string, tr Transporter) {
 			"name":        name,
 		})
 	})
+	tr.SetOnServerError(func(message string, retryAfterMs int64) {
+		slog.Debug("emit server:error", "addr", serverAddr, "error", message, "retry_after_ms", retryAfterMs)
+		wailsrt.EventsEmit(a.ctx, "server:error", map[string]any{
+			"server_addr":    serverAddr,
+			"error":          message,
+			"retry_after_ms": retryAfterMs,
+		})
+	})
 	tr.SetOnOwnerChanged(func(ownerID uint16) {
 		slog.Debug("emit channel:owner", "addr", serverAddr, "owner_id", ownerID)
 		wailsrt.EventsEmit(a.ctx, "channel:owner", map[string]any{
@@ -499,25 +939,65 @@ func (a *App) wireSessionCallbacks(serverAddr string, tr Transporter) {
 			"owner_id":    int(ownerID),
 		})
 	})
-	tr.SetOnKicked(func() {
+	tr.SetOnKicked(func(reason string) {
 		if a.connected.Load() {
 			a.connected.Store(false)
 			a.audio.Stop()
 		}
-		slog.Debug("emit connection:kicked", "addr", serverAddr)
+		slog.Debug("emit connection:kicked", "addr", serverAddr, "reason", reason)
 		wailsrt.EventsEmit(a.ctx, "connection:kicked", map[string]any{
 			"server_addr": serverAddr,
+			"reason":      reason,
 		})
-		slog.Info("kicked from server", "addr", serverAddr)
+		slog.Info("kicked from server", "addr", serverAddr, "reason", reason)
 	})
 	tr.SetOnChannelList(func(channels []ChannelInfo) {
+		a.mu.Lock()
+		a.channels = channels
+		a.mu.Unlock()
+		if a.connected.Load() {
+			a.applyChannelBitrateCap(a.activeChannel.Load())
+		}
 		slog.Debug("emit channel:list", "addr", serverAddr)
 		wailsrt.EventsEmit(a.ctx, "channel:list", map[string]any{
 			"server_addr": serverAddr,
 			"channels":    channels,
 		})
+		a.autoJoin(serverAddr, channels)
+	})
+	tr.SetOnCategoryList(func(categories []CategoryInfo) {
+		wailsrt.EventsEmit(a.ctx, "channel:categories", map[string]any{
+			"server_addr": serverAddr,
+			"categories":  categories,
+		})
 	})
 	tr.SetOnUserChannel(func(userID uint16, channelID int64) {
+		if userID == tr.MyID() {
+			a.activeChannel.Store(channelID)
+			a.applyChannelBitrateCap(channelID)
+			// The server moved us out of voice (e.g. idle timeout) without
+			// a local DisconnectVoice; stop transmitting to match.
+			if channelID == 0 && a.connected.CompareAndSwap(true, false) {
+				a.audio.Stop()
+				a.audio.PlayNotification(SoundDisconnect)
+				slog.Info("moved out of voice by server", "addr", serverAddr)
+				wailsrt.EventsEmit(a.ctx, "voice:server_disconnected", map[string]any{
+					"server_addr": serverAddr,
+				})
+			}
+		}
+		// A whisper ends once its target and we are no longer together;
+		// re-setting the target re-runs that check.
+		if w := tr.WhisperTarget(); w != 0 && (userID == w || userID == tr.MyID()) {
+			if err := tr.SetWhisperTarget(w); err != nil {
+				tr.ClearWhisper()
+				slog.Debug("emit voice:whisper_ended", "addr", serverAddr, "user_id", w)
+				wailsrt.EventsEmit(a.ctx, "voice:whisper_ended", map[string]any{
+					"server_addr": serverAddr,
+					"user_id":     int(w),
+				})
+			}
+		}
 		slog.Debug("emit channel:user_moved", "addr", serverAddr, "user_id", userID, "channel_id", channelID)
 		wailsrt.EventsEmit(a.ctx, "channel:user_moved", map[string]any{
 			"server_addr": serverAddr,
@@ -526,6 +1006,9 @@ func (a *App) wireSessionCallbacks(serverAddr string, tr Transporter) {
 		})
 	})
 	tr.SetOnUserRenamed(func(userID uint16, username string) {
+		if a.blocks.observe(serverAddr, userID, username) {
+			tr.BlockUser(userID)
+		}
 		slog.Debug("emit user:renamed", "addr", serverAddr, "user_id", userID, "username", username)
 		wailsrt.EventsEmit(a.ctx, "user:renamed", map[string]any{
 			"server_addr": serverAddr,
@@ -576,6 +1059,79 @@ func (a *App) wireSessionCallbacks(serverAddr string, tr Transporter) {
 			"id":          int(userID),
 		})
 	})
+	tr.SetOnReactionsUpdated(func(msgID uint64, reactions []ChatHistoryReaction) {
+		slog.Debug("emit chat:reactions_updated", "addr", serverAddr, "msg_id", msgID, "emojis", len(reactions))
+		wailsrt.EventsEmit(a.ctx, "chat:reactions_updated", map[string]any{
+			"server_addr": serverAddr,
+			"msg_id":      msgID,
+			"reactions":   reactions,
+		})
+	})
+	tr.SetOnMessageRead(func(msgID uint64, readers []uint16) {
+		ids := make([]int, len(readers))
+		for i, id := range readers {
+			ids[i] = int(id)
+		}
+		slog.Debug("emit chat:message_read", "addr", serverAddr, "msg_id", msgID, "readers", len(ids))
+		wailsrt.EventsEmit(a.ctx, "chat:message_read", map[string]any{
+			"server_addr": serverAddr,
+			"msg_id":      msgID,
+			"readers":     ids,
+		})
+	})
+	tr.SetOnUserStatus(func(userID uint16, status string) {
+		if userID == tr.MyID() {
+			a.doNotDisturb.Store(status == "dnd")
+		}
+		slog.Debug("emit user:status", "addr", serverAddr, "user_id", userID, "status", status)
+		wailsrt.EventsEmit(a.ctx, "user:status", map[string]any{
+			"server_addr": serverAddr,
+			"user_id":     int(userID),
+			"status":      status,
+		})
+	})
+	tr.SetOnUserMuted(func(userID uint16, muted bool) {
+		if userID == tr.MyID() {
+			switch {
+			case muted && !a.audio.IsMuted():
+				a.audio.SetMuted(true)
+				a.serverMutedMic.Store(true)
+			case !muted && a.serverMutedMic.Swap(false):
+				a.audio.SetMuted(false)
+				_ = tr.SendVoiceFlags(a.audio.IsMuted(), a.audio.IsDeafened())
+			}
+		}
+		slog.Debug("emit user:server_muted", "addr", serverAddr, "user_id", userID, "muted", muted)
+		wailsrt.EventsEmit(a.ctx, "user:server_muted", map[string]any{
+			"server_addr": serverAddr,
+			"user_id":     int(userID),
+			"muted":       muted,
+			"self":        userID == tr.MyID(),
+		})
+	})
+	tr.SetOnPermissions(func(perms Permissions) {
+		slog.Debug("emit permissions:update", "addr", serverAddr, "owner_id", perms.OwnerID, "roles", len(perms.Roles))
+		wailsrt.EventsEmit(a.ctx, "permissions:update", map[string]any{
+			"server_addr": serverAddr,
+			"owner_id":    int(perms.OwnerID),
+			"co_owners":   perms.CoOwners,
+			"roles":       perms.Roles,
+		})
+	})
+	tr.SetOnDM(func(senderID, targetID uint16, username, message string, ts int64) {
+		slog.Debug("emit chat:dm", "addr", serverAddr, "sender_id", senderID, "target_id", targetID)
+		wailsrt.EventsEmit(a.ctx, "chat:dm", map[string]any{
+			"server_addr": serverAddr,
+			"sender_id":   int(senderID),
+			"target_id":   int(targetID),
+			"username":    username,
+			"message":     message,
+			"ts":          ts,
+		})
+		if senderID != tr.MyID() {
+			a.notifyDM()
+		}
+	})
 	tr.SetOnUserTyping(func(userID uint16, username string, channelID int64) {
 		slog.Debug("emit chat:user_typing", "addr", serverAddr, "user_id", userID, "channel_id", channelID)
 		wailsrt.EventsEmit(a.ctx, "chat:user_typing", map[string]any{
@@ -611,33 +1167,62 @@ func (a *App) wireSessionCallbacks(serverAddr string, tr Transporter) {
 	})
 	tr.SetOnMessageHistory(func(channelID int64, messages []ChatHistoryMessage) {
 		slog.Debug("emit chat:history", "addr", serverAddr, "channel_id", channelID)
-		// Enrich messages with file URLs before emitting.
-		enriched := make([]map[string]any, len(messages))
-		for i, m := range messages {
-			item := map[string]any{
-				"msg_id":   m.MsgID,
-				"username": m.Username,
-				"message":  m.Message,
-				"ts":       m.TS,
-			}
-			if m.FileID != "" {
-				item["file_id"] = m.FileID
-				item["file_name"] = m.FileName
-				item["file_size"] = m.FileSize
-				item["file_url"] = fileURLForTransport(tr, m.FileID)
-			}
-			if len(m.Reactions) > 0 {
-				item["reactions"] = m.Reactions
-			}
-			enriched[i] = item
-		}
 		wailsrt.EventsEmit(a.ctx, "chat:history", map[string]any{
 			"server_addr": serverAddr,
 			"channel_id":  channelID,
-			"messages":    enriched,
+			"messages":    historyPayload(tr, messages),
+		})
+	})
+	tr.SetOnThread(func(msgID uint64, messages []ChatHistoryMessage) {
+		slog.Debug("emit chat:thread", "addr", serverAddr, "msg_id", msgID, "count", len(messages))
+		wailsrt.EventsEmit(a.ctx, "chat:thread", map[string]any{
+			"server_addr": serverAddr,
+			"msg_id":      msgID,
+			"messages":    historyPayload(tr, messages),
+		})
+	})
+	tr.SetOnEditHistory(func(msgID uint64, edits []MessageEdit) {
+		slog.Debug("emit chat:edit_history", "addr", serverAddr, "msg_id", msgID, "count", len(edits))
+		wailsrt.EventsEmit(a.ctx, "chat:edit_history", map[string]any{
+			"server_addr": serverAddr,
+			"msg_id":      msgID,
+			"edits":       edits,
+		})
+	})
+	tr.SetOnAuditLog(func(entries []AuditEntry) {
+		slog.Debug("emit audit:log", "addr", serverAddr, "count", len(entries))
+		wailsrt.EventsEmit(a.ctx, "audit:log", map[string]any{
+			"server_addr": serverAddr,
+			"entries":     entries,
+		})
+	})
+	tr.SetOnAuditEntry(func(entry AuditEntry) {
+		slog.Debug("emit audit:entry", "addr", serverAddr, "action", entry.Action)
+		wailsrt.EventsEmit(a.ctx, "audit:entry", map[string]any{
+			"server_addr": serverAddr,
+			"entry":       entry,
+		})
+	})
+	tr.SetOnPinnedList(func(channelID int64, pins []PinnedMessage) {
+		slog.Debug("emit chat:pinned_list", "addr", serverAddr, "channel_id", channelID, "count", len(pins))
+		wailsrt.EventsEmit(a.ctx, "chat:pinned_list", map[string]any{
+			"server_addr": serverAddr,
+			"channel_id":  channelID,
+			"pins":        pins,
+		})
+	})
+	tr.SetOnBanList(func(bans []BanInfo) {
+		slog.Debug("emit bans:list", "addr", serverAddr, "count", len(bans))
+		wailsrt.EventsEmit(a.ctx, "bans:list", map[string]any{
+			"server_addr": serverAddr,
+			"bans":        bans,
 		})
 	})
 	tr.SetOnUserVoiceFlags(func(userID uint16, muted, deafened bool) {
+		// The server mutes us when we may not speak in our channel.
+		if userID == tr.MyID() && muted && !a.audio.IsMuted() {
+			a.audio.SetMuted(true)
+		}
 		slog.Debug("emit channel:user_voice_flags", "addr", serverAddr, "user_id", userID, "muted", muted, "deafened", deafened)
 		wailsrt.EventsEmit(a.ctx, "channel:user_voice_flags", map[string]any{
 			"server_addr": serverAddr,
@@ -646,35 +1231,151 @@ func (a *App) wireSessionCallbacks(serverAddr string, tr Transporter) {
 			"deafened":    deafened,
 		})
 	})
-	a.audio.OnSpeaking = func() {
-		a.mu.RLock()
-		currentTr := a.transport
-		currentAddr := a.serverAddr
-		a.mu.RUnlock()
-		if currentTr == nil {
+	tr.SetOnRecordingStarted(func(userID uint16, consentRequired bool) {
+		slog.Debug("emit voice:recording_started", "addr", serverAddr, "user_id", userID, "consent_required", consentRequired)
+		wailsrt.EventsEmit(a.ctx, "voice:recording_started", map[string]any{
+			"server_addr":      serverAddr,
+			"user_id":          userID,
+			"consent_required": consentRequired,
+		})
+	})
+	tr.SetOnRecordingStopped(func(userID uint16) {
+		slog.Debug("emit voice:recording_stopped", "addr", serverAddr, "user_id", userID)
+		wailsrt.EventsEmit(a.ctx, "voice:recording_stopped", map[string]any{
+			"server_addr": serverAddr,
+			"user_id":     userID,
+		})
+	})
+
+	tr.SetOnSoundboard(func(userID uint16, clipID string) {
+		if err := a.audio.PlaySoundboard(clipID); err != nil {
+			slog.Debug("play soundboard", "clip_id", clipID, "err", err)
 			return
 		}
-		slog.Debug("emit audio:speaking", "addr", currentAddr, "id", currentTr.MyID())
-		wailsrt.EventsEmit(a.ctx, "audio:speaking", map[string]any{
-			"server_addr": currentAddr,
-			"id":          int(currentTr.MyID()),
+		slog.Debug("emit voice:soundboard", "addr", serverAddr, "user_id", userID, "clip_id", clipID)
+		wailsrt.EventsEmit(a.ctx, "voice:soundboard", map[string]any{
+			"server_addr": serverAddr,
+			"user_id":     int(userID),
+			"clip_id":     clipID,
 		})
-	}
-}
-
-// Disconnect tears down the voice and control session.
-func (a *App) Disconnect() {
-	slog.Debug("Disconnect")
-	if a.connected.Load() {
-		_ = a.DisconnectVoice()
-	}
-
-	a.mu.Lock()
-	tr := a.transport
-	a.transport = nil
-	a.serverAddr = ""
-	a.mu.Unlock()
-
+	})
+	// voice:peer_stalled fires with stalled true when a peer's audio is
+	// not reaching us, a hint that a TURN relay may be needed, and again
+	// with stalled false once it arrives.
+	tr.SetOnPeerAudioStalled(func(id uint16) {
+		slog.Debug("emit voice:peer_stalled", "addr", serverAddr, "user_id", id, "stalled", true)
+		wailsrt.EventsEmit(a.ctx, "voice:peer_stalled", map[string]any{
+			"server_addr": serverAddr,
+			"user_id":     int(id),
+			"stalled":     true,
+		})
+	})
+	tr.SetOnPeerAudioResumed(func(id uint16) {
+		slog.Debug("emit voice:peer_stalled", "addr", serverAddr, "user_id", id, "stalled", false)
+		wailsrt.EventsEmit(a.ctx, "voice:peer_stalled", map[string]any{
+			"server_addr": serverAddr,
+			"user_id":     int(id),
+			"stalled":     false,
+		})
+	})
+	tr.SetOnAnnouncement(func(text, postedBy string) {
+		slog.Debug("emit server:announcement", "addr", serverAddr, "len", len(text))
+		wailsrt.EventsEmit(a.ctx, "server:announcement", map[string]any{
+			"server_addr": serverAddr,
+			"text":        text,
+			"posted_by":   postedBy,
+		})
+	})
+	tr.SetOnMOTD(func(text string) {
+		slog.Debug("emit server:motd", "addr", serverAddr, "len", len(text))
+		wailsrt.EventsEmit(a.ctx, "server:motd", map[string]any{
+			"server_addr": serverAddr,
+			"motd":        text,
+		})
+	})
+	tr.SetOnEmojiList(func(emoji []string) {
+		slog.Debug("emit server:emoji_list", "addr", serverAddr, "count", len(emoji))
+		if emoji == nil {
+			emoji = []string{}
+		}
+		wailsrt.EventsEmit(a.ctx, "server:emoji_list", map[string]any{
+			"server_addr": serverAddr,
+			"emoji":       emoji,
+		})
+	})
+	tr.SetOnServerShutdown(func(graceMs int64) {
+		slog.Debug("emit server:shutdown", "addr", serverAddr, "grace_ms", graceMs)
+		wailsrt.EventsEmit(a.ctx, "server:shutdown", map[string]any{
+			"server_addr": serverAddr,
+			"grace_ms":    graceMs,
+		})
+	})
+	tr.SetOnStopRecording(func() {
+		if err := a.audio.StopRecording(); err != nil {
+			slog.Error("finish local recording", "err", err)
+		}
+		slog.Debug("emit recording:stopped", "addr", serverAddr)
+		wailsrt.EventsEmit(a.ctx, "recording:stopped", map[string]any{
+			"server_addr": serverAddr,
+		})
+	})
+	tr.SetOnMention(func(msgID uint64, channelID int64, senderID uint16, username string) {
+		a.notifyMention(serverAddr, channelID)
+		slog.Debug("emit chat:mention", "addr", serverAddr, "msg_id", msgID, "channel_id", channelID, "sender_id", senderID)
+		wailsrt.EventsEmit(a.ctx, "chat:mention", map[string]any{
+			"server_addr": serverAddr,
+			"msg_id":      msgID,
+			"channel_id":  channelID,
+			"sender_id":   int(senderID),
+			"username":    username,
+		})
+	})
+	a.audio.OnSpeaking = func() {
+		a.mu.RLock()
+		currentTr := a.transport
+		currentAddr := a.serverAddr
+		a.mu.RUnlock()
+		if currentTr == nil {
+			return
+		}
+		if a.connected.Load() {
+			if err := currentTr.SendVoiceActivity(); err != nil {
+				slog.Debug("send voice activity", "err", err)
+			}
+			if err := currentTr.SendSpeaking(); err != nil {
+				slog.Debug("send speaking", "err", err)
+			}
+		}
+		slog.Debug("emit audio:speaking", "addr", currentAddr, "id", currentTr.MyID())
+		wailsrt.EventsEmit(a.ctx, "audio:speaking", map[string]any{
+			"server_addr": currentAddr,
+			"id":          int(currentTr.MyID()),
+		})
+	}
+	a.audio.UserVolumeFunc = func(senderID uint16) float64 {
+		a.mu.RLock()
+		currentTr := a.transport
+		a.mu.RUnlock()
+		if currentTr == nil {
+			return 1.0
+		}
+		return currentTr.GetUserVolume(senderID) * a.monitorGain(currentTr, senderID)
+	}
+}
+
+// Disconnect tears down the voice and control session.
+func (a *App) Disconnect() {
+	slog.Debug("Disconnect")
+	if a.connected.Load() {
+		_ = a.DisconnectVoice()
+	}
+
+	a.mu.Lock()
+	tr := a.transport
+	a.transport = nil
+	a.serverAddr = ""
+	a.mu.Unlock()
+
 	if tr != nil {
 		tr.Disconnect()
 	}
@@ -692,6 +1393,12 @@ func (a *App) Disconnect() {
 // Returns an error message string or "" on success (Wails JS binding convention).
 func (a *App) DisconnectVoice() string {
 	slog.Debug("DisconnectVoice")
+	return a.leaveVoice(func(tr Transporter) error { return tr.JoinChannel(0) })
+}
+
+// leaveVoice stops local audio, tells the server with leave and mirrors
+// the move to the lobby locally. See DisconnectVoice and RecordingConsent.
+func (a *App) leaveVoice(leave func(tr Transporter) error) string {
 	a.mu.RLock()
 	tr := a.transport
 	addr := a.serverAddr
@@ -703,9 +1410,10 @@ func (a *App) DisconnectVoice() string {
 	}
 
 	a.audio.Stop()
+	tr.ClearWhisper()
 
 	var serverErr string
-	if err := tr.JoinChannel(0); err != nil {
+	if err := leave(tr); err != nil {
 		if !strings.Contains(err.Error(), "control websocket not connected") {
 			serverErr = err.Error()
 		}
@@ -714,6 +1422,8 @@ func (a *App) DisconnectVoice() string {
 	// Always mark voice as disconnected locally, even if the server
 	// message failed. Audio is already stopped at this point.
 	a.connected.Store(false)
+	a.activeChannel.Store(0)
+	a.applyChannelBitrateCap(0)
 
 	// Emit a local channel:user_moved event so the frontend sees the user
 	// leave the channel immediately, without waiting for the server
@@ -733,6 +1443,23 @@ func (a *App) DisconnectVoice() string {
 	return serverErr
 }
 
+// RecordingConsent answers someone recording our voice channel when the
+// server requires consent: true lets us unmute, false leaves voice.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) RecordingConsent(consent bool) string {
+	if !consent {
+		return a.leaveVoice(func(tr Transporter) error { return tr.SendRecordingConsent(false) })
+	}
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.SendRecordingConsent(true); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
 // ConnectVoice restarts audio capture/playback and joins the given channel.
 // Call this after DisconnectVoice to rejoin voice in a channel.
 // Returns an error message string or "" on success (Wails JS binding convention).
@@ -763,6 +1490,7 @@ func (a *App) ConnectVoice(channelID int) string {
 		return err.Error()
 	}
 	a.connected.Store(true)
+	a.applyChannelBitrateCap(int64(channelID))
 	a.audio.PlayNotification(SoundConnect)
 
 	a.mu.RLock()
@@ -780,14 +1508,35 @@ func (a *App) GetMetrics() Metrics {
 	return a.cachedMetrics
 }
 
+// GetPeerMetrics returns connection quality to each remote peer, ordered by
+// user ID, for spotting a single bad connection. It is empty when not
+// connected.
+func (a *App) GetPeerMetrics() []PeerMetrics {
+	a.mu.RLock()
+	tr := a.transport
+	a.mu.RUnlock()
+	out := []PeerMetrics{}
+	if tr == nil {
+		return out
+	}
+	for _, m := range tr.GetPeerMetrics() {
+		out = append(out, m)
+	}
+	slices.SortFunc(out, func(x, y PeerMetrics) int { return int(x.UserID) - int(y.UserID) })
+	return out
+}
+
 // adaptInterval is the metrics refresh interval.
 const adaptInterval = 5 * time.Second
 
-// adaptBitrateLoop caches quality metrics for the frontend. Adaptive bitrate
-// and custom jitter-depth control were removed in favor of WebRTC defaults.
+// adaptBitrateLoop caches quality metrics for the frontend and, when adaptive
+// bitrate is enabled, steps the Opus bitrate within the configured range
+// based on the measured quality. Playback jitter depth is a user setting;
+// see SetJitterBufferMs.
 func (a *App) adaptBitrateLoop(done <-chan struct{}) {
 	ticker := time.NewTicker(adaptInterval)
 	defer ticker.Stop()
+	var adapter bitrateAdapter
 
 	for {
 		select {
@@ -804,15 +1553,26 @@ func (a *App) adaptBitrateLoop(done <-chan struct{}) {
 
 			// Collect local frame drops since the last tick.
 			captureDrops, playbackDropsLocal := a.audio.DroppedFrames()
-			m.CaptureDropped = captureDrops
+			m.CaptureDropped += captureDrops
 			m.PlaybackDropped += playbackDropsLocal
 
 			// Compute quality level including local drops.
-			totalDrops := captureDrops + m.PlaybackDropped
+			totalDrops := m.CaptureDropped + m.PlaybackDropped
 			dropRate := float64(totalDrops) / adaptInterval.Seconds()
 
-			m.OpusTargetKbps = a.audio.CurrentBitrate()
 			m.QualityLevel = qualityLevel(m.PacketLoss, m.RTTMs, m.JitterMs, dropRate)
+			if a.audio.FECEnabled() {
+				a.audio.SetPacketLoss(fecLossPercent(m.PacketLoss))
+			}
+			if a.adaptiveBitrate.Load() {
+				cur := a.audio.CurrentBitrate()
+				floor, ceiling := a.audio.adaptiveBitrateRange()
+				if next := adapter.next(cur, m.QualityLevel, floor, ceiling); next != cur {
+					slog.Info("adaptive bitrate", "quality", m.QualityLevel, "from_kbps", cur, "to_kbps", next)
+					a.audio.SetBitrate(next)
+				}
+			}
+			m.OpusTargetKbps = a.audio.CurrentBitrate()
 			a.metricsMu.Lock()
 			a.cachedMetrics = m
 			a.metricsMu.Unlock()
@@ -834,18 +1594,69 @@ func (a *App) GetConfig() Config {
 func (a *App) ApplyConfig() {
 	cfg := LoadConfig()
 	a.audio.SetVolume(cfg.Volume)
+	// The frame size decides how much bitrate the packet cap allows.
+	if err := a.audio.SetCaptureFrameMs(cfg.CaptureFrameMs); err != nil {
+		slog.Warn("ignoring saved capture frame size", "ms", cfg.CaptureFrameMs, "err", err)
+	}
+	// Apply the packet cap first so the saved bitrate is clamped to it.
+	if err := a.audio.SetMaxPacketBytes(cfg.MaxPacketBytes); err != nil {
+		slog.Warn("ignoring saved max packet size", "bytes", cfg.MaxPacketBytes, "err", err)
+	}
 	if cfg.AudioBitrate > 0 {
-		a.audio.SetBitrate(cfg.AudioBitrate)
+		a.SetAudioBitrate(cfg.AudioBitrate)
+	}
+	// The profile's bitrate, FEC and DTX are saved in their own fields (and
+	// may have been tweaked since); only its complexity lives here.
+	if p, ok := audioProfiles[cfg.AudioProfile]; ok {
+		a.audio.SetOpusComplexity(p.complexity)
 	}
+	if err := a.audio.SetOpusApplication(cfg.OpusApplication); err != nil {
+		slog.Warn("ignoring saved opus application", "mode", cfg.OpusApplication, "err", err)
+	}
+	if err := a.audio.SetBitrateRange(cfg.BitrateFloorKbps, cfg.BitrateCeilingKbps); err != nil {
+		slog.Warn("ignoring saved bitrate range", "floor_kbps", cfg.BitrateFloorKbps, "ceiling_kbps", cfg.BitrateCeilingKbps, "err", err)
+	}
+	a.SetAdaptiveBitrate(cfg.AdaptiveBitrate)
 	a.audio.SetAEC(cfg.AECEnabled)
 	a.audio.SetAGC(cfg.AGCEnabled)
+	a.audio.SetAGCTarget(cfg.AGCTargetDb)
+	a.audio.SetFEC(cfg.FECEnabled)
+	a.audio.SetDTX(cfg.DTXEnabled)
+	a.SetOnsetRedundancy(cfg.OnsetRedundancy)
+	a.audio.SetNoiseGate(cfg.NoiseGateDb, cfg.NoiseGateEnabled)
+	a.audio.SetSidetone(cfg.SidetoneEnabled, cfg.SidetoneGain)
+	a.SetMonitorVolume(cfg.MonitorVolume)
+	if msg := a.SetDropPolicy(cfg.DropPolicy); msg != "" {
+		slog.Warn("ignoring saved drop policy", "policy", cfg.DropPolicy, "err", msg)
+	}
+	a.audio.SetAutoLevel(cfg.AutoLevel)
+	a.SetStereo(cfg.Stereo)
+	a.audio.SetJitterBufferMs(cfg.JitterBufferMs)
 	a.audio.SetPTTMode(cfg.PTTEnabled)
+	pttKey := ""
+	if cfg.PTTEnabled {
+		pttKey = cfg.PTTKey
+	}
+	a.SetPTTHotkey(pttKey)
 	a.SetNoiseSuppression(cfg.NoiseEnabled)
+	if validSignal(cfg.SignalType) {
+		_ = a.audio.SetSignalType(cfg.SignalType)
+	}
+	a.audio.SetSignalAutoDetect(cfg.SignalAutoDetect)
+	a.SetUploadBandwidthLimit(cfg.UploadLimitKbps)
+	a.audio.SetDoNotDisturb(cfg.DoNotDisturb)
+	a.notifyMu.Lock()
+	a.channelNotify = cfg.ChannelNotify
+	a.notifyMu.Unlock()
+	a.blocks.load(cfg.BlockedUsers)
+	a.notifyMuted.load(cfg.NotifyMutedUsers)
+	a.SetJoinLeaveSounds(cfg.JoinLeaveSounds)
+	a.SetInCallAlerts(cfg.InCallAlerts)
 	if cfg.InputDeviceID >= 0 {
-		a.audio.SetInputDevice(cfg.InputDeviceID)
+		a.SetInputDevice(cfg.InputDeviceID)
 	}
 	if cfg.OutputDeviceID >= 0 {
-		a.audio.SetOutputDevice(cfg.OutputDeviceID)
+		a.SetOutputDevice(cfg.OutputDeviceID)
 	}
 }
 
@@ -943,16 +1754,151 @@ func (a *App) RenameServer(name string) string {
 	return ""
 }
 
-// KickUser removes the given user from the server. Only succeeds if the
-// caller is the channel owner; the server enforces the check.
+// KickUser removes the given user from the server, showing them reason if it
+// is not blank. Only admins and the owner may kick; the server enforces this.
 // Returns an error message string or "" on success (Wails JS binding convention).
-func (a *App) KickUser(id int) string {
+func (a *App) KickUser(id int, reason string) string {
 	slog.Debug("KickUser", "user_id", id)
 	tr, err := a.requireTransport()
 	if err != nil {
 		return err.Error()
 	}
-	if err := tr.KickUser(uint16(id)); err != nil {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		err = tr.KickUser(uint16(id))
+	} else {
+		err = tr.KickUserWithReason(uint16(id), reason)
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// BanUser bans a user for durationS seconds (0 = permanently) and
+// disconnects them. Only admins and the owner may ban; the server enforces
+// this.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) BanUser(id int, reason string, durationS int) string {
+	slog.Debug("BanUser", "user_id", id, "duration_s", durationS)
+	if durationS < 0 {
+		return "ban duration must not be negative"
+	}
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.BanUser(uint16(id), strings.TrimSpace(reason), durationS); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// RequestBans asks the server for its active bans; they arrive as a
+// bans:list event. The server only answers admins and the owner.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) RequestBans() string {
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.RequestBans(); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// Unban lifts the ban with the given ID from bans:list; the updated list
+// follows as another bans:list event.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) Unban(id int64) string {
+	slog.Debug("Unban", "ban_id", id)
+	if id <= 0 {
+		return "invalid ban id"
+	}
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.Unban(id); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// SetStatus sets our presence status: "online", "away" or "dnd". While it
+// is "dnd", join and leave sounds are not played.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SetStatus(status string) string {
+	slog.Debug("SetStatus", "status", status)
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.SetStatus(strings.ToLower(strings.TrimSpace(status))); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// MuteUserServer mutes a user in voice for durationS seconds (0 = until
+// UnmuteUserServer). They cannot unmute themselves meanwhile. Moderators
+// and above may mute users they outrank; the server enforces this.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) MuteUserServer(id int, durationS int) string {
+	slog.Debug("MuteUserServer", "user_id", id, "duration_s", durationS)
+	if durationS < 0 {
+		return "mute duration must not be negative"
+	}
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.MuteUserServer(uint16(id), durationS); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// UnmuteUserServer lifts a mute set with MuteUserServer.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) UnmuteUserServer(id int) string {
+	slog.Debug("UnmuteUserServer", "user_id", id)
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.UnmuteUserServer(uint16(id)); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// TransferOwner hands server ownership to another connected user. Only the
+// owner may do this; the server enforces it.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) TransferOwner(id int) string {
+	slog.Debug("TransferOwner", "user_id", id)
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.TransferOwner(uint16(id)); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// SetRole gives another user a role ("USER", "MODERATOR" or "ADMIN"). Only
+// the owner may do this; the server enforces it.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SetRole(id int, role string) string {
+	slog.Debug("SetRole", "user_id", id, "role", role)
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.SetRole(uint16(id), role); err != nil {
 		return err.Error()
 	}
 	return ""
@@ -970,9 +1916,41 @@ func (a *App) JoinChannel(id int) string {
 	if err := tr.JoinChannel(int64(id)); err != nil {
 		return err.Error()
 	}
+	a.applyChannelBitrateCap(int64(id))
+	a.announceRecording()
 	return ""
 }
 
+// applyChannelBitrateCap clamps the Opus bitrate to the cap of channelID
+// (0 = not in voice) and, when the cap changes, returns to the user's
+// preferred bitrate within it.
+func (a *App) applyChannelBitrateCap(channelID int64) {
+	kbps := 0
+	if channelID != 0 {
+		a.mu.RLock()
+		for _, ch := range a.channels {
+			if ch.ID == channelID {
+				kbps = ch.MaxBitrateKbps
+				break
+			}
+		}
+		a.mu.RUnlock()
+	}
+	if kbps == a.audio.ChannelBitrateCap() {
+		return
+	}
+	a.audio.SetChannelBitrateCap(kbps)
+	preferred := int(a.manualBitrateKbps.Load())
+	if preferred <= 0 {
+		preferred = opusBitrate / 1000
+	}
+	if a.adaptiveBitrate.Load() {
+		preferred = a.clampToAdaptiveRange(preferred)
+	}
+	a.audio.SetBitrate(preferred)
+	slog.Info("channel bitrate cap", "channel_id", channelID, "cap_kbps", kbps, "kbps", a.audio.CurrentBitrate())
+}
+
 // SendChannelChat sends a channel-scoped chat message to all users in that channel.
 // Returns an error message string or "" on success (Wails JS binding convention).
 func (a *App) SendChannelChat(channelID int, message string) string {
@@ -987,6 +1965,36 @@ func (a *App) SendChannelChat(channelID int, message string) string {
 	return ""
 }
 
+// SendTyping tells the server we are typing in channelID. The frontend may
+// call it on every keystroke; the transport throttles it.
+func (a *App) SendTyping(channelID int) {
+	tr, err := a.requireTransport()
+	if err != nil {
+		return
+	}
+	if err := tr.SendTyping(int64(channelID)); err != nil {
+		slog.Debug("send typing", "channel_id", channelID, "err", err)
+	}
+}
+
+// SendDM sends a private message to the user with the given ID. Only that
+// user and we receive it.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SendDM(id int, message string) string {
+	slog.Debug("SendDM", "target_id", id, "length", len(message))
+	if id <= 0 {
+		return "invalid user"
+	}
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.SendDM(uint16(id), message); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
 // EditMessage asks the server to update a chat message's text.
 // Only the original sender is allowed to edit; the server enforces the check.
 // Returns an error message string or "" on success (Wails JS binding convention).
@@ -1017,6 +2025,24 @@ func (a *App) DeleteMessage(msgID int) string {
 	return ""
 }
 
+// PurgeMessages deletes the newest count messages in a channel. Only
+// moderators and above may; the server caps count.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) PurgeMessages(channelID, count int) string {
+	slog.Debug("PurgeMessages", "channel_id", channelID, "count", count)
+	if count < 1 {
+		return "count must be at least 1"
+	}
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.PurgeMessages(int64(channelID), count); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
 // AddReaction adds an emoji reaction to a message.
 // Returns an error message string or "" on success (Wails JS binding convention).
 func (a *App) AddReaction(msgID int, emoji string) string {
@@ -1045,6 +2071,36 @@ func (a *App) RemoveReaction(msgID int, emoji string) string {
 	return ""
 }
 
+// GetAllowedEmoji returns the connected server's reaction allow-list. It is
+// empty when any emoji may be used or when not connected.
+func (a *App) GetAllowedEmoji() []string {
+	a.mu.RLock()
+	tr := a.transport
+	a.mu.RUnlock()
+	if tr == nil {
+		return []string{}
+	}
+	if list := tr.AllowedEmoji(); list != nil {
+		return list
+	}
+	return []string{}
+}
+
+// SendReadReceipt reports that a message has scrolled into view. It is
+// fire-and-forget like SendTyping: a lost receipt is not worth an error.
+func (a *App) SendReadReceipt(msgID int) {
+	if msgID <= 0 {
+		return
+	}
+	tr, err := a.requireTransport()
+	if err != nil {
+		return
+	}
+	if err := tr.SendReadReceipt(uint64(msgID)); err != nil {
+		slog.Debug("send read receipt", "msg_id", msgID, "err", err)
+	}
+}
+
 // SendChat sends a chat message to the server for fan-out to all participants.
 // Returns an error message string or "" on success (Wails JS binding convention).
 func (a *App) SendChat(message string) string {
@@ -1060,6 +2116,34 @@ func (a *App) SendChat(message string) string {
 }
 
 // fileURL constructs a download URL for the given file ID using the API base URL.
+// historyPayload converts persisted messages to frontend event items,
+// enriching attachments with their download URLs.
+func historyPayload(tr Transporter, messages []ChatHistoryMessage) []map[string]any {
+	enriched := make([]map[string]any, len(messages))
+	for i, m := range messages {
+		item := map[string]any{
+			"msg_id":   m.MsgID,
+			"username": m.Username,
+			"message":  m.Message,
+			"ts":       m.TS,
+		}
+		if m.FileID != "" {
+			item["file_id"] = m.FileID
+			item["file_name"] = m.FileName
+			item["file_size"] = m.FileSize
+			item["file_url"] = fileURLForTransport(tr, m.FileID)
+		}
+		if m.ReplyTo != 0 {
+			item["reply_to"] = m.ReplyTo
+		}
+		if len(m.Reactions) > 0 {
+			item["reactions"] = m.Reactions
+		}
+		enriched[i] = item
+	}
+	return enriched
+}
+
 func fileURLForTransport(tr Transporter, fileID string) string {
 	if tr == nil || fileID == "" {
 		return ""
@@ -1115,59 +2199,101 @@ type uploadResponse struct {
 	ContentType  string `json:"content_type"`
 }
 
-const maxFileSize = 10 * 1024 * 1024 // 10 MB
-
-func (a *App) uploadFilePath(channelID int64, path string) string {
-	tr, err := a.requireTransport()
-	if err != nil {
-		return err.Error()
+// formatUploadLimit renders an upload limit for error messages, in whole
+// MB or KB when it divides evenly.
+func formatUploadLimit(n int64) string {
+	switch {
+	case n >= 1024*1024 && n%(1024*1024) == 0:
+		return fmt.Sprintf("%d MB", n/(1024*1024))
+	case n >= 1024 && n%1024 == 0:
+		return fmt.Sprintf("%d KB", n/1024)
+	default:
+		return fmt.Sprintf("%d byte", n)
 	}
+}
+
+// uploadBlob uploads the file at path to the server's blob API and returns
+// the stored blob's metadata.
+func (a *App) uploadBlob(tr Transporter, path string) (uploadResponse, error) {
 	base := tr.APIBaseURL()
 	if base == "" {
-		return "server API not available"
+		return uploadResponse{}, fmt.Errorf("server API not available")
 	}
 
-	// Validate file size before uploading.
+	// Validate file size against the server's limit before uploading.
 	info, err := os.Stat(path)
 	if err != nil {
-		return err.Error()
+		return uploadResponse{}, err
 	}
-	if info.Size() > maxFileSize {
-		return fmt.Sprintf("file exceeds %d MB limit", maxFileSize/(1024*1024))
+	if limit := tr.MaxUploadBytes(); info.Size() > limit {
+		return uploadResponse{}, fmt.Errorf("file exceeds the server's %s upload limit", formatUploadLimit(limit))
 	}
 
 	f, err := os.Open(path)
 	if err != nil {
-		return err.Error()
+		return uploadResponse{}, err
 	}
 	defer f.Close()
 
-	// Build multipart form.
-	var buf bytes.Buffer
-	w := multipart.NewWriter(&buf)
-	fw, err := w.CreateFormFile("file", filepath.Base(path))
-	if err != nil {
-		return err.Error()
-	}
-	if _, err := io.Copy(fw, f); err != nil {
-		return err.Error()
-	}
-	w.Close()
-
-	resp, err := http.Post(base+"/api/upload", w.FormDataContentType(), &buf) //nolint:gosec — LAN server, not arbitrary URL
+	// Stream the multipart form through a pipe so the body can be paced
+	// and its progress reported as the HTTP client consumes it.
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	w := multipart.NewWriter(pw)
+	go func() {
+		fw, err := w.CreateFormFile("file", filepath.Base(path))
+		if err == nil {
+			_, err = io.Copy(fw, f)
+		}
+		if err == nil {
+			err = w.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	fileName := filepath.Base(path)
+	body := newProgressReader(
+		newThrottledReader(pr, func() int {
+			return effectiveUploadKbps(int(a.uploadLimitKbps.Load()), a.connected.Load())
+		}),
+		info.Size(),
+		func(p uploadProgress) {
+			wailsrt.EventsEmit(a.ctx, "upload:progress", map[string]any{
+				"file_name":         fileName,
+				"sent":              p.Sent,
+				"total":             p.Total,
+				"percent":           p.Percent,
+				"remaining_seconds": p.RemainingSeconds,
+			})
+		},
+	)
+
+	resp, err := http.Post(base+"/api/upload", w.FormDataContentType(), body) //nolint:gosec — LAN server, not arbitrary URL
 	if err != nil {
-		return err.Error()
+		return uploadResponse{}, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Sprintf("upload failed (%d): %s", resp.StatusCode, string(body))
+		return uploadResponse{}, fmt.Errorf("upload failed (%d): %s", resp.StatusCode, string(body))
 	}
 
 	var ur uploadResponse
 	if err := json.NewDecoder(resp.Body).Decode(&ur); err != nil {
-		return "failed to parse upload response"
+		return uploadResponse{}, fmt.Errorf("failed to parse upload response")
+	}
+	return ur, nil
+}
+
+func (a *App) uploadFilePath(channelID int64, path string) string {
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	ur, err := a.uploadBlob(tr, path)
+	if err != nil {
+		return err.Error()
 	}
 
 	// Send a chat message with the file metadata.
@@ -1177,6 +2303,38 @@ func (a *App) uploadFilePath(channelID int64, path string) string {
 	return ""
 }
 
+// errDMUnsupported is returned for direct file transfers while the server
+// has no direct-message channels to scope them to.
+var errDMUnsupported = fmt.Errorf("direct messages are not supported by this server")
+
+// UploadFileToUser uploads a file at path and sends it privately to userID,
+// for files dropped onto a user in the user list.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) UploadFileToUser(userID int, path string) string {
+	if path == "" {
+		return "no file path"
+	}
+	if userID <= 0 {
+		return "invalid user"
+	}
+	if _, err := a.requireTransport(); err != nil {
+		return err.Error()
+	}
+	// Refuse before uploading so an undeliverable file isn't left behind as
+	// an orphaned blob on the server.
+	return errDMUnsupported.Error()
+}
+
+// SetUploadBandwidthLimit caps file upload bandwidth in kbps (0 = unlimited).
+// While in a voice call uploads are additionally held to voiceUploadCapKbps
+// so transfers don't degrade the call.
+func (a *App) SetUploadBandwidthLimit(kbps int) {
+	if kbps < 0 {
+		kbps = 0
+	}
+	a.uploadLimitKbps.Store(int32(kbps))
+}
+
 // CreateChannel asks the server to create a new channel.
 // Returns an error message string or "" on success (Wails JS binding convention).
 func (a *App) CreateChannel(name string) string {
@@ -1205,6 +2363,121 @@ func (a *App) RenameChannel(id int, name string) string {
 	return ""
 }
 
+// SetChannelBitrate asks the server to cap the bitrate used in a channel;
+// kbps = 0 removes the cap. Only the owner may.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SetChannelBitrate(id, kbps int) string {
+	slog.Debug("SetChannelBitrate", "channel_id", id, "kbps", kbps)
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.SetChannelBitrate(int64(id), kbps); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// SetSlowMode sets the minimum number of seconds between one user's
+// messages in a channel; 0 turns slow mode off. Only admins and the owner
+// may, and they are not limited by it.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SetSlowMode(channelID, seconds int) string {
+	slog.Debug("SetSlowMode", "channel_id", channelID, "seconds", seconds)
+	if seconds < 0 {
+		return "slow mode must not be negative"
+	}
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.SetSlowMode(int64(channelID), seconds); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// SetChannelLock locks or unlocks a voice channel. Users below moderator
+// cannot join a locked channel, but those already in it stay. Only
+// moderators and above may change it.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SetChannelLock(channelID int64, locked bool) string {
+	slog.Debug("SetChannelLock", "channel_id", channelID, "locked", locked)
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.SetChannelLock(channelID, locked); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// SetChannelTTL sets how many seconds the server keeps messages in a
+// channel before deleting them; 0 keeps them forever. Only the owner may.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SetChannelTTL(channelID int64, seconds int) string {
+	slog.Debug("SetChannelTTL", "channel_id", channelID, "seconds", seconds)
+	if seconds < 0 {
+		return "message retention must not be negative"
+	}
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.SetChannelTTL(channelID, seconds); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// SetChannelRecordRole sets the lowest role allowed to record in a
+// channel; "" lets everyone. Only the owner may.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SetChannelRecordRole(channelID int64, role string) string {
+	slog.Debug("SetChannelRecordRole", "channel_id", channelID, "role", role)
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.SetChannelRecordRole(channelID, role); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// CreateCategory asks the server to create a channel category.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) CreateCategory(name string) string {
+	slog.Debug("CreateCategory", "name", name)
+	if strings.TrimSpace(name) == "" {
+		return "category name is required"
+	}
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.CreateCategory(name); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// AssignChannelCategory asks the server to move a channel into a category;
+// categoryID 0 makes the channel uncategorized.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) AssignChannelCategory(channelID, categoryID int64) string {
+	slog.Debug("AssignChannelCategory", "channel_id", channelID, "category_id", categoryID)
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.AssignChannelCategory(channelID, categoryID); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
 // DeleteChannel asks the server to delete a channel.
 // Returns an error message string or "" on success (Wails JS binding convention).
 func (a *App) DeleteChannel(id int) string {
@@ -1245,6 +2518,74 @@ func (a *App) RequestMessages(channelID int) string {
 	return ""
 }
 
+// RequestMessagesBefore asks the server for up to limit messages in a
+// channel older than msg ID before (0 for the newest), to lazy-load
+// scrollback. The page arrives as a chat:history event.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) RequestMessagesBefore(channelID, before, limit int) string {
+	if before < 0 {
+		return "invalid cursor"
+	}
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.RequestMessagesBefore(int64(channelID), uint64(before), limit); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// RequestThread asks the server for the reply chain ending at msgID; it
+// arrives as a chat:thread event.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) RequestThread(msgID int) string {
+	if msgID <= 0 {
+		return "invalid message"
+	}
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.RequestThread(uint64(msgID)); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// RequestEditHistory asks the server for the earlier versions of an edited
+// message; they arrive as a chat:edit_history event. Only the sender and
+// the server owner may fetch them.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) RequestEditHistory(msgID int) string {
+	if msgID <= 0 {
+		return "invalid message"
+	}
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.RequestEditHistory(uint64(msgID)); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// RequestAuditLog asks the server for its recent moderation audit log; it
+// arrives as an audit:log event, and later actions stream in as audit:entry.
+// The server only answers admins and the owner.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) RequestAuditLog() string {
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.RequestAuditLog(); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
 // RequestServerInfo asks the server to send its name and metadata.
 // Returns an error message string or "" on success (Wails JS binding convention).
 func (a *App) RequestServerInfo() string {
@@ -1258,6 +2599,20 @@ func (a *App) RequestServerInfo() string {
 	return ""
 }
 
+// GetPermissions asks the server for the current owner and role
+// assignments, delivered as a permissions:update event.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) GetPermissions() string {
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.GetPermissions(); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
 // StartVideo notifies all peers that this user has started video.
 // Returns an error message string or "" on success (Wails JS binding convention).
 func (a *App) StartVideo() string {
@@ -1329,6 +2684,65 @@ func (a *App) MoveUserToChannel(userID int, channelID int) string {
 	return ""
 }
 
+// PlaySoundboard asks the server to play a soundboard clip for everyone in
+// our voice channel, ourselves included.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) PlaySoundboard(clipID string) string {
+	slog.Debug("PlaySoundboard", "clip_id", clipID)
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.SendSoundboard(clipID); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// SetAnnouncement sets the server announcement shown to everyone; empty
+// text clears it. Only admins and the owner may.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SetAnnouncement(text string) string {
+	slog.Debug("SetAnnouncement", "len", len(text))
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.SetAnnouncement(text); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// StartWhisper sends our voice only to the given user, who must be in our
+// voice channel, until StopWhisper is called.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) StartWhisper(id int) string {
+	slog.Debug("StartWhisper", "user_id", id)
+	if id <= 0 || id > math.MaxUint16 {
+		return "invalid user"
+	}
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.SetWhisperTarget(uint16(id)); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// StopWhisper goes back to transmitting to the whole channel.
+func (a *App) StopWhisper() {
+	slog.Debug("StopWhisper")
+	a.mu.RLock()
+	tr := a.transport
+	a.mu.RUnlock()
+	if tr != nil {
+		tr.ClearWhisper()
+	}
+}
+
 // sendFailureThreshold is the number of consecutive SendAudio errors before
 // the send loop gives up and disconnects. 50 errors ≈ 1 s of voice at 50 fps.
 // Mirrors the server-side circuit breaker threshold for symmetry.
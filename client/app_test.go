@@ -3,8 +3,13 @@ package main
 import (
 	"context"
 	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
 	"sync"
 	"testing"
+	"time"
 )
 
 // ---------------------------------------------------------------------------
@@ -18,15 +23,51 @@ type mockTransport struct {
 	connectCalled bool
 	connectAddr   string
 	connectUser   string
+	reconnectMax  int
+	reconnectBase time.Duration
 	connectErr    error
 	disconnected  int // count
 
 	// Muting
 	mutedUsers map[uint16]bool
 
+	// Blocking
+	blockedUsers map[uint16]bool
+
 	// Per-user volume
 	userVolumes map[uint16]float64
 
+	// Local recordings announced with start_recording
+	recordingStarts int
+	recordingStops  int
+
+	// Scrollback pages requested with get_messages_before
+	messagePages []struct {
+		channelID int64
+		before    uint64
+		limit     int
+	}
+
+	// Channel exports: the transcript served, or the error returned
+	exportBody string
+	exportErr  error
+
+	// Playback drop policy
+	dropPolicy string
+
+	// Pinned list requests, and messages pinned or unpinned
+	pinnedRequests []int64
+	pinned         []uint64
+	unpinned       []uint64
+
+	// Ban list requests, and bans lifted with unban
+	banListRequests int
+	unbanned        []int64
+
+	// Monitored channels, and the users heard only through them
+	monitoredChannels []int64
+	monitoredOnly     map[uint16]bool
+
 	// Control messages sent
 	chatsSent    []string
 	channelChats []struct {
@@ -47,6 +88,8 @@ type mockTransport struct {
 		emoji string
 	}
 	kickedUsers     []uint16
+	kickReasons     []string
+	statuses        []string
 	renamedUsers    []string
 	renamedServers  []string
 	channelsJoined  []int64
@@ -56,7 +99,32 @@ type mockTransport struct {
 		name string
 	}
 	channelsDeleted []int64
-	usersMovedTo    []struct {
+	slowModes       []struct {
+		id      int64
+		seconds int
+	}
+	channelTTLs []struct {
+		id      int64
+		seconds int
+	}
+	recordRoles []struct {
+		id   int64
+		role string
+	}
+	channelLocks []struct {
+		id     int64
+		locked bool
+	}
+	purges []struct {
+		channelID int64
+		count     int
+	}
+	serverUnmutes []uint16
+	serverMutes   []struct {
+		id        uint16
+		durationS int
+	}
+	usersMovedTo []struct {
 		userID    uint16
 		channelID int64
 	}
@@ -66,11 +134,27 @@ type mockTransport struct {
 		quality  string
 	}
 	fileChatsSent []struct {
-		channelID int64
-		fileID    string
-		fileSize  int64
+		channelID         int64
+		fileID            string
+		fileSize          int64
 		fileName, message string
 	}
+	dmsSent []struct {
+		targetID uint16
+		msg      string
+	}
+	recordingConsents []bool
+	bannedUsers       []struct {
+		id        uint16
+		reason    string
+		durationS int
+	}
+	voiceFlags          [][2]bool
+	readReceipts        []uint64
+	ownerTransfers      []uint16
+	roleAssignments     map[uint16]string
+	categoriesCreated   []string
+	categoryAssignments [][2]int64 // channel ID, category ID
 
 	// Configurable error returns
 	sendChatErr         error
@@ -80,6 +164,8 @@ type mockTransport struct {
 	addReactionErr      error
 	removeReactionErr   error
 	kickUserErr         error
+	banUserErr          error
+	transferOwnerErr    error
 	renameUserErr       error
 	renameServerErr     error
 	joinChannelErr      error
@@ -90,6 +176,7 @@ type mockTransport struct {
 	sendVideoStateErr   error
 	requestVideoQualErr error
 	sendFileChatErr     error
+	sendDMErr           error
 
 	// Callback storage
 	onUserList           func([]UserInfo)
@@ -101,9 +188,19 @@ type mockTransport struct {
 	onChannelChatMessage func(uint64, uint16, int64, string, string, int64, string, string, int64, []uint16)
 	onLinkPreview        func(uint64, int64, string, string, string, string, string)
 	onServerInfo         func(string)
-	onKicked             func()
+	onServerError        func(string, int64)
+	onKicked             func(string)
+	onUserMuted          func(uint16, bool)
+	onUserStatus         func(uint16, string)
+	onSpeaking           func(uint16, bool)
+	onMOTD               func(string)
+	onJoinSounds         func(string, string)
+	onEmojiList          func([]string)
+	onServerShutdown     func(int64)
+	onStopRecording      func()
 	onOwnerChanged       func(uint16)
 	onChannelList        func([]ChannelInfo)
+	onCategoryList       func([]CategoryInfo)
 	onUserChannel        func(uint16, int64)
 	onUserRenamed        func(uint16, string)
 	onMessageEdited      func(uint64, string, int64)
@@ -111,21 +208,31 @@ type mockTransport struct {
 	onVideoState         func(uint16, bool, bool)
 	onReactionAdded      func(uint64, string, uint16)
 	onReactionRemoved    func(uint64, string, uint16)
+	onReactionsUpdated   func(uint64, []ChatHistoryReaction)
+	onPermissions        func(Permissions)
+	onDM                 func(uint16, uint16, string, string, int64)
+	onReconnecting       func(int)
 	onUserTyping         func(uint16, string, int64)
 	onMessagePinned      func(uint64, int64, uint16)
 	onMessageUnpinned    func(uint64)
 	onVideoLayers        func(uint16, []VideoLayer)
+	onRecordingStarted   func(uint16, bool)
+	onRecordingStopped   func(uint16)
 
 	// Return values
 	myIDValue     uint16
 	metricsValue  Metrics
+	peerMetrics   map[uint16]PeerMetrics
 	apiBaseURLVal string
+	maxUploadVal  int64
+	allowedEmoji  []string
 }
 
 func newMockTransport() *mockTransport {
 	return &mockTransport{
-		mutedUsers:  make(map[uint16]bool),
-		userVolumes: make(map[uint16]float64),
+		mutedUsers:   make(map[uint16]bool),
+		blockedUsers: make(map[uint16]bool),
+		userVolumes:  make(map[uint16]float64),
 	}
 }
 
@@ -144,8 +251,15 @@ func (m *mockTransport) Disconnect() {
 	m.disconnected++
 }
 
-func (m *mockTransport) SendAudio(_ []byte) error                               { return nil }
-func (m *mockTransport) StartReceiving(_ context.Context, _ chan<- TaggedAudio) {}
+func (m *mockTransport) SetReconnect(maxAttempts int, baseDelay time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reconnectMax = maxAttempts
+	m.reconnectBase = baseDelay
+}
+
+func (m *mockTransport) SendAudio(_ []byte) error                             { return nil }
+func (m *mockTransport) StartReceiving(_ context.Context, _ chan TaggedAudio) {}
 func (m *mockTransport) MyID() uint16 {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -156,9 +270,29 @@ func (m *mockTransport) GetMetrics() Metrics {
 	defer m.mu.Unlock()
 	return m.metricsValue
 }
+func (m *mockTransport) GetPeerMetrics() map[uint16]PeerMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.peerMetrics
+}
 
 func (m *mockTransport) MuteUser(id uint16)   { m.mu.Lock(); m.mutedUsers[id] = true; m.mu.Unlock() }
 func (m *mockTransport) UnmuteUser(id uint16) { m.mu.Lock(); delete(m.mutedUsers, id); m.mu.Unlock() }
+func (m *mockTransport) BlockUser(id uint16) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.blockedUsers[id] = true
+}
+func (m *mockTransport) UnblockUser(id uint16) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.blockedUsers, id)
+}
+func (m *mockTransport) IsBlocked(id uint16) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.blockedUsers[id]
+}
 func (m *mockTransport) IsUserMuted(id uint16) bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -188,6 +322,11 @@ func (m *mockTransport) GetUserVolume(id uint16) float64 {
 	}
 	return v
 }
+func (m *mockTransport) MonitoredOnly(id uint16) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.monitoredOnly[id]
+}
 
 // Callback setters — store the callback for later inspection.
 func (m *mockTransport) SetOnUserList(fn func([]UserInfo))        { m.onUserList = fn }
@@ -205,7 +344,8 @@ func (m *mockTransport) SetOnLinkPreview(fn func(uint64, int64, string, string,
 	m.onLinkPreview = fn
 }
 func (m *mockTransport) SetOnServerInfo(fn func(string))          { m.onServerInfo = fn }
-func (m *mockTransport) SetOnKicked(fn func())                    { m.onKicked = fn }
+func (m *mockTransport) SetOnServerError(fn func(string, int64))  { m.onServerError = fn }
+func (m *mockTransport) SetOnKicked(fn func(string))              { m.onKicked = fn }
 func (m *mockTransport) SetOnOwnerChanged(fn func(uint16))        { m.onOwnerChanged = fn }
 func (m *mockTransport) SetOnChannelList(fn func([]ChannelInfo))  { m.onChannelList = fn }
 func (m *mockTransport) SetOnUserChannel(fn func(uint16, int64))  { m.onUserChannel = fn }
@@ -219,13 +359,65 @@ func (m *mockTransport) SetOnReactionAdded(fn func(uint64, string, uint16)) { m.
 func (m *mockTransport) SetOnReactionRemoved(fn func(uint64, string, uint16)) {
 	m.onReactionRemoved = fn
 }
-func (m *mockTransport) SetOnUserTyping(fn func(uint16, string, int64))    { m.onUserTyping = fn }
-func (m *mockTransport) SetOnMessagePinned(fn func(uint64, int64, uint16)) { m.onMessagePinned = fn }
-func (m *mockTransport) SetOnMessageUnpinned(fn func(uint64))              { m.onMessageUnpinned = fn }
-func (m *mockTransport) SetOnVideoLayers(fn func(uint16, []VideoLayer))    { m.onVideoLayers = fn }
+func (m *mockTransport) SetOnReactionsUpdated(fn func(uint64, []ChatHistoryReaction)) {
+	m.onReactionsUpdated = fn
+}
+func (m *mockTransport) SetOnPermissions(fn func(Permissions)) { m.onPermissions = fn }
+func (m *mockTransport) SetOnDM(fn func(uint16, uint16, string, string, int64)) {
+	m.onDM = fn
+}
+func (m *mockTransport) SetOnReconnecting(fn func(int)) {
+	m.onReconnecting = fn
+}
+func (m *mockTransport) SetOnUserTyping(fn func(uint16, string, int64))           { m.onUserTyping = fn }
+func (m *mockTransport) SetOnMessagePinned(fn func(uint64, int64, uint16))        { m.onMessagePinned = fn }
+func (m *mockTransport) SetOnMessageUnpinned(fn func(uint64))                     { m.onMessageUnpinned = fn }
+func (m *mockTransport) SetOnVideoLayers(fn func(uint16, []VideoLayer))           { m.onVideoLayers = fn }
 func (m *mockTransport) SetOnMessageHistory(fn func(int64, []ChatHistoryMessage)) {}
+func (m *mockTransport) SetOnThread(fn func(uint64, []ChatHistoryMessage))        {}
+func (m *mockTransport) SetOnEditHistory(fn func(uint64, []MessageEdit))          {}
+func (m *mockTransport) SetOnAuditLog(fn func([]AuditEntry))                      {}
+func (m *mockTransport) SetOnAuditEntry(fn func(AuditEntry))                      {}
 func (m *mockTransport) SetOnUserVoiceFlags(fn func(uint16, bool, bool))          {}
-func (m *mockTransport) SendVoiceFlags(muted, deafened bool) error                { return nil }
+func (m *mockTransport) SetOnSoundboard(fn func(uint16, string))                  {}
+func (m *mockTransport) SetOnRecordingStarted(fn func(uint16, bool))              { m.onRecordingStarted = fn }
+func (m *mockTransport) SetOnRecordingStopped(fn func(uint16))                    { m.onRecordingStopped = fn }
+func (m *mockTransport) SendRecordingConsent(consent bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recordingConsents = append(m.recordingConsents, consent)
+	return nil
+}
+func (m *mockTransport) SetOnPeerAudioStalled(fn func(uint16))               {}
+func (m *mockTransport) SetOnPeerAudioResumed(fn func(uint16))               {}
+func (m *mockTransport) SetOnAnnouncement(fn func(string, string))           {}
+func (m *mockTransport) SetOnMention(fn func(uint64, int64, uint16, string)) {}
+func (m *mockTransport) SetOnMessageRead(fn func(uint64, []uint16))          {}
+func (m *mockTransport) SetOnUserMuted(fn func(uint16, bool))                { m.onUserMuted = fn }
+func (m *mockTransport) SetOnUserStatus(fn func(uint16, string))             { m.onUserStatus = fn }
+func (m *mockTransport) SetOnSpeaking(fn func(uint16, bool))                 { m.onSpeaking = fn }
+func (m *mockTransport) SetOnMOTD(fn func(string))                           { m.onMOTD = fn }
+func (m *mockTransport) SetOnJoinSounds(fn func(string, string))             { m.onJoinSounds = fn }
+func (m *mockTransport) SetOnEmojiList(fn func([]string))                    { m.onEmojiList = fn }
+func (m *mockTransport) SetOnCategoryList(fn func([]CategoryInfo))           { m.onCategoryList = fn }
+func (m *mockTransport) SetOnServerShutdown(fn func(int64))                  { m.onServerShutdown = fn }
+func (m *mockTransport) SetOnStopRecording(fn func())                        { m.onStopRecording = fn }
+func (m *mockTransport) SendVoiceActivity() error                            { return nil }
+func (m *mockTransport) SendSpeaking() error                                 { return nil }
+func (m *mockTransport) SetStereo(enabled bool)                              {}
+func (m *mockTransport) SetOnsetRedundancy(enabled bool)                     {}
+func (m *mockTransport) SetWhisperTarget(id uint16) error                    { return nil }
+func (m *mockTransport) ClearWhisper()                                       {}
+func (m *mockTransport) WhisperTarget() uint16                               { return 0 }
+func (m *mockTransport) SendSoundboard(clipID string) error                  { return nil }
+func (m *mockTransport) SendTyping(channelID int64) error                    { return nil }
+func (m *mockTransport) SetAnnouncement(text string) error                   { return nil }
+func (m *mockTransport) SetStatus(status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.statuses = append(m.statuses, status)
+	return nil
+}
 
 // Chat operations
 func (m *mockTransport) SendChat(message string) error {
@@ -237,6 +429,18 @@ func (m *mockTransport) SendChat(message string) error {
 	m.chatsSent = append(m.chatsSent, message)
 	return nil
 }
+func (m *mockTransport) SendDM(targetID uint16, message string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sendDMErr != nil {
+		return m.sendDMErr
+	}
+	m.dmsSent = append(m.dmsSent, struct {
+		targetID uint16
+		msg      string
+	}{targetID, message})
+	return nil
+}
 func (m *mockTransport) SendChannelChat(channelID int64, message string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -256,9 +460,9 @@ func (m *mockTransport) SendFileChat(channelID int64, fileID string, fileSize in
 		return m.sendFileChatErr
 	}
 	m.fileChatsSent = append(m.fileChatsSent, struct {
-		channelID int64
-		fileID    string
-		fileSize  int64
+		channelID         int64
+		fileID            string
+		fileSize          int64
 		fileName, message string
 	}{channelID, fileID, fileSize, fileName, message})
 	return nil
@@ -284,6 +488,15 @@ func (m *mockTransport) DeleteMessage(msgID uint64) error {
 	m.deletedMessages = append(m.deletedMessages, msgID)
 	return nil
 }
+func (m *mockTransport) PurgeMessages(channelID int64, count int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.purges = append(m.purges, struct {
+		channelID int64
+		count     int
+	}{channelID, count})
+	return nil
+}
 func (m *mockTransport) AddReaction(msgID uint64, emoji string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -308,6 +521,12 @@ func (m *mockTransport) RemoveReaction(msgID uint64, emoji string) error {
 	}{msgID, emoji})
 	return nil
 }
+func (m *mockTransport) SendReadReceipt(msgID uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.readReceipts = append(m.readReceipts, msgID)
+	return nil
+}
 func (m *mockTransport) KickUser(id uint16) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -317,6 +536,68 @@ func (m *mockTransport) KickUser(id uint16) error {
 	m.kickedUsers = append(m.kickedUsers, id)
 	return nil
 }
+func (m *mockTransport) KickUserWithReason(id uint16, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.kickUserErr != nil {
+		return m.kickUserErr
+	}
+	m.kickedUsers = append(m.kickedUsers, id)
+	m.kickReasons = append(m.kickReasons, reason)
+	return nil
+}
+func (m *mockTransport) MuteUserServer(id uint16, durationS int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.serverMutes = append(m.serverMutes, struct {
+		id        uint16
+		durationS int
+	}{id, durationS})
+	return nil
+}
+func (m *mockTransport) UnmuteUserServer(id uint16) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.serverUnmutes = append(m.serverUnmutes, id)
+	return nil
+}
+func (m *mockTransport) BanUser(id uint16, reason string, durationS int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.banUserErr != nil {
+		return m.banUserErr
+	}
+	m.bannedUsers = append(m.bannedUsers, struct {
+		id        uint16
+		reason    string
+		durationS int
+	}{id, reason, durationS})
+	return nil
+}
+func (m *mockTransport) TransferOwner(id uint16) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.transferOwnerErr != nil {
+		return m.transferOwnerErr
+	}
+	m.ownerTransfers = append(m.ownerTransfers, id)
+	return nil
+}
+func (m *mockTransport) SetRole(id uint16, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.roleAssignments == nil {
+		m.roleAssignments = make(map[uint16]string)
+	}
+	m.roleAssignments[id] = role
+	return nil
+}
+func (m *mockTransport) SendVoiceFlags(muted, deafened bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.voiceFlags = append(m.voiceFlags, [2]bool{muted, deafened})
+	return nil
+}
 func (m *mockTransport) RenameUser(name string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -353,6 +634,138 @@ func (m *mockTransport) CreateChannel(name string) error {
 	m.channelsCreated = append(m.channelsCreated, name)
 	return nil
 }
+func (m *mockTransport) SetChannelBitrate(id int64, kbps int) error { return nil }
+func (m *mockTransport) CreateCategory(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.categoriesCreated = append(m.categoriesCreated, name)
+	return nil
+}
+
+func (m *mockTransport) AssignChannelCategory(channelID, categoryID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.categoryAssignments = append(m.categoryAssignments, [2]int64{channelID, categoryID})
+	return nil
+}
+
+func (m *mockTransport) SetSlowMode(id int64, seconds int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.slowModes = append(m.slowModes, struct {
+		id      int64
+		seconds int
+	}{id, seconds})
+	return nil
+}
+func (m *mockTransport) SetChannelLock(id int64, locked bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.channelLocks = append(m.channelLocks, struct {
+		id     int64
+		locked bool
+	}{id, locked})
+	return nil
+}
+func (m *mockTransport) SetChannelTTL(id int64, seconds int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.channelTTLs = append(m.channelTTLs, struct {
+		id      int64
+		seconds int
+	}{id, seconds})
+	return nil
+}
+func (m *mockTransport) SetChannelRecordRole(id int64, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recordRoles = append(m.recordRoles, struct {
+		id   int64
+		role string
+	}{id, role})
+	return nil
+}
+func (m *mockTransport) StartRecording() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recordingStarts++
+	return nil
+}
+func (m *mockTransport) StopRecording() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recordingStops++
+	return nil
+}
+func (m *mockTransport) RequestMessagesBefore(channelID int64, before uint64, limit int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messagePages = append(m.messagePages, struct {
+		channelID int64
+		before    uint64
+		limit     int
+	}{channelID, before, limit})
+	return nil
+}
+func (m *mockTransport) SetOnBanList(fn func([]BanInfo))                 {}
+func (m *mockTransport) SetOnPinnedList(fn func(int64, []PinnedMessage)) {}
+func (m *mockTransport) RequestPinned(channelID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pinnedRequests = append(m.pinnedRequests, channelID)
+	return nil
+}
+func (m *mockTransport) PinMessage(msgID uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pinned = append(m.pinned, msgID)
+	return nil
+}
+func (m *mockTransport) UnpinMessage(msgID uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unpinned = append(m.unpinned, msgID)
+	return nil
+}
+func (m *mockTransport) ExportChannel(_ context.Context, channelID int64, format string, w io.Writer) error {
+	m.mu.Lock()
+	body, err := m.exportBody, m.exportErr
+	m.mu.Unlock()
+	if _, werr := io.WriteString(w, body); werr != nil {
+		return werr
+	}
+	return err
+}
+func (m *mockTransport) SetDropPolicy(policy string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dropPolicy = policy
+	return nil
+}
+func (m *mockTransport) RequestBans() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.banListRequests++
+	return nil
+}
+func (m *mockTransport) Unban(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unbanned = append(m.unbanned, id)
+	return nil
+}
+func (m *mockTransport) AddMonitorChannel(channelID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.monitoredChannels = append(m.monitoredChannels, channelID)
+	return nil
+}
+func (m *mockTransport) RemoveMonitorChannel(channelID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.monitoredChannels = slices.DeleteFunc(m.monitoredChannels, func(id int64) bool { return id == channelID })
+	return nil
+}
 func (m *mockTransport) RenameChannel(id int64, name string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -412,9 +825,26 @@ func (m *mockTransport) APIBaseURL() string {
 	defer m.mu.Unlock()
 	return m.apiBaseURLVal
 }
-func (m *mockTransport) RequestChannels() error    { return nil }
-func (m *mockTransport) RequestMessages(_ int64) error { return nil }
-func (m *mockTransport) RequestServerInfo() error  { return nil }
+func (m *mockTransport) MaxUploadBytes() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.maxUploadVal > 0 {
+		return m.maxUploadVal
+	}
+	return defaultMaxUploadBytes
+}
+func (m *mockTransport) AllowedEmoji() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.allowedEmoji
+}
+func (m *mockTransport) RequestChannels() error            { return nil }
+func (m *mockTransport) RequestMessages(_ int64) error     { return nil }
+func (m *mockTransport) RequestThread(_ uint64) error      { return nil }
+func (m *mockTransport) RequestEditHistory(_ uint64) error { return nil }
+func (m *mockTransport) RequestAuditLog() error            { return nil }
+func (m *mockTransport) RequestServerInfo() error          { return nil }
+func (m *mockTransport) GetPermissions() error             { return nil }
 
 // Verify interface compliance at compile time.
 var _ Transporter = (*mockTransport)(nil)
@@ -471,6 +901,18 @@ func TestConnectAlreadyConnected(t *testing.T) {
 	}
 }
 
+func TestConnectEnablesReconnect(t *testing.T) {
+	app, mt := newTestApp()
+	if result := app.Connect("localhost:8080", "alice"); result != "" {
+		t.Fatalf("connect: %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if mt.reconnectMax != reconnectAttempts || mt.reconnectBase != reconnectBaseDelay {
+		t.Errorf("reconnect = (%d, %v), want (%d, %v)", mt.reconnectMax, mt.reconnectBase, reconnectAttempts, reconnectBaseDelay)
+	}
+}
+
 func TestConnectTransportError(t *testing.T) {
 	app, mt := newTestApp()
 	mt.connectErr = errors.New("dial failed")
@@ -598,6 +1040,49 @@ func TestSendChannelChatError(t *testing.T) {
 	}
 }
 
+// ===========================================================================
+// SendDM
+// ===========================================================================
+
+func TestSendDMSuccess(t *testing.T) {
+	app, mt := newTestApp()
+	if result := app.SendDM(7, "psst"); result != "" {
+		t.Fatalf("expected success, got %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if len(mt.dmsSent) != 1 || mt.dmsSent[0].targetID != 7 || mt.dmsSent[0].msg != "psst" {
+		t.Errorf("unexpected dms: %+v", mt.dmsSent)
+	}
+}
+
+func TestSendDMInvalidUser(t *testing.T) {
+	app, _ := newTestApp()
+	if result := app.SendDM(0, "psst"); result != "invalid user" {
+		t.Errorf("expected 'invalid user', got %q", result)
+	}
+}
+
+func TestStartWhisperInvalidUser(t *testing.T) {
+	app, _ := newTestApp()
+	for _, id := range []int{0, -1, 65536, 65538} {
+		if result := app.StartWhisper(id); result != "invalid user" {
+			t.Errorf("StartWhisper(%d) = %q, want 'invalid user'", id, result)
+		}
+	}
+	if result := app.StartWhisper(65535); result != "" {
+		t.Errorf("StartWhisper(65535) = %q, want success", result)
+	}
+}
+
+func TestSendDMError(t *testing.T) {
+	app, mt := newTestApp()
+	mt.sendDMErr = errors.New("unknown user 7")
+	if result := app.SendDM(7, "psst"); result != "unknown user 7" {
+		t.Errorf("expected transport error, got %q", result)
+	}
+}
+
 // ===========================================================================
 // EditMessage
 // ===========================================================================
@@ -628,102 +1113,310 @@ func TestEditMessageError(t *testing.T) {
 }
 
 // ===========================================================================
-// DeleteMessage
+// DeleteMessage
+// ===========================================================================
+
+func TestDeleteMessageSuccess(t *testing.T) {
+	app, mt := newTestApp()
+	result := app.DeleteMessage(200)
+	if result != "" {
+		t.Errorf("expected empty result, got %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if len(mt.deletedMessages) != 1 || mt.deletedMessages[0] != 200 {
+		t.Errorf("expected delete of msg 200, got %v", mt.deletedMessages)
+	}
+}
+
+func TestDeleteMessageError(t *testing.T) {
+	app, mt := newTestApp()
+	mt.deleteMessageErr = errors.New("forbidden")
+	result := app.DeleteMessage(1)
+	if result != "forbidden" {
+		t.Errorf("expected 'forbidden', got %q", result)
+	}
+}
+
+// ===========================================================================
+// PurgeMessages
+// ===========================================================================
+
+func TestPurgeMessages(t *testing.T) {
+	app, mt := newTestApp()
+	if result := app.PurgeMessages(3, 0); result == "" {
+		t.Error("expected an error for a zero count")
+	}
+	if result := app.PurgeMessages(3, 25); result != "" {
+		t.Fatalf("expected empty result, got %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if len(mt.purges) != 1 || mt.purges[0].channelID != 3 || mt.purges[0].count != 25 {
+		t.Errorf("unexpected purge requests: %v", mt.purges)
+	}
+}
+
+// ===========================================================================
+// AddReaction / RemoveReaction
+// ===========================================================================
+
+func TestAddReactionSuccess(t *testing.T) {
+	app, mt := newTestApp()
+	result := app.AddReaction(10, "thumbsup")
+	if result != "" {
+		t.Errorf("expected empty result, got %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if len(mt.reactions) != 1 || mt.reactions[0].msgID != 10 || mt.reactions[0].emoji != "thumbsup" {
+		t.Errorf("unexpected reaction: %v", mt.reactions)
+	}
+}
+
+func TestAddReactionError(t *testing.T) {
+	app, mt := newTestApp()
+	mt.addReactionErr = errors.New("rate limited")
+	result := app.AddReaction(10, "fire")
+	if result != "rate limited" {
+		t.Errorf("expected 'rate limited', got %q", result)
+	}
+}
+
+func TestRemoveReactionSuccess(t *testing.T) {
+	app, mt := newTestApp()
+	result := app.RemoveReaction(10, "thumbsup")
+	if result != "" {
+		t.Errorf("expected empty result, got %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if len(mt.removedReactions) != 1 || mt.removedReactions[0].emoji != "thumbsup" {
+		t.Errorf("unexpected removed reaction: %v", mt.removedReactions)
+	}
+}
+
+func TestRemoveReactionError(t *testing.T) {
+	app, mt := newTestApp()
+	mt.removeReactionErr = errors.New("not found")
+	result := app.RemoveReaction(10, "fire")
+	if result != "not found" {
+		t.Errorf("expected 'not found', got %q", result)
+	}
+}
+
+func TestGetAllowedEmoji(t *testing.T) {
+	app, mt := newTestApp()
+	if got := app.GetAllowedEmoji(); got == nil || len(got) != 0 {
+		t.Errorf("no allow-list: got %#v, want an empty slice", got)
+	}
+	mt.allowedEmoji = []string{"👍", "🎉"}
+	if got := app.GetAllowedEmoji(); len(got) != 2 || got[0] != "👍" {
+		t.Errorf("GetAllowedEmoji = %q", got)
+	}
+	app.transport = nil
+	if got := app.GetAllowedEmoji(); got == nil || len(got) != 0 {
+		t.Errorf("not connected: got %#v, want an empty slice", got)
+	}
+}
+
+func TestSendReadReceipt(t *testing.T) {
+	app, mt := newTestApp()
+	app.SendReadReceipt(0) // no message ID yet; nothing to report
+	app.SendReadReceipt(42)
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if !slices.Equal(mt.readReceipts, []uint64{42}) {
+		t.Errorf("read receipts = %v, want [42]", mt.readReceipts)
+	}
+}
+
+// ===========================================================================
+// KickUser
+// ===========================================================================
+
+func TestKickUserSuccess(t *testing.T) {
+	app, mt := newTestApp()
+	result := app.KickUser(7, "")
+	if result != "" {
+		t.Errorf("expected empty result, got %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if len(mt.kickedUsers) != 1 || mt.kickedUsers[0] != 7 {
+		t.Errorf("expected kick of user 7, got %v", mt.kickedUsers)
+	}
+	if len(mt.kickReasons) != 0 {
+		t.Errorf("expected a kick without a reason, got %q", mt.kickReasons)
+	}
+}
+
+func TestKickUserWithReason(t *testing.T) {
+	app, mt := newTestApp()
+	if result := app.KickUser(7, "  cool off  "); result != "" {
+		t.Fatalf("expected empty result, got %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if len(mt.kickReasons) != 1 || mt.kickReasons[0] != "cool off" {
+		t.Errorf("expected trimmed reason, got %q", mt.kickReasons)
+	}
+}
+
+func TestKickUserError(t *testing.T) {
+	app, mt := newTestApp()
+	mt.kickUserErr = errors.New("not owner")
+	result := app.KickUser(7, "")
+	if result != "not owner" {
+		t.Errorf("expected 'not owner', got %q", result)
+	}
+}
+
+// ===========================================================================
+// RequestBans / Unban
+// ===========================================================================
+
+func TestRequestBansAndUnban(t *testing.T) {
+	app, mt := newTestApp()
+	if result := app.RequestBans(); result != "" {
+		t.Fatalf("request bans: expected empty result, got %q", result)
+	}
+	if result := app.Unban(0); result == "" {
+		t.Error("expected an error for a zero ban id")
+	}
+	if result := app.Unban(12); result != "" {
+		t.Fatalf("unban: expected empty result, got %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if mt.banListRequests != 1 || !slices.Equal(mt.unbanned, []int64{12}) {
+		t.Errorf("requests = %d, unbanned = %v", mt.banListRequests, mt.unbanned)
+	}
+}
+
+func TestRequestBansNotConnected(t *testing.T) {
+	app := NewApp()
+	if result := app.RequestBans(); result == "" {
+		t.Error("expected an error when not connected")
+	}
+}
+
+// ===========================================================================
+// SetStatus
+// ===========================================================================
+
+func TestSetStatus(t *testing.T) {
+	app, mt := newTestApp()
+	if result := app.SetStatus(" DND "); result != "" {
+		t.Fatalf("expected empty result, got %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if len(mt.statuses) != 1 || mt.statuses[0] != "dnd" {
+		t.Errorf("unexpected statuses: %q", mt.statuses)
+	}
+}
+
+// ===========================================================================
+// MuteUserServer
+// ===========================================================================
+
+func TestMuteUserServer(t *testing.T) {
+	app, mt := newTestApp()
+	if result := app.MuteUserServer(7, -1); result == "" {
+		t.Error("expected an error for a negative duration")
+	}
+	if result := app.MuteUserServer(7, 300); result != "" {
+		t.Fatalf("expected empty result, got %q", result)
+	}
+	if result := app.UnmuteUserServer(7); result != "" {
+		t.Fatalf("expected empty result, got %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if len(mt.serverMutes) != 1 || mt.serverMutes[0].id != 7 || mt.serverMutes[0].durationS != 300 {
+		t.Errorf("unexpected mutes: %v", mt.serverMutes)
+	}
+	if len(mt.serverUnmutes) != 1 || mt.serverUnmutes[0] != 7 {
+		t.Errorf("unexpected unmutes: %v", mt.serverUnmutes)
+	}
+}
+
+// ===========================================================================
+// BanUser
 // ===========================================================================
 
-func TestDeleteMessageSuccess(t *testing.T) {
+func TestBanUserSuccess(t *testing.T) {
 	app, mt := newTestApp()
-	result := app.DeleteMessage(200)
+	result := app.BanUser(7, "spam", 3600)
 	if result != "" {
 		t.Errorf("expected empty result, got %q", result)
 	}
 	mt.mu.Lock()
 	defer mt.mu.Unlock()
-	if len(mt.deletedMessages) != 1 || mt.deletedMessages[0] != 200 {
-		t.Errorf("expected delete of msg 200, got %v", mt.deletedMessages)
-	}
-}
-
-func TestDeleteMessageError(t *testing.T) {
-	app, mt := newTestApp()
-	mt.deleteMessageErr = errors.New("forbidden")
-	result := app.DeleteMessage(1)
-	if result != "forbidden" {
-		t.Errorf("expected 'forbidden', got %q", result)
+	if len(mt.bannedUsers) != 1 || mt.bannedUsers[0].id != 7 || mt.bannedUsers[0].reason != "spam" || mt.bannedUsers[0].durationS != 3600 {
+		t.Errorf("expected ban of user 7, got %+v", mt.bannedUsers)
 	}
 }
 
-// ===========================================================================
-// AddReaction / RemoveReaction
-// ===========================================================================
-
-func TestAddReactionSuccess(t *testing.T) {
+func TestBanUserRejectsNegativeDuration(t *testing.T) {
 	app, mt := newTestApp()
-	result := app.AddReaction(10, "thumbsup")
-	if result != "" {
-		t.Errorf("expected empty result, got %q", result)
+	if result := app.BanUser(7, "", -1); result == "" {
+		t.Error("expected an error for a negative duration")
 	}
 	mt.mu.Lock()
 	defer mt.mu.Unlock()
-	if len(mt.reactions) != 1 || mt.reactions[0].msgID != 10 || mt.reactions[0].emoji != "thumbsup" {
-		t.Errorf("unexpected reaction: %v", mt.reactions)
+	if len(mt.bannedUsers) != 0 {
+		t.Errorf("expected no ban to be sent, got %+v", mt.bannedUsers)
 	}
 }
 
-func TestAddReactionError(t *testing.T) {
+func TestBanUserError(t *testing.T) {
 	app, mt := newTestApp()
-	mt.addReactionErr = errors.New("rate limited")
-	result := app.AddReaction(10, "fire")
-	if result != "rate limited" {
-		t.Errorf("expected 'rate limited', got %q", result)
+	mt.banUserErr = errors.New("unknown user 7")
+	result := app.BanUser(7, "", 0)
+	if result != "unknown user 7" {
+		t.Errorf("expected 'unknown user 7', got %q", result)
 	}
 }
 
-func TestRemoveReactionSuccess(t *testing.T) {
+// ===========================================================================
+// TransferOwner
+// ===========================================================================
+
+func TestTransferOwnerSuccess(t *testing.T) {
 	app, mt := newTestApp()
-	result := app.RemoveReaction(10, "thumbsup")
-	if result != "" {
+	if result := app.TransferOwner(7); result != "" {
 		t.Errorf("expected empty result, got %q", result)
 	}
 	mt.mu.Lock()
 	defer mt.mu.Unlock()
-	if len(mt.removedReactions) != 1 || mt.removedReactions[0].emoji != "thumbsup" {
-		t.Errorf("unexpected removed reaction: %v", mt.removedReactions)
+	if len(mt.ownerTransfers) != 1 || mt.ownerTransfers[0] != 7 {
+		t.Errorf("expected transfer to user 7, got %v", mt.ownerTransfers)
 	}
 }
 
-func TestRemoveReactionError(t *testing.T) {
+func TestTransferOwnerError(t *testing.T) {
 	app, mt := newTestApp()
-	mt.removeReactionErr = errors.New("not found")
-	result := app.RemoveReaction(10, "fire")
-	if result != "not found" {
-		t.Errorf("expected 'not found', got %q", result)
+	mt.transferOwnerErr = errors.New("unknown user 7")
+	if result := app.TransferOwner(7); result != "unknown user 7" {
+		t.Errorf("expected 'unknown user 7', got %q", result)
 	}
 }
 
 // ===========================================================================
-// KickUser
+// SetRole
 // ===========================================================================
 
-func TestKickUserSuccess(t *testing.T) {
+func TestSetRole(t *testing.T) {
 	app, mt := newTestApp()
-	result := app.KickUser(7)
-	if result != "" {
+	if result := app.SetRole(7, "MODERATOR"); result != "" {
 		t.Errorf("expected empty result, got %q", result)
 	}
 	mt.mu.Lock()
 	defer mt.mu.Unlock()
-	if len(mt.kickedUsers) != 1 || mt.kickedUsers[0] != 7 {
-		t.Errorf("expected kick of user 7, got %v", mt.kickedUsers)
-	}
-}
-
-func TestKickUserError(t *testing.T) {
-	app, mt := newTestApp()
-	mt.kickUserErr = errors.New("not owner")
-	result := app.KickUser(7)
-	if result != "not owner" {
-		t.Errorf("expected 'not owner', got %q", result)
+	if mt.roleAssignments[7] != "MODERATOR" {
+		t.Errorf("unexpected role assignments: %v", mt.roleAssignments)
 	}
 }
 
@@ -818,6 +1511,31 @@ func TestJoinChannelError(t *testing.T) {
 	}
 }
 
+func TestJoinChannelAppliesBitrateCap(t *testing.T) {
+	app, _ := newTestApp()
+	app.SetAudioBitrate(64)
+	app.channels = []ChannelInfo{{ID: 1, MaxBitrateKbps: 32}, {ID: 2}}
+
+	if result := app.JoinChannel(1); result != "" {
+		t.Fatalf("join: %q", result)
+	}
+	if got := app.audio.CurrentBitrate(); got != 32 {
+		t.Errorf("in capped channel: got %d kbps, want 32", got)
+	}
+	if result := app.JoinChannel(2); result != "" {
+		t.Fatalf("join: %q", result)
+	}
+	if got := app.audio.CurrentBitrate(); got != 64 {
+		t.Errorf("in uncapped channel: got %d kbps, want 64", got)
+	}
+
+	app.JoinChannel(1)
+	app.DisconnectVoice()
+	if got := app.audio.CurrentBitrate(); got != 64 {
+		t.Errorf("after leaving: got %d kbps, want 64", got)
+	}
+}
+
 // ===========================================================================
 // CreateChannel
 // ===========================================================================
@@ -844,6 +1562,31 @@ func TestCreateChannelError(t *testing.T) {
 	}
 }
 
+// ===========================================================================
+// Channel categories
+// ===========================================================================
+
+func TestCreateCategoryAndAssignChannel(t *testing.T) {
+	app, mt := newTestApp()
+	if result := app.CreateCategory("  "); result == "" {
+		t.Error("expected an error for an empty category name")
+	}
+	if result := app.CreateCategory("Games"); result != "" {
+		t.Fatalf("CreateCategory: %q", result)
+	}
+	if result := app.AssignChannelCategory(3, 1); result != "" {
+		t.Fatalf("AssignChannelCategory: %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if !slices.Equal(mt.categoriesCreated, []string{"Games"}) {
+		t.Errorf("categories created = %v", mt.categoriesCreated)
+	}
+	if len(mt.categoryAssignments) != 1 || mt.categoryAssignments[0] != [2]int64{3, 1} {
+		t.Errorf("category assignments = %v", mt.categoryAssignments)
+	}
+}
+
 // ===========================================================================
 // RenameChannel
 // ===========================================================================
@@ -870,6 +1613,159 @@ func TestRenameChannelError(t *testing.T) {
 	}
 }
 
+// ===========================================================================
+// SetSlowMode
+// ===========================================================================
+
+func TestSetSlowMode(t *testing.T) {
+	app, mt := newTestApp()
+	if result := app.SetSlowMode(5, -1); result == "" {
+		t.Error("expected an error for a negative interval")
+	}
+	if result := app.SetSlowMode(5, 30); result != "" {
+		t.Fatalf("expected empty result, got %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if len(mt.slowModes) != 1 || mt.slowModes[0].id != 5 || mt.slowModes[0].seconds != 30 {
+		t.Errorf("unexpected slow mode requests: %v", mt.slowModes)
+	}
+}
+
+// ===========================================================================
+// SetChannelLock
+// ===========================================================================
+
+func TestSetChannelLock(t *testing.T) {
+	app, mt := newTestApp()
+	if result := app.SetChannelLock(5, true); result != "" {
+		t.Fatalf("expected empty result, got %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if len(mt.channelLocks) != 1 || mt.channelLocks[0].id != 5 || !mt.channelLocks[0].locked {
+		t.Errorf("unexpected lock requests: %v", mt.channelLocks)
+	}
+}
+
+// ===========================================================================
+// SetChannelRecordRole
+// ===========================================================================
+
+func TestSetChannelRecordRole(t *testing.T) {
+	app, mt := newTestApp()
+	if result := app.SetChannelRecordRole(5, "MODERATOR"); result != "" {
+		t.Fatalf("expected empty result, got %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if len(mt.recordRoles) != 1 || mt.recordRoles[0].id != 5 || mt.recordRoles[0].role != "MODERATOR" {
+		t.Errorf("unexpected record role requests: %v", mt.recordRoles)
+	}
+}
+
+// ===========================================================================
+// RequestMessagesBefore
+// ===========================================================================
+
+func TestRequestMessagesBefore(t *testing.T) {
+	app, mt := newTestApp()
+	if result := app.RequestMessagesBefore(3, 120, 50); result != "" {
+		t.Fatalf("expected empty result, got %q", result)
+	}
+	if result := app.RequestMessagesBefore(3, -1, 50); result == "" {
+		t.Error("expected an error for a negative cursor")
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if len(mt.messagePages) != 1 || mt.messagePages[0].channelID != 3 || mt.messagePages[0].before != 120 || mt.messagePages[0].limit != 50 {
+		t.Errorf("unexpected page requests: %v", mt.messagePages)
+	}
+}
+
+// ===========================================================================
+// Monitor channels
+// ===========================================================================
+
+func TestAddAndRemoveMonitorChannel(t *testing.T) {
+	app, mt := newTestApp()
+	if result := app.AddMonitorChannel(7); result != "" {
+		t.Fatalf("add: expected empty result, got %q", result)
+	}
+	if result := app.AddMonitorChannel(8); result != "" {
+		t.Fatalf("add: expected empty result, got %q", result)
+	}
+	if result := app.RemoveMonitorChannel(7); result != "" {
+		t.Fatalf("remove: expected empty result, got %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if !slices.Equal(mt.monitoredChannels, []int64{8}) {
+		t.Errorf("monitored channels = %v, want [8]", mt.monitoredChannels)
+	}
+}
+
+func TestMonitorVolumeAppliesOnlyToMonitoredVoices(t *testing.T) {
+	app, mt := newTestApp()
+	mt.monitoredOnly = map[uint16]bool{3: true}
+	app.SetMonitorVolume(0.25)
+
+	if g := app.monitorGain(mt, 3); g != 0.25 {
+		t.Errorf("monitored voice gain = %v, want 0.25", g)
+	}
+	if g := app.monitorGain(mt, 4); g != 1 {
+		t.Errorf("own channel gain = %v, want 1", g)
+	}
+	app.SetMonitorVolume(3)
+	if g := app.monitorGain(mt, 3); g != 1 {
+		t.Errorf("gain should be clamped to 1, got %v", g)
+	}
+}
+
+// ===========================================================================
+// SetDropPolicy
+// ===========================================================================
+
+func TestSetDropPolicy(t *testing.T) {
+	app, mt := newTestApp()
+	if result := app.SetDropPolicy("oldest"); result != "" {
+		t.Fatalf("expected empty result, got %q", result)
+	}
+	if result := app.SetDropPolicy("random"); result == "" {
+		t.Error("expected an error for an unknown policy")
+	}
+	if p := app.dropPolicy(); p != DropPolicyOldest {
+		t.Errorf("dropPolicy() = %q, want oldest", p)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if mt.dropPolicy != DropPolicyOldest {
+		t.Errorf("transport drop policy = %q, want oldest", mt.dropPolicy)
+	}
+}
+
+// ===========================================================================
+// SetChannelTTL
+// ===========================================================================
+
+func TestSetChannelTTL(t *testing.T) {
+	app, mt := newTestApp()
+	if result := app.SetChannelTTL(5, -1); result == "" {
+		t.Error("expected an error for a negative ttl")
+	}
+	if result := app.SetChannelTTL(5, 3600); result != "" {
+		t.Fatalf("expected empty result, got %q", result)
+	}
+	if result := app.SetChannelTTL(5, 0); result != "" {
+		t.Fatalf("expected empty result clearing the ttl, got %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if len(mt.channelTTLs) != 2 || mt.channelTTLs[0].seconds != 3600 || mt.channelTTLs[1].seconds != 0 {
+		t.Errorf("unexpected ttl requests: %v", mt.channelTTLs)
+	}
+}
+
 // ===========================================================================
 // DeleteChannel
 // ===========================================================================
@@ -1062,6 +1958,50 @@ func TestSetDeafened(t *testing.T) {
 	// Internally sets deafened on the audio engine + plays notification.
 }
 
+func TestDeafenMutesAndRestoresUnmuted(t *testing.T) {
+	app, mt := newTestApp()
+	app.SetDeafened(true)
+	if !app.audio.IsMuted() {
+		t.Error("expected deafen to mute the microphone")
+	}
+	app.SetDeafened(false)
+	if app.audio.IsMuted() {
+		t.Error("expected undeafen to restore the unmuted state")
+	}
+	want := [][2]bool{{true, true}, {false, false}}
+	if !slices.Equal(mt.voiceFlags, want) {
+		t.Errorf("voice flags = %v, want %v", mt.voiceFlags, want)
+	}
+}
+
+func TestUndeafenKeepsExplicitMute(t *testing.T) {
+	app, mt := newTestApp()
+	app.SetMuted(true)
+	app.SetDeafened(true)
+	app.SetDeafened(false)
+	if !app.audio.IsMuted() {
+		t.Error("expected explicit mute to survive a deafen toggle")
+	}
+	want := [][2]bool{{true, false}, {true, true}, {true, false}}
+	if !slices.Equal(mt.voiceFlags, want) {
+		t.Errorf("voice flags = %v, want %v", mt.voiceFlags, want)
+	}
+}
+
+func TestUnmuteWhileDeafenedAppliesOnUndeafen(t *testing.T) {
+	app, _ := newTestApp()
+	app.SetMuted(true)
+	app.SetDeafened(true)
+	app.SetMuted(false)
+	if !app.audio.IsMuted() {
+		t.Error("expected to stay muted while deafened")
+	}
+	app.SetDeafened(false)
+	if app.audio.IsMuted() {
+		t.Error("expected the unmute to apply after undeafening")
+	}
+}
+
 func TestSetAEC(t *testing.T) {
 	app, _ := newTestApp()
 	app.SetAEC(true)
@@ -1085,14 +2025,28 @@ func TestPTTKeyDownUp(t *testing.T) {
 	app.PTTKeyUp()
 }
 
+func TestRequestEditHistoryRejectsInvalidID(t *testing.T) {
+	app, _ := newTestApp()
+	if msg := app.RequestEditHistory(0); msg == "" {
+		t.Fatal("expected an error for msg_id 0")
+	}
+	if msg := app.RequestEditHistory(4); msg != "" {
+		t.Fatalf("unexpected error: %q", msg)
+	}
+}
+
 func TestSetInputDevice(t *testing.T) {
 	app, _ := newTestApp()
-	app.SetInputDevice(2)
+	if msg := app.SetInputDevice(2); msg != "" {
+		t.Errorf("SetInputDevice while stopped: %q", msg)
+	}
 }
 
 func TestSetOutputDevice(t *testing.T) {
 	app, _ := newTestApp()
-	app.SetOutputDevice(3)
+	if msg := app.SetOutputDevice(3); msg != "" {
+		t.Errorf("SetOutputDevice while stopped: %q", msg)
+	}
 }
 
 func TestGetInputLevel(t *testing.T) {
@@ -1270,6 +2224,23 @@ func TestGetMetricsReturnsCache(t *testing.T) {
 	}
 }
 
+func TestGetPeerMetricsSortedByUser(t *testing.T) {
+	app, mt := newTestApp()
+	mt.peerMetrics = map[uint16]PeerMetrics{
+		7: {UserID: 7, RTTMs: 80},
+		3: {UserID: 3, PacketLoss: 0.2},
+	}
+	got := app.GetPeerMetrics()
+	if len(got) != 2 || got[0].UserID != 3 || got[1].UserID != 7 {
+		t.Fatalf("GetPeerMetrics = %+v, want users 3 then 7", got)
+	}
+
+	app.transport = nil
+	if got := app.GetPeerMetrics(); got == nil || len(got) != 0 {
+		t.Errorf("GetPeerMetrics without a transport = %#v, want an empty slice", got)
+	}
+}
+
 // ===========================================================================
 // fileURL
 // ===========================================================================
@@ -1312,6 +2283,64 @@ func TestUploadFileFromPathNoAPIBase(t *testing.T) {
 	}
 }
 
+func TestUploadFileFromPathOverServerLimit(t *testing.T) {
+	app, mt := newTestApp()
+	mt.apiBaseURLVal = "http://127.0.0.1:1"
+	mt.maxUploadVal = 4
+	path := filepath.Join(t.TempDir(), "big.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	result := app.UploadFileFromPath(1, path)
+	if result != "file exceeds the server's 4 byte upload limit" {
+		t.Errorf("unexpected result %q", result)
+	}
+}
+
+func TestFormatUploadLimit(t *testing.T) {
+	cases := map[int64]string{
+		defaultMaxUploadBytes: "10 MB",
+		512 * 1024:            "512 KB",
+		1500:                  "1500 byte",
+	}
+	for n, want := range cases {
+		if got := formatUploadLimit(n); got != want {
+			t.Errorf("formatUploadLimit(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+// ===========================================================================
+// UploadFileToUser
+// ===========================================================================
+
+func TestUploadFileToUserEmptyPath(t *testing.T) {
+	app, _ := newTestApp()
+	if result := app.UploadFileToUser(2, ""); result != "no file path" {
+		t.Errorf("expected 'no file path', got %q", result)
+	}
+}
+
+func TestUploadFileToUserNoSession(t *testing.T) {
+	app := &App{audio: NewAudioEngine()}
+	if result := app.UploadFileToUser(2, "/tmp/file.txt"); result != "no active server session" {
+		t.Errorf("expected 'no active server session', got %q", result)
+	}
+}
+
+func TestUploadFileToUserDMUnsupported(t *testing.T) {
+	app, mt := newTestApp()
+	result := app.UploadFileToUser(2, "/tmp/file.txt")
+	if result != errDMUnsupported.Error() {
+		t.Errorf("expected DM unsupported error, got %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if len(mt.fileChatsSent) != 0 {
+		t.Errorf("no file message should be sent, got %d", len(mt.fileChatsSent))
+	}
+}
+
 // ===========================================================================
 // DisconnectVoice / ConnectVoice (partial tests: no audio start)
 // ===========================================================================
@@ -1347,6 +2376,30 @@ func TestDisconnectVoiceError(t *testing.T) {
 	}
 }
 
+func TestRecordingConsentIsSent(t *testing.T) {
+	app, mt := newTestApp()
+	app.connected.Store(true)
+	if msg := app.RecordingConsent(true); msg != "" {
+		t.Fatalf("accept: %s", msg)
+	}
+	if !app.connected.Load() {
+		t.Error("accepting should keep us in voice")
+	}
+	// Declining leaves voice.
+	if msg := app.RecordingConsent(false); msg != "" {
+		t.Fatalf("decline: %s", msg)
+	}
+	if app.connected.Load() {
+		t.Error("declining should leave voice")
+	}
+
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if !slices.Equal(mt.recordingConsents, []bool{true, false}) {
+		t.Errorf("consents sent = %v, want [true false]", mt.recordingConsents)
+	}
+}
+
 // ===========================================================================
 // wireCallbacks: verify all callbacks are set
 // ===========================================================================
@@ -1384,15 +2437,51 @@ func TestWireCallbacksSetsAllCallbacks(t *testing.T) {
 	if mt.onServerInfo == nil {
 		t.Error("onServerInfo not set")
 	}
+	if mt.onServerError == nil {
+		t.Error("onServerError not set")
+	}
 	if mt.onKicked == nil {
 		t.Error("onKicked not set")
 	}
+	if mt.onUserMuted == nil {
+		t.Error("onUserMuted not set")
+	}
+	if mt.onUserStatus == nil {
+		t.Error("onUserStatus not set")
+	}
+	if mt.onSpeaking == nil {
+		t.Error("onSpeaking not set")
+	}
+	if mt.onMOTD == nil {
+		t.Error("onMOTD not set")
+	}
+	if mt.onJoinSounds == nil {
+		t.Error("onJoinSounds not set")
+	}
+	if mt.onEmojiList == nil {
+		t.Error("onEmojiList not set")
+	}
+	if mt.onServerShutdown == nil {
+		t.Error("onServerShutdown not set")
+	}
+	if mt.onStopRecording == nil {
+		t.Error("onStopRecording not set")
+	}
 	if mt.onOwnerChanged == nil {
 		t.Error("onOwnerChanged not set")
 	}
+	if mt.onRecordingStarted == nil {
+		t.Error("onRecordingStarted not set")
+	}
+	if mt.onRecordingStopped == nil {
+		t.Error("onRecordingStopped not set")
+	}
 	if mt.onChannelList == nil {
 		t.Error("onChannelList not set")
 	}
+	if mt.onCategoryList == nil {
+		t.Error("onCategoryList not set")
+	}
 	if mt.onUserChannel == nil {
 		t.Error("onUserChannel not set")
 	}
@@ -1414,6 +2503,18 @@ func TestWireCallbacksSetsAllCallbacks(t *testing.T) {
 	if mt.onReactionRemoved == nil {
 		t.Error("onReactionRemoved not set")
 	}
+	if mt.onReactionsUpdated == nil {
+		t.Error("onReactionsUpdated not set")
+	}
+	if mt.onPermissions == nil {
+		t.Error("onPermissions not set")
+	}
+	if mt.onDM == nil {
+		t.Error("onDM not set")
+	}
+	if mt.onReconnecting == nil {
+		t.Error("onReconnecting not set")
+	}
 	if mt.onUserTyping == nil {
 		t.Error("onUserTyping not set")
 	}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"log/slog"
 	"math"
 	"sync"
@@ -20,6 +21,7 @@ const (
 	captureChannelBuf  = 30   // ~600ms @ 50 fps — low latency; drops if consumer falls behind
 	playbackChannelBuf = 30   // ~600ms @ 50 fps — low latency; silence fills gaps
 	opusMaxPacketBytes = 1275 // RFC 6716 max Opus packet size
+	opusMinPacketBytes = 16   // smallest useful payload cap: 6.4 kbps at 20 ms
 )
 
 // AudioDevice describes an available audio device.
@@ -45,6 +47,8 @@ type opusEncoder interface {
 	SetDTX(dtx bool) error
 	SetInBandFEC(fec bool) error
 	SetPacketLossPerc(lossPerc int) error
+	SetMaxBandwidth(maxBw opus.Bandwidth) error
+	SetComplexity(complexity int) error
 }
 
 // opusDecoder abstracts Opus decoding for testing.
@@ -77,12 +81,21 @@ type AudioEngine struct {
 	UserVolumeFunc func(senderID uint16) float64
 	// notifCh carries pre-chunked raw PCM float32 frames (FrameSize each)
 	// synthesised by PlayNotification. Mixed into the output after voice decoding.
-	notifCh    chan []float32
-	notifScale atomic.Uint32 // float32 bits: notification volume scale (default 1.0)
+	notifCh      chan []float32
+	notifScale   atomic.Uint32 // float32 bits: notification volume scale (default 1.0)
+	doNotDisturb atomic.Bool   // suppresses PlayNotification and PlayAlert
+	// sounds holds server-provided notification sounds; see LoadSound.
+	sounds soundCache
+	// alertCh carries PlayAlert frames, mixed into the call audio with the
+	// other participants ducked underneath.
+	alertCh chan []float32
 
 	echoCancellationEnabled atomic.Bool
 	autoGainControlEnabled  atomic.Bool
 	noiseSuppressionEnabled atomic.Bool
+	fecEnabled              atomic.Bool // Opus in-band FEC on encode, FEC recovery on decode
+	dtxEnabled              atomic.Bool // skip sending silent frames; see SetDTX
+	stereo                  atomic.Bool // capture and encode two channels; see SetStereo
 
 	running        atomic.Bool
 	testMode       atomic.Bool
@@ -91,6 +104,50 @@ type AudioEngine struct {
 	pttMode        atomic.Bool  // true = push-to-talk controls transmit
 	pttActive      atomic.Bool  // true = PTT key is held, mic is hot
 	currentBitrate atomic.Int32 // kbps; set in Start() and updated by SetBitrate()
+	maxPacketBytes atomic.Int32 // Opus payload cap per frame; 0 = no cap
+	bitrateFloor   atomic.Int32 // kbps; lower bound for adaptive bitrate
+	bitrateCeiling atomic.Int32 // kbps; upper bound for adaptive bitrate
+	jitterBufferMs atomic.Int32 // per-sender playback holdback; see SetJitterBufferMs
+	// channelBitrateCap is the voice channel's bitrate cap in kbps (0 = none);
+	// see SetChannelBitrateCap.
+	channelBitrateCap atomic.Int32
+	// Capture noise gate; see SetNoiseGate. noiseGateDb holds float64 bits.
+	noiseGateEnabled atomic.Bool
+	noiseGateDb      atomic.Uint64
+	// agcTargetDb is the AGC target loudness as float64 bits; see
+	// SetAGCTarget.
+	agcTargetDb atomic.Uint64
+	// opusComplexity is the encoder's CPU/quality trade-off (0-10); see
+	// SetOpusComplexity.
+	opusComplexity atomic.Int32
+	// captureFrameMs is the duration of each encoded frame; see
+	// SetCaptureFrameMs.
+	captureFrameMs atomic.Int32
+	// recorder is the local recording in progress, if any; see
+	// StartRecording.
+	recorder atomic.Pointer[localRecorder]
+	// latency is the loopback measurement in progress, if any; see
+	// MeasureLatency.
+	latency atomic.Pointer[latencyProbe]
+	// Microphone monitoring; see SetSidetone. sidetoneGain holds float64
+	// bits.
+	sidetoneEnabled atomic.Bool
+	sidetoneGain    atomic.Uint64
+	sidetone        sidetoneQueue
+	// autoLevel scales each sender toward a common loudness; see
+	// SetAutoLevel.
+	autoLevel atomic.Bool
+
+	// Opus signal-type hint. signalManual is the user's override, used
+	// while signalAuto is off; signalActive is what the encoder is tuned for.
+	// Both strings are guarded by mu.
+	signalAuto   atomic.Bool
+	signalManual string
+	signalActive string
+
+	// opusApp is the Opus application mode the encoder is created with;
+	// see SetOpusApplication. Guarded by mu.
+	opusApp string
 
 	// Dropped frame counters: incremented when CaptureOut / PlaybackIn channels
 	// are full and a frame is silently discarded. Read and reset by DroppedFrames().
@@ -101,6 +158,15 @@ type AudioEngine struct {
 	// for the input level meter. Updated every captureLoop iteration.
 	inputLevel atomic.Uint32
 
+	// Device changes while running; see SetInputDevice and
+	// SetOutputDevice. captureChannels and captureFrames describe the
+	// running capture stream, which a new input device must match; both are
+	// guarded by mu.
+	captureSwap     chan streamSwap
+	playbackSwap    chan streamSwap
+	captureChannels int
+	captureFrames   int
+
 	stopCh     chan struct{}
 	wg         sync.WaitGroup // tracks captureLoop + playbackLoop goroutines
 	OnSpeaking func()         // called (throttled) when mic audio exceeds speaking threshold
@@ -116,15 +182,30 @@ func NewAudioEngine() *AudioEngine {
 		inputDeviceID:  -1,
 		outputDeviceID: -1,
 		volume:         1.0,
+		signalManual:   SignalVoice,
+		signalActive:   SignalVoice,
+		opusApp:        OpusApplicationVoIP,
 		CaptureOut:     make(chan []byte, captureChannelBuf),
 		PlaybackIn:     make(chan TaggedAudio, playbackChannelBuf),
 		notifCh:        make(chan []float32, notifChannelBuf),
+		alertCh:        make(chan []float32, alertChannelBuf),
+		captureSwap:    make(chan streamSwap),
+		playbackSwap:   make(chan streamSwap),
 		stopCh:         make(chan struct{}),
 	}
 	ae.notifScale.Store(math.Float32bits(1.0))
+	ae.bitrateFloor.Store(defaultBitrateFloorKbps)
+	ae.bitrateCeiling.Store(defaultBitrateCeilingKbps)
+	ae.jitterBufferMs.Store(defaultJitterBufferMs)
+	ae.noiseGateDb.Store(math.Float64bits(defaultNoiseGateDb))
+	ae.agcTargetDb.Store(math.Float64bits(defaultAGCTargetDb))
+	ae.opusComplexity.Store(defaultOpusComplexity)
+	ae.captureFrameMs.Store(defaultCaptureFrameMs)
 	ae.echoCancellationEnabled.Store(true)
 	ae.noiseSuppressionEnabled.Store(true)
 	ae.autoGainControlEnabled.Store(true)
+	ae.fecEnabled.Store(true)
+	ae.dtxEnabled.Store(true)
 	return ae
 }
 
@@ -160,18 +241,164 @@ func listDevices(match func(*portaudio.DeviceInfo) bool) []AudioDevice {
 	return out
 }
 
-// SetInputDevice sets the input device by index.
-func (ae *AudioEngine) SetInputDevice(id int) {
+// SetInputDevice sets the input device by index. While the engine is
+// running only the capture stream is reopened, so the call carries on; if
+// the new device can't be opened the previous one stays in use and the
+// error is returned.
+func (ae *AudioEngine) SetInputDevice(id int) error {
 	ae.mu.Lock()
+	prev := ae.inputDeviceID
 	ae.inputDeviceID = id
 	ae.mu.Unlock()
+	if !ae.running.Load() || id == prev {
+		return nil
+	}
+	err := ae.reopenCapture(id)
+	if err != nil {
+		ae.mu.Lock()
+		ae.inputDeviceID = prev
+		ae.mu.Unlock()
+	}
+	return err
 }
 
-// SetOutputDevice sets the output device by index.
-func (ae *AudioEngine) SetOutputDevice(id int) {
+// SetOutputDevice sets the output device by index, resampling playback if
+// the device can't run at 48 kHz. While the engine is running only the
+// playback stream is reopened; queued audio keeps buffering meanwhile. If
+// the new device can't be opened the previous one stays in use and the
+// error is returned.
+func (ae *AudioEngine) SetOutputDevice(id int) error {
 	ae.mu.Lock()
+	prev := ae.outputDeviceID
 	ae.outputDeviceID = id
 	ae.mu.Unlock()
+	if !ae.running.Load() || id == prev {
+		return nil
+	}
+	err := ae.reopenPlayback(id)
+	if err != nil {
+		ae.mu.Lock()
+		ae.outputDeviceID = prev
+		ae.mu.Unlock()
+	}
+	return err
+}
+
+// streamSwap hands a newly opened stream to the loop that owns the old
+// one, so the loops never share a stream with another goroutine. The loop
+// stops and closes the old stream, then closes done.
+type streamSwap struct {
+	stream paStream
+	buf    []float32
+	rs     *resampler // playback only
+	done   chan struct{}
+}
+
+// swapTimeout bounds how long a device change waits for its loop to take
+// the new stream, in case the loop has exited on a stream error.
+const swapTimeout = time.Second
+
+// reopenCapture opens device id with the running capture stream's format
+// and hands it to captureLoop.
+func (ae *AudioEngine) reopenCapture(id int) error {
+	devices, err := portaudio.Devices()
+	if err != nil {
+		return err
+	}
+	dev, err := resolveDevice(devices, id, portaudio.DefaultInputDevice)
+	if err != nil {
+		return err
+	}
+	ae.mu.Lock()
+	chans, frames := ae.captureChannels, ae.captureFrames
+	ae.mu.Unlock()
+	if dev.MaxInputChannels < chans {
+		return fmt.Errorf("input device %q has %d channels, need %d", dev.Name, dev.MaxInputChannels, chans)
+	}
+	stream, buf, err := openCapture(dev, chans, frames)
+	if err != nil {
+		return err
+	}
+	if err := stream.Start(); err != nil {
+		stream.Close()
+		return err
+	}
+	if err := ae.handOver(ae.captureSwap, streamSwap{stream: stream, buf: buf}); err != nil {
+		return err
+	}
+	slog.Info("input device changed", "device", dev.Name)
+	return nil
+}
+
+// reopenPlayback opens device id for playback and hands it to
+// playbackLoop.
+func (ae *AudioEngine) reopenPlayback(id int) error {
+	devices, err := portaudio.Devices()
+	if err != nil {
+		return err
+	}
+	dev, err := resolveDevice(devices, id, portaudio.DefaultOutputDevice)
+	if err != nil {
+		return err
+	}
+	stream, buf, rs, err := openPlayback(dev)
+	if err != nil {
+		return err
+	}
+	if err := stream.Start(); err != nil {
+		stream.Close()
+		return err
+	}
+	if err := ae.handOver(ae.playbackSwap, streamSwap{stream: stream, buf: buf, rs: rs}); err != nil {
+		return err
+	}
+	slog.Info("output device changed", "device", dev.Name)
+	return nil
+}
+
+// handOver passes sw, whose stream is already started, to the loop
+// reading ch and waits until the loop has switched to it. If the engine
+// stops first the new stream is closed instead; the device ID is kept for
+// the next Start.
+func (ae *AudioEngine) handOver(ch chan streamSwap, sw streamSwap) error {
+	ae.mu.Lock()
+	stopCh := ae.stopCh
+	ae.mu.Unlock()
+	sw.done = make(chan struct{})
+	select {
+	case ch <- sw:
+		<-sw.done
+		return nil
+	case <-stopCh:
+		sw.stream.Close()
+		return nil
+	case <-time.After(swapTimeout):
+		sw.stream.Close()
+		return fmt.Errorf("audio stream is not running")
+	}
+}
+
+// takeStream installs sw's stream in *field and retires the old one. It
+// is called by the loop that owns *field, between reads or writes. If the
+// engine has stopped meanwhile the new stream is closed instead, so Stop
+// only ever has to deal with streams it can see; takeStream then reports
+// false and the loop should exit.
+func (ae *AudioEngine) takeStream(field *paStream, sw streamSwap) bool {
+	defer close(sw.done)
+	ae.mu.Lock()
+	if !ae.running.Load() {
+		ae.mu.Unlock()
+		sw.stream.Close()
+		return false
+	}
+	old := *field
+	*field = sw.stream
+	ae.mu.Unlock()
+	if old != nil {
+		old.Stop()
+		old.Close()
+	}
+	return true
 }
 
 // SetVolume sets the playback volume in [0.0, 1.0].
@@ -192,7 +419,8 @@ func (ae *AudioEngine) SetAEC(enabled bool) {
 	ae.echoCancellationEnabled.Store(enabled)
 }
 
-// SetAGC enables or disables automatic gain control preference.
+// SetAGC enables or disables automatic gain control, which steers the
+// microphone level toward the target set with SetAGCTarget.
 func (ae *AudioEngine) SetAGC(enabled bool) {
 	ae.autoGainControlEnabled.Store(enabled)
 }
@@ -225,7 +453,8 @@ func (ae *AudioEngine) InputLevel() float32 {
 }
 
 // SetBitrate changes the Opus encoder target bitrate (kbps) on the fly.
-// The value is clamped to the valid Opus range [6, 510].
+// The value is clamped to the valid Opus range [6, 510] and to the channel
+// and packet-size caps.
 // Safe to call concurrently with audio capture.
 func (ae *AudioEngine) SetBitrate(kbps int) {
 	if kbps < 6 {
@@ -234,6 +463,12 @@ func (ae *AudioEngine) SetBitrate(kbps int) {
 	if kbps > 510 {
 		kbps = 510
 	}
+	if limit := packetLimitKbps(int(ae.maxPacketBytes.Load()), ae.CaptureFrameMs()); limit > 0 && kbps > limit {
+		kbps = limit
+	}
+	if limit := int(ae.channelBitrateCap.Load()); limit > 0 && kbps > limit {
+		kbps = limit
+	}
 	ae.mu.Lock()
 	if ae.encoder != nil {
 		if err := ae.encoder.SetBitrate(kbps * 1000); err != nil {
@@ -250,6 +485,42 @@ func (ae *AudioEngine) CurrentBitrate() int {
 	return int(ae.currentBitrate.Load())
 }
 
+// SetMaxPacketBytes caps the size of each encoded Opus payload so a
+// frame always fits in one datagram on MTU-constrained links. The encoder is
+// handed an output buffer of n bytes, which libopus treats as a hard bound on
+// the frame's size, and the target bitrate is lowered to what n bytes per
+// frame can carry. n = 0 removes the cap.
+func (ae *AudioEngine) SetMaxPacketBytes(n int) error {
+	if n != 0 && (n < opusMinPacketBytes || n > opusMaxPacketBytes) {
+		return fmt.Errorf("max packet size must be 0 or between %d and %d bytes", opusMinPacketBytes, opusMaxPacketBytes)
+	}
+	ae.maxPacketBytes.Store(int32(n))
+	if limit := packetLimitKbps(n, ae.CaptureFrameMs()); limit > 0 && ae.CurrentBitrate() > limit {
+		ae.SetBitrate(limit)
+	}
+	slog.Debug("max packet size updated", "bytes", n)
+	return nil
+}
+
+// MaxPacketBytes returns the Opus payload cap in bytes (0 = no cap).
+func (ae *AudioEngine) MaxPacketBytes() int {
+	return int(ae.maxPacketBytes.Load())
+}
+
+// packetBuf returns buf truncated to the configured payload cap.
+func (ae *AudioEngine) packetBuf(buf []byte) []byte {
+	if n := int(ae.maxPacketBytes.Load()); n > 0 && n < len(buf) {
+		return buf[:n]
+	}
+	return buf
+}
+
+// packetLimitKbps is the highest bitrate whose frameMs frames fit in n
+// bytes, or 0 when n is 0 (no cap).
+func packetLimitKbps(n, frameMs int) int {
+	return n * 8 / frameMs
+}
+
 // SetPacketLoss tells the Opus encoder the expected packet loss percentage
 // so it can tune how much FEC redundancy to embed. lossPercent is clamped
 // to [0, 100].
@@ -270,6 +541,25 @@ func (ae *AudioEngine) SetPacketLoss(lossPercent int) {
 	slog.Debug("packet loss updated", "percent", lossPercent)
 }
 
+// newEncoder creates an Opus encoder for channels capture channels in the
+// engine's application mode and applies its current settings, targeting
+// kbps. Caller holds ae.mu.
+func (ae *AudioEngine) newEncoder(channels, kbps int) (*opus.Encoder, error) {
+	enc, err := opus.NewEncoder(sampleRate, channels, opusApplications[ae.opusApp])
+	if err != nil {
+		return nil, err
+	}
+	enc.SetBitrate(kbps * 1000)
+	enc.SetDTX(ae.dtxEnabled.Load())
+	enc.SetInBandFEC(ae.fecEnabled.Load())
+	enc.SetPacketLossPerc(fecMinLossPercent) // conservative default estimate
+	enc.SetComplexity(int(ae.opusComplexity.Load()))
+	if err := applySignal(enc, ae.signalActive, ae.dtxEnabled.Load()); err != nil {
+		slog.Error("set opus signal", "signal", ae.signalActive, "err", err)
+	}
+	return enc, nil
+}
+
 // Start initializes the Opus codec and starts capture/playback streams.
 func (ae *AudioEngine) Start() error {
 	ae.mu.Lock()
@@ -279,68 +569,55 @@ func (ae *AudioEngine) Start() error {
 		return nil
 	}
 
-	enc, err := opus.NewEncoder(sampleRate, channels, opus.AppVoIP)
+	devices, err := portaudio.Devices()
 	if err != nil {
 		return err
 	}
-	targetKbps := int(ae.currentBitrate.Load())
-	if targetKbps <= 0 {
-		targetKbps = opusBitrate / 1000
-	}
-	enc.SetBitrate(targetKbps * 1000)
-	enc.SetDTX(true)
-	enc.SetInBandFEC(true)
-	enc.SetPacketLossPerc(5) // conservative default estimate
-	ae.encoder = enc
-	ae.currentBitrate.Store(int32(targetKbps))
 
-	dec, err := opus.NewDecoder(sampleRate, channels)
+	inputDev, err := resolveDevice(devices, ae.inputDeviceID, portaudio.DefaultInputDevice)
 	if err != nil {
 		return err
 	}
-	ae.decoder = dec
 
-	devices, err := portaudio.Devices()
+	outputDev, err := resolveDevice(devices, ae.outputDeviceID, portaudio.DefaultOutputDevice)
 	if err != nil {
 		return err
 	}
 
-	inputDev, err := resolveDevice(devices, ae.inputDeviceID, portaudio.DefaultInputDevice)
-	if err != nil {
-		return err
+	captureChannels := captureChannelCount(ae.stereo.Load(), inputDev.MaxInputChannels)
+	if ae.stereo.Load() && captureChannels == 1 {
+		slog.Warn("input device is mono, capturing mono", "device", inputDev.Name)
 	}
 
-	outputDev, err := resolveDevice(devices, ae.outputDeviceID, portaudio.DefaultOutputDevice)
+	targetKbps := int(ae.currentBitrate.Load())
+	if targetKbps <= 0 {
+		targetKbps = opusBitrate / 1000
+	}
+	if limit := packetLimitKbps(int(ae.maxPacketBytes.Load()), ae.CaptureFrameMs()); limit > 0 && targetKbps > limit {
+		targetKbps = limit
+	}
+	if limit := int(ae.channelBitrateCap.Load()); limit > 0 && targetKbps > limit {
+		targetKbps = limit
+	}
+	enc, err := ae.newEncoder(captureChannels, targetKbps)
 	if err != nil {
 		return err
 	}
+	ae.encoder = enc
+	ae.currentBitrate.Store(int32(targetKbps))
 
-	captureBuf := make([]float32, FrameSize)
-	captureParams := portaudio.StreamParameters{
-		Input: portaudio.StreamDeviceParameters{
-			Device:   inputDev,
-			Channels: channels,
-			Latency:  inputDev.DefaultLowInputLatency,
-		},
-		SampleRate:      sampleRate,
-		FramesPerBuffer: FrameSize,
-	}
-	captureStream, err := portaudio.OpenStream(captureParams, captureBuf)
+	dec, err := opus.NewDecoder(sampleRate, channels)
 	if err != nil {
 		return err
 	}
+	ae.decoder = dec
 
-	playbackBuf := make([]float32, FrameSize)
-	playbackParams := portaudio.StreamParameters{
-		Output: portaudio.StreamDeviceParameters{
-			Device:   outputDev,
-			Channels: channels,
-			Latency:  outputDev.DefaultLowOutputLatency,
-		},
-		SampleRate:      sampleRate,
-		FramesPerBuffer: FrameSize,
+	frameSamples := ae.captureFrameSamples()
+	captureStream, captureBuf, err := openCapture(inputDev, captureChannels, frameSamples)
+	if err != nil {
+		return err
 	}
-	playbackStream, err := portaudio.OpenStream(playbackParams, playbackBuf)
+	playbackStream, playbackBuf, rs, err := openPlayback(outputDev)
 	if err != nil {
 		captureStream.Close()
 		return err
@@ -360,19 +637,75 @@ func (ae *AudioEngine) Start() error {
 
 	ae.captureStream = captureStream
 	ae.playbackStream = playbackStream
+	ae.captureChannels = captureChannels
+	ae.captureFrames = frameSamples
 	ae.stopCh = make(chan struct{})
 	ae.notifCh = make(chan []float32, notifChannelBuf)
+	ae.alertCh = make(chan []float32, alertChannelBuf)
 	ae.running.Store(true)
 
 	ae.wg.Add(2)
-	go func() { defer ae.wg.Done(); ae.captureLoop(captureBuf) }()
-	go func() { defer ae.wg.Done(); ae.playbackLoop(playbackBuf) }()
+	go func() { defer ae.wg.Done(); ae.captureLoop(captureBuf, frameSamples) }()
+	go func() { defer ae.wg.Done(); ae.playbackLoop(playbackBuf, rs) }()
 
-	slog.Debug("audio stream parameters", "sampleRate", sampleRate, "frameSize", FrameSize, "channels", channels)
+	slog.Debug("audio stream parameters", "sampleRate", sampleRate, "frameSize", FrameSize, "capture_frame_samples", frameSamples, "capture_channels", captureChannels, "playback_channels", channels, "playback_buffer", len(playbackBuf))
 	slog.Info("audio engine started", "capture", inputDev.Name, "playback", outputDev.Name)
 	return nil
 }
 
+// openCapture opens an input stream on dev reading frameSamples samples
+// per channel into the returned buffer.
+func openCapture(dev *portaudio.DeviceInfo, chans, frameSamples int) (paStream, []float32, error) {
+	buf := make([]float32, frameSamples*chans)
+	params := portaudio.StreamParameters{
+		Input: portaudio.StreamDeviceParameters{
+			Device:   dev,
+			Channels: chans,
+			Latency:  dev.DefaultLowInputLatency,
+		},
+		SampleRate:      sampleRate,
+		FramesPerBuffer: frameSamples,
+	}
+	stream, err := portaudio.OpenStream(params, buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stream, buf, nil
+}
+
+// openPlayback opens an output stream on dev writing from the returned
+// buffer. Decoded audio is always 48 kHz; devices that can't run at that
+// rate are opened at their own rate and the returned resampler converts
+// to it.
+func openPlayback(dev *portaudio.DeviceInfo) (paStream, []float32, *resampler, error) {
+	params := func(rate float64, frames int) portaudio.StreamParameters {
+		return portaudio.StreamParameters{
+			Output: portaudio.StreamDeviceParameters{
+				Device:   dev,
+				Channels: channels,
+				Latency:  dev.DefaultLowOutputLatency,
+			},
+			SampleRate:      rate,
+			FramesPerBuffer: frames,
+		}
+	}
+	buf := make([]float32, FrameSize)
+	rate := outputSampleRate(dev, func(rate float64) bool {
+		return portaudio.IsFormatSupported(params(rate, FrameSize), buf) == nil
+	})
+	var rs *resampler
+	if rate != sampleRate {
+		rs = newResampler(sampleRate, rate)
+		buf = make([]float32, rs.outFrameSize(FrameSize))
+		slog.Info("resampling playback", "device", dev.Name, "device_rate", rate)
+	}
+	stream, err := portaudio.OpenStream(params(rate, len(buf)), buf)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return stream, buf, rs, nil
+}
+
 // resolveDevice returns the device at idx if valid, otherwise calls fallback.
 func resolveDevice(devices []*portaudio.DeviceInfo, idx int, fallback func() (*portaudio.DeviceInfo, error)) (*portaudio.DeviceInfo, error) {
 	if idx >= 0 && idx < len(devices) {
@@ -503,15 +836,51 @@ func frameRMS(buf []float32) float32 {
 	return float32(math.Sqrt(sum / float64(len(buf))))
 }
 
-func (ae *AudioEngine) captureLoop(buf []float32) {
-	// Reuse allocations across frames.
-	pcm := make([]int16, FrameSize)
+// captureLoop reads, encodes and sends frameSamples samples per channel at
+// a time.
+func (ae *AudioEngine) captureLoop(buf []float32, frameSamples int) {
+	// Reuse allocations across frames. buf holds interleaved samples, so a
+	// stereo capture is twice frameSamples; analysis runs on a mono downmix.
 	opusBuf := make([]byte, opusMaxPacketBytes)
+	var loopbackSeq uint16 // test-mode frames pass through the jitter buffer
+	// The gates' hangovers are counted in frames; keep them the same length
+	// in time whatever the frame size.
+	gate := dtxGate{hangoverFrames: scaleFrameCount(dtxHangoverFrames, frameSamples)}
+	noise := noiseGate{hangoverFrames: scaleFrameCount(noiseGateHangoverFrames, frameSamples)}
+	var gain agc
+	var (
+		pcm    []int16
+		stereo bool
+		mono   []float32
+	)
+	// useBuf switches to the buffer bound to the current capture stream.
+	useBuf := func(b []float32) {
+		buf = b
+		pcm = make([]int16, len(buf))
+		stereo = len(buf) > frameSamples
+		mono = buf
+		if stereo {
+			mono = make([]float32, frameSamples)
+		}
+	}
+	useBuf(buf)
 	var lastSpeakEmit time.Time
+	classifier := newSignalClassifier(ae.SignalType())
 
 	for ae.running.Load() {
+		select {
+		case sw := <-ae.captureSwap:
+			if !ae.takeStream(&ae.captureStream, sw) {
+				return
+			}
+			useBuf(sw.buf)
+		default:
+		}
+
+		// The encoder is read with the stream since SetOpusApplication may
+		// replace it between frames.
 		ae.mu.Lock()
-		cs := ae.captureStream
+		cs, enc := ae.captureStream, ae.encoder
 		ae.mu.Unlock()
 		if cs == nil {
 			return
@@ -523,10 +892,20 @@ func (ae *AudioEngine) captureLoop(buf []float32) {
 			return
 		}
 
-		rms := frameRMS(buf)
+		if p := ae.latency.Load(); p != nil {
+			p.capture(buf, stereo)
+		}
+		if stereo {
+			downmix(mono, buf)
+		}
+		rms := frameRMS(mono)
 		ae.inputLevel.Store(math.Float32bits(rms))
+		// The level meter shows what the mic hears; everything after it,
+		// including the speaking indicator, sees the gated frame.
+		rms = ae.gateFrame(&noise, buf, rms)
+		rms = ae.agcFrame(&gain, buf, rms)
 
-		if ae.OnSpeaking != nil && !ae.muted.Load() && rms > 0.01 && time.Since(lastSpeakEmit) > 80*time.Millisecond {
+		if ae.OnSpeaking != nil && !ae.IsMuted() && rms > 0.01 && time.Since(lastSpeakEmit) > 80*time.Millisecond {
 			lastSpeakEmit = time.Now()
 			ae.OnSpeaking()
 		}
@@ -537,34 +916,52 @@ func (ae *AudioEngine) captureLoop(buf []float32) {
 		if ae.pttMode.Load() && !ae.pttActive.Load() {
 			continue
 		}
+		ae.feedSidetone(buf, stereo)
+
+		if ae.signalAuto.Load() {
+			if signal, changed := classifier.push(mono); changed {
+				ae.setActiveSignal(signal)
+			}
+		}
 
 		// Convert float32 to int16 for Opus encoder.
 		for i, s := range buf {
 			pcm[i] = int16(clampFloat32(s) * 32767)
 		}
 
-		n, err := ae.encoder.Encode(pcm, opusBuf)
+		n, err := enc.Encode(pcm, ae.packetBuf(opusBuf))
 		if err != nil {
 			slog.Error("opus encode", "err", err)
 			continue
 		}
 
+		// DTX only applies to speech; music keeps every frame so quiet
+		// passages aren't gated out.
+		if ae.dtxEnabled.Load() && ae.SignalType() == SignalVoice && !gate.transmit(rms, n) {
+			continue
+		}
+
 		encoded := make([]byte, n)
 		copy(encoded, opusBuf[:n])
 
 		// In test mode, loop back directly to playback; otherwise send to network
-		// (unless muted).
+		// (unless muted or deafened).
 		if ae.testMode.Load() {
 			select {
-			case ae.PlaybackIn <- TaggedAudio{SenderID: 0, Seq: 0, OpusData: encoded}:
+			case ae.PlaybackIn <- TaggedAudio{SenderID: 0, Seq: loopbackSeq, OpusData: encoded}:
 			default:
 			}
-		} else if !ae.muted.Load() {
+			loopbackSeq++
+		} else if !ae.IsMuted() {
 			select {
 			case ae.CaptureOut <- encoded:
 			default:
 				ae.captureDropped.Add(1)
 			}
+			// Record what we send, as others hear it.
+			if rec := ae.recorder.Load(); rec != nil {
+				rec.captured(buf, stereo)
+			}
 		}
 	}
 }
@@ -573,27 +970,68 @@ func (ae *AudioEngine) captureLoop(buf []float32) {
 // for senders that have gone silent (every N playback cycles ≈ N*20 ms).
 const decoderPruneInterval = 500 // ~10 s
 
-func (ae *AudioEngine) playbackLoop(buf []float32) {
-	pcm := make([]int16, FrameSize)
+// playbackLoop mixes FrameSize samples at 48 kHz every cycle and writes them
+// to the playback stream through out, the stream's buffer. When rs is set
+// the device runs at another rate: each mixed frame is resampled and out is
+// written whenever enough converted audio has built up.
+func (ae *AudioEngine) playbackLoop(out []float32, rs *resampler) {
+	var (
+		buf       []float32
+		converted []float32
+	)
+	// useBuf switches to the buffer bound to the current playback stream.
+	useBuf := func(o []float32, r *resampler) {
+		out, rs = o, r
+		buf, converted = out, nil
+		if rs != nil {
+			buf = make([]float32, FrameSize)
+			converted = make([]float32, 0, len(out)+rs.outFrameSize(FrameSize)+1)
+		}
+	}
+	useBuf(out, rs)
+	pcm := make([]int16, opusMaxFrameSamples)
+	// pending holds each sender's decoded audio not yet played. Every cycle
+	// plays FrameSize samples, so a 10 ms packet takes two cycles' decodes
+	// and a 60 ms packet lasts three cycles.
+	pending := make(map[uint16][]int16)
 	decoders := make(map[uint16]opusDecoder)
 	lastDecoded := make(map[uint16]time.Time)
-	latestFrame := make(map[uint16]TaggedAudio)
+	lastSeq := make(map[uint16]uint16)
+	buffers := make(map[uint16]*jitterBuffer)
+	levels := make(map[uint16]*autoLeveler)
 	var pruneCounter int
+	duck := float32(1)
 
 	for {
-		// Check for stop before every write cycle.
+		// Check for stop, or a new output device, before every write
+		// cycle. Tagged frames keep queueing in PlaybackIn while a device
+		// is being opened and are drained below.
 		select {
 		case <-ae.stopCh:
 			return
+		case sw := <-ae.playbackSwap:
+			if !ae.takeStream(&ae.playbackStream, sw) {
+				return
+			}
+			useBuf(sw.buf, sw.rs)
 		default:
 		}
 
-		// Drain all available tagged frames; keep the most recent frame per sender.
+		// Drain all available tagged frames into each sender's jitter buffer.
+		depth := jitterFrames(ae.JitterBufferMs())
 	drain:
 		for {
 			select {
 			case tagged := <-ae.PlaybackIn:
-				latestFrame[tagged.SenderID] = tagged
+				jb, ok := buffers[tagged.SenderID]
+				if !ok {
+					jb = &jitterBuffer{}
+					buffers[tagged.SenderID] = jb
+				}
+				jb.depth = depth
+				if !jb.push(tagged) {
+					ae.playbackDropped.Add(1)
+				}
 			default:
 				break drain
 			}
@@ -608,46 +1046,74 @@ func (ae *AudioEngine) playbackLoop(buf []float32) {
 			ae.mu.Unlock()
 			scale := float32(vol) / 32768.0
 
-			for senderID, tagged := range latestFrame {
-				dec, ok := decoders[senderID]
-				if !ok {
-					d, err := opus.NewDecoder(sampleRate, channels)
+			for senderID, jb := range buffers {
+				out := pending[senderID]
+				for len(out) < FrameSize {
+					// Once this cycle has audio, top up only from frames
+					// already queued so a short packet isn't an underrun.
+					if len(out) > 0 && !jb.ready() {
+						break
+					}
+					tagged, ok := jb.pop()
+					if !ok {
+						break
+					}
+					dec, ok := decoders[senderID]
+					if !ok {
+						d, err := opus.NewDecoder(sampleRate, channels)
+						if err != nil {
+							slog.Error("create opus decoder", "sender", senderID, "err", err)
+							break
+						}
+						dec = d
+						decoders[senderID] = dec
+						slog.Debug("created opus decoder for new sender", "sender", senderID)
+					}
+
+					// A single lost frame is rebuilt from this packet's FEC
+					// data so the decoder's state carries through the gap
+					// instead of resetting on the next frame. The lost frame
+					// is taken to be as long as this one.
+					if prev, ok := lastSeq[senderID]; ok && ae.fecEnabled.Load() && fecRecoverable(prev, tagged.Seq) {
+						if n := opusPacketSamples(tagged.OpusData); n > 0 && n <= len(pcm) {
+							if err := dec.DecodeFEC(tagged.OpusData, pcm[:n]); err != nil {
+								slog.Debug("opus fec decode", "sender", senderID, "err", err)
+							}
+						}
+					}
+					lastSeq[senderID] = tagged.Seq
+
+					n, err := dec.Decode(tagged.OpusData, pcm)
 					if err != nil {
-						slog.Error("create opus decoder", "sender", senderID, "err", err)
+						slog.Error("opus decode", "sender", senderID, "err", err)
 						continue
 					}
-					dec = d
-					decoders[senderID] = dec
-					slog.Debug("created opus decoder for new sender", "sender", senderID)
-				}
-
-				n, err := dec.Decode(tagged.OpusData, pcm)
-				if err != nil {
-					slog.Error("opus decode", "sender", senderID, "err", err)
-					continue
+					lastDecoded[senderID] = time.Now()
+					out = append(out, pcm[:n]...)
 				}
-				lastDecoded[senderID] = time.Now()
+				n := min(len(out), FrameSize)
 
-				// Per-user volume multiplier.
-				userScale := scale
-				if ae.UserVolumeFunc != nil {
-					userScale = scale * float32(ae.UserVolumeFunc(senderID))
-				}
+				// Per-user volume, times the auto-level gain when enabled.
+				userScale := scale * ae.senderGain(senderID, out[:n], levels)
 
 				// Additively mix this sender into the output buffer.
 				for i := 0; i < n; i++ {
-					buf[i] += float32(pcm[i]) * userScale
+					buf[i] += float32(out[i]) * userScale
 				}
+				// Keep the rest for the next cycle, reusing the buffer.
+				pending[senderID] = out[:copy(out, out[n:])]
 			}
 
 			// Clamp mixed output to [-1.0, 1.0].
 			for i := range buf {
 				buf[i] = clampFloat32(buf[i])
 			}
-		}
-
-		for senderID := range latestFrame {
-			delete(latestFrame, senderID)
+		} else {
+			// Nothing is played while deafened; start fresh on undeafen.
+			for senderID := range buffers {
+				delete(buffers, senderID)
+				delete(pending, senderID)
+			}
 		}
 
 		// Periodically prune stale decoders for users that have gone silent.
@@ -659,10 +1125,40 @@ func (ae *AudioEngine) playbackLoop(buf []float32) {
 				if seen.Before(cutoff) {
 					delete(lastDecoded, senderID)
 					delete(decoders, senderID)
+					delete(lastSeq, senderID)
+					delete(buffers, senderID)
+					delete(pending, senderID)
+					delete(levels, senderID)
 				}
 			}
 		}
 
+		if p := ae.latency.Load(); p != nil {
+			p.played(buf)
+		}
+
+		// Local recordings take the call audio, not alerts and UI sounds.
+		if rec := ae.recorder.Load(); rec != nil {
+			rec.writeFrame(buf)
+		}
+
+		// Mix in one alert frame if available, ducking the call audio under it.
+		var alertFrame []float32
+		select {
+		case alertFrame = <-ae.alertCh:
+		default:
+		}
+		nextDuck := nextDuckGain(duck, alertFrame != nil)
+		applyGainRamp(buf, duck, nextDuck)
+		duck = nextDuck
+		for i, s := range alertFrame {
+			buf[i] = clampFloat32(buf[i] + s)
+		}
+
+		// Sidetone comes after the recording and ducking: it is the user's
+		// own microphone, already recorded from the capture side.
+		ae.sidetone.mix(buf)
+
 		// Mix in one notification frame if available. Notifications bypass the
 		// deafen check so UI sounds (mute, join/leave) are always audible.
 		select {
@@ -674,21 +1170,41 @@ func (ae *AudioEngine) playbackLoop(buf []float32) {
 		default:
 		}
 
-		ae.mu.Lock()
-		ps := ae.playbackStream
-		ae.mu.Unlock()
-		if ps == nil {
-			return
+		if rs == nil {
+			if !ae.writePlayback() {
+				return
+			}
+			continue
 		}
-		if err := ps.Write(); err != nil {
-			if ae.running.Load() {
-				slog.Error("playback write", "err", err)
+		converted = rs.resample(converted, buf)
+		for len(converted) >= len(out) {
+			copy(out, converted)
+			converted = converted[:copy(converted, converted[len(out):])]
+			if !ae.writePlayback() {
+				return
 			}
-			return
 		}
 	}
 }
 
+// writePlayback writes the playback stream's buffer to the device. It
+// returns false when the loop should exit: the stream is gone or failed.
+func (ae *AudioEngine) writePlayback() bool {
+	ae.mu.Lock()
+	ps := ae.playbackStream
+	ae.mu.Unlock()
+	if ps == nil {
+		return false
+	}
+	if err := ps.Write(); err != nil {
+		if ae.running.Load() {
+			slog.Error("playback write", "err", err)
+		}
+		return false
+	}
+	return true
+}
+
 // StartTest enables loopback test mode (capture goes directly to playback).
 func (ae *AudioEngine) StartTest() error {
 	ae.testMode.Store(true)
@@ -701,18 +1217,21 @@ func (ae *AudioEngine) StopTest() {
 	ae.Stop()
 }
 
-// SetMuted mutes or unmutes the microphone (stops sending audio).
+// SetMuted mutes or unmutes the microphone (stops sending audio). The
+// choice is remembered while deafened and applies again on undeafen.
 func (ae *AudioEngine) SetMuted(muted bool) {
 	ae.muted.Store(muted)
 }
 
-// SetDeafened enables or disables audio playback.
+// SetDeafened enables or disables audio playback. Deafening also stops
+// sending audio without touching the mute setting.
 func (ae *AudioEngine) SetDeafened(deafened bool) {
 	ae.deafened.Store(deafened)
 }
 
-// IsMuted reports whether the microphone is currently muted.
-func (ae *AudioEngine) IsMuted() bool { return ae.muted.Load() }
+// IsMuted reports whether the microphone is currently muted, either
+// explicitly or because playback is deafened.
+func (ae *AudioEngine) IsMuted() bool { return ae.muted.Load() || ae.deafened.Load() }
 
 // IsDeafened reports whether audio playback is currently disabled.
 func (ae *AudioEngine) IsDeafened() bool { return ae.deafened.Load() }
@@ -758,7 +1277,7 @@ func (ae *AudioEngine) AddPlaybackDrop() {
 // EncodeFrame encodes a PCM int16 frame to Opus. Exported for testing.
 func (ae *AudioEngine) EncodeFrame(pcm []int16) ([]byte, error) {
 	buf := make([]byte, opusMaxPacketBytes)
-	n, err := ae.encoder.Encode(pcm, buf)
+	n, err := ae.encoder.Encode(pcm, ae.packetBuf(buf))
 	if err != nil {
 		return nil, err
 	}
@@ -767,7 +1286,7 @@ func (ae *AudioEngine) EncodeFrame(pcm []int16) ([]byte, error) {
 
 // DecodeFrame decodes an Opus frame to PCM int16. Exported for testing.
 func (ae *AudioEngine) DecodeFrame(data []byte) ([]int16, error) {
-	pcm := make([]int16, FrameSize)
+	pcm := make([]int16, opusMaxFrameSamples)
 	n, err := ae.decoder.Decode(data, pcm)
 	if err != nil {
 		return nil, err
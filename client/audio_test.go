@@ -102,6 +102,8 @@ func (m *mockEncoder) SetBitrate(int) error       { return nil }
 func (m *mockEncoder) SetDTX(bool) error           { return nil }
 func (m *mockEncoder) SetInBandFEC(bool) error      { return nil }
 func (m *mockEncoder) SetPacketLossPerc(int) error  { return nil }
+func (m *mockEncoder) SetMaxBandwidth(opus.Bandwidth) error { return nil }
+func (m *mockEncoder) SetComplexity(int) error { return nil }
 
 // startWithMocks wires mock streams/encoder and starts the capture+playback
 // goroutines the same way Start() does, but without touching real PortAudio.
@@ -119,8 +121,8 @@ func startWithMocks(ae *AudioEngine, capture, playback paStream) {
 	playbackBuf := make([]float32, FrameSize)
 
 	ae.wg.Add(2)
-	go func() { defer ae.wg.Done(); ae.captureLoop(captureBuf) }()
-	go func() { defer ae.wg.Done(); ae.playbackLoop(playbackBuf) }()
+	go func() { defer ae.wg.Done(); ae.captureLoop(captureBuf, FrameSize) }()
+	go func() { defer ae.wg.Done(); ae.playbackLoop(playbackBuf, nil) }()
 }
 
 // TestStopReturnsWhenStreamsUnblock verifies that Stop() completes promptly
@@ -288,6 +290,86 @@ func TestStopConcurrent(t *testing.T) {
 	}
 }
 
+// pacedPAStream is a working device: each Write takes about one
+// millisecond and is counted.
+type pacedPAStream struct {
+	writes  atomic.Int64
+	stopped atomic.Bool
+	closed  atomic.Bool
+}
+
+func (p *pacedPAStream) Start() error { return nil }
+func (p *pacedPAStream) Stop() error  { p.stopped.Store(true); return nil }
+func (p *pacedPAStream) Abort() error { return p.Stop() }
+func (p *pacedPAStream) Close() error { p.closed.Store(true); return nil }
+func (p *pacedPAStream) Read() error  { time.Sleep(time.Millisecond); return nil }
+
+func (p *pacedPAStream) Write() error {
+	if p.stopped.Load() {
+		return fmt.Errorf("stream stopped")
+	}
+	time.Sleep(time.Millisecond)
+	p.writes.Add(1)
+	return nil
+}
+
+// waitWrites spins until s has been written to, or fails the test.
+func waitWrites(t *testing.T, s *pacedPAStream) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for s.writes.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("playback loop never wrote to the stream")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestOutputDeviceSwapDuringPlayback simulates changing the output device
+// mid-call: playback moves to the new stream and the old one is released
+// while the engine keeps running.
+func TestOutputDeviceSwapDuringPlayback(t *testing.T) {
+	ae := NewAudioEngine()
+	capture := newMockPAStream(false)
+	old := &pacedPAStream{}
+	startWithMocks(ae, capture, old)
+	defer ae.Stop()
+	waitWrites(t, old)
+
+	next := &pacedPAStream{}
+	if err := ae.handOver(ae.playbackSwap, streamSwap{stream: next, buf: make([]float32, FrameSize)}); err != nil {
+		t.Fatalf("handOver: %v", err)
+	}
+	if !old.stopped.Load() || !old.closed.Load() {
+		t.Error("old playback stream was not stopped and closed")
+	}
+	waitWrites(t, next)
+	if !ae.running.Load() {
+		t.Error("engine stopped during the device change")
+	}
+
+	ae.Stop()
+	if !next.closed.Load() {
+		t.Error("new playback stream was not closed by Stop")
+	}
+}
+
+// TestDeviceSwapAfterStopClosesStream checks that a stream opened for a
+// device change is closed, not leaked, when the engine has stopped.
+func TestDeviceSwapAfterStopClosesStream(t *testing.T) {
+	ae := NewAudioEngine()
+	startWithMocks(ae, newMockPAStream(false), &pacedPAStream{})
+	ae.Stop()
+
+	next := &pacedPAStream{}
+	if err := ae.handOver(ae.playbackSwap, streamSwap{stream: next, buf: make([]float32, FrameSize)}); err != nil {
+		t.Fatalf("handOver: %v", err)
+	}
+	if !next.closed.Load() {
+		t.Error("stream handed over after Stop was not closed")
+	}
+}
+
 func TestOpusEncodeDecodeRoundTrip(t *testing.T) {
 	enc, err := opus.NewEncoder(sampleRate, channels, opus.AppVoIP)
 	if err != nil {
@@ -654,3 +736,71 @@ func TestOpusMultipleFrames(t *testing.T) {
 		}
 	}
 }
+
+func TestMaxPacketBytesBoundsEncodedFrames(t *testing.T) {
+	enc, err := opus.NewEncoder(sampleRate, channels, opus.AppVoIP)
+	if err != nil {
+		t.Fatalf("new encoder: %v", err)
+	}
+	enc.SetBitrate(128000)
+
+	ae := NewAudioEngine()
+	ae.encoder = enc
+	if err := ae.SetMaxPacketBytes(40); err != nil {
+		t.Fatalf("SetMaxPacketBytes: %v", err)
+	}
+
+	// Noise-like content so the encoder wants every bit it can get.
+	for frame := 0; frame < 10; frame++ {
+		pcm := make([]int16, FrameSize)
+		for i := range pcm {
+			x := float64(i + frame*FrameSize)
+			pcm[i] = int16((math.Sin(x*0.37) + math.Sin(x*1.91) + math.Sin(x*2.73)) * 9000)
+		}
+		out, err := ae.EncodeFrame(pcm)
+		if err != nil {
+			t.Fatalf("frame %d encode: %v", frame, err)
+		}
+		if len(out) > 40 {
+			t.Fatalf("frame %d: %d bytes exceeds 40-byte cap", frame, len(out))
+		}
+	}
+}
+
+func TestMaxPacketBytesClampsBitrate(t *testing.T) {
+	ae := NewAudioEngine()
+	ae.SetBitrate(64)
+
+	// 40 bytes per 20 ms frame carries at most 16 kbps.
+	if err := ae.SetMaxPacketBytes(40); err != nil {
+		t.Fatalf("SetMaxPacketBytes: %v", err)
+	}
+	if got := ae.CurrentBitrate(); got != 16 {
+		t.Errorf("bitrate after cap: got %d, want 16", got)
+	}
+	ae.SetBitrate(64)
+	if got := ae.CurrentBitrate(); got != 16 {
+		t.Errorf("bitrate above cap: got %d, want 16", got)
+	}
+
+	// Removing the cap allows higher bitrates again.
+	if err := ae.SetMaxPacketBytes(0); err != nil {
+		t.Fatalf("SetMaxPacketBytes(0): %v", err)
+	}
+	ae.SetBitrate(64)
+	if got := ae.CurrentBitrate(); got != 64 {
+		t.Errorf("bitrate after removing cap: got %d, want 64", got)
+	}
+}
+
+func TestMaxPacketBytesRejectsOutOfRange(t *testing.T) {
+	ae := NewAudioEngine()
+	for _, n := range []int{-1, opusMinPacketBytes - 1, opusMaxPacketBytes + 1} {
+		if err := ae.SetMaxPacketBytes(n); err == nil {
+			t.Errorf("SetMaxPacketBytes(%d): expected error", n)
+		}
+	}
+	if ae.MaxPacketBytes() != 0 {
+		t.Errorf("rejected values should leave the cap unset, got %d", ae.MaxPacketBytes())
+	}
+}
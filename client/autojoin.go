@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+
+	wailsrt "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// autoJoinState carries a pending "join voice on connect" target from Connect
+// until the server's channel list arrives and the channel can be checked.
+type autoJoinState struct {
+	mu        sync.Mutex
+	channelID int64         // pending target; 0 = nothing pending
+	occupancy map[int64]int // users per channel from the connect snapshot
+}
+
+// arm sets the channel to join once the channel list is known.
+func (s *autoJoinState) arm(channelID int64) {
+	s.mu.Lock()
+	s.channelID = channelID
+	s.occupancy = nil
+	s.mu.Unlock()
+}
+
+// disarm cancels any pending auto-join.
+func (s *autoJoinState) disarm() {
+	s.arm(0)
+}
+
+// observeUsers records channel occupancy from a user list while armed, so
+// take can honour the target channel's MaxUsers.
+func (s *autoJoinState) observeUsers(users []UserInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.channelID == 0 {
+		return
+	}
+	s.occupancy = make(map[int64]int)
+	for _, u := range users {
+		if u.ChannelID != 0 {
+			s.occupancy[u.ChannelID]++
+		}
+	}
+}
+
+// take consumes the pending target against the server's channel list. It
+// returns 0 and no error when nothing is pending, and an error when the
+// channel no longer exists or is full. Either way the target is cleared:
+// auto-join is attempted once per connect.
+func (s *autoJoinState) take(channels []ChannelInfo) (int64, error) {
+	s.mu.Lock()
+	id := s.channelID
+	occupancy := s.occupancy
+	s.channelID = 0
+	s.occupancy = nil
+	s.mu.Unlock()
+
+	if id == 0 {
+		return 0, nil
+	}
+	for _, ch := range channels {
+		if ch.ID != id {
+			continue
+		}
+		if ch.MaxUsers > 0 && occupancy[id] >= ch.MaxUsers {
+			return 0, fmt.Errorf("channel %q is full", ch.Name)
+		}
+		return id, nil
+	}
+	return 0, fmt.Errorf("channel %d no longer exists", id)
+}
+
+// autoJoin handles a channel list for a freshly connected session, joining
+// the configured voice channel if one is pending.
+func (a *App) autoJoin(serverAddr string, channels []ChannelInfo) {
+	id, err := a.autoJoinVoice.take(channels)
+	if err == nil && id == 0 {
+		return
+	}
+	if err == nil {
+		slog.Info("auto-joining voice", "addr", serverAddr, "channel_id", id)
+		// Outside the transport's callback goroutine: ConnectVoice
+		// starts audio and sends on the control connection.
+		go func() {
+			if errMsg := a.ConnectVoice(int(id)); errMsg != "" {
+				a.autoJoinFailed(serverAddr, id, errMsg)
+				return
+			}
+			if a.ctx != nil {
+				wailsrt.EventsEmit(a.ctx, "voice:auto_joined", map[string]any{
+					"server_addr": serverAddr,
+					"channel_id":  id,
+				})
+			}
+		}()
+		return
+	}
+	a.autoJoinFailed(serverAddr, id, err.Error())
+}
+
+func (a *App) autoJoinFailed(serverAddr string, channelID int64, reason string) {
+	slog.Warn("auto-join voice skipped", "addr", serverAddr, "channel_id", channelID, "reason", reason)
+	if a.ctx != nil {
+		wailsrt.EventsEmit(a.ctx, "voice:auto_join_failed", map[string]any{
+			"server_addr": serverAddr,
+			"reason":      reason,
+		})
+	}
+}
+
+// SetAutoJoinVoice sets the voice channel to join automatically after
+// connecting to addr. channelID 0 turns auto-join off for that server.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SetAutoJoinVoice(addr string, channelID int) string {
+	normalized, err := a.normalizedAddr(addr)
+	if err != nil {
+		return err.Error()
+	}
+	if channelID < 0 {
+		return "invalid channel"
+	}
+	cfg := LoadConfig()
+	autoJoin := maps.Clone(cfg.AutoJoinVoice)
+	if autoJoin == nil {
+		autoJoin = make(map[string]int64)
+	}
+	if channelID == 0 {
+		delete(autoJoin, normalized)
+	} else {
+		autoJoin[normalized] = int64(channelID)
+	}
+	cfg.AutoJoinVoice = autoJoin
+	if err := SaveConfig(cfg); err != nil {
+		slog.Error("save auto-join voice failed", "addr", normalized, "err", err)
+		return err.Error()
+	}
+	return ""
+}
+
+// GetAutoJoinVoice returns the auto-join voice channel for addr, or 0.
+func (a *App) GetAutoJoinVoice(addr string) int {
+	normalized, err := a.normalizedAddr(addr)
+	if err != nil {
+		return 0
+	}
+	return int(LoadConfig().AutoJoinVoice[normalized])
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAutoJoinTakeJoinsExistingChannel(t *testing.T) {
+	var s autoJoinState
+	s.arm(2)
+	id, err := s.take([]ChannelInfo{{ID: 1, Name: "Lobby"}, {ID: 2, Name: "Games"}})
+	if err != nil || id != 2 {
+		t.Fatalf("take = %d, %v; want 2, nil", id, err)
+	}
+	// Only attempted once per connect.
+	if id, err := s.take([]ChannelInfo{{ID: 2, Name: "Games"}}); id != 0 || err != nil {
+		t.Fatalf("second take = %d, %v; want 0, nil", id, err)
+	}
+}
+
+func TestAutoJoinTakeNothingPending(t *testing.T) {
+	var s autoJoinState
+	if id, err := s.take([]ChannelInfo{{ID: 1}}); id != 0 || err != nil {
+		t.Fatalf("take = %d, %v; want 0, nil", id, err)
+	}
+}
+
+func TestAutoJoinTakeMissingChannel(t *testing.T) {
+	var s autoJoinState
+	s.arm(9)
+	id, err := s.take([]ChannelInfo{{ID: 1, Name: "Lobby"}})
+	if id != 0 || err == nil || !strings.Contains(err.Error(), "no longer exists") {
+		t.Fatalf("take = %d, %v; want missing-channel error", id, err)
+	}
+}
+
+func TestAutoJoinTakeRespectsMaxUsers(t *testing.T) {
+	var s autoJoinState
+	s.arm(3)
+	s.observeUsers([]UserInfo{{ID: 1, ChannelID: 3}, {ID: 2, ChannelID: 3}, {ID: 4}})
+	id, err := s.take([]ChannelInfo{{ID: 3, Name: "Duo", MaxUsers: 2}})
+	if id != 0 || err == nil || !strings.Contains(err.Error(), "full") {
+		t.Fatalf("take = %d, %v; want full-channel error", id, err)
+	}
+
+	s.arm(3)
+	s.observeUsers([]UserInfo{{ID: 1, ChannelID: 3}})
+	if id, err := s.take([]ChannelInfo{{ID: 3, Name: "Duo", MaxUsers: 2}}); id != 3 || err != nil {
+		t.Fatalf("take = %d, %v; want 3, nil", id, err)
+	}
+}
+
+func TestAutoJoinDisarm(t *testing.T) {
+	var s autoJoinState
+	s.arm(2)
+	s.disarm()
+	if id, err := s.take([]ChannelInfo{{ID: 2}}); id != 0 || err != nil {
+		t.Fatalf("take after disarm = %d, %v; want 0, nil", id, err)
+	}
+}
+
+func TestSetAutoJoinVoicePersists(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	app, _ := newTestApp()
+
+	if errMsg := app.SetAutoJoinVoice("example.com", 4); errMsg != "" {
+		t.Fatalf("SetAutoJoinVoice: %s", errMsg)
+	}
+	// Lookups normalise the address the same way Connect does.
+	if got := app.GetAutoJoinVoice("example.com:8080"); got != 4 {
+		t.Fatalf("GetAutoJoinVoice = %d, want 4", got)
+	}
+	if errMsg := app.SetAutoJoinVoice("example.com", 0); errMsg != "" {
+		t.Fatalf("clear auto-join: %s", errMsg)
+	}
+	if got := app.GetAutoJoinVoice("example.com"); got != 0 {
+		t.Fatalf("GetAutoJoinVoice after clear = %d, want 0", got)
+	}
+	if errMsg := app.SetAutoJoinVoice("example.com", -1); errMsg == "" {
+		t.Fatal("expected error for negative channel")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import "math"
+
+const (
+	// autoLevelTargetRMS is the loudness auto-leveling steers each speaker
+	// toward (about -20 dBFS).
+	autoLevelTargetRMS = 0.1
+	// Auto-level gain bounds (-12 dB to +12 dB), so a whisper isn't blown
+	// up into noise and a shout isn't pushed to nothing.
+	autoLevelMinGain = 0.25
+	autoLevelMaxGain = 4
+	// autoLevelSilenceRMS is the level below which a frame is treated as a
+	// pause and leaves the speaker's estimate alone.
+	autoLevelSilenceRMS = 0.005
+	// autoLevelSmoothing is how far each speech frame moves the level
+	// estimate; at 20 ms frames the gain settles over a second or two.
+	autoLevelSmoothing = 0.05
+)
+
+// autoLeveler tracks one sender's recent speech level for auto-leveling.
+type autoLeveler struct {
+	rms float32 // smoothed level of speech frames
+}
+
+func newAutoLeveler() *autoLeveler {
+	// Starting at the target means unity gain until the sender is heard.
+	return &autoLeveler{rms: autoLevelTargetRMS}
+}
+
+// observe folds a decoded frame into the level estimate and returns the
+// gain that brings the sender toward autoLevelTargetRMS.
+func (l *autoLeveler) observe(pcm []int16) float32 {
+	if len(pcm) > 0 {
+		var sum float64
+		for _, s := range pcm {
+			v := float64(s) / 32768
+			sum += v * v
+		}
+		if rms := float32(math.Sqrt(sum / float64(len(pcm)))); rms >= autoLevelSilenceRMS {
+			l.rms += (rms - l.rms) * autoLevelSmoothing
+		}
+	}
+	return max(autoLevelMinGain, min(autoLevelMaxGain, autoLevelTargetRMS/l.rms))
+}
+
+// SetAutoLevel turns per-speaker auto-leveling on or off. While on, the
+// playback mixer scales each sender toward a common loudness; per-user
+// volume still applies on top as a trim.
+func (ae *AudioEngine) SetAutoLevel(enabled bool) {
+	ae.autoLevel.Store(enabled)
+}
+
+// AutoLevel reports whether per-speaker auto-leveling is on.
+func (ae *AudioEngine) AutoLevel() bool {
+	return ae.autoLevel.Load()
+}
+
+// senderGain returns the playback multiplier for senderID's next frame,
+// pcm: the user's manual volume times the auto-level gain when enabled.
+// levels holds the mixer's per-sender auto-level state.
+func (ae *AudioEngine) senderGain(senderID uint16, pcm []int16, levels map[uint16]*autoLeveler) float32 {
+	gain := float32(1)
+	if ae.UserVolumeFunc != nil {
+		gain = float32(ae.UserVolumeFunc(senderID))
+	}
+	if !ae.autoLevel.Load() {
+		return gain
+	}
+	l, ok := levels[senderID]
+	if !ok {
+		l = newAutoLeveler()
+		levels[senderID] = l
+	}
+	return gain * l.observe(pcm)
+}
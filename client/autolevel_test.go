@@ -0,0 +1,73 @@
+package main
+
+import "testing"
+
+// levelTestFrame returns a frame of alternating ±amp samples (RMS = amp).
+func levelTestFrame(amp float32) []int16 {
+	pcm := make([]int16, FrameSize)
+	for i := range pcm {
+		pcm[i] = int16(amp * 32767)
+		if i%2 == 1 {
+			pcm[i] = -pcm[i]
+		}
+	}
+	return pcm
+}
+
+func TestAutoLevelRaisesQuietSender(t *testing.T) {
+	ae := NewAudioEngine()
+	ae.SetAutoLevel(true)
+	levels := make(map[uint16]*autoLeveler)
+
+	quiet := levelTestFrame(0.02)
+	prev := ae.senderGain(7, quiet, levels)
+	for i := range 30 {
+		g := ae.senderGain(7, quiet, levels)
+		if g <= prev {
+			t.Fatalf("frame %d: gain %v did not rise from %v", i, g, prev)
+		}
+		prev = g
+	}
+	if prev < 1.5 || prev > autoLevelMaxGain {
+		t.Errorf("gain after 30 quiet frames = %v, want in [1.5, %v]", prev, autoLevelMaxGain)
+	}
+
+	// Pauses leave the estimate alone.
+	if g := ae.senderGain(7, make([]int16, FrameSize), levels); g != prev {
+		t.Errorf("silent frame moved gain from %v to %v", prev, g)
+	}
+}
+
+func TestAutoLevelLowersLoudSender(t *testing.T) {
+	ae := NewAudioEngine()
+	ae.SetAutoLevel(true)
+	levels := make(map[uint16]*autoLeveler)
+
+	var g float32
+	for range 30 {
+		g = ae.senderGain(7, levelTestFrame(0.5), levels)
+	}
+	if g >= 1 || g < autoLevelMinGain {
+		t.Errorf("gain after 30 loud frames = %v, want in [%v, 1)", g, autoLevelMinGain)
+	}
+}
+
+func TestAutoLevelAppliesManualVolumeAsTrim(t *testing.T) {
+	ae := NewAudioEngine()
+	ae.UserVolumeFunc = func(uint16) float64 { return 0.5 }
+	levels := make(map[uint16]*autoLeveler)
+	quiet := levelTestFrame(0.02)
+
+	if g := ae.senderGain(7, quiet, levels); g != 0.5 {
+		t.Errorf("gain with auto-level off = %v, want the manual 0.5", g)
+	}
+
+	ae.SetAutoLevel(true)
+	ref := newAutoLeveler()
+	for i := range 10 {
+		want := 0.5 * ref.observe(quiet)
+		if g := ae.senderGain(7, quiet, levels); g != want {
+			t.Fatalf("frame %d: gain = %v, want manual 0.5 times auto %v", i, g, want*2)
+		}
+	}
+}
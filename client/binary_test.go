@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBinaryControlRoundTrip(t *testing.T) {
+	in := `{"emoji":"👍","msg_id":-7,"muted":true,"reactions":[{"count":2,"user_ids":["a","b"]}],"ts":1700000000123,"type":"reaction_update"}`
+	bin, err := jsonToBinary([]byte(in))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	out, err := binaryToJSON(bin)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if string(out) != in {
+		t.Errorf("round trip = %s, want %s", out, in)
+	}
+}
+
+func TestBinaryPingIsSmallerThanJSON(t *testing.T) {
+	js, err := json.Marshal(ControlMsg{Type: "ping", Ts: 1700000000123})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	bin, err := jsonToBinary(js)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	t.Logf("ping: json %d bytes, binary %d bytes", len(js), len(bin))
+	if len(bin) >= len(js)*3/4 {
+		t.Errorf("binary ping is %d bytes, json %d; want at least 25%% smaller", len(bin), len(js))
+	}
+}
+
+func TestBinaryToJSONRejectsTruncated(t *testing.T) {
+	if _, err := binaryToJSON([]byte{0x81, 0xa4, 't', 'y'}); err == nil {
+		t.Error("expected error for truncated frame")
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+const (
+	// Default adaptive bitrate bounds (kbps): the floor still carries
+	// intelligible wideband speech, the ceiling is transparent for voice.
+	defaultBitrateFloorKbps   = 16
+	defaultBitrateCeilingKbps = 64
+
+	opusMinBitrateKbps = 6
+	opusMaxBitrateKbps = 510
+
+	// On a "poor" tick the bitrate drops to 3/4 of its current value;
+	// cutting quickly is what relieves a congested link.
+	bitrateStepDownNum = 3
+	bitrateStepDownDen = 4
+
+	// Recovery is slower: +8 kbps after this many consecutive "good" ticks
+	// (2 × adaptInterval = 10 s), so a brief good patch doesn't bounce the
+	// rate straight back into congestion.
+	bitrateStepUpKbps  = 8
+	bitrateStepUpTicks = 2
+
+	// A step up followed by a "poor" tick within bitrateProbeTicks is taken
+	// as the link not coping with the higher rate: the good streak needed
+	// for the next step up doubles, up to bitrateMaxStepUpTicks (60 s).
+	// This hysteresis stops the rate bouncing between two levels on a link
+	// that sits right at its capacity.
+	bitrateProbeTicks     = 3
+	bitrateMaxStepUpTicks = 12
+)
+
+// bitrateAdapter chooses the next Opus bitrate from the connection quality
+// level each adaptInterval. It is only touched from adaptBitrateLoop.
+type bitrateAdapter struct {
+	goodStreak int
+	// upTicks is the good streak required to step up; 0 means
+	// bitrateStepUpTicks.
+	upTicks int
+	// sinceUp counts ticks since the last step up while it is still on
+	// probation; 0 means no step up is being probed.
+	sinceUp int
+}
+
+// next returns the bitrate to use after a tick with the given quality,
+// clamped to [floor, ceiling].
+func (b *bitrateAdapter) next(current int, quality string, floor, ceiling int) int {
+	if b.upTicks == 0 {
+		b.upTicks = bitrateStepUpTicks
+	}
+	probing := b.sinceUp > 0
+	if probing {
+		b.sinceUp++
+	}
+
+	next := current
+	switch quality {
+	case "poor":
+		b.goodStreak = 0
+		next = current * bitrateStepDownNum / bitrateStepDownDen
+		if probing {
+			b.upTicks = min(b.upTicks*2, bitrateMaxStepUpTicks)
+			b.sinceUp = 0
+		}
+	case "good":
+		b.goodStreak++
+		if b.goodStreak >= b.upTicks && current < ceiling {
+			b.goodStreak = 0
+			next = current + bitrateStepUpKbps
+			b.sinceUp = 1
+		}
+	default:
+		b.goodStreak = 0
+	}
+	// A step up that survived its probation resets the backoff.
+	if b.sinceUp > bitrateProbeTicks {
+		b.upTicks = bitrateStepUpTicks
+		b.sinceUp = 0
+	}
+	if next < floor {
+		next = floor
+	}
+	if next > ceiling {
+		next = ceiling
+	}
+	return next
+}
+
+// SetBitrateRange sets the floor and ceiling (kbps) adaptive bitrate may
+// move between.
+func (ae *AudioEngine) SetBitrateRange(floor, ceiling int) error {
+	if floor < opusMinBitrateKbps || ceiling > opusMaxBitrateKbps || floor > ceiling {
+		return fmt.Errorf("bitrate range must satisfy %d <= floor <= ceiling <= %d kbps", opusMinBitrateKbps, opusMaxBitrateKbps)
+	}
+	ae.bitrateFloor.Store(int32(floor))
+	ae.bitrateCeiling.Store(int32(ceiling))
+	slog.Debug("bitrate range updated", "floor_kbps", floor, "ceiling_kbps", ceiling)
+	return nil
+}
+
+// BitrateRange returns the adaptive bitrate floor and ceiling in kbps.
+func (ae *AudioEngine) BitrateRange() (floor, ceiling int) {
+	return int(ae.bitrateFloor.Load()), int(ae.bitrateCeiling.Load())
+}
+
+// adaptiveBitrateRange returns the bounds adaptive bitrate moves between:
+// the configured range with the ceiling lowered to the channel and
+// packet-size caps where those are tighter. A cap below the floor lowers
+// the floor with it.
+func (ae *AudioEngine) adaptiveBitrateRange() (floor, ceiling int) {
+	floor, ceiling = ae.BitrateRange()
+	if limit := packetLimitKbps(int(ae.maxPacketBytes.Load()), ae.CaptureFrameMs()); limit > 0 {
+		ceiling = min(ceiling, limit)
+	}
+	if limit := ae.ChannelBitrateCap(); limit > 0 {
+		ceiling = min(ceiling, limit)
+	}
+	return min(floor, ceiling), ceiling
+}
+
+// SetChannelBitrateCap caps the Opus bitrate at kbps for the voice channel
+// we are in, lowering the current bitrate if it is above. kbps = 0 removes
+// the cap; the bitrate is not raised again, so callers restore the
+// preferred rate with SetBitrate.
+func (ae *AudioEngine) SetChannelBitrateCap(kbps int) {
+	if kbps < 0 {
+		kbps = 0
+	}
+	ae.channelBitrateCap.Store(int32(kbps))
+	if kbps > 0 && ae.CurrentBitrate() > kbps {
+		ae.SetBitrate(kbps)
+	}
+}
+
+// ChannelBitrateCap returns the current channel bitrate cap in kbps (0 = none).
+func (ae *AudioEngine) ChannelBitrateCap() int {
+	return int(ae.channelBitrateCap.Load())
+}
@@ -0,0 +1,233 @@
+package main
+
+import "testing"
+
+func TestBitrateAdapterStepsDownOnPoor(t *testing.T) {
+	var b bitrateAdapter
+	if got := b.next(64, "poor", 16, 64); got != 48 {
+		t.Errorf("poor from 64: got %d, want 48", got)
+	}
+	// Repeated poor ticks bottom out at the floor.
+	kbps := 64
+	for i := 0; i < 10; i++ {
+		kbps = b.next(kbps, "poor", 16, 64)
+	}
+	if kbps != 16 {
+		t.Errorf("after sustained poor quality: got %d, want floor 16", kbps)
+	}
+}
+
+func TestBitrateAdapterStepsUpAfterStableGood(t *testing.T) {
+	var b bitrateAdapter
+	if got := b.next(32, "good", 16, 64); got != 32 {
+		t.Errorf("first good tick should hold: got %d, want 32", got)
+	}
+	if got := b.next(32, "good", 16, 64); got != 40 {
+		t.Errorf("second good tick should step up: got %d, want 40", got)
+	}
+	// Ceiling is respected.
+	kbps := 40
+	for i := 0; i < 20; i++ {
+		kbps = b.next(kbps, "good", 16, 64)
+	}
+	if kbps != 64 {
+		t.Errorf("after sustained good quality: got %d, want ceiling 64", kbps)
+	}
+}
+
+func TestBitrateAdapterModerateResetsStreak(t *testing.T) {
+	var b bitrateAdapter
+	b.next(32, "good", 16, 64)
+	if got := b.next(32, "moderate", 16, 64); got != 32 {
+		t.Errorf("moderate should hold: got %d, want 32", got)
+	}
+	if got := b.next(32, "good", 16, 64); got != 32 {
+		t.Errorf("good streak should restart after moderate: got %d, want 32", got)
+	}
+}
+
+func TestBitrateAdapterBacksOffAfterFailedStepUp(t *testing.T) {
+	var b bitrateAdapter
+	kbps := 32
+	kbps = b.next(kbps, "good", 16, 64)
+	kbps = b.next(kbps, "good", 16, 64) // steps up to 40
+	kbps = b.next(kbps, "poor", 16, 64) // straight back into loss: 30
+	if kbps != 30 {
+		t.Fatalf("poor after step up: got %d, want 30", kbps)
+	}
+
+	// The next step up now needs twice the good streak.
+	for i := 0; i < 2*bitrateStepUpTicks-1; i++ {
+		if got := b.next(kbps, "good", 16, 64); got != kbps {
+			t.Fatalf("good tick %d during backoff: got %d, want %d", i+1, got, kbps)
+		}
+	}
+	if got := b.next(kbps, "good", 16, 64); got != kbps+bitrateStepUpKbps {
+		t.Fatalf("step up after backoff: got %d, want %d", got, kbps+bitrateStepUpKbps)
+	}
+
+	// Repeated failures never push the wait past the cap.
+	for i := 0; i < 10; i++ {
+		kbps = 32
+		for b.next(kbps, "good", 16, 64) == kbps {
+			// Good ticks until it steps up.
+		}
+		b.next(kbps+bitrateStepUpKbps, "poor", 16, 64)
+	}
+	if b.upTicks != bitrateMaxStepUpTicks {
+		t.Errorf("upTicks after repeated failures: got %d, want %d", b.upTicks, bitrateMaxStepUpTicks)
+	}
+}
+
+func TestBitrateAdapterBackoffResetsAfterStableStepUp(t *testing.T) {
+	b := bitrateAdapter{upTicks: 8}
+	kbps := 32
+	for kbps == 32 {
+		kbps = b.next(kbps, "good", 16, 64)
+	}
+	// Quality holds through the probation window.
+	for i := 0; i < bitrateProbeTicks; i++ {
+		b.next(kbps, "moderate", 16, 64)
+	}
+	if b.upTicks != bitrateStepUpTicks {
+		t.Errorf("upTicks after a stable step up: got %d, want %d", b.upTicks, bitrateStepUpTicks)
+	}
+	// Loss long after the step up is not blamed on it.
+	b.next(kbps, "poor", 16, 64)
+	if b.upTicks != bitrateStepUpTicks {
+		t.Errorf("upTicks after unrelated loss: got %d, want %d", b.upTicks, bitrateStepUpTicks)
+	}
+}
+
+func TestBitrateAdapterClampsIntoRange(t *testing.T) {
+	var b bitrateAdapter
+	if got := b.next(128, "moderate", 16, 64); got != 64 {
+		t.Errorf("above ceiling: got %d, want 64", got)
+	}
+	if got := b.next(8, "moderate", 16, 64); got != 16 {
+		t.Errorf("below floor: got %d, want 16", got)
+	}
+}
+
+func TestSetBitrateRangeValidation(t *testing.T) {
+	ae := NewAudioEngine()
+	if floor, ceiling := ae.BitrateRange(); floor != defaultBitrateFloorKbps || ceiling != defaultBitrateCeilingKbps {
+		t.Errorf("default range: got %d-%d", floor, ceiling)
+	}
+	if err := ae.SetBitrateRange(24, 96); err != nil {
+		t.Fatalf("SetBitrateRange(24, 96): %v", err)
+	}
+	if floor, ceiling := ae.BitrateRange(); floor != 24 || ceiling != 96 {
+		t.Errorf("range after set: got %d-%d, want 24-96", floor, ceiling)
+	}
+	for _, r := range [][2]int{{5, 64}, {16, 511}, {64, 32}} {
+		if err := ae.SetBitrateRange(r[0], r[1]); err == nil {
+			t.Errorf("SetBitrateRange(%d, %d): expected error", r[0], r[1])
+		}
+	}
+	if floor, ceiling := ae.BitrateRange(); floor != 24 || ceiling != 96 {
+		t.Errorf("invalid ranges should be ignored: got %d-%d", floor, ceiling)
+	}
+}
+
+func TestSetAdaptiveBitrateOffRestoresManual(t *testing.T) {
+	app, _ := newTestApp()
+	app.SetAudioBitrate(48)
+	app.SetAdaptiveBitrate(true)
+	app.audio.SetBitrate(16) // as if the adapter had stepped down
+
+	app.SetAdaptiveBitrate(false)
+	if got := app.GetAudioBitrate(); got != 48 {
+		t.Errorf("bitrate after disabling adaptation: got %d, want 48", got)
+	}
+}
+
+func TestChannelBitrateCap(t *testing.T) {
+	ae := NewAudioEngine()
+	ae.SetBitrate(64)
+	ae.SetChannelBitrateCap(32)
+	if got := ae.CurrentBitrate(); got != 32 {
+		t.Fatalf("after cap: got %d kbps, want 32", got)
+	}
+	ae.SetBitrate(64)
+	if got := ae.CurrentBitrate(); got != 32 {
+		t.Errorf("SetBitrate above the cap: got %d kbps, want 32", got)
+	}
+	ae.SetChannelBitrateCap(0)
+	ae.SetBitrate(64)
+	if got := ae.CurrentBitrate(); got != 64 {
+		t.Errorf("after removing the cap: got %d kbps, want 64", got)
+	}
+}
+
+func TestSetAudioBitrateClampedWhileAdaptive(t *testing.T) {
+	app, _ := newTestApp()
+	if msg := app.SetBitrateRange(24, 48); msg != "" {
+		t.Fatalf("SetBitrateRange: %s", msg)
+	}
+	app.SetAudioBitrate(128)
+	if got := app.GetAudioBitrate(); got != 128 {
+		t.Errorf("manual bitrate with adaptation off: got %d, want 128", got)
+	}
+
+	app.SetAdaptiveBitrate(true)
+	if got := app.GetAudioBitrate(); got != 48 {
+		t.Errorf("enabling adaptation: got %d, want clamped to 48", got)
+	}
+	app.SetAudioBitrate(8)
+	if got := app.GetAudioBitrate(); got != 24 {
+		t.Errorf("below the floor: got %d, want 24", got)
+	}
+	app.SetAudioBitrate(96)
+	if got := app.GetAudioBitrate(); got != 48 {
+		t.Errorf("above the ceiling: got %d, want 48", got)
+	}
+	if msg := app.SetBitrateRange(16, 32); msg != "" {
+		t.Fatalf("SetBitrateRange: %s", msg)
+	}
+	if got := app.GetAudioBitrate(); got != 32 {
+		t.Errorf("after lowering the ceiling: got %d, want 32", got)
+	}
+
+	app.SetAdaptiveBitrate(false)
+	if got := app.GetAudioBitrate(); got != 96 {
+		t.Errorf("disabling adaptation: got %d, want the manual 96", got)
+	}
+}
+
+func TestAdaptationRespectsRangeAndChannelCap(t *testing.T) {
+	ae := NewAudioEngine()
+	if err := ae.SetBitrateRange(24, 48); err != nil {
+		t.Fatalf("SetBitrateRange: %v", err)
+	}
+	run := func(quality string, ticks int) int {
+		var b bitrateAdapter
+		for range ticks {
+			floor, ceiling := ae.adaptiveBitrateRange()
+			ae.SetBitrate(b.next(ae.CurrentBitrate(), quality, floor, ceiling))
+		}
+		return ae.CurrentBitrate()
+	}
+
+	ae.SetBitrate(32)
+	if got := run("good", 40); got != 48 {
+		t.Errorf("after good ticks: got %d, want the 48 ceiling", got)
+	}
+	if got := run("poor", 20); got != 24 {
+		t.Errorf("after poor ticks: got %d, want the 24 floor", got)
+	}
+
+	// A channel cap inside the range lowers the ceiling further.
+	ae.SetChannelBitrateCap(32)
+	if floor, ceiling := ae.adaptiveBitrateRange(); floor != 24 || ceiling != 32 {
+		t.Errorf("range under a 32 kbps cap: got %d-%d, want 24-32", floor, ceiling)
+	}
+	if got := run("good", 40); got != 32 {
+		t.Errorf("after good ticks under the cap: got %d, want 32", got)
+	}
+	// A cap below the floor takes precedence.
+	ae.SetChannelBitrateCap(16)
+	if floor, ceiling := ae.adaptiveBitrateRange(); floor != 16 || ceiling != 16 {
+		t.Errorf("range under a 16 kbps cap: got %d-%d, want 16-16", floor, ceiling)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// blockState remembers blocked users by username for each server, since
+// user IDs are handed out afresh on every connection. names maps the
+// current session's IDs to usernames so a block can be saved by name, and
+// so saved blocks are re-applied as users appear. The users whose join and
+// leave sounds are muted are kept the same way.
+type blockState struct {
+	mu      sync.Mutex
+	blocked map[string][]string // server addr → usernames; Config.BlockedUsers
+	names   map[uint16]string
+}
+
+// load replaces the saved blocks, as read from config.
+func (b *blockState) load(saved map[string][]string) {
+	b.mu.Lock()
+	b.blocked = maps.Clone(saved)
+	b.mu.Unlock()
+}
+
+// observe records that id is username in the session on addr and reports
+// whether that username is blocked there.
+func (b *blockState) observe(addr string, id uint16, username string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.names == nil {
+		b.names = make(map[uint16]string)
+	}
+	b.names[id] = username
+	return slices.Contains(b.blocked[addr], username)
+}
+
+// has reports whether id's username is in the set for addr.
+func (b *blockState) has(addr string, id uint16) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	name, ok := b.names[id]
+	return ok && slices.Contains(b.blocked[addr], name)
+}
+
+// forget drops a user who left the session.
+func (b *blockState) forget(id uint16) {
+	b.mu.Lock()
+	delete(b.names, id)
+	b.mu.Unlock()
+}
+
+// set blocks or unblocks id's username on addr and returns the blocks to
+// save.
+func (b *blockState) set(addr string, id uint16, block bool) (map[string][]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	name, ok := b.names[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown user %d", id)
+	}
+	names := slices.DeleteFunc(slices.Clone(b.blocked[addr]), func(n string) bool { return n == name })
+	if block {
+		names = append(names, name)
+	}
+	if b.blocked == nil {
+		b.blocked = make(map[string][]string)
+	}
+	if len(names) == 0 {
+		delete(b.blocked, addr)
+	} else {
+		b.blocked[addr] = names
+	}
+	return maps.Clone(b.blocked), nil
+}
+
+// BlockUser hides a user on the current server: their voice is muted and
+// their chat, typing and reactions are dropped. The block is saved by
+// username, so it applies again on later connections.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) BlockUser(id int) string {
+	return a.setBlocked(id, true)
+}
+
+// UnblockUser lifts a block set by BlockUser.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) UnblockUser(id int) string {
+	return a.setBlocked(id, false)
+}
+
+func (a *App) setBlocked(id int, block bool) string {
+	slog.Debug("setBlocked", "user_id", id, "blocked", block)
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	a.mu.RLock()
+	addr := a.serverAddr
+	a.mu.RUnlock()
+	saved, err := a.blocks.set(addr, uint16(id), block)
+	if err != nil {
+		return err.Error()
+	}
+	if block {
+		tr.BlockUser(uint16(id))
+	} else {
+		tr.UnblockUser(uint16(id))
+	}
+
+	cfg := LoadConfig()
+	cfg.BlockedUsers = saved
+	if err := SaveConfig(cfg); err != nil {
+		slog.Error("save blocked users failed", "user_id", id, "err", err)
+		return err.Error()
+	}
+	return ""
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBlockUserPersistsByUsername(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	app, mt := newTestApp()
+	app.serverAddr = "srv:8080"
+	// As the session's user list reports bob.
+	app.blocks.observe("srv:8080", 5, "bob")
+
+	if errMsg := app.BlockUser(9); errMsg == "" {
+		t.Error("expected an error blocking an unknown user")
+	}
+	if errMsg := app.BlockUser(5); errMsg != "" {
+		t.Fatalf("BlockUser: %s", errMsg)
+	}
+	if !mt.IsBlocked(5) {
+		t.Fatal("bob should be blocked on the transport")
+	}
+	if got := LoadConfig().BlockedUsers["srv:8080"]; !slices.Equal(got, []string{"bob"}) {
+		t.Fatalf("saved blocks = %v, want [bob]", got)
+	}
+
+	// After a restart bob has a new ID, but the block follows his name.
+	app2, mt2 := newTestApp()
+	app2.serverAddr = "srv:8080"
+	app2.ApplyConfig()
+	if !app2.blocks.observe("srv:8080", 12, "bob") {
+		t.Fatal("bob should be blocked after a restart")
+	}
+	if app2.blocks.observe("srv:8080", 13, "carol") || app2.blocks.observe("other:8080", 14, "bob") {
+		t.Fatal("the block should only cover bob on srv:8080")
+	}
+	mt2.BlockUser(12)
+
+	if errMsg := app2.UnblockUser(12); errMsg != "" {
+		t.Fatalf("UnblockUser: %s", errMsg)
+	}
+	if mt2.IsBlocked(12) {
+		t.Error("bob should be unblocked")
+	}
+	if got := LoadConfig().BlockedUsers; len(got) != 0 {
+		t.Errorf("saved blocks = %v, want none", got)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// signalChannelLabel names the data channel each side of a voice peer
+// opens for ephemeral signals.
+const signalChannelLabel = "bken-signals"
+
+// peerSignal is an ephemeral signal sent straight to a voice peer over its
+// data channel: speaking starts and stops, and typing. Anything the server
+// persists, such as reactions, stays on the control websocket.
+type peerSignal struct {
+	Type      string `json:"type"`
+	Speaking  *bool  `json:"speaking,omitempty"`
+	ChannelID string `json:"channel_id,omitempty"` // wire ID, for typing
+	Username  string `json:"username,omitempty"`
+}
+
+// setupSignalChannels opens our signal channel to peer and accepts the
+// peer's. Signals are sent on our channel and received on theirs, so
+// neither side depends on who made the offer. If the channel cannot be
+// created the peer still works; signals keep going over the websocket.
+func (t *Transport) setupSignalChannels(peer *peerState) {
+	dc, err := peer.pc.CreateDataChannel(signalChannelLabel, nil)
+	if err != nil {
+		slog.Warn("create signal channel, using websocket", "remote_id", peer.id, "err", err)
+	} else {
+		dc.OnOpen(func() {
+			peer.mu.Lock()
+			peer.signalsOut = dc
+			peer.mu.Unlock()
+			slog.Debug("signal channel open", "remote_id", peer.id)
+		})
+		dc.OnClose(func() {
+			peer.mu.Lock()
+			if peer.signalsOut == dc {
+				peer.signalsOut = nil
+			}
+			peer.mu.Unlock()
+		})
+	}
+
+	peer.pc.OnDataChannel(func(in *webrtc.DataChannel) {
+		if in.Label() != signalChannelLabel {
+			return
+		}
+		in.OnOpen(func() {
+			peer.mu.Lock()
+			peer.signalsIn = true
+			peer.mu.Unlock()
+		})
+		in.OnClose(func() {
+			peer.mu.Lock()
+			peer.signalsIn = false
+			peer.mu.Unlock()
+		})
+		in.OnMessage(func(msg webrtc.DataChannelMessage) {
+			t.handlePeerSignal(peer.id, msg.Data)
+		})
+	})
+}
+
+// peerSignalsOpen reports whether id's signal channel to us is open. While
+// it is, id sends its signals there too, so the websocket copies relayed by
+// the server are duplicates and are dropped.
+func (t *Transport) peerSignalsOpen(id uint16) bool {
+	t.mu.Lock()
+	peer := t.peers[id]
+	t.mu.Unlock()
+	if peer == nil {
+		return false
+	}
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+	return peer.signalsIn
+}
+
+// sendPeerSignal sends sig to every voice peer whose signal channel is
+// open. A failed send is only logged: the websocket copy still reaches the
+// server, and closing channels are cleared by their OnClose.
+func (t *Transport) sendPeerSignal(sig peerSignal) {
+	data, err := json.Marshal(sig)
+	if err != nil {
+		slog.Error("marshal peer signal", "type", sig.Type, "err", err)
+		return
+	}
+	t.mu.Lock()
+	peers := make([]*peerState, 0, len(t.peers))
+	for _, p := range t.peers {
+		peers = append(peers, p)
+	}
+	t.mu.Unlock()
+
+	for _, p := range peers {
+		p.mu.Lock()
+		dc := p.signalsOut
+		p.mu.Unlock()
+		if dc == nil {
+			continue
+		}
+		if err := dc.SendText(string(data)); err != nil {
+			slog.Debug("send peer signal", "remote_id", p.id, "type", sig.Type, "err", err)
+		}
+	}
+}
+
+// handlePeerSignal delivers a signal received from voice peer id.
+func (t *Transport) handlePeerSignal(id uint16, data []byte) {
+	var sig peerSignal
+	if err := json.Unmarshal(data, &sig); err != nil {
+		slog.Debug("invalid peer signal", "remote_id", id, "err", err)
+		return
+	}
+	t.cbMu.RLock()
+	onSpeaking := t.onSpeaking
+	onUserTyping := t.onUserTyping
+	t.cbMu.RUnlock()
+
+	switch sig.Type {
+	case "speaking":
+		if sig.Speaking != nil && *sig.Speaking {
+			t.notePeerSpeaking(id)
+		}
+		if sig.Speaking != nil && onSpeaking != nil {
+			onSpeaking(id, *sig.Speaking)
+		}
+	case "typing":
+		if channelID := t.localChannelID(sig.ChannelID); channelID != 0 && onUserTyping != nil && !t.IsBlocked(id) {
+			onUserTyping(id, sig.Username, channelID)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pion/webrtc/v4"
+)
+
+func TestHandlePeerSignalSpeaking(t *testing.T) {
+	tr := NewTransport()
+	var gotID uint16
+	var gotSpeaking bool
+	calls := 0
+	tr.SetOnSpeaking(func(id uint16, speaking bool) {
+		gotID, gotSpeaking = id, speaking
+		calls++
+	})
+
+	tr.handlePeerSignal(7, []byte(`{"type":"speaking","speaking":true}`))
+	if calls != 1 || gotID != 7 || !gotSpeaking {
+		t.Fatalf("onSpeaking calls=%d id=%d speaking=%v, want 1 7 true", calls, gotID, gotSpeaking)
+	}
+
+	// A speaking signal without a value is ignored.
+	tr.handlePeerSignal(7, []byte(`{"type":"speaking"}`))
+	if calls != 1 {
+		t.Fatalf("onSpeaking calls = %d after empty signal, want 1", calls)
+	}
+}
+
+func TestHandlePeerSignalTyping(t *testing.T) {
+	tr := NewTransport()
+	var gotName string
+	var gotChannel int64
+	tr.SetOnUserTyping(func(id uint16, username string, channelID int64) {
+		gotName, gotChannel = username, channelID
+	})
+
+	tr.handlePeerSignal(3, []byte(`{"type":"typing","channel_id":"12","username":"bob"}`))
+	if gotName != "bob" || gotChannel != 12 {
+		t.Fatalf("typing = %q in %d, want bob in 12", gotName, gotChannel)
+	}
+}
+
+func TestHandlePeerSignalInvalid(t *testing.T) {
+	tr := NewTransport()
+	tr.SetOnSpeaking(func(uint16, bool) { t.Fatal("onSpeaking called for invalid signal") })
+	tr.handlePeerSignal(1, []byte("not json"))
+	tr.handlePeerSignal(1, []byte(`{"type":"unknown"}`))
+}
+
+func TestPeerSignalsOpenUnknownPeer(t *testing.T) {
+	tr := NewTransport()
+	if tr.peerSignalsOpen(42) {
+		t.Fatal("peerSignalsOpen = true for unknown peer")
+	}
+	tr.mu.Lock()
+	tr.peers[42] = &peerState{id: 42, signalsIn: true}
+	tr.mu.Unlock()
+	if !tr.peerSignalsOpen(42) {
+		t.Fatal("peerSignalsOpen = false with open channel")
+	}
+}
+
+func TestSendPeerSignalSkipsClosedChannels(t *testing.T) {
+	// Peers without an open signal channel are skipped rather than erroring.
+	tr := NewTransport()
+	tr.mu.Lock()
+	tr.peers[1] = &peerState{id: 1}
+	tr.mu.Unlock()
+	speaking := true
+	tr.sendPeerSignal(peerSignal{Type: "speaking", Speaking: &speaking})
+}
+
+// TestSignalRoundTripDataChannelVsWebsocket measures the round trip of a
+// small signal over a loopback data channel and over a loopback websocket
+// echo. It only logs the numbers; loopback timing is too noisy to assert.
+func TestSignalRoundTripDataChannelVsWebsocket(t *testing.T) {
+	if testing.Short() {
+		t.Skip("opens local peer connections")
+	}
+	const rounds = 50
+	payload := `{"type":"speaking","speaking":true}`
+
+	dcRTT := dataChannelRoundTrip(t, payload, rounds)
+	wsRTT := websocketRoundTrip(t, payload, rounds)
+	t.Logf("mean round trip over %d signals: data channel %v, websocket %v", rounds, dcRTT, wsRTT)
+}
+
+func dataChannelRoundTrip(t *testing.T, payload string, rounds int) time.Duration {
+	t.Helper()
+	a, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatalf("new peer connection: %v", err)
+	}
+	defer a.Close()
+	b, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatalf("new peer connection: %v", err)
+	}
+	defer b.Close()
+
+	b.OnDataChannel(func(dc *webrtc.DataChannel) {
+		dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+			_ = dc.SendText(string(msg.Data))
+		})
+	})
+
+	dc, err := a.CreateDataChannel(signalChannelLabel, nil)
+	if err != nil {
+		t.Fatalf("create data channel: %v", err)
+	}
+	opened := make(chan struct{})
+	echoes := make(chan struct{}, 1)
+	dc.OnOpen(func() { close(opened) })
+	dc.OnMessage(func(webrtc.DataChannelMessage) { echoes <- struct{}{} })
+
+	offer, err := a.CreateOffer(nil)
+	if err != nil {
+		t.Fatalf("create offer: %v", err)
+	}
+	aGathered := webrtc.GatheringCompletePromise(a)
+	if err := a.SetLocalDescription(offer); err != nil {
+		t.Fatalf("set offer: %v", err)
+	}
+	<-aGathered
+	if err := b.SetRemoteDescription(*a.LocalDescription()); err != nil {
+		t.Fatalf("remote offer: %v", err)
+	}
+	answer, err := b.CreateAnswer(nil)
+	if err != nil {
+		t.Fatalf("create answer: %v", err)
+	}
+	bGathered := webrtc.GatheringCompletePromise(b)
+	if err := b.SetLocalDescription(answer); err != nil {
+		t.Fatalf("set answer: %v", err)
+	}
+	<-bGathered
+	if err := a.SetRemoteDescription(*b.LocalDescription()); err != nil {
+		t.Fatalf("remote answer: %v", err)
+	}
+
+	select {
+	case <-opened:
+	case <-time.After(10 * time.Second):
+		t.Skip("data channel did not open on loopback")
+	}
+
+	start := time.Now()
+	for i := 0; i < rounds; i++ {
+		if err := dc.SendText(payload); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+		select {
+		case <-echoes:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no echo for signal %d", i)
+		}
+	}
+	return time.Since(start) / time.Duration(rounds)
+}
+
+func websocketRoundTrip(t *testing.T, payload string, rounds int) time.Duration {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			mt, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(mt, data); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	for i := 0; i < rounds; i++ {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(payload)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if _, _, err := conn.ReadMessage(); err != nil {
+			t.Fatalf("read: %v", err)
+		}
+	}
+	return time.Since(start) / time.Duration(rounds)
+}
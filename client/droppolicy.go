@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// Playback drop policies, for when the playback channel is full.
+const (
+	// DropPolicyNewest discards the frame that just arrived.
+	DropPolicyNewest = "newest"
+	// DropPolicyOldest discards the frame that has waited longest, so
+	// the mixer always has the freshest audio.
+	DropPolicyOldest = "oldest"
+)
+
+// SetDropPolicy chooses which frame is lost when received audio arrives
+// faster than playback drains it: DropPolicyNewest (the default) or
+// DropPolicyOldest. Dropping the oldest keeps latency down at the cost of
+// a skip in audio that was already late.
+func (t *Transport) SetDropPolicy(policy string) error {
+	switch policy {
+	case DropPolicyNewest:
+		t.dropOldest.Store(false)
+	case DropPolicyOldest:
+		t.dropOldest.Store(true)
+	default:
+		return fmt.Errorf("drop policy must be %q or %q", DropPolicyNewest, DropPolicyOldest)
+	}
+	return nil
+}
+
+// deliverPlayback queues a received frame for the mixer, applying the drop
+// policy when playbackCh is full.
+func (t *Transport) deliverPlayback(playbackCh chan TaggedAudio, frame TaggedAudio) {
+	select {
+	case playbackCh <- frame:
+		return
+	default:
+	}
+	t.playbackDropped.Add(1)
+	if !t.dropOldest.Load() {
+		return
+	}
+	select {
+	case <-playbackCh:
+	default:
+	}
+	select {
+	case playbackCh <- frame:
+	default:
+		// The mixer is not the only sender; lose this one too.
+		t.playbackDropped.Add(1)
+	}
+}
+
+// SetDropPolicy sets which received frame playback drops under
+// backpressure: "newest" or "oldest". It applies to the current session
+// and every later one.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SetDropPolicy(policy string) string {
+	if policy != DropPolicyNewest && policy != DropPolicyOldest {
+		return fmt.Sprintf("drop policy must be %q or %q", DropPolicyNewest, DropPolicyOldest)
+	}
+	a.dropOldest.Store(policy == DropPolicyOldest)
+	a.mu.RLock()
+	tr := a.transport
+	a.mu.RUnlock()
+	if tr != nil {
+		if err := tr.SetDropPolicy(policy); err != nil {
+			return err.Error()
+		}
+	}
+	slog.Debug("drop policy updated", "policy", policy)
+	return ""
+}
+
+// dropPolicy returns the policy chosen with SetDropPolicy.
+func (a *App) dropPolicy() string {
+	if a.dropOldest.Load() {
+		return DropPolicyOldest
+	}
+	return DropPolicyNewest
+}
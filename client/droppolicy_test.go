@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+// fillPlayback returns a transport hearing user 2 whose playback channel is
+// already full with frames 1 and 2.
+func fillPlayback(t *testing.T, policy string) (*Transport, chan TaggedAudio) {
+	t.Helper()
+	tr := NewTransport()
+	if err := tr.SetDropPolicy(policy); err != nil {
+		t.Fatalf("set drop policy: %v", err)
+	}
+	tr.myChannel.Store(1)
+	tr.userChannels.Store(uint16(2), int64(1))
+	playback := make(chan TaggedAudio, 2)
+	playback <- TaggedAudio{SenderID: 2, Seq: 1}
+	playback <- TaggedAudio{SenderID: 2, Seq: 2}
+	tr.playbackCh = playback
+	return tr, playback
+}
+
+func queuedSeqs(playback chan TaggedAudio) []uint16 {
+	close(playback)
+	var seqs []uint16
+	for a := range playback {
+		seqs = append(seqs, a.Seq)
+	}
+	return seqs
+}
+
+func TestDropOldestKeepsFreshestFrame(t *testing.T) {
+	tr, playback := fillPlayback(t, DropPolicyOldest)
+	tr.handleIncomingAudio(2, 3, []byte{1, 2, 3})
+
+	if seqs := queuedSeqs(playback); len(seqs) != 2 || seqs[0] != 2 || seqs[1] != 3 {
+		t.Errorf("queued seqs = %v, want [2 3]", seqs)
+	}
+	if dropped := tr.playbackDropped.Load(); dropped != 1 {
+		t.Errorf("dropped = %d, want 1", dropped)
+	}
+}
+
+func TestDropNewestKeepsQueuedFrames(t *testing.T) {
+	tr, playback := fillPlayback(t, DropPolicyNewest)
+	tr.handleIncomingAudio(2, 3, []byte{1, 2, 3})
+
+	if seqs := queuedSeqs(playback); len(seqs) != 2 || seqs[0] != 1 || seqs[1] != 2 {
+		t.Errorf("queued seqs = %v, want [1 2]", seqs)
+	}
+	if dropped := tr.playbackDropped.Load(); dropped != 1 {
+		t.Errorf("dropped = %d, want 1", dropped)
+	}
+}
+
+func TestSetDropPolicyRejectsUnknown(t *testing.T) {
+	if err := NewTransport().SetDropPolicy("middle"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"cmp"
+	"log/slog"
+)
+
+const (
+	// dtxVADThreshold is the frame RMS below which input counts as silence.
+	// It sits under the speaking indicator's 0.01 so soft speech still goes
+	// out.
+	dtxVADThreshold = 0.005
+	// dtxFrameBytes is the largest frame libopus emits when its own DTX
+	// has decided a frame carries no signal.
+	dtxFrameBytes = 2
+	// dtxHangoverFrames keeps transmitting for a short while after speech
+	// stops so word endings aren't clipped (10 frames = 200 ms).
+	dtxHangoverFrames = 10
+)
+
+// SetDTX enables or disables discontinuous transmission. With DTX on, the
+// Opus encoder's DTX is enabled for speech and silent frames are not sent
+// at all, so a quiet microphone costs no bandwidth.
+func (ae *AudioEngine) SetDTX(enabled bool) {
+	ae.dtxEnabled.Store(enabled)
+	ae.mu.Lock()
+	if ae.encoder != nil {
+		if err := applySignal(ae.encoder, ae.signalActive, enabled); err != nil {
+			slog.Error("set opus dtx", "enabled", enabled, "err", err)
+		}
+	}
+	ae.mu.Unlock()
+	slog.Debug("dtx updated", "enabled", enabled)
+}
+
+// DTXEnabled reports whether discontinuous transmission is enabled.
+func (ae *AudioEngine) DTXEnabled() bool {
+	return ae.dtxEnabled.Load()
+}
+
+// dtxGate decides per frame whether captured audio is worth sending.
+type dtxGate struct {
+	hangover int // frames still to send after the last non-silent one
+	// hangoverFrames is the hangover length in frames; 0 means
+	// dtxHangoverFrames. Set it for frames other than 20 ms.
+	hangoverFrames int
+}
+
+// transmit reports whether a frame with the given input level and encoded
+// size should be sent. A frame is silent if it is below the VAD threshold
+// or the encoder has already reduced it to a DTX frame; silent frames are
+// sent only during the hangover after speech.
+func (g *dtxGate) transmit(rms float32, encodedBytes int) bool {
+	if rms >= dtxVADThreshold && encodedBytes > dtxFrameBytes {
+		g.hangover = cmp.Or(g.hangoverFrames, dtxHangoverFrames)
+		return true
+	}
+	if g.hangover > 0 {
+		g.hangover--
+		return true
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"gopkg.in/hraban/opus.v2"
+)
+
+func TestDTXGateHangover(t *testing.T) {
+	var g dtxGate
+	if g.transmit(0, 1) {
+		t.Fatal("silence before any speech should not be sent")
+	}
+	if !g.transmit(0.2, 80) {
+		t.Fatal("speech should be sent")
+	}
+	for i := 0; i < dtxHangoverFrames; i++ {
+		if !g.transmit(0, 1) {
+			t.Fatalf("silent frame %d inside the hangover was dropped", i)
+		}
+	}
+	if g.transmit(0, 1) {
+		t.Fatal("silence after the hangover should not be sent")
+	}
+	// A loud frame the encoder still reduced to DTX counts as silence.
+	if g.transmit(0.2, dtxFrameBytes) {
+		t.Fatal("encoder DTX frame should not be sent")
+	}
+}
+
+// dtxBytesSent encodes one second of frames from gen with DTX on and
+// returns how many bytes the gate lets through.
+func dtxBytesSent(t *testing.T, gen func(i int) float64) int {
+	t.Helper()
+	enc, err := opus.NewEncoder(sampleRate, channels, opus.AppVoIP)
+	if err != nil {
+		t.Fatalf("new encoder: %v", err)
+	}
+	if err := applySignal(enc, SignalVoice, true); err != nil {
+		t.Fatalf("apply signal: %v", err)
+	}
+	if err := enc.SetBitrate(opusBitrate); err != nil {
+		t.Fatalf("set bitrate: %v", err)
+	}
+
+	var gate dtxGate
+	pcm := make([]int16, FrameSize)
+	samples := make([]float32, FrameSize)
+	out := make([]byte, opusMaxPacketBytes)
+	sent := 0
+	for frame := 0; frame < 50; frame++ {
+		for i := range pcm {
+			v := gen(i + frame*FrameSize)
+			samples[i] = float32(v)
+			pcm[i] = int16(v * 32767)
+		}
+		n, err := enc.Encode(pcm, out)
+		if err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+		if gate.transmit(frameRMS(samples), n) {
+			sent += n
+		}
+	}
+	return sent
+}
+
+func TestDTXSilenceSendsNearZeroBytes(t *testing.T) {
+	silent := dtxBytesSent(t, func(int) float64 { return 0 })
+	// A rich multi-tone keeps the encoder near its target bitrate.
+	tone := dtxBytesSent(t, func(i int) float64 {
+		x := float64(i)
+		return (math.Sin(x*0.37) + math.Sin(x*1.91) + math.Sin(x*2.73)) * 0.27
+	})
+
+	// One second at 32 kbps is 4000 bytes; VBR may undershoot somewhat.
+	if tone < 2000 {
+		t.Errorf("tone sent %d bytes in 1 s, want roughly %d", tone, opusBitrate/8)
+	}
+	if silent > 50 {
+		t.Errorf("silence sent %d bytes in 1 s, want near zero", silent)
+	}
+}
+
+func TestSetDTXAppliesToVoiceEncoder(t *testing.T) {
+	ae := NewAudioEngine()
+	if !ae.DTXEnabled() {
+		t.Fatal("DTX should be on by default")
+	}
+	enc := &signalEncoder{}
+	ae.encoder = enc
+
+	ae.SetDTX(false)
+	if enc.dtx {
+		t.Error("encoder DTX still on after SetDTX(false)")
+	}
+	ae.SetDTX(true)
+	if !enc.dtx {
+		t.Error("encoder DTX off after SetDTX(true)")
+	}
+
+	// Music never uses DTX, whatever the setting.
+	if err := ae.SetSignalType(SignalMusic); err != nil {
+		t.Fatalf("SetSignalType(music): %v", err)
+	}
+	ae.SetDTX(true)
+	if enc.dtx {
+		t.Error("encoder DTX on for music")
+	}
+}
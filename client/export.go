@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	wailsrt "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// exportTimeout bounds how long a channel export download may take.
+const exportTimeout = 2 * time.Minute
+
+// ExportChannel downloads a transcript of channelID's stored chat from the
+// server into w, as "json" or "md". It authenticates with this session's
+// token, so it works only once the snapshot has arrived.
+func (t *Transport) ExportChannel(ctx context.Context, channelID int64, format string, w io.Writer) error {
+	t.mu.Lock()
+	base, token := t.apiBaseURL, t.sessionToken
+	t.mu.Unlock()
+	if base == "" || token == "" {
+		return fmt.Errorf("server API not available")
+	}
+	u := fmt.Sprintf("%s/api/channels/%s/export?format=%s", base, url.PathEscape(t.wireChannelID(channelID)), url.QueryEscape(format))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("export failed (%d): %s", resp.StatusCode, body)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// ExportChannel saves a transcript of a channel's stored chat, as "json" or
+// "md", to a file picked in the native save dialog. It returns the path
+// written, or "" when the dialog is cancelled.
+func (a *App) ExportChannel(channelID int64, format string) (string, error) {
+	if format != "json" && format != "md" {
+		return "", fmt.Errorf("format must be json or md")
+	}
+	tr, err := a.requireTransport()
+	if err != nil {
+		return "", err
+	}
+	path, err := wailsrt.SaveFileDialog(a.ctx, wailsrt.SaveDialogOptions{
+		Title:           "Export Channel",
+		DefaultFilename: fmt.Sprintf("channel-%d.%s", channelID, format),
+	})
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", nil // user cancelled
+	}
+	if err := exportChannelTo(tr, channelID, format, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// exportChannelTo downloads the transcript to path. It is written beside
+// path first and renamed into place, so a failed download never leaves a
+// truncated transcript behind.
+func exportChannelTo(tr Transporter, channelID int64, format, path string) error {
+	tmp := path + ".part"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	err = tr.ExportChannel(ctx, channelID, format, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	slog.Info("channel exported", "channel_id", channelID, "format", format, "path", path)
+	return nil
+}
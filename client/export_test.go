@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTransportExportChannelSendsSessionToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, "invalid session token", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/api/channels/chan-a/export" || r.URL.Query().Get("format") != "md" {
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte("# Channel chan-a transcript\n"))
+	}))
+	defer srv.Close()
+
+	tr := NewTransport()
+	var out strings.Builder
+	if err := tr.ExportChannel(context.Background(), 1, "md", &out); err == nil {
+		t.Fatal("expected an error before the snapshot")
+	}
+
+	tr.apiBaseURL = srv.URL
+	tr.sessionToken = "tok"
+	id := tr.localChannelID("chan-a")
+	if err := tr.ExportChannel(context.Background(), id, "md", &out); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if out.String() != "# Channel chan-a transcript\n" {
+		t.Errorf("body = %q", out.String())
+	}
+
+	tr.sessionToken = "stale"
+	if err := tr.ExportChannel(context.Background(), id, "md", &out); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected a 401 error, got %v", err)
+	}
+}
+
+func TestExportChannelToWritesFileOnlyOnSuccess(t *testing.T) {
+	_, mt := newTestApp()
+	dir := t.TempDir()
+
+	mt.exportBody = `[{"id":1}]`
+	path := filepath.Join(dir, "channel-1.json")
+	if err := exportChannelTo(mt, 1, "json", path); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != `[{"id":1}]` {
+		t.Fatalf("exported file = %q, %v", data, err)
+	}
+
+	// A download cut off part way leaves nothing behind.
+	mt.exportBody, mt.exportErr = `[{"id":1},`, errors.New("connection reset")
+	failed := filepath.Join(dir, "channel-2.json")
+	if err := exportChannelTo(mt, 2, "json", failed); err == nil {
+		t.Fatal("expected the download error")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "channel-1.json" {
+		t.Errorf("files left after a failed export: %v", entries)
+	}
+}
+
+func TestAppExportChannelRejectsUnknownFormat(t *testing.T) {
+	app, _ := newTestApp()
+	if _, err := app.ExportChannel(1, "pdf"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
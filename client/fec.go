@@ -0,0 +1,52 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+)
+
+// fecMinLossPercent is the loss estimate the encoder is given while FEC is on
+// and the link looks clean. Opus only embeds redundancy when told to expect
+// some loss, so a floor keeps FEC ready for the first dropped packet.
+const fecMinLossPercent = 5
+
+// SetFEC enables or disables Opus in-band forward error correction. With FEC
+// on, each packet carries a low-bitrate copy of the previous frame so a
+// single lost packet can be reconstructed by the receiver.
+func (ae *AudioEngine) SetFEC(enabled bool) {
+	ae.fecEnabled.Store(enabled)
+	ae.mu.Lock()
+	if ae.encoder != nil {
+		if err := ae.encoder.SetInBandFEC(enabled); err != nil {
+			slog.Error("set opus fec", "enabled", enabled, "err", err)
+		}
+	}
+	ae.mu.Unlock()
+	slog.Debug("fec updated", "enabled", enabled)
+}
+
+// FECEnabled reports whether in-band FEC is enabled.
+func (ae *AudioEngine) FECEnabled() bool {
+	return ae.fecEnabled.Load()
+}
+
+// fecLossPercent converts a measured loss fraction (0.0–1.0) into the
+// expected packet loss percentage given to the encoder, never below
+// fecMinLossPercent.
+func fecLossPercent(loss float64) int {
+	pct := int(math.Round(loss * 100))
+	if pct < fecMinLossPercent {
+		return fecMinLossPercent
+	}
+	if pct > 100 {
+		return 100
+	}
+	return pct
+}
+
+// fecRecoverable reports whether the frame before seq was lost and can be
+// rebuilt from seq's FEC data. Opus FEC only covers the immediately preceding
+// frame, so exactly one missing sequence number qualifies.
+func fecRecoverable(prev, seq uint16) bool {
+	return seq-prev == 2
+}
@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestFECLossPercent(t *testing.T) {
+	cases := []struct {
+		loss float64
+		want int
+	}{
+		{0, fecMinLossPercent},
+		{0.02, fecMinLossPercent},
+		{0.08, 8},
+		{0.126, 13},
+		{1.5, 100},
+	}
+	for _, c := range cases {
+		if got := fecLossPercent(c.loss); got != c.want {
+			t.Errorf("fecLossPercent(%v) = %d, want %d", c.loss, got, c.want)
+		}
+	}
+}
+
+func TestFECRecoverable(t *testing.T) {
+	cases := []struct {
+		prev, seq uint16
+		want      bool
+	}{
+		{10, 11, false}, // in order
+		{10, 12, true},  // one frame lost
+		{10, 13, false}, // two lost: FEC only covers the previous frame
+		{10, 10, false}, // duplicate
+		{65535, 1, true},
+		{65534, 0, true},
+	}
+	for _, c := range cases {
+		if got := fecRecoverable(c.prev, c.seq); got != c.want {
+			t.Errorf("fecRecoverable(%d, %d) = %v, want %v", c.prev, c.seq, got, c.want)
+		}
+	}
+}
+
+func TestSetFEC(t *testing.T) {
+	ae := NewAudioEngine()
+	if !ae.FECEnabled() {
+		t.Fatal("FEC should default to enabled")
+	}
+	ae.SetFEC(false)
+	if ae.FECEnabled() {
+		t.Fatal("FEC should be disabled")
+	}
+}
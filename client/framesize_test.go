@@ -0,0 +1,158 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"gopkg.in/hraban/opus.v2"
+)
+
+func TestSetCaptureFrameMs(t *testing.T) {
+	ae := NewAudioEngine()
+	if got := ae.CaptureFrameMs(); got != 20 {
+		t.Fatalf("default frame size = %d ms, want 20", got)
+	}
+	for _, ms := range []int{10, 20, 40, 60} {
+		if err := ae.SetCaptureFrameMs(ms); err != nil {
+			t.Errorf("SetCaptureFrameMs(%d): %v", ms, err)
+		}
+		if got := ae.captureFrameSamples(); got != sampleRate*ms/1000 {
+			t.Errorf("%d ms frames hold %d samples", ms, got)
+		}
+	}
+	if err := ae.SetCaptureFrameMs(30); err == nil {
+		t.Error("expected an error for a 30 ms frame")
+	}
+	if got := ae.CaptureFrameMs(); got != 60 {
+		t.Errorf("rejected size changed the frame size to %d ms", got)
+	}
+}
+
+func TestCaptureFrameMsTightensPacketCap(t *testing.T) {
+	ae := NewAudioEngine()
+	ae.SetBitrate(32)
+	// 100 bytes per 20 ms frame allows 40 kbps; per 60 ms frame, 13 kbps.
+	if err := ae.SetMaxPacketBytes(100); err != nil {
+		t.Fatalf("set max packet bytes: %v", err)
+	}
+	if got := ae.CurrentBitrate(); got != 32 {
+		t.Fatalf("bitrate = %d, want 32 under the 20 ms cap", got)
+	}
+	if err := ae.SetCaptureFrameMs(60); err != nil {
+		t.Fatalf("set frame size: %v", err)
+	}
+	if got := ae.CurrentBitrate(); got != 13 {
+		t.Errorf("bitrate = %d, want 13 under the 60 ms cap", got)
+	}
+}
+
+func TestOpusPacketDuration(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		pkt  []byte
+		want time.Duration
+	}{
+		{"empty", nil, 0},
+		{"silk 20ms", []byte{0x08}, 20 * time.Millisecond},
+		{"silk 60ms", []byte{0x18}, 60 * time.Millisecond},
+		{"silk 10ms x2", []byte{0x01, 0xaa}, 20 * time.Millisecond},
+		{"hybrid 20ms", []byte{0x68}, 20 * time.Millisecond},
+		{"celt 2.5ms", []byte{0x80}, 2500 * time.Microsecond},
+		{"celt 10ms", []byte{0x90}, 10 * time.Millisecond},
+		{"celt 20ms x2", []byte{0x99}, 40 * time.Millisecond},
+		{"celt 20ms x3", []byte{0x9b, 0x03}, 60 * time.Millisecond},
+		{"code 3 without count", []byte{0x9b}, 0},
+	} {
+		if got := opusPacketDuration(tt.pkt); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEncodedPacketsCarryFrameSize(t *testing.T) {
+	enc, err := opus.NewEncoder(sampleRate, channels, opus.AppVoIP)
+	if err != nil {
+		t.Fatalf("new encoder: %v", err)
+	}
+	out := make([]byte, opusMaxPacketBytes)
+	for _, ms := range captureFrameSizes {
+		pcm := make([]int16, sampleRate*ms/1000)
+		for i := range pcm {
+			pcm[i] = int16(8000 * math.Sin(float64(i)/8))
+		}
+		n, err := enc.Encode(pcm, out)
+		if err != nil {
+			t.Fatalf("encode %d ms: %v", ms, err)
+		}
+		if got := opusPacketDuration(out[:n]); got != time.Duration(ms)*time.Millisecond {
+			t.Errorf("%d ms frame encoded as a %v packet", ms, got)
+		}
+	}
+}
+
+func TestScaleFrameCount(t *testing.T) {
+	for _, tt := range []struct{ frames, samples, want int }{
+		{10, FrameSize, 10},
+		{10, FrameSize / 2, 20},
+		{10, 3 * FrameSize, 4},
+	} {
+		if got := scaleFrameCount(tt.frames, tt.samples); got != tt.want {
+			t.Errorf("scaleFrameCount(%d, %d) = %d, want %d", tt.frames, tt.samples, got, tt.want)
+		}
+	}
+}
+
+func TestIncomingAudioFrameSizeChangeMidSession(t *testing.T) {
+	tr := NewTransport()
+	tr.myChannel.Store(1)
+	tr.userChannels.Store(uint16(2), int64(1))
+
+	// 20 ms packets, then the sender switches to 60 ms packets. Sequence
+	// numbers stay contiguous, so nothing is lost, and packets arriving
+	// 60 ms apart are on time rather than jittery.
+	silk20, silk60 := []byte{0x08, 0}, []byte{0x18, 0}
+	for seq := uint16(1); seq <= 3; seq++ {
+		tr.handleIncomingAudio(2, seq, silk20)
+		time.Sleep(20 * time.Millisecond)
+	}
+	for seq := uint16(4); seq <= 10; seq++ {
+		time.Sleep(60 * time.Millisecond)
+		tr.handleIncomingAudio(2, seq, silk60)
+	}
+	if lost := tr.peerStats[2].lost; lost != 0 {
+		t.Fatalf("lost = %d across a frame size change, want 0", lost)
+	}
+	if got := tr.peerStats[2].expected; got != 9 {
+		t.Errorf("expected packets = %d, want 9", got)
+	}
+	if jitter := tr.peerStats[2].jitterMs; jitter > 8 {
+		t.Errorf("jitter = %.1f ms for on-time 60 ms packets", jitter)
+	}
+
+	// A real gap is still counted after the change.
+	tr.handleIncomingAudio(2, 13, silk60)
+	if lost := tr.peerStats[2].lost; lost != 2 {
+		t.Fatalf("lost = %d after skipping two sequence numbers, want 2", lost)
+	}
+}
+
+func TestSetFrameSizePersists(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	app, _ := newTestApp()
+	if msg := app.SetFrameSize(15); msg == "" {
+		t.Error("expected an error for a 15 ms frame")
+	}
+	if msg := app.SetFrameSize(40); msg != "" {
+		t.Fatalf("SetFrameSize: %s", msg)
+	}
+	if got := LoadConfig().CaptureFrameMs; got != 40 {
+		t.Errorf("saved frame size = %d, want 40", got)
+	}
+
+	app2, _ := newTestApp()
+	app2.ApplyConfig()
+	if got := app2.audio.CaptureFrameMs(); got != 40 {
+		t.Errorf("after ApplyConfig: frame size = %d, want 40", got)
+	}
+}
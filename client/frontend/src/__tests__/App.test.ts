@@ -259,6 +259,25 @@ describe('App', () => {
     expect(toasts.value.some(t => t.message.includes('Disconnected by server owner'))).toBe(true)
   })
 
+  it('tells us when a moderator mutes us', async () => {
+    mount(App)
+    await flushPromises()
+    emitWailsEvent('user:server_muted', { user_id: 2, muted: true, self: false })
+    emitWailsEvent('user:server_muted', { user_id: 1, muted: true, self: true })
+    await flushPromises()
+    const { toasts } = useToast()
+    expect(toasts.value.filter(t => t.message === 'A moderator muted you')).toHaveLength(1)
+  })
+
+  it('shows the kick reason from connection:kicked', async () => {
+    mount(App)
+    await flushPromises()
+    emitWailsEvent('connection:kicked', { server_addr: 'localhost:8080', reason: 'cool off' })
+    await flushPromises()
+    const { toasts } = useToast()
+    expect(toasts.value.some(t => t.message === 'Disconnected by server owner: cool off')).toBe(true)
+  })
+
   it('handles chat:message_edited event', async () => {
     const w = mount(App)
     await flushPromises()
@@ -525,9 +544,9 @@ describe('App', () => {
     const channel = w.findComponent({ name: 'ChannelView' })
     channel.vm.$emit('connect', { username: 'TestUser', addr: 'localhost:8080' })
     await flushPromises()
-    channel.vm.$emit('kickUser', 99)
+    channel.vm.$emit('kickUser', 99, 'spam')
     await flushPromises()
-    expect(go.KickUser).toHaveBeenCalledWith(99)
+    expect(go.KickUser).toHaveBeenCalledWith(99, 'spam')
   })
 
   it('handles startVideo event', async () => {
@@ -1,6 +1,7 @@
 import { describe, it, expect, vi, beforeEach, afterEach } from 'vitest'
 import { mount, flushPromises } from '@vue/test-utils'
 import AudioDeviceSettings from '../AudioDeviceSettings.vue'
+import { getGoMock } from './setup'
 
 describe('AudioDeviceSettings', () => {
   beforeEach(() => {
@@ -70,4 +71,19 @@ describe('AudioDeviceSettings', () => {
     expect(w.text()).toContain('Mic Level')
     expect(w.find('[aria-label="Audio bitrate"]').exists()).toBe(true)
   })
+
+  it('keeps the previous speaker when switching fails', async () => {
+    const go = getGoMock()
+    go.GetOutputDevices.mockResolvedValueOnce([{ id: 4, name: 'USB Headset' }])
+    const w = mount(AudioDeviceSettings)
+    await flushPromises()
+
+    go.SetOutputDevice.mockResolvedValueOnce('device unavailable')
+    const select = w.find('[aria-label="Speaker device"]')
+    await select.setValue('4')
+    await flushPromises()
+
+    expect((select.element as HTMLSelectElement).value).toBe('-1')
+    expect(w.text()).toContain('device unavailable')
+  })
 })
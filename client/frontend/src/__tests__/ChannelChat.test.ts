@@ -38,6 +38,14 @@ describe('ChannelChat', () => {
     expect(w.text()).toContain('No messages in this channel yet')
   })
 
+  it('shows the selected channel slow mode', async () => {
+    const channels: Channel[] = [{ id: 1, name: 'general', slow_mode_seconds: 30 }, { id: 2, name: 'random' }]
+    const w = mount(ChannelChat, { props: { ...baseProps, channels, selectedChannelId: 1 } })
+    expect(w.text()).toContain('slow mode 30s')
+    await w.setProps({ selectedChannelId: 2 })
+    expect(w.text()).not.toContain('slow mode')
+  })
+
   it('shows disconnected message when not connected', () => {
     const w = mount(ChannelChat, { props: { ...baseProps, connected: false } })
     expect(w.text()).toContain('Connect to a server to start chatting')
@@ -168,6 +176,20 @@ describe('ChannelChat', () => {
     expect(w.text()).toContain('2')
   })
 
+  it('limits the reaction picker to the allowed emoji', async () => {
+    const messages = [makeMsg()]
+    const w = mount(ChannelChat, { props: { ...baseProps, messages, allowedEmoji: ['🦀', '🎉'] } })
+    await w.find('button[title="React"]').trigger('click')
+    const picks = w.findAll('button.text-base').map(b => b.text())
+    expect(picks).toEqual(['🦀', '🎉'])
+  })
+
+  it('offers the common emoji without an allow-list', async () => {
+    const w = mount(ChannelChat, { props: { ...baseProps, messages: [makeMsg()] } })
+    await w.find('button[title="React"]').trigger('click')
+    expect(w.findAll('button.text-base').map(b => b.text())).toContain('🔥')
+  })
+
   it('renders file attachment link', () => {
     const messages = [
       makeMsg({
@@ -26,6 +26,7 @@ describe('ChannelView', () => {
     showSystemMessages: true,
     servers: [{ name: 'Local Dev', addr: 'localhost:8080' }],
     userVoiceFlags: {} as Record<number, { muted: boolean; deafened: boolean }>,
+    whisperTarget: 0,
   }
 
   it('mounts without errors', async () => {
@@ -137,9 +138,65 @@ describe('ChannelView', () => {
     const w = mount(ChannelView, { props: baseProps })
     await flushPromises()
     const sc = w.findComponent({ name: 'ServerChannels' })
-    sc.vm.$emit('kickUser', 42)
+    sc.vm.$emit('kickUser', 42, 'spam')
     await flushPromises()
-    expect(w.emitted('kickUser')).toEqual([[42]])
+    expect(w.emitted('kickUser')).toEqual([[42, 'spam']])
+  })
+
+  it('emits setSlowMode from ServerChannels', async () => {
+    const w = mount(ChannelView, { props: baseProps })
+    await flushPromises()
+    const sc = w.findComponent({ name: 'ServerChannels' })
+    sc.vm.$emit('setSlowMode', 3, 30)
+    await flushPromises()
+    expect(w.emitted('setSlowMode')).toEqual([[3, 30]])
+  })
+
+  it('emits setChannelLock from ServerChannels', async () => {
+    const w = mount(ChannelView, { props: baseProps })
+    await flushPromises()
+    const sc = w.findComponent({ name: 'ServerChannels' })
+    sc.vm.$emit('setChannelLock', 3, true)
+    await flushPromises()
+    expect(w.emitted('setChannelLock')).toEqual([[3, true]])
+  })
+
+  it('emits muteUser and unmuteUser from ServerChannels', async () => {
+    const w = mount(ChannelView, { props: baseProps })
+    await flushPromises()
+    const sc = w.findComponent({ name: 'ServerChannels' })
+    sc.vm.$emit('muteUser', 42, 300)
+    sc.vm.$emit('unmuteUser', 42)
+    await flushPromises()
+    expect(w.emitted('muteUser')).toEqual([[42, 300]])
+    expect(w.emitted('unmuteUser')).toEqual([[42]])
+  })
+
+  it('emits purgeMessages from ServerChannels', async () => {
+    const w = mount(ChannelView, { props: baseProps })
+    await flushPromises()
+    const sc = w.findComponent({ name: 'ServerChannels' })
+    sc.vm.$emit('purgeMessages', 3, 50)
+    await flushPromises()
+    expect(w.emitted('purgeMessages')).toEqual([[3, 50]])
+  })
+
+  it('emits setStatus from ServerChannels', async () => {
+    const w = mount(ChannelView, { props: baseProps })
+    await flushPromises()
+    const sc = w.findComponent({ name: 'ServerChannels' })
+    sc.vm.$emit('setStatus', 'dnd')
+    await flushPromises()
+    expect(w.emitted('setStatus')).toEqual([['dnd']])
+  })
+
+  it('emits transferOwner from ServerChannels', async () => {
+    const w = mount(ChannelView, { props: baseProps })
+    await flushPromises()
+    const sc = w.findComponent({ name: 'ServerChannels' })
+    sc.vm.$emit('transferOwner', 42)
+    await flushPromises()
+    expect(w.emitted('transferOwner')).toEqual([[42]])
   })
 
   it('emits editMessage from channel chat', async () => {
@@ -0,0 +1,30 @@
+import { describe, it, expect } from 'vitest'
+import { mount } from '@vue/test-utils'
+import RecordingBanner from '../RecordingBanner.vue'
+
+describe('RecordingBanner', () => {
+  it('names who is recording', () => {
+    const w = mount(RecordingBanner, { props: { recorders: ['alice'], needsConsent: false } })
+    expect(w.text()).toContain('alice is recording this channel')
+    expect(w.findAll('button')).toHaveLength(0)
+  })
+
+  it('lists several recorders', () => {
+    const w = mount(RecordingBanner, { props: { recorders: ['alice', 'bob'], needsConsent: false } })
+    expect(w.text()).toContain('alice, bob are recording')
+  })
+
+  it('asks for consent when required', async () => {
+    const w = mount(RecordingBanner, { props: { recorders: ['alice'], needsConsent: true } })
+    expect(w.text()).toContain('muted until you accept')
+    await w.find('[aria-label="Accept recording"]').trigger('click')
+    await w.find('[aria-label="Decline recording and leave voice"]').trigger('click')
+    expect(w.emitted('accept')).toHaveLength(1)
+    expect(w.emitted('decline')).toHaveLength(1)
+  })
+
+  it('has role=alert', () => {
+    const w = mount(RecordingBanner, { props: { recorders: ['alice'], needsConsent: false } })
+    expect(w.find('[role="alert"]').exists()).toBe(true)
+  })
+})
@@ -1,7 +1,7 @@
 import { describe, it, expect } from 'vitest'
 import { mount } from '@vue/test-utils'
 import ServerChannels from '../ServerChannels.vue'
-import type { Channel, User } from '../types'
+import type { Category, Channel, User } from '../types'
 
 const stubs = { global: { stubs: { teleport: true } } }
 
@@ -26,6 +26,7 @@ describe('ServerChannels', () => {
     muted: false,
     deafened: false,
     userVoiceFlags: {} as Record<number, { muted: boolean; deafened: boolean }>,
+    whisperTarget: 0,
   }
 
   it('mounts without errors', () => {
@@ -233,4 +234,44 @@ describe('ServerChannels', () => {
     const listItems = channelList.findAll('li')
     expect(listItems.length).toBe(0)
   })
+
+  it('shows away and dnd next to usernames', () => {
+    const users: User[] = [
+      { id: 1, username: 'Alice', status: 'away' },
+      { id: 2, username: 'Bob', status: 'dnd' },
+    ]
+    const w = mount(ServerChannels, { props: { ...baseProps, users }, ...stubs })
+    expect(w.text()).toContain('away')
+    expect(w.text()).toContain('DND')
+  })
+
+  it('emits setStatus from the status menu on ourselves', async () => {
+    const w = mount(ServerChannels, { props: baseProps, ...stubs })
+    const me = w.findAll('button').find(b => b.text().includes('Alice'))!
+    await me.trigger('contextmenu')
+    const away = w.findAll('a').find(a => a.text() === 'Away')!
+    await away.trigger('click')
+    expect(w.emitted('setStatus')).toEqual([['away']])
+  })
+
+  it('groups categorized channels under their category heading', () => {
+    const channels: Channel[] = [
+      { id: 1, name: 'Raid', category_id: 7, category_name: 'Games' },
+      { id: 2, name: 'General' },
+    ]
+    const categories: Category[] = [{ id: 7, name: 'Games' }]
+    const w = mount(ServerChannels, { props: { ...baseProps, channels, categories }, ...stubs })
+    const text = w.find('ul.flex-1').text()
+    expect(text.indexOf('General')).toBeLessThan(text.indexOf('Games'))
+    expect(text.indexOf('Games')).toBeLessThan(text.indexOf('Raid'))
+  })
+
+  it('emits assignChannelCategory from the owner channel menu', async () => {
+    const categories: Category[] = [{ id: 7, name: 'Games' }]
+    const w = mount(ServerChannels, { props: { ...baseProps, isOwner: true, categories }, ...stubs })
+    await w.find('ul.flex-1 a').trigger('contextmenu')
+    const games = w.findAll('a').find(a => a.text() === 'Games')!
+    await games.trigger('click')
+    expect(w.emitted('assignChannelCategory')).toEqual([[1, 7]])
+  })
 })
@@ -69,6 +69,7 @@ const defaultChannelViewProps = () => ({
   showSystemMessages: true,
   servers: [{ name: 'Local Dev', addr: 'localhost:8080' }],
   userVoiceFlags: {} as Record<number, { muted: boolean; deafened: boolean }>,
+  whisperTarget: 0,
 })
 
 const defaultChannelChatProps = () => ({
@@ -104,6 +105,7 @@ const defaultServerChannelsProps = () => ({
   muted: false,
   deafened: false,
   userVoiceFlags: {} as Record<number, { muted: boolean; deafened: boolean }>,
+  whisperTarget: 0,
 })
 
 beforeEach(() => {
@@ -162,6 +162,7 @@ const goApp: Record<string, any> = {
   Connect: vi.fn().mockResolvedValue(''),
   Disconnect: vi.fn().mockResolvedValue(undefined),
   DisconnectVoice: vi.fn().mockResolvedValue(''),
+  RecordingConsent: vi.fn().mockResolvedValue(''),
   ConnectVoice: vi.fn().mockResolvedValue(''),
   GetAutoLogin: vi.fn().mockResolvedValue({ username: '', addr: '' }),
   GetConfig: vi.fn().mockImplementation(() => Promise.resolve({ ...savedConfig })),
@@ -170,6 +171,8 @@ const goApp: Record<string, any> = {
   GetStartupAddr: vi.fn().mockResolvedValue(''),
   SendChat: vi.fn().mockResolvedValue(''),
   SendChannelChat: vi.fn().mockResolvedValue(''),
+  SendTyping: vi.fn().mockResolvedValue(undefined),
+  SendReadReceipt: vi.fn().mockResolvedValue(undefined),
   EditMessage: vi.fn().mockResolvedValue(''),
   DeleteMessage: vi.fn().mockResolvedValue(''),
   AddReaction: vi.fn().mockResolvedValue(''),
@@ -177,19 +180,44 @@ const goApp: Record<string, any> = {
   JoinChannel: vi.fn().mockResolvedValue(''),
   CreateChannel: vi.fn().mockResolvedValue(''),
   RenameChannel: vi.fn().mockResolvedValue(''),
+  SetChannelBitrate: vi.fn().mockResolvedValue(''),
+  SetSlowMode: vi.fn().mockResolvedValue(''),
+  SetChannelLock: vi.fn().mockResolvedValue(''),
+  SetChannelTTL: vi.fn().mockResolvedValue(''),
+  SetChannelRecordRole: vi.fn().mockResolvedValue(''),
+  CreateCategory: vi.fn().mockResolvedValue(''),
+  AssignChannelCategory: vi.fn().mockResolvedValue(''),
+  PurgeMessages: vi.fn().mockResolvedValue(''),
   DeleteChannel: vi.fn().mockResolvedValue(''),
   MoveUserToChannel: vi.fn().mockResolvedValue(''),
   KickUser: vi.fn().mockResolvedValue(''),
+  BanUser: vi.fn().mockResolvedValue(''),
+  RequestBans: vi.fn().mockResolvedValue(''),
+  Unban: vi.fn().mockResolvedValue(''),
+  MuteUserServer: vi.fn().mockResolvedValue(''),
+  UnmuteUserServer: vi.fn().mockResolvedValue(''),
+  SetStatus: vi.fn().mockResolvedValue(''),
+  TransferOwner: vi.fn().mockResolvedValue(''),
+  SetRole: vi.fn().mockResolvedValue(''),
   UploadFile: vi.fn().mockResolvedValue(''),
+  ExportChannel: vi.fn().mockResolvedValue(''),
   UploadFileFromPath: vi.fn().mockResolvedValue(''),
   RenameUser: vi.fn().mockResolvedValue(''),
   RenameServer: vi.fn().mockResolvedValue(''),
+  SetAnnouncement: vi.fn().mockResolvedValue(''),
   SetMuted: vi.fn().mockResolvedValue(undefined),
   SetDeafened: vi.fn().mockResolvedValue(undefined),
   SetAEC: vi.fn().mockResolvedValue(undefined),
   SetAGC: vi.fn().mockResolvedValue(undefined),
+  SetAGCLevel: vi.fn().mockResolvedValue(undefined),
+  SetAGCTarget: vi.fn().mockResolvedValue(undefined),
   SetAudioBitrate: vi.fn().mockResolvedValue(undefined),
   GetAudioBitrate: vi.fn().mockResolvedValue(32),
+  SetAudioProfile: vi.fn().mockResolvedValue(''),
+  SetOpusApplication: vi.fn().mockResolvedValue(''),
+  SetFrameSize: vi.fn().mockResolvedValue(''),
+  StartLocalRecording: vi.fn().mockResolvedValue(''),
+  StopLocalRecording: vi.fn().mockResolvedValue(''),
   GetBuildInfo: vi.fn().mockResolvedValue({
     commit: 'deadbeefcaf0',
     build_time: '2026-02-21T00:00:00Z',
@@ -199,12 +227,27 @@ const goApp: Record<string, any> = {
     dirty: false,
   }),
   SetNoiseSuppression: vi.fn().mockResolvedValue(undefined),
+  SetNoiseGate: vi.fn().mockResolvedValue(undefined),
+  SetSidetone: vi.fn().mockResolvedValue(undefined),
+  SetMonitorVolume: vi.fn().mockResolvedValue(undefined),
+  SetDropPolicy: vi.fn().mockResolvedValue(''),
+  AddMonitorChannel: vi.fn().mockResolvedValue(''),
+  RemoveMonitorChannel: vi.fn().mockResolvedValue(''),
+  SetAutoLevel: vi.fn().mockResolvedValue(undefined),
   SetNotificationVolume: vi.fn().mockResolvedValue(undefined),
   GetNotificationVolume: vi.fn().mockResolvedValue(0.5),
   SetPTTMode: vi.fn().mockResolvedValue(undefined),
+  StartWhisper: vi.fn().mockResolvedValue(''),
+  StopWhisper: vi.fn().mockResolvedValue(undefined),
+  PlaySoundboard: vi.fn().mockResolvedValue(''),
+  SetPTTHotkey: vi.fn().mockResolvedValue(''),
   PTTKeyDown: vi.fn().mockResolvedValue(undefined),
   PTTKeyUp: vi.fn().mockResolvedValue(undefined),
   MuteUser: vi.fn().mockResolvedValue(undefined),
+  BlockUser: vi.fn().mockResolvedValue(''),
+  UnblockUser: vi.fn().mockResolvedValue(''),
+  SetJoinLeaveSounds: vi.fn().mockResolvedValue(undefined),
+  MuteUserNotifications: vi.fn().mockResolvedValue(''),
   UnmuteUser: vi.fn().mockResolvedValue(undefined),
   GetMutedUsers: vi.fn().mockResolvedValue([]),
   SetUserVolume: vi.fn().mockResolvedValue(undefined),
@@ -213,11 +256,14 @@ const goApp: Record<string, any> = {
   GetOutputDevices: vi.fn().mockResolvedValue([]),
   GetInputLevel: vi.fn().mockResolvedValue(0),
   GetMetrics: vi.fn().mockResolvedValue({ latency: 0, jitter: 0, loss: 0 }),
-  SetInputDevice: vi.fn().mockResolvedValue(undefined),
-  SetOutputDevice: vi.fn().mockResolvedValue(undefined),
+  GetPeerMetrics: vi.fn().mockResolvedValue([]),
+  GetAllowedEmoji: vi.fn().mockResolvedValue([]),
+  SetInputDevice: vi.fn().mockResolvedValue(''),
+  SetOutputDevice: vi.fn().mockResolvedValue(''),
   SetVolume: vi.fn().mockResolvedValue(undefined),
   StartTest: vi.fn().mockResolvedValue(''),
   StopTest: vi.fn().mockResolvedValue(undefined),
+  MeasureAudioLatency: vi.fn().mockResolvedValue({ latency_ms: 60 }),
   IsConnected: vi.fn().mockResolvedValue(false),
   StartVideo: vi.fn().mockResolvedValue(''),
   StopVideo: vi.fn().mockResolvedValue(''),
@@ -226,6 +272,12 @@ const goApp: Record<string, any> = {
   RequestVideoQuality: vi.fn().mockResolvedValue(''),
   RequestChannels: vi.fn().mockResolvedValue(''),
   RequestMessages: vi.fn().mockResolvedValue(''),
+  RequestMessagesBefore: vi.fn().mockResolvedValue(''),
+  RequestEditHistory: vi.fn().mockResolvedValue(''),
+  RequestPinned: vi.fn().mockResolvedValue(''),
+  PinMessage: vi.fn().mockResolvedValue(''),
+  UnpinMessage: vi.fn().mockResolvedValue(''),
+  RequestAuditLog: vi.fn().mockResolvedValue(''),
   RequestServerInfo: vi.fn().mockResolvedValue(''),
 }
 
@@ -64,6 +64,7 @@ export class BrowserTransport {
   private selfId = ''
   private selfLocalId = 0
   private serverAddr = ''
+  private sessionToken = ''
   private idMap = new Map<string, number>()
   private nextId = 1
 
@@ -199,6 +200,62 @@ export class BrowserTransport {
     this.send({ type: 'get_messages', channel_id: String(channelId) })
   }
 
+  /** Request up to limit messages older than msg ID before (0 = newest). */
+  requestMessagesBefore(channelId: number, before: number, limit: number): void {
+    this.send({ type: 'get_messages_before', channel_id: String(channelId), before, limit })
+  }
+
+  /** Request the reply chain ending at a message. */
+  requestThread(msgId: number): void {
+    this.send({ type: 'get_thread', msg_id: msgId })
+  }
+
+  requestEditHistory(msgId: number): void {
+    this.send({ type: 'get_edit_history', msg_id: msgId })
+  }
+
+  /** Request the messages pinned in a channel. */
+  requestPinned(channelId: number): void {
+    this.send({ type: 'get_pinned', channel_id: String(channelId) })
+  }
+
+  /** Pin or unpin a message; the server only accepts moderators and above. */
+  setPinned(msgId: number, pinned: boolean): void {
+    this.send({ type: pinned ? 'pin_message' : 'unpin_message', msg_id: msgId })
+  }
+
+  /** Request the moderation audit log; the server ignores non-admins. */
+  requestAuditLog(): void {
+    this.send({ type: 'get_audit_log' })
+  }
+
+  /** Request the active bans; the server ignores non-admins. */
+  requestBans(): void {
+    this.send({ type: 'get_bans' })
+  }
+
+  /** Lift a ban by its ID from ban_list. */
+  unban(banId: number): void {
+    this.send({ type: 'unban', ban_id: banId })
+  }
+
+  /** Download a channel transcript ("json" or "md"); resolves to the file name. */
+  async exportChannel(channelId: number, format: string): Promise<string> {
+    if (!this.sessionToken) throw new Error('server API not available')
+    const resp = await fetch(
+      `http://${this.serverAddr}/api/channels/${channelId}/export?format=${encodeURIComponent(format)}`,
+      { headers: { Authorization: `Bearer ${this.sessionToken}` } },
+    )
+    if (!resp.ok) throw new Error(`export failed (${resp.status})`)
+    const name = `channel-${channelId}.${format}`
+    const link = document.createElement('a')
+    link.href = URL.createObjectURL(await resp.blob())
+    link.download = name
+    link.click()
+    URL.revokeObjectURL(link.href)
+    return name
+  }
+
   /** Send a text message (lobby chat). */
   sendChat(message: string): void {
     this.send({ type: 'send_text', message })
@@ -209,6 +266,36 @@ export class BrowserTransport {
     this.send({ type: 'send_text', channel_id: String(channelId), message })
   }
 
+  /** Send a private message to one user. Returns '' or an error string. */
+  sendDM(targetId: number, message: string): string {
+    for (const [serverId, local] of this.idMap) {
+      if (local === targetId) {
+        this.send({ type: 'dm', user_id: serverId, message })
+        return ''
+      }
+    }
+    return `unknown user ${targetId}`
+  }
+
+  private historyMessages(raw: any[] | undefined): any[] {
+    return (raw || []).map((m: any) => ({
+      msg_id: m.msg_id,
+      username: m.username,
+      message: m.message,
+      ts: m.ts,
+      reactions: m.reactions?.map((rx: any) => ({
+        emoji: rx.emoji,
+        user_ids: (rx.user_ids || []).map((uid: string) => this.translateId(uid)),
+        count: rx.count,
+      })),
+      file_id: m.file_id,
+      file_name: m.file_name,
+      file_size: m.file_size,
+      file_url: m.file_id ? `/api/blobs/${m.file_id}` : undefined,
+      reply_to: m.reply_to || undefined,
+    }))
+  }
+
   private send(msg: Record<string, any>): void {
     if (this.ws && this.ws.readyState === WebSocket.OPEN) {
       this.ws.send(JSON.stringify(msg))
@@ -220,6 +307,7 @@ export class BrowserTransport {
   private handleSnapshot(msg: any): void {
     this.selfId = msg.self_id
     this.selfLocalId = this.translateId(msg.self_id)
+    this.sessionToken = msg.session_token ?? ''
     const users = (msg.users || []).map((u: any) => ({
       id: this.translateId(u.id),
       username: u.username,
@@ -329,6 +417,17 @@ export class BrowserTransport {
         break
       }
 
+      case 'dm':
+        this.eventBus.EventsEmit('chat:dm', {
+          server_addr: this.serverAddr,
+          sender_id: msg.user?.id ? this.translateId(msg.user.id) : 0,
+          target_id: msg.user_id ? this.translateId(msg.user_id) : 0,
+          username: msg.user?.username || '',
+          message: msg.message,
+          ts: msg.ts,
+        })
+        break
+
       case 'reaction_added': {
         const localId = msg.user_id ? this.translateId(msg.user_id) : 0
         this.eventBus.EventsEmit('chat:reaction_added', {
@@ -349,25 +448,74 @@ export class BrowserTransport {
         break
       }
 
+      case 'thread': {
+        this.eventBus.EventsEmit('chat:thread', {
+          msg_id: msg.msg_id,
+          messages: this.historyMessages(msg.messages),
+        })
+        break
+      }
+
+      case 'edit_history': {
+        this.eventBus.EventsEmit('chat:edit_history', {
+          msg_id: msg.msg_id,
+          edits: msg.edits ?? [],
+        })
+        break
+      }
+
+      case 'audit_log': {
+        this.eventBus.EventsEmit('audit:log', { entries: msg.audit ?? [] })
+        break
+      }
+
+      case 'ban_list': {
+        this.eventBus.EventsEmit('bans:list', { bans: msg.bans ?? [] })
+        break
+      }
+
+      case 'pinned_list': {
+        const pins = msg.pins ?? []
+        const messages = this.historyMessages(pins)
+        this.eventBus.EventsEmit('chat:pinned_list', {
+          channel_id: msg.channel_id ? parseInt(msg.channel_id, 10) || 0 : 0,
+          pins: messages.map((m, i) => ({
+            ...m,
+            pinned_by: pins[i].pinned_by ? this.translateId(pins[i].pinned_by) : 0,
+            pinned_at: pins[i].pinned_at,
+          })),
+        })
+        break
+      }
+
+      case 'message_pinned': {
+        this.eventBus.EventsEmit('chat:message_pinned', {
+          msg_id: msg.msg_id,
+          channel_id: msg.channel_id ? parseInt(msg.channel_id, 10) || 0 : 0,
+          user_id: msg.user_id ? this.translateId(msg.user_id) : 0,
+        })
+        break
+      }
+
+      case 'message_unpinned': {
+        this.eventBus.EventsEmit('chat:message_unpinned', { msg_id: msg.msg_id })
+        break
+      }
+
+      case 'audit_entry': {
+        for (const entry of msg.audit ?? []) {
+          this.eventBus.EventsEmit('audit:entry', { entry })
+        }
+        break
+      }
+
       case 'message_history': {
         const channelId = msg.channel_id
           ? parseInt(msg.channel_id, 10) || 0
           : 0
-        const messages = (msg.messages || []).map((m: any) => ({
-          msg_id: m.msg_id,
-          username: m.username,
-          message: m.message,
-          ts: m.ts,
-          reactions: m.reactions?.map((rx: any) => ({
-            emoji: rx.emoji,
-            user_ids: (rx.user_ids || []).map((uid: string) => this.translateId(uid)),
-            count: rx.count,
-          })),
-          file_id: m.file_id,
-          file_name: m.file_name,
-          file_size: m.file_size,
-          file_url: m.file_id ? `/api/blobs/${m.file_id}` : undefined,
-        }))
+        const messages = this.historyMessages(msg.messages)
+        // Pages from get_messages_before are newest first.
+        if (msg.before) messages.reverse()
         this.eventBus.EventsEmit('chat:history', {
           channel_id: channelId,
           messages,
@@ -420,6 +568,7 @@ export class BrowserTransport {
         self.disconnectVoice()
         return Promise.resolve('')
       },
+      RecordingConsent: () => Promise.resolve(''),
       ConnectVoice: (channelID: number) => {
         self.joinVoice(channelID)
         return Promise.resolve('')
@@ -440,10 +589,39 @@ export class BrowserTransport {
         self.requestServerInfo()
         return Promise.resolve('')
       },
+      GetPermissions: () => Promise.resolve(''),
       RequestMessages: (channelID: number) => {
         self.requestMessages(channelID)
         return Promise.resolve('')
       },
+      RequestMessagesBefore: (channelID: number, before: number, limit: number) => {
+        self.requestMessagesBefore(channelID, before, limit)
+        return Promise.resolve('')
+      },
+      RequestThread: (msgID: number) => {
+        self.requestThread(msgID)
+        return Promise.resolve('')
+      },
+      RequestEditHistory: (msgID: number) => {
+        self.requestEditHistory(msgID)
+        return Promise.resolve('')
+      },
+      RequestAuditLog: () => {
+        self.requestAuditLog()
+        return Promise.resolve('')
+      },
+      RequestPinned: (channelID: number) => {
+        self.requestPinned(channelID)
+        return Promise.resolve('')
+      },
+      PinMessage: (msgID: number) => {
+        self.setPinned(msgID, true)
+        return Promise.resolve('')
+      },
+      UnpinMessage: (msgID: number) => {
+        self.setPinned(msgID, false)
+        return Promise.resolve('')
+      },
       SendChat: (msg: string) => {
         self.sendChat(msg)
         return Promise.resolve('')
@@ -452,6 +630,9 @@ export class BrowserTransport {
         self.sendChannelChat(channelID, msg)
         return Promise.resolve('')
       },
+      SendTyping: () => Promise.resolve(),
+      SendReadReceipt: () => Promise.resolve(),
+      SendDM: (id: number, msg: string) => Promise.resolve(self.sendDM(id, msg)),
 
       // --- Config (localStorage-backed) ---
       GetAutoLogin: () => Promise.resolve({ username: '', addr: '' }),
@@ -490,25 +671,84 @@ export class BrowserTransport {
       SetDeafened: () => Promise.resolve(),
       SetAEC: () => Promise.resolve(),
       SetAGC: () => Promise.resolve(),
+      SetAGCLevel: () => Promise.resolve(),
+      SetAGCTarget: () => Promise.resolve(),
+      SetFEC: () => Promise.resolve(),
+      SetDTX: () => Promise.resolve(),
+      SetOnsetRedundancy: () => Promise.resolve(),
+      SetNoiseGate: () => Promise.resolve(),
+      SetSidetone: () => Promise.resolve(),
+      SetMonitorVolume: () => Promise.resolve(),
+      SetDropPolicy: () => Promise.resolve(''),
+      SetAutoLevel: () => Promise.resolve(),
+      SetJitterBufferMs: () => Promise.resolve(),
+      SetStereo: () => Promise.resolve(''),
       SetAudioBitrate: () => Promise.resolve(),
       GetAudioBitrate: () => Promise.resolve(32),
+      SetAudioProfile: () => Promise.resolve(''),
+      SetOpusApplication: () => Promise.resolve(''),
+      SetFrameSize: () => Promise.resolve(''),
+      StartLocalRecording: () => Promise.resolve(''),
+      StopLocalRecording: () => Promise.resolve(''),
       GetInputLevel: () => Promise.resolve(0),
       SetNotificationVolume: () => Promise.resolve(),
       GetNotificationVolume: () => Promise.resolve(0.5),
+      SetDoNotDisturb: () => Promise.resolve(),
+      IsDoNotDisturb: () => Promise.resolve(false),
+      SetChannelNotifyLevel: () => Promise.resolve(''),
+      GetChannelNotifyLevel: () => Promise.resolve('mentions'),
+      SetInCallAlerts: () => Promise.resolve(),
+      IsInCallAlerts: () => Promise.resolve(false),
+      SetAutoJoinVoice: () => Promise.resolve(''),
+      GetAutoJoinVoice: () => Promise.resolve(0),
       SetPTTMode: () => Promise.resolve(),
+      StartWhisper: () => Promise.resolve('Whisper is not available in browser mode'),
+      StopWhisper: () => Promise.resolve(),
+      PlaySoundboard: () => Promise.resolve('Soundboard is not available in browser mode'),
+      SetPTTHotkey: () => Promise.resolve(''),
       PTTKeyDown: () => Promise.resolve(),
       PTTKeyUp: () => Promise.resolve(),
       MuteUser: () => Promise.resolve(),
+      BlockUser: () => Promise.resolve(''),
+      UnblockUser: () => Promise.resolve(''),
+      SetJoinLeaveSounds: () => Promise.resolve(),
+      MuteUserNotifications: () => Promise.resolve(''),
       UnmuteUser: () => Promise.resolve(),
       GetMutedUsers: () => Promise.resolve([]),
       SetUserVolume: () => Promise.resolve(),
       GetUserVolume: () => Promise.resolve(1.0),
       KickUser: () => Promise.resolve(''),
+      BanUser: () => Promise.resolve(''),
+      RequestBans: () => {
+        self.requestBans()
+        return Promise.resolve('')
+      },
+      Unban: (id: number) => {
+        self.unban(id)
+        return Promise.resolve('')
+      },
+      MuteUserServer: () => Promise.resolve(''),
+      UnmuteUserServer: () => Promise.resolve(''),
+      SetStatus: () => Promise.resolve(''),
+      TransferOwner: () => Promise.resolve(''),
+      SetRole: () => Promise.resolve(''),
       RenameServer: () => Promise.resolve(''),
+      SetAnnouncement: () => Promise.resolve(''),
       RenameUser: () => Promise.resolve(''),
       RenameChannel: () => Promise.resolve(''),
+      SetChannelBitrate: () => Promise.resolve(''),
+      SetSlowMode: () => Promise.resolve(''),
+      SetChannelLock: () => Promise.resolve(''),
+      SetChannelTTL: () => Promise.resolve(''),
+      SetChannelRecordRole: () => Promise.resolve(''),
+      AddMonitorChannel: () => Promise.resolve(''),
+      RemoveMonitorChannel: () => Promise.resolve(''),
+      CreateCategory: () => Promise.resolve(''),
+      AssignChannelCategory: () => Promise.resolve(''),
+      PurgeMessages: () => Promise.resolve(''),
       DeleteChannel: () => Promise.resolve(''),
       MoveUserToChannel: () => Promise.resolve(''),
+      ExportChannel: (channelID: number, format: string) => self.exportChannel(channelID, format),
       UploadFile: (channelID: number) => {
         return new Promise<string>((resolve) => {
           const input = document.createElement('input')
@@ -559,11 +799,14 @@ export class BrowserTransport {
       GetOutputDevices: () => Promise.resolve([]),
       GetMetrics: () =>
         Promise.resolve({ latency: 0, jitter: 0, loss: 0 }),
-      SetInputDevice: () => Promise.resolve(),
-      SetOutputDevice: () => Promise.resolve(),
+      GetPeerMetrics: () => Promise.resolve([]),
+      GetAllowedEmoji: () => Promise.resolve([]),
+      SetInputDevice: () => Promise.resolve(''),
+      SetOutputDevice: () => Promise.resolve(''),
       SetVolume: () => Promise.resolve(),
       StartTest: () => Promise.resolve(''),
       StopTest: () => Promise.resolve(),
+      MeasureAudioLatency: () => Promise.resolve({ latency_ms: 0, error: 'not available in browser mode' }),
       IsConnected: () =>
         Promise.resolve(
           self.ws !== null && self.ws.readyState === WebSocket.OPEN,
@@ -2,6 +2,8 @@ import { ref } from 'vue'
 
 const speakingUsers = ref<Set<number>>(new Set())
 const speakingTimers = new Map<number, ReturnType<typeof setTimeout>>()
+// Users the server reports as speaking; they stay lit until it reports the stop.
+const heldSpeakers = new Set<number>()
 
 function setSpeaking(id: number): void {
   const next = new Set(speakingUsers.value)
@@ -9,6 +11,8 @@ function setSpeaking(id: number): void {
   speakingUsers.value = next
   const existing = speakingTimers.get(id)
   if (existing) clearTimeout(existing)
+  speakingTimers.delete(id)
+  if (heldSpeakers.has(id)) return
   speakingTimers.set(id, setTimeout(() => {
     const updated = new Set(speakingUsers.value)
     updated.delete(id)
@@ -17,9 +21,21 @@ function setSpeaking(id: number): void {
   }, 500))
 }
 
+/** Applies the server's speaking start/stop for id, whether or not we hear them. */
+function setSpeakingState(id: number, speaking: boolean): void {
+  if (speaking) {
+    heldSpeakers.add(id)
+  } else if (!heldSpeakers.delete(id)) {
+    return
+  }
+  // A stop fades out on the usual timer.
+  setSpeaking(id)
+}
+
 function clearSpeaking(): void {
   speakingTimers.forEach(t => clearTimeout(t))
   speakingTimers.clear()
+  heldSpeakers.clear()
   speakingUsers.value = new Set()
 }
 
@@ -28,5 +44,5 @@ function cleanup(): void {
 }
 
 export function useSpeakingUsers() {
-  return { speakingUsers, setSpeaking, clearSpeaking, cleanup }
+  return { speakingUsers, setSpeaking, setSpeakingState, clearSpeaking, cleanup }
 }
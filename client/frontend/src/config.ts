@@ -11,6 +11,10 @@ export interface ServerEntry {
 
 export type MessageDensity = 'compact' | 'default' | 'comfortable'
 
+/** Which received frame playback drops when it falls behind. */
+export type DropPolicy = 'newest' | 'oldest'
+export type OpusApplication = 'voip' | 'audio' | 'lowdelay'
+
 export interface Config {
   theme: string
   theme_mode?: string
@@ -19,11 +23,26 @@ export interface Config {
   output_device_id: number
   volume: number
   audio_bitrate_kbps: number
+  opus_application?: OpusApplication
   noise_enabled: boolean
   aec_enabled: boolean
   agc_enabled: boolean
+  fec_enabled?: boolean
+  dtx_enabled?: boolean
+  onset_redundancy?: boolean
+  stereo?: boolean
+  jitter_buffer_ms?: number
   ptt_enabled: boolean
   ptt_key: string
+  noise_gate_enabled?: boolean
+  noise_gate_db?: number
+  agc_target_db?: number
+  sidetone_enabled?: boolean
+  sidetone_gain?: number
+  monitor_volume?: number
+  join_leave_sounds?: boolean
+  drop_policy?: DropPolicy
+  auto_level?: boolean
   servers: ServerEntry[]
   message_density?: MessageDensity
   show_system_messages?: boolean
@@ -123,6 +142,10 @@ export function DisconnectVoice(): Promise<string> {
   return bridge()['DisconnectVoice']()
 }
 
+export function RecordingConsent(consent: boolean): Promise<string> {
+  return bridge()['RecordingConsent'](consent)
+}
+
 export function GetAutoLogin(): Promise<{ username: string; addr: string }> {
   return bridge()['GetAutoLogin']()
 }
@@ -163,6 +186,82 @@ export function SetAGC(enabled: boolean): Promise<void> {
   return bridge()['SetAGC'](enabled)
 }
 
+export function SetAGCTarget(dbfs: number): Promise<void> {
+  return bridge()['SetAGCTarget'](dbfs)
+}
+
+export function SetAGCLevel(level: number): Promise<void> {
+  return bridge()['SetAGCLevel'](level)
+}
+
+// --- FEC bindings ---
+
+export function SetFEC(enabled: boolean): Promise<void> {
+  return bridge()['SetFEC'](enabled)
+}
+
+// --- DTX bindings ---
+
+export function SetDTX(enabled: boolean): Promise<void> {
+  return bridge()['SetDTX'](enabled)
+}
+
+// --- Onset redundancy bindings ---
+
+export function SetOnsetRedundancy(enabled: boolean): Promise<void> {
+  return bridge()['SetOnsetRedundancy'](enabled)
+}
+
+// --- Noise gate bindings ---
+
+export function SetNoiseGate(db: number, enabled: boolean): Promise<void> {
+  return bridge()['SetNoiseGate'](db, enabled)
+}
+
+// --- Sidetone bindings ---
+
+export function SetSidetone(enabled: boolean, gain: number): Promise<void> {
+  return bridge()['SetSidetone'](enabled, gain)
+}
+
+// --- Monitor bindings ---
+
+export function AddMonitorChannel(channelID: number): Promise<string> {
+  return bridge()['AddMonitorChannel'](channelID)
+}
+
+export function RemoveMonitorChannel(channelID: number): Promise<string> {
+  return bridge()['RemoveMonitorChannel'](channelID)
+}
+
+export function SetMonitorVolume(volume: number): Promise<void> {
+  return bridge()['SetMonitorVolume'](volume)
+}
+
+// --- Playback drop policy bindings ---
+
+export function SetDropPolicy(policy: DropPolicy): Promise<string> {
+  return bridge()['SetDropPolicy'](policy)
+}
+
+// --- Auto-level bindings ---
+
+export function SetAutoLevel(enabled: boolean): Promise<void> {
+  return bridge()['SetAutoLevel'](enabled)
+}
+
+// --- Stereo bindings ---
+
+export function SetStereo(enabled: boolean): Promise<string> {
+  return bridge()['SetStereo'](enabled)
+}
+
+// --- Jitter buffer bindings ---
+
+export function SetJitterBufferMs(ms: number): Promise<void> {
+  return bridge()['SetJitterBufferMs'](ms)
+}
+
 // --- Audio bitrate bindings ---
 
 export function SetAudioBitrate(kbps: number): Promise<void> {
@@ -173,6 +272,32 @@ export function GetAudioBitrate(): Promise<number> {
   return bridge()['GetAudioBitrate']()
 }
 
+export function SetAudioProfile(profile: string): Promise<string> {
+  return bridge()['SetAudioProfile'](profile)
+}
+
+export function SetOpusApplication(mode: OpusApplication): Promise<string> {
+  return bridge()['SetOpusApplication'](mode)
+}
+
+export function SetFrameSize(ms: number): Promise<string> {
+  return bridge()['SetFrameSize'](ms)
+}
+
+export function MeasureAudioLatency(): Promise<{ latency_ms: number; error?: string }> {
+  return bridge()['MeasureAudioLatency']()
+}
+
+// --- Local recording bindings ---
+
+export function StartLocalRecording(path: string): Promise<string> {
+  return bridge()['StartLocalRecording'](path)
+}
+
+export function StopLocalRecording(): Promise<string> {
+  return bridge()['StopLocalRecording']()
+}
+
 // --- Input Level bindings ---
 
 export function GetInputLevel(): Promise<number> {
@@ -189,12 +314,72 @@ export function GetNotificationVolume(): Promise<number> {
   return bridge()['GetNotificationVolume']()
 }
 
+// --- Do-not-disturb bindings ---
+
+export function SetDoNotDisturb(enabled: boolean): Promise<void> {
+  return bridge()['SetDoNotDisturb'](enabled)
+}
+
+export function IsDoNotDisturb(): Promise<boolean> {
+  return bridge()['IsDoNotDisturb']()
+}
+
+// --- Channel notification level bindings ---
+
+export function SetChannelNotifyLevel(channelID: number, level: string): Promise<string> {
+  return bridge()['SetChannelNotifyLevel'](channelID, level)
+}
+
+export function GetChannelNotifyLevel(channelID: number): Promise<string> {
+  return bridge()['GetChannelNotifyLevel'](channelID)
+}
+
+// --- In-call alert bindings ---
+
+export function SetInCallAlerts(enabled: boolean): Promise<void> {
+  return bridge()['SetInCallAlerts'](enabled)
+}
+
+export function IsInCallAlerts(): Promise<boolean> {
+  return bridge()['IsInCallAlerts']()
+}
+
+// --- Auto-join voice bindings ---
+
+export function SetAutoJoinVoice(addr: string, channelID: number): Promise<string> {
+  return bridge()['SetAutoJoinVoice'](addr, channelID)
+}
+
+export function GetAutoJoinVoice(addr: string): Promise<number> {
+  return bridge()['GetAutoJoinVoice'](addr)
+}
+
+// --- Whisper bindings ---
+
+export function StartWhisper(userID: number): Promise<string> {
+  return bridge()['StartWhisper'](userID)
+}
+
+export function StopWhisper(): Promise<void> {
+  return bridge()['StopWhisper']()
+}
+
+// --- Soundboard bindings ---
+
+export function PlaySoundboard(clipID: string): Promise<string> {
+  return bridge()['PlaySoundboard'](clipID)
+}
+
 // --- PTT bindings ---
 
 export function SetPTTMode(enabled: boolean): Promise<void> {
   return bridge()['SetPTTMode'](enabled)
 }
 
+export function SetPTTHotkey(keyName: string): Promise<string> {
+  return bridge()['SetPTTHotkey'](keyName)
+}
+
 export function PTTKeyDown(): Promise<void> {
   return bridge()['PTTKeyDown']()
 }
@@ -217,6 +402,26 @@ export function GetMutedUsers(): Promise<number[]> {
   return bridge()['GetMutedUsers']()
 }
 
+// --- Blocking bindings ---
+
+export function BlockUser(id: number): Promise<string> {
+  return bridge()['BlockUser'](id)
+}
+
+export function UnblockUser(id: number): Promise<string> {
+  return bridge()['UnblockUser'](id)
+}
+
+// --- Join/leave sound bindings ---
+
+export function SetJoinLeaveSounds(enabled: boolean): Promise<void> {
+  return bridge()['SetJoinLeaveSounds'](enabled)
+}
+
+export function MuteUserNotifications(id: number, muted: boolean): Promise<string> {
+  return bridge()['MuteUserNotifications'](id, muted)
+}
+
 // --- Per-user volume bindings ---
 
 export function SetUserVolume(userID: number, volume: number): Promise<void> {
@@ -251,14 +456,50 @@ export function RemoveReaction(msgID: number, emoji: string): Promise<string> {
 
 // --- Moderation bindings ---
 
-export function KickUser(id: number): Promise<string> {
-  return bridge()['KickUser'](id)
+export function KickUser(id: number, reason = ''): Promise<string> {
+  return bridge()['KickUser'](id, reason)
+}
+
+export function BanUser(id: number, reason: string, durationS: number): Promise<string> {
+  return bridge()['BanUser'](id, reason, durationS)
+}
+
+export function RequestBans(): Promise<string> {
+  return bridge()['RequestBans']()
+}
+
+export function Unban(id: number): Promise<string> {
+  return bridge()['Unban'](id)
+}
+
+export function MuteUserServer(id: number, durationS: number): Promise<string> {
+  return bridge()['MuteUserServer'](id, durationS)
+}
+
+export function UnmuteUserServer(id: number): Promise<string> {
+  return bridge()['UnmuteUserServer'](id)
+}
+
+export function SetStatus(status: string): Promise<string> {
+  return bridge()['SetStatus'](status)
+}
+
+export function SetRole(id: number, role: string): Promise<string> {
+  return bridge()['SetRole'](id, role)
+}
+
+export function TransferOwner(id: number): Promise<string> {
+  return bridge()['TransferOwner'](id)
 }
 
 export function RenameServer(name: string): Promise<string> {
   return bridge()['RenameServer'](name)
 }
 
+export function SetAnnouncement(text: string): Promise<string> {
+  return bridge()['SetAnnouncement'](text)
+}
+
 export function RenameUser(name: string): Promise<string> {
   return bridge()['RenameUser'](name)
 }
@@ -294,6 +535,18 @@ export function SendChannelChat(channelID: number, message: string): Promise<str
   return bridge()['SendChannelChat'](channelID, message)
 }
 
+export function SendTyping(channelID: number): Promise<void> {
+  return bridge()['SendTyping'](channelID)
+}
+
+export function SendReadReceipt(msgID: number): Promise<void> {
+  return bridge()['SendReadReceipt'](msgID)
+}
+
+export function SendDM(id: number, message: string): Promise<string> {
+  return bridge()['SendDM'](id, message)
+}
+
 // --- Channel management bindings (owner-only) ---
 
 export function CreateChannel(name: string): Promise<string> {
@@ -304,6 +557,38 @@ export function RenameChannel(id: number, name: string): Promise<string> {
   return bridge()['RenameChannel'](id, name)
 }
 
+export function SetChannelBitrate(id: number, kbps: number): Promise<string> {
+  return bridge()['SetChannelBitrate'](id, kbps)
+}
+
+export function SetSlowMode(channelID: number, seconds: number): Promise<string> {
+  return bridge()['SetSlowMode'](channelID, seconds)
+}
+
+export function SetChannelLock(channelID: number, locked: boolean): Promise<string> {
+  return bridge()['SetChannelLock'](channelID, locked)
+}
+
+export function SetChannelTTL(channelID: number, seconds: number): Promise<string> {
+  return bridge()['SetChannelTTL'](channelID, seconds)
+}
+
+export function SetChannelRecordRole(channelID: number, role: string): Promise<string> {
+  return bridge()['SetChannelRecordRole'](channelID, role)
+}
+
+export function CreateCategory(name: string): Promise<string> {
+  return bridge()['CreateCategory'](name)
+}
+
+export function AssignChannelCategory(channelID: number, categoryID: number): Promise<string> {
+  return bridge()['AssignChannelCategory'](channelID, categoryID)
+}
+
+export function PurgeMessages(channelID: number, count: number): Promise<string> {
+  return bridge()['PurgeMessages'](channelID, count)
+}
+
 export function DeleteChannel(id: number): Promise<string> {
   return bridge()['DeleteChannel'](id)
 }
@@ -322,6 +607,13 @@ export function UploadFileFromPath(channelID: number, path: string): Promise<str
   return bridge()['UploadFileFromPath'](channelID, path)
 }
 
+// --- Channel export bindings ---
+
+/** Saves a channel transcript via the save dialog; resolves to the path, or '' if cancelled. */
+export function ExportChannel(channelID: number, format: 'json' | 'md'): Promise<string> {
+  return bridge()['ExportChannel'](channelID, format)
+}
+
 // --- Video bindings ---
 
 export function StartVideo(): Promise<string> {
@@ -356,6 +648,38 @@ export function RequestMessages(channelID: number): Promise<string> {
   return bridge()['RequestMessages'](channelID)
 }
 
+export function RequestMessagesBefore(channelID: number, before: number, limit: number): Promise<string> {
+  return bridge()['RequestMessagesBefore'](channelID, before, limit)
+}
+
+export function RequestThread(msgID: number): Promise<string> {
+  return bridge()['RequestThread'](msgID)
+}
+
+export function RequestEditHistory(msgID: number): Promise<string> {
+  return bridge()['RequestEditHistory'](msgID)
+}
+
+export function RequestPinned(channelID: number): Promise<string> {
+  return bridge()['RequestPinned'](channelID)
+}
+
+export function PinMessage(msgID: number): Promise<string> {
+  return bridge()['PinMessage'](msgID)
+}
+
+export function UnpinMessage(msgID: number): Promise<string> {
+  return bridge()['UnpinMessage'](msgID)
+}
+
+export function RequestAuditLog(): Promise<string> {
+  return bridge()['RequestAuditLog']()
+}
+
 export function RequestServerInfo(): Promise<string> {
   return bridge()['RequestServerInfo']()
 }
+
+export function GetPermissions(): Promise<string> {
+  return bridge()['GetPermissions']()
+}
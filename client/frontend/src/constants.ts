@@ -3,6 +3,16 @@
 /** The custom protocol prefix used for bken invite links. */
 export const BKEN_SCHEME = 'bken://'
 
+/** Soundboard clips bundled with the client; IDs must match the server's. */
+export const SOUNDBOARD_CLIPS = [
+  { id: 'airhorn', label: 'Airhorn' },
+  { id: 'applause', label: 'Applause' },
+  { id: 'drumroll', label: 'Drumroll' },
+  { id: 'rimshot', label: 'Rimshot' },
+  { id: 'sad_trombone', label: 'Sad trombone' },
+  { id: 'tada', label: 'Ta-da' },
+] as const
+
 // --- localStorage / config keys ---
 
 /** localStorage key for the last server address the user connected to. */
@@ -8,6 +8,7 @@ export interface User {
   role?: 'OWNER' | 'ADMIN' | 'MODERATOR' | 'USER'
   muted?: boolean
   deafened?: boolean
+  status?: 'away' | 'dnd' // absent means online
 }
 
 /** A voice channel on the server. */
@@ -15,6 +16,21 @@ export interface Channel {
   id: number
   name: string
   max_users?: number // 0 or absent = unlimited
+  min_role_to_speak?: string // absent = everyone
+  min_role_to_chat?: string // absent = everyone
+  min_role_to_record?: string // absent = everyone
+  max_bitrate_kbps?: number // 0 or absent = no cap
+  slow_mode_seconds?: number // 0 or absent = off
+  message_ttl_seconds?: number // 0 or absent = keep forever
+  category_id?: number // 0 or absent = uncategorized
+  category_name?: string
+  locked?: boolean // new joins limited to moderators and above
+}
+
+/** A named group of channels within a server. */
+export interface Category {
+  id: number
+  name: string
 }
 
 /** Payload emitted when a user joins. */
@@ -109,4 +125,5 @@ export interface ChatMessage {
   mentions?: number[] // user IDs mentioned via @DisplayName
   reactions?: ReactionInfo[] // emoji reactions on this message
   pinned?: boolean   // true if the message is pinned
+  readBy?: number[]  // user IDs that have seen the message
 }
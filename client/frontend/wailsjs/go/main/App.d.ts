@@ -3,14 +3,24 @@
 import {main} from '../models';
 import {config} from '../models';
 
+export function AddMonitorChannel(arg1:number):Promise<string>;
+
 export function AddReaction(arg1:number,arg2:string):Promise<string>;
 
 export function ApplyConfig():Promise<void>;
 
+export function AssignChannelCategory(arg1:number,arg2:number):Promise<string>;
+
+export function BanUser(arg1:number,arg2:string,arg3:number):Promise<string>;
+
+export function BlockUser(arg1:number):Promise<string>;
+
 export function Connect(arg1:string,arg2:string):Promise<string>;
 
 export function ConnectVoice(arg1:number):Promise<string>;
 
+export function CreateCategory(arg1:string):Promise<string>;
+
 export function CreateChannel(arg1:string):Promise<string>;
 
 export function DeleteChannel(arg1:number):Promise<string>;
@@ -23,18 +33,32 @@ export function DisconnectVoice():Promise<string>;
 
 export function EditMessage(arg1:number,arg2:string):Promise<string>;
 
+export function ExportChannel(arg1:number,arg2:string):Promise<string>;
+
+export function GetAllowedEmoji():Promise<Array<string>>;
+
 export function GetAudioBitrate():Promise<number>;
 
+export function GetAutoJoinVoice(arg1:string):Promise<number>;
+
 export function GetAutoLogin():Promise<main.AutoLogin>;
 
+export function GetBitrateCeiling():Promise<number>;
+
+export function GetBitrateFloor():Promise<number>;
+
 export function GetBuildInfo():Promise<main.BuildInfo>;
 
+export function GetChannelNotifyLevel(arg1:number):Promise<string>;
+
 export function GetConfig():Promise<config.Config>;
 
 export function GetInputDevices():Promise<Array<main.AudioDevice>>;
 
 export function GetInputLevel():Promise<number>;
 
+export function GetMaxPacketBytes():Promise<number>;
+
 export function GetMetrics():Promise<main.Metrics>;
 
 export function GetMutedUsers():Promise<Array<number>>;
@@ -43,24 +67,51 @@ export function GetNotificationVolume():Promise<number>;
 
 export function GetOutputDevices():Promise<Array<main.AudioDevice>>;
 
+export function GetPeerMetrics():Promise<Array<main.PeerMetrics>>;
+
+export function GetPermissions():Promise<string>;
+
+export function GetSignalType():Promise<string>;
+
 export function GetStartupAddr():Promise<string>;
 
 export function GetUserVolume(arg1:number):Promise<number>;
 
+export function IsAdaptiveBitrate():Promise<boolean>;
+
 export function IsConnected():Promise<boolean>;
 
+export function IsDoNotDisturb():Promise<boolean>;
+
+export function IsInCallAlerts():Promise<boolean>;
+
 export function JoinChannel(arg1:number):Promise<string>;
 
-export function KickUser(arg1:number):Promise<string>;
+export function KickUser(arg1:number,arg2:string):Promise<string>;
+
+export function MeasureAudioLatency():Promise<main.LatencyResult>;
 
 export function MoveUserToChannel(arg1:number,arg2:number):Promise<string>;
 
 export function MuteUser(arg1:number):Promise<void>;
 
+export function MuteUserNotifications(arg1:number,arg2:boolean):Promise<string>;
+
+export function MuteUserServer(arg1:number,arg2:number):Promise<string>;
+
 export function PTTKeyDown():Promise<void>;
 
 export function PTTKeyUp():Promise<void>;
 
+export function PinMessage(arg1:number):Promise<string>;
+
+export function PlaySoundboard(arg1:string):Promise<string>;
+export function RecordingConsent(arg1:boolean):Promise<string>;
+
+export function PurgeMessages(arg1:number,arg2:number):Promise<string>;
+
+export function RemoveMonitorChannel(arg1:number):Promise<string>;
+
 export function RemoveReaction(arg1:number,arg2:string):Promise<string>;
 
 export function RenameChannel(arg1:number,arg2:string):Promise<string>;
@@ -69,12 +120,24 @@ export function RenameServer(arg1:string):Promise<string>;
 
 export function RenameUser(arg1:string):Promise<string>;
 
+export function RequestAuditLog():Promise<string>;
+
+export function RequestBans():Promise<string>;
+
 export function RequestChannels():Promise<string>;
 
+export function RequestEditHistory(arg1:number):Promise<string>;
+
 export function RequestMessages(arg1:number):Promise<string>;
 
+export function RequestMessagesBefore(arg1:number,arg2:number,arg3:number):Promise<string>;
+
+export function RequestPinned(arg1:number):Promise<string>;
+
 export function RequestServerInfo():Promise<string>;
 
+export function RequestThread(arg1:number):Promise<string>;
+
 export function RequestVideoQuality(arg1:number,arg2:string):Promise<string>;
 
 export function SaveConfig(arg1:config.Config):Promise<void>;
@@ -83,44 +146,140 @@ export function SendChannelChat(arg1:number,arg2:string):Promise<string>;
 
 export function SendChat(arg1:string):Promise<string>;
 
+export function SendDM(arg1:number,arg2:string):Promise<string>;
+
+export function SendReadReceipt(arg1:number):Promise<void>;
+
+export function SendTyping(arg1:number):Promise<void>;
+
 export function SetAEC(arg1:boolean):Promise<void>;
 
 export function SetAGC(arg1:boolean):Promise<void>;
 
+export function SetAGCLevel(arg1:number):Promise<void>;
+
+export function SetAGCTarget(arg1:number):Promise<void>;
+
+export function SetAdaptiveBitrate(arg1:boolean):Promise<void>;
+
+export function SetAnnouncement(arg1:string):Promise<string>;
+
 export function SetAudioBitrate(arg1:number):Promise<void>;
 
+export function SetAudioProfile(arg1:string):Promise<string>;
+
+export function SetAutoJoinVoice(arg1:string,arg2:number):Promise<string>;
+
+export function SetAutoLevel(arg1:boolean):Promise<void>;
+
+export function SetBitrateRange(arg1:number,arg2:number):Promise<string>;
+
+export function SetChannelBitrate(arg1:number,arg2:number):Promise<string>;
+
+export function SetChannelLock(arg1:number,arg2:boolean):Promise<string>;
+
+export function SetChannelNotifyLevel(arg1:number,arg2:string):Promise<string>;
+
+export function SetChannelRecordRole(arg1:number,arg2:string):Promise<string>;
+
+export function SetChannelTTL(arg1:number,arg2:number):Promise<string>;
+
+export function SetDTX(arg1:boolean):Promise<void>;
+
 export function SetDeafened(arg1:boolean):Promise<void>;
 
-export function SetInputDevice(arg1:number):Promise<void>;
+export function SetDoNotDisturb(arg1:boolean):Promise<void>;
+
+export function SetDropPolicy(arg1:string):Promise<string>;
+
+export function SetFEC(arg1:boolean):Promise<void>;
+
+export function SetFrameSize(arg1:number):Promise<string>;
+
+export function SetInCallAlerts(arg1:boolean):Promise<void>;
+
+export function SetInputDevice(arg1:number):Promise<string>;
+
+export function SetJitterBufferMs(arg1:number):Promise<void>;
+
+export function SetJoinLeaveSounds(arg1:boolean):Promise<void>;
+
+export function SetMaxPacketBytes(arg1:number):Promise<string>;
+
+export function SetMonitorVolume(arg1:number):Promise<void>;
 
 export function SetMuted(arg1:boolean):Promise<void>;
 
+export function SetNoiseGate(arg1:number,arg2:boolean):Promise<void>;
+
 export function SetNoiseSuppression(arg1:boolean):Promise<void>;
 
 export function SetNotificationVolume(arg1:number):Promise<void>;
 
-export function SetOutputDevice(arg1:number):Promise<void>;
+export function SetOnsetRedundancy(arg1:boolean):Promise<void>;
+
+export function SetOpusApplication(arg1:string):Promise<string>;
+
+export function SetOutputDevice(arg1:number):Promise<string>;
+
+export function SetPTTHotkey(arg1:string):Promise<string>;
 
 export function SetPTTMode(arg1:boolean):Promise<void>;
 
+export function SetRole(arg1:number,arg2:string):Promise<string>;
+
+export function SetSidetone(arg1:boolean,arg2:number):Promise<void>;
+
+export function SetSignalAutoDetect(arg1:boolean):Promise<void>;
+
+export function SetSignalType(arg1:string):Promise<string>;
+
+export function SetSlowMode(arg1:number,arg2:number):Promise<string>;
+
+export function SetStatus(arg1:string):Promise<string>;
+
+export function SetStereo(arg1:boolean):Promise<string>;
+
+export function SetUploadBandwidthLimit(arg1:number):Promise<void>;
+
 export function SetUserVolume(arg1:number,arg2:number):Promise<void>;
 
 export function SetVolume(arg1:number):Promise<void>;
 
+export function StartLocalRecording(arg1:string):Promise<string>;
+
 export function StartScreenShare():Promise<string>;
 
 export function StartTest():Promise<string>;
 
 export function StartVideo():Promise<string>;
 
+export function StartWhisper(arg1:number):Promise<string>;
+
+export function StopLocalRecording():Promise<string>;
+
 export function StopScreenShare():Promise<string>;
 
 export function StopTest():Promise<void>;
 
 export function StopVideo():Promise<string>;
 
+export function StopWhisper():Promise<void>;
+
+export function TransferOwner(arg1:number):Promise<string>;
+
+export function Unban(arg1:number):Promise<string>;
+
+export function UnblockUser(arg1:number):Promise<string>;
+
 export function UnmuteUser(arg1:number):Promise<void>;
 
+export function UnmuteUserServer(arg1:number):Promise<string>;
+
+export function UnpinMessage(arg1:number):Promise<string>;
+
 export function UploadFile(arg1:number):Promise<string>;
 
 export function UploadFileFromPath(arg1:number,arg2:string):Promise<string>;
+
+export function UploadFileToUser(arg1:number,arg2:string):Promise<string>;
@@ -2,6 +2,10 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddMonitorChannel(arg1) {
+  return window['go']['main']['App']['AddMonitorChannel'](arg1);
+}
+
 export function AddReaction(arg1, arg2) {
   return window['go']['main']['App']['AddReaction'](arg1, arg2);
 }
@@ -10,6 +14,18 @@ export function ApplyConfig() {
   return window['go']['main']['App']['ApplyConfig']();
 }
 
+export function AssignChannelCategory(arg1, arg2) {
+  return window['go']['main']['App']['AssignChannelCategory'](arg1, arg2);
+}
+
+export function BanUser(arg1, arg2, arg3) {
+  return window['go']['main']['App']['BanUser'](arg1, arg2, arg3);
+}
+
+export function BlockUser(arg1) {
+  return window['go']['main']['App']['BlockUser'](arg1);
+}
+
 export function Connect(arg1, arg2) {
   return window['go']['main']['App']['Connect'](arg1, arg2);
 }
@@ -18,6 +34,10 @@ export function ConnectVoice(arg1) {
   return window['go']['main']['App']['ConnectVoice'](arg1);
 }
 
+export function CreateCategory(arg1) {
+  return window['go']['main']['App']['CreateCategory'](arg1);
+}
+
 export function CreateChannel(arg1) {
   return window['go']['main']['App']['CreateChannel'](arg1);
 }
@@ -42,18 +62,42 @@ export function EditMessage(arg1, arg2) {
   return window['go']['main']['App']['EditMessage'](arg1, arg2);
 }
 
+export function ExportChannel(arg1, arg2) {
+  return window['go']['main']['App']['ExportChannel'](arg1, arg2);
+}
+
+export function GetAllowedEmoji() {
+  return window['go']['main']['App']['GetAllowedEmoji']();
+}
+
 export function GetAudioBitrate() {
   return window['go']['main']['App']['GetAudioBitrate']();
 }
 
+export function GetAutoJoinVoice(arg1) {
+  return window['go']['main']['App']['GetAutoJoinVoice'](arg1);
+}
+
 export function GetAutoLogin() {
   return window['go']['main']['App']['GetAutoLogin']();
 }
 
+export function GetBitrateCeiling() {
+  return window['go']['main']['App']['GetBitrateCeiling']();
+}
+
+export function GetBitrateFloor() {
+  return window['go']['main']['App']['GetBitrateFloor']();
+}
+
 export function GetBuildInfo() {
   return window['go']['main']['App']['GetBuildInfo']();
 }
 
+export function GetChannelNotifyLevel(arg1) {
+  return window['go']['main']['App']['GetChannelNotifyLevel'](arg1);
+}
+
 export function GetConfig() {
   return window['go']['main']['App']['GetConfig']();
 }
@@ -66,6 +110,10 @@ export function GetInputLevel() {
   return window['go']['main']['App']['GetInputLevel']();
 }
 
+export function GetMaxPacketBytes() {
+  return window['go']['main']['App']['GetMaxPacketBytes']();
+}
+
 export function GetMetrics() {
   return window['go']['main']['App']['GetMetrics']();
 }
@@ -82,6 +130,18 @@ export function GetOutputDevices() {
   return window['go']['main']['App']['GetOutputDevices']();
 }
 
+export function GetPeerMetrics() {
+  return window['go']['main']['App']['GetPeerMetrics']();
+}
+
+export function GetPermissions() {
+  return window['go']['main']['App']['GetPermissions']();
+}
+
+export function GetSignalType() {
+  return window['go']['main']['App']['GetSignalType']();
+}
+
 export function GetStartupAddr() {
   return window['go']['main']['App']['GetStartupAddr']();
 }
@@ -90,16 +150,32 @@ export function GetUserVolume(arg1) {
   return window['go']['main']['App']['GetUserVolume'](arg1);
 }
 
+export function IsAdaptiveBitrate() {
+  return window['go']['main']['App']['IsAdaptiveBitrate']();
+}
+
 export function IsConnected() {
   return window['go']['main']['App']['IsConnected']();
 }
 
+export function IsDoNotDisturb() {
+  return window['go']['main']['App']['IsDoNotDisturb']();
+}
+
+export function IsInCallAlerts() {
+  return window['go']['main']['App']['IsInCallAlerts']();
+}
+
 export function JoinChannel(arg1) {
   return window['go']['main']['App']['JoinChannel'](arg1);
 }
 
-export function KickUser(arg1) {
-  return window['go']['main']['App']['KickUser'](arg1);
+export function KickUser(arg1, arg2) {
+  return window['go']['main']['App']['KickUser'](arg1, arg2);
+}
+
+export function MeasureAudioLatency() {
+  return window['go']['main']['App']['MeasureAudioLatency']();
 }
 
 export function MoveUserToChannel(arg1, arg2) {
@@ -110,6 +186,14 @@ export function MuteUser(arg1) {
   return window['go']['main']['App']['MuteUser'](arg1);
 }
 
+export function MuteUserNotifications(arg1, arg2) {
+  return window['go']['main']['App']['MuteUserNotifications'](arg1, arg2);
+}
+
+export function MuteUserServer(arg1, arg2) {
+  return window['go']['main']['App']['MuteUserServer'](arg1, arg2);
+}
+
 export function PTTKeyDown() {
   return window['go']['main']['App']['PTTKeyDown']();
 }
@@ -118,6 +202,26 @@ export function PTTKeyUp() {
   return window['go']['main']['App']['PTTKeyUp']();
 }
 
+export function PinMessage(arg1) {
+  return window['go']['main']['App']['PinMessage'](arg1);
+}
+
+export function PlaySoundboard(arg1) {
+  return window['go']['main']['App']['PlaySoundboard'](arg1);
+}
+
+export function PurgeMessages(arg1, arg2) {
+  return window['go']['main']['App']['PurgeMessages'](arg1, arg2);
+}
+
+export function RecordingConsent(arg1) {
+  return window['go']['main']['App']['RecordingConsent'](arg1);
+}
+
+export function RemoveMonitorChannel(arg1) {
+  return window['go']['main']['App']['RemoveMonitorChannel'](arg1);
+}
+
 export function RemoveReaction(arg1, arg2) {
   return window['go']['main']['App']['RemoveReaction'](arg1, arg2);
 }
@@ -134,18 +238,42 @@ export function RenameUser(arg1) {
   return window['go']['main']['App']['RenameUser'](arg1);
 }
 
+export function RequestAuditLog() {
+  return window['go']['main']['App']['RequestAuditLog']();
+}
+
+export function RequestBans() {
+  return window['go']['main']['App']['RequestBans']();
+}
+
 export function RequestChannels() {
   return window['go']['main']['App']['RequestChannels']();
 }
 
+export function RequestEditHistory(arg1) {
+  return window['go']['main']['App']['RequestEditHistory'](arg1);
+}
+
 export function RequestMessages(arg1) {
   return window['go']['main']['App']['RequestMessages'](arg1);
 }
 
+export function RequestMessagesBefore(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RequestMessagesBefore'](arg1, arg2, arg3);
+}
+
+export function RequestPinned(arg1) {
+  return window['go']['main']['App']['RequestPinned'](arg1);
+}
+
 export function RequestServerInfo() {
   return window['go']['main']['App']['RequestServerInfo']();
 }
 
+export function RequestThread(arg1) {
+  return window['go']['main']['App']['RequestThread'](arg1);
+}
+
 export function RequestVideoQuality(arg1, arg2) {
   return window['go']['main']['App']['RequestVideoQuality'](arg1, arg2);
 }
@@ -162,6 +290,18 @@ export function SendChat(arg1) {
   return window['go']['main']['App']['SendChat'](arg1);
 }
 
+export function SendDM(arg1, arg2) {
+  return window['go']['main']['App']['SendDM'](arg1, arg2);
+}
+
+export function SendReadReceipt(arg1) {
+  return window['go']['main']['App']['SendReadReceipt'](arg1);
+}
+
+export function SendTyping(arg1) {
+  return window['go']['main']['App']['SendTyping'](arg1);
+}
+
 export function SetAEC(arg1) {
   return window['go']['main']['App']['SetAEC'](arg1);
 }
@@ -170,22 +310,118 @@ export function SetAGC(arg1) {
   return window['go']['main']['App']['SetAGC'](arg1);
 }
 
+export function SetAGCLevel(arg1) {
+  return window['go']['main']['App']['SetAGCLevel'](arg1);
+}
+
+export function SetAGCTarget(arg1) {
+  return window['go']['main']['App']['SetAGCTarget'](arg1);
+}
+
+export function SetAdaptiveBitrate(arg1) {
+  return window['go']['main']['App']['SetAdaptiveBitrate'](arg1);
+}
+
+export function SetAnnouncement(arg1) {
+  return window['go']['main']['App']['SetAnnouncement'](arg1);
+}
+
 export function SetAudioBitrate(arg1) {
   return window['go']['main']['App']['SetAudioBitrate'](arg1);
 }
 
+export function SetAudioProfile(arg1) {
+  return window['go']['main']['App']['SetAudioProfile'](arg1);
+}
+
+export function SetAutoJoinVoice(arg1, arg2) {
+  return window['go']['main']['App']['SetAutoJoinVoice'](arg1, arg2);
+}
+
+export function SetAutoLevel(arg1) {
+  return window['go']['main']['App']['SetAutoLevel'](arg1);
+}
+
+export function SetBitrateRange(arg1, arg2) {
+  return window['go']['main']['App']['SetBitrateRange'](arg1, arg2);
+}
+
+export function SetChannelBitrate(arg1, arg2) {
+  return window['go']['main']['App']['SetChannelBitrate'](arg1, arg2);
+}
+
+export function SetChannelLock(arg1, arg2) {
+  return window['go']['main']['App']['SetChannelLock'](arg1, arg2);
+}
+
+export function SetChannelNotifyLevel(arg1, arg2) {
+  return window['go']['main']['App']['SetChannelNotifyLevel'](arg1, arg2);
+}
+
+export function SetChannelRecordRole(arg1, arg2) {
+  return window['go']['main']['App']['SetChannelRecordRole'](arg1, arg2);
+}
+
+export function SetChannelTTL(arg1, arg2) {
+  return window['go']['main']['App']['SetChannelTTL'](arg1, arg2);
+}
+
+export function SetDTX(arg1) {
+  return window['go']['main']['App']['SetDTX'](arg1);
+}
+
 export function SetDeafened(arg1) {
   return window['go']['main']['App']['SetDeafened'](arg1);
 }
 
+export function SetDoNotDisturb(arg1) {
+  return window['go']['main']['App']['SetDoNotDisturb'](arg1);
+}
+
+export function SetDropPolicy(arg1) {
+  return window['go']['main']['App']['SetDropPolicy'](arg1);
+}
+
+export function SetFEC(arg1) {
+  return window['go']['main']['App']['SetFEC'](arg1);
+}
+
+export function SetFrameSize(arg1) {
+  return window['go']['main']['App']['SetFrameSize'](arg1);
+}
+
+export function SetInCallAlerts(arg1) {
+  return window['go']['main']['App']['SetInCallAlerts'](arg1);
+}
+
 export function SetInputDevice(arg1) {
   return window['go']['main']['App']['SetInputDevice'](arg1);
 }
 
+export function SetJitterBufferMs(arg1) {
+  return window['go']['main']['App']['SetJitterBufferMs'](arg1);
+}
+
+export function SetJoinLeaveSounds(arg1) {
+  return window['go']['main']['App']['SetJoinLeaveSounds'](arg1);
+}
+
+export function SetMaxPacketBytes(arg1) {
+  return window['go']['main']['App']['SetMaxPacketBytes'](arg1);
+}
+
+export function SetMonitorVolume(arg1) {
+  return window['go']['main']['App']['SetMonitorVolume'](arg1);
+}
+
 export function SetMuted(arg1) {
   return window['go']['main']['App']['SetMuted'](arg1);
 }
 
+export function SetNoiseGate(arg1, arg2) {
+  return window['go']['main']['App']['SetNoiseGate'](arg1, arg2);
+}
+
 export function SetNoiseSuppression(arg1) {
   return window['go']['main']['App']['SetNoiseSuppression'](arg1);
 }
@@ -194,14 +430,58 @@ export function SetNotificationVolume(arg1) {
   return window['go']['main']['App']['SetNotificationVolume'](arg1);
 }
 
+export function SetOnsetRedundancy(arg1) {
+  return window['go']['main']['App']['SetOnsetRedundancy'](arg1);
+}
+
+export function SetOpusApplication(arg1) {
+  return window['go']['main']['App']['SetOpusApplication'](arg1);
+}
+
 export function SetOutputDevice(arg1) {
   return window['go']['main']['App']['SetOutputDevice'](arg1);
 }
 
+export function SetPTTHotkey(arg1) {
+  return window['go']['main']['App']['SetPTTHotkey'](arg1);
+}
+
 export function SetPTTMode(arg1) {
   return window['go']['main']['App']['SetPTTMode'](arg1);
 }
 
+export function SetRole(arg1, arg2) {
+  return window['go']['main']['App']['SetRole'](arg1, arg2);
+}
+
+export function SetSidetone(arg1, arg2) {
+  return window['go']['main']['App']['SetSidetone'](arg1, arg2);
+}
+
+export function SetSignalAutoDetect(arg1) {
+  return window['go']['main']['App']['SetSignalAutoDetect'](arg1);
+}
+
+export function SetSignalType(arg1) {
+  return window['go']['main']['App']['SetSignalType'](arg1);
+}
+
+export function SetSlowMode(arg1, arg2) {
+  return window['go']['main']['App']['SetSlowMode'](arg1, arg2);
+}
+
+export function SetStatus(arg1) {
+  return window['go']['main']['App']['SetStatus'](arg1);
+}
+
+export function SetStereo(arg1) {
+  return window['go']['main']['App']['SetStereo'](arg1);
+}
+
+export function SetUploadBandwidthLimit(arg1) {
+  return window['go']['main']['App']['SetUploadBandwidthLimit'](arg1);
+}
+
 export function SetUserVolume(arg1, arg2) {
   return window['go']['main']['App']['SetUserVolume'](arg1, arg2);
 }
@@ -210,6 +490,10 @@ export function SetVolume(arg1) {
   return window['go']['main']['App']['SetVolume'](arg1);
 }
 
+export function StartLocalRecording(arg1) {
+  return window['go']['main']['App']['StartLocalRecording'](arg1);
+}
+
 export function StartScreenShare() {
   return window['go']['main']['App']['StartScreenShare']();
 }
@@ -222,6 +506,14 @@ export function StartVideo() {
   return window['go']['main']['App']['StartVideo']();
 }
 
+export function StartWhisper(arg1) {
+  return window['go']['main']['App']['StartWhisper'](arg1);
+}
+
+export function StopLocalRecording() {
+  return window['go']['main']['App']['StopLocalRecording']();
+}
+
 export function StopScreenShare() {
   return window['go']['main']['App']['StopScreenShare']();
 }
@@ -234,10 +526,34 @@ export function StopVideo() {
   return window['go']['main']['App']['StopVideo']();
 }
 
+export function StopWhisper() {
+  return window['go']['main']['App']['StopWhisper']();
+}
+
+export function TransferOwner(arg1) {
+  return window['go']['main']['App']['TransferOwner'](arg1);
+}
+
+export function Unban(arg1) {
+  return window['go']['main']['App']['Unban'](arg1);
+}
+
+export function UnblockUser(arg1) {
+  return window['go']['main']['App']['UnblockUser'](arg1);
+}
+
 export function UnmuteUser(arg1) {
   return window['go']['main']['App']['UnmuteUser'](arg1);
 }
 
+export function UnmuteUserServer(arg1) {
+  return window['go']['main']['App']['UnmuteUserServer'](arg1);
+}
+
+export function UnpinMessage(arg1) {
+  return window['go']['main']['App']['UnpinMessage'](arg1);
+}
+
 export function UploadFile(arg1) {
   return window['go']['main']['App']['UploadFile'](arg1);
 }
@@ -245,3 +561,7 @@ export function UploadFile(arg1) {
 export function UploadFileFromPath(arg1, arg2) {
   return window['go']['main']['App']['UploadFileFromPath'](arg1, arg2);
 }
+
+export function UploadFileToUser(arg1, arg2) {
+  return window['go']['main']['App']['UploadFileToUser'](arg1, arg2);
+}
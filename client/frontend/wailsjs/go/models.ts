@@ -21,11 +21,37 @@ export namespace config {
 	    output_device_id: number;
 	    volume: number;
 	    audio_bitrate_kbps: number;
+	    audio_profile: string;
+	    opus_application: string;
+	    max_packet_bytes: number;
+	    capture_frame_ms: number;
+	    adaptive_bitrate: boolean;
+	    bitrate_floor_kbps: number;
+	    bitrate_ceiling_kbps: number;
 	    noise_enabled: boolean;
 	    aec_enabled: boolean;
 	    agc_enabled: boolean;
+	    fec_enabled: boolean;
+	    dtx_enabled: boolean;
+	    stereo: boolean;
 	    ptt_enabled: boolean;
 	    ptt_key: string;
+	    noise_gate_enabled: boolean;
+	    noise_gate_db: number;
+	    agc_target_db: number;
+	    monitor_volume: number;
+	    drop_policy: string;
+	    jitter_buffer_ms: number;
+	    do_not_disturb: boolean;
+	    channel_notify: Record<string, string>;
+	    in_call_alerts: boolean;
+	    auto_join_voice: Record<string, number>;
+	    blocked_users: Record<string, Array<string>>;
+	    join_leave_sounds: boolean;
+	    notify_muted_users: Record<string, Array<string>>;
+	    signal_auto_detect: boolean;
+	    signal_type: string;
+	    upload_limit_kbps: number;
 	    servers: ServerEntry[];
 	
 	    static createFrom(source: any = {}) {
@@ -40,11 +66,37 @@ export namespace config {
 	        this.output_device_id = source["output_device_id"];
 	        this.volume = source["volume"];
 	        this.audio_bitrate_kbps = source["audio_bitrate_kbps"];
+	        this.audio_profile = source["audio_profile"];
+	        this.opus_application = source["opus_application"];
+	        this.max_packet_bytes = source["max_packet_bytes"];
+	        this.capture_frame_ms = source["capture_frame_ms"];
+	        this.adaptive_bitrate = source["adaptive_bitrate"];
+	        this.bitrate_floor_kbps = source["bitrate_floor_kbps"];
+	        this.bitrate_ceiling_kbps = source["bitrate_ceiling_kbps"];
 	        this.noise_enabled = source["noise_enabled"];
 	        this.aec_enabled = source["aec_enabled"];
 	        this.agc_enabled = source["agc_enabled"];
+	        this.fec_enabled = source["fec_enabled"];
+	        this.dtx_enabled = source["dtx_enabled"];
+	        this.stereo = source["stereo"];
 	        this.ptt_enabled = source["ptt_enabled"];
 	        this.ptt_key = source["ptt_key"];
+	        this.noise_gate_enabled = source["noise_gate_enabled"];
+	        this.noise_gate_db = source["noise_gate_db"];
+	        this.agc_target_db = source["agc_target_db"];
+	        this.monitor_volume = source["monitor_volume"];
+	        this.drop_policy = source["drop_policy"];
+	        this.jitter_buffer_ms = source["jitter_buffer_ms"];
+	        this.do_not_disturb = source["do_not_disturb"];
+	        this.channel_notify = source["channel_notify"];
+	        this.in_call_alerts = source["in_call_alerts"];
+	        this.auto_join_voice = source["auto_join_voice"];
+	        this.blocked_users = source["blocked_users"];
+	        this.join_leave_sounds = source["join_leave_sounds"];
+	        this.notify_muted_users = source["notify_muted_users"];
+	        this.signal_auto_detect = source["signal_auto_detect"];
+	        this.signal_type = source["signal_type"];
+	        this.upload_limit_kbps = source["upload_limit_kbps"];
 	        this.servers = this.convertValues(source["servers"], ServerEntry);
 	    }
 	
@@ -121,6 +173,20 @@ export namespace main {
 	        this.dirty = source["dirty"];
 	    }
 	}
+	export class LatencyResult {
+	    latency_ms: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LatencyResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.latency_ms = source["latency_ms"];
+	        this.error = source["error"];
+	    }
+	}
 	export class Metrics {
 	    rtt_ms: number;
 	    packet_loss: number;
@@ -147,6 +213,24 @@ export namespace main {
 	        this.playback_dropped = source["playback_dropped"];
 	    }
 	}
+	export class PeerMetrics {
+	    user_id: number;
+	    rtt_ms: number;
+	    packet_loss: number;
+	    jitter_ms: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PeerMetrics(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.user_id = source["user_id"];
+	        this.rtt_ms = source["rtt_ms"];
+	        this.packet_loss = source["packet_loss"];
+	        this.jitter_ms = source["jitter_ms"];
+	    }
+	}
 
 }
 
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// hotkeyPollInterval is how often the global push-to-talk key is sampled.
+// Short enough that the first syllable isn't clipped.
+const hotkeyPollInterval = 10 * time.Millisecond
+
+// keyWatcher reports whether one key is held anywhere on the desktop, not
+// just in the bken window. Implementations are platform specific; see
+// openKeyWatcher. Pressed and Close are only called from the poll goroutine.
+type keyWatcher interface {
+	Pressed() bool
+	Close()
+}
+
+// pttHotkey mirrors a global key's state into push-to-talk so PTT keeps
+// working while the window is in the background.
+type pttHotkey struct {
+	mu   sync.Mutex
+	key  string
+	stop chan struct{}
+	done chan struct{}
+
+	// open is openKeyWatcher; swapped out in tests.
+	open func(key string) (keyWatcher, error)
+}
+
+// set starts watching key, calling onChange whenever it is pressed or
+// released. An empty key stops watching. A key already being watched is
+// left alone.
+func (h *pttHotkey) set(key string, onChange func(active bool)) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if key == h.key {
+		return nil
+	}
+	h.stopLocked()
+	if key == "" {
+		return nil
+	}
+
+	open := h.open
+	if open == nil {
+		open = openKeyWatcher
+	}
+	w, err := open(key)
+	if err != nil {
+		return err
+	}
+	h.key = key
+	h.stop = make(chan struct{})
+	h.done = make(chan struct{})
+	go pollKey(w, onChange, h.stop, h.done)
+	slog.Info("global ptt hotkey registered", "key", key)
+	return nil
+}
+
+// stopLocked stops the poller and waits for it to release the key. Caller
+// holds h.mu.
+func (h *pttHotkey) stopLocked() {
+	if h.stop == nil {
+		return
+	}
+	close(h.stop)
+	<-h.done
+	slog.Info("global ptt hotkey removed", "key", h.key)
+	h.key, h.stop, h.done = "", nil, nil
+}
+
+// pollKey samples w until stop is closed, reporting each transition. A key
+// still held when polling stops is reported released so PTT doesn't stick.
+func pollKey(w keyWatcher, onChange func(active bool), stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	defer w.Close()
+
+	ticker := time.NewTicker(hotkeyPollInterval)
+	defer ticker.Stop()
+	held := false
+	for {
+		select {
+		case <-stop:
+			if held {
+				onChange(false)
+			}
+			return
+		case <-ticker.C:
+			if pressed := w.Pressed(); pressed != held {
+				held = pressed
+				onChange(held)
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+//go:build darwin
+
+package main
+
+/*
+#cgo LDFLAGS: -framework ApplicationServices
+#include <ApplicationServices/ApplicationServices.h>
+*/
+import "C"
+
+import "fmt"
+
+// macKeys maps KeyboardEvent.code names to macOS virtual key codes
+// (kVK_* in HIToolbox/Events.h).
+var macKeys = map[string]C.CGKeyCode{
+	"KeyA": 0x00, "KeyS": 0x01, "KeyD": 0x02, "KeyF": 0x03, "KeyH": 0x04,
+	"KeyG": 0x05, "KeyZ": 0x06, "KeyX": 0x07, "KeyC": 0x08, "KeyV": 0x09,
+	"KeyB": 0x0B, "KeyQ": 0x0C, "KeyW": 0x0D, "KeyE": 0x0E, "KeyR": 0x0F,
+	"KeyY": 0x10, "KeyT": 0x11, "KeyO": 0x1F, "KeyU": 0x20, "KeyI": 0x22,
+	"KeyP": 0x23, "KeyL": 0x25, "KeyJ": 0x26, "KeyK": 0x28, "KeyN": 0x2D, "KeyM": 0x2E,
+	"Digit1": 0x12, "Digit2": 0x13, "Digit3": 0x14, "Digit4": 0x15, "Digit6": 0x16,
+	"Digit5": 0x17, "Digit9": 0x19, "Digit7": 0x1A, "Digit8": 0x1C, "Digit0": 0x1D,
+	"Equal": 0x18, "Minus": 0x1B, "BracketRight": 0x1E, "BracketLeft": 0x21,
+	"Quote": 0x27, "Semicolon": 0x29, "Backslash": 0x2A, "Comma": 0x2B,
+	"Slash": 0x2C, "Period": 0x2F, "Backquote": 0x32,
+	"Tab": 0x30, "Space": 0x31, "CapsLock": 0x39,
+	"ShiftLeft": 0x38, "ShiftRight": 0x3C,
+	"AltLeft": 0x3A, "AltRight": 0x3D,
+	"ControlLeft": 0x3B, "ControlRight": 0x3E,
+	"Home": 0x73, "PageUp": 0x74, "Delete": 0x75, "End": 0x77, "PageDown": 0x79,
+	"F1": 0x7A, "F2": 0x78, "F3": 0x63, "F4": 0x76, "F5": 0x60, "F6": 0x61,
+	"F7": 0x62, "F8": 0x64, "F9": 0x65, "F10": 0x6D, "F11": 0x67, "F12": 0x6F,
+	"F13": 0x69, "F14": 0x6B, "F15": 0x71, "F16": 0x6A, "F17": 0x40, "F18": 0x4F,
+	"F19": 0x50, "F20": 0x5A,
+}
+
+type macKeyWatcher struct{ code C.CGKeyCode }
+
+// openKeyWatcher watches key through the HID system key state. macOS only
+// reports it to apps granted Input Monitoring, so ask for that up front.
+func openKeyWatcher(key string) (keyWatcher, error) {
+	code, ok := macKeys[key]
+	if !ok {
+		return nil, fmt.Errorf("%q can't be used as a global push-to-talk key", key)
+	}
+	if !C.CGPreflightListenEventAccess() {
+		C.CGRequestListenEventAccess()
+		return nil, fmt.Errorf("allow bken under System Settings > Privacy & Security > Input Monitoring to use push-to-talk in the background")
+	}
+	return macKeyWatcher{code: code}, nil
+}
+
+func (w macKeyWatcher) Pressed() bool {
+	return bool(C.CGEventSourceKeyState(C.kCGEventSourceStateHIDSystemState, w.code))
+}
+
+func (macKeyWatcher) Close() {}
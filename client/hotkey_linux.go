@@ -0,0 +1,79 @@
+//go:build linux
+
+package main
+
+/*
+#cgo LDFLAGS: -lX11
+#include <X11/Xlib.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// x11Keys maps KeyboardEvent.code names to X11 keysyms (X11/keysymdef.h).
+var x11Keys = func() map[string]C.KeySym {
+	m := map[string]C.KeySym{
+		"Backquote": 0x60, "Minus": 0x2d, "Equal": 0x3d,
+		"BracketLeft": 0x5b, "BracketRight": 0x5d, "Backslash": 0x5c,
+		"Semicolon": 0x3b, "Quote": 0x27, "Comma": 0x2c, "Period": 0x2e, "Slash": 0x2f,
+		"Space": 0x20, "Tab": 0xff09, "CapsLock": 0xffe5,
+		"ShiftLeft": 0xffe1, "ShiftRight": 0xffe2,
+		"ControlLeft": 0xffe3, "ControlRight": 0xffe4,
+		"AltLeft": 0xffe9, "AltRight": 0xffea,
+		"Insert": 0xff63, "Delete": 0xffff, "Home": 0xff50, "End": 0xff57,
+		"PageUp": 0xff55, "PageDown": 0xff56, "Pause": 0xff13, "ScrollLock": 0xff14,
+	}
+	for c := 'a'; c <= 'z'; c++ {
+		m["Key"+string(c-'a'+'A')] = C.KeySym(c)
+	}
+	for c := '0'; c <= '9'; c++ {
+		m["Digit"+string(c)] = C.KeySym(c)
+	}
+	for i := 1; i <= 24; i++ {
+		m[fmt.Sprintf("F%d", i)] = C.KeySym(0xffbe + i - 1)
+	}
+	return m
+}()
+
+type x11KeyWatcher struct {
+	display *C.Display
+	keycode C.KeyCode
+}
+
+// openKeyWatcher watches key through the X server's keymap. Wayland
+// compositors don't expose other clients' input, and XWayland only sees
+// keys while one of its own windows has focus, so Wayland sessions are
+// refused rather than silently never firing (see configureLinuxDesktopEnv).
+func openKeyWatcher(key string) (keyWatcher, error) {
+	sym, ok := x11Keys[key]
+	if !ok {
+		return nil, fmt.Errorf("%q can't be used as a global push-to-talk key", key)
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		return nil, errors.New("global push-to-talk isn't available on Wayland; the key still works while bken is focused")
+	}
+	display := C.XOpenDisplay(nil)
+	if display == nil {
+		return nil, errors.New("global push-to-talk needs an X11 display")
+	}
+	keycode := C.XKeysymToKeycode(display, sym)
+	if keycode == 0 {
+		C.XCloseDisplay(display)
+		return nil, fmt.Errorf("%q isn't on the current keyboard layout", key)
+	}
+	return &x11KeyWatcher{display: display, keycode: keycode}, nil
+}
+
+func (w *x11KeyWatcher) Pressed() bool {
+	var keys [32]C.char
+	C.XQueryKeymap(w.display, &keys[0])
+	return byte(keys[w.keycode/8])&(1<<(w.keycode%8)) != 0
+}
+
+func (w *x11KeyWatcher) Close() {
+	C.XCloseDisplay(w.display)
+}
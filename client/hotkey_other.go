@@ -0,0 +1,9 @@
+//go:build !windows && !darwin && !linux
+
+package main
+
+import "errors"
+
+func openKeyWatcher(string) (keyWatcher, error) {
+	return nil, errors.New("global push-to-talk isn't supported on this platform")
+}
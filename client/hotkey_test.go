@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeKeyWatcher struct {
+	pressed atomic.Bool
+	closed  atomic.Bool
+}
+
+func (w *fakeKeyWatcher) Pressed() bool { return w.pressed.Load() }
+func (w *fakeKeyWatcher) Close()        { w.closed.Store(true) }
+
+// activeRecorder collects onChange calls from the poll goroutine.
+type activeRecorder struct {
+	mu     sync.Mutex
+	events []bool
+}
+
+func (r *activeRecorder) record(active bool) {
+	r.mu.Lock()
+	r.events = append(r.events, active)
+	r.mu.Unlock()
+}
+
+func (r *activeRecorder) waitFor(t *testing.T, n int) []bool {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		got := append([]bool(nil), r.events...)
+		r.mu.Unlock()
+		if len(got) >= n {
+			return got
+		}
+		time.Sleep(hotkeyPollInterval)
+	}
+	t.Fatalf("timed out waiting for %d key events", n)
+	return nil
+}
+
+func TestPTTHotkeyReportsPressAndRelease(t *testing.T) {
+	w := &fakeKeyWatcher{}
+	var opened string
+	h := pttHotkey{open: func(key string) (keyWatcher, error) {
+		opened = key
+		return w, nil
+	}}
+	var rec activeRecorder
+	if err := h.set("Backquote", rec.record); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if opened != "Backquote" {
+		t.Fatalf("opened %q, want Backquote", opened)
+	}
+
+	w.pressed.Store(true)
+	rec.waitFor(t, 1)
+	w.pressed.Store(false)
+	got := rec.waitFor(t, 2)
+	if !got[0] || got[1] {
+		t.Fatalf("events = %v, want [true false]", got)
+	}
+
+	if err := h.set("", rec.record); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	if !w.closed.Load() {
+		t.Fatal("watcher not closed after clearing the hotkey")
+	}
+}
+
+func TestPTTHotkeyReleasesHeldKeyOnStop(t *testing.T) {
+	w := &fakeKeyWatcher{}
+	h := pttHotkey{open: func(string) (keyWatcher, error) { return w, nil }}
+	var rec activeRecorder
+	if err := h.set("Space", rec.record); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	w.pressed.Store(true)
+	rec.waitFor(t, 1)
+
+	// Rebinding while the old key is held must not leave PTT stuck on.
+	if err := h.set("KeyV", rec.record); err != nil {
+		t.Fatalf("rebind: %v", err)
+	}
+	if got := rec.waitFor(t, 2); got[1] {
+		t.Fatalf("events = %v, want a release after rebinding", got)
+	}
+	h.set("", rec.record)
+}
+
+func TestPTTHotkeyOpenFailureKeepsNothingRegistered(t *testing.T) {
+	h := pttHotkey{open: func(string) (keyWatcher, error) {
+		return nil, errors.New("no global hooks here")
+	}}
+	if err := h.set("Space", func(bool) {}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if h.key != "" || h.stop != nil {
+		t.Fatalf("failed registration left state behind: key=%q", h.key)
+	}
+}
@@ -0,0 +1,57 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+)
+
+var procGetAsyncKeyState = syscall.NewLazyDLL("user32.dll").NewProc("GetAsyncKeyState")
+
+// windowsKeys maps KeyboardEvent.code names to Win32 virtual-key codes.
+var windowsKeys = func() map[string]uintptr {
+	m := map[string]uintptr{
+		"Backquote": 0xC0, "Minus": 0xBD, "Equal": 0xBB,
+		"BracketLeft": 0xDB, "BracketRight": 0xDD, "Backslash": 0xDC,
+		"Semicolon": 0xBA, "Quote": 0xDE, "Comma": 0xBC, "Period": 0xBE, "Slash": 0xBF,
+		"Space": 0x20, "Tab": 0x09, "CapsLock": 0x14,
+		"ShiftLeft": 0xA0, "ShiftRight": 0xA1,
+		"ControlLeft": 0xA2, "ControlRight": 0xA3,
+		"AltLeft": 0xA4, "AltRight": 0xA5,
+		"Insert": 0x2D, "Delete": 0x2E, "Home": 0x24, "End": 0x23,
+		"PageUp": 0x21, "PageDown": 0x22, "Pause": 0x13, "ScrollLock": 0x91,
+	}
+	for c := 'A'; c <= 'Z'; c++ {
+		m["Key"+string(c)] = uintptr(c)
+	}
+	for c := '0'; c <= '9'; c++ {
+		m["Digit"+string(c)] = uintptr(c)
+	}
+	for i := 1; i <= 24; i++ {
+		m[fmt.Sprintf("F%d", i)] = uintptr(0x70 + i - 1)
+	}
+	return m
+}()
+
+type windowsKeyWatcher struct{ vk uintptr }
+
+// openKeyWatcher watches key with GetAsyncKeyState, which sees the
+// physical key state regardless of which window has focus.
+func openKeyWatcher(key string) (keyWatcher, error) {
+	vk, ok := windowsKeys[key]
+	if !ok {
+		return nil, fmt.Errorf("%q can't be used as a global push-to-talk key", key)
+	}
+	if err := procGetAsyncKeyState.Find(); err != nil {
+		return nil, fmt.Errorf("global push-to-talk is unavailable: %w", err)
+	}
+	return windowsKeyWatcher{vk: vk}, nil
+}
+
+func (w windowsKeyWatcher) Pressed() bool {
+	state, _, _ := procGetAsyncKeyState.Call(w.vk)
+	return state&0x8000 != 0
+}
+
+func (windowsKeyWatcher) Close() {}
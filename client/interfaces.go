@@ -1,16 +1,22 @@
 package main
 
-import "context"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 // Transporter is the interface wrapping the Transport methods used by App.
 // Defining it here lets App be tested with a mock transport.
 type Transporter interface {
 	Connect(ctx context.Context, addr, username string) error
 	Disconnect()
+	SetReconnect(maxAttempts int, baseDelay time.Duration)
 	SendAudio(opusData []byte) error
-	StartReceiving(ctx context.Context, playbackCh chan<- TaggedAudio)
+	StartReceiving(ctx context.Context, playbackCh chan TaggedAudio)
 	MyID() uint16
 	GetMetrics() Metrics
+	GetPeerMetrics() map[uint16]PeerMetrics
 
 	// Per-user local muting — purely client-side, no server involvement.
 	MuteUser(id uint16)
@@ -18,9 +24,15 @@ type Transporter interface {
 	IsUserMuted(id uint16) bool
 	MutedUsers() []uint16
 
+	// Blocking — local muting that also hides the user's chat.
+	BlockUser(id uint16)
+	UnblockUser(id uint16)
+	IsBlocked(id uint16) bool
+
 	// Per-user volume — client-side volume multiplier per remote user.
 	SetUserVolume(id uint16, volume float64)
 	GetUserVolume(id uint16) float64
+	MonitoredOnly(id uint16) bool
 
 	// Callback setters — prefer setters over exported fields so the interface
 	// can be satisfied by both the real Transport and test doubles.
@@ -33,9 +45,11 @@ type Transporter interface {
 	SetOnChannelChatMessage(fn func(msgID uint64, senderID uint16, channelID int64, username, message string, ts int64, fileID string, fileName string, fileSize int64, mentions []uint16))
 	SetOnLinkPreview(fn func(msgID uint64, channelID int64, url, title, desc, image, siteName string))
 	SetOnServerInfo(fn func(name string))
-	SetOnKicked(fn func())
+	SetOnServerError(fn func(message string, retryAfterMs int64))
+	SetOnKicked(fn func(reason string))
 	SetOnOwnerChanged(fn func(ownerID uint16))
 	SetOnChannelList(fn func([]ChannelInfo))
+	SetOnCategoryList(fn func([]CategoryInfo))
 	SetOnUserChannel(fn func(userID uint16, channelID int64))
 	SetOnUserRenamed(fn func(userID uint16, username string))
 	SetOnMessageEdited(fn func(msgID uint64, message string, ts int64))
@@ -43,29 +57,86 @@ type Transporter interface {
 	SetOnVideoState(fn func(userID uint16, active bool, screenShare bool))
 	SetOnReactionAdded(fn func(msgID uint64, emoji string, userID uint16))
 	SetOnReactionRemoved(fn func(msgID uint64, emoji string, userID uint16))
+	SetOnReactionsUpdated(fn func(msgID uint64, reactions []ChatHistoryReaction))
+	SetOnPermissions(fn func(Permissions))
+	SetOnDM(fn func(senderID, targetID uint16, username, message string, ts int64))
+	SetOnReconnecting(fn func(attempt int))
 	SetOnUserTyping(fn func(userID uint16, username string, channelID int64))
 	SetOnMessagePinned(fn func(msgID uint64, channelID int64, userID uint16))
 	SetOnMessageUnpinned(fn func(msgID uint64))
+	SetOnPinnedList(fn func(channelID int64, pins []PinnedMessage))
 	SetOnVideoLayers(fn func(userID uint16, layers []VideoLayer))
 	SetOnMessageHistory(fn func(channelID int64, messages []ChatHistoryMessage))
+	SetOnThread(fn func(msgID uint64, messages []ChatHistoryMessage))
+	SetOnEditHistory(fn func(msgID uint64, edits []MessageEdit))
+	SetOnAuditLog(fn func(entries []AuditEntry))
+	SetOnAuditEntry(fn func(entry AuditEntry))
+	SetOnBanList(fn func(bans []BanInfo))
 	SetOnUserVoiceFlags(fn func(userID uint16, muted, deafened bool))
+	SetOnRecordingStarted(fn func(userID uint16, consentRequired bool))
+	SetOnRecordingStopped(fn func(userID uint16))
+	SetOnSoundboard(fn func(userID uint16, clipID string))
+	SetOnPeerAudioStalled(fn func(id uint16))
+	SetOnPeerAudioResumed(fn func(id uint16))
+	SetOnAnnouncement(fn func(text, postedBy string))
+	SetOnMention(fn func(msgID uint64, channelID int64, senderID uint16, username string))
+	SetOnMessageRead(fn func(msgID uint64, readers []uint16))
+	SetOnUserMuted(fn func(userID uint16, muted bool))
+	SetOnUserStatus(fn func(userID uint16, status string))
+	SetOnSpeaking(fn func(userID uint16, speaking bool))
+	SetOnMOTD(fn func(text string))
+	SetOnJoinSounds(fn func(joinURL, leaveURL string))
+	SetOnEmojiList(fn func(emoji []string))
+	SetOnServerShutdown(fn func(graceMs int64))
+	SetOnStopRecording(fn func())
 
 	// Voice state broadcasting.
 	SendVoiceFlags(muted, deafened bool) error
+	SendRecordingConsent(consent bool) error
+	SendVoiceActivity() error
+	SendSpeaking() error
+	SetStatus(status string) error
+	SetStereo(enabled bool)
+	SetOnsetRedundancy(enabled bool)
+	SetDropPolicy(policy string) error
+	SetWhisperTarget(id uint16) error
+	ClearWhisper()
+	WhisperTarget() uint16
+	SendSoundboard(clipID string) error
+	SetAnnouncement(text string) error
 
 	// Chat.
 	SendChat(message string) error
+	SendTyping(channelID int64) error
 	SendFileChat(channelID int64, fileID string, fileSize int64, fileName, message string) error
+	SendDM(targetID uint16, message string) error
 	EditMessage(msgID uint64, message string) error
 	DeleteMessage(msgID uint64) error
+	PurgeMessages(channelID int64, count int) error
 	AddReaction(msgID uint64, emoji string) error
 	RemoveReaction(msgID uint64, emoji string) error
+	SendReadReceipt(msgID uint64) error
 
 	// File API.
 	APIBaseURL() string
+	MaxUploadBytes() int64
+	ExportChannel(ctx context.Context, channelID int64, format string, w io.Writer) error
+
+	// Reactions.
+	AllowedEmoji() []string
 
 	// Moderation.
 	KickUser(id uint16) error
+	KickUserWithReason(id uint16, reason string) error
+	BanUser(id uint16, reason string, durationS int) error
+	RequestBans() error
+	Unban(id int64) error
+	PinMessage(msgID uint64) error
+	UnpinMessage(msgID uint64) error
+	MuteUserServer(id uint16, durationS int) error
+	UnmuteUserServer(id uint16) error
+	TransferOwner(id uint16) error
+	SetRole(id uint16, role string) error
 
 	// Server management (owner-only; server enforces).
 	RenameServer(name string) error
@@ -78,13 +149,30 @@ type Transporter interface {
 	SendChannelChat(channelID int64, message string) error
 	CreateChannel(name string) error
 	RenameChannel(id int64, name string) error
+	SetChannelBitrate(id int64, kbps int) error
+	SetSlowMode(id int64, seconds int) error
+	SetChannelLock(id int64, locked bool) error
+	SetChannelTTL(id int64, seconds int) error
+	SetChannelRecordRole(id int64, role string) error
+	StartRecording() error
+	StopRecording() error
+	AddMonitorChannel(channelID int64) error
+	RemoveMonitorChannel(channelID int64) error
+	CreateCategory(name string) error
+	AssignChannelCategory(channelID, categoryID int64) error
 	DeleteChannel(id int64) error
 	MoveUser(userID uint16, channelID int64) error
 
 	// Pull-based state requests.
 	RequestChannels() error
 	RequestMessages(channelID int64) error
+	RequestMessagesBefore(channelID int64, before uint64, limit int) error
+	RequestThread(msgID uint64) error
+	RequestEditHistory(msgID uint64) error
+	RequestPinned(channelID int64) error
+	RequestAuditLog() error
 	RequestServerInfo() error
+	GetPermissions() error
 
 	// Video.
 	SendVideoState(active bool, screenShare bool) error
@@ -16,13 +16,77 @@ type Config struct {
 	OutputDeviceID int     `json:"output_device_id"`
 	Volume         float64 `json:"volume"`
 	AudioBitrate   int     `json:"audio_bitrate_kbps"`
+	// AudioProfile names the encoder preset last chosen ("voice-low-cpu",
+	// "voice" or "music"); it also decides the Opus complexity.
+	AudioProfile string `json:"audio_profile"`
+	// MaxPacketBytes caps each encoded Opus frame; 0 means no cap.
+	MaxPacketBytes int `json:"max_packet_bytes"`
+	// OpusApplication is the Opus encoder mode: "voip", "audio" or
+	// "lowdelay".
+	OpusApplication string `json:"opus_application"`
+	// CaptureFrameMs is the audio carried by each encoded Opus frame: 10,
+	// 20, 40 or 60 ms.
+	CaptureFrameMs int `json:"capture_frame_ms"`
+	// Adaptive bitrate: when enabled the bitrate follows connection quality
+	// within [BitrateFloorKbps, BitrateCeilingKbps].
+	AdaptiveBitrate    bool `json:"adaptive_bitrate"`
+	BitrateFloorKbps   int  `json:"bitrate_floor_kbps"`
+	BitrateCeilingKbps int  `json:"bitrate_ceiling_kbps"`
 	// WebRTC built-in voice processing preferences.
-	NoiseEnabled bool          `json:"noise_enabled"`
-	AECEnabled   bool          `json:"aec_enabled"`
-	AGCEnabled   bool          `json:"agc_enabled"`
-	PTTEnabled   bool          `json:"ptt_enabled"`
-	PTTKey       string        `json:"ptt_key"` // keyboard key code (e.g. "Space", "Backquote")
-	Servers      []ServerEntry `json:"servers"`
+	NoiseEnabled bool   `json:"noise_enabled"`
+	AECEnabled   bool   `json:"aec_enabled"`
+	AGCEnabled   bool   `json:"agc_enabled"`
+	FECEnabled   bool   `json:"fec_enabled"` // Opus in-band forward error correction
+	DTXEnabled   bool   `json:"dtx_enabled"` // don't send silent frames
+	Stereo       bool   `json:"stereo"`      // stereo capture; mono saves bandwidth
+	PTTEnabled   bool   `json:"ptt_enabled"`
+	PTTKey       string `json:"ptt_key"` // keyboard key code (e.g. "Space", "Backquote")
+	// OnsetRedundancy sends the first frames after silence twice.
+	OnsetRedundancy bool `json:"onset_redundancy"`
+	// Noise gate: capture frames quieter than NoiseGateDb (dBFS) are
+	// silenced before encoding.
+	NoiseGateEnabled bool    `json:"noise_gate_enabled"`
+	NoiseGateDb      float64 `json:"noise_gate_db"`
+	// AGCTargetDb is the loudness (dBFS) automatic gain control steers the
+	// microphone toward when AGCEnabled is set.
+	AGCTargetDb float64 `json:"agc_target_db"`
+	// Sidetone plays the microphone back locally at SidetoneGain (0-1).
+	SidetoneEnabled bool    `json:"sidetone_enabled"`
+	SidetoneGain    float64 `json:"sidetone_gain"`
+	// MonitorVolume is how loud monitored voice channels play (0-1).
+	MonitorVolume float64 `json:"monitor_volume"`
+	// DropPolicy is which received frame playback loses when it falls
+	// behind: "newest" or "oldest".
+	DropPolicy string `json:"drop_policy"`
+	// AutoLevel brings every speaker toward a common playback loudness.
+	AutoLevel bool `json:"auto_level"`
+	// JitterBufferMs is how much audio playback holds back per speaker.
+	JitterBufferMs int `json:"jitter_buffer_ms"`
+	// DoNotDisturb suppresses notification sounds.
+	DoNotDisturb bool `json:"do_not_disturb"`
+	// ChannelNotify holds per-channel notification levels ("all",
+	// "mentions", "none") keyed by "<server addr>/<channel id>".
+	ChannelNotify map[string]string `json:"channel_notify,omitempty"`
+	// InCallAlerts mixes mention alerts into call audio, ducking the
+	// other participants briefly.
+	InCallAlerts bool `json:"in_call_alerts"`
+	// AutoJoinVoice maps a server address to the voice channel ID joined
+	// automatically after connecting.
+	AutoJoinVoice map[string]int64 `json:"auto_join_voice,omitempty"`
+	// BlockedUsers maps a server address to the usernames blocked there.
+	BlockedUsers map[string][]string `json:"blocked_users,omitempty"`
+	// JoinLeaveSounds plays a sound when users join or leave a server.
+	// NotifyMutedUsers maps a server address to the usernames whose join
+	// and leave sounds are muted there.
+	JoinLeaveSounds  bool                `json:"join_leave_sounds"`
+	NotifyMutedUsers map[string][]string `json:"notify_muted_users,omitempty"`
+	// Opus signal-type hint: auto-detect speech vs music, or a fixed
+	// "voice"/"music" type when auto-detection is off.
+	SignalAutoDetect bool   `json:"signal_auto_detect"`
+	SignalType       string `json:"signal_type"`
+	// UploadLimitKbps caps file upload bandwidth; 0 means unlimited.
+	UploadLimitKbps int           `json:"upload_limit_kbps"`
+	Servers         []ServerEntry `json:"servers"`
 }
 
 // ServerEntry is a saved server shown in the server browser.
@@ -34,16 +98,31 @@ type ServerEntry struct {
 // Default returns a Config populated with sensible defaults.
 func Default() Config {
 	return Config{
-		Theme:          "dark",
-		Volume:         1.0,
-		AudioBitrate:   32,
-		NoiseEnabled:   true,
-		AECEnabled:     true,
-		AGCEnabled:     true,
-		PTTEnabled:     false,
-		PTTKey:         "Backquote",
-		InputDeviceID:  -1,
-		OutputDeviceID: -1,
+		Theme:              "dark",
+		Volume:             1.0,
+		AudioBitrate:       32,
+		AudioProfile:       "voice",
+		OpusApplication:    "voip",
+		CaptureFrameMs:     20,
+		BitrateFloorKbps:   16,
+		BitrateCeilingKbps: 64,
+		NoiseEnabled:       true,
+		AECEnabled:         true,
+		AGCEnabled:         true,
+		FECEnabled:         true,
+		DTXEnabled:         true,
+		JitterBufferMs:     40,
+		PTTEnabled:         false,
+		PTTKey:             "Backquote",
+		NoiseGateDb:        -50,
+		AGCTargetDb:        -20,
+		SidetoneGain:       0.5,
+		MonitorVolume:      0.5,
+		DropPolicy:         "newest",
+		JoinLeaveSounds:    true,
+		SignalType:         "voice",
+		InputDeviceID:      -1,
+		OutputDeviceID:     -1,
 		Servers: []ServerEntry{
 			{Name: "Local Dev", Addr: "localhost:8080"},
 		},
@@ -34,12 +34,54 @@ func TestDefault(t *testing.T) {
 	if !cfg.AECEnabled {
 		t.Error("expected echo cancellation enabled by default")
 	}
+	if !cfg.DTXEnabled {
+		t.Error("expected DTX enabled by default")
+	}
+	if cfg.Stereo {
+		t.Error("expected mono capture by default")
+	}
+	if cfg.JitterBufferMs != 40 {
+		t.Errorf("expected 40 ms jitter buffer by default, got %d", cfg.JitterBufferMs)
+	}
 	if cfg.PTTEnabled {
 		t.Error("expected PTT disabled by default")
 	}
 	if cfg.PTTKey != "Backquote" {
 		t.Errorf("expected default PTT key 'Backquote', got %q", cfg.PTTKey)
 	}
+	if cfg.NoiseGateEnabled || cfg.NoiseGateDb != -50 {
+		t.Errorf("expected noise gate off at -50 dB by default, got %v at %v dB", cfg.NoiseGateEnabled, cfg.NoiseGateDb)
+	}
+	if cfg.AGCTargetDb != -20 {
+		t.Errorf("expected AGC target -20 dB by default, got %v", cfg.AGCTargetDb)
+	}
+	if cfg.SidetoneEnabled || cfg.SidetoneGain != 0.5 {
+		t.Errorf("expected sidetone off at gain 0.5 by default, got %v at %v", cfg.SidetoneEnabled, cfg.SidetoneGain)
+	}
+	if cfg.AutoLevel {
+		t.Error("expected auto-level disabled by default")
+	}
+	if cfg.SignalAutoDetect {
+		t.Error("expected signal auto-detect disabled by default")
+	}
+	if cfg.AudioProfile != "voice" {
+		t.Errorf("expected default audio profile 'voice', got %q", cfg.AudioProfile)
+	}
+	if cfg.OpusApplication != "voip" {
+		t.Errorf("expected default opus application 'voip', got %q", cfg.OpusApplication)
+	}
+	if cfg.CaptureFrameMs != 20 {
+		t.Errorf("expected default capture frame 20 ms, got %d", cfg.CaptureFrameMs)
+	}
+	if cfg.SignalType != "voice" {
+		t.Errorf("expected default signal type 'voice', got %q", cfg.SignalType)
+	}
+	if cfg.AdaptiveBitrate {
+		t.Error("expected adaptive bitrate disabled by default")
+	}
+	if cfg.BitrateFloorKbps != 16 || cfg.BitrateCeilingKbps != 64 {
+		t.Errorf("expected default bitrate range 16-64, got %d-%d", cfg.BitrateFloorKbps, cfg.BitrateCeilingKbps)
+	}
 }
 
 func TestSaveAndLoad(t *testing.T) {
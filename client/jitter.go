@@ -0,0 +1,124 @@
+package main
+
+import "log/slog"
+
+const (
+	// frameMs is the duration of one playback frame (FrameSize samples).
+	frameMs = 20
+	// defaultJitterBufferMs is how much audio each sender's buffer holds
+	// back before playback starts.
+	defaultJitterBufferMs = 40
+	// maxJitterBufferMs bounds the user setting; beyond this the added
+	// latency makes conversation impractical.
+	maxJitterBufferMs = 500
+	// jitterResetSeqGap is how far behind the last played frame a Seq may
+	// fall before it is taken as a restarted stream rather than a late frame.
+	jitterResetSeqGap = 100
+)
+
+// SetJitterBufferMs sets how many milliseconds of audio the playback mixer
+// holds back per sender before playing, clamped to [0, maxJitterBufferMs].
+// A deeper buffer rides out bursty links and reorders late packets at the
+// cost of that much extra latency.
+func (ae *AudioEngine) SetJitterBufferMs(ms int) {
+	if ms < 0 {
+		ms = 0
+	}
+	if ms > maxJitterBufferMs {
+		ms = maxJitterBufferMs
+	}
+	ae.jitterBufferMs.Store(int32(ms))
+	slog.Debug("jitter buffer updated", "ms", ms)
+}
+
+// JitterBufferMs returns the configured jitter buffer depth.
+func (ae *AudioEngine) JitterBufferMs() int {
+	return int(ae.jitterBufferMs.Load())
+}
+
+// jitterFrames converts a buffer depth in ms to whole frames, rounding up so
+// any non-zero depth holds back at least one frame.
+func jitterFrames(ms int) int {
+	return (ms + frameMs - 1) / frameMs
+}
+
+// seqBefore reports whether RTP sequence number a precedes b, allowing for
+// wraparound.
+func seqBefore(a, b uint16) bool {
+	return int16(a-b) < 0
+}
+
+// jitterBuffer holds one sender's frames in Seq order. It releases nothing
+// until depth frames are queued or the first frame has waited depth cycles,
+// then plays one frame per cycle until it runs dry and has to refill.
+type jitterBuffer struct {
+	depth   int
+	frames  []TaggedAudio
+	primed  bool
+	waited  int
+	played  bool
+	lastSeq uint16
+}
+
+// push queues a frame in Seq order. Duplicates and frames arriving after
+// their slot has already played are dropped. Returns false if the frame was
+// dropped, including when the buffer is full and the oldest frame is
+// discarded to make room.
+func (b *jitterBuffer) push(f TaggedAudio) bool {
+	if b.played && !seqBefore(b.lastSeq, f.Seq) {
+		if int16(b.lastSeq-f.Seq) < jitterResetSeqGap {
+			return false
+		}
+		// The sender restarted its sequence; start over.
+		*b = jitterBuffer{depth: b.depth}
+	}
+	i := len(b.frames)
+	for i > 0 && seqBefore(f.Seq, b.frames[i-1].Seq) {
+		i--
+	}
+	if i > 0 && b.frames[i-1].Seq == f.Seq {
+		return false
+	}
+	b.frames = append(b.frames, TaggedAudio{})
+	copy(b.frames[i+1:], b.frames[i:])
+	b.frames[i] = f
+
+	// Cap the queue so a sender whose clock runs fast can't grow latency
+	// without bound.
+	if limit := b.depth + jitterFrames(maxJitterBufferMs); len(b.frames) > limit {
+		b.frames = b.frames[1:]
+		return false
+	}
+	return true
+}
+
+// ready reports whether pop would return a frame without counting an
+// underrun.
+func (b *jitterBuffer) ready() bool {
+	return b.primed && len(b.frames) > 0
+}
+
+// pop returns the next frame to play this cycle, if any.
+func (b *jitterBuffer) pop() (TaggedAudio, bool) {
+	if !b.primed {
+		if len(b.frames) == 0 {
+			return TaggedAudio{}, false
+		}
+		b.waited++
+		if len(b.frames) < b.depth && b.waited <= b.depth {
+			return TaggedAudio{}, false
+		}
+		b.primed = true
+	}
+	if len(b.frames) == 0 {
+		// Underrun: refill before playing again.
+		b.primed = false
+		b.waited = 0
+		return TaggedAudio{}, false
+	}
+	f := b.frames[0]
+	b.frames = b.frames[1:]
+	b.played = true
+	b.lastSeq = f.Seq
+	return f, true
+}
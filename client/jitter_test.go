@@ -0,0 +1,120 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// jitteryArrivals is a 20 ms-per-cycle arrival schedule: frame 1 arrives a
+// cycle late behind frame 2, then the link stalls and 5 and 6 land together.
+var jitteryArrivals = [][]uint16{{0}, {2}, {1, 3}, {4}, {}, {5, 6}, {7}, {8}, {9}}
+
+// simulateJitter plays the schedule through a buffer of the given depth and
+// returns the frames played, the cycle playback started on, and how many
+// silent cycles interrupted it.
+func simulateJitter(depthMs int) (played []uint16, start, gaps int) {
+	jb := &jitterBuffer{depth: jitterFrames(depthMs)}
+	start = -1
+	silent := 0
+	for cycle := 0; cycle < len(jitteryArrivals)+10; cycle++ {
+		if cycle < len(jitteryArrivals) {
+			for _, seq := range jitteryArrivals[cycle] {
+				jb.push(TaggedAudio{SenderID: 1, Seq: seq})
+			}
+		}
+		f, ok := jb.pop()
+		if !ok {
+			silent++
+			continue
+		}
+		if start < 0 {
+			start = cycle
+		} else {
+			gaps += silent
+		}
+		silent = 0
+		played = append(played, f.Seq)
+	}
+	return played, start, gaps
+}
+
+func TestJitterBufferDepthTradesLatencyForSmoothness(t *testing.T) {
+	// No holdback: playback starts at once, but the late frame is dropped
+	// and the stall is audible.
+	played, start, gaps := simulateJitter(0)
+	if want := []uint16{0, 2, 3, 4, 5, 6, 7, 8, 9}; !slices.Equal(played, want) {
+		t.Errorf("0 ms played %v, want %v", played, want)
+	}
+	if start != 0 || gaps != 1 {
+		t.Errorf("0 ms start=%d gaps=%d, want start=0 gaps=1", start, gaps)
+	}
+
+	// 60 ms holdback: two cycles (40 ms) more latency, but every frame is
+	// played in order with no gaps.
+	played, start, gaps = simulateJitter(60)
+	if want := []uint16{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}; !slices.Equal(played, want) {
+		t.Errorf("60 ms played %v, want %v", played, want)
+	}
+	if start != 2 || gaps != 0 {
+		t.Errorf("60 ms start=%d gaps=%d, want start=2 gaps=0", start, gaps)
+	}
+}
+
+func TestJitterBufferReleasesShortBursts(t *testing.T) {
+	// A single frame shorter than the depth still plays once it has waited
+	// the full holdback.
+	jb := &jitterBuffer{depth: 3}
+	jb.push(TaggedAudio{Seq: 7})
+	for i := 0; i < 3; i++ {
+		if _, ok := jb.pop(); ok {
+			t.Fatalf("frame released early on cycle %d", i)
+		}
+	}
+	if f, ok := jb.pop(); !ok || f.Seq != 7 {
+		t.Fatalf("pop = %v, %v; want seq 7", f.Seq, ok)
+	}
+}
+
+func TestJitterBufferDropsDuplicatesAndLateFrames(t *testing.T) {
+	jb := &jitterBuffer{}
+	if !jb.push(TaggedAudio{Seq: 65535}) {
+		t.Fatal("first frame rejected")
+	}
+	if jb.push(TaggedAudio{Seq: 65535}) {
+		t.Fatal("duplicate accepted")
+	}
+	if !jb.push(TaggedAudio{Seq: 0}) {
+		t.Fatal("frame after wraparound rejected")
+	}
+	if f, _ := jb.pop(); f.Seq != 65535 {
+		t.Fatalf("popped %d, want 65535", f.Seq)
+	}
+	if jb.push(TaggedAudio{Seq: 65534}) {
+		t.Fatal("frame older than the last played accepted")
+	}
+	if f, _ := jb.pop(); f.Seq != 0 {
+		t.Fatalf("popped %d, want 0", f.Seq)
+	}
+	// A sequence far behind the last played frame is a restarted stream.
+	if !jb.push(TaggedAudio{Seq: 60000}) {
+		t.Fatal("restarted stream rejected")
+	}
+}
+
+func TestSetJitterBufferMsClamps(t *testing.T) {
+	ae := NewAudioEngine()
+	if got := ae.JitterBufferMs(); got != defaultJitterBufferMs {
+		t.Fatalf("default = %d, want %d", got, defaultJitterBufferMs)
+	}
+	ae.SetJitterBufferMs(-10)
+	if got := ae.JitterBufferMs(); got != 0 {
+		t.Errorf("negative clamped to %d, want 0", got)
+	}
+	ae.SetJitterBufferMs(10_000)
+	if got := ae.JitterBufferMs(); got != maxJitterBufferMs {
+		t.Errorf("large clamped to %d, want %d", got, maxJitterBufferMs)
+	}
+	if got := jitterFrames(defaultJitterBufferMs); got != 2 {
+		t.Errorf("jitterFrames(%d) = %d, want 2", defaultJitterBufferMs, got)
+	}
+}
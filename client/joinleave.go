@@ -0,0 +1,47 @@
+package main
+
+import "log/slog"
+
+// SetJoinLeaveSounds turns the sounds played when users join or leave the
+// server on or off for everyone. MuteUserNotifications silences single
+// users instead.
+func (a *App) SetJoinLeaveSounds(enabled bool) {
+	a.joinLeaveSilenced.Store(!enabled)
+	slog.Debug("join/leave sounds updated", "enabled", enabled)
+}
+
+// MuteUserNotifications silences or restores the join and leave sounds for
+// one user on the current server. Like a block, it is saved by username,
+// so it applies again on later connections.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) MuteUserNotifications(id int, muted bool) string {
+	slog.Debug("MuteUserNotifications", "user_id", id, "muted", muted)
+	if _, err := a.requireTransport(); err != nil {
+		return err.Error()
+	}
+	a.mu.RLock()
+	addr := a.serverAddr
+	a.mu.RUnlock()
+	saved, err := a.notifyMuted.set(addr, uint16(id), muted)
+	if err != nil {
+		return err.Error()
+	}
+
+	cfg := LoadConfig()
+	cfg.NotifyMutedUsers = saved
+	if err := SaveConfig(cfg); err != nil {
+		slog.Error("save notification mutes failed", "user_id", id, "err", err)
+		return err.Error()
+	}
+	return ""
+}
+
+// playJoinLeaveSound plays sound (or the server's url for it) for user id
+// joining or leaving the session on addr, unless do-not-disturb, the global
+// toggle or a per-user mute silences it.
+func (a *App) playJoinLeaveSound(addr string, id uint16, url string, sound NotificationSound) {
+	if a.doNotDisturb.Load() || a.joinLeaveSilenced.Load() || a.notifyMuted.has(addr, id) {
+		return
+	}
+	a.audio.PlaySound(url, sound)
+}
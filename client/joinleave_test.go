@@ -0,0 +1,68 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// expectNoSound fails if ae queues a notification within a short wait.
+func expectNoSound(t *testing.T, ae *AudioEngine, what string) {
+	t.Helper()
+	select {
+	case <-ae.notifCh:
+		t.Fatalf("%s: notification frame queued", what)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestJoinLeaveSoundsCanBeDisabled(t *testing.T) {
+	app, _ := newTestApp()
+	app.SetJoinLeaveSounds(false)
+	app.playJoinLeaveSound("srv:8080", 5, "", SoundUserJoined)
+	expectNoSound(t, app.audio, "join with sounds disabled")
+	app.playJoinLeaveSound("srv:8080", 5, "", SoundUserLeft)
+	expectNoSound(t, app.audio, "leave with sounds disabled")
+
+	app.SetJoinLeaveSounds(true)
+	app.playJoinLeaveSound("srv:8080", 5, "", SoundUserJoined)
+	select {
+	case <-app.audio.notifCh:
+	case <-time.After(time.Second):
+		t.Fatal("join sound not played after re-enabling")
+	}
+}
+
+func TestMuteUserNotificationsPersistsByUsername(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	app, _ := newTestApp()
+	app.serverAddr = "srv:8080"
+	app.notifyMuted.observe("srv:8080", 5, "bob")
+
+	if errMsg := app.MuteUserNotifications(9, true); errMsg == "" {
+		t.Error("expected an error muting an unknown user")
+	}
+	if errMsg := app.MuteUserNotifications(5, true); errMsg != "" {
+		t.Fatalf("MuteUserNotifications: %s", errMsg)
+	}
+	app.playJoinLeaveSound("srv:8080", 5, "", SoundUserJoined)
+	expectNoSound(t, app.audio, "join of a muted user")
+	if got := LoadConfig().NotifyMutedUsers["srv:8080"]; !slices.Equal(got, []string{"bob"}) {
+		t.Fatalf("saved mutes = %v, want [bob]", got)
+	}
+
+	// After a restart bob has a new ID, but the mute follows his name.
+	app2, _ := newTestApp()
+	app2.serverAddr = "srv:8080"
+	app2.ApplyConfig()
+	app2.notifyMuted.observe("srv:8080", 12, "bob")
+	if !app2.notifyMuted.has("srv:8080", 12) {
+		t.Fatal("bob's join sounds should stay muted after a restart")
+	}
+	if errMsg := app2.MuteUserNotifications(12, false); errMsg != "" {
+		t.Fatalf("unmute: %s", errMsg)
+	}
+	if got := LoadConfig().NotifyMutedUsers; len(got) != 0 {
+		t.Errorf("saved mutes = %v, want none", got)
+	}
+}
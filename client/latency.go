@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// latencyQuietFrames is how many captured frames are replaced with
+	// silence before the test tone, so audio already in flight has drained
+	// when the tone is listened for.
+	latencyQuietFrames = 10
+	// latencyToneHz and latencyToneAmp describe the one-frame test tone.
+	latencyToneHz  = 1000
+	latencyToneAmp = 0.8
+	// latencyDetectRMS is the playback level that counts as hearing the
+	// tone; Opus smears it a little, but a full frame stays well above.
+	latencyDetectRMS = 0.2
+	// latencyTimeout bounds MeasureLatency when the tone never comes back.
+	latencyTimeout = 2 * time.Second
+)
+
+// latencyProbe measures the loopback delay from the capture loop to the
+// playback mix. While it runs it takes over the captured audio: a few
+// frames of silence, one frame of tone, then silence until it is heard.
+type latencyProbe struct {
+	mu     sync.Mutex
+	quiet  int       // silent frames still to send before the tone
+	sentAt time.Time // when the tone was captured; zero until then
+	done   bool
+	result chan time.Duration // buffered; receives the delay once
+	now    func() time.Time
+}
+
+func newLatencyProbe() *latencyProbe {
+	return &latencyProbe{
+		quiet:  latencyQuietFrames,
+		result: make(chan time.Duration, 1),
+		now:    time.Now,
+	}
+}
+
+// capture replaces a captured frame, interleaved stereo when stereo is set.
+func (p *latencyProbe) capture(frame []float32, stereo bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.quiet > 0 || !p.sentAt.IsZero() {
+		p.quiet = max(p.quiet-1, 0)
+		zeroFloat32(frame)
+		return
+	}
+	ch := 1
+	if stereo {
+		ch = 2
+	}
+	for i := range frame {
+		t := float64(i/ch) / sampleRate
+		frame[i] = float32(latencyToneAmp * math.Sin(2*math.Pi*latencyToneHz*t))
+	}
+	p.sentAt = p.now()
+}
+
+// played looks for the tone in a mixed playback frame.
+func (p *latencyProbe) played(frame []float32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done || p.sentAt.IsZero() || frameRMS(frame) < latencyDetectRMS {
+		return
+	}
+	p.done = true
+	p.result <- p.now().Sub(p.sentAt)
+}
+
+// MeasureLatency plays a test tone through the loopback test and returns
+// how long it took from capture to the playback mix. The microphone is
+// replaced by the probe while it runs. Only valid during StartTest.
+func (ae *AudioEngine) MeasureLatency() (time.Duration, error) {
+	if !ae.testMode.Load() || !ae.running.Load() {
+		return 0, errors.New("start the audio test first")
+	}
+	p := newLatencyProbe()
+	if !ae.latency.CompareAndSwap(nil, p) {
+		return 0, errors.New("a latency measurement is already running")
+	}
+	defer ae.latency.Store(nil)
+
+	select {
+	case d := <-p.result:
+		return d, nil
+	case <-time.After(latencyTimeout):
+		return 0, errors.New("test tone was not heard back; push-to-talk may be holding back the mic")
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// runLoopback feeds loud microphone frames through p and a loopback that
+// plays each frame delay frames after capture, returning the measured
+// latency once the probe hears its tone.
+func runLoopback(t *testing.T, p *latencyProbe, delay int, stereo bool) time.Duration {
+	t.Helper()
+	now := time.Unix(1000, 0)
+	p.now = func() time.Time { return now }
+	frameLen := FrameSize
+	if stereo {
+		frameLen *= 2
+	}
+	var line [][]float32
+	for range 100 {
+		mic := make([]float32, frameLen)
+		for i := range mic {
+			mic[i] = 0.9 // the user talking over the test
+		}
+		p.capture(mic, stereo)
+		if stereo {
+			mono := make([]float32, FrameSize)
+			downmix(mono, mic)
+			mic = mono
+		}
+		line = append(line, mic)
+		out := make([]float32, FrameSize)
+		if len(line) > delay {
+			out, line = line[0], line[1:]
+		}
+		p.played(out)
+		select {
+		case d := <-p.result:
+			return d
+		default:
+		}
+		now = now.Add(20 * time.Millisecond)
+	}
+	t.Fatal("tone never heard")
+	return 0
+}
+
+func TestLatencyProbeMeasuresLoopbackDelay(t *testing.T) {
+	for _, stereo := range []bool{false, true} {
+		if d := runLoopback(t, newLatencyProbe(), 4, stereo); d != 80*time.Millisecond {
+			t.Errorf("stereo=%v: latency %v, want 80ms", stereo, d)
+		}
+	}
+	// The tone in a frame straight back is measured as no delay at all.
+	if d := runLoopback(t, newLatencyProbe(), 0, false); d != 0 {
+		t.Errorf("latency %v with no delay, want 0", d)
+	}
+}
+
+func TestMeasureAudioLatencyNeedsLoopbackTest(t *testing.T) {
+	app, _ := newTestApp()
+	res := app.MeasureAudioLatency()
+	if res.Error == "" || res.LatencyMs != 0 {
+		t.Fatalf("got %+v, want an error outside the audio test", res)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"slices"
+)
+
+// AddMonitorChannel asks the server to let us also hear channelID while
+// staying in our own voice channel. Only moderators and above may; the
+// server enforces the check. Once our voice state lists the channel, its
+// members send us their audio and canHear accepts it.
+func (t *Transport) AddMonitorChannel(channelID int64) error {
+	return t.writeJSON(map[string]any{
+		"type":       "monitor_channel",
+		"channel_id": t.wireChannelID(channelID),
+	})
+}
+
+// RemoveMonitorChannel stops monitoring channelID.
+func (t *Transport) RemoveMonitorChannel(channelID int64) error {
+	return t.writeJSON(map[string]any{
+		"type":       "unmonitor_channel",
+		"channel_id": t.wireChannelID(channelID),
+	})
+}
+
+// MonitoredOnly reports whether we hear id only because we monitor their
+// channel, not because they share ours.
+func (t *Transport) MonitoredOnly(id uint16) bool {
+	v, ok := t.userChannels.Load(id)
+	if !ok {
+		return false
+	}
+	channelID, _ := v.(int64)
+	return channelID != 0 && channelID != t.myChannel.Load() && t.monitors(t.MyID(), channelID)
+}
+
+// setMonitoring records the channels a user monitors from their voice
+// state.
+func (t *Transport) setMonitoring(id uint16, voice *backendVoiceState) {
+	if voice == nil || len(voice.Monitoring) == 0 {
+		t.userMonitoring.Delete(id)
+		return
+	}
+	channels := make([]int64, 0, len(voice.Monitoring))
+	for _, wire := range voice.Monitoring {
+		channels = append(channels, t.localChannelID(wire))
+	}
+	t.userMonitoring.Store(id, channels)
+}
+
+// monitors reports whether user id monitors channelID.
+func (t *Transport) monitors(id uint16, channelID int64) bool {
+	if channelID == 0 {
+		return false
+	}
+	v, ok := t.userMonitoring.Load(id)
+	if !ok {
+		return false
+	}
+	channels, _ := v.([]int64)
+	return slices.Contains(channels, channelID)
+}
+
+// AddMonitorChannel also listens to another voice channel on this server
+// without leaving our own. Moderators and above only.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) AddMonitorChannel(channelID int64) string {
+	slog.Debug("AddMonitorChannel", "channel_id", channelID)
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.AddMonitorChannel(channelID); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// RemoveMonitorChannel stops listening to a channel added with
+// AddMonitorChannel.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) RemoveMonitorChannel(channelID int64) string {
+	slog.Debug("RemoveMonitorChannel", "channel_id", channelID)
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.RemoveMonitorChannel(channelID); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// SetMonitorVolume sets how loud monitored channels play (0-1), on top of
+// each user's own volume, so they can be kept under our own channel.
+func (a *App) SetMonitorVolume(volume float64) {
+	volume = min(max(volume, 0), 1)
+	a.monitorVolume.Store(math.Float64bits(volume))
+}
+
+// monitorGain returns the extra playback gain for senderID on tr.
+func (a *App) monitorGain(tr Transporter, senderID uint16) float64 {
+	if !tr.MonitoredOnly(senderID) {
+		return 1
+	}
+	return math.Float64frombits(a.monitorVolume.Load())
+}
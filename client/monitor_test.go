@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestMonitoredChannelIsHeard(t *testing.T) {
+	tr := NewTransport()
+	tr.myID = 1
+	tr.myChannel.Store(1)
+	tr.userChannels.Store(uint16(2), int64(1))
+	tr.userChannels.Store(uint16(3), int64(2))
+
+	if !tr.canHear(2) || tr.canHear(3) {
+		t.Fatalf("before monitoring: canHear(2)=%v canHear(3)=%v", tr.canHear(2), tr.canHear(3))
+	}
+
+	tr.setMonitoring(1, &backendVoiceState{ChannelID: "1", Monitoring: []string{"2"}})
+	if !tr.canHear(3) {
+		t.Error("a member of the monitored channel should be heard")
+	}
+	if !tr.MonitoredOnly(3) || tr.MonitoredOnly(2) {
+		t.Errorf("MonitoredOnly(3)=%v MonitoredOnly(2)=%v, want true/false", tr.MonitoredOnly(3), tr.MonitoredOnly(2))
+	}
+
+	tr.setMonitoring(1, &backendVoiceState{ChannelID: "1"})
+	if tr.canHear(3) {
+		t.Error("monitoring ended but the channel is still heard")
+	}
+}
+
+func TestMonitorReceivesOurAudio(t *testing.T) {
+	tr := NewTransport()
+	tr.myChannel.Store(1)
+	tr.userChannels.Store(uint16(3), int64(2))
+
+	if tr.monitors(3, 1) {
+		t.Fatal("user 3 is not monitoring yet")
+	}
+	// user 3 sits in channel 2 but monitors ours, so writeAudio includes them.
+	tr.setMonitoring(3, &backendVoiceState{ChannelID: "2", Monitoring: []string{"1"}})
+	if !tr.monitors(3, 1) || tr.peerInMyChannel(3, 1) {
+		t.Errorf("monitors(3, 1)=%v peerInMyChannel=%v, want true/false", tr.monitors(3, 1), tr.peerInMyChannel(3, 1))
+	}
+	tr.setMonitoring(3, nil)
+	if tr.monitors(3, 1) {
+		t.Error("leaving voice should end monitoring")
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"cmp"
+	"log/slog"
+	"math"
+)
+
+const (
+	// Noise gate threshold range in dBFS of frame RMS.
+	minNoiseGateDb     = -80
+	maxNoiseGateDb     = 0
+	defaultNoiseGateDb = -50
+	// noiseGateHangoverFrames keeps the gate open for a short while after
+	// the level drops so word tails aren't clipped (10 frames = 200 ms).
+	noiseGateHangoverFrames = 10
+)
+
+// SetNoiseGate configures the capture noise gate. While enabled, frames
+// whose RMS is below thresholdDb (dBFS, clamped to [-80, 0]) are replaced
+// with silence before encoding. The gate stays off in push-to-talk mode,
+// where holding the key already decides what is sent.
+func (ae *AudioEngine) SetNoiseGate(thresholdDb float64, enabled bool) {
+	thresholdDb = max(minNoiseGateDb, min(maxNoiseGateDb, thresholdDb))
+	ae.noiseGateDb.Store(math.Float64bits(thresholdDb))
+	ae.noiseGateEnabled.Store(enabled)
+	slog.Debug("noise gate updated", "threshold_db", thresholdDb, "enabled", enabled)
+}
+
+// NoiseGate returns the noise gate threshold in dBFS and whether it is on.
+func (ae *AudioEngine) NoiseGate() (thresholdDb float64, enabled bool) {
+	return math.Float64frombits(ae.noiseGateDb.Load()), ae.noiseGateEnabled.Load()
+}
+
+// gateFrame applies the noise gate to one captured frame with level rms,
+// zeroing buf when the gate is closed. It returns the frame's level after
+// gating so later stages see gated frames as silence.
+func (ae *AudioEngine) gateFrame(g *noiseGate, buf []float32, rms float32) float32 {
+	if !ae.noiseGateEnabled.Load() || ae.pttMode.Load() {
+		return rms
+	}
+	threshold := float32(math.Pow(10, math.Float64frombits(ae.noiseGateDb.Load())/20))
+	if g.open(rms, threshold) {
+		return rms
+	}
+	clear(buf)
+	return 0
+}
+
+// noiseGate tracks the hangover of the capture noise gate.
+type noiseGate struct {
+	hangover int // frames still to pass after the last one above threshold
+	// hangoverFrames is the hangover length in frames; 0 means
+	// noiseGateHangoverFrames. Set it for frames other than 20 ms.
+	hangoverFrames int
+}
+
+// open reports whether a frame with the given level passes the gate.
+func (g *noiseGate) open(rms, threshold float32) bool {
+	if rms >= threshold {
+		g.hangover = cmp.Or(g.hangoverFrames, noiseGateHangoverFrames)
+		return true
+	}
+	if g.hangover > 0 {
+		g.hangover--
+		return true
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// gateTestFrame returns a frame of alternating ±amp samples (RMS = amp).
+func gateTestFrame(amp float32) []float32 {
+	buf := make([]float32, FrameSize)
+	for i := range buf {
+		if i%2 == 0 {
+			buf[i] = amp
+		} else {
+			buf[i] = -amp
+		}
+	}
+	return buf
+}
+
+func TestNoiseGateNoiseThenBurst(t *testing.T) {
+	ae := NewAudioEngine()
+	ae.SetNoiseGate(-40, true)
+	var g noiseGate
+
+	// -60 dBFS noise stays below the -40 dBFS threshold.
+	const noise, burst = 0.001, 0.3
+	for i := 0; i < 5; i++ {
+		buf := gateTestFrame(noise)
+		if rms := ae.gateFrame(&g, buf, frameRMS(buf)); rms != 0 || buf[0] != 0 {
+			t.Fatalf("noise frame %d passed the gate (rms %v, sample %v)", i, rms, buf[0])
+		}
+	}
+
+	buf := gateTestFrame(burst)
+	if rms := ae.gateFrame(&g, buf, frameRMS(buf)); rms == 0 || buf[0] != burst {
+		t.Fatal("burst was gated")
+	}
+
+	// The word tail passes during the hangover, then the gate closes.
+	for i := 0; i < noiseGateHangoverFrames; i++ {
+		buf := gateTestFrame(noise)
+		ae.gateFrame(&g, buf, frameRMS(buf))
+		if buf[0] != noise {
+			t.Fatalf("frame %d inside the hangover was gated", i)
+		}
+	}
+	buf = gateTestFrame(noise)
+	ae.gateFrame(&g, buf, frameRMS(buf))
+	if buf[0] != 0 {
+		t.Fatal("noise after the hangover passed the gate")
+	}
+}
+
+func TestNoiseGateBypassedForPTT(t *testing.T) {
+	ae := NewAudioEngine()
+	ae.SetNoiseGate(-20, true)
+	ae.SetPTTMode(true)
+	ae.SetPTTActive(true)
+	var g noiseGate
+
+	buf := gateTestFrame(0.001)
+	ae.gateFrame(&g, buf, frameRMS(buf))
+	if buf[0] == 0 {
+		t.Fatal("noise gate dropped audio while push-to-talk was held")
+	}
+}
+
+func TestSetNoiseGateClampsThreshold(t *testing.T) {
+	ae := NewAudioEngine()
+	if db, enabled := ae.NoiseGate(); db != defaultNoiseGateDb || enabled {
+		t.Errorf("default gate: got %v dB enabled=%v", db, enabled)
+	}
+	ae.SetNoiseGate(-120, true)
+	if db, _ := ae.NoiseGate(); db != minNoiseGateDb {
+		t.Errorf("low threshold: got %v dB, want %d", db, minNoiseGateDb)
+	}
+	ae.SetNoiseGate(math.Inf(1), false)
+	if db, enabled := ae.NoiseGate(); db != maxNoiseGateDb || enabled {
+		t.Errorf("high threshold: got %v dB enabled=%v", db, enabled)
+	}
+}
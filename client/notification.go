@@ -8,12 +8,14 @@ import (
 type NotificationSound int
 
 const (
-	SoundConnect   NotificationSound = iota // ascending two-tone: C5 → G5
-	SoundDisconnect                         // descending two-tone: G5 → C5
-	SoundUserJoined                         // single high ping: A5
-	SoundUserLeft                           // single low ping: A4
-	SoundMute                               // descending tone: C5 → A4
-	SoundUnmute                             // ascending tone: A4 → C5
+	SoundConnect    NotificationSound = iota // ascending two-tone: C5 → G5
+	SoundDisconnect                          // descending two-tone: G5 → C5
+	SoundUserJoined                          // single high ping: A5
+	SoundUserLeft                            // single low ping: A4
+	SoundMute                                // descending tone: C5 → A4
+	SoundUnmute                              // ascending tone: A4 → C5
+	SoundMessage                             // short soft ping: E6
+	SoundMention                             // bright two-note chime: E6 → A6
 )
 
 // notifVolume is the peak amplitude of notification tones in the [-1, 1] range.
@@ -22,11 +24,21 @@ const notifVolume = 0.18
 // PlayNotification enqueues synthesised PCM frames for sound onto notifCh.
 // It runs asynchronously and drops frames if the channel is full so it never
 // blocks the caller. The goroutine exits when the audio engine stops.
+// Nothing is played while do-not-disturb is on.
 func (ae *AudioEngine) PlayNotification(sound NotificationSound) {
+	if ae.doNotDisturb.Load() {
+		return
+	}
 	frames := generateNotificationFrames(sound)
 	if len(frames) == 0 {
 		return
 	}
+	ae.queueNotification(frames)
+}
+
+// queueNotification pushes frames onto notifCh from a goroutine, dropping
+// any that don't fit.
+func (ae *AudioEngine) queueNotification(frames [][]float32) {
 	go func() {
 		stopCh := ae.stopCh
 		for _, frame := range frames {
@@ -41,6 +53,17 @@ func (ae *AudioEngine) PlayNotification(sound NotificationSound) {
 	}()
 }
 
+// SetDoNotDisturb suppresses (true) or restores (false) notification sounds.
+// Voice audio is unaffected.
+func (ae *AudioEngine) SetDoNotDisturb(enabled bool) {
+	ae.doNotDisturb.Store(enabled)
+}
+
+// IsDoNotDisturb reports whether notification sounds are suppressed.
+func (ae *AudioEngine) IsDoNotDisturb() bool {
+	return ae.doNotDisturb.Load()
+}
+
 // generateNotificationFrames returns a slice of FrameSize float32 PCM frames
 // for the requested sound.
 func generateNotificationFrames(sound NotificationSound) [][]float32 {
@@ -62,6 +85,10 @@ func generateNotificationFrames(sound NotificationSound) [][]float32 {
 		tones = []tone{{523, 80}, {440, 100}} // C5 → A4
 	case SoundUnmute:
 		tones = []tone{{440, 80}, {523, 100}} // A4 → C5
+	case SoundMessage:
+		tones = []tone{{1319, 60}} // E6
+	case SoundMention:
+		tones = []tone{{1319, 60}, {1760, 90}} // E6 → A6
 	default:
 		return nil
 	}
@@ -99,8 +126,12 @@ func generateSineTone(freq float64, durationMs int) [][]float32 {
 		}
 		raw[i] = s * env * notifVolume
 	}
+	return chunkFrames(raw)
+}
 
-	// Chunk into FrameSize slices.
+// chunkFrames splits raw into FrameSize slices ready to push onto notifCh,
+// padding the last one with silence.
+func chunkFrames(raw []float32) [][]float32 {
 	var frames [][]float32
 	for off := 0; off < len(raw); off += FrameSize {
 		end := off + FrameSize
@@ -3,6 +3,7 @@ package main
 import (
 	"math"
 	"testing"
+	"time"
 )
 
 func TestGenerateSineToneFrameCount(t *testing.T) {
@@ -77,6 +78,8 @@ func TestGenerateNotificationFramesAllSounds(t *testing.T) {
 		SoundUserLeft,
 		SoundMute,
 		SoundUnmute,
+		SoundMessage,
+		SoundMention,
 	}
 	for _, s := range sounds {
 		frames := generateNotificationFrames(s)
@@ -98,3 +101,25 @@ func TestGenerateNotificationFramesUnknownSound(t *testing.T) {
 		t.Errorf("unknown sound should return nil, got %d frames", len(frames))
 	}
 }
+
+func TestDoNotDisturbSuppressesNotifications(t *testing.T) {
+	ae := NewAudioEngine()
+	ae.SetDoNotDisturb(true)
+	if !ae.IsDoNotDisturb() {
+		t.Fatal("IsDoNotDisturb should report true")
+	}
+	ae.PlayNotification(SoundUserJoined)
+	select {
+	case <-ae.notifCh:
+		t.Fatal("notification frame queued while do-not-disturb is on")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	ae.SetDoNotDisturb(false)
+	ae.PlayNotification(SoundUserJoined)
+	select {
+	case <-ae.notifCh:
+	case <-time.After(time.Second):
+		t.Fatal("notification not queued after do-not-disturb was turned off")
+	}
+}
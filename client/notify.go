@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// Per-channel chat notification levels.
+const (
+	NotifyAll      = "all"      // sound and badge for every message
+	NotifyMentions = "mentions" // only for messages that mention us
+	NotifyNone     = "none"     // never
+)
+
+func validNotifyLevel(level string) bool {
+	switch level {
+	case NotifyAll, NotifyMentions, NotifyNone:
+		return true
+	}
+	return false
+}
+
+// channelNotifyKey is the Config.ChannelNotify key for a channel on a server.
+func channelNotifyKey(serverAddr string, channelID int64) string {
+	return serverAddr + "/" + strconv.FormatInt(channelID, 10)
+}
+
+// channelNotifyLevel returns the effective level for a channel: the saved
+// setting if there is one, otherwise "all" for the channel we are in and
+// "mentions" everywhere else.
+func (a *App) channelNotifyLevel(serverAddr string, channelID int64) string {
+	a.notifyMu.Lock()
+	level, ok := a.channelNotify[channelNotifyKey(serverAddr, channelID)]
+	a.notifyMu.Unlock()
+	if ok {
+		return level
+	}
+	if channelID == a.activeChannel.Load() {
+		return NotifyAll
+	}
+	return NotifyMentions
+}
+
+// shouldNotifyChat reports whether an incoming chat message warrants a sound
+// and an unread badge. Our own messages never do.
+func (a *App) shouldNotifyChat(serverAddr string, channelID int64, senderID, myID uint16, mentions []uint16) bool {
+	if senderID == myID {
+		return false
+	}
+	switch a.channelNotifyLevel(serverAddr, channelID) {
+	case NotifyAll:
+		return true
+	case NotifyMentions:
+		return slices.Contains(mentions, myID)
+	}
+	return false
+}
+
+// SetChannelNotifyLevel sets the notification level ("all", "mentions" or
+// "none") for a channel on the current server and saves it to the config.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SetChannelNotifyLevel(channelID int, level string) string {
+	if !validNotifyLevel(level) {
+		return fmt.Sprintf("unknown notification level %q", level)
+	}
+	a.mu.RLock()
+	addr := a.serverAddr
+	a.mu.RUnlock()
+	if addr == "" {
+		return "not connected to a server"
+	}
+
+	a.notifyMu.Lock()
+	if a.channelNotify == nil {
+		a.channelNotify = make(map[string]string)
+	}
+	a.channelNotify[channelNotifyKey(addr, int64(channelID))] = level
+	saved := maps.Clone(a.channelNotify)
+	a.notifyMu.Unlock()
+
+	cfg := LoadConfig()
+	cfg.ChannelNotify = saved
+	if err := SaveConfig(cfg); err != nil {
+		slog.Error("save channel notify level failed", "channel_id", channelID, "err", err)
+		return err.Error()
+	}
+	return ""
+}
+
+// GetChannelNotifyLevel returns the effective notification level for a
+// channel on the current server.
+func (a *App) GetChannelNotifyLevel(channelID int) string {
+	a.mu.RLock()
+	addr := a.serverAddr
+	a.mu.RUnlock()
+	return a.channelNotifyLevel(addr, int64(channelID))
+}
+
+// notifyChat marks payload with whether the message should badge the channel
+// and plays a cue when it should. Messages that mention us stay quiet here:
+// the server follows them with a mention, which notifyMention sounds.
+func (a *App) notifyChat(payload map[string]any, serverAddr string, channelID int64, senderID, myID uint16, mentions []uint16) {
+	notify := a.shouldNotifyChat(serverAddr, channelID, senderID, myID, mentions)
+	payload["notify"] = notify
+	if !notify || slices.Contains(mentions, myID) {
+		return
+	}
+	a.audio.PlayNotification(SoundMessage)
+}
+
+// notifyMention plays the mention cue unless the channel is muted. During a
+// call it is mixed into the call audio when in-call alerts are enabled.
+func (a *App) notifyMention(serverAddr string, channelID int64) {
+	if a.channelNotifyLevel(serverAddr, channelID) == NotifyNone {
+		return
+	}
+	if a.inCallAlerts.Load() && a.connected.Load() {
+		if err := a.audio.PlayAlert(AlertMention); err != nil {
+			slog.Warn("play mention alert", "err", err)
+		}
+		return
+	}
+	a.audio.PlayNotification(SoundMention)
+}
+
+// notifyDM plays a cue for an incoming direct message, mixed into the call
+// audio when in-call alerts are enabled and we are in voice.
+func (a *App) notifyDM() {
+	if a.inCallAlerts.Load() && a.connected.Load() {
+		if err := a.audio.PlayAlert(AlertDM); err != nil {
+			slog.Warn("play dm alert", "err", err)
+		}
+		return
+	}
+	a.audio.PlayNotification(SoundMessage)
+}
+
+// SetInCallAlerts enables or disables mixing mention alerts into call audio.
+func (a *App) SetInCallAlerts(enabled bool) {
+	a.inCallAlerts.Store(enabled)
+}
+
+// IsInCallAlerts reports whether in-call mention alerts are enabled.
+func (a *App) IsInCallAlerts() bool {
+	return a.inCallAlerts.Load()
+}
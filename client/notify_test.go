@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+func TestChannelNotifyLevelDefaults(t *testing.T) {
+	app, _ := newTestApp()
+	app.activeChannel.Store(3)
+	if got := app.channelNotifyLevel("srv:8080", 3); got != NotifyAll {
+		t.Errorf("active channel: got %q, want %q", got, NotifyAll)
+	}
+	if got := app.channelNotifyLevel("srv:8080", 4); got != NotifyMentions {
+		t.Errorf("other channel: got %q, want %q", got, NotifyMentions)
+	}
+}
+
+func TestShouldNotifyChat(t *testing.T) {
+	app, _ := newTestApp()
+	app.activeChannel.Store(1)
+	app.channelNotify = map[string]string{
+		channelNotifyKey("srv:8080", 1): NotifyNone,
+		channelNotifyKey("srv:8080", 2): NotifyAll,
+	}
+	const me, other = 7, 9
+	tests := []struct {
+		name     string
+		channel  int64
+		sender   uint16
+		mentions []uint16
+		want     bool
+	}{
+		{"muted active channel", 1, other, []uint16{me}, false},
+		{"all", 2, other, nil, true},
+		{"own message", 2, me, nil, false},
+		{"default mentions, not mentioned", 5, other, []uint16{other}, false},
+		{"default mentions, mentioned", 5, other, []uint16{other, me}, true},
+	}
+	for _, tt := range tests {
+		if got := app.shouldNotifyChat("srv:8080", tt.channel, tt.sender, me, tt.mentions); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetChannelNotifyLevelPersists(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	app, _ := newTestApp()
+	app.serverAddr = "srv:8080"
+
+	if errMsg := app.SetChannelNotifyLevel(4, "loud"); errMsg == "" {
+		t.Fatal("expected error for unknown level")
+	}
+	if errMsg := app.SetChannelNotifyLevel(4, NotifyNone); errMsg != "" {
+		t.Fatalf("SetChannelNotifyLevel: %s", errMsg)
+	}
+	if got := app.GetChannelNotifyLevel(4); got != NotifyNone {
+		t.Errorf("got %q, want %q", got, NotifyNone)
+	}
+	if got := LoadConfig().ChannelNotify["srv:8080/4"]; got != NotifyNone {
+		t.Errorf("saved level: got %q, want %q", got, NotifyNone)
+	}
+
+	// A fresh app picks the level up from config.
+	app2, _ := newTestApp()
+	app2.serverAddr = "srv:8080"
+	app2.ApplyConfig()
+	if got := app2.GetChannelNotifyLevel(4); got != NotifyNone {
+		t.Errorf("after ApplyConfig: got %q, want %q", got, NotifyNone)
+	}
+}
+
+func TestSetChannelNotifyLevelRequiresServer(t *testing.T) {
+	app, _ := newTestApp()
+	if errMsg := app.SetChannelNotifyLevel(1, NotifyAll); errMsg == "" {
+		t.Fatal("expected error with no server")
+	}
+}
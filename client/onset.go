@@ -0,0 +1,104 @@
+package main
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	// onsetRedundantFrames is how many frames at the start of a talk spurt
+	// are sent twice while onset redundancy is on.
+	onsetRedundantFrames = 3
+	// onsetGapFrames is how many frame durations the sender must have
+	// been quiet for the next frame to start a new talk spurt.
+	onsetGapFrames = 3
+	// opusClockRate is the RTP clock rate for Opus, whatever the sample
+	// rate actually encoded (RFC 7587).
+	opusClockRate = 48000
+)
+
+// SetOnsetRedundancy enables or disables repeating the first frames after a
+// silence gap. The first packet after DTX silence is the one most often
+// lost, and Opus FEC cannot rebuild it since there was no previous packet
+// to carry its copy, so each onset frame is sent again just ahead of the
+// next frame. Receivers drop whichever copy arrives second.
+func (t *Transport) SetOnsetRedundancy(enabled bool) {
+	t.onsetRedundancy.Store(enabled)
+}
+
+// opusPacketizer turns Opus frames into RTP packets for one peer's track,
+// one frame per packet.
+type opusPacketizer struct {
+	seq uint16
+	ts  uint32
+	// repeat is the previous onset frame's packet, sent again ahead of
+	// the next frame.
+	repeat *rtp.Packet
+}
+
+func newOpusPacketizer() *opusPacketizer {
+	return &opusPacketizer{seq: uint16(rand.Uint32()), ts: rand.Uint32()}
+}
+
+// packetize returns the packets to send for a frame of duration d: a repeat
+// of the previous frame if it asked for one, then the frame's own packet.
+// onset marks the frame as starting a talk spurt; redundant asks for it to
+// be repeated with the next frame.
+func (p *opusPacketizer) packetize(frame []byte, d time.Duration, onset, redundant bool) []*rtp.Packet {
+	pkt := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         onset,
+			SequenceNumber: p.seq,
+			Timestamp:      p.ts,
+		},
+		Payload: frame,
+	}
+	p.seq++
+	p.ts += uint32(d * opusClockRate / time.Second)
+
+	out := make([]*rtp.Packet, 0, 2)
+	if p.repeat != nil {
+		out = append(out, p.repeat)
+		p.repeat = nil
+	}
+	out = append(out, pkt)
+	if redundant {
+		p.repeat = pkt
+	}
+	return out
+}
+
+// onsetFrame reports whether a frame written at now starts a talk spurt,
+// and whether it should be sent twice. Only the pacer goroutine calls it.
+func (t *Transport) onsetFrame(now time.Time, d time.Duration) (onset, redundant bool) {
+	onset = t.lastAudioWrite.IsZero() || now.Sub(t.lastAudioWrite) > onsetGapFrames*d
+	t.lastAudioWrite = now
+	if onset {
+		t.onsetLeft = onsetRedundantFrames
+	}
+	if t.onsetLeft == 0 {
+		return onset, false
+	}
+	t.onsetLeft--
+	return onset, t.onsetRedundancy.Load()
+}
+
+// duplicateSeqLocked reports whether seq from senderID has already been
+// received, remembering it if not. lastSeq must already reflect any
+// forward progress seq made. Caller holds t.statsMu.
+func (t *Transport) duplicateSeqLocked(senderID, seq uint16) bool {
+	back := int(t.lastSeq[senderID] - seq)
+	if back >= 64 {
+		// Too far behind to tell; let it through.
+		return false
+	}
+	bit := uint64(1) << back
+	if t.seenSeqs[senderID]&bit != 0 {
+		return true
+	}
+	t.seenSeqs[senderID] |= bit
+	return false
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// onsetSpurt packetizes a talk spurt of n 20 ms frames, each frame's
+// payload being its index, the way writeAudio does for one peer.
+func onsetSpurt(t *testing.T, tx *Transport, start time.Time, n int) []*rtp.Packet {
+	t.Helper()
+	p := newOpusPacketizer()
+	var out []*rtp.Packet
+	for i := range n {
+		d := 20 * time.Millisecond
+		onset, redundant := tx.onsetFrame(start.Add(time.Duration(i)*d), d)
+		out = append(out, p.packetize([]byte{byte(i)}, d, onset, redundant)...)
+	}
+	return out
+}
+
+// receiveSpurt feeds packets to a fresh receiver and returns the frames it
+// hands to playback.
+func receiveSpurt(t *testing.T, packets []*rtp.Packet) (*Transport, []byte) {
+	t.Helper()
+	rx := NewTransport()
+	rx.myChannel.Store(1)
+	rx.userChannels.Store(uint16(2), int64(1))
+	playback := make(chan TaggedAudio, len(packets))
+	rx.playbackCh = playback
+	for _, pkt := range packets {
+		rx.handleIncomingAudio(2, pkt.SequenceNumber, pkt.Payload)
+	}
+	close(playback)
+	var frames []byte
+	for a := range playback {
+		frames = append(frames, a.OpusData[0])
+	}
+	return rx, frames
+}
+
+func TestOnsetRedundancyFillsLostFirstPacket(t *testing.T) {
+	tx := NewTransport()
+	tx.SetOnsetRedundancy(true)
+	packets := onsetSpurt(t, tx, time.Now(), 5)
+	if len(packets) != 5+onsetRedundantFrames {
+		t.Fatalf("sent %d packets, want %d", len(packets), 5+onsetRedundantFrames)
+	}
+	if !packets[0].Marker {
+		t.Error("first packet of the spurt should carry the marker bit")
+	}
+
+	// The original first packet is lost; its repeat arrives with frame 1.
+	rx, frames := receiveSpurt(t, packets[1:])
+	if string(frames) != "\x00\x01\x02\x03\x04" {
+		t.Fatalf("played frames %v, want 0-4 once each", frames)
+	}
+	if ps := rx.peerStats[2]; ps.lost != 0 || ps.expected != 4 {
+		t.Errorf("lost/expected = %d/%d, want 0/4", ps.lost, ps.expected)
+	}
+
+	// With nothing lost, every repeat is dropped and not counted.
+	rx, frames = receiveSpurt(t, packets)
+	if string(frames) != "\x00\x01\x02\x03\x04" {
+		t.Fatalf("played frames %v, want 0-4 once each", frames)
+	}
+	if ps := rx.peerStats[2]; ps.lost != 0 || ps.expected != 4 {
+		t.Errorf("lost/expected = %d/%d, want 0/4", ps.lost, ps.expected)
+	}
+}
+
+func TestOnsetFrameFollowsSilence(t *testing.T) {
+	tx := NewTransport()
+	d := 20 * time.Millisecond
+	now := time.Now()
+
+	// Redundancy is off by default, but onsets are still marked.
+	if onset, redundant := tx.onsetFrame(now, d); !onset || redundant {
+		t.Fatalf("first frame: onset/redundant = %v/%v, want true/false", onset, redundant)
+	}
+
+	for i := 1; i < 10; i++ {
+		if onset, _ := tx.onsetFrame(now.Add(time.Duration(i)*d), d); onset {
+			t.Fatalf("frame %d mid-spurt marked as an onset", i)
+		}
+	}
+	tx.SetOnsetRedundancy(true)
+	now = now.Add(10 * d)
+	if onset, redundant := tx.onsetFrame(now, d); onset || redundant {
+		t.Fatalf("late in the spurt: onset/redundant = %v/%v, want false/false", onset, redundant)
+	}
+
+	// A DTX pause starts a new spurt whose first frames are repeated.
+	now = now.Add(time.Second)
+	for i := range onsetRedundantFrames + 1 {
+		onset, redundant := tx.onsetFrame(now.Add(time.Duration(i)*d), d)
+		if onset != (i == 0) || redundant != (i < onsetRedundantFrames) {
+			t.Fatalf("frame %d after pause: onset/redundant = %v/%v", i, onset, redundant)
+		}
+	}
+}
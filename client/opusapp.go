@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"gopkg.in/hraban/opus.v2"
+)
+
+// Opus application modes accepted by SetOpusApplication.
+const (
+	// OpusApplicationVoIP favours speech intelligibility; the default.
+	OpusApplicationVoIP = "voip"
+	// OpusApplicationAudio keeps music and other non-speech audio closest
+	// to the input.
+	OpusApplicationAudio = "audio"
+	// OpusApplicationLowDelay turns off the speech modes to shave a few
+	// milliseconds of algorithmic delay.
+	OpusApplicationLowDelay = "lowdelay"
+)
+
+// opusApplications maps each mode to its libopus application.
+var opusApplications = map[string]opus.Application{
+	OpusApplicationVoIP:     opus.AppVoIP,
+	OpusApplicationAudio:    opus.AppAudio,
+	OpusApplicationLowDelay: opus.AppRestrictedLowdelay,
+}
+
+// SetOpusApplication sets the Opus application mode: "voip", "audio" or
+// "lowdelay". libopus fixes the mode when an encoder is created, so a
+// running engine builds a new encoder with the current settings and
+// captureLoop picks it up on its next frame. The streams and the peer
+// connections carrying the packets are left alone; Opus packets are
+// self-describing, so receivers need no notice.
+func (ae *AudioEngine) SetOpusApplication(mode string) error {
+	if _, ok := opusApplications[mode]; !ok {
+		return fmt.Errorf("unknown opus application %q", mode)
+	}
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+	if mode == ae.opusApp {
+		return nil
+	}
+	prev := ae.opusApp
+	ae.opusApp = mode
+	if ae.encoder != nil {
+		enc, err := ae.newEncoder(ae.captureChannels, int(ae.currentBitrate.Load()))
+		if err != nil {
+			ae.opusApp = prev
+			return err
+		}
+		ae.encoder = enc
+	}
+	slog.Debug("opus application updated", "mode", mode)
+	return nil
+}
+
+// OpusApplication returns the Opus application mode.
+func (ae *AudioEngine) OpusApplication() string {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+	return ae.opusApp
+}
+
+// SetOpusApplication sets the Opus application mode ("voip", "audio" or
+// "lowdelay") and saves it to the config. In voice the encoder is rebuilt
+// in place without reconnecting.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SetOpusApplication(mode string) string {
+	if err := a.audio.SetOpusApplication(mode); err != nil {
+		return err.Error()
+	}
+	cfg := LoadConfig()
+	cfg.OpusApplication = mode
+	if err := SaveConfig(cfg); err != nil {
+		slog.Error("save opus application failed", "mode", mode, "err", err)
+		return err.Error()
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+
+	"client/internal/config"
+)
+
+func TestOpusApplicationDefaultsToVoIP(t *testing.T) {
+	if got := NewAudioEngine().OpusApplication(); got != OpusApplicationVoIP {
+		t.Errorf("engine default = %q, want %q", got, OpusApplicationVoIP)
+	}
+	if got := config.Default().OpusApplication; got != OpusApplicationVoIP {
+		t.Errorf("config default = %q, want %q", got, OpusApplicationVoIP)
+	}
+	if got := audioProfiles[AudioProfileVoice].application; got != OpusApplicationVoIP {
+		t.Errorf("voice profile = %q, want %q", got, OpusApplicationVoIP)
+	}
+}
+
+func TestSetOpusApplicationRebuildsEncoder(t *testing.T) {
+	ae := NewAudioEngine()
+	// Stand in for a running engine: an encoder is in use for mono capture.
+	ae.captureChannels = 1
+	ae.encoder = &mockEncoder{}
+
+	for _, mode := range []string{OpusApplicationAudio, OpusApplicationLowDelay, OpusApplicationVoIP} {
+		prev := ae.encoder
+		if err := ae.SetOpusApplication(mode); err != nil {
+			t.Fatalf("SetOpusApplication(%q): %v", mode, err)
+		}
+		if got := ae.OpusApplication(); got != mode {
+			t.Errorf("OpusApplication() = %q, want %q", got, mode)
+		}
+		if ae.encoder == prev {
+			t.Errorf("%s: encoder was not rebuilt", mode)
+		}
+		if _, err := ae.EncodeFrame(make([]int16, FrameSize)); err != nil {
+			t.Errorf("%s: encode: %v", mode, err)
+		}
+	}
+
+	if err := ae.SetOpusApplication("karaoke"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+	if got := ae.OpusApplication(); got != OpusApplicationVoIP {
+		t.Errorf("after a bad mode: %q, want %q", got, OpusApplicationVoIP)
+	}
+}
+
+func TestSetOpusApplicationBeforeStart(t *testing.T) {
+	ae := NewAudioEngine()
+	if err := ae.SetOpusApplication(OpusApplicationAudio); err != nil {
+		t.Fatalf("SetOpusApplication: %v", err)
+	}
+	if ae.encoder != nil {
+		t.Error("expected no encoder until the engine starts")
+	}
+	if got := ae.OpusApplication(); got != OpusApplicationAudio {
+		t.Errorf("OpusApplication() = %q, want %q", got, OpusApplicationAudio)
+	}
+}
+
+func TestAppSetOpusApplicationPersists(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	app, _ := newTestApp()
+	if errMsg := app.SetOpusApplication(OpusApplicationLowDelay); errMsg != "" {
+		t.Fatalf("SetOpusApplication: %s", errMsg)
+	}
+	if cfg := LoadConfig(); cfg.OpusApplication != OpusApplicationLowDelay {
+		t.Errorf("saved %q, want %q", cfg.OpusApplication, OpusApplicationLowDelay)
+	}
+	if errMsg := app.SetOpusApplication("karaoke"); errMsg == "" {
+		t.Error("expected an error for an unknown mode")
+	}
+
+	// A fresh app restores it on startup.
+	app2, _ := newTestApp()
+	app2.ApplyConfig()
+	if got := app2.audio.OpusApplication(); got != OpusApplicationLowDelay {
+		t.Errorf("after ApplyConfig: %q, want %q", got, OpusApplicationLowDelay)
+	}
+}
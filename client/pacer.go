@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sendQueueFrames is how many encoded frames may wait to be sent. A burst
+// longer than this drops its oldest frames, so a capture stall cannot
+// build up latency.
+const sendQueueFrames = 5
+
+// framePacer sends encoded audio frames at the rate they play out,
+// whatever rhythm the capture goroutine delivers them in. Each frame goes
+// out one frame duration after the previous one; after a pause the next
+// frame goes out at once.
+type framePacer struct {
+	queue chan []byte
+	// write sends one frame and returns its duration.
+	write func(frame []byte) (time.Duration, error)
+	// dropped counts frames discarded because the queue was full. Read
+	// and reset by GetMetrics.
+	dropped atomic.Uint64
+
+	errMu   sync.Mutex
+	lastErr error // from the most recent write
+}
+
+func newFramePacer(size int, write func(frame []byte) (time.Duration, error)) *framePacer {
+	return &framePacer{queue: make(chan []byte, size), write: write}
+}
+
+// push queues frame for sending, dropping the oldest queued frame when the
+// queue is full. It returns the error from the most recent write, so a
+// failing track is still reported to the caller.
+func (p *framePacer) push(frame []byte) error {
+	for {
+		select {
+		case p.queue <- frame:
+			p.errMu.Lock()
+			defer p.errMu.Unlock()
+			return p.lastErr
+		default:
+		}
+		select {
+		case <-p.queue:
+			p.dropped.Add(1)
+		default:
+		}
+	}
+}
+
+// run writes queued frames until ctx is done.
+func (p *framePacer) run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	var next time.Time // when the next frame is due
+	for {
+		var frame []byte
+		select {
+		case <-ctx.Done():
+			return
+		case frame = <-p.queue:
+		}
+		if wait := time.Until(next); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+		} else {
+			next = time.Now()
+		}
+		d, err := p.write(frame)
+		p.errMu.Lock()
+		p.lastErr = err
+		p.errMu.Unlock()
+		next = next.Add(d)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFramePacerSpreadsBurst(t *testing.T) {
+	const frames = 10
+	sent := make(chan time.Time, frames)
+	p := newFramePacer(frames, func([]byte) (time.Duration, error) {
+		sent <- time.Now()
+		return 20 * time.Millisecond, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.run(ctx)
+
+	for i := 0; i < frames; i++ {
+		if err := p.push([]byte{byte(i)}); err != nil {
+			t.Fatalf("push %d: %v", i, err)
+		}
+	}
+	var times []time.Time
+	for i := 0; i < frames; i++ {
+		select {
+		case at := <-sent:
+			times = append(times, at)
+		case <-time.After(time.Second):
+			t.Fatalf("only %d of %d frames written", i, frames)
+		}
+	}
+	for i := 1; i < frames; i++ {
+		if gap := times[i].Sub(times[i-1]); gap < 15*time.Millisecond {
+			t.Errorf("frame %d written %v after the previous one, want about 20ms", i, gap)
+		}
+	}
+	if span := times[frames-1].Sub(times[0]); span < 170*time.Millisecond || span > 300*time.Millisecond {
+		t.Errorf("burst of %d frames written over %v, want about 180ms", frames, span)
+	}
+	if d := p.dropped.Load(); d != 0 {
+		t.Errorf("dropped = %d, want 0", d)
+	}
+}
+
+func TestFramePacerDropsOldestWhenFull(t *testing.T) {
+	p := newFramePacer(2, func([]byte) (time.Duration, error) { return 0, nil })
+	for i := byte(1); i <= 4; i++ {
+		_ = p.push([]byte{i})
+	}
+	if d := p.dropped.Load(); d != 2 {
+		t.Fatalf("dropped = %d, want 2", d)
+	}
+	if first := <-p.queue; first[0] != 3 {
+		t.Errorf("oldest queued frame = %d, want 3", first[0])
+	}
+}
+
+func TestFramePacerReportsWriteErrors(t *testing.T) {
+	errWrite := errors.New("track closed")
+	p := newFramePacer(1, func([]byte) (time.Duration, error) { return 0, errWrite })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.run(ctx)
+
+	if err := p.push([]byte{1}); err != nil {
+		t.Fatalf("first push: %v", err)
+	}
+	// Writes are asynchronous; a later push reports the failure.
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if err := p.push([]byte{2}); errors.Is(err, errWrite) {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("write error was never reported by push")
+}
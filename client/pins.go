@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// PinnedMessage is a message pinned in a channel, in pinned_list. PinnedBy
+// is the user who pinned it and PinnedAt when, in Unix milliseconds.
+type PinnedMessage struct {
+	ChatHistoryMessage
+	PinnedBy uint16 `json:"pinned_by"`
+	PinnedAt int64  `json:"pinned_at"`
+}
+
+// RequestPinned asks the server for the messages pinned in a channel; the
+// reply arrives through the onPinnedList callback, most recently pinned
+// first.
+func (t *Transport) RequestPinned(channelID int64) error {
+	return t.writeJSON(map[string]any{
+		"type":       "get_pinned",
+		"channel_id": t.wireChannelID(channelID),
+	})
+}
+
+// PinMessage asks the server to pin a message in its channel. Only
+// moderators and above may, and a channel holds a limited number of pins;
+// the server rejects the request otherwise. Everyone is told through the
+// onMessagePinned callback.
+func (t *Transport) PinMessage(msgID uint64) error {
+	if msgID == 0 {
+		return fmt.Errorf("msg_id is required")
+	}
+	return t.writeCtrl(ControlMsg{Type: "pin_message", MsgID: msgID})
+}
+
+// UnpinMessage asks the server to unpin a message. Like PinMessage it is
+// for moderators and above.
+func (t *Transport) UnpinMessage(msgID uint64) error {
+	if msgID == 0 {
+		return fmt.Errorf("msg_id is required")
+	}
+	return t.writeCtrl(ControlMsg{Type: "unpin_message", MsgID: msgID})
+}
+
+// RequestPinned asks for the messages pinned in a channel; they arrive as a
+// chat:pinned_list event.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) RequestPinned(channelID int64) string {
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if err := tr.RequestPinned(channelID); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// PinMessage pins a message in its channel. Moderators and above only.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) PinMessage(msgID int) string {
+	slog.Debug("PinMessage", "msg_id", msgID)
+	return a.setPinned(msgID, true)
+}
+
+// UnpinMessage unpins a message. Moderators and above only.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) UnpinMessage(msgID int) string {
+	slog.Debug("UnpinMessage", "msg_id", msgID)
+	return a.setPinned(msgID, false)
+}
+
+func (a *App) setPinned(msgID int, pinned bool) string {
+	if msgID <= 0 {
+		return "invalid message id"
+	}
+	tr, err := a.requireTransport()
+	if err != nil {
+		return err.Error()
+	}
+	if pinned {
+		err = tr.PinMessage(uint64(msgID))
+	} else {
+		err = tr.UnpinMessage(uint64(msgID))
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPinnedListDelivered(t *testing.T) {
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{"type": "snapshot", "self_id": "u1", "users": []map[string]any{{"id": "u1", "username": "alice"}}})
+		req := readFakeMsg(conn)
+		for req != nil && req["type"] != "get_pinned" {
+			req = readFakeMsg(conn)
+		}
+		if req["channel_id"] != "3" {
+			t.Errorf("unexpected get_pinned: %v", req)
+		}
+		_ = conn.WriteJSON(map[string]any{"type": "message_pinned", "msg_id": 9, "channel_id": "3", "user_id": "u1"})
+		_ = conn.WriteJSON(map[string]any{
+			"type":       "pinned_list",
+			"channel_id": "3",
+			"pins": []map[string]any{
+				{"msg_id": 9, "username": "bob", "message": "read the rules", "ts": 1000, "pinned_by": "u1", "pinned_at": 2000},
+			},
+		})
+		for { // block until the client disconnects
+			if readFakeMsg(conn) == nil {
+				return
+			}
+		}
+	})
+
+	type pinEvent struct {
+		msgID     uint64
+		channelID int64
+		userID    uint16
+	}
+	pinnedEvents := make(chan pinEvent, 1)
+	lists := make(chan []PinnedMessage, 1)
+	var listChannel int64
+	tr := NewTransport()
+	tr.SetOnMessagePinned(func(msgID uint64, channelID int64, userID uint16) {
+		pinnedEvents <- pinEvent{msgID, channelID, userID}
+	})
+	tr.SetOnPinnedList(func(channelID int64, pins []PinnedMessage) {
+		listChannel = channelID
+		lists <- pins
+	})
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+	if err := tr.RequestPinned(3); err != nil {
+		t.Fatalf("request pinned: %v", err)
+	}
+
+	select {
+	case ev := <-pinnedEvents:
+		if ev != (pinEvent{9, 3, tr.MyID()}) {
+			t.Errorf("message_pinned = %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message_pinned delivered")
+	}
+	select {
+	case pins := <-lists:
+		if listChannel != 3 || len(pins) != 1 {
+			t.Fatalf("pinned_list for channel %d = %+v", listChannel, pins)
+		}
+		if p := pins[0]; p.MsgID != 9 || p.Message != "read the rules" || p.PinnedBy != tr.MyID() || p.PinnedAt != 2000 {
+			t.Errorf("unexpected pin: %+v", p)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no pinned_list delivered")
+	}
+}
+
+func TestPinAndUnpinMessage(t *testing.T) {
+	app, mt := newTestApp()
+	if result := app.PinMessage(0); result == "" {
+		t.Error("expected an error for a zero message id")
+	}
+	if result := app.PinMessage(7); result != "" {
+		t.Fatalf("pin: expected empty result, got %q", result)
+	}
+	if result := app.UnpinMessage(7); result != "" {
+		t.Fatalf("unpin: expected empty result, got %q", result)
+	}
+	if result := app.RequestPinned(2); result != "" {
+		t.Fatalf("request pinned: expected empty result, got %q", result)
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if !slices.Equal(mt.pinned, []uint64{7}) || !slices.Equal(mt.unpinned, []uint64{7}) || !slices.Equal(mt.pinnedRequests, []int64{2}) {
+		t.Errorf("pinned = %v, unpinned = %v, requests = %v", mt.pinned, mt.unpinned, mt.pinnedRequests)
+	}
+}
+
+func TestRequestPinnedNotConnected(t *testing.T) {
+	app := NewApp()
+	if result := app.RequestPinned(1); result == "" {
+		t.Error("expected an error when not connected")
+	}
+}
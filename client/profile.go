@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+const (
+	// Opus encoder complexity range. libopus defaults to the maximum, which
+	// is what the encoder has always run at here.
+	minOpusComplexity     = 0
+	maxOpusComplexity     = 10
+	defaultOpusComplexity = maxOpusComplexity
+)
+
+// SetOpusComplexity sets the Opus encoder complexity, clamped to [0, 10].
+// Lower values cost less CPU per frame at some loss of quality, which keeps
+// small boards such as a Raspberry Pi from falling behind in real time.
+func (ae *AudioEngine) SetOpusComplexity(n int) {
+	n = max(minOpusComplexity, min(maxOpusComplexity, n))
+	ae.opusComplexity.Store(int32(n))
+	ae.mu.Lock()
+	if ae.encoder != nil {
+		if err := ae.encoder.SetComplexity(n); err != nil {
+			slog.Error("set opus complexity", "complexity", n, "err", err)
+		}
+	}
+	ae.mu.Unlock()
+	slog.Debug("opus complexity updated", "complexity", n)
+}
+
+// OpusComplexity returns the Opus encoder complexity (0-10).
+func (ae *AudioEngine) OpusComplexity() int {
+	return int(ae.opusComplexity.Load())
+}
+
+// Audio profiles accepted by SetAudioProfile.
+const (
+	AudioProfileVoiceLowCPU = "voice-low-cpu"
+	AudioProfileVoice       = "voice"
+	AudioProfileMusic       = "music"
+)
+
+// audioProfile is the set of encoder settings a profile selects.
+type audioProfile struct {
+	complexity  int
+	bitrateKbps int
+	fec         bool
+	dtx         bool
+	application string
+}
+
+// audioProfiles maps each profile to its settings. "voice" is the default
+// and matches the engine's built-in settings.
+var audioProfiles = map[string]audioProfile{
+	// Cheap to encode: low complexity, and no FEC redundancy to produce.
+	AudioProfileVoiceLowCPU: {complexity: 3, bitrateKbps: 24, fec: false, dtx: true, application: OpusApplicationVoIP},
+	AudioProfileVoice:       {complexity: defaultOpusComplexity, bitrateKbps: opusBitrate / 1000, fec: true, dtx: true, application: OpusApplicationVoIP},
+	// Music needs headroom, must not be cut off in quiet passages, and
+	// should not be shaped as speech.
+	AudioProfileMusic: {complexity: defaultOpusComplexity, bitrateKbps: 128, fec: true, dtx: false, application: OpusApplicationAudio},
+}
+
+// applyAudioProfile applies a profile's settings to the audio engine.
+func (a *App) applyAudioProfile(p audioProfile) {
+	a.audio.SetOpusComplexity(p.complexity)
+	a.SetAudioBitrate(p.bitrateKbps)
+	a.audio.SetFEC(p.fec)
+	a.audio.SetDTX(p.dtx)
+	if err := a.audio.SetOpusApplication(p.application); err != nil {
+		slog.Error("set opus application", "mode", p.application, "err", err)
+	}
+}
+
+// SetAudioProfile switches to a named audio profile ("voice-low-cpu",
+// "voice" or "music"), which sets the encoder complexity, bitrate, FEC, DTX
+// and Opus application mode together, and saves the choice to the config.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) SetAudioProfile(profile string) string {
+	p, ok := audioProfiles[profile]
+	if !ok {
+		return fmt.Sprintf("unknown audio profile %q", profile)
+	}
+	a.applyAudioProfile(p)
+
+	cfg := LoadConfig()
+	cfg.AudioProfile = profile
+	cfg.AudioBitrate = p.bitrateKbps
+	cfg.FECEnabled = p.fec
+	cfg.DTXEnabled = p.dtx
+	cfg.OpusApplication = p.application
+	if err := SaveConfig(cfg); err != nil {
+		slog.Error("save audio profile failed", "profile", profile, "err", err)
+		return err.Error()
+	}
+	slog.Info("audio profile set", "profile", profile, "complexity", p.complexity, "kbps", a.audio.CurrentBitrate())
+	return ""
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	"client/internal/config"
+)
+
+func TestSetOpusComplexityClamps(t *testing.T) {
+	ae := NewAudioEngine()
+	if got := ae.OpusComplexity(); got != defaultOpusComplexity {
+		t.Fatalf("default complexity = %d, want %d", got, defaultOpusComplexity)
+	}
+	for _, tt := range []struct{ in, want int }{{-1, 0}, {4, 4}, {11, 10}} {
+		ae.SetOpusComplexity(tt.in)
+		if got := ae.OpusComplexity(); got != tt.want {
+			t.Errorf("SetOpusComplexity(%d): got %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSetAudioProfile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	tests := []struct {
+		profile    string
+		kbps       int
+		complexity int
+		fec, dtx   bool
+		app        string
+	}{
+		{AudioProfileVoiceLowCPU, 24, 3, false, true, OpusApplicationVoIP},
+		{AudioProfileVoice, 32, 10, true, true, OpusApplicationVoIP},
+		{AudioProfileMusic, 128, 10, true, false, OpusApplicationAudio},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			app, _ := newTestApp()
+			if errMsg := app.SetAudioProfile(tt.profile); errMsg != "" {
+				t.Fatalf("SetAudioProfile: %s", errMsg)
+			}
+			if got := app.audio.CurrentBitrate(); got != tt.kbps {
+				t.Errorf("bitrate = %d, want %d", got, tt.kbps)
+			}
+			if got := app.audio.OpusComplexity(); got != tt.complexity {
+				t.Errorf("complexity = %d, want %d", got, tt.complexity)
+			}
+			if app.audio.FECEnabled() != tt.fec || app.audio.DTXEnabled() != tt.dtx {
+				t.Errorf("fec/dtx = %v/%v, want %v/%v", app.audio.FECEnabled(), app.audio.DTXEnabled(), tt.fec, tt.dtx)
+			}
+			if got := app.audio.OpusApplication(); got != tt.app {
+				t.Errorf("opus application = %q, want %q", got, tt.app)
+			}
+			if cfg := LoadConfig(); cfg.AudioProfile != tt.profile || cfg.AudioBitrate != tt.kbps {
+				t.Errorf("saved profile %q at %d kbps, want %q at %d kbps", cfg.AudioProfile, cfg.AudioBitrate, tt.profile, tt.kbps)
+			}
+
+			// A fresh app restores the profile on startup.
+			app2, _ := newTestApp()
+			app2.ApplyConfig()
+			if app2.audio.CurrentBitrate() != tt.kbps || app2.audio.OpusComplexity() != tt.complexity {
+				t.Errorf("after ApplyConfig: %d kbps at complexity %d, want %d at %d",
+					app2.audio.CurrentBitrate(), app2.audio.OpusComplexity(), tt.kbps, tt.complexity)
+			}
+			if got := app2.audio.OpusApplication(); got != tt.app {
+				t.Errorf("after ApplyConfig: opus application %q, want %q", got, tt.app)
+			}
+		})
+	}
+
+	app, _ := newTestApp()
+	if errMsg := app.SetAudioProfile("podcast"); errMsg == "" {
+		t.Error("expected an error for an unknown profile")
+	}
+}
+
+func TestDefaultAudioProfileMatchesEngineDefaults(t *testing.T) {
+	ae := NewAudioEngine()
+	p := audioProfiles[AudioProfileVoice]
+	if p.complexity != ae.OpusComplexity() || p.fec != ae.FECEnabled() || p.dtx != ae.DTXEnabled() {
+		t.Errorf("voice profile %+v does not match the engine defaults", p)
+	}
+	if p.bitrateKbps != config.Default().AudioBitrate {
+		t.Errorf("voice profile bitrate %d, want the default %d", p.bitrateKbps, config.Default().AudioBitrate)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Local recordings are 16-bit mono PCM WAV at the engine's sample rate.
+const (
+	wavHeaderBytes   = 44
+	wavBitsPerSample = 16
+	// maxRecordMicSamples bounds the microphone audio queued for mixing
+	// (200 ms), should capture run ahead of playback.
+	maxRecordMicSamples = sampleRate / 5
+)
+
+// localRecorder writes what the user hears — the mixed voice playback plus
+// their own microphone — to a WAV file. The playback loop feeds it one mixed
+// frame per cycle; the capture loop queues each microphone frame, which is
+// mixed into the next playback frames.
+type localRecorder struct {
+	mu      sync.Mutex
+	f       *os.File // nil once closed
+	w       *bufio.Writer
+	samples int64     // samples written after the header
+	mic     []float32 // microphone samples (mono) not yet mixed in
+	err     error     // first write error; later frames are dropped
+}
+
+func newLocalRecorder(path string) (*localRecorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot write recording: %w", err)
+	}
+	r := &localRecorder{f: f, w: bufio.NewWriter(f)}
+	// Sizes are patched in close, once the length is known.
+	if err := writeWAVHeader(r.w, 0); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("cannot write recording: %w", err)
+	}
+	return r, nil
+}
+
+// captured queues a microphone frame; frame is interleaved stereo when
+// stereo is set.
+func (r *localRecorder) captured(frame []float32, stereo bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if stereo {
+		start, n := len(r.mic), len(frame)/2
+		r.mic = slices.Grow(r.mic, n)[:start+n]
+		downmix(r.mic[start:], frame)
+	} else {
+		r.mic = append(r.mic, frame...)
+	}
+	if over := len(r.mic) - maxRecordMicSamples; over > 0 {
+		r.mic = r.mic[:copy(r.mic, r.mic[over:])]
+	}
+}
+
+// writeFrame mixes queued microphone audio into mix and appends the result
+// to the file. mix itself is not modified.
+func (r *localRecorder) writeFrame(mix []float32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil || r.err != nil {
+		return
+	}
+	var b [2]byte
+	for i, s := range mix {
+		if i < len(r.mic) {
+			s += r.mic[i]
+		}
+		binary.LittleEndian.PutUint16(b[:], uint16(int16(clampFloat32(s)*32767)))
+		if _, err := r.w.Write(b[:]); err != nil {
+			r.err = err
+			slog.Error("local recording write failed", "err", err)
+			return
+		}
+	}
+	r.mic = r.mic[:copy(r.mic, r.mic[min(len(mix), len(r.mic)):])]
+	r.samples += int64(len(mix))
+}
+
+// close flushes the file and fills in the WAV sizes.
+func (r *localRecorder) close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	f := r.f
+	r.f = nil
+	err := r.err
+	if err == nil {
+		err = r.w.Flush()
+	}
+	if err == nil {
+		if _, err = f.Seek(0, io.SeekStart); err == nil {
+			err = writeWAVHeader(f, r.samples*wavBitsPerSample/8)
+		}
+	}
+	return errors.Join(err, f.Close())
+}
+
+// writeWAVHeader writes a PCM WAV header for dataBytes of sample data.
+func writeWAVHeader(w io.Writer, dataBytes int64) error {
+	const blockAlign = channels * wavBitsPerSample / 8
+	h := make([]byte, 0, wavHeaderBytes)
+	h = append(h, "RIFF"...)
+	h = binary.LittleEndian.AppendUint32(h, uint32(36+dataBytes))
+	h = append(h, "WAVEfmt "...)
+	h = binary.LittleEndian.AppendUint32(h, 16) // fmt chunk size
+	h = binary.LittleEndian.AppendUint16(h, 1)  // PCM
+	h = binary.LittleEndian.AppendUint16(h, channels)
+	h = binary.LittleEndian.AppendUint32(h, sampleRate)
+	h = binary.LittleEndian.AppendUint32(h, sampleRate*blockAlign)
+	h = binary.LittleEndian.AppendUint16(h, blockAlign)
+	h = binary.LittleEndian.AppendUint16(h, wavBitsPerSample)
+	h = append(h, "data"...)
+	h = binary.LittleEndian.AppendUint32(h, uint32(dataBytes))
+	_, err := w.Write(h)
+	return err
+}
+
+// StartRecording records what the user hears to a WAV file at path. It may
+// be called before Start: frames are written once audio is running.
+func (ae *AudioEngine) StartRecording(path string) error {
+	if ae.recorder.Load() != nil {
+		return fmt.Errorf("already recording")
+	}
+	r, err := newLocalRecorder(path)
+	if err != nil {
+		return err
+	}
+	if !ae.recorder.CompareAndSwap(nil, r) {
+		r.close()
+		return fmt.Errorf("already recording")
+	}
+	slog.Info("local recording started", "path", path)
+	return nil
+}
+
+// StopRecording finishes the current recording, if any.
+func (ae *AudioEngine) StopRecording() error {
+	r := ae.recorder.Swap(nil)
+	if r == nil {
+		return nil
+	}
+	err := r.close()
+	slog.Info("local recording stopped", "seconds", r.samples/sampleRate, "err", err)
+	return err
+}
+
+// Recording reports whether a local recording is in progress.
+func (ae *AudioEngine) Recording() bool {
+	return ae.recorder.Load() != nil
+}
+
+// StartLocalRecording records what we hear in voice — everyone else plus
+// our own microphone — to a WAV file at path. It can be started before
+// joining voice; capture begins once audio starts. The server stops it in
+// channels whose record role is above ours.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) StartLocalRecording(path string) string {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "recording path is required"
+	}
+	if err := a.audio.StartRecording(path); err != nil {
+		slog.Error("start local recording failed", "path", path, "err", err)
+		return err.Error()
+	}
+	a.announceRecording()
+	return ""
+}
+
+// announceRecording tells the server a local recording is running, so it
+// can stop us in channels where our role may not record. It is repeated on
+// each channel join.
+func (a *App) announceRecording() {
+	if !a.audio.Recording() {
+		return
+	}
+	tr, err := a.requireTransport()
+	if err != nil {
+		return
+	}
+	if err := tr.StartRecording(); err != nil {
+		slog.Warn("announce local recording", "err", err)
+	}
+}
+
+// StopLocalRecording finishes the local recording, if one is running, and
+// tells the server so the channel stops showing us as recording.
+// Returns an error message string or "" on success (Wails JS binding convention).
+func (a *App) StopLocalRecording() string {
+	wasRecording := a.audio.Recording()
+	if err := a.audio.StopRecording(); err != nil {
+		return err.Error()
+	}
+	if !wasRecording {
+		return ""
+	}
+	if tr, err := a.requireTransport(); err == nil {
+		if err := tr.StopRecording(); err != nil {
+			slog.Warn("announce stopped recording", "err", err)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalRecordingWritesMixedWAV(t *testing.T) {
+	ae := NewAudioEngine()
+	path := filepath.Join(t.TempDir(), "call.wav")
+	if err := ae.StartRecording(path); err != nil {
+		t.Fatalf("start recording: %v", err)
+	}
+	if !ae.Recording() {
+		t.Fatal("expected a recording in progress")
+	}
+
+	mix := make([]float32, FrameSize)
+	mic := make([]float32, FrameSize)
+	for i := range mix {
+		mix[i] = 0.25
+		mic[i] = 0.25
+	}
+	rec := ae.recorder.Load()
+	rec.captured(mic, false)
+	rec.writeFrame(mix) // the microphone frame is mixed in once...
+	rec.writeFrame(mix) // ...and not repeated
+
+	if err := ae.StopRecording(); err != nil {
+		t.Fatalf("stop recording: %v", err)
+	}
+	if ae.Recording() {
+		t.Fatal("recording should have stopped")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read recording: %v", err)
+	}
+	wantData := 2 * FrameSize * wavBitsPerSample / 8
+	if len(data) != wavHeaderBytes+wantData {
+		t.Fatalf("file is %d bytes, want %d", len(data), wavHeaderBytes+wantData)
+	}
+	if string(data[0:4]) != "RIFF" || string(data[8:16]) != "WAVEfmt " || string(data[36:40]) != "data" {
+		t.Fatalf("bad WAV header: %q", data[:wavHeaderBytes])
+	}
+	if got := binary.LittleEndian.Uint32(data[4:]); got != uint32(36+wantData) {
+		t.Errorf("RIFF size = %d, want %d", got, 36+wantData)
+	}
+	if got := binary.LittleEndian.Uint32(data[40:]); got != uint32(wantData) {
+		t.Errorf("data size = %d, want %d", got, wantData)
+	}
+	if got := binary.LittleEndian.Uint32(data[24:]); got != sampleRate {
+		t.Errorf("sample rate = %d, want %d", got, sampleRate)
+	}
+
+	sample := func(i int) int16 {
+		return int16(binary.LittleEndian.Uint16(data[wavHeaderBytes+2*i:]))
+	}
+	pcm := func(v float32) int16 { return int16(v * 32767) }
+	if got, want := sample(0), pcm(0.5); got != want {
+		t.Errorf("first frame sample = %d, want %d (playback plus microphone)", got, want)
+	}
+	if got, want := sample(FrameSize), pcm(0.25); got != want {
+		t.Errorf("second frame sample = %d, want %d (playback only)", got, want)
+	}
+}
+
+func TestStartLocalRecordingErrors(t *testing.T) {
+	app, _ := newTestApp()
+	if msg := app.StartLocalRecording(" "); msg == "" {
+		t.Error("expected an error for an empty path")
+	}
+	if msg := app.StartLocalRecording(filepath.Join(t.TempDir(), "missing", "call.wav")); !strings.Contains(msg, "cannot write recording") {
+		t.Errorf("unwritable path: got %q", msg)
+	}
+	if app.audio.Recording() {
+		t.Fatal("a failed start must not leave a recording running")
+	}
+
+	path := filepath.Join(t.TempDir(), "call.wav")
+	if msg := app.StartLocalRecording(path); msg != "" {
+		t.Fatalf("start: %s", msg)
+	}
+	if msg := app.StartLocalRecording(path); msg == "" {
+		t.Error("expected an error when already recording")
+	}
+	if msg := app.StopLocalRecording(); msg != "" {
+		t.Fatalf("stop: %s", msg)
+	}
+	if msg := app.StopLocalRecording(); msg != "" {
+		t.Errorf("stopping with nothing recording: got %q", msg)
+	}
+}
+
+func TestLocalRecordingIsAnnounced(t *testing.T) {
+	app, mt := newTestApp()
+	app.JoinChannel(1)
+	if msg := app.StartLocalRecording(filepath.Join(t.TempDir(), "call.wav")); msg != "" {
+		t.Fatalf("start: %s", msg)
+	}
+	// Each join while recording asks again, as the new channel may need a
+	// higher role.
+	app.JoinChannel(2)
+	app.StopLocalRecording()
+	app.JoinChannel(3)
+
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if mt.recordingStarts != 2 {
+		t.Errorf("start_recording sent %d times, want 2", mt.recordingStarts)
+	}
+	if mt.recordingStops != 1 {
+		t.Errorf("stop_recording sent %d times, want 1", mt.recordingStops)
+	}
+}
+
+func TestLocalRecordingSpreadsLongMicFrames(t *testing.T) {
+	r, err := newLocalRecorder(filepath.Join(t.TempDir(), "call.wav"))
+	if err != nil {
+		t.Fatalf("new recorder: %v", err)
+	}
+	defer r.close()
+
+	// A 60 ms capture frame covers three 20 ms playback frames.
+	mic := make([]float32, 3*FrameSize)
+	r.captured(mic, false)
+	for i := range 3 {
+		if len(r.mic) != (3-i)*FrameSize {
+			t.Fatalf("before frame %d: %d microphone samples queued, want %d", i, len(r.mic), (3-i)*FrameSize)
+		}
+		r.writeFrame(make([]float32, FrameSize))
+	}
+	if len(r.mic) != 0 {
+		t.Fatalf("%d microphone samples left over", len(r.mic))
+	}
+
+	// Capture running ahead of playback is bounded.
+	for range 20 {
+		r.captured(mic, false)
+	}
+	if len(r.mic) != maxRecordMicSamples {
+		t.Fatalf("queued %d microphone samples, want the %d cap", len(r.mic), maxRecordMicSamples)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import "github.com/gordonklaus/portaudio"
+
+// outputSampleRate returns the rate to open the playback device at: 48 kHz
+// when the device accepts it, otherwise the device's default rate, which
+// playbackLoop resamples to. supported reports whether the device can be
+// opened at a given rate.
+func outputSampleRate(dev *portaudio.DeviceInfo, supported func(rate float64) bool) float64 {
+	if dev.DefaultSampleRate <= 0 || dev.DefaultSampleRate == sampleRate || supported(sampleRate) {
+		return sampleRate
+	}
+	return dev.DefaultSampleRate
+}
+
+// resampler converts mono PCM from one sample rate to another by linear
+// interpolation. It carries its position and the last input sample across
+// calls, so consecutive frames join without clicks.
+type resampler struct {
+	inRate, outRate float64
+	step            float64 // input samples per output sample
+	// pos is where the next output sample falls, in samples from the start
+	// of the next input frame. It is -1 or more; -1 is the previous frame's
+	// last sample.
+	pos  float64
+	last float32
+}
+
+// newResampler returns a resampler from inRate to outRate.
+func newResampler(inRate, outRate float64) *resampler {
+	return &resampler{inRate: inRate, outRate: outRate, step: inRate / outRate}
+}
+
+// outFrameSize returns how many samples one frame of n input samples
+// resamples to, rounded down. Individual calls to resample may return one
+// sample more.
+func (r *resampler) outFrameSize(n int) int {
+	return int(float64(n) * r.outRate / r.inRate)
+}
+
+// resample appends in, converted to the output rate, to dst and returns
+// the extended slice.
+func (r *resampler) resample(dst, in []float32) []float32 {
+	n := float64(len(in))
+	if n == 0 {
+		return dst
+	}
+	for r.pos <= n-1 {
+		i := int(r.pos + 1) // index into [last, in...]
+		frac := float32(r.pos + 1 - float64(i))
+		a := r.last
+		if i > 0 {
+			a = in[i-1]
+		}
+		b := a
+		if i < len(in) {
+			b = in[i]
+		}
+		dst = append(dst, a+(b-a)*frac)
+		r.pos += r.step
+	}
+	r.pos -= n
+	r.last = in[len(in)-1]
+	return dst
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+func TestResample48kTo44k1Length(t *testing.T) {
+	rs := newResampler(sampleRate, 44100)
+	if got := rs.outFrameSize(FrameSize); got != 882 {
+		t.Fatalf("outFrameSize = %d, want 882", got)
+	}
+
+	// One second of 48 kHz frames comes out as one second at 44.1 kHz,
+	// give or take the sample still waiting on the next frame.
+	in := make([]float32, FrameSize)
+	var out []float32
+	for i := 0; i < sampleRate/FrameSize; i++ {
+		for j := range in {
+			in[j] = float32(math.Sin(2 * math.Pi * 440 * float64(i*FrameSize+j) / sampleRate))
+		}
+		out = rs.resample(out, in)
+	}
+	if len(out) < 44099 || len(out) > 44100 {
+		t.Errorf("resampled length = %d, want about 44100", len(out))
+	}
+}
+
+func TestResampleIsContinuousAcrossFrames(t *testing.T) {
+	rs := newResampler(sampleRate, 44100)
+	// A ramp stays a ramp: no sample may jump back at a frame boundary.
+	var out []float32
+	in := make([]float32, FrameSize)
+	for f := 0; f < 5; f++ {
+		for j := range in {
+			in[j] = float32(f*FrameSize + j)
+		}
+		out = rs.resample(out, in)
+	}
+	for i := 1; i < len(out); i++ {
+		d := out[i] - out[i-1]
+		if d < 1 || d > 1.2 {
+			t.Fatalf("step %d = %v, want about %v", i, d, float32(sampleRate)/44100)
+		}
+	}
+}
+
+func TestOutputSampleRate(t *testing.T) {
+	locked := &portaudio.DeviceInfo{DefaultSampleRate: 44100}
+	no := func(float64) bool { return false }
+	yes := func(float64) bool { return true }
+	if got := outputSampleRate(locked, no); got != 44100 {
+		t.Errorf("device without 48 kHz: rate = %v, want 44100", got)
+	}
+	if got := outputSampleRate(locked, yes); got != sampleRate {
+		t.Errorf("device with 48 kHz: rate = %v, want %d", got, sampleRate)
+	}
+	if got := outputSampleRate(&portaudio.DeviceInfo{}, no); got != sampleRate {
+		t.Errorf("device without a default rate: rate = %v, want %d", got, sampleRate)
+	}
+}
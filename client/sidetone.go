@@ -0,0 +1,90 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"sync"
+)
+
+const (
+	// maxSidetoneGain bounds the sidetone level; 1.0 plays the microphone
+	// at the level it was captured.
+	maxSidetoneGain = 1.0
+	// maxSidetoneSamples caps queued sidetone audio (60 ms). Sidetone is
+	// only useful with low latency, so older samples are dropped when the
+	// playback loop falls behind.
+	maxSidetoneSamples = 3 * FrameSize
+)
+
+// sidetoneQueue holds gain-scaled microphone audio waiting to be mixed into
+// playback. Capture frames need not be FrameSize long, so samples are
+// queued rather than whole frames.
+type sidetoneQueue struct {
+	mu      sync.Mutex
+	samples []float32
+}
+
+// push queues frame scaled by gain, downmixing interleaved stereo first.
+func (q *sidetoneQueue) push(frame []float32, stereo bool, gain float32) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	start := len(q.samples)
+	if stereo {
+		q.samples = append(q.samples, make([]float32, len(frame)/2)...)
+		downmix(q.samples[start:], frame)
+	} else {
+		q.samples = append(q.samples, frame...)
+	}
+	for i := start; i < len(q.samples); i++ {
+		q.samples[i] *= gain
+	}
+	if over := len(q.samples) - maxSidetoneSamples; over > 0 {
+		q.samples = q.samples[:copy(q.samples, q.samples[over:])]
+	}
+}
+
+// mix adds up to len(buf) queued samples into buf and drops them from the
+// queue.
+func (q *sidetoneQueue) mix(buf []float32) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := min(len(buf), len(q.samples))
+	for i := range n {
+		buf[i] = clampFloat32(buf[i] + q.samples[i])
+	}
+	q.samples = q.samples[:copy(q.samples, q.samples[n:])]
+}
+
+func (q *sidetoneQueue) reset() {
+	q.mu.Lock()
+	q.samples = q.samples[:0]
+	q.mu.Unlock()
+}
+
+// SetSidetone turns microphone monitoring on or off. While enabled, what
+// the microphone captures is played back locally at gain (clamped to
+// [0, 1]) so users can judge their own level. Sidetone is local only:
+// nothing extra is sent to peers, and it stays silent while muted.
+func (ae *AudioEngine) SetSidetone(enabled bool, gain float64) {
+	gain = max(0, min(maxSidetoneGain, gain))
+	ae.sidetoneGain.Store(math.Float64bits(gain))
+	ae.sidetoneEnabled.Store(enabled)
+	if !enabled {
+		ae.sidetone.reset()
+	}
+	slog.Debug("sidetone updated", "enabled", enabled, "gain", gain)
+}
+
+// Sidetone reports whether sidetone is on and its gain.
+func (ae *AudioEngine) Sidetone() (enabled bool, gain float64) {
+	return ae.sidetoneEnabled.Load(), math.Float64frombits(ae.sidetoneGain.Load())
+}
+
+// feedSidetone queues one captured frame for sidetone playback. Nothing is
+// queued while muted, or in test mode where capture is already looped back.
+func (ae *AudioEngine) feedSidetone(buf []float32, stereo bool) {
+	if !ae.sidetoneEnabled.Load() || ae.IsMuted() || ae.testMode.Load() {
+		return
+	}
+	ae.sidetone.push(buf, stereo, float32(math.Float64frombits(ae.sidetoneGain.Load())))
+}
@@ -0,0 +1,66 @@
+package main
+
+import "testing"
+
+func TestSidetoneMixesScaledCapture(t *testing.T) {
+	ae := NewAudioEngine()
+	ae.SetSidetone(true, 0.5)
+
+	ae.feedSidetone(gateTestFrame(0.4), false)
+	out := make([]float32, FrameSize)
+	ae.sidetone.mix(out)
+	if out[0] != 0.2 || out[1] != -0.2 {
+		t.Fatalf("sidetone samples = %v, %v, want 0.2, -0.2", out[0], out[1])
+	}
+
+	// The frame is played once, and nothing goes out to peers.
+	out = make([]float32, FrameSize)
+	ae.sidetone.mix(out)
+	if out[0] != 0 {
+		t.Errorf("sidetone replayed a consumed frame: %v", out[0])
+	}
+	if n := len(ae.CaptureOut); n != 0 {
+		t.Errorf("sidetone queued %d frames for sending", n)
+	}
+}
+
+func TestSidetoneSilentWhileMutedOrOff(t *testing.T) {
+	ae := NewAudioEngine()
+	ae.feedSidetone(gateTestFrame(0.4), false)
+
+	ae.SetSidetone(true, 1)
+	ae.SetMuted(true)
+	ae.feedSidetone(gateTestFrame(0.4), false)
+
+	out := make([]float32, FrameSize)
+	ae.sidetone.mix(out)
+	if out[0] != 0 {
+		t.Errorf("sidetone played %v while off or muted", out[0])
+	}
+}
+
+func TestSidetoneCapsQueuedAudio(t *testing.T) {
+	ae := NewAudioEngine()
+	ae.SetSidetone(true, 1)
+	for range 10 {
+		ae.feedSidetone(gateTestFrame(0.4), false)
+	}
+	if n := len(ae.sidetone.samples); n != maxSidetoneSamples {
+		t.Errorf("queued %d sidetone samples, want %d", n, maxSidetoneSamples)
+	}
+}
+
+func TestSetSidetoneClampsGain(t *testing.T) {
+	ae := NewAudioEngine()
+	if enabled, _ := ae.Sidetone(); enabled {
+		t.Error("sidetone should be off by default")
+	}
+	ae.SetSidetone(true, 3)
+	if _, gain := ae.Sidetone(); gain != 1 {
+		t.Errorf("gain = %v, want clamped to 1", gain)
+	}
+	ae.SetSidetone(true, -1)
+	if _, gain := ae.Sidetone(); gain != 0 {
+		t.Errorf("gain = %v, want clamped to 0", gain)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+
+	"gopkg.in/hraban/opus.v2"
+)
+
+// Opus signal-type hints. "voice" is the default for a voice chat client;
+// "music" suits users playing instruments or sharing audio through their mic.
+const (
+	SignalVoice = "voice"
+	SignalMusic = "music"
+)
+
+const (
+	// signalWindowFrames is the analysis window for the classifier:
+	// 50 frames × 20 ms = 1 s, long enough to span several syllables.
+	signalWindowFrames = 50
+
+	// signalHoldWindows is how many consecutive windows must agree before
+	// the classifier switches, so a held vowel or a pause between songs
+	// doesn't flap the encoder between modes.
+	signalHoldWindows = 2
+
+	// signalSilenceRMS is the mean window RMS below which the input is
+	// treated as silence and the current decision is kept.
+	signalSilenceRMS = 0.005
+
+	// Speech alternates voiced/unvoiced sounds and pauses between syllables,
+	// so it has many low-energy frames and an unstable zero-crossing rate.
+	// Music is comparatively steady on both measures.
+	signalLowEnergyRatio = 0.3
+	signalZCRVariation   = 0.6
+)
+
+// validSignal reports whether s is a recognised signal-type hint.
+func validSignal(s string) bool {
+	return s == SignalVoice || s == SignalMusic
+}
+
+// signalClassifier labels capture audio as speech or music from per-frame
+// energy and zero-crossing statistics over a sliding one-second window
+// (after Scheirer & Slaney). It is only touched from the capture goroutine.
+type signalClassifier struct {
+	rms [signalWindowFrames]float64
+	zcr [signalWindowFrames]float64
+	n   int
+
+	current   string
+	candidate string
+	streak    int
+}
+
+func newSignalClassifier(initial string) *signalClassifier {
+	return &signalClassifier{current: initial}
+}
+
+// push adds one capture frame. It returns the current classification and
+// whether it changed as a result of this frame.
+func (c *signalClassifier) push(frame []float32) (string, bool) {
+	c.rms[c.n] = float64(frameRMS(frame))
+	c.zcr[c.n] = zeroCrossingRate(frame)
+	c.n++
+	if c.n < signalWindowFrames {
+		return c.current, false
+	}
+	c.n = 0
+
+	label, ok := c.classifyWindow()
+	if !ok {
+		c.candidate, c.streak = "", 0
+		return c.current, false
+	}
+	if label == c.current {
+		c.candidate, c.streak = "", 0
+		return c.current, false
+	}
+	if label != c.candidate {
+		c.candidate, c.streak = label, 0
+	}
+	c.streak++
+	if c.streak < signalHoldWindows {
+		return c.current, false
+	}
+	c.current = label
+	c.candidate, c.streak = "", 0
+	return c.current, true
+}
+
+// classifyWindow labels the completed window. ok is false for windows that
+// are too quiet to say anything about the content.
+func (c *signalClassifier) classifyWindow() (label string, ok bool) {
+	var meanRMS, meanZCR float64
+	for i := range c.rms {
+		meanRMS += c.rms[i]
+		meanZCR += c.zcr[i]
+	}
+	meanRMS /= signalWindowFrames
+	meanZCR /= signalWindowFrames
+	if meanRMS < signalSilenceRMS {
+		return "", false
+	}
+
+	var lowEnergy int
+	var zcrVar float64
+	for i := range c.rms {
+		if c.rms[i] < 0.5*meanRMS {
+			lowEnergy++
+		}
+		d := c.zcr[i] - meanZCR
+		zcrVar += d * d
+	}
+	lowRatio := float64(lowEnergy) / signalWindowFrames
+	var zcrCV float64
+	if meanZCR > 0 {
+		zcrCV = math.Sqrt(zcrVar/signalWindowFrames) / meanZCR
+	}
+
+	if lowRatio >= signalLowEnergyRatio || zcrCV >= signalZCRVariation {
+		return SignalVoice, true
+	}
+	return SignalMusic, true
+}
+
+// zeroCrossingRate returns the fraction of adjacent sample pairs in frame
+// that change sign — a cheap proxy for where the spectral energy sits.
+func zeroCrossingRate(frame []float32) float64 {
+	if len(frame) < 2 {
+		return 0
+	}
+	var crossings int
+	for i := 1; i < len(frame); i++ {
+		if (frame[i-1] >= 0) != (frame[i] >= 0) {
+			crossings++
+		}
+	}
+	return float64(crossings) / float64(len(frame)-1)
+}
+
+// applySignal tunes enc for the given signal type. hraban/opus does not
+// expose OPUS_SET_SIGNAL, so the hint is expressed through the controls the
+// binding does offer: speech is capped at super-wideband (nothing useful in
+// a voice lives above 12 kHz, so the bits go where the voice is) with DTX
+// as configured, while music gets the full band and DTX off so quiet
+// passages are not gated out as silence.
+func applySignal(enc opusEncoder, signal string, dtx bool) error {
+	switch signal {
+	case SignalVoice:
+		if err := enc.SetMaxBandwidth(opus.SuperWideband); err != nil {
+			return err
+		}
+		return enc.SetDTX(dtx)
+	case SignalMusic:
+		if err := enc.SetMaxBandwidth(opus.Fullband); err != nil {
+			return err
+		}
+		return enc.SetDTX(false)
+	default:
+		return fmt.Errorf("unknown signal type %q", signal)
+	}
+}
+
+// SetSignalAutoDetect enables or disables speech/music auto-detection on
+// the capture path. When disabled the manual signal type (SetSignalType)
+// is applied to the encoder.
+func (ae *AudioEngine) SetSignalAutoDetect(enabled bool) {
+	ae.signalAuto.Store(enabled)
+	if !enabled {
+		ae.mu.Lock()
+		manual := ae.signalManual
+		ae.mu.Unlock()
+		ae.setActiveSignal(manual)
+	}
+	slog.Debug("signal auto-detect updated", "enabled", enabled)
+}
+
+// IsSignalAutoDetect reports whether speech/music auto-detection is enabled.
+func (ae *AudioEngine) IsSignalAutoDetect() bool {
+	return ae.signalAuto.Load()
+}
+
+// SetSignalType sets the manual signal-type override ("voice" or "music").
+// It takes effect immediately unless auto-detection is enabled.
+func (ae *AudioEngine) SetSignalType(signal string) error {
+	if !validSignal(signal) {
+		return fmt.Errorf("unknown signal type %q", signal)
+	}
+	ae.mu.Lock()
+	ae.signalManual = signal
+	ae.mu.Unlock()
+	if !ae.signalAuto.Load() {
+		ae.setActiveSignal(signal)
+	}
+	return nil
+}
+
+// SignalType returns the signal type currently applied to the encoder —
+// the detected type while auto-detection is on, else the manual override.
+func (ae *AudioEngine) SignalType() string {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+	return ae.signalActive
+}
+
+// setActiveSignal records signal as the active type and pushes it to the
+// encoder if one is running.
+func (ae *AudioEngine) setActiveSignal(signal string) {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+	if ae.signalActive == signal {
+		return
+	}
+	ae.signalActive = signal
+	if ae.encoder != nil {
+		if err := applySignal(ae.encoder, signal, ae.dtxEnabled.Load()); err != nil {
+			slog.Error("set opus signal", "signal", signal, "err", err)
+			return
+		}
+	}
+	slog.Debug("opus signal updated", "signal", signal)
+}
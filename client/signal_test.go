@@ -0,0 +1,150 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"gopkg.in/hraban/opus.v2"
+)
+
+// feedClassifier pushes `seconds` worth of frames produced by gen (sample
+// index → value) and returns the classifier's final label and whether it
+// changed at any point.
+func feedClassifier(c *signalClassifier, seconds int, gen func(n int) float32) (string, bool) {
+	frame := make([]float32, FrameSize)
+	var changed bool
+	label := c.current
+	for f := 0; f < seconds*signalWindowFrames; f++ {
+		for i := range frame {
+			frame[i] = gen(f*FrameSize + i)
+		}
+		var ch bool
+		label, ch = c.push(frame)
+		changed = changed || ch
+	}
+	return label, changed
+}
+
+// chord is a steady three-note chord — sustained, constant-level content.
+func chord(n int) float32 {
+	t := float64(n) / sampleRate
+	return float32(0.2*math.Sin(2*math.Pi*261.6*t) +
+		0.2*math.Sin(2*math.Pi*329.6*t) +
+		0.2*math.Sin(2*math.Pi*392.0*t))
+}
+
+// syllables mimics speech: ~4 Hz bursts alternating a voiced (low) and an
+// unvoiced (high) sound, separated by pauses.
+func syllables(n int) float32 {
+	t := float64(n) / sampleRate
+	phase := math.Mod(t*4, 1)
+	switch {
+	case phase < 0.35:
+		return float32(0.3 * math.Sin(2*math.Pi*180*t))
+	case phase < 0.5:
+		return float32(0.1 * math.Sin(2*math.Pi*5500*t))
+	default:
+		return 0
+	}
+}
+
+func TestSignalClassifierDetectsMusic(t *testing.T) {
+	c := newSignalClassifier(SignalVoice)
+	label, changed := feedClassifier(c, 3, chord)
+	if label != SignalMusic || !changed {
+		t.Errorf("steady chord: got %q (changed=%v), want %q", label, changed, SignalMusic)
+	}
+}
+
+func TestSignalClassifierDetectsSpeech(t *testing.T) {
+	c := newSignalClassifier(SignalMusic)
+	label, changed := feedClassifier(c, 3, syllables)
+	if label != SignalVoice || !changed {
+		t.Errorf("syllabic input: got %q (changed=%v), want %q", label, changed, SignalVoice)
+	}
+}
+
+func TestSignalClassifierHoldsThroughSilence(t *testing.T) {
+	c := newSignalClassifier(SignalMusic)
+	label, changed := feedClassifier(c, 3, func(int) float32 { return 0 })
+	if label != SignalMusic || changed {
+		t.Errorf("silence: got %q (changed=%v), want unchanged %q", label, changed, SignalMusic)
+	}
+}
+
+func TestSignalClassifierNeedsConsecutiveWindows(t *testing.T) {
+	c := newSignalClassifier(SignalVoice)
+	// A single window of music is not enough to switch.
+	label, changed := feedClassifier(c, 1, chord)
+	if label != SignalVoice || changed {
+		t.Errorf("after one window: got %q (changed=%v), want %q", label, changed, SignalVoice)
+	}
+}
+
+func TestZeroCrossingRate(t *testing.T) {
+	if got := zeroCrossingRate([]float32{1, -1, 1, -1, 1}); got != 1 {
+		t.Errorf("alternating signal: got %v, want 1", got)
+	}
+	if got := zeroCrossingRate([]float32{1, 1, 1, 1}); got != 0 {
+		t.Errorf("constant signal: got %v, want 0", got)
+	}
+}
+
+// signalEncoder records the bandwidth/DTX settings applied to it.
+type signalEncoder struct {
+	mockEncoder
+	maxBw opus.Bandwidth
+	dtx   bool
+}
+
+func (e *signalEncoder) SetMaxBandwidth(bw opus.Bandwidth) error { e.maxBw = bw; return nil }
+func (e *signalEncoder) SetDTX(dtx bool) error                  { e.dtx = dtx; return nil }
+
+func TestSetSignalTypeAppliesToEncoder(t *testing.T) {
+	ae := NewAudioEngine()
+	enc := &signalEncoder{}
+	ae.encoder = enc
+
+	if err := ae.SetSignalType(SignalMusic); err != nil {
+		t.Fatalf("SetSignalType(music): %v", err)
+	}
+	if ae.SignalType() != SignalMusic {
+		t.Errorf("active signal: got %q, want %q", ae.SignalType(), SignalMusic)
+	}
+	if enc.maxBw != opus.Fullband || enc.dtx {
+		t.Errorf("music: maxBw=%v dtx=%v, want Fullband with DTX off", enc.maxBw, enc.dtx)
+	}
+
+	if err := ae.SetSignalType(SignalVoice); err != nil {
+		t.Fatalf("SetSignalType(voice): %v", err)
+	}
+	if enc.maxBw != opus.SuperWideband || !enc.dtx {
+		t.Errorf("voice: maxBw=%v dtx=%v, want SuperWideband with DTX on", enc.maxBw, enc.dtx)
+	}
+}
+
+func TestSetSignalTypeRejectsUnknown(t *testing.T) {
+	ae := NewAudioEngine()
+	if err := ae.SetSignalType("podcast"); err == nil {
+		t.Error("expected error for unknown signal type")
+	}
+	if ae.SignalType() != SignalVoice {
+		t.Errorf("active signal: got %q, want default %q", ae.SignalType(), SignalVoice)
+	}
+}
+
+func TestSignalManualOverrideDeferredWhileAuto(t *testing.T) {
+	ae := NewAudioEngine()
+	ae.SetSignalAutoDetect(true)
+	if err := ae.SetSignalType(SignalMusic); err != nil {
+		t.Fatalf("SetSignalType: %v", err)
+	}
+	if ae.SignalType() != SignalVoice {
+		t.Errorf("manual override applied while auto-detect on: got %q", ae.SignalType())
+	}
+
+	ae.SetSignalAutoDetect(false)
+	if ae.SignalType() != SignalMusic {
+		t.Errorf("disabling auto-detect should apply manual type: got %q", ae.SignalType())
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limits on a server-provided sound, so a bad URL can't make the client
+// hold a large file or play for long.
+const (
+	maxSoundBytes     = 1 << 20 // 1 MB
+	maxSoundMs        = 5000
+	soundFetchTimeout = 10 * time.Second
+)
+
+// soundCache holds decoded sound files keyed by URL, ready to play.
+type soundCache struct {
+	mu     sync.Mutex
+	frames map[string][][]float32
+}
+
+func (c *soundCache) get(url string) ([][]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	frames, ok := c.frames[url]
+	return frames, ok
+}
+
+func (c *soundCache) put(url string, frames [][]float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.frames == nil {
+		c.frames = make(map[string][][]float32)
+	}
+	c.frames[url] = frames
+}
+
+// LoadSound fetches the WAV file at url and caches it for PlaySound. A url
+// that is already cached is not fetched again.
+func (ae *AudioEngine) LoadSound(ctx context.Context, url string) error {
+	if _, ok := ae.sounds.get(url); ok {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch sound: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSoundBytes+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > maxSoundBytes {
+		return fmt.Errorf("sound is larger than %d bytes", maxSoundBytes)
+	}
+	pcm, err := decodeWAV(data)
+	if err != nil {
+		return err
+	}
+	ae.sounds.put(url, chunkFrames(normalizePeak(pcm, notifVolume)))
+	slog.Debug("sound loaded", "url", url, "samples", len(pcm))
+	return nil
+}
+
+// PlaySound plays the sound cached for url like PlayNotification, or the
+// bundled fallback when url is empty or has not loaded.
+func (ae *AudioEngine) PlaySound(url string, fallback NotificationSound) {
+	if url == "" {
+		ae.PlayNotification(fallback)
+		return
+	}
+	frames, ok := ae.sounds.get(url)
+	if !ok {
+		ae.PlayNotification(fallback)
+		return
+	}
+	if ae.doNotDisturb.Load() {
+		return
+	}
+	ae.queueNotification(frames)
+}
+
+// decodeWAV decodes 16-bit PCM WAV data to mono samples at sampleRate.
+// Stereo is averaged to mono and other rates are resampled.
+func decodeWAV(data []byte) ([]float32, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, errors.New("not a WAV file")
+	}
+	var (
+		chans, bits int
+		rate        int
+		pcm         []byte
+		haveFmt     bool
+	)
+	for off := 12; off+8 <= len(data); {
+		id := string(data[off : off+4])
+		size := int(binary.LittleEndian.Uint32(data[off+4 : off+8]))
+		off += 8
+		if size > len(data)-off {
+			size = len(data) - off // tolerate a truncated final chunk
+		}
+		body := data[off : off+size]
+		switch id {
+		case "fmt ":
+			if len(body) < 16 {
+				return nil, errors.New("WAV fmt chunk too short")
+			}
+			if format := binary.LittleEndian.Uint16(body[0:2]); format != 1 {
+				return nil, fmt.Errorf("WAV format %d is not PCM", format)
+			}
+			chans = int(binary.LittleEndian.Uint16(body[2:4]))
+			rate = int(binary.LittleEndian.Uint32(body[4:8]))
+			bits = int(binary.LittleEndian.Uint16(body[14:16]))
+			haveFmt = true
+		case "data":
+			pcm = body
+		}
+		off += size + size%2 // chunks are word aligned
+	}
+	if !haveFmt || pcm == nil {
+		return nil, errors.New("WAV file has no fmt or data chunk")
+	}
+	if bits != 16 || (chans != 1 && chans != 2) || rate <= 0 {
+		return nil, fmt.Errorf("unsupported WAV: %d-bit, %d channels, %d Hz", bits, chans, rate)
+	}
+
+	frames := len(pcm) / (2 * chans)
+	if limit := rate * maxSoundMs / 1000; frames > limit {
+		frames = limit
+	}
+	mono := make([]float32, frames)
+	for i := range mono {
+		var sum float32
+		for c := 0; c < chans; c++ {
+			s := int16(binary.LittleEndian.Uint16(pcm[2*(i*chans+c):]))
+			sum += float32(s) / 32768
+		}
+		mono[i] = sum / float32(chans)
+	}
+	if rate != sampleRate {
+		mono = newResampler(float64(rate), sampleRate).resample(nil, mono)
+	}
+	return mono, nil
+}
+
+// normalizePeak scales pcm down in place so its loudest sample is at most
+// peak, matching the bundled tones; quieter sounds are left alone.
+func normalizePeak(pcm []float32, peak float32) []float32 {
+	var loudest float32
+	for _, s := range pcm {
+		if s < 0 {
+			s = -s
+		}
+		loudest = max(loudest, s)
+	}
+	if loudest <= peak {
+		return pcm
+	}
+	scale := peak / loudest
+	for i := range pcm {
+		pcm[i] *= scale
+	}
+	return pcm
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// makeWAV encodes samples (interleaved when chans is 2) as 16-bit PCM WAV.
+func makeWAV(rate, chans int, samples []int16) []byte {
+	var b bytes.Buffer
+	dataLen := 2 * len(samples)
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+dataLen))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, uint32(16))
+	binary.Write(&b, binary.LittleEndian, uint16(1))
+	binary.Write(&b, binary.LittleEndian, uint16(chans))
+	binary.Write(&b, binary.LittleEndian, uint32(rate))
+	binary.Write(&b, binary.LittleEndian, uint32(rate*chans*2))
+	binary.Write(&b, binary.LittleEndian, uint16(chans*2))
+	binary.Write(&b, binary.LittleEndian, uint16(16))
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(dataLen))
+	binary.Write(&b, binary.LittleEndian, samples)
+	return b.Bytes()
+}
+
+func TestDecodeWAVDownmixesAndResamples(t *testing.T) {
+	stereo := make([]int16, 2*2400) // 100 ms at 24 kHz
+	for i := 0; i < len(stereo); i += 2 {
+		stereo[i], stereo[i+1] = 16384, 0
+	}
+	pcm, err := decodeWAV(makeWAV(24000, 2, stereo))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if n := len(pcm); n < 4790 || n > 4810 {
+		t.Fatalf("expected ~4800 samples at 48 kHz, got %d", n)
+	}
+	if s := pcm[len(pcm)/2]; s < 0.24 || s > 0.26 {
+		t.Fatalf("expected the channels averaged to 0.25, got %f", s)
+	}
+
+	if _, err := decodeWAV([]byte("RIFF....WAVE")); err == nil {
+		t.Fatal("expected an error for a WAV without chunks")
+	}
+	if _, err := decodeWAV([]byte("not a wav file at all")); err == nil {
+		t.Fatal("expected an error for non-WAV data")
+	}
+}
+
+func TestPlaySoundUsesLoadedSoundOrFallback(t *testing.T) {
+	chime := makeWAV(sampleRate, 1, make([]int16, 3*FrameSize))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chime.wav" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(chime)
+	}))
+	defer srv.Close()
+
+	ae := NewAudioEngine()
+	ctx := context.Background()
+	if err := ae.LoadSound(ctx, srv.URL+"/chime.wav"); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if err := ae.LoadSound(ctx, srv.URL+"/missing.wav"); err == nil {
+		t.Fatal("expected an error for a missing sound")
+	}
+
+	countFrames := func() int {
+		n := 0
+		for {
+			select {
+			case <-ae.notifCh:
+				n++
+			case <-time.After(100 * time.Millisecond):
+				return n
+			}
+		}
+	}
+	ae.PlaySound(srv.URL+"/chime.wav", SoundUserJoined)
+	if n := countFrames(); n != 3 {
+		t.Fatalf("expected the 3 frames of the loaded sound, got %d", n)
+	}
+	ae.PlaySound(srv.URL+"/missing.wav", SoundUserJoined)
+	if want, n := len(generateNotificationFrames(SoundUserJoined)), countFrames(); n != want {
+		t.Fatalf("expected the bundled sound's %d frames, got %d", want, n)
+	}
+
+	ae.SetDoNotDisturb(true)
+	ae.PlaySound(srv.URL+"/chime.wav", SoundUserJoined)
+	if n := countFrames(); n != 0 {
+		t.Fatalf("expected nothing played in do-not-disturb, got %d frames", n)
+	}
+}
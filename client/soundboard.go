@@ -0,0 +1,53 @@
+package main
+
+import "fmt"
+
+// soundboardClips maps each bundled soundboard clip ID to its tone sequence
+// ({Hz, ms}). The IDs must match the server's list; it rejects any other.
+var soundboardClips = map[string][][2]int{
+	"airhorn":      {{466, 180}, {466, 90}, {466, 400}},
+	"applause":     {{1568, 30}, {1175, 30}, {1397, 30}, {1047, 30}, {1661, 30}, {1245, 30}, {1480, 30}, {1109, 30}, {1568, 30}, {1319, 30}},
+	"drumroll":     {{147, 40}, {131, 40}, {147, 40}, {131, 40}, {147, 40}, {131, 40}, {147, 40}, {131, 40}, {196, 200}},
+	"rimshot":      {{196, 80}, {196, 80}, {1047, 160}},
+	"sad_trombone": {{392, 300}, {370, 300}, {349, 300}, {330, 600}},
+	"tada":         {{523, 100}, {659, 100}, {784, 300}},
+}
+
+// PlaySoundboard mixes a soundboard clip into the output through the
+// notification mixer, so it follows NotificationVolume. Like PlayAlert it
+// is silent while deafened or in do-not-disturb.
+func (ae *AudioEngine) PlaySoundboard(clipID string) error {
+	frames, err := generateSoundboardFrames(clipID)
+	if err != nil {
+		return err
+	}
+	if ae.doNotDisturb.Load() || ae.deafened.Load() {
+		return nil
+	}
+	go func() {
+		stopCh := ae.stopCh
+		for _, frame := range frames {
+			select {
+			case <-stopCh:
+				return
+			case ae.notifCh <- frame:
+			default:
+				// Channel full — skip frame rather than block.
+			}
+		}
+	}()
+	return nil
+}
+
+// generateSoundboardFrames returns the PCM frames for a soundboard clip.
+func generateSoundboardFrames(clipID string) ([][]float32, error) {
+	tones, ok := soundboardClips[clipID]
+	if !ok {
+		return nil, fmt.Errorf("unknown soundboard clip %q", clipID)
+	}
+	var frames [][]float32
+	for _, t := range tones {
+		frames = append(frames, generateSineTone(float64(t[0]), t[1])...)
+	}
+	return frames, nil
+}
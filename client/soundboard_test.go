@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestGenerateSoundboardFrames(t *testing.T) {
+	for clipID := range soundboardClips {
+		frames, err := generateSoundboardFrames(clipID)
+		if err != nil {
+			t.Fatalf("%s: %v", clipID, err)
+		}
+		if len(frames) == 0 {
+			t.Fatalf("%s: no frames", clipID)
+		}
+		for _, f := range frames {
+			if len(f) != FrameSize {
+				t.Fatalf("%s: frame length %d, want %d", clipID, len(f), FrameSize)
+			}
+		}
+	}
+	if _, err := generateSoundboardFrames("kazoo"); err == nil {
+		t.Error("expected error for unknown clip")
+	}
+}
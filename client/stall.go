@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// peerStallGrace is how long a connected peer that says it is speaking may
+// go without a single audio packet reaching us before we call its audio
+// stalled.
+const peerStallGrace = 5 * time.Second
+
+// peerConnected records that the peer connection to p reached (connected)
+// or left (!connected) the connected state.
+func (p *peerState) peerConnected(connected bool, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if connected {
+		p.connectedAt = now
+	} else {
+		p.connectedAt = time.Time{}
+	}
+}
+
+// notePeerSpeaking records that peer id told us it is speaking. Peers with
+// DTX on send no audio while silent, so only a peer that claims to be
+// talking can be caught not reaching us.
+func (t *Transport) notePeerSpeaking(id uint16) {
+	t.mu.Lock()
+	p := t.peers[id]
+	t.mu.Unlock()
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.claimedSpeaking = true
+	p.mu.Unlock()
+}
+
+// checkPeerStalls looks for one-way audio: a peer whose connection has
+// been up for peerStallGrace and who has said it is speaking, yet none of
+// whose packets have arrived. Each such peer is reported once through
+// onPeerAudioStalled; handleIncomingAudio clears the stall when packets
+// turn up. Peers we have muted or cannot hear are skipped, since their
+// packets are dropped before they are counted.
+func (t *Transport) checkPeerStalls(now time.Time) {
+	t.mu.Lock()
+	peers := make([]*peerState, 0, len(t.peers))
+	for _, p := range t.peers {
+		peers = append(peers, p)
+	}
+	t.mu.Unlock()
+
+	t.cbMu.RLock()
+	onStalled := t.onPeerAudioStalled
+	t.cbMu.RUnlock()
+
+	for _, p := range peers {
+		p.mu.Lock()
+		connectedAt, claimedSpeaking := p.connectedAt, p.claimedSpeaking
+		p.mu.Unlock()
+		if connectedAt.IsZero() || now.Sub(connectedAt) < peerStallGrace || !claimedSpeaking {
+			continue
+		}
+		if t.muted.Has(p.id) || !t.canHear(p.id) {
+			continue
+		}
+		t.statsMu.Lock()
+		_, heard := t.lastSeen[p.id]
+		t.statsMu.Unlock()
+		if heard {
+			continue
+		}
+		if _, already := t.stalledPeers.LoadOrStore(p.id, struct{}{}); already {
+			continue
+		}
+		slog.Warn("peer audio stalled", "remote_id", p.id, "connected_for", now.Sub(connectedAt))
+		if onStalled != nil {
+			onStalled(p.id)
+		}
+	}
+}
+
+// clearPeerStall reports that audio from a peer flagged by checkPeerStalls
+// has started arriving.
+func (t *Transport) clearPeerStall(id uint16) {
+	if _, ok := t.stalledPeers.LoadAndDelete(id); !ok {
+		return
+	}
+	slog.Info("peer audio resumed", "remote_id", id)
+	t.cbMu.RLock()
+	onResumed := t.onPeerAudioResumed
+	t.cbMu.RUnlock()
+	if onResumed != nil {
+		onResumed(id)
+	}
+}
+
+func (t *Transport) SetOnPeerAudioStalled(fn func(id uint16)) {
+	t.cbMu.Lock()
+	t.onPeerAudioStalled = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnPeerAudioResumed(fn func(id uint16)) {
+	t.cbMu.Lock()
+	t.onPeerAudioResumed = fn
+	t.cbMu.Unlock()
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// stallTransport returns a transport in channel 1 with a connected peer 2
+// that has said it is speaking, and the stall events it reports.
+func stallTransport(t *testing.T, connectedAt time.Time) (*Transport, *[]string) {
+	t.Helper()
+	tr := NewTransport()
+	tr.myChannel.Store(1)
+	tr.userChannels.Store(uint16(2), int64(1))
+	peer := &peerState{id: 2}
+	tr.peers[2] = peer
+	peer.peerConnected(true, connectedAt)
+	tr.notePeerSpeaking(2)
+
+	var events []string
+	tr.SetOnPeerAudioStalled(func(id uint16) {
+		if id != 2 {
+			t.Errorf("stalled id = %d, want 2", id)
+		}
+		events = append(events, "stalled")
+	})
+	tr.SetOnPeerAudioResumed(func(id uint16) {
+		if id != 2 {
+			t.Errorf("resumed id = %d, want 2", id)
+		}
+		events = append(events, "resumed")
+	})
+	return tr, &events
+}
+
+func TestPeerAudioStallDetectedAndCleared(t *testing.T) {
+	start := time.Now()
+	tr, events := stallTransport(t, start)
+
+	tr.checkPeerStalls(start.Add(peerStallGrace / 2))
+	if len(*events) != 0 {
+		t.Fatalf("stall reported inside the grace period: %v", *events)
+	}
+	tr.checkPeerStalls(start.Add(peerStallGrace))
+	tr.checkPeerStalls(start.Add(2 * peerStallGrace))
+	if len(*events) != 1 || (*events)[0] != "stalled" {
+		t.Fatalf("events after grace = %v, want one stall", *events)
+	}
+
+	tr.handleIncomingAudio(2, 1, []byte{1, 2, 3})
+	tr.handleIncomingAudio(2, 2, []byte{1, 2, 3})
+	if len(*events) != 2 || (*events)[1] != "resumed" {
+		t.Fatalf("events after packets = %v, want stalled then resumed", *events)
+	}
+	tr.checkPeerStalls(start.Add(3 * peerStallGrace))
+	if len(*events) != 2 {
+		t.Errorf("stall reported again while audio flows: %v", *events)
+	}
+}
+
+func TestPeerAudioStallNeedsSpeakingConnectedPeer(t *testing.T) {
+	start := time.Now()
+	later := start.Add(2 * peerStallGrace)
+
+	// A silent peer with DTX sends nothing and is not stalled.
+	tr, events := stallTransport(t, start)
+	tr.peers[2].claimedSpeaking = false
+	tr.checkPeerStalls(later)
+	if len(*events) != 0 {
+		t.Errorf("silent peer reported stalled: %v", *events)
+	}
+
+	// Nor is one whose connection dropped, or one we have muted.
+	tr, events = stallTransport(t, start)
+	tr.peers[2].peerConnected(false, start)
+	tr.checkPeerStalls(later)
+	tr, events2 := stallTransport(t, start)
+	tr.muted.Add(2)
+	tr.checkPeerStalls(later)
+	if len(*events) != 0 || len(*events2) != 0 {
+		t.Errorf("events = %v, %v, want none", *events, *events2)
+	}
+}
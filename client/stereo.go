@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// opusStereoFmtp is appended to the Opus fmtp line when sending stereo, so
+// the remote side knows the stream carries two channels.
+const opusStereoFmtp = "stereo=1;sprop-stereo=1"
+
+// SetStereo selects stereo (true) or mono (false) capture. Mono is the
+// default because voice gains nothing from a second channel. The mode
+// takes effect the next time the engine starts.
+func (ae *AudioEngine) SetStereo(enabled bool) {
+	ae.stereo.Store(enabled)
+	slog.Debug("stereo updated", "enabled", enabled)
+}
+
+// Stereo reports whether stereo capture is selected.
+func (ae *AudioEngine) Stereo() bool {
+	return ae.stereo.Load()
+}
+
+// captureChannelCount returns how many channels to capture: two when stereo
+// is requested and the input device has them, otherwise one.
+func captureChannelCount(stereo bool, maxInputChannels int) int {
+	if stereo && maxInputChannels >= 2 {
+		return 2
+	}
+	return 1
+}
+
+// downmix averages interleaved stereo samples in src into mono in dst,
+// which must hold len(src)/2 samples.
+func downmix(dst, src []float32) {
+	for i := range dst {
+		dst[i] = (src[2*i] + src[2*i+1]) / 2
+	}
+}
+
+// opusCapability returns the codec capability for the local audio track.
+func opusCapability(stereo bool) webrtc.RTPCodecCapability {
+	c := webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: sampleRate, Channels: 1}
+	if stereo {
+		c.Channels = 2
+		c.SDPFmtpLine = opusStereoFmtp
+	}
+	return c
+}
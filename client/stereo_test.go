@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pion/webrtc/v4"
+)
+
+func TestStereoDefaultsOff(t *testing.T) {
+	if NewAudioEngine().Stereo() {
+		t.Error("audio engine should default to mono")
+	}
+}
+
+func TestCaptureChannelCount(t *testing.T) {
+	cases := []struct {
+		stereo   bool
+		maxInput int
+		want     int
+	}{
+		{false, 2, 1},
+		{true, 2, 2},
+		{true, 8, 2},
+		{true, 1, 1}, // mono device falls back
+	}
+	for _, c := range cases {
+		if got := captureChannelCount(c.stereo, c.maxInput); got != c.want {
+			t.Errorf("captureChannelCount(%v, %d) = %d, want %d", c.stereo, c.maxInput, got, c.want)
+		}
+	}
+}
+
+func TestDownmix(t *testing.T) {
+	src := []float32{1, 0, 0.5, 0.5, -1, 1}
+	dst := make([]float32, 3)
+	downmix(dst, src)
+	want := []float32{0.5, 0.5, 0}
+	for i := range want {
+		if dst[i] != want[i] {
+			t.Fatalf("downmix = %v, want %v", dst, want)
+		}
+	}
+}
+
+func TestOpusCapability(t *testing.T) {
+	mono := opusCapability(false)
+	if mono.MimeType != webrtc.MimeTypeOpus || mono.Channels != 1 || mono.SDPFmtpLine != "" {
+		t.Errorf("unexpected mono capability: %+v", mono)
+	}
+	stereo := opusCapability(true)
+	if stereo.Channels != 2 || stereo.SDPFmtpLine != opusStereoFmtp {
+		t.Errorf("unexpected stereo capability: %+v", stereo)
+	}
+}
+
+func TestAppSetStereoWhileIdle(t *testing.T) {
+	app, _ := newTestApp()
+	if result := app.SetStereo(true); result != "" {
+		t.Fatalf("expected success, got %q", result)
+	}
+	if !app.audio.Stereo() {
+		t.Error("expected stereo enabled on the audio engine")
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"io"
+	"time"
+)
+
+// voiceUploadCapKbps caps upload bandwidth while a voice session is active.
+// Opus voice needs ~32-64 kbps up; keeping bulk transfers well under a
+// typical uplink leaves headroom so a large file can't starve the call.
+const voiceUploadCapKbps = 256
+
+// uploadProgressInterval bounds how often upload progress is reported.
+const uploadProgressInterval = 250 * time.Millisecond
+
+// effectiveUploadKbps combines the user's upload limit with the in-call cap.
+// A result of 0 means unlimited.
+func effectiveUploadKbps(limitKbps int, inVoice bool) int {
+	if limitKbps < 0 {
+		limitKbps = 0
+	}
+	if inVoice && (limitKbps == 0 || limitKbps > voiceUploadCapKbps) {
+		return voiceUploadCapKbps
+	}
+	return limitKbps
+}
+
+// throttledReader rate-limits reads from r to the bandwidth returned by
+// kbps, which is consulted on every read so a limit change (for example
+// joining a voice channel mid-upload) applies immediately.
+type throttledReader struct {
+	r     io.Reader
+	kbps  func() int
+	now   func() time.Time
+	sleep func(time.Duration)
+
+	windowKbps  int
+	windowStart time.Time
+	windowBytes int64
+}
+
+func newThrottledReader(r io.Reader, kbps func() int) *throttledReader {
+	return &throttledReader{r: r, kbps: kbps, now: time.Now, sleep: time.Sleep}
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	kbps := t.kbps()
+	if kbps <= 0 {
+		t.windowKbps = 0
+		return t.r.Read(p)
+	}
+	if kbps != t.windowKbps {
+		t.windowKbps = kbps
+		t.windowStart = t.now()
+		t.windowBytes = 0
+	}
+
+	bytesPerSec := int64(kbps) * 1000 / 8
+	// Hand out at most ~50 ms worth per read so the pacing stays smooth
+	// instead of bursting a large buffer and then sleeping.
+	chunk := bytesPerSec / 20
+	if chunk < 1 {
+		chunk = 1
+	}
+	if int64(len(p)) > chunk {
+		p = p[:chunk]
+	}
+
+	n, err := t.r.Read(p)
+	t.windowBytes += int64(n)
+	due := time.Duration(t.windowBytes * int64(time.Second) / bytesPerSec)
+	if wait := due - t.now().Sub(t.windowStart); wait > 0 {
+		t.sleep(wait)
+	}
+	return n, err
+}
+
+// uploadProgress is one progress report for an in-flight upload.
+type uploadProgress struct {
+	Sent             int64
+	Total            int64
+	Percent          float64
+	RemainingSeconds float64 // -1 while the rate is still unknown
+}
+
+// progressReader reports how much of r has been consumed, at most once per
+// uploadProgressInterval plus a final report at EOF.
+type progressReader struct {
+	r          io.Reader
+	total      int64
+	onProgress func(uploadProgress)
+	now        func() time.Time
+
+	sent       int64
+	start      time.Time
+	lastReport time.Time
+}
+
+func newProgressReader(r io.Reader, total int64, onProgress func(uploadProgress)) *progressReader {
+	return &progressReader{r: r, total: total, onProgress: onProgress, now: time.Now}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	now := p.now()
+	if p.start.IsZero() {
+		p.start = now
+	}
+	n, err := p.r.Read(b)
+	p.sent += int64(n)
+	if err == io.EOF || now.Sub(p.lastReport) >= uploadProgressInterval {
+		p.lastReport = now
+		p.onProgress(p.snapshot(now))
+	}
+	return n, err
+}
+
+func (p *progressReader) snapshot(now time.Time) uploadProgress {
+	// The multipart envelope adds a few hundred bytes on top of the file,
+	// so clamp to the file size the user cares about.
+	sent := p.sent
+	if sent > p.total {
+		sent = p.total
+	}
+	out := uploadProgress{Sent: sent, Total: p.total, RemainingSeconds: -1}
+	if p.total > 0 {
+		out.Percent = float64(sent) * 100 / float64(p.total)
+	}
+	if elapsed := now.Sub(p.start).Seconds(); elapsed > 0 && sent > 0 {
+		rate := float64(sent) / elapsed
+		out.RemainingSeconds = float64(p.total-sent) / rate
+	}
+	return out
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+// fakeClock advances only when sleep is called, so throttling can be
+// verified without real waits.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time                        { return c.t }
+func (c *fakeClock) sleep(d time.Duration)                 { c.t = c.t.Add(d) }
+func (c *fakeClock) elapsed(start time.Time) time.Duration { return c.t.Sub(start) }
+
+func TestEffectiveUploadKbps(t *testing.T) {
+	cases := []struct {
+		limit   int
+		inVoice bool
+		want    int
+	}{
+		{0, false, 0},
+		{-5, false, 0},
+		{1000, false, 1000},
+		{0, true, voiceUploadCapKbps},
+		{1000, true, voiceUploadCapKbps},
+		{64, true, 64},
+	}
+	for _, c := range cases {
+		if got := effectiveUploadKbps(c.limit, c.inVoice); got != c.want {
+			t.Errorf("effectiveUploadKbps(%d, %v) = %d, want %d", c.limit, c.inVoice, got, c.want)
+		}
+	}
+}
+
+func TestThrottledReaderPacesToLimit(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	start := clock.now()
+
+	// 80 kbps = 10 000 bytes/s; 50 000 bytes should take ~5 s.
+	data := make([]byte, 50_000)
+	tr := newThrottledReader(bytes.NewReader(data), func() int { return 80 })
+	tr.now, tr.sleep = clock.now, clock.sleep
+
+	n, err := io.Copy(io.Discard, tr)
+	if err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if n != int64(len(data)) {
+		t.Fatalf("copied %d bytes, want %d", n, len(data))
+	}
+	if got := clock.elapsed(start); got < 4900*time.Millisecond || got > 5100*time.Millisecond {
+		t.Errorf("elapsed %v, want ~5s", got)
+	}
+}
+
+func TestThrottledReaderUnlimited(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	start := clock.now()
+
+	tr := newThrottledReader(bytes.NewReader(make([]byte, 1<<20)), func() int { return 0 })
+	tr.now, tr.sleep = clock.now, clock.sleep
+
+	if _, err := io.Copy(io.Discard, tr); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if got := clock.elapsed(start); got != 0 {
+		t.Errorf("unlimited reader slept %v", got)
+	}
+}
+
+func TestThrottledReaderAppliesLimitChange(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	start := clock.now()
+
+	kbps := 0
+	tr := newThrottledReader(bytes.NewReader(make([]byte, 20_000)), func() int { return kbps })
+	tr.now, tr.sleep = clock.now, clock.sleep
+
+	buf := make([]byte, 10_000)
+	if _, err := io.ReadFull(tr, buf); err != nil {
+		t.Fatalf("read unlimited half: %v", err)
+	}
+	if clock.elapsed(start) != 0 {
+		t.Fatal("first half should not be throttled")
+	}
+
+	// Joining a call mid-upload: the remaining 10 000 bytes at 80 kbps
+	// (10 000 bytes/s) should take ~1 s.
+	kbps = 80
+	if _, err := io.Copy(io.Discard, tr); err != nil {
+		t.Fatalf("read throttled half: %v", err)
+	}
+	if got := clock.elapsed(start); got < 900*time.Millisecond || got > 1100*time.Millisecond {
+		t.Errorf("elapsed %v after limit change, want ~1s", got)
+	}
+}
+
+func TestProgressReaderReportsCompletion(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	var reports []uploadProgress
+
+	// Pace the source so each read advances the clock by one progress interval.
+	src := newThrottledReader(bytes.NewReader(make([]byte, 4000)), func() int { return 80 })
+	src.now, src.sleep = clock.now, clock.sleep
+	pr := newProgressReader(src, 4000, func(p uploadProgress) { reports = append(reports, p) })
+	pr.now = clock.now
+
+	if _, err := io.Copy(io.Discard, pr); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if len(reports) < 2 {
+		t.Fatalf("expected several progress reports, got %d", len(reports))
+	}
+	last := reports[len(reports)-1]
+	if last.Sent != 4000 || last.Percent != 100 {
+		t.Errorf("final report = %+v, want 4000 bytes at 100%%", last)
+	}
+	if last.RemainingSeconds != 0 {
+		t.Errorf("final remaining = %v, want 0", last.RemainingSeconds)
+	}
+	mid := reports[len(reports)/2]
+	if mid.Sent > 0 && mid.Sent < 4000 && mid.RemainingSeconds <= 0 {
+		t.Errorf("mid-upload report should estimate remaining time: %+v", mid)
+	}
+}
+
+func TestProgressReaderClampsToTotal(t *testing.T) {
+	var last uploadProgress
+	// The multipart envelope makes the body larger than the file itself.
+	pr := newProgressReader(bytes.NewReader(make([]byte, 1200)), 1000, func(p uploadProgress) { last = p })
+	if _, err := io.Copy(io.Discard, pr); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if last.Sent != 1000 || last.Percent != 100 {
+		t.Errorf("report = %+v, want clamped to 1000 bytes / 100%%", last)
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"hash/fnv"
 	"log/slog"
+	"maps"
 	"math"
 	"net"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,8 +17,8 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v4"
-	"github.com/pion/webrtc/v4/pkg/media"
 )
 
 // mutedSet is a concurrent set of uint16 user IDs.
@@ -88,6 +90,7 @@ type UserInfo struct {
 	Username  string `json:"username"`
 	ChannelID int64  `json:"channel_id,omitempty"` // 0 = not in any channel
 	Role      string `json:"role,omitempty"`       // OWNER/ADMIN/MODERATOR/USER
+	Status    string `json:"status,omitempty"`     // "away" or "dnd"; "" = online
 }
 
 // ChannelInfo describes a voice channel.
@@ -95,6 +98,39 @@ type ChannelInfo struct {
 	ID       int64  `json:"id"`
 	Name     string `json:"name"`
 	MaxUsers int    `json:"max_users,omitempty"` // 0 = unlimited
+	// Lowest roles allowed to speak and chat; empty means everyone.
+	MinRoleToSpeak string `json:"min_role_to_speak,omitempty"`
+	MinRoleToChat  string `json:"min_role_to_chat,omitempty"`
+	// MinRoleToRecord is the lowest role allowed to record here.
+	MinRoleToRecord string `json:"min_role_to_record,omitempty"`
+	// MaxBitrateKbps caps the Opus bitrate used in this channel; 0 = no cap.
+	MaxBitrateKbps int `json:"max_bitrate_kbps,omitempty"`
+	// SlowModeSeconds is the minimum time between one user's messages
+	// here; 0 = off. Admins and the owner are exempt.
+	SlowModeSeconds int `json:"slow_mode_seconds,omitempty"`
+	// MessageTTLSeconds is how long the server keeps messages here before
+	// deleting them; 0 = forever.
+	MessageTTLSeconds int `json:"message_ttl_seconds,omitempty"`
+	// CategoryID and CategoryName group the channel; 0 = uncategorized.
+	CategoryID   int64  `json:"category_id,omitempty"`
+	CategoryName string `json:"category_name,omitempty"`
+	// Locked channels take no new joins from users below moderator;
+	// whoever is already inside stays.
+	Locked bool `json:"locked,omitempty"`
+}
+
+// CategoryInfo is a channel category, sent alongside channel_list.
+type CategoryInfo struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// Permissions is the server's owner and role assignments, as returned for
+// get_permissions. CoOwners is only filled in for admins and the owner.
+type Permissions struct {
+	OwnerID  uint16            `json:"owner_id"`
+	CoOwners []uint16          `json:"co_owners"`
+	Roles    map[uint16]string `json:"roles"`
 }
 
 // ChatHistoryMessage is a single message in a channel's message history.
@@ -106,9 +142,41 @@ type ChatHistoryMessage struct {
 	FileID    string               `json:"file_id,omitempty"`
 	FileName  string               `json:"file_name,omitempty"`
 	FileSize  int64                `json:"file_size,omitempty"`
+	ReplyTo   int64                `json:"reply_to,omitempty"`
 	Reactions []ChatHistoryReaction `json:"reactions,omitempty"`
 }
 
+// MessageEdit is an earlier version of an edited message and when it was
+// replaced, in Unix milliseconds.
+type MessageEdit struct {
+	Message string `json:"message"`
+	TS      int64  `json:"ts"`
+}
+
+// AuditEntry is one moderation action from the server's audit log, in
+// audit_log and audit_entry. TS is in Unix milliseconds.
+type AuditEntry struct {
+	ActorID    string `json:"actor_id,omitempty"`
+	ActorName  string `json:"actor_name,omitempty"`
+	Action     string `json:"action"`
+	TargetID   string `json:"target_id,omitempty"`
+	TargetName string `json:"target_name,omitempty"`
+	Details    string `json:"details,omitempty"`
+	TS         int64  `json:"ts"`
+}
+
+// BanInfo is one active ban in ban_list. TS and ExpiresAt are in Unix
+// milliseconds; ExpiresAt is 0 for a permanent ban.
+type BanInfo struct {
+	ID        int64  `json:"id"`
+	IP        string `json:"ip"`
+	Username  string `json:"username,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	BannedBy  string `json:"banned_by,omitempty"`
+	TS        int64  `json:"ts"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+}
+
 // ChatHistoryReaction describes a single emoji reaction in message history.
 type ChatHistoryReaction struct {
 	Emoji   string   `json:"emoji"`
@@ -128,6 +196,7 @@ type backendUser struct {
 	ID       string             `json:"id"`
 	Username string             `json:"username"`
 	Voice    *backendVoiceState `json:"voice,omitempty"`
+	Status   string             `json:"status,omitempty"`
 }
 
 type backendVoiceState struct {
@@ -135,12 +204,26 @@ type backendVoiceState struct {
 	ChannelID string `json:"channel_id"`
 	Muted     bool   `json:"muted,omitempty"`
 	Deafened  bool   `json:"deafened,omitempty"`
+	// Monitoring lists the other channels the user listens to; see
+	// AddMonitorChannel.
+	Monitoring []string `json:"monitoring,omitempty"`
 }
 
 type backendSnapshotMsg struct {
-	Type   string        `json:"type"`
-	SelfID string        `json:"self_id"`
-	Users  []backendUser `json:"users"`
+	Type             string          `json:"type"`
+	SelfID           string          `json:"self_id"`
+	OwnerID          string          `json:"owner_id,omitempty"`
+	Users            []backendUser   `json:"users"`
+	ProtocolVersion  int             `json:"protocol_version,omitempty"`
+	Proto            string          `json:"proto,omitempty"`
+	MOTD             string          `json:"motd,omitempty"`
+	AllowedEmoji     []string        `json:"allowed_emoji,omitempty"`
+	MaxUploadBytes   int64           `json:"max_upload_bytes,omitempty"`
+	MaxMessageLength int             `json:"max_message_length,omitempty"`
+	ICEServers       []ICEServerInfo `json:"ice_servers,omitempty"`
+	JoinSoundURL     string          `json:"join_sound_url,omitempty"`
+	LeaveSoundURL    string          `json:"leave_sound_url,omitempty"`
+	SessionToken     string          `json:"session_token,omitempty"`
 }
 
 type backendUserMsg struct {
@@ -148,6 +231,7 @@ type backendUserMsg struct {
 	User      *backendUser `json:"user,omitempty"`
 	ServerID  string       `json:"server_id,omitempty"`
 	ChannelID string       `json:"channel_id,omitempty"`
+	UserID    string       `json:"user_id,omitempty"` // dm: recipient
 	Message   string       `json:"message,omitempty"`
 	MsgID     int64        `json:"msg_id,omitempty"`
 	Ts        int64        `json:"ts,omitempty"`
@@ -155,6 +239,28 @@ type backendUserMsg struct {
 	FileID    string       `json:"file_id,omitempty"`
 	FileName  string       `json:"file_name,omitempty"`
 	FileSize  int64        `json:"file_size,omitempty"`
+	Mentions  []string     `json:"mentions,omitempty"` // text_message: mentioned user IDs
+
+	// RetryAfterMs accompanies errors for rate-limited requests, such as a
+	// voice channel switch inside the server's cooldown.
+	RetryAfterMs int64 `json:"retry_after_ms,omitempty"`
+}
+
+// backendHistoryMsg is one persisted message in message_history and thread.
+type backendHistoryMsg struct {
+	MsgID     int64  `json:"msg_id"`
+	Username  string `json:"username"`
+	Message   string `json:"message"`
+	TS        int64  `json:"ts"`
+	FileID    string `json:"file_id"`
+	FileName  string `json:"file_name"`
+	FileSize  int64  `json:"file_size"`
+	ReplyTo   int64  `json:"reply_to"`
+	Reactions []struct {
+		Emoji   string   `json:"emoji"`
+		UserIDs []string `json:"user_ids"`
+		Count   int      `json:"count"`
+	} `json:"reactions"`
 }
 
 // Metrics holds connection quality metrics shown in the UI.
@@ -169,6 +275,14 @@ type Metrics struct {
 	PlaybackDropped uint64  `json:"playback_dropped"` // frames dropped on recv side since last tick
 }
 
+// PeerMetrics holds receive-side connection quality for one remote peer.
+type PeerMetrics struct {
+	UserID     uint16  `json:"user_id"`
+	RTTMs      float64 `json:"rtt_ms"`      // from the peer's RTCP receiver reports
+	PacketLoss float64 `json:"packet_loss"` // 0.0–1.0, over the last metrics interval
+	JitterMs   float64 `json:"jitter_ms"`   // inter-arrival jitter (smoothed)
+}
+
 // qualityLevel classifies connection quality from metrics.
 // Thresholds: good (loss<2%, RTT<100ms, jitter<20ms, drops<1/s),
 // moderate (loss<10%, RTT<300ms, jitter<50ms, drops<5/s), poor (everything else).
@@ -183,14 +297,56 @@ func qualityLevel(loss, rttMs, jitterMs, dropRate float64) string {
 	return "good"
 }
 
+// peerStats is the receive-side quality accounting for one remote peer.
+// Guarded by Transport.statsMu.
+type peerStats struct {
+	// Sequence-gap counts since the last GetMetrics, and the loss ratio
+	// they gave over the interval before that.
+	lost, expected uint64
+	loss           float64
+
+	jitterMs float64 // EWMA of |actual_gap - frame duration|
+	rttMs    float64 // EWMA; 0 until the peer's first receiver report
+}
+
 type peerState struct {
 	id      uint16
 	pc      *webrtc.PeerConnection
-	track   *webrtc.TrackLocalStaticSample
+	sender  *webrtc.RTPSender
 	trackID string
 
 	mu         sync.Mutex
+	track      *webrtc.TrackLocalStaticRTP // replaced by SetStereo
+	packetizer *opusPacketizer
 	pendingICE []webrtc.ICECandidateInit
+	// Data channels for ephemeral signals; see setupSignalChannels.
+	// signalsOut is ours once open, signalsIn whether the peer's is open.
+	signalsOut *webrtc.DataChannel
+	signalsIn  bool
+	// connectedAt is when the connection last became connected, zero
+	// while it is not; claimedSpeaking whether the peer has told us it is
+	// speaking. See checkPeerStalls.
+	connectedAt     time.Time
+	claimedSpeaking bool
+}
+
+// writeFrame sends one Opus frame of duration d on the peer's audio track,
+// along with a repeat of the previous frame if that asked for one. It
+// returns the payload bytes written.
+func (p *peerState) writeFrame(frame []byte, d time.Duration, onset, redundant bool) (int, error) {
+	p.mu.Lock()
+	track := p.track
+	packets := p.packetizer.packetize(frame, d, onset, redundant)
+	p.mu.Unlock()
+
+	sent := 0
+	for _, pkt := range packets {
+		if err := track.WriteRTP(pkt); err != nil {
+			return sent, err
+		}
+		sent += len(pkt.Payload)
+	}
+	return sent, nil
 }
 
 // Transport manages the websocket signaling channel and WebRTC media peers.
@@ -216,20 +372,27 @@ type Transport struct {
 	// Bytes sent since the last GetMetrics call (for bitrate calculation).
 	bytesSent atomic.Uint64
 
-	// Packet loss accounting via incoming sequence-gap detection.
-	lostPackets     atomic.Uint64
-	expectedPackets atomic.Uint64
-
-	// Inter-arrival jitter: EWMA of |actual_gap - 20ms| across all senders,
-	// stored as float64 bits for atomic access. Units: milliseconds.
-	smoothedJitter atomic.Uint64
-
 	// Dropped frame counters: incremented when the playback channel is full
 	// and a received frame cannot be delivered.
 	playbackDropped atomic.Uint64
+	// dropOldest makes a full playback channel lose its oldest frame
+	// instead of the new one; see SetDropPolicy.
+	dropOldest atomic.Bool
+
+	// pacer spaces outgoing audio frames; see SendAudio.
+	pacer *framePacer
+	// onsetRedundancy repeats the first frames of each talk spurt; see
+	// SetOnsetRedundancy. lastAudioWrite and onsetLeft track talk spurts
+	// and are used only by the pacer goroutine.
+	onsetRedundancy atomic.Bool
+	lastAudioWrite  time.Time
+	onsetLeft       int
 
 	// muted holds the set of remote user IDs whose audio is suppressed locally.
 	muted mutedSet
+	// blocked holds the remote user IDs whose chat, typing and reactions
+	// are dropped as well; see BlockUser.
+	blocked mutedSet
 
 	// userVolume stores per-user volume multipliers (uint16 -> float64).
 	// Default (absent) means 1.0. Range is [0.0, 2.0] (0%-200%).
@@ -242,6 +405,47 @@ type Transport struct {
 	// cause to the onDisconnected callback. Protected by mu.
 	disconnectReason string
 
+	// Automatic reconnect after an unexpected close; reconnectMax 0 turns it
+	// off. username is kept from Connect to replay hello. noReconnect marks
+	// a close the server meant (version mismatch, kick), where retrying
+	// cannot help. All protected by mu.
+	reconnectMax  int
+	reconnectBase time.Duration
+	username      string
+	noReconnect   bool
+
+	// closeGen is bumped by every deliberate Disconnect, so a session's
+	// goroutines can tell it apart from a dropped connection.
+	closeGen atomic.Uint64
+
+	// stereo selects a two-channel Opus capability for local tracks.
+	stereo atomic.Bool
+
+	// motdShown is set once the message of the day has been reported for
+	// this Connect, so reconnects do not show it again.
+	motdShown atomic.Bool
+
+	// binaryCtrl is set once the snapshot accepts protoBinary; from then on
+	// writeJSON sends MessagePack binary frames. Cleared on every dial.
+	binaryCtrl atomic.Bool
+
+	// whisperTarget is the only peer SendAudio writes to while set; 0
+	// means the usual fan-out to everyone in our channel.
+	whisperTarget atomic.Uint32
+
+	// lastVoiceActivity is the Unix ms of the last voice_activity sent; see
+	// SendVoiceActivity.
+	lastVoiceActivity atomic.Int64
+
+	// speakingTimer sends our speaking stop once SendSpeaking has not been
+	// called for speakingHangover; nil while we are not speaking.
+	speakingMu    sync.Mutex
+	speakingTimer *time.Timer
+
+	// lastTypingChannel and lastTypingAt throttle SendTyping.
+	lastTypingChannel int64     // protected by mu
+	lastTypingAt      time.Time // protected by mu
+
 	// lastMetricsTime is the timestamp of the previous GetMetrics call.
 	metricsMu       sync.Mutex
 	lastMetricsTime time.Time
@@ -253,12 +457,30 @@ type Transport struct {
 	// apiBaseURL is the HTTP base URL for the server's REST API (e.g. "http://host:8080").
 	// Set from the api_port field in user_list.
 	apiBaseURL string // protected by mu
+	// sessionToken authenticates REST calls for this session; it comes
+	// from the snapshot.
+	sessionToken string // protected by mu
+	// maxUploadBytes is the server's advertised upload limit from the
+	// snapshot; 0 until received or when the server predates it.
+	maxUploadBytes int64 // protected by mu
+	// maxMessageLen is the server's advertised chat message limit from the
+	// snapshot; 0 until received or when the server predates it.
+	maxMessageLen int // protected by mu
+	// allowedEmoji is the server's reaction allow-list from the snapshot;
+	// empty means any emoji.
+	allowedEmoji []string // protected by mu
+	// lastMsgSeq is the highest msg_id seen per wire channel ID. It survives
+	// reconnects so resume can ask the server for only what was missed.
+	lastMsgSeq map[string]int64 // protected by mu
 
 	// playbackCh receives decoded Opus payloads from remote tracks.
-	playbackCh chan<- TaggedAudio
+	playbackCh chan TaggedAudio
 
 	// userChannels tracks the latest channel for each connected user.
 	userChannels sync.Map // map[uint16]int64
+	// userMonitoring holds the channels each user monitors besides their
+	// own; see AddMonitorChannel.
+	userMonitoring sync.Map // map[uint16][]int64
 
 	// ID/channel mapping for backend protocol compatibility.
 	userIDByWire    map[string]uint16 // protected by mu
@@ -266,7 +488,9 @@ type Transport struct {
 	channelIDByWire map[string]int64  // protected by mu
 	wireChannelByID map[int64]string  // protected by mu
 
-	// iceServers holds ICE configuration received from the server in user_list.
+	// iceServers holds the ICE configuration most recently received from
+	// the server: the server-wide list in snapshot (or user_list), then the
+	// joined channel's list in ice_update. New peers use it.
 	iceServers []ICEServerInfo // protected by mu
 
 	// peers holds one RTCPeerConnection per remote user.
@@ -276,10 +500,15 @@ type Transport struct {
 	statsMu      sync.Mutex
 	lastSeq      map[uint16]uint16
 	hasSeq       map[uint16]bool
+	seenSeqs     map[uint16]uint64 // bit i: lastSeq-i received; see duplicateSeqLocked
 	lastSeen     map[uint16]time.Time
 	lastArrival  map[uint16]time.Time
 	lastSpeaking map[uint16]time.Time
+	peerStats    map[uint16]*peerStats
 	pruneCounter int
+	// stalledPeers holds the peers checkPeerStalls found connected but
+	// sending us no audio.
+	stalledPeers sync.Map // map[uint16]struct{}
 
 	// Callbacks — set via setters before calling Connect.
 	cbMu                 sync.RWMutex
@@ -288,12 +517,16 @@ type Transport struct {
 	onUserLeft           func(uint16)
 	onAudioReceived      func(uint16)
 	onDisconnected       func(reason string)
+	onReconnecting       func(attempt int)
 	onChatMessage        func(msgID uint64, senderID uint16, username, message string, ts int64, fileID string, fileName string, fileSize int64, mentions []uint16)
 	onChannelChatMessage func(msgID uint64, senderID uint16, channelID int64, username, message string, ts int64, fileID string, fileName string, fileSize int64, mentions []uint16)
+	onDM                 func(senderID, targetID uint16, username, message string, ts int64)
 	onServerInfo         func(name string)
-	onKicked             func()
+	onServerError        func(message string, retryAfterMs int64)
+	onKicked             func(reason string)
 	onOwnerChanged       func(ownerID uint16)
 	onChannelList        func([]ChannelInfo)
+	onCategoryList       func([]CategoryInfo)
 	onUserChannel        func(userID uint16, channelID int64)
 	onLinkPreview        func(msgID uint64, channelID int64, url, title, desc, image, siteName string)
 	onUserRenamed        func(userID uint16, username string)
@@ -302,12 +535,36 @@ type Transport struct {
 	onVideoState         func(userID uint16, active bool, screenShare bool)
 	onReactionAdded      func(msgID uint64, emoji string, userID uint16)
 	onReactionRemoved    func(msgID uint64, emoji string, userID uint16)
+	onReactionsUpdated   func(msgID uint64, reactions []ChatHistoryReaction)
+	onPermissions        func(Permissions)
 	onUserTyping         func(userID uint16, username string, channelID int64)
 	onMessagePinned      func(msgID uint64, channelID int64, userID uint16)
 	onMessageUnpinned    func(msgID uint64)
+	onPinnedList         func(channelID int64, pins []PinnedMessage)
 	onVideoLayers        func(userID uint16, layers []VideoLayer)
 	onMessageHistory     func(channelID int64, messages []ChatHistoryMessage)
+	onThread             func(msgID uint64, messages []ChatHistoryMessage)
+	onEditHistory        func(msgID uint64, edits []MessageEdit)
+	onAuditLog           func(entries []AuditEntry)
+	onAuditEntry         func(entry AuditEntry)
+	onBanList            func(bans []BanInfo)
 	onUserVoiceFlags     func(userID uint16, muted, deafened bool)
+	onRecordingStarted   func(userID uint16, consentRequired bool)
+	onRecordingStopped   func(userID uint16)
+	onSoundboard         func(userID uint16, clipID string)
+	onAnnouncement       func(text, postedBy string)
+	onMention            func(msgID uint64, channelID int64, senderID uint16, username string)
+	onMessageRead        func(msgID uint64, readers []uint16)
+	onUserMuted          func(userID uint16, muted bool)
+	onUserStatus         func(userID uint16, status string)
+	onSpeaking           func(userID uint16, speaking bool)
+	onMOTD               func(text string)
+	onJoinSounds         func(joinURL, leaveURL string)
+	onEmojiList          func(emoji []string)
+	onServerShutdown     func(graceMs int64)
+	onStopRecording      func()
+	onPeerAudioStalled   func(id uint16)
+	onPeerAudioResumed   func(id uint16)
 }
 
 // Verify Transport satisfies the Transporter interface at compile time.
@@ -315,19 +572,23 @@ var _ Transporter = (*Transport)(nil)
 
 // NewTransport creates a ready-to-use Transport.
 func NewTransport() *Transport {
-	return &Transport{
+	t := &Transport{
 		lastMetricsTime: time.Now(),
 		peers:           make(map[uint16]*peerState),
 		lastSeq:         make(map[uint16]uint16),
 		hasSeq:          make(map[uint16]bool),
+		seenSeqs:        make(map[uint16]uint64),
 		lastSeen:        make(map[uint16]time.Time),
 		lastArrival:     make(map[uint16]time.Time),
 		lastSpeaking:    make(map[uint16]time.Time),
+		peerStats:       make(map[uint16]*peerStats),
 		userIDByWire:    make(map[string]uint16),
 		wireIDByUser:    make(map[uint16]string),
 		channelIDByWire: make(map[string]int64),
 		wireChannelByID: make(map[int64]string),
 	}
+	t.pacer = newFramePacer(sendQueueFrames, t.writeAudio)
+	return t
 }
 
 // --- Callback setters (satisfy Transporter interface) ---
@@ -362,6 +623,12 @@ func (t *Transport) SetOnDisconnected(fn func(reason string)) {
 	t.cbMu.Unlock()
 }
 
+func (t *Transport) SetOnReconnecting(fn func(attempt int)) {
+	t.cbMu.Lock()
+	t.onReconnecting = fn
+	t.cbMu.Unlock()
+}
+
 func (t *Transport) SetOnChatMessage(fn func(msgID uint64, senderID uint16, username, message string, ts int64, fileID string, fileName string, fileSize int64, mentions []uint16)) {
 	t.cbMu.Lock()
 	t.onChatMessage = fn
@@ -374,13 +641,25 @@ func (t *Transport) SetOnChannelChatMessage(fn func(msgID uint64, senderID uint1
 	t.cbMu.Unlock()
 }
 
+func (t *Transport) SetOnDM(fn func(senderID, targetID uint16, username, message string, ts int64)) {
+	t.cbMu.Lock()
+	t.onDM = fn
+	t.cbMu.Unlock()
+}
+
 func (t *Transport) SetOnServerInfo(fn func(name string)) {
 	t.cbMu.Lock()
 	t.onServerInfo = fn
 	t.cbMu.Unlock()
 }
 
-func (t *Transport) SetOnKicked(fn func()) {
+func (t *Transport) SetOnServerError(fn func(message string, retryAfterMs int64)) {
+	t.cbMu.Lock()
+	t.onServerError = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnKicked(fn func(reason string)) {
 	t.cbMu.Lock()
 	t.onKicked = fn
 	t.cbMu.Unlock()
@@ -398,6 +677,12 @@ func (t *Transport) SetOnChannelList(fn func([]ChannelInfo)) {
 	t.cbMu.Unlock()
 }
 
+func (t *Transport) SetOnCategoryList(fn func([]CategoryInfo)) {
+	t.cbMu.Lock()
+	t.onCategoryList = fn
+	t.cbMu.Unlock()
+}
+
 func (t *Transport) SetOnUserChannel(fn func(userID uint16, channelID int64)) {
 	t.cbMu.Lock()
 	t.onUserChannel = fn
@@ -446,6 +731,18 @@ func (t *Transport) SetOnReactionRemoved(fn func(msgID uint64, emoji string, use
 	t.cbMu.Unlock()
 }
 
+func (t *Transport) SetOnReactionsUpdated(fn func(msgID uint64, reactions []ChatHistoryReaction)) {
+	t.cbMu.Lock()
+	t.onReactionsUpdated = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnPermissions(fn func(Permissions)) {
+	t.cbMu.Lock()
+	t.onPermissions = fn
+	t.cbMu.Unlock()
+}
+
 func (t *Transport) SetOnUserTyping(fn func(userID uint16, username string, channelID int64)) {
 	t.cbMu.Lock()
 	t.onUserTyping = fn
@@ -476,12 +773,135 @@ func (t *Transport) SetOnMessageHistory(fn func(channelID int64, messages []Chat
 	t.cbMu.Unlock()
 }
 
+func (t *Transport) SetOnThread(fn func(msgID uint64, messages []ChatHistoryMessage)) {
+	t.cbMu.Lock()
+	t.onThread = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnEditHistory(fn func(msgID uint64, edits []MessageEdit)) {
+	t.cbMu.Lock()
+	t.onEditHistory = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnAuditLog(fn func(entries []AuditEntry)) {
+	t.cbMu.Lock()
+	t.onAuditLog = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnAuditEntry(fn func(entry AuditEntry)) {
+	t.cbMu.Lock()
+	t.onAuditEntry = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnBanList(fn func(bans []BanInfo)) {
+	t.cbMu.Lock()
+	t.onBanList = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnPinnedList(fn func(channelID int64, pins []PinnedMessage)) {
+	t.cbMu.Lock()
+	t.onPinnedList = fn
+	t.cbMu.Unlock()
+}
+
 func (t *Transport) SetOnUserVoiceFlags(fn func(userID uint16, muted, deafened bool)) {
 	t.cbMu.Lock()
 	t.onUserVoiceFlags = fn
 	t.cbMu.Unlock()
 }
 
+func (t *Transport) SetOnRecordingStarted(fn func(userID uint16, consentRequired bool)) {
+	t.cbMu.Lock()
+	t.onRecordingStarted = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnRecordingStopped(fn func(userID uint16)) {
+	t.cbMu.Lock()
+	t.onRecordingStopped = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnSoundboard(fn func(userID uint16, clipID string)) {
+	t.cbMu.Lock()
+	t.onSoundboard = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnAnnouncement(fn func(text, postedBy string)) {
+	t.cbMu.Lock()
+	t.onAnnouncement = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnMention(fn func(msgID uint64, channelID int64, senderID uint16, username string)) {
+	t.cbMu.Lock()
+	t.onMention = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnMessageRead(fn func(msgID uint64, readers []uint16)) {
+	t.cbMu.Lock()
+	t.onMessageRead = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnUserMuted(fn func(userID uint16, muted bool)) {
+	t.cbMu.Lock()
+	t.onUserMuted = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnUserStatus(fn func(userID uint16, status string)) {
+	t.cbMu.Lock()
+	t.onUserStatus = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnSpeaking(fn func(userID uint16, speaking bool)) {
+	t.cbMu.Lock()
+	t.onSpeaking = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnMOTD(fn func(text string)) {
+	t.cbMu.Lock()
+	t.onMOTD = fn
+	t.cbMu.Unlock()
+}
+
+// SetOnJoinSounds sets the callback for the server's own join and leave
+// sounds, called with each snapshot. The URLs are absolute; "" means the
+// bundled sound.
+func (t *Transport) SetOnJoinSounds(fn func(joinURL, leaveURL string)) {
+	t.cbMu.Lock()
+	t.onJoinSounds = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnEmojiList(fn func(emoji []string)) {
+	t.cbMu.Lock()
+	t.onEmojiList = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnServerShutdown(fn func(graceMs int64)) {
+	t.cbMu.Lock()
+	t.onServerShutdown = fn
+	t.cbMu.Unlock()
+}
+
+func (t *Transport) SetOnStopRecording(fn func()) {
+	t.cbMu.Lock()
+	t.onStopRecording = fn
+	t.cbMu.Unlock()
+}
+
 // SendVoiceFlags sends a set_voice_state message to the server.
 func (t *Transport) SendVoiceFlags(muted, deafened bool) error {
 	return t.writeJSON(map[string]any{
@@ -505,6 +925,23 @@ func (t *Transport) IsUserMuted(id uint16) bool { return t.muted.Has(id) }
 // MutedUsers returns the IDs of all currently muted remote users.
 func (t *Transport) MutedUsers() []uint16 { return t.muted.Slice() }
 
+// BlockUser hides a remote user entirely: their voice is muted and their
+// chat messages, DMs, mentions, typing indicators, reactions and
+// soundboard clips are dropped before any callback sees them.
+func (t *Transport) BlockUser(id uint16) {
+	t.blocked.Add(id)
+	t.muted.Add(id)
+}
+
+// UnblockUser reverses BlockUser, unmuting the user's voice too.
+func (t *Transport) UnblockUser(id uint16) {
+	t.blocked.Remove(id)
+	t.muted.Remove(id)
+}
+
+// IsBlocked reports whether id is blocked.
+func (t *Transport) IsBlocked(id uint16) bool { return t.blocked.Has(id) }
+
 // SetUserVolume sets the local playback volume multiplier for a remote user.
 // volume is in [0.0, 2.0] representing 0%-200%. Default (unset) is 1.0.
 func (t *Transport) SetUserVolume(id uint16, volume float64) {
@@ -527,10 +964,99 @@ func (t *Transport) GetUserVolume(id uint16) float64 {
 	return v.(float64)
 }
 
-// KickUser sends a kick request to the server. Only succeeds if the caller is
-// the channel owner; the server enforces the authorisation check.
+// KickUser asks the server to disconnect a user without giving a reason.
 func (t *Transport) KickUser(id uint16) error {
-	return t.writeCtrl(ControlMsg{Type: "kick", ID: id})
+	return t.KickUserWithReason(id, "")
+}
+
+// KickUserWithReason asks the server to disconnect a user, passing reason on
+// to them in the kicked message. Unlike a ban they may reconnect straight
+// away. The server ignores the request unless we are an admin or the owner
+// and outrank the target.
+func (t *Transport) KickUserWithReason(id uint16, reason string) error {
+	wire, ok := t.wireUserID(id)
+	if !ok {
+		return fmt.Errorf("unknown user %d", id)
+	}
+	return t.writeJSON(map[string]any{
+		"type":    "kick",
+		"user_id": wire,
+		"reason":  reason,
+	})
+}
+
+// BanUser asks the server to ban a user by the address they connected from
+// and disconnect them. durationS is the ban length in seconds; 0 bans for
+// good. The server ignores the request unless we are an admin or the owner
+// and outrank the target.
+func (t *Transport) BanUser(id uint16, reason string, durationS int) error {
+	wire, ok := t.wireUserID(id)
+	if !ok {
+		return fmt.Errorf("unknown user %d", id)
+	}
+	return t.writeJSON(map[string]any{
+		"type":       "ban_user",
+		"user_id":    wire,
+		"reason":     reason,
+		"duration_s": durationS,
+	})
+}
+
+// RequestBans asks the server for its active bans; the reply arrives
+// through the onBanList callback, newest first. The server only answers
+// admins and the owner, and ignores everyone else.
+func (t *Transport) RequestBans() error {
+	return t.writeJSON(map[string]any{"type": "get_bans"})
+}
+
+// Unban asks the server to lift the ban with the given ID, as listed in
+// ban_list. The server answers with the updated list.
+func (t *Transport) Unban(id int64) error {
+	return t.writeJSON(map[string]any{
+		"type":   "unban",
+		"ban_id": id,
+	})
+}
+
+// MuteUserServer asks the server to mute a user in voice for durationS
+// seconds, or until UnmuteUserServer when durationS is 0. The server
+// rejects it unless we are a moderator or above and outrank the target.
+func (t *Transport) MuteUserServer(id uint16, durationS int) error {
+	wire, ok := t.wireUserID(id)
+	if !ok {
+		return fmt.Errorf("unknown user %d", id)
+	}
+	return t.writeJSON(map[string]any{
+		"type":       "mute_user",
+		"user_id":    wire,
+		"duration_s": durationS,
+	})
+}
+
+// UnmuteUserServer asks the server to lift a mute set by MuteUserServer.
+func (t *Transport) UnmuteUserServer(id uint16) error {
+	wire, ok := t.wireUserID(id)
+	if !ok {
+		return fmt.Errorf("unknown user %d", id)
+	}
+	return t.writeJSON(map[string]any{
+		"type":    "mute_user",
+		"user_id": wire,
+		"muted":   false,
+	})
+}
+
+// TransferOwner asks the server to hand ownership to another connected
+// user. The server rejects it unless we are the owner.
+func (t *Transport) TransferOwner(id uint16) error {
+	wire, ok := t.wireUserID(id)
+	if !ok {
+		return fmt.Errorf("unknown user %d", id)
+	}
+	return t.writeJSON(map[string]any{
+		"type":    "transfer_owner",
+		"user_id": wire,
+	})
 }
 
 // RenameServer sends a rename request to the server. Only succeeds if the
@@ -564,12 +1090,100 @@ func (t *Transport) RenameChannel(id int64, name string) error {
 	return t.writeCtrl(ControlMsg{Type: "rename_channel", ChannelID: id, Message: name})
 }
 
+// SetChannelBitrate asks the server to cap the bitrate used in a channel
+// (0 removes the cap). Only the owner may; the server enforces the check.
+func (t *Transport) SetChannelBitrate(id int64, kbps int) error {
+	return t.writeJSON(map[string]any{
+		"type":             "set_channel_bitrate",
+		"channel_id":       t.wireChannelID(id),
+		"max_bitrate_kbps": kbps,
+	})
+}
+
+// SetSlowMode asks the server to limit how often each user may post in a
+// channel (0 turns slow mode off). Only admins and the owner may; the
+// server enforces the check.
+func (t *Transport) SetSlowMode(id int64, seconds int) error {
+	return t.writeJSON(map[string]any{
+		"type":              "set_slow_mode",
+		"channel_id":        t.wireChannelID(id),
+		"slow_mode_seconds": seconds,
+	})
+}
+
+// SetChannelLock asks the server to lock or unlock a channel. Only
+// moderators and above may; the server enforces the check.
+func (t *Transport) SetChannelLock(id int64, locked bool) error {
+	return t.writeJSON(map[string]any{
+		"type":       "set_channel_lock",
+		"channel_id": t.wireChannelID(id),
+		"locked":     locked,
+	})
+}
+
+// SetChannelRecordRole asks the server to set the lowest role allowed to
+// record in a channel ("" lets everyone). Only the owner may; the server
+// enforces the check.
+func (t *Transport) SetChannelRecordRole(id int64, role string) error {
+	return t.writeJSON(map[string]any{
+		"type":               "set_channel_record_role",
+		"channel_id":         t.wireChannelID(id),
+		"min_role_to_record": role,
+	})
+}
+
+// SetChannelTTL asks the server to delete messages in a channel once they
+// are older than seconds (0 keeps them forever). Only the owner may; the
+// server enforces the check.
+func (t *Transport) SetChannelTTL(id int64, seconds int) error {
+	return t.writeJSON(map[string]any{
+		"type":                "set_channel_ttl",
+		"channel_id":          t.wireChannelID(id),
+		"message_ttl_seconds": seconds,
+	})
+}
+
+// CreateCategory asks the server to create a channel category.
+// Only succeeds if the caller is the owner; the server enforces the check.
+func (t *Transport) CreateCategory(name string) error {
+	return t.writeJSON(map[string]any{"type": "create_category", "message": name})
+}
+
+// AssignChannelCategory asks the server to move a channel into a category;
+// categoryID 0 makes it uncategorized. Owner only.
+func (t *Transport) AssignChannelCategory(channelID, categoryID int64) error {
+	return t.writeJSON(map[string]any{
+		"type":        "assign_channel_category",
+		"channel_id":  t.wireChannelID(channelID),
+		"category_id": categoryID,
+	})
+}
+
 // DeleteChannel asks the server to delete a channel.
 // Only succeeds if the caller is the channel owner; the server enforces the check.
 func (t *Transport) DeleteChannel(id int64) error {
 	return t.writeCtrl(ControlMsg{Type: "delete_channel", ChannelID: id})
 }
 
+// StartRecording tells the server a local recording is running in our
+// voice channel, so its members are told and, if required, asked to
+// consent. If our role is too low for the channel, the server replies with
+// stop_recording.
+func (t *Transport) StartRecording() error {
+	return t.writeJSON(map[string]any{"type": "start_recording"})
+}
+
+// StopRecording tells the server our local recording has finished.
+func (t *Transport) StopRecording() error {
+	return t.writeJSON(map[string]any{"type": "stop_recording"})
+}
+
+// SendRecordingConsent answers a recording_started that requires consent:
+// true lets us unmute while the channel is recorded, false leaves voice.
+func (t *Transport) SendRecordingConsent(consent bool) error {
+	return t.writeJSON(map[string]any{"type": "recording_consent", "consent": consent})
+}
+
 // MoveUser asks the server to move a user to a different channel.
 // Only succeeds if the caller is the channel owner; the server enforces the check.
 func (t *Transport) MoveUser(userID uint16, channelID int64) error {
@@ -605,6 +1219,26 @@ func (t *Transport) RequestChannels() error {
 	return t.writeJSON(map[string]any{"type": "get_channels"})
 }
 
+// SetRole asks the server to give another user a role: "USER",
+// "MODERATOR" or "ADMIN". Only the owner may; the server enforces it.
+func (t *Transport) SetRole(id uint16, role string) error {
+	wire, ok := t.wireUserID(id)
+	if !ok {
+		return fmt.Errorf("unknown user %d", id)
+	}
+	return t.writeJSON(map[string]any{
+		"type":    "set_role",
+		"user_id": wire,
+		"role":    role,
+	})
+}
+
+// GetPermissions asks the server for the current owner and role
+// assignments; the reply arrives through the onPermissions callback.
+func (t *Transport) GetPermissions() error {
+	return t.writeJSON(map[string]any{"type": "get_permissions"})
+}
+
 // RequestMessages asks the server to send message history for a channel.
 func (t *Transport) RequestMessages(channelID int64) error {
 	return t.writeJSON(map[string]any{
@@ -613,15 +1247,70 @@ func (t *Transport) RequestMessages(channelID int64) error {
 	})
 }
 
+// RequestMessagesBefore asks the server for up to limit messages in a
+// channel older than before, for loading scrollback; before 0 starts from
+// the newest. The page arrives through the onMessageHistory callback,
+// oldest first like any other history, so its first message is the cursor
+// for the next page. The server caps limit.
+func (t *Transport) RequestMessagesBefore(channelID int64, before uint64, limit int) error {
+	return t.writeJSON(map[string]any{
+		"type":       "get_messages_before",
+		"channel_id": t.wireChannelID(channelID),
+		"before":     before,
+		"limit":      limit,
+	})
+}
+
+// RequestThread asks the server for the reply chain ending at msgID; the
+// reply arrives through the onThread callback, root message first.
+func (t *Transport) RequestThread(msgID uint64) error {
+	if msgID == 0 {
+		return fmt.Errorf("msg_id is required")
+	}
+	return t.writeCtrl(ControlMsg{Type: "get_thread", MsgID: msgID})
+}
+
+// RequestEditHistory asks the server for the earlier versions of an edited
+// message; the reply arrives through the onEditHistory callback, oldest
+// first. Only the sender and the server owner may fetch it.
+func (t *Transport) RequestEditHistory(msgID uint64) error {
+	if msgID == 0 {
+		return fmt.Errorf("msg_id is required")
+	}
+	return t.writeCtrl(ControlMsg{Type: "get_edit_history", MsgID: msgID})
+}
+
+// RequestAuditLog asks the server for its recent audit log; the reply
+// arrives through the onAuditLog callback, newest first. The server only
+// answers admins and the owner, and ignores everyone else.
+func (t *Transport) RequestAuditLog() error {
+	return t.writeCtrl(ControlMsg{Type: "get_audit_log"})
+}
+
 // RequestServerInfo asks the server to send its name and metadata.
 func (t *Transport) RequestServerInfo() error {
 	return t.writeJSON(map[string]any{"type": "get_server_info"})
 }
 
+// rehydrate requests the state the snapshot does not carry. It runs after
+// every snapshot, so a reconnected session ends up with the same channel
+// list and server name as a fresh one without relying on the UI to ask.
+func (t *Transport) rehydrate() {
+	if err := t.RequestChannels(); err != nil {
+		slog.Debug("rehydrate channels failed", "err", err)
+	}
+	if err := t.RequestServerInfo(); err != nil {
+		slog.Debug("rehydrate server info failed", "err", err)
+	}
+	if err := t.GetPermissions(); err != nil {
+		slog.Debug("rehydrate permissions failed", "err", err)
+	}
+}
+
 // EditMessage asks the server to update a message's text. Only the original
 // sender is allowed to edit; the server enforces the authorisation check.
 func (t *Transport) EditMessage(msgID uint64, message string) error {
-	if err := validateChat(message); err != nil {
+	if err := validateChat(message, t.MaxMessageLength()); err != nil {
 		return err
 	}
 	return t.writeCtrl(ControlMsg{Type: "edit_message", MsgID: msgID, Message: message})
@@ -633,6 +1322,16 @@ func (t *Transport) DeleteMessage(msgID uint64) error {
 	return t.writeCtrl(ControlMsg{Type: "delete_message", MsgID: msgID})
 }
 
+// PurgeMessages asks the server to delete the newest count messages in a
+// channel. Only moderators and above may; the server enforces the check.
+func (t *Transport) PurgeMessages(channelID int64, count int) error {
+	return t.writeJSON(map[string]any{
+		"type":       "purge_messages",
+		"channel_id": t.wireChannelID(channelID),
+		"count":      count,
+	})
+}
+
 // APIBaseURL returns the HTTP base URL for the server's REST API, or "" if not yet known.
 func (t *Transport) APIBaseURL() string {
 	t.mu.Lock()
@@ -640,26 +1339,76 @@ func (t *Transport) APIBaseURL() string {
 	return t.apiBaseURL
 }
 
-// SendFileChat sends a chat message with a file attachment.
-// The file metadata must come from a prior upload to the server's API.
-func (t *Transport) SendFileChat(channelID int64, fileID string, fileSize int64, fileName, message string) error {
-	return t.writeJSON(map[string]any{
-		"type":       "send_text",
-		"server_id":  t.backendServerID(),
-		"channel_id": t.wireChannelID(channelID),
-		"message":    message,
-		"file_id":    fileID,
-		"file_name":  fileName,
-		"file_size":  fileSize,
-	})
+// resolveSoundURL makes a server sound URL absolute: a path is taken to be
+// on the server's REST API at base.
+func resolveSoundURL(base, u string) string {
+	if strings.HasPrefix(u, "/") {
+		if base == "" {
+			return ""
+		}
+		return base + u
+	}
+	return u
 }
 
-// SendChannelChat sends a channel-scoped chat message.
-func (t *Transport) SendChannelChat(channelID int64, message string) error {
-	if err := validateChat(message); err != nil {
-		return err
-	}
-	return t.writeJSON(map[string]any{
+// defaultMaxUploadBytes is the upload limit assumed for servers that do not
+// advertise one.
+const defaultMaxUploadBytes = 10 * 1024 * 1024 // 10 MB
+
+// MaxUploadBytes returns the largest file the server accepts, falling back
+// to defaultMaxUploadBytes when the server has not advertised a limit.
+func (t *Transport) MaxUploadBytes() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.maxUploadBytes > 0 {
+		return t.maxUploadBytes
+	}
+	return defaultMaxUploadBytes
+}
+
+// defaultMaxMessageLength is the chat message limit assumed for servers that
+// do not advertise one, and before the snapshot arrives.
+const defaultMaxMessageLength = 500
+
+// MaxMessageLength returns the longest chat message the server accepts,
+// falling back to defaultMaxMessageLength when it has not advertised one.
+func (t *Transport) MaxMessageLength() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.maxMessageLen > 0 {
+		return t.maxMessageLen
+	}
+	return defaultMaxMessageLength
+}
+
+// AllowedEmoji returns the server's reaction allow-list, or nil when any
+// emoji may be used.
+func (t *Transport) AllowedEmoji() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.allowedEmoji...)
+}
+
+// SendFileChat sends a chat message with a file attachment.
+// The file metadata must come from a prior upload to the server's API.
+func (t *Transport) SendFileChat(channelID int64, fileID string, fileSize int64, fileName, message string) error {
+	return t.writeJSON(map[string]any{
+		"type":       "send_text",
+		"server_id":  t.backendServerID(),
+		"channel_id": t.wireChannelID(channelID),
+		"message":    message,
+		"file_id":    fileID,
+		"file_name":  fileName,
+		"file_size":  fileSize,
+	})
+}
+
+// SendChannelChat sends a channel-scoped chat message.
+func (t *Transport) SendChannelChat(channelID int64, message string) error {
+	if err := validateChat(message, t.MaxMessageLength()); err != nil {
+		return err
+	}
+	return t.writeJSON(map[string]any{
 		"type":       "send_text",
 		"server_id":  t.backendServerID(),
 		"channel_id": t.wireChannelID(channelID),
@@ -669,7 +1418,7 @@ func (t *Transport) SendChannelChat(channelID int64, message string) error {
 
 // SendChat sends a chat message to the server for fan-out to all participants.
 func (t *Transport) SendChat(message string) error {
-	if err := validateChat(message); err != nil {
+	if err := validateChat(message, t.MaxMessageLength()); err != nil {
 		return err
 	}
 	return t.writeJSON(map[string]any{
@@ -680,6 +1429,132 @@ func (t *Transport) SendChat(message string) error {
 	})
 }
 
+// SendDM sends a private message to one user. The server delivers it to the
+// recipient and echoes it back to us; nobody else sees it.
+func (t *Transport) SendDM(targetID uint16, message string) error {
+	if err := validateChat(message, t.MaxMessageLength()); err != nil {
+		return err
+	}
+	wire, ok := t.wireUserID(targetID)
+	if !ok {
+		return fmt.Errorf("unknown user %d", targetID)
+	}
+	return t.writeJSON(map[string]any{
+		"type":    "dm",
+		"user_id": wire,
+		"message": message,
+	})
+}
+
+// voiceActivityInterval bounds how often SendVoiceActivity reaches the
+// server. It only needs to be well under any sensible idle timeout.
+const voiceActivityInterval = 10 * time.Second
+
+// SetStatus asks the server to set our presence status: "online", "away"
+// or "dnd". The server rejects anything else.
+func (t *Transport) SetStatus(status string) error {
+	return t.writeJSON(map[string]any{
+		"type":   "set_status",
+		"status": status,
+	})
+}
+
+// SendVoiceActivity tells the server the local user is transmitting so it
+// does not move them out of voice as idle. Voice flows peer to peer, so the
+// server cannot observe it directly. Calls are throttled to one per
+// voiceActivityInterval; it is safe to call on every speaking frame.
+func (t *Transport) SendVoiceActivity() error {
+	now := time.Now().UnixMilli()
+	last := t.lastVoiceActivity.Load()
+	if now-last < voiceActivityInterval.Milliseconds() || !t.lastVoiceActivity.CompareAndSwap(last, now) {
+		return nil
+	}
+	return t.writeCtrl(ControlMsg{Type: "voice_activity"})
+}
+
+// speakingHangover is how long SendSpeaking waits for the next call before
+// reporting that we stopped speaking. The audio engine signals speaking at
+// most every 80 ms, so this bridges the gaps between words.
+const speakingHangover = 400 * time.Millisecond
+
+// SendSpeaking tells the server we are transmitting voice, so everyone in
+// our channel sees us speaking, including those who muted us locally. The
+// first call sends a start; the stop follows once calls have stopped for
+// speakingHangover. It is safe to call on every speaking frame.
+func (t *Transport) SendSpeaking() error {
+	t.speakingMu.Lock()
+	defer t.speakingMu.Unlock()
+	if t.speakingTimer != nil {
+		t.speakingTimer.Reset(speakingHangover)
+		return nil
+	}
+	t.speakingTimer = time.AfterFunc(speakingHangover, t.stopSpeaking)
+	speaking := true
+	t.sendPeerSignal(peerSignal{Type: "speaking", Speaking: &speaking})
+	return t.writeJSON(map[string]any{"type": "speaking", "speaking": true})
+}
+
+// stopSpeaking sends the stop for a start sent by SendSpeaking. A duplicate
+// stop is harmless; the server ignores it.
+func (t *Transport) stopSpeaking() {
+	t.speakingMu.Lock()
+	t.speakingTimer = nil
+	t.speakingMu.Unlock()
+	speaking := false
+	t.sendPeerSignal(peerSignal{Type: "speaking", Speaking: &speaking})
+	if err := t.writeJSON(map[string]any{"type": "speaking", "speaking": false}); err != nil {
+		slog.Debug("send speaking stop", "err", err)
+	}
+}
+
+// SendSoundboard asks the server to play a soundboard clip for everyone in
+// our voice channel. The server validates the clip and rate-limits triggers.
+func (t *Transport) SendSoundboard(clipID string) error {
+	if clipID == "" {
+		return fmt.Errorf("clip must not be empty")
+	}
+	return t.writeJSON(map[string]any{
+		"type":    "soundboard",
+		"clip_id": clipID,
+	})
+}
+
+// SetAnnouncement replaces the server announcement shown to everyone; empty
+// text clears it. Only admins and the owner may; the server enforces it.
+func (t *Transport) SetAnnouncement(text string) error {
+	return t.writeJSON(map[string]any{
+		"type":    "set_announcement",
+		"message": text,
+	})
+}
+
+// typingInterval is how often SendTyping sends for the same channel while
+// the user keeps typing. The server debounces on the same interval.
+const typingInterval = 3 * time.Second
+
+// SendTyping tells others on the server that we are typing in channelID.
+// It is safe to call on every keystroke: repeats for the same channel within
+// typingInterval are dropped.
+func (t *Transport) SendTyping(channelID int64) error {
+	now := time.Now()
+	t.mu.Lock()
+	if channelID == t.lastTypingChannel && now.Sub(t.lastTypingAt) < typingInterval {
+		t.mu.Unlock()
+		return nil
+	}
+	t.lastTypingChannel = channelID
+	t.lastTypingAt = now
+	username := t.username
+	t.mu.Unlock()
+	wire := t.wireChannelID(channelID)
+	t.sendPeerSignal(peerSignal{Type: "typing", ChannelID: wire, Username: username})
+	return t.writeJSON(map[string]any{
+		"type":       "typing",
+		"server_id":  t.backendServerID(),
+		"channel_id": wire,
+	})
+}
+
 // AddReaction adds an emoji reaction to a message.
 func (t *Transport) AddReaction(msgID uint64, emoji string) error {
 	if emoji == "" {
@@ -696,13 +1571,23 @@ func (t *Transport) RemoveReaction(msgID uint64, emoji string) error {
 	return t.writeCtrl(ControlMsg{Type: "remove_reaction", MsgID: msgID, Emoji: emoji})
 }
 
-// validateChat returns an error if the message is empty or too long.
-func validateChat(message string) error {
+// SendReadReceipt tells the server we have seen a message. The server counts
+// each reader once, so repeats are harmless.
+func (t *Transport) SendReadReceipt(msgID uint64) error {
+	if msgID == 0 {
+		return fmt.Errorf("message ID must not be zero")
+	}
+	return t.writeCtrl(ControlMsg{Type: "read_receipt", MsgID: msgID})
+}
+
+// validateChat returns an error if the message is empty or longer than
+// limit bytes.
+func validateChat(message string, limit int) error {
 	if message == "" {
 		return fmt.Errorf("message must not be empty")
 	}
-	if len(message) > 500 {
-		return fmt.Errorf("message must not exceed 500 characters")
+	if len(message) > limit {
+		return fmt.Errorf("message must not exceed %d characters", limit)
 	}
 	return nil
 }
@@ -712,13 +1597,20 @@ func (t *Transport) writeJSON(v any) error {
 	if err != nil {
 		return fmt.Errorf("marshal: %w", err)
 	}
+	frameType := websocket.TextMessage
+	if t.binaryCtrl.Load() {
+		if data, err = jsonToBinary(data); err != nil {
+			return fmt.Errorf("binary encode: %w", err)
+		}
+		frameType = websocket.BinaryMessage
+	}
 	t.ctrlMu.Lock()
 	defer t.ctrlMu.Unlock()
 	if t.ws == nil {
 		return fmt.Errorf("control websocket not connected")
 	}
 	_ = t.ws.SetWriteDeadline(time.Now().Add(5 * time.Second))
-	if err := t.ws.WriteMessage(websocket.TextMessage, data); err != nil {
+	if err := t.ws.WriteMessage(frameType, data); err != nil {
 		return fmt.Errorf("websocket write: %w", err)
 	}
 	return nil
@@ -737,6 +1629,36 @@ func (t *Transport) writeCtrlBestEffort(msg ControlMsg) {
 	}
 }
 
+// historyMessages converts persisted messages from the server, mapping
+// reaction user IDs to local IDs.
+func (t *Transport) historyMessages(in []backendHistoryMsg) []ChatHistoryMessage {
+	msgs := make([]ChatHistoryMessage, len(in))
+	for i, m := range in {
+		msgs[i] = ChatHistoryMessage{
+			MsgID:    m.MsgID,
+			Username: m.Username,
+			Message:  m.Message,
+			TS:       m.TS,
+			FileID:   m.FileID,
+			FileName: m.FileName,
+			FileSize: m.FileSize,
+			ReplyTo:  m.ReplyTo,
+		}
+		for _, rx := range m.Reactions {
+			localIDs := make([]uint16, len(rx.UserIDs))
+			for j, uid := range rx.UserIDs {
+				localIDs[j] = t.localUserID(uid)
+			}
+			msgs[i].Reactions = append(msgs[i].Reactions, ChatHistoryReaction{
+				Emoji:   rx.Emoji,
+				UserIDs: localIDs,
+				Count:   rx.Count,
+			})
+		}
+	}
+	return msgs
+}
+
 func (t *Transport) backendServerID() string {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -841,6 +1763,28 @@ func (t *Transport) localUserID(wire string) uint16 {
 	return candidate
 }
 
+// wireUserID returns the server's ID for a local user ID, if the user has
+// been seen on this connection.
+func (t *Transport) wireUserID(id uint16) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	wire, ok := t.wireIDByUser[id]
+	return wire, ok
+}
+
+// protocolVersion is the major websocket protocol version this client speaks.
+// It must match the server's; see versionMismatchReason.
+const protocolVersion = 1
+
+// versionMismatchReason builds the user-facing disconnect reason for a
+// server speaking a different protocol version.
+func versionMismatchReason(serverVersion int) string {
+	if serverVersion > protocolVersion {
+		return fmt.Sprintf("This server requires protocol version %d — please update bken", serverVersion)
+	}
+	return fmt.Sprintf("This server speaks an older protocol (version %d) — ask the host to update the server", serverVersion)
+}
+
 // connectTimeout is the maximum time allowed for the initial websocket dial + hello handshake.
 const connectTimeout = 10 * time.Second
 
@@ -883,19 +1827,37 @@ func (t *Transport) Connect(ctx context.Context, addr, username string) error {
 		return err
 	}
 
-	// Defensive cleanup in case a stale session exists.
+	// Defensive cleanup in case a stale session exists. This also stops
+	// any reconnect still in progress for it.
 	t.Disconnect()
+	t.muted.Clear()
+	t.blocked.Clear()
+	t.motdShown.Store(false)
+	t.mu.Lock()
+	t.lastMsgSeq = nil
+	t.mu.Unlock()
 
+	return t.dial(ctx, normalizedAddr, username)
+}
+
+// dial opens a new control session to normalizedAddr and sends hello. It is
+// shared by Connect and the reconnect loop, which keeps per-user settings
+// such as local mutes across the new session.
+func (t *Transport) dial(ctx context.Context, normalizedAddr, username string) error {
 	// Reset per-session state.
-	t.muted.Clear()
 	t.clearUserChannels()
 	t.resetPeerStats()
 
 	t.mu.Lock()
 	t.disconnectReason = ""
+	t.noReconnect = false
+	t.username = username
 	t.serverAddr = normalizedAddr
 	t.serverID = normalizedAddr
 	t.apiBaseURL = "http://" + normalizedAddr
+	t.sessionToken = ""
+	t.maxUploadBytes = 0
+	t.maxMessageLen = 0
 	t.myID = 0
 	t.myChannel.Store(0)
 	t.userIDByWire = make(map[string]uint16)
@@ -912,6 +1874,7 @@ func (t *Transport) Connect(ctx context.Context, addr, username string) error {
 	d := websocket.Dialer{HandshakeTimeout: connectTimeout}
 
 	var conn *websocket.Conn
+	var err error
 	for _, dialAddr := range dialAddrsForWebsocket(normalizedAddr) {
 		slog.Debug("dialing websocket", "addr", dialAddr)
 		conn, _, err = d.DialContext(dialCtx, "ws://"+dialAddr+"/ws", nil)
@@ -932,20 +1895,27 @@ func (t *Transport) Connect(ctx context.Context, addr, username string) error {
 
 	// Reset per-session metrics.
 	t.smoothedRTT.Store(0)
-	t.smoothedJitter.Store(0)
 	t.bytesSent.Store(0)
-	t.lostPackets.Store(0)
-	t.expectedPackets.Store(0)
+	t.binaryCtrl.Store(false)
+	t.whisperTarget.Store(0)
 	t.lastPongTime.Store(time.Now().UnixNano())
 	t.metricsMu.Lock()
 	t.lastMetricsTime = time.Now()
 	t.metricsMu.Unlock()
 
+	bi := currentBuildInfo()
 	if err := t.writeJSON(map[string]any{
-		"type":     "hello",
-		"username": username,
+		"type":             "hello",
+		"username":         username,
+		"protocol_version": protocolVersion,
+		"proto":            protoBinary,
+		"client_info": map[string]any{
+			"commit": bi.Commit,
+			"os":     bi.GOOS,
+			"arch":   bi.GOARCH,
+		},
 	}); err != nil {
-		t.Disconnect()
+		t.teardown()
 		return fmt.Errorf("send hello: %w", err)
 	}
 	slog.Debug("hello sent", "username", username)
@@ -953,18 +1923,27 @@ func (t *Transport) Connect(ctx context.Context, addr, username string) error {
 		"type":      "connect_server",
 		"server_id": t.backendServerID(),
 	}); err != nil {
-		t.Disconnect()
+		t.teardown()
 		return fmt.Errorf("connect server: %w", err)
 	}
 
-	go t.readControl(sessionCtx, conn)
+	go t.readControl(sessionCtx, conn, t.closeGen.Load())
 	go t.pingLoop(sessionCtx)
+	go t.pacer.run(sessionCtx)
 
 	return nil
 }
 
-// Disconnect closes the websocket and all peer connections.
+// Disconnect closes the websocket and all peer connections. A deliberate
+// disconnect is never followed by an automatic reconnect.
 func (t *Transport) Disconnect() {
+	t.closeGen.Add(1)
+	t.teardown()
+}
+
+// teardown closes the current session without marking it deliberate, so an
+// unexpected close can still be followed by a reconnect.
+func (t *Transport) teardown() {
 	slog.Debug("disconnecting")
 	t.ctrlMu.Lock()
 	ws := t.ws
@@ -1010,59 +1989,86 @@ func (t *Transport) clearUserChannels() {
 		t.userChannels.Delete(k)
 		return true
 	})
+	t.userMonitoring.Clear()
 }
 
 func (t *Transport) resetPeerStats() {
 	t.statsMu.Lock()
 	t.lastSeq = make(map[uint16]uint16)
 	t.hasSeq = make(map[uint16]bool)
+	t.seenSeqs = make(map[uint16]uint64)
 	t.lastSeen = make(map[uint16]time.Time)
 	t.lastArrival = make(map[uint16]time.Time)
 	t.lastSpeaking = make(map[uint16]time.Time)
+	t.peerStats = make(map[uint16]*peerStats)
 	t.pruneCounter = 0
 	t.statsMu.Unlock()
 }
 
-// SendAudio writes an Opus frame to every active WebRTC peer in the same voice channel.
+// SendAudio queues an Opus frame for every active WebRTC peer in the same
+// voice channel. Frames are written at the rate they play out rather than
+// as capture delivers them, so a burst from the capture goroutine does not
+// reach receivers as jitter. The error, if any, is from an earlier write.
 func (t *Transport) SendAudio(opusData []byte) error {
 	if len(opusData) == 0 {
 		return nil
 	}
+	if t.myChannel.Load() == 0 {
+		return nil
+	}
+	t.mu.Lock()
+	noPeers := len(t.peers) == 0
+	t.mu.Unlock()
+	if noPeers {
+		return nil
+	}
+	return t.pacer.push(append([]byte(nil), opusData...))
+}
+
+// writeAudio writes one queued Opus frame to every active WebRTC peer in
+// the same voice channel and returns the frame's duration.
+func (t *Transport) writeAudio(opusData []byte) (time.Duration, error) {
+	// The frame size is configurable, so take the duration from the packet;
+	// it sets how far the RTP timestamp advances.
+	duration := opusPacketDuration(opusData)
+	if duration == 0 {
+		duration = frameMs * time.Millisecond
+	}
 
 	myChannel := t.myChannel.Load()
 	if myChannel == 0 {
-		return nil
+		return duration, nil
 	}
+	onset, redundant := t.onsetFrame(time.Now(), duration)
 
 	t.mu.Lock()
-	if len(t.peers) == 0 {
-		t.mu.Unlock()
-		return nil
-	}
-	peers := make([]*peerState, 0, len(t.peers))
-	for _, p := range t.peers {
-		peers = append(peers, p)
+	var peers []*peerState
+	if whisper := uint16(t.whisperTarget.Load()); whisper != 0 {
+		// Whispering: only the target hears us. If they have left the
+		// channel nothing is sent rather than falling back to everyone.
+		if p, ok := t.peers[whisper]; ok {
+			peers = append(peers, p)
+		}
+	} else {
+		peers = make([]*peerState, 0, len(t.peers))
+		for _, p := range t.peers {
+			peers = append(peers, p)
+		}
 	}
 	t.mu.Unlock()
 
 	var firstErr error
 	for _, p := range peers {
-		if !t.peerInMyChannel(p.id, myChannel) {
+		if !t.peerInMyChannel(p.id, myChannel) && !t.monitors(p.id, myChannel) {
 			continue
 		}
-		sample := media.Sample{
-			Data:     append([]byte(nil), opusData...),
-			Duration: 20 * time.Millisecond,
+		n, err := p.writeFrame(append([]byte(nil), opusData...), duration, onset, redundant)
+		t.bytesSent.Add(uint64(n))
+		if err != nil && firstErr == nil {
+			firstErr = err
 		}
-		if err := p.track.WriteSample(sample); err != nil {
-			if firstErr == nil {
-				firstErr = err
-			}
-			continue
-		}
-		t.bytesSent.Add(uint64(len(opusData)))
 	}
-	return firstErr
+	return duration, firstErr
 }
 
 func (t *Transport) peerInMyChannel(peerID uint16, myChannel int64) bool {
@@ -1090,8 +2096,40 @@ func (t *Transport) MyID() uint16 {
 	return t.myID
 }
 
+// SetWhisperTarget sends our voice only to id until ClearWhisper is
+// called. The target must be in our voice channel; peers receive a whisper
+// like any other audio.
+func (t *Transport) SetWhisperTarget(id uint16) error {
+	myChannel := t.myChannel.Load()
+	if myChannel == 0 {
+		return fmt.Errorf("join a voice channel to whisper")
+	}
+	if id == 0 || id == t.MyID() {
+		return fmt.Errorf("choose someone else to whisper to")
+	}
+	if !t.peerInMyChannel(id, myChannel) {
+		return fmt.Errorf("user %d is not in your voice channel", id)
+	}
+	if prev := t.whisperTarget.Swap(uint32(id)); prev != uint32(id) {
+		slog.Info("whisper started", "target", id)
+	}
+	return nil
+}
+
+// ClearWhisper restores normal transmission to the whole channel.
+func (t *Transport) ClearWhisper() {
+	if prev := t.whisperTarget.Swap(0); prev != 0 {
+		slog.Info("whisper stopped", "target", prev)
+	}
+}
+
+// WhisperTarget returns the peer being whispered to, or 0.
+func (t *Transport) WhisperTarget() uint16 {
+	return uint16(t.whisperTarget.Load())
+}
+
 // StartReceiving stores the playback channel used by incoming WebRTC tracks.
-func (t *Transport) StartReceiving(ctx context.Context, playbackCh chan<- TaggedAudio) {
+func (t *Transport) StartReceiving(ctx context.Context, playbackCh chan TaggedAudio) {
 	slog.Debug("start receiving")
 	t.mu.Lock()
 	if t.ws == nil {
@@ -1153,11 +2191,7 @@ func (t *Transport) ensurePeer(remoteID uint16) (*peerState, bool) {
 	}
 
 	trackID := fmt.Sprintf("audio-%d-to-%d", myID, remoteID)
-	track, err := webrtc.NewTrackLocalStaticSample(
-		webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 1},
-		trackID,
-		"bken",
-	)
+	track, err := webrtc.NewTrackLocalStaticRTP(opusCapability(t.stereo.Load()), trackID, "bken")
 	if err != nil {
 		_ = pc.Close()
 		slog.Error("create local track", "remote_id", remoteID, "err", err)
@@ -1171,21 +2205,32 @@ func (t *Transport) ensurePeer(remoteID uint16) (*peerState, bool) {
 		return nil, false
 	}
 
-	// Drain RTCP so interceptors do not back up.
+	// Drain RTCP so interceptors do not back up, taking the round trip
+	// time to this peer from its reports on our audio.
 	go func() {
-		rtcpBuf := make([]byte, 1500)
 		for {
-			if _, _, err := sender.Read(rtcpBuf); err != nil {
+			pkts, _, err := sender.ReadRTCP()
+			if err != nil {
 				return
 			}
+			for _, pkt := range pkts {
+				switch p := pkt.(type) {
+				case *rtcp.ReceiverReport:
+					t.recordPeerRTT(remoteID, p.Reports, time.Now())
+				case *rtcp.SenderReport:
+					t.recordPeerRTT(remoteID, p.Reports, time.Now())
+				}
+			}
 		}
 	}()
 
 	peer := &peerState{
-		id:      remoteID,
-		pc:      pc,
-		track:   track,
-		trackID: trackID,
+		id:         remoteID,
+		pc:         pc,
+		sender:     sender,
+		track:      track,
+		packetizer: newOpusPacketizer(),
+		trackID:    trackID,
 	}
 
 	pc.OnICECandidate(func(c *webrtc.ICECandidate) {
@@ -1206,6 +2251,10 @@ func (t *Transport) ensurePeer(remoteID uint16) (*peerState, bool) {
 
 	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
 		switch state {
+		case webrtc.PeerConnectionStateConnected:
+			peer.peerConnected(true, time.Now())
+		case webrtc.PeerConnectionStateDisconnected:
+			peer.peerConnected(false, time.Now())
 		case webrtc.PeerConnectionStateFailed, webrtc.PeerConnectionStateClosed:
 			t.closePeer(remoteID)
 		}
@@ -1217,6 +2266,7 @@ func (t *Transport) ensurePeer(remoteID uint16) (*peerState, bool) {
 		}
 		go t.readRemoteTrack(remoteID, remoteTrack)
 	})
+	t.setupSignalChannels(peer)
 
 	t.mu.Lock()
 	if existing, ok := t.peers[remoteID]; ok {
@@ -1230,6 +2280,39 @@ func (t *Transport) ensurePeer(remoteID uint16) (*peerState, bool) {
 	return peer, true
 }
 
+// SetStereo switches local audio tracks between mono and stereo Opus. The
+// change alters the SDP, so every existing peer gets a new track and is
+// renegotiated with a fresh offer.
+func (t *Transport) SetStereo(enabled bool) {
+	if t.stereo.Swap(enabled) == enabled {
+		return
+	}
+	t.mu.Lock()
+	peers := make([]*peerState, 0, len(t.peers))
+	for _, p := range t.peers {
+		peers = append(peers, p)
+	}
+	t.mu.Unlock()
+
+	capability := opusCapability(enabled)
+	for _, p := range peers {
+		track, err := webrtc.NewTrackLocalStaticRTP(capability, p.trackID, "bken")
+		if err != nil {
+			slog.Error("create local track", "remote_id", p.id, "err", err)
+			continue
+		}
+		if err := p.sender.ReplaceTrack(track); err != nil {
+			slog.Error("replace local track", "remote_id", p.id, "err", err)
+			continue
+		}
+		p.mu.Lock()
+		p.track = track
+		p.mu.Unlock()
+		go t.createAndSendOffer(p.id)
+	}
+	slog.Info("stereo updated", "enabled", enabled, "peers", len(peers))
+}
+
 func (t *Transport) closePeer(remoteID uint16) {
 	var peer *peerState
 	t.mu.Lock()
@@ -1246,10 +2329,13 @@ func (t *Transport) closePeer(remoteID uint16) {
 	t.statsMu.Lock()
 	delete(t.lastSeq, remoteID)
 	delete(t.hasSeq, remoteID)
+	delete(t.seenSeqs, remoteID)
 	delete(t.lastSeen, remoteID)
 	delete(t.lastArrival, remoteID)
 	delete(t.lastSpeaking, remoteID)
+	delete(t.peerStats, remoteID)
 	t.statsMu.Unlock()
+	t.stalledPeers.Delete(remoteID)
 }
 
 func (t *Transport) createAndSendOffer(remoteID uint16) {
@@ -1374,15 +2460,25 @@ func (t *Transport) handleIncomingAudio(senderID uint16, seq uint16, payload []b
 		return
 	}
 
+	t.clearPeerStall(senderID)
+
 	now := time.Now()
 	shouldNotifySpeaking := false
 
-	const expectedGapMs = 20.0
+	// Packets arrive one frame apart, and senders choose their frame size.
+	expectedGapMs := float64(opusPacketDuration(payload)) / float64(time.Millisecond)
+	if expectedGapMs == 0 {
+		expectedGapMs = frameMs
+	}
 	const jitterAlpha = 1.0 / 16.0
 
 	t.statsMu.Lock()
 	t.lastSeen[senderID] = now
+	ps := t.peerStatsLocked(senderID)
 
+	// Frames a sender skips for DTX are never handed to its packetizer, so
+	// they leave no sequence gap and aren't counted as lost here. The long
+	// arrival gap they do leave is kept out of the jitter estimate below.
 	forwardProgress := false
 	if prev, has := t.lastSeq[senderID]; has && t.hasSeq[senderID] {
 		diff := int(seq) - int(prev)
@@ -1392,28 +2488,34 @@ func (t *Transport) handleIncomingAudio(senderID uint16, seq uint16, payload []b
 		if diff > 0 && diff < 1000 {
 			forwardProgress = true
 			t.lastSeq[senderID] = seq
-			t.expectedPackets.Add(uint64(diff))
+			t.seenSeqs[senderID] <<= diff
+			ps.expected += uint64(diff)
 			if diff > 1 {
-				t.lostPackets.Add(uint64(diff - 1))
+				ps.lost += uint64(diff - 1)
 			}
 		}
 	} else {
 		forwardProgress = true
 		t.lastSeq[senderID] = seq
 		t.hasSeq[senderID] = true
+		t.seenSeqs[senderID] = 0
+	}
+	// Onset frames may arrive twice; see SetOnsetRedundancy. A repeat
+	// was never counted as expected above, and must not be played.
+	if t.duplicateSeqLocked(senderID, seq) {
+		t.statsMu.Unlock()
+		return
 	}
 
 	if forwardProgress {
 		if prevArrival, ok := t.lastArrival[senderID]; ok {
 			gapMs := float64(now.Sub(prevArrival).Microseconds()) / 1000.0
-			if gapMs < 100.0 {
+			if gapMs < expectedGapMs+80 {
 				d := gapMs - expectedGapMs
 				if d < 0 {
 					d = -d
 				}
-				old := math.Float64frombits(t.smoothedJitter.Load())
-				next := old + jitterAlpha*(d-old)
-				t.smoothedJitter.Store(math.Float64bits(next))
+				ps.jitterMs += jitterAlpha * (d - ps.jitterMs)
 			}
 		}
 		t.lastArrival[senderID] = now
@@ -1432,8 +2534,10 @@ func (t *Transport) handleIncomingAudio(senderID uint16, seq uint16, payload []b
 				delete(t.lastSeen, id)
 				delete(t.lastSeq, id)
 				delete(t.hasSeq, id)
+				delete(t.seenSeqs, id)
 				delete(t.lastArrival, id)
 				delete(t.lastSpeaking, id)
+				delete(t.peerStats, id)
 			}
 		}
 	}
@@ -1457,11 +2561,72 @@ func (t *Transport) handleIncomingAudio(senderID uint16, seq uint16, payload []b
 		return
 	}
 
-	select {
-	case playbackCh <- TaggedAudio{SenderID: senderID, Seq: seq, OpusData: frame}:
-	default:
-		t.playbackDropped.Add(1)
+	t.deliverPlayback(playbackCh, TaggedAudio{SenderID: senderID, Seq: seq, OpusData: frame})
+}
+
+// peerStatsLocked returns the stats entry for peerID, creating it if
+// needed. Caller must hold t.statsMu.
+func (t *Transport) peerStatsLocked(peerID uint16) *peerStats {
+	ps, ok := t.peerStats[peerID]
+	if !ok {
+		ps = &peerStats{}
+		t.peerStats[peerID] = ps
 	}
+	return ps
+}
+
+// ntpEpochOffset is the number of seconds from the NTP epoch (1900) to the
+// Unix epoch.
+const ntpEpochOffset = 2208988800
+
+// ntpCompact returns the middle 32 bits of now as an NTP timestamp, the
+// 16.16 fixed-point form RTCP uses for LSR and DLSR.
+func ntpCompact(now time.Time) uint32 {
+	secs := uint64(now.Unix()) + ntpEpochOffset
+	frac := uint64(now.Nanosecond()) << 32 / uint64(time.Second)
+	return uint32((secs<<32 | frac) >> 16)
+}
+
+// recordPeerRTT folds the round trip times in remoteID's reception reports
+// into its smoothed RTT. Per RFC 3550 §6.4.1 the round trip is the time
+// since the sender report the peer last saw (LSR), less how long the peer
+// held it (DLSR); reports without an LSR carry no measurement.
+func (t *Transport) recordPeerRTT(remoteID uint16, reports []rtcp.ReceptionReport, now time.Time) {
+	t.mu.Lock()
+	_, ok := t.peers[remoteID]
+	t.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	arrival := ntpCompact(now)
+	for _, r := range reports {
+		if r.LastSenderReport == 0 {
+			continue
+		}
+		rtt := arrival - r.LastSenderReport - r.Delay
+		if rtt >= 1<<31 {
+			continue // negative: clock skew or a stale report
+		}
+		sample := float64(rtt) * 1000 / 65536
+
+		t.statsMu.Lock()
+		ps := t.peerStatsLocked(remoteID)
+		if ps.rttMs == 0 {
+			ps.rttMs = sample
+		} else {
+			ps.rttMs = 0.125*sample + 0.875*ps.rttMs
+		}
+		t.statsMu.Unlock()
+	}
+}
+
+// lossRatio returns lost as a fraction of expected, capped at 1.
+func lossRatio(lost, expected uint64) float64 {
+	if expected == 0 {
+		return 0
+	}
+	return min(float64(lost)/float64(expected), 1)
 }
 
 func (t *Transport) canHear(peerID uint16) bool {
@@ -1480,7 +2645,7 @@ func (t *Transport) canHear(peerID uint16) bool {
 	if peerChannel == 0 {
 		return false
 	}
-	return peerChannel == myChannel
+	return peerChannel == myChannel || t.monitors(t.MyID(), peerChannel)
 }
 
 func (t *Transport) ensurePeersFromUserList(users []UserInfo) {
@@ -1514,18 +2679,31 @@ func (t *Transport) GetMetrics() Metrics {
 	bytes := t.bytesSent.Swap(0)
 	bitrate := float64(bytes*8) / elapsed / 1000 // kbps
 
-	lost := t.lostPackets.Swap(0)
-	expected := t.expectedPackets.Swap(0)
-	var loss float64
-	if expected > 0 {
-		loss = float64(lost) / float64(expected)
-		if loss > 1 {
-			loss = 1
+	// Loss is summed over peers and jitter averaged over those we have
+	// heard from; each peer's loss for the interval is kept for
+	// GetPeerMetrics.
+	var lost, expected uint64
+	var jitterSum float64
+	var heard int
+	t.statsMu.Lock()
+	for id, ps := range t.peerStats {
+		lost += ps.lost
+		expected += ps.expected
+		ps.loss = lossRatio(ps.lost, ps.expected)
+		ps.lost, ps.expected = 0, 0
+		if _, ok := t.lastArrival[id]; ok {
+			jitterSum += ps.jitterMs
+			heard++
 		}
 	}
+	t.statsMu.Unlock()
+	loss := lossRatio(lost, expected)
+	var jitterMs float64
+	if heard > 0 {
+		jitterMs = jitterSum / float64(heard)
+	}
 
 	rtt := math.Float64frombits(t.smoothedRTT.Load())
-	jitterMs := math.Float64frombits(t.smoothedJitter.Load())
 	playbackDrops := t.playbackDropped.Swap(0)
 
 	return Metrics{
@@ -1533,11 +2711,25 @@ func (t *Transport) GetMetrics() Metrics {
 		PacketLoss:      loss,
 		JitterMs:        jitterMs,
 		BitrateKbps:     bitrate,
+		CaptureDropped:  t.pacer.dropped.Swap(0),
 		PlaybackDropped: playbackDrops,
 		QualityLevel:    qualityLevel(loss, rtt, jitterMs, 0),
 	}
 }
 
+// GetPeerMetrics returns receive-side quality per remote peer, for telling
+// one bad connection apart from a bad network. Unlike GetMetrics it resets
+// nothing; PacketLoss covers the interval up to the last GetMetrics call.
+func (t *Transport) GetPeerMetrics() map[uint16]PeerMetrics {
+	t.statsMu.Lock()
+	defer t.statsMu.Unlock()
+	out := make(map[uint16]PeerMetrics, len(t.peerStats))
+	for id, ps := range t.peerStats {
+		out[id] = PeerMetrics{UserID: id, RTTMs: ps.rttMs, PacketLoss: ps.loss, JitterMs: ps.jitterMs}
+	}
+	return out
+}
+
 // pongTimeout is the maximum time allowed between pongs before the connection
 // is considered dead and the client disconnects. 3 missed pings at 2 s each.
 const pongTimeout = 6 * time.Second
@@ -1555,6 +2747,7 @@ func (t *Transport) pingLoop(ctx context.Context) {
 			ts := time.Now().UnixMilli()
 			t.lastPingTs.Store(ts)
 			t.writeCtrlBestEffort(ControlMsg{Type: "ping", Ts: ts})
+			t.checkPeerStalls(time.Now())
 
 			lastPong := t.lastPongTime.Load()
 			if lastPong > 0 && time.Since(time.Unix(0, lastPong)) > pongTimeout {
@@ -1562,7 +2755,7 @@ func (t *Transport) pingLoop(ctx context.Context) {
 				t.mu.Lock()
 				t.disconnectReason = "Server unreachable (ping timeout)"
 				t.mu.Unlock()
-				t.Disconnect()
+				t.teardown()
 				return
 			}
 		}
@@ -1570,15 +2763,21 @@ func (t *Transport) pingLoop(ctx context.Context) {
 }
 
 // readControl reads JSON control messages from the server websocket.
-func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
+func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn, gen uint64) {
 	_ = ctx
 	slog.Debug("read control loop started")
 
 	for {
-		_, data, err := conn.ReadMessage()
+		frameType, data, err := conn.ReadMessage()
 		if err != nil {
 			break
 		}
+		if frameType == websocket.BinaryMessage {
+			if data, err = binaryToJSON(data); err != nil {
+				slog.Error("invalid binary control message", "err", err)
+				continue
+			}
+		}
 
 		t.cbMu.RLock()
 		onUserList := t.onUserList
@@ -1586,10 +2785,13 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 		onUserLeft := t.onUserLeft
 		onChat := t.onChatMessage
 		onChannelChat := t.onChannelChatMessage
+		onDM := t.onDM
 		onServerInfo := t.onServerInfo
+		onServerError := t.onServerError
 		onKicked := t.onKicked
 		onOwnerChanged := t.onOwnerChanged
 		onChannelList := t.onChannelList
+		onCategoryList := t.onCategoryList
 		onUserChannel := t.onUserChannel
 		onLinkPreview := t.onLinkPreview
 		onUserRenamed := t.onUserRenamed
@@ -1598,12 +2800,34 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 		onVideoState := t.onVideoState
 		onReactionAdded := t.onReactionAdded
 		onReactionRemoved := t.onReactionRemoved
+		onReactionsUpdated := t.onReactionsUpdated
+		onPermissions := t.onPermissions
 		onUserTyping := t.onUserTyping
 		onMessagePinned := t.onMessagePinned
 		onMessageUnpinned := t.onMessageUnpinned
+		onPinnedList := t.onPinnedList
 		onVideoLayers := t.onVideoLayers
 		onMessageHistory := t.onMessageHistory
+		onThread := t.onThread
+		onEditHistory := t.onEditHistory
+		onAuditLog := t.onAuditLog
+		onAuditEntry := t.onAuditEntry
+		onBanList := t.onBanList
 		onUserVoiceFlags := t.onUserVoiceFlags
+		onRecordingStarted := t.onRecordingStarted
+		onRecordingStopped := t.onRecordingStopped
+		onSoundboard := t.onSoundboard
+		onAnnouncement := t.onAnnouncement
+		onMention := t.onMention
+		onMessageRead := t.onMessageRead
+		onUserMuted := t.onUserMuted
+		onUserStatus := t.onUserStatus
+		onSpeaking := t.onSpeaking
+		onMOTD := t.onMOTD
+		onJoinSounds := t.onJoinSounds
+		onEmojiList := t.onEmojiList
+		onServerShutdown := t.onServerShutdown
+		onStopRecording := t.onStopRecording
 		t.cbMu.RUnlock()
 
 		var header struct {
@@ -1622,10 +2846,27 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 				continue
 			}
 
-			slog.Debug("snapshot received", "self_id", msg.SelfID, "users", len(msg.Users))
+			slog.Debug("snapshot received", "self_id", msg.SelfID, "users", len(msg.Users), "protocol_version", msg.ProtocolVersion)
+			if msg.ProtocolVersion != 0 && msg.ProtocolVersion != protocolVersion {
+				slog.Warn("protocol version mismatch", "server", msg.ProtocolVersion, "client", protocolVersion)
+				t.mu.Lock()
+				t.disconnectReason = versionMismatchReason(msg.ProtocolVersion)
+				t.noReconnect = true
+				t.mu.Unlock()
+				_ = conn.Close()
+				continue
+			}
+			if msg.Proto == protoBinary {
+				t.binaryCtrl.Store(true)
+			}
 			selfID := t.localUserID(msg.SelfID)
 			t.mu.Lock()
 			t.myID = selfID
+			t.maxUploadBytes = msg.MaxUploadBytes
+			t.maxMessageLen = msg.MaxMessageLength
+			t.sessionToken = msg.SessionToken
+			t.allowedEmoji = msg.AllowedEmoji
+			t.iceServers = msg.ICEServers
 			t.mu.Unlock()
 
 			users := make([]UserInfo, 0, len(msg.Users))
@@ -1637,15 +2878,29 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 					channelID = t.localChannelID(u.Voice.ChannelID)
 				}
 				t.userChannels.Store(id, channelID)
+				t.setMonitoring(id, u.Voice)
 				if id == selfID {
 					t.myChannel.Store(channelID)
 				}
-				users = append(users, UserInfo{ID: id, Username: u.Username, ChannelID: channelID})
+				users = append(users, UserInfo{ID: id, Username: u.Username, ChannelID: channelID, Status: u.Status})
 			}
 
 			if onUserList != nil {
 				onUserList(users)
 			}
+			if msg.OwnerID != "" && onOwnerChanged != nil {
+				onOwnerChanged(t.localUserID(msg.OwnerID))
+			}
+			if msg.MOTD != "" && onMOTD != nil && t.motdShown.CompareAndSwap(false, true) {
+				onMOTD(msg.MOTD)
+			}
+			if onJoinSounds != nil {
+				base := t.APIBaseURL()
+				onJoinSounds(resolveSoundURL(base, msg.JoinSoundURL), resolveSoundURL(base, msg.LeaveSoundURL))
+			}
+			if onEmojiList != nil {
+				onEmojiList(append([]string(nil), msg.AllowedEmoji...))
+			}
 			if onUserVoiceFlags != nil {
 				for _, u := range msg.Users {
 					if u.Voice != nil {
@@ -1654,6 +2909,7 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 					}
 				}
 			}
+			t.rehydrate()
 		case "user_joined":
 			var msg backendUserMsg
 			if err := json.Unmarshal(data, &msg); err != nil {
@@ -1669,12 +2925,16 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 				channelID = t.localChannelID(msg.User.Voice.ChannelID)
 			}
 			t.userChannels.Store(id, channelID)
+			t.setMonitoring(id, msg.User.Voice)
 			if onUserJoined != nil {
 				onUserJoined(id, msg.User.Username)
 			}
 			if onUserChannel != nil {
 				onUserChannel(id, channelID)
 			}
+			if msg.User.Status != "" && onUserStatus != nil {
+				onUserStatus(id, msg.User.Status)
+			}
 		case "user_left":
 			var msg backendUserMsg
 			if err := json.Unmarshal(data, &msg); err != nil {
@@ -1686,6 +2946,7 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 			}
 			id := t.localUserID(msg.User.ID)
 			t.userChannels.Delete(id)
+			t.userMonitoring.Delete(id)
 			t.closePeer(id)
 			if onUserLeft != nil {
 				onUserLeft(id)
@@ -1705,6 +2966,7 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 				channelID = t.localChannelID(msg.User.Voice.ChannelID)
 			}
 			t.userChannels.Store(id, channelID)
+			t.setMonitoring(id, msg.User.Voice)
 			if id == t.MyID() {
 				t.myChannel.Store(channelID)
 			}
@@ -1728,13 +2990,108 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 			if msg.Ts == 0 {
 				msg.Ts = time.Now().UnixMilli()
 			}
+			t.noteSeq(msg.ChannelID, msg.MsgID)
+			if t.IsBlocked(id) {
+				continue
+			}
 			msgID := uint64(msg.MsgID)
+			var mentions []uint16
+			for _, wire := range msg.Mentions {
+				if m := t.localUserID(wire); m != 0 {
+					mentions = append(mentions, m)
+				}
+			}
 			if channelID != 0 {
 				if onChannelChat != nil {
-					onChannelChat(msgID, id, channelID, msg.User.Username, msg.Message, msg.Ts, msg.FileID, msg.FileName, msg.FileSize, nil)
+					onChannelChat(msgID, id, channelID, msg.User.Username, msg.Message, msg.Ts, msg.FileID, msg.FileName, msg.FileSize, mentions)
 				}
 			} else if onChat != nil {
-				onChat(msgID, id, msg.User.Username, msg.Message, msg.Ts, msg.FileID, msg.FileName, msg.FileSize, nil)
+				onChat(msgID, id, msg.User.Username, msg.Message, msg.Ts, msg.FileID, msg.FileName, msg.FileSize, mentions)
+			}
+		case "mention":
+			var msg backendUserMsg
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid mention message", "err", err)
+				continue
+			}
+			if msg.User == nil {
+				continue
+			}
+			id := t.localUserID(msg.User.ID)
+			if onMention != nil && !t.IsBlocked(id) {
+				onMention(uint64(msg.MsgID), t.localChannelID(msg.ChannelID), id, msg.User.Username)
+			}
+		case "dm":
+			var msg backendUserMsg
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid dm message", "err", err)
+				continue
+			}
+			if msg.User == nil {
+				continue
+			}
+			if msg.Ts == 0 {
+				msg.Ts = time.Now().UnixMilli()
+			}
+			id := t.localUserID(msg.User.ID)
+			if onDM != nil && !t.IsBlocked(id) {
+				onDM(id, t.localUserID(msg.UserID), msg.User.Username, msg.Message, msg.Ts)
+			}
+		case "user_typing":
+			var msg backendUserMsg
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid user_typing message", "err", err)
+				continue
+			}
+			if msg.User == nil {
+				continue
+			}
+			id := t.localUserID(msg.User.ID)
+			if t.peerSignalsOpen(id) {
+				continue // already delivered over the peer's data channel
+			}
+			if onUserTyping != nil && !t.IsBlocked(id) {
+				onUserTyping(id, msg.User.Username, t.localChannelID(msg.ChannelID))
+			}
+		case "soundboard":
+			var msg struct {
+				UserID string `json:"user_id"`
+				ClipID string `json:"clip_id"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid soundboard message", "err", err)
+				continue
+			}
+			id := t.localUserID(msg.UserID)
+			if onSoundboard != nil && !t.IsBlocked(id) {
+				onSoundboard(id, msg.ClipID)
+			}
+		case "ice_update":
+			var msg struct {
+				ChannelID  string          `json:"channel_id"`
+				ICEServers []ICEServerInfo `json:"ice_servers"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid ice_update message", "err", err)
+				continue
+			}
+			// Existing peers keep their configuration; only connections made
+			// from now on use the new list.
+			t.mu.Lock()
+			t.iceServers = msg.ICEServers
+			t.mu.Unlock()
+			slog.Debug("ice servers updated", "channel_id", msg.ChannelID, "count", len(msg.ICEServers))
+		case "announcement":
+			var msg struct {
+				Message  string `json:"message"`
+				PostedBy string `json:"posted_by"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid announcement message", "err", err)
+				continue
+			}
+			if onAnnouncement != nil {
+				onAnnouncement(msg.Message, msg.PostedBy)
 			}
 		case "reaction_added":
 			var msg struct {
@@ -1747,7 +3104,7 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 				continue
 			}
 			id := t.localUserID(msg.UserID)
-			if onReactionAdded != nil {
+			if onReactionAdded != nil && !t.IsBlocked(id) {
 				onReactionAdded(uint64(msg.MsgID), msg.Emoji, id)
 			}
 		case "reaction_removed":
@@ -1761,69 +3118,289 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 				continue
 			}
 			id := t.localUserID(msg.UserID)
-			if onReactionRemoved != nil {
+			if onReactionRemoved != nil && !t.IsBlocked(id) {
 				onReactionRemoved(uint64(msg.MsgID), msg.Emoji, id)
 			}
+		case "reaction_update":
+			var msg struct {
+				MsgID     int64 `json:"msg_id"`
+				Reactions []struct {
+					Emoji   string   `json:"emoji"`
+					UserIDs []string `json:"user_ids"`
+					Count   int      `json:"count"`
+				} `json:"reactions"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid reaction_update message", "err", err)
+				continue
+			}
+			reactions := make([]ChatHistoryReaction, len(msg.Reactions))
+			for i, rx := range msg.Reactions {
+				localIDs := make([]uint16, len(rx.UserIDs))
+				for j, uid := range rx.UserIDs {
+					localIDs[j] = t.localUserID(uid)
+				}
+				reactions[i] = ChatHistoryReaction{Emoji: rx.Emoji, UserIDs: localIDs, Count: rx.Count}
+			}
+			if onReactionsUpdated != nil {
+				onReactionsUpdated(uint64(msg.MsgID), reactions)
+			}
+		case "message_read":
+			var msg struct {
+				MsgID   int64    `json:"msg_id"`
+				Readers []string `json:"readers"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid message_read message", "err", err)
+				continue
+			}
+			readers := make([]uint16, len(msg.Readers))
+			for i, uid := range msg.Readers {
+				readers[i] = t.localUserID(uid)
+			}
+			if onMessageRead != nil {
+				onMessageRead(uint64(msg.MsgID), readers)
+			}
+		case "user_status":
+			var msg backendUserMsg
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid user_status message", "err", err)
+				continue
+			}
+			if msg.User != nil && onUserStatus != nil {
+				onUserStatus(t.localUserID(msg.User.ID), msg.User.Status)
+			}
+		case "user_renamed":
+			var msg backendUserMsg
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid user_renamed message", "err", err)
+				continue
+			}
+			if msg.User != nil && onUserRenamed != nil {
+				onUserRenamed(t.localUserID(msg.User.ID), msg.User.Username)
+			}
+		case "speaking":
+			var msg struct {
+				UserID   string `json:"user_id"`
+				Speaking bool   `json:"speaking"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid speaking message", "err", err)
+				continue
+			}
+			id := t.localUserID(msg.UserID)
+			if t.peerSignalsOpen(id) {
+				continue // already delivered over the peer's data channel
+			}
+			if msg.Speaking {
+				t.notePeerSpeaking(id)
+			}
+			if onSpeaking != nil {
+				onSpeaking(id, msg.Speaking)
+			}
+		case "user_muted":
+			var msg struct {
+				UserID string `json:"user_id"`
+				Muted  bool   `json:"muted"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid user_muted message", "err", err)
+				continue
+			}
+			if onUserMuted != nil {
+				onUserMuted(t.localUserID(msg.UserID), msg.Muted)
+			}
 		case "message_history":
 			var msg struct {
-				ChannelID string `json:"channel_id"`
-				Messages  []struct {
-					MsgID     int64  `json:"msg_id"`
-					Username  string `json:"username"`
-					Message   string `json:"message"`
-					TS        int64  `json:"ts"`
-					FileID    string `json:"file_id"`
-					FileName  string `json:"file_name"`
-					FileSize  int64  `json:"file_size"`
-					Reactions []struct {
-						Emoji   string   `json:"emoji"`
-						UserIDs []string `json:"user_ids"`
-						Count   int      `json:"count"`
-					} `json:"reactions"`
-				} `json:"messages"`
+				ChannelID string              `json:"channel_id"`
+				Messages  []backendHistoryMsg `json:"messages"`
+				Before    uint64              `json:"before,omitempty"`
 			}
 			if err := json.Unmarshal(data, &msg); err != nil {
 				slog.Error("invalid message_history message", "err", err)
 				continue
 			}
+			if msg.Before > 0 {
+				// Pages from get_messages_before are newest first.
+				slices.Reverse(msg.Messages)
+			}
+			for _, m := range msg.Messages {
+				t.noteSeq(msg.ChannelID, m.MsgID)
+			}
 			channelID := t.localChannelID(msg.ChannelID)
-			msgs := make([]ChatHistoryMessage, len(msg.Messages))
-			for i, m := range msg.Messages {
-				msgs[i] = ChatHistoryMessage{
-					MsgID:    m.MsgID,
-					Username: m.Username,
-					Message:  m.Message,
-					TS:       m.TS,
-					FileID:   m.FileID,
-					FileName: m.FileName,
-					FileSize: m.FileSize,
+			msgs := t.historyMessages(msg.Messages)
+			if onMessageHistory != nil {
+				onMessageHistory(channelID, msgs)
+			}
+		case "thread":
+			var msg struct {
+				MsgID    int64               `json:"msg_id"`
+				Messages []backendHistoryMsg `json:"messages"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid thread message", "err", err)
+				continue
+			}
+			if onThread != nil {
+				onThread(uint64(msg.MsgID), t.historyMessages(msg.Messages))
+			}
+		case "edit_history":
+			var msg struct {
+				MsgID int64         `json:"msg_id"`
+				Edits []MessageEdit `json:"edits"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid edit_history message", "err", err)
+				continue
+			}
+			if onEditHistory != nil {
+				onEditHistory(uint64(msg.MsgID), msg.Edits)
+			}
+		case "audit_log":
+			var msg struct {
+				Audit []AuditEntry `json:"audit"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid audit_log message", "err", err)
+				continue
+			}
+			if onAuditLog != nil {
+				onAuditLog(msg.Audit)
+			}
+		case "audit_entry":
+			var msg struct {
+				Audit []AuditEntry `json:"audit"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid audit_entry message", "err", err)
+				continue
+			}
+			if onAuditEntry != nil {
+				for _, e := range msg.Audit {
+					onAuditEntry(e)
 				}
-				for _, rx := range m.Reactions {
-					localIDs := make([]uint16, len(rx.UserIDs))
-					for j, uid := range rx.UserIDs {
-						localIDs[j] = t.localUserID(uid)
-					}
-					msgs[i].Reactions = append(msgs[i].Reactions, ChatHistoryReaction{
-						Emoji:   rx.Emoji,
-						UserIDs: localIDs,
-						Count:   rx.Count,
-					})
+			}
+		case "ban_list":
+			var msg struct {
+				Bans []BanInfo `json:"bans"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid ban_list message", "err", err)
+				continue
+			}
+			if onBanList != nil {
+				onBanList(msg.Bans)
+			}
+		case "message_pinned":
+			var msg struct {
+				MsgID     int64  `json:"msg_id"`
+				ChannelID string `json:"channel_id"`
+				UserID    string `json:"user_id"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid message_pinned message", "err", err)
+				continue
+			}
+			if onMessagePinned != nil {
+				onMessagePinned(uint64(msg.MsgID), t.localChannelID(msg.ChannelID), t.localUserID(msg.UserID))
+			}
+		case "message_unpinned":
+			var msg struct {
+				MsgID int64 `json:"msg_id"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid message_unpinned message", "err", err)
+				continue
+			}
+			if onMessageUnpinned != nil {
+				onMessageUnpinned(uint64(msg.MsgID))
+			}
+		case "pinned_list":
+			var msg struct {
+				ChannelID string `json:"channel_id"`
+				Pins      []struct {
+					backendHistoryMsg
+					PinnedBy string `json:"pinned_by"`
+					PinnedAt int64  `json:"pinned_at"`
+				} `json:"pins"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid pinned_list message", "err", err)
+				continue
+			}
+			history := make([]backendHistoryMsg, len(msg.Pins))
+			for i, p := range msg.Pins {
+				history[i] = p.backendHistoryMsg
+			}
+			pins := make([]PinnedMessage, len(msg.Pins))
+			for i, m := range t.historyMessages(history) {
+				pins[i] = PinnedMessage{
+					ChatHistoryMessage: m,
+					PinnedBy:           t.localUserID(msg.Pins[i].PinnedBy),
+					PinnedAt:           msg.Pins[i].PinnedAt,
 				}
 			}
-			if onMessageHistory != nil {
-				onMessageHistory(channelID, msgs)
+			if onPinnedList != nil {
+				onPinnedList(t.localChannelID(msg.ChannelID), pins)
 			}
 		case "channel_list":
 			var msg struct {
-				Channels []ChannelInfo `json:"channels"`
+				Channels   []ChannelInfo  `json:"channels"`
+				Categories []CategoryInfo `json:"categories"`
 			}
 			if err := json.Unmarshal(data, &msg); err != nil {
 				slog.Error("invalid channel_list message", "err", err)
 				continue
 			}
+			// Categories first, so a UI grouping by them has the names
+			// before the channels arrive.
+			if onCategoryList != nil {
+				onCategoryList(msg.Categories)
+			}
 			if onChannelList != nil {
 				onChannelList(msg.Channels)
 			}
+		case "owner_changed":
+			var msg struct {
+				OwnerID string `json:"owner_id"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid owner_changed message", "err", err)
+				continue
+			}
+			if onOwnerChanged != nil {
+				onOwnerChanged(t.localUserID(msg.OwnerID))
+			}
+		case "role_changed":
+			// Fetch the full assignments so onPermissions stays the one
+			// source of truth; only admins see co_owners.
+			if err := t.GetPermissions(); err != nil {
+				slog.Debug("refresh permissions after role_changed", "err", err)
+			}
+		case "permissions":
+			var msg struct {
+				OwnerID  string            `json:"owner_id"`
+				CoOwners []string          `json:"co_owners"`
+				Roles    map[string]string `json:"roles"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid permissions message", "err", err)
+				continue
+			}
+			perms := Permissions{
+				OwnerID:  t.localUserID(msg.OwnerID),
+				CoOwners: make([]uint16, len(msg.CoOwners)),
+				Roles:    make(map[uint16]string, len(msg.Roles)),
+			}
+			for i, id := range msg.CoOwners {
+				perms.CoOwners[i] = t.localUserID(id)
+			}
+			for id, role := range msg.Roles {
+				perms.Roles[t.localUserID(id)] = role
+			}
+			if onPermissions != nil {
+				onPermissions(perms)
+			}
 		case "server_info":
 			var msg struct {
 				ServerName string `json:"server_name"`
@@ -1835,6 +3412,40 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 			if msg.ServerName != "" && onServerInfo != nil {
 				onServerInfo(msg.ServerName)
 			}
+		case "recording_started", "recording_stopped":
+			var msg struct {
+				UserID          string `json:"user_id"`
+				ConsentRequired bool   `json:"consent_required"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid recording message", "type", header.Type, "err", err)
+				continue
+			}
+			id := t.localUserID(msg.UserID)
+			if header.Type == "recording_started" {
+				if onRecordingStarted != nil {
+					onRecordingStarted(id, msg.ConsentRequired)
+				}
+			} else if onRecordingStopped != nil {
+				onRecordingStopped(id)
+			}
+		case "server_shutdown":
+			var msg struct {
+				GraceMs int64 `json:"grace_ms"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid server_shutdown message", "err", err)
+				continue
+			}
+			slog.Info("server shutting down", "grace_ms", msg.GraceMs)
+			if onServerShutdown != nil {
+				onServerShutdown(msg.GraceMs)
+			}
+		case "stop_recording":
+			slog.Info("server refused local recording")
+			if onStopRecording != nil {
+				onStopRecording()
+			}
 		case "pong":
 			t.lastPongTime.Store(time.Now().UnixNano())
 			sent := t.lastPingTs.Load()
@@ -1849,10 +3460,29 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 				}
 				t.smoothedRTT.Store(math.Float64bits(next))
 			}
+		case "version_mismatch":
+			var msg struct {
+				ProtocolVersion int    `json:"protocol_version"`
+				Error           string `json:"error"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Error("invalid version_mismatch message", "err", err)
+				continue
+			}
+			slog.Warn("server rejected protocol version", "server", msg.ProtocolVersion, "client", protocolVersion, "error", msg.Error)
+			t.mu.Lock()
+			t.disconnectReason = versionMismatchReason(msg.ProtocolVersion)
+			t.noReconnect = true
+			t.mu.Unlock()
+			// The server closes after this message; the next read fails and
+			// the loop exits with the reason set above.
 		case "error":
 			var msg backendUserMsg
 			if err := json.Unmarshal(data, &msg); err == nil && msg.Error != "" {
-				slog.Warn("server error", "error", msg.Error)
+				slog.Warn("server error", "error", msg.Error, "retry_after_ms", msg.RetryAfterMs)
+				if onServerError != nil {
+					onServerError(msg.Error, msg.RetryAfterMs)
+				}
 			}
 		default:
 			var msg ControlMsg
@@ -1925,6 +3555,9 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 					onUserLeft(msg.ID)
 				}
 			case "chat":
+				if t.IsBlocked(msg.ID) {
+					continue
+				}
 				if msg.ChannelID != 0 {
 					if onChannelChat != nil {
 						onChannelChat(msg.MsgID, msg.ID, msg.ChannelID, msg.Username, msg.Message, msg.Ts, msg.FileID, msg.FileName, msg.FileSize, msg.Mentions)
@@ -1947,8 +3580,11 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 					onOwnerChanged(msg.OwnerID)
 				}
 			case "kicked":
+				t.mu.Lock()
+				t.noReconnect = true
+				t.mu.Unlock()
 				if onKicked != nil {
-					onKicked()
+					onKicked(msg.Message)
 				}
 			case "channel_list":
 				if onChannelList != nil {
@@ -1975,25 +3611,17 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 					onMessageDeleted(msg.MsgID)
 				}
 			case "reaction_added":
-				if onReactionAdded != nil {
+				if onReactionAdded != nil && !t.IsBlocked(msg.ID) {
 					onReactionAdded(msg.MsgID, msg.Emoji, msg.ID)
 				}
 			case "reaction_removed":
-				if onReactionRemoved != nil {
+				if onReactionRemoved != nil && !t.IsBlocked(msg.ID) {
 					onReactionRemoved(msg.MsgID, msg.Emoji, msg.ID)
 				}
 			case "user_typing":
-				if onUserTyping != nil {
+				if onUserTyping != nil && !t.IsBlocked(msg.ID) {
 					onUserTyping(msg.ID, msg.Username, msg.ChannelID)
 				}
-			case "message_pinned":
-				if onMessagePinned != nil {
-					onMessagePinned(msg.MsgID, msg.ChannelID, msg.ID)
-				}
-			case "message_unpinned":
-				if onMessageUnpinned != nil {
-					onMessageUnpinned(msg.MsgID)
-				}
 			case "video_state":
 				if onVideoState != nil {
 					active := msg.VideoActive != nil && *msg.VideoActive
@@ -2015,6 +3643,19 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 
 	slog.Debug("read control loop exiting")
 
+	// A deliberate Disconnect has already torn the session down, and a new
+	// one may have been dialled since; only clean up after a dropped link.
+	if t.closeGen.Load() == gen {
+		voiceChannel := t.wireChannelID(t.myChannel.Load())
+		t.mu.Lock()
+		playbackCh := t.playbackCh
+		t.mu.Unlock()
+		t.teardown()
+		if t.reconnect(gen, voiceChannel, playbackCh) {
+			return
+		}
+	}
+
 	t.mu.Lock()
 	reason := t.disconnectReason
 	t.disconnectReason = ""
@@ -2023,8 +3664,6 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 		reason = "Connection closed by server"
 	}
 
-	t.Disconnect()
-
 	t.cbMu.RLock()
 	onDisconnected := t.onDisconnected
 	t.cbMu.RUnlock()
@@ -2033,6 +3672,114 @@ func (t *Transport) readControl(ctx context.Context, conn *websocket.Conn) {
 	}
 }
 
+// maxReconnectDelay caps the exponential backoff between reconnect attempts.
+const maxReconnectDelay = 30 * time.Second
+
+// SetReconnect enables automatic reconnection after an unexpected close:
+// up to maxAttempts dials, the first after baseDelay and each later one
+// after twice the previous delay (capped at maxReconnectDelay).
+// maxAttempts 0 disables it.
+func (t *Transport) SetReconnect(maxAttempts int, baseDelay time.Duration) {
+	if maxAttempts < 0 {
+		maxAttempts = 0
+	}
+	t.mu.Lock()
+	t.reconnectMax = maxAttempts
+	t.reconnectBase = baseDelay
+	t.mu.Unlock()
+}
+
+// reconnectDelay returns the backoff before the given (1-based) attempt.
+func reconnectDelay(base time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < maxReconnectDelay; i++ {
+		d *= 2
+	}
+	return min(d, maxReconnectDelay)
+}
+
+// reconnect re-dials the server after the session started with generation
+// gen dropped, then rejoins the voice channel it was in (by wire ID, "0"
+// for none) and resumes playback. It reports whether
+// the drop has been dealt with: true once a new session is up or a
+// deliberate Disconnect has taken over, false when reconnecting is off or
+// every attempt failed.
+func (t *Transport) reconnect(gen uint64, voiceChannel string, playbackCh chan TaggedAudio) bool {
+	t.mu.Lock()
+	maxAttempts, base := t.reconnectMax, t.reconnectBase
+	addr, username := t.serverAddr, t.username
+	skip := t.noReconnect
+	t.mu.Unlock()
+	if maxAttempts == 0 || skip || addr == "" {
+		return false
+	}
+
+	t.cbMu.RLock()
+	onReconnecting := t.onReconnecting
+	t.cbMu.RUnlock()
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		time.Sleep(reconnectDelay(base, attempt))
+		if t.closeGen.Load() != gen {
+			slog.Debug("reconnect cancelled by disconnect")
+			return true
+		}
+		slog.Info("reconnecting", "addr", addr, "attempt", attempt)
+		if onReconnecting != nil {
+			onReconnecting(attempt)
+		}
+		if err := t.dial(context.Background(), addr, username); err != nil {
+			slog.Warn("reconnect failed", "addr", addr, "attempt", attempt, "err", err)
+			continue
+		}
+		// dial reset the ID maps; the wire ID maps back to the same local ID.
+		if channelID := t.localChannelID(voiceChannel); channelID != 0 {
+			if playbackCh != nil {
+				t.StartReceiving(context.Background(), playbackCh)
+			}
+			if err := t.JoinChannel(channelID); err != nil {
+				slog.Warn("rejoin voice after reconnect failed", "channel_id", channelID, "err", err)
+			}
+		}
+		if err := t.sendResume(); err != nil {
+			slog.Warn("resume after reconnect failed", "err", err)
+		}
+		slog.Info("reconnected", "addr", addr, "attempt", attempt)
+		return true
+	}
+	return false
+}
+
+// noteSeq records msgID as seen in wireChannel if it is the newest so far.
+func (t *Transport) noteSeq(wireChannel string, msgID int64) {
+	if wireChannel == "" || msgID <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if msgID > t.lastMsgSeq[wireChannel] {
+		if t.lastMsgSeq == nil {
+			t.lastMsgSeq = make(map[string]int64)
+		}
+		t.lastMsgSeq[wireChannel] = msgID
+	}
+}
+
+// sendResume tells the server the last msg_id seen in each channel so it
+// replays only the messages sent while the connection was down.
+func (t *Transport) sendResume() error {
+	t.mu.Lock()
+	seqs := maps.Clone(t.lastMsgSeq)
+	t.mu.Unlock()
+	if len(seqs) == 0 {
+		return nil
+	}
+	return t.writeJSON(map[string]any{
+		"type": "resume",
+		"seqs": seqs,
+	})
+}
+
 // TaggedAudio is a voice frame tagged with the sender's ID and sequence number.
 // Used to feed the playback mixer in the audio engine.
 type TaggedAudio struct {
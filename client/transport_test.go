@@ -1,9 +1,23 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pion/rtcp"
 )
 
 func TestDialAddrsForWebsocketLocalhost(t *testing.T) {
@@ -442,6 +456,20 @@ func TestPlaybackDroppedCounter(t *testing.T) {
 	}
 }
 
+func TestMetricsIncludesSendQueueDrops(t *testing.T) {
+	tr := NewTransport()
+	// Nothing drains the queue without a session, so a burst overflows it.
+	for i := 0; i < sendQueueFrames+3; i++ {
+		_ = tr.pacer.push([]byte{byte(i)})
+	}
+	if m := tr.GetMetrics(); m.CaptureDropped != 3 {
+		t.Errorf("CaptureDropped = %d, want 3", m.CaptureDropped)
+	}
+	if m := tr.GetMetrics(); m.CaptureDropped != 0 {
+		t.Errorf("CaptureDropped after reset = %d, want 0", m.CaptureDropped)
+	}
+}
+
 func TestSendAudioNilSessionPoolSafe(t *testing.T) {
 	// SendAudio with nil session should return nil without touching the pool
 	// in a way that causes panics.
@@ -527,6 +555,71 @@ func TestBuildICEServersFromServer(t *testing.T) {
 	}
 }
 
+func TestICEUpdateUsedForNextPeer(t *testing.T) {
+	// The server sends the joined channel's ICE servers in ice_update;
+	// peers created afterwards must use them instead of the snapshot list.
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{
+			"type":        "snapshot",
+			"self_id":     "u1",
+			"users":       []map[string]any{{"id": "u1", "username": "alice"}},
+			"ice_servers": []map[string]any{{"urls": []string{"stun:global.example.com:3478"}}},
+		})
+		for {
+			msg := readFakeMsg(conn)
+			if msg == nil {
+				return
+			}
+			if msg["type"] != "join_voice" {
+				continue
+			}
+			url := "stun:global.example.com:3478"
+			if msg["channel_id"] == "3" {
+				url = "turn:eu.example.com:3478"
+			}
+			_ = conn.WriteJSON(map[string]any{
+				"type":        "ice_update",
+				"channel_id":  msg["channel_id"],
+				"ice_servers": []map[string]any{{"urls": []string{url}}},
+			})
+		}
+	})
+
+	tr := NewTransport()
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	waitForICE := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			tr.mu.Lock()
+			servers := tr.buildICEServers()
+			tr.mu.Unlock()
+			if len(servers) == 1 && servers[0].URLs[0] == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("ice servers = %+v, want %s", servers, want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	waitForICE("stun:global.example.com:3478")
+	if err := tr.JoinChannel(3); err != nil {
+		t.Fatalf("join channel 3: %v", err)
+	}
+	waitForICE("turn:eu.example.com:3478")
+	if err := tr.JoinChannel(4); err != nil {
+		t.Fatalf("join channel 4: %v", err)
+	}
+	waitForICE("stun:global.example.com:3478")
+}
+
 // --- Per-user volume tests ---
 
 func TestUserVolumeDefault(t *testing.T) {
@@ -571,3 +664,2003 @@ func TestUserVolumeIsolated(t *testing.T) {
 		t.Errorf("unset user volume = %f, want 1.0", v)
 	}
 }
+
+// --- fake control server ---
+
+// fakeConn is the server side of a fake control connection.
+type fakeConn struct {
+	*websocket.Conn
+	errs chan<- error
+}
+
+// startFakeServer runs a websocket endpoint at /ws that hands each accepted
+// connection to serve, and returns the host:port to pass to Connect. When the
+// test ends it closes the connections, waits for serve to return and fails
+// the test with any read errors reported by readFakeMsg.
+func startFakeServer(t *testing.T, serve func(conn *fakeConn)) string {
+	t.Helper()
+	upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	errs := make(chan error, 16)
+	var (
+		mu    sync.Mutex
+		conns []*websocket.Conn
+		wg    sync.WaitGroup
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		wg.Add(1)
+		defer wg.Done()
+		mu.Lock()
+		conns = append(conns, conn)
+		mu.Unlock()
+		defer conn.Close()
+		serve(&fakeConn{Conn: conn, errs: errs})
+	}))
+	t.Cleanup(func() {
+		// Upgraded connections are hijacked, so srv.Close neither closes
+		// them nor waits for their handlers.
+		mu.Lock()
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+		mu.Unlock()
+		srv.Close()
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Error("fake server handler did not return")
+		}
+		close(errs)
+		for err := range errs {
+			t.Errorf("fake server read: %v", err)
+		}
+	})
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+// readFakeMsg reads one JSON message from the client side of a fake server.
+// It returns nil once the connection is closed; other failures, such as the
+// client never sending the message, are reported to the test.
+func readFakeMsg(conn *fakeConn) map[string]any {
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg map[string]any
+	if err := conn.ReadJSON(&msg); err != nil {
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) && !errors.Is(err, net.ErrClosed) {
+			select {
+			case conn.errs <- err:
+			default:
+			}
+		}
+		return nil
+	}
+	return msg
+}
+
+// --- protocol version tests ---
+
+func TestHelloCarriesProtocolVersion(t *testing.T) {
+	got := make(chan float64, 1)
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		hello := readFakeMsg(conn)
+		v, _ := hello["protocol_version"].(float64)
+		got <- v
+	})
+
+	tr := NewTransport()
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	select {
+	case v := <-got:
+		if int(v) != protocolVersion {
+			t.Errorf("hello protocol_version = %v, want %d", v, protocolVersion)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server never received hello")
+	}
+}
+
+func TestHelloCarriesClientInfo(t *testing.T) {
+	got := make(chan map[string]any, 1)
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		hello := readFakeMsg(conn)
+		info, _ := hello["client_info"].(map[string]any)
+		got <- info
+	})
+
+	tr := NewTransport()
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	select {
+	case info := <-got:
+		if info["os"] != runtime.GOOS || info["arch"] != runtime.GOARCH {
+			t.Errorf("client_info = %v, want os=%s arch=%s", info, runtime.GOOS, runtime.GOARCH)
+		}
+		if commit, _ := info["commit"].(string); commit == "" {
+			t.Errorf("client_info missing commit: %v", info)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server never received hello")
+	}
+}
+
+func TestBinaryProtoNegotiatedInSnapshot(t *testing.T) {
+	got := make(chan string, 1)
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		hello := readFakeMsg(conn)
+		if hello["proto"] != protoBinary {
+			t.Errorf("hello proto = %v, want %q", hello["proto"], protoBinary)
+		}
+		readFakeMsg(conn) // connect_server
+		js, _ := json.Marshal(map[string]any{"type": "snapshot", "self_id": "u1", "proto": protoBinary})
+		snap, err := jsonToBinary(js)
+		if err != nil {
+			t.Errorf("encode snapshot: %v", err)
+			return
+		}
+		_ = conn.WriteMessage(websocket.BinaryMessage, snap)
+		for {
+			frameType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if frameType != websocket.BinaryMessage {
+				continue
+			}
+			js, err := binaryToJSON(data)
+			if err != nil {
+				t.Errorf("decode: %v", err)
+				return
+			}
+			if strings.Contains(string(js), "get_channels") {
+				got <- string(js)
+				return
+			}
+		}
+	})
+
+	tr := NewTransport()
+	users := make(chan []UserInfo, 1)
+	tr.SetOnUserList(func(u []UserInfo) { users <- u })
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	select {
+	case <-users:
+	case <-time.After(2 * time.Second):
+		t.Fatal("binary snapshot was not handled")
+	}
+	if err := tr.RequestChannels(); err != nil {
+		t.Fatalf("request channels: %v", err)
+	}
+	select {
+	case js := <-got:
+		if js != `{"type":"get_channels"}` {
+			t.Errorf("binary frame decoded to %s", js)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server never received a binary frame")
+	}
+}
+
+func TestVersionMismatchSurfacesUpdateReason(t *testing.T) {
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{
+			"type":             "version_mismatch",
+			"protocol_version": protocolVersion + 1,
+			"error":            "server requires a newer protocol",
+		})
+	})
+
+	reasons := make(chan string, 1)
+	tr := NewTransport()
+	tr.SetOnDisconnected(func(reason string) { reasons <- reason })
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	select {
+	case reason := <-reasons:
+		if !strings.Contains(reason, "please update") {
+			t.Errorf("disconnect reason = %q, want an update prompt", reason)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("onDisconnected was not called")
+	}
+}
+
+func TestServerErrorCarriesRetryAfter(t *testing.T) {
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{
+			"type":           "error",
+			"error":          "switching channels too quickly; try again in 3s",
+			"retry_after_ms": 2500,
+		})
+	})
+
+	type serverErr struct {
+		msg        string
+		retryAfter int64
+	}
+	got := make(chan serverErr, 1)
+	tr := NewTransport()
+	tr.SetOnServerError(func(message string, retryAfterMs int64) {
+		got <- serverErr{message, retryAfterMs}
+	})
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	select {
+	case e := <-got:
+		if e.retryAfter != 2500 || !strings.Contains(e.msg, "try again") {
+			t.Errorf("server error = %+v", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("onServerError was not called")
+	}
+}
+
+func TestReactionUpdateMapsUserIDs(t *testing.T) {
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{
+			"type":   "reaction_update",
+			"msg_id": 42,
+			"reactions": []map[string]any{
+				{"emoji": "👍", "user_ids": []string{"u3", "u5"}, "count": 2},
+			},
+		})
+	})
+
+	type update struct {
+		msgID     uint64
+		reactions []ChatHistoryReaction
+	}
+	got := make(chan update, 1)
+	tr := NewTransport()
+	tr.SetOnReactionsUpdated(func(msgID uint64, reactions []ChatHistoryReaction) {
+		got <- update{msgID, reactions}
+	})
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	select {
+	case u := <-got:
+		if u.msgID != 42 || len(u.reactions) != 1 {
+			t.Fatalf("update = %+v", u)
+		}
+		rx := u.reactions[0]
+		if rx.Emoji != "👍" || rx.Count != 2 || len(rx.UserIDs) != 2 || rx.UserIDs[0] != 3 || rx.UserIDs[1] != 5 {
+			t.Errorf("reaction = %+v", rx)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("onReactionsUpdated was not called")
+	}
+}
+
+func TestPermissionsAndOwnerFromServer(t *testing.T) {
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{
+			"type":     "snapshot",
+			"self_id":  "u2",
+			"owner_id": "u1",
+			"users": []map[string]any{
+				{"id": "u1", "username": "alice"},
+				{"id": "u2", "username": "bob"},
+			},
+		})
+		for {
+			msg := readFakeMsg(conn)
+			if msg == nil {
+				return
+			}
+			if msg["type"] == "get_permissions" {
+				_ = conn.WriteJSON(map[string]any{
+					"type":     "permissions",
+					"owner_id": "u1",
+					"roles":    map[string]string{"u1": "OWNER", "u2": "MODERATOR"},
+				})
+				return
+			}
+		}
+	})
+
+	owners := make(chan uint16, 1)
+	perms := make(chan Permissions, 1)
+	tr := NewTransport()
+	tr.SetOnOwnerChanged(func(id uint16) { owners <- id })
+	tr.SetOnPermissions(func(p Permissions) { perms <- p })
+	if err := tr.Connect(context.Background(), addr, "bob"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	select {
+	case id := <-owners:
+		if id != 1 {
+			t.Errorf("snapshot owner = %d, want 1", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("snapshot owner_id was not reported")
+	}
+	// Rehydration after the snapshot asks for permissions.
+	select {
+	case p := <-perms:
+		if p.OwnerID != 1 || p.Roles[1] != "OWNER" || p.Roles[2] != "MODERATOR" || len(p.CoOwners) != 0 {
+			t.Errorf("permissions = %+v", p)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("onPermissions was not called")
+	}
+}
+
+func TestUserRenamedMapsWireID(t *testing.T) {
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{
+			"type":    "snapshot",
+			"self_id": "u1",
+			"users": []map[string]any{
+				{"id": "u1", "username": "alice"},
+				{"id": "u2", "username": "bob"},
+			},
+		})
+		_ = conn.WriteJSON(map[string]any{
+			"type": "user_renamed",
+			"user": map[string]any{"id": "u2", "username": "robert"},
+		})
+		for { // block until the client disconnects
+			if readFakeMsg(conn) == nil {
+				return
+			}
+		}
+	})
+
+	type rename struct {
+		id   uint16
+		name string
+	}
+	renames := make(chan rename, 1)
+	tr := NewTransport()
+	tr.SetOnUserRenamed(func(id uint16, name string) { renames <- rename{id, name} })
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	select {
+	case got := <-renames:
+		if got.id != 2 || got.name != "robert" {
+			t.Errorf("user_renamed = %+v, want id 2 renamed to robert", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("user_renamed was not reported")
+	}
+}
+
+func TestRoleChangedRefreshesPermissions(t *testing.T) {
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{
+			"type":     "snapshot",
+			"self_id":  "u2",
+			"owner_id": "u1",
+			"users": []map[string]any{
+				{"id": "u1", "username": "alice"},
+				{"id": "u2", "username": "bob"},
+			},
+		})
+		role := "USER"
+		for {
+			msg := readFakeMsg(conn)
+			if msg == nil {
+				return
+			}
+			if msg["type"] != "get_permissions" {
+				continue
+			}
+			_ = conn.WriteJSON(map[string]any{
+				"type":     "permissions",
+				"owner_id": "u1",
+				"roles":    map[string]string{"u1": "OWNER", "u2": role},
+			})
+			if role == "USER" {
+				role = "MODERATOR"
+				_ = conn.WriteJSON(map[string]any{"type": "role_changed", "user_id": "u2", "role": role})
+			}
+		}
+	})
+
+	perms := make(chan Permissions, 2)
+	tr := NewTransport()
+	tr.SetOnPermissions(func(p Permissions) { perms <- p })
+	if err := tr.Connect(context.Background(), addr, "bob"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	for _, want := range []string{"USER", "MODERATOR"} {
+		select {
+		case p := <-perms:
+			if p.Roles[2] != want {
+				t.Fatalf("bob's role = %q, want %q", p.Roles[2], want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no permissions with bob as %s", want)
+		}
+	}
+}
+
+func TestSendDMAndReceive(t *testing.T) {
+	sent := make(chan map[string]any, 1)
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{
+			"type":    "snapshot",
+			"self_id": "u1",
+			"users": []map[string]any{
+				{"id": "u1", "username": "alice"},
+				{"id": "u2", "username": "bob"},
+			},
+		})
+		for {
+			msg := readFakeMsg(conn)
+			if msg == nil {
+				return
+			}
+			if msg["type"] == "dm" {
+				sent <- msg
+				_ = conn.WriteJSON(map[string]any{
+					"type":    "dm",
+					"user":    map[string]any{"id": "u2", "username": "bob"},
+					"user_id": "u1",
+					"message": "hi alice",
+					"ts":      1234,
+				})
+				return
+			}
+		}
+	})
+
+	type dm struct {
+		sender, target uint16
+		username, msg  string
+		ts             int64
+	}
+	received := make(chan dm, 1)
+	users := make(chan []UserInfo, 1)
+	tr := NewTransport()
+	tr.SetOnUserList(func(u []UserInfo) { users <- u })
+	tr.SetOnDM(func(senderID, targetID uint16, username, message string, ts int64) {
+		received <- dm{senderID, targetID, username, message, ts}
+	})
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	// Local IDs come from the snapshot, so wait for it before addressing bob.
+	select {
+	case <-users:
+	case <-time.After(2 * time.Second):
+		t.Fatal("snapshot was not handled")
+	}
+	if err := tr.SendDM(99, "hello?"); err == nil {
+		t.Fatal("expected an error for a user never seen on this connection")
+	}
+	if err := tr.SendDM(2, "hi bob"); err != nil {
+		t.Fatalf("send dm: %v", err)
+	}
+	select {
+	case msg := <-sent:
+		if msg["user_id"] != "u2" || msg["message"] != "hi bob" {
+			t.Errorf("unexpected dm on the wire: %v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("dm was not sent")
+	}
+	select {
+	case got := <-received:
+		want := dm{2, 1, "bob", "hi alice", 1234}
+		if got != want {
+			t.Errorf("onDM got %+v, want %+v", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("onDM was not called")
+	}
+}
+
+func TestSetAnnouncementAndReceive(t *testing.T) {
+	sent := make(chan map[string]any, 1)
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{
+			"type":    "snapshot",
+			"self_id": "u1",
+			"users":   []map[string]any{{"id": "u1", "username": "alice"}},
+		})
+		for {
+			msg := readFakeMsg(conn)
+			if msg == nil {
+				return
+			}
+			if msg["type"] == "set_announcement" {
+				sent <- msg
+				_ = conn.WriteJSON(map[string]any{
+					"type":      "announcement",
+					"message":   msg["message"],
+					"posted_by": "alice",
+				})
+				return
+			}
+		}
+	})
+
+	type announcement struct{ text, postedBy string }
+	received := make(chan announcement, 1)
+	tr := NewTransport()
+	tr.SetOnAnnouncement(func(text, postedBy string) {
+		received <- announcement{text, postedBy}
+	})
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	if err := tr.SetAnnouncement("game night at 8"); err != nil {
+		t.Fatalf("set announcement: %v", err)
+	}
+	select {
+	case msg := <-sent:
+		if msg["message"] != "game night at 8" {
+			t.Errorf("unexpected set_announcement on the wire: %v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("set_announcement was not sent")
+	}
+	select {
+	case got := <-received:
+		if want := (announcement{"game night at 8", "alice"}); got != want {
+			t.Errorf("onAnnouncement got %+v, want %+v", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("onAnnouncement was not called")
+	}
+}
+
+func TestMentionCallbacks(t *testing.T) {
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{
+			"type":    "snapshot",
+			"self_id": "u1",
+			"users": []map[string]any{
+				{"id": "u1", "username": "alice"},
+				{"id": "u2", "username": "bob"},
+			},
+		})
+		bob := map[string]any{"id": "u2", "username": "bob"}
+		_ = conn.WriteJSON(map[string]any{
+			"type":       "text_message",
+			"channel_id": "3",
+			"message":    "@alice look",
+			"msg_id":     7,
+			"user":       bob,
+			"mentions":   []string{"u1"},
+		})
+		_ = conn.WriteJSON(map[string]any{
+			"type":       "mention",
+			"channel_id": "3",
+			"msg_id":     7,
+			"user":       bob,
+		})
+		for { // block until the client disconnects
+			if readFakeMsg(conn) == nil {
+				return
+			}
+		}
+	})
+
+	chatMentions := make(chan []uint16, 1)
+	type mention struct {
+		msgID     uint64
+		channelID int64
+		senderID  uint16
+		username  string
+	}
+	mentions := make(chan mention, 1)
+	tr := NewTransport()
+	tr.SetOnChannelChatMessage(func(_ uint64, _ uint16, _ int64, _, _ string, _ int64, _, _ string, _ int64, m []uint16) {
+		chatMentions <- m
+	})
+	tr.SetOnMention(func(msgID uint64, channelID int64, senderID uint16, username string) {
+		mentions <- mention{msgID, channelID, senderID, username}
+	})
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	select {
+	case got := <-chatMentions:
+		if !slices.Equal(got, []uint16{tr.MyID()}) {
+			t.Errorf("chat mentions = %v, want [%d]", got, tr.MyID())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("chat message was not delivered")
+	}
+	select {
+	case got := <-mentions:
+		if got.msgID != 7 || got.channelID != 3 || got.senderID == 0 || got.username != "bob" {
+			t.Errorf("unexpected mention: %+v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("onMention was not called")
+	}
+}
+
+func TestBlockedUserChatIsDropped(t *testing.T) {
+	ready := make(chan struct{})
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{
+			"type":    "snapshot",
+			"self_id": "u1",
+			"users": []map[string]any{
+				{"id": "u1", "username": "alice"},
+				{"id": "u2", "username": "bob"},
+				{"id": "u3", "username": "carol"},
+			},
+		})
+		<-ready
+		bob := map[string]any{"id": "u2", "username": "bob"}
+		carol := map[string]any{"id": "u3", "username": "carol"}
+		for _, msg := range []map[string]any{
+			{"type": "text_message", "channel_id": "3", "message": "hi", "msg_id": 7, "user": bob},
+			{"type": "dm", "user_id": "u1", "message": "psst", "user": bob},
+			{"type": "user_typing", "channel_id": "3", "user": bob},
+			{"type": "reaction_added", "msg_id": 7, "emoji": "👍", "user_id": "u2"},
+			{"type": "text_message", "channel_id": "3", "message": "hello", "msg_id": 8, "user": carol},
+		} {
+			_ = conn.WriteJSON(msg)
+		}
+		for { // block until the client disconnects
+			if readFakeMsg(conn) == nil {
+				return
+			}
+		}
+	})
+
+	events := make(chan string, 8)
+	tr := NewTransport()
+	tr.SetOnChannelChatMessage(func(_ uint64, _ uint16, _ int64, username, _ string, _ int64, _, _ string, _ int64, _ []uint16) {
+		events <- "chat from " + username
+	})
+	tr.SetOnDM(func(_, _ uint16, username, _ string, _ int64) {
+		events <- "dm from " + username
+	})
+	tr.SetOnUserTyping(func(_ uint16, username string, _ int64) {
+		events <- "typing from " + username
+	})
+	tr.SetOnReactionAdded(func(_ uint64, _ string, _ uint16) {
+		events <- "reaction"
+	})
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	bob := tr.localUserID("u2")
+	tr.BlockUser(bob)
+	if !tr.IsBlocked(bob) || !tr.IsUserMuted(bob) {
+		t.Fatal("blocking should also mute the user's voice")
+	}
+	close(ready)
+
+	select {
+	case got := <-events:
+		if got != "chat from carol" {
+			t.Fatalf("first event %q, want carol's chat; bob's events were not dropped", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("carol's chat was not delivered")
+	}
+
+	tr.UnblockUser(bob)
+	if tr.IsBlocked(bob) || tr.IsUserMuted(bob) {
+		t.Error("unblocking should unmute the user")
+	}
+}
+
+func TestReadReceiptAndMessageRead(t *testing.T) {
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{
+			"type":    "snapshot",
+			"self_id": "u1",
+			"users": []map[string]any{
+				{"id": "u1", "username": "alice"},
+				{"id": "u2", "username": "bob"},
+			},
+		})
+		for {
+			msg := readFakeMsg(conn)
+			if msg == nil {
+				return
+			}
+			if msg["type"] == "read_receipt" {
+				_ = conn.WriteJSON(map[string]any{
+					"type":    "message_read",
+					"msg_id":  msg["msg_id"],
+					"readers": []string{"u2", "u1"},
+				})
+			}
+		}
+	})
+
+	type read struct {
+		msgID   uint64
+		readers []uint16
+	}
+	received := make(chan read, 1)
+	tr := NewTransport()
+	tr.SetOnMessageRead(func(msgID uint64, readers []uint16) {
+		received <- read{msgID, readers}
+	})
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	if err := tr.SendReadReceipt(0); err == nil {
+		t.Error("expected an error for message ID 0")
+	}
+	if err := tr.SendReadReceipt(9); err != nil {
+		t.Fatalf("send read receipt: %v", err)
+	}
+	select {
+	case got := <-received:
+		if got.msgID != 9 || len(got.readers) != 2 || got.readers[1] != tr.MyID() || got.readers[0] == 0 {
+			t.Errorf("onMessageRead got %+v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("onMessageRead was not called")
+	}
+}
+
+func TestRequestThreadDeliversChain(t *testing.T) {
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{"type": "snapshot", "self_id": "u1"})
+		for {
+			msg := readFakeMsg(conn)
+			if msg == nil {
+				return
+			}
+			if msg["type"] == "get_thread" {
+				_ = conn.WriteJSON(map[string]any{
+					"type":   "thread",
+					"msg_id": msg["msg_id"],
+					"messages": []map[string]any{
+						{"msg_id": 3, "username": "alice", "message": "root", "ts": 1},
+						{"msg_id": 5, "username": "bob", "message": "reply", "ts": 2, "reply_to": 3},
+					},
+				})
+				return
+			}
+		}
+	})
+
+	type thread struct {
+		msgID uint64
+		msgs  []ChatHistoryMessage
+	}
+	got := make(chan thread, 1)
+	tr := NewTransport()
+	tr.SetOnThread(func(msgID uint64, messages []ChatHistoryMessage) {
+		got <- thread{msgID, messages}
+	})
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	if err := tr.RequestThread(0); err == nil {
+		t.Fatal("expected an error for msg_id 0")
+	}
+	if err := tr.RequestThread(5); err != nil {
+		t.Fatalf("request thread: %v", err)
+	}
+	select {
+	case th := <-got:
+		if th.msgID != 5 || len(th.msgs) != 2 {
+			t.Fatalf("unexpected thread: %+v", th)
+		}
+		if th.msgs[0].Message != "root" || th.msgs[1].ReplyTo != 3 {
+			t.Errorf("thread not decoded root first with reply_to: %+v", th.msgs)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("onThread was not called")
+	}
+}
+
+func TestRequestEditHistoryDeliversEdits(t *testing.T) {
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{"type": "snapshot", "self_id": "u1"})
+		for {
+			msg := readFakeMsg(conn)
+			if msg == nil {
+				return
+			}
+			if msg["type"] == "get_edit_history" {
+				_ = conn.WriteJSON(map[string]any{
+					"type":   "edit_history",
+					"msg_id": msg["msg_id"],
+					"edits": []map[string]any{
+						{"message": "helo", "ts": 10},
+						{"message": "hello", "ts": 20},
+					},
+				})
+			}
+		}
+	})
+
+	type history struct {
+		msgID uint64
+		edits []MessageEdit
+	}
+	got := make(chan history, 1)
+	tr := NewTransport()
+	tr.SetOnEditHistory(func(msgID uint64, edits []MessageEdit) {
+		got <- history{msgID, edits}
+	})
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	if err := tr.RequestEditHistory(0); err == nil {
+		t.Fatal("expected an error for msg_id 0")
+	}
+	if err := tr.RequestEditHistory(7); err != nil {
+		t.Fatalf("request edit history: %v", err)
+	}
+	select {
+	case h := <-got:
+		if h.msgID != 7 || len(h.edits) != 2 || h.edits[0] != (MessageEdit{Message: "helo", TS: 10}) {
+			t.Fatalf("unexpected edit history: %+v", h)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("onEditHistory was not called")
+	}
+}
+
+func TestAuditLogAndLiveEntries(t *testing.T) {
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{"type": "snapshot", "self_id": "u1"})
+		for {
+			msg := readFakeMsg(conn)
+			if msg == nil {
+				return
+			}
+			if msg["type"] == "get_audit_log" {
+				_ = conn.WriteJSON(map[string]any{
+					"type":  "audit_log",
+					"audit": []map[string]any{{"action": "ban", "target_name": "carol", "ts": 20}},
+				})
+				_ = conn.WriteJSON(map[string]any{
+					"type":  "audit_entry",
+					"audit": []map[string]any{{"action": "kick", "actor_id": "u1", "ts": 30}},
+				})
+			}
+		}
+	})
+
+	logs := make(chan []AuditEntry, 1)
+	live := make(chan AuditEntry, 1)
+	tr := NewTransport()
+	tr.SetOnAuditLog(func(entries []AuditEntry) { logs <- entries })
+	tr.SetOnAuditEntry(func(entry AuditEntry) { live <- entry })
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	if err := tr.RequestAuditLog(); err != nil {
+		t.Fatalf("request audit log: %v", err)
+	}
+	select {
+	case entries := <-logs:
+		if len(entries) != 1 || entries[0] != (AuditEntry{Action: "ban", TargetName: "carol", TS: 20}) {
+			t.Fatalf("unexpected audit log: %+v", entries)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("onAuditLog was not called")
+	}
+	select {
+	case e := <-live:
+		if e != (AuditEntry{Action: "kick", ActorID: "u1", TS: 30}) {
+			t.Fatalf("unexpected audit entry: %+v", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("onAuditEntry was not called")
+	}
+}
+
+func TestSendVoiceActivityIsThrottled(t *testing.T) {
+	got := make(chan string, 4)
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{"type": "snapshot", "self_id": "u1"})
+		for {
+			msg := readFakeMsg(conn)
+			if msg == nil {
+				return
+			}
+			if typ, _ := msg["type"].(string); typ == "voice_activity" || typ == "DisconnectVoice" {
+				got <- typ
+			}
+		}
+	})
+
+	tr := NewTransport()
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	for i := 0; i < 3; i++ {
+		if err := tr.SendVoiceActivity(); err != nil {
+			t.Fatalf("send voice activity: %v", err)
+		}
+	}
+	// A later message marks the end of the stream so we can count.
+	if err := tr.JoinChannel(0); err != nil {
+		t.Fatalf("join channel 0: %v", err)
+	}
+
+	var types []string
+	for len(types) == 0 || types[len(types)-1] != "DisconnectVoice" {
+		select {
+		case typ := <-got:
+			types = append(types, typ)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out; got %v", types)
+		}
+	}
+	if len(types) != 2 || types[0] != "voice_activity" {
+		t.Fatalf("expected one voice_activity before DisconnectVoice, got %v", types)
+	}
+}
+
+func TestSendTypingThrottledPerChannel(t *testing.T) {
+	got := make(chan map[string]any, 8)
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{
+			"type":    "snapshot",
+			"self_id": "u1",
+			"users": []map[string]any{
+				{"id": "u1", "username": "alice"},
+				{"id": "u2", "username": "bob"},
+			},
+		})
+		_ = conn.WriteJSON(map[string]any{
+			"type":       "user_typing",
+			"user":       map[string]any{"id": "u2", "username": "bob"},
+			"server_id":  "srv-1",
+			"channel_id": "4",
+		})
+		for {
+			msg := readFakeMsg(conn)
+			if msg == nil {
+				return
+			}
+			if typ, _ := msg["type"].(string); typ == "typing" || typ == "DisconnectVoice" {
+				got <- msg
+			}
+		}
+	})
+
+	type typing struct {
+		userID    uint16
+		username  string
+		channelID int64
+	}
+	received := make(chan typing, 1)
+	tr := NewTransport()
+	tr.SetOnUserTyping(func(userID uint16, username string, channelID int64) {
+		received <- typing{userID, username, channelID}
+	})
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	select {
+	case ev := <-received:
+		if want := (typing{2, "bob", 4}); ev != want {
+			t.Errorf("onUserTyping got %+v, want %+v", ev, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("onUserTyping was not called")
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := tr.SendTyping(1); err != nil {
+			t.Fatalf("send typing: %v", err)
+		}
+	}
+	if err := tr.SendTyping(2); err != nil {
+		t.Fatalf("send typing: %v", err)
+	}
+	// A later message marks the end of the stream so we can count.
+	if err := tr.JoinChannel(0); err != nil {
+		t.Fatalf("join channel 0: %v", err)
+	}
+
+	var channels []any
+	for {
+		select {
+		case msg := <-got:
+			if msg["type"] == "DisconnectVoice" {
+				if len(channels) != 2 || channels[0] != "1" || channels[1] != "2" {
+					t.Fatalf("expected one typing each for channels 1 and 2, got %v", channels)
+				}
+				return
+			}
+			channels = append(channels, msg["channel_id"])
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out; got %v", channels)
+		}
+	}
+}
+
+func TestVersionMismatchReasonOlderServer(t *testing.T) {
+	reason := versionMismatchReason(protocolVersion - 1)
+	if strings.Contains(reason, "please update bken") {
+		t.Errorf("older server should not ask the user to update the client: %q", reason)
+	}
+}
+
+func TestRecordingNoticesAndConsent(t *testing.T) {
+	consent := make(chan map[string]any, 1)
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{
+			"type":    "snapshot",
+			"self_id": "u1",
+			"users": []map[string]any{
+				{"id": "u1", "username": "alice"},
+				{"id": "u2", "username": "bob"},
+			},
+		})
+		_ = conn.WriteJSON(map[string]any{"type": "recording_started", "user_id": "u2", "consent_required": true})
+		for {
+			msg := readFakeMsg(conn)
+			if msg == nil {
+				return
+			}
+			if msg["type"] == "recording_consent" {
+				consent <- msg
+				_ = conn.WriteJSON(map[string]any{"type": "recording_stopped", "user_id": "u2"})
+			}
+		}
+	})
+
+	type notice struct {
+		id      uint16
+		started bool
+		consent bool
+	}
+	notices := make(chan notice, 2)
+	tr := NewTransport()
+	tr.SetOnRecordingStarted(func(id uint16, consentRequired bool) { notices <- notice{id, true, consentRequired} })
+	tr.SetOnRecordingStopped(func(id uint16) { notices <- notice{id: id} })
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+	bob := tr.localUserID("u2")
+
+	select {
+	case got := <-notices:
+		if got != (notice{bob, true, true}) {
+			t.Errorf("recording_started = %+v, want id %d requiring consent", got, bob)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("recording_started was not reported")
+	}
+
+	if err := tr.SendRecordingConsent(true); err != nil {
+		t.Fatalf("send consent: %v", err)
+	}
+	select {
+	case msg := <-consent:
+		if msg["consent"] != true {
+			t.Errorf("recording_consent = %v, want consent true", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("recording_consent was not sent")
+	}
+
+	select {
+	case got := <-notices:
+		if got != (notice{id: bob}) {
+			t.Errorf("recording_stopped = %+v, want id %d", got, bob)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("recording_stopped was not reported")
+	}
+}
+
+// --- reconnect rehydration tests ---
+
+func TestSnapshotTriggersRehydration(t *testing.T) {
+	serve := func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		readFakeMsg(conn) // connect_server
+		_ = conn.WriteJSON(map[string]any{
+			"type":             "snapshot",
+			"self_id":          "u1",
+			"protocol_version": protocolVersion,
+			"users":            []map[string]any{{"id": "u1", "username": "alice"}},
+		})
+
+		seen := map[string]bool{}
+		for !seen["get_channels"] || !seen["get_server_info"] {
+			msg := readFakeMsg(conn)
+			if msg == nil {
+				return
+			}
+			typ, _ := msg["type"].(string)
+			seen[typ] = true
+			switch typ {
+			case "get_channels":
+				_ = conn.WriteJSON(map[string]any{
+					"type":     "channel_list",
+					"channels": []map[string]any{{"id": 1, "name": "General"}},
+				})
+			case "get_server_info":
+				_ = conn.WriteJSON(map[string]any{"type": "server_info", "server_name": "Test Server"})
+			}
+		}
+		// Hold the connection open until the client hangs up.
+		_, _, _ = conn.ReadMessage()
+	}
+
+	// Connect twice against the same server to cover the reconnect path.
+	addr := startFakeServer(t, serve)
+	tr := NewTransport()
+	channels := make(chan []ChannelInfo, 2)
+	names := make(chan string, 2)
+	tr.SetOnChannelList(func(chs []ChannelInfo) { channels <- chs })
+	tr.SetOnServerInfo(func(name string) { names <- name })
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+			t.Fatalf("connect %d: %v", attempt, err)
+		}
+		select {
+		case chs := <-channels:
+			if len(chs) != 1 || chs[0].Name != "General" {
+				t.Errorf("connect %d: channel list = %+v", attempt, chs)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("connect %d: channel list was not rehydrated", attempt)
+		}
+		select {
+		case name := <-names:
+			if name != "Test Server" {
+				t.Errorf("connect %d: server name = %q", attempt, name)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("connect %d: server info was not rehydrated", attempt)
+		}
+		tr.Disconnect()
+	}
+}
+
+// --- message of the day tests ---
+
+func TestMOTDShownOncePerConnect(t *testing.T) {
+	serve := func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		readFakeMsg(conn) // connect_server
+		// A second snapshot stands in for the one a reconnect receives.
+		for i := 0; i < 2; i++ {
+			_ = conn.WriteJSON(map[string]any{"type": "snapshot", "self_id": "u1", "motd": "Be kind"})
+		}
+		// Hold the connection open until the client hangs up.
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}
+
+	addr := startFakeServer(t, serve)
+	tr := NewTransport()
+	motds := make(chan string, 4)
+	tr.SetOnMOTD(func(text string) { motds <- text })
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+			t.Fatalf("connect %d: %v", attempt, err)
+		}
+		select {
+		case text := <-motds:
+			if text != "Be kind" {
+				t.Errorf("connect %d: motd = %q", attempt, text)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("connect %d: motd was not reported", attempt)
+		}
+		select {
+		case text := <-motds:
+			t.Errorf("connect %d: motd reported again: %q", attempt, text)
+		case <-time.After(200 * time.Millisecond):
+		}
+		tr.Disconnect()
+	}
+}
+
+func TestServerShutdownReportsGrace(t *testing.T) {
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		readFakeMsg(conn) // connect_server
+		_ = conn.WriteJSON(map[string]any{"type": "snapshot", "self_id": "u1"})
+		_ = conn.WriteJSON(map[string]any{"type": "server_shutdown", "grace_ms": 15000})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	tr := NewTransport()
+	graces := make(chan int64, 1)
+	tr.SetOnServerShutdown(func(graceMs int64) { graces <- graceMs })
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	select {
+	case grace := <-graces:
+		if grace != 15000 {
+			t.Errorf("grace = %d ms, want 15000", grace)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("server_shutdown was not reported")
+	}
+}
+
+func TestSnapshotCarriesAllowedEmoji(t *testing.T) {
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		readFakeMsg(conn) // connect_server
+		_ = conn.WriteJSON(map[string]any{"type": "snapshot", "self_id": "u1", "allowed_emoji": []string{"👍", "🎉"}})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	tr := NewTransport()
+	lists := make(chan []string, 1)
+	tr.SetOnEmojiList(func(emoji []string) { lists <- emoji })
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	select {
+	case got := <-lists:
+		if !slices.Equal(got, []string{"👍", "🎉"}) {
+			t.Errorf("emoji list = %q", got)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("emoji list was not reported")
+	}
+	if got := tr.AllowedEmoji(); !slices.Equal(got, []string{"👍", "🎉"}) {
+		t.Errorf("AllowedEmoji = %q", got)
+	}
+}
+
+func TestValidateChatUsesAdvertisedLimit(t *testing.T) {
+	long := strings.Repeat("x", 1500)
+	tr := NewTransport()
+	// Before any snapshot the client assumes the old 500-byte limit.
+	if err := tr.SendChat(long); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("SendChat before snapshot: err = %v, want the 500 limit", err)
+	}
+
+	got := make(chan string, 1)
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{"type": "snapshot", "self_id": "u1", "max_message_length": 2000})
+		for {
+			msg := readFakeMsg(conn)
+			if msg == nil {
+				return
+			}
+			if msg["type"] == "send_text" {
+				got <- msg["message"].(string)
+			}
+		}
+	})
+	snapshots := make(chan struct{}, 1)
+	tr.SetOnEmojiList(func([]string) { snapshots <- struct{}{} })
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+	select {
+	case <-snapshots:
+	case <-time.After(3 * time.Second):
+		t.Fatal("snapshot was not processed")
+	}
+
+	if got := tr.MaxMessageLength(); got != 2000 {
+		t.Fatalf("MaxMessageLength = %d, want 2000", got)
+	}
+	if err := tr.SendChat(long); err != nil {
+		t.Fatalf("SendChat under a 2000 limit: %v", err)
+	}
+	if err := tr.SendChat(long + long); err == nil {
+		t.Fatal("expected 3000 characters to exceed the advertised limit")
+	}
+	select {
+	case m := <-got:
+		if m != long {
+			t.Fatalf("server received %d characters, want 1500", len(m))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("send_text did not reach the server")
+	}
+}
+
+func TestChannelListCarriesCategories(t *testing.T) {
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		readFakeMsg(conn) // connect_server
+		_ = conn.WriteJSON(map[string]any{
+			"type":       "channel_list",
+			"channels":   []map[string]any{{"id": 1, "name": "General"}, {"id": 2, "name": "Raid", "category_id": 7, "category_name": "Games"}},
+			"categories": []map[string]any{{"id": 7, "name": "Games"}},
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	tr := NewTransport()
+	var events []string
+	done := make(chan struct{})
+	tr.SetOnCategoryList(func(cats []CategoryInfo) {
+		if len(cats) != 1 || cats[0] != (CategoryInfo{ID: 7, Name: "Games"}) {
+			t.Errorf("categories = %+v", cats)
+		}
+		events = append(events, "categories")
+	})
+	tr.SetOnChannelList(func(chs []ChannelInfo) {
+		if len(chs) != 2 || chs[1].CategoryID != 7 || chs[1].CategoryName != "Games" || chs[0].CategoryID != 0 {
+			t.Errorf("channels = %+v", chs)
+		}
+		events = append(events, "channels")
+		close(done)
+	})
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("channel list was not reported")
+	}
+	if !slices.Equal(events, []string{"categories", "channels"}) {
+		t.Errorf("callback order = %v, want categories before channels", events)
+	}
+}
+
+// --- file chat tests ---
+
+func TestSendFileChatRoundTrip(t *testing.T) {
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		readFakeMsg(conn) // connect_server
+		for {
+			msg := readFakeMsg(conn)
+			if msg == nil {
+				return
+			}
+			if msg["type"] != "send_text" {
+				continue
+			}
+			// Relay the way the server does: echo the file fields back in a
+			// text_message broadcast.
+			_ = conn.WriteJSON(map[string]any{
+				"type":       "text_message",
+				"server_id":  msg["server_id"],
+				"channel_id": msg["channel_id"],
+				"message":    msg["message"],
+				"msg_id":     7,
+				"ts":         1234,
+				"user":       map[string]any{"id": "u1", "username": "alice"},
+				"file_id":    msg["file_id"],
+				"file_name":  msg["file_name"],
+				"file_size":  msg["file_size"],
+			})
+			return
+		}
+	})
+
+	type fileMsg struct {
+		channelID        int64
+		fileID, fileName string
+		fileSize         int64
+	}
+	got := make(chan fileMsg, 1)
+	tr := NewTransport()
+	tr.SetOnChannelChatMessage(func(_ uint64, _ uint16, channelID int64, _, _ string, _ int64, fileID, fileName string, fileSize int64, _ []uint16) {
+		got <- fileMsg{channelID, fileID, fileName, fileSize}
+	})
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	if err := tr.SendFileChat(3, "blob-1", 2048, "notes.txt", ""); err != nil {
+		t.Fatalf("SendFileChat: %v", err)
+	}
+
+	select {
+	case m := <-got:
+		want := fileMsg{3, "blob-1", "notes.txt", 2048}
+		if m != want {
+			t.Errorf("file message = %+v, want %+v", m, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("file message never arrived")
+	}
+}
+
+// --- automatic reconnect tests ---
+
+func TestReconnectDelayBacksOff(t *testing.T) {
+	base := 500 * time.Millisecond
+	want := []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second}
+	for i, w := range want {
+		if got := reconnectDelay(base, i+1); got != w {
+			t.Errorf("attempt %d: delay = %v, want %v", i+1, got, w)
+		}
+	}
+	if got := reconnectDelay(base, 20); got != maxReconnectDelay {
+		t.Errorf("delay should cap at %v, got %v", maxReconnectDelay, got)
+	}
+}
+
+func TestReconnectAfterUnexpectedClose(t *testing.T) {
+	var conns atomic.Int32
+	joined := make(chan string, 1)
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		n := conns.Add(1)
+		readFakeMsg(conn) // hello
+		readFakeMsg(conn) // connect_server
+		selfID := fmt.Sprintf("u%d", n)
+		_ = conn.WriteJSON(map[string]any{
+			"type":    "snapshot",
+			"self_id": selfID,
+			"users": []map[string]any{{
+				"id":       selfID,
+				"username": "alice",
+				"voice":    map[string]any{"server_id": "s", "channel_id": "3"},
+			}},
+		})
+		if n == 1 {
+			return // drop the first connection
+		}
+		for {
+			var msg map[string]any
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			if msg["type"] == "join_voice" {
+				joined <- msg["channel_id"].(string)
+			}
+		}
+	})
+
+	attempts := make(chan int, 4)
+	disconnects := make(chan string, 4)
+	tr := NewTransport()
+	tr.SetReconnect(3, 10*time.Millisecond)
+	tr.SetOnReconnecting(func(attempt int) { attempts <- attempt })
+	tr.SetOnDisconnected(func(reason string) { disconnects <- reason })
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	select {
+	case a := <-attempts:
+		if a != 1 {
+			t.Errorf("first reconnect attempt = %d, want 1", a)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("onReconnecting was not called")
+	}
+	select {
+	case ch := <-joined:
+		if ch != "3" {
+			t.Errorf("rejoined channel %q, want 3", ch)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("voice channel was not rejoined after reconnect")
+	}
+	select {
+	case reason := <-disconnects:
+		t.Errorf("onDisconnected(%q) fired although the reconnect succeeded", reason)
+	default:
+	}
+
+	// A deliberate disconnect must not trigger another reconnect.
+	tr.Disconnect()
+	time.Sleep(100 * time.Millisecond)
+	if n := conns.Load(); n != 2 {
+		t.Errorf("server saw %d connections, want 2", n)
+	}
+	select {
+	case a := <-attempts:
+		t.Errorf("unexpected reconnect attempt %d after Disconnect", a)
+	default:
+	}
+}
+
+func TestReconnectResumesFromLastSeenMessage(t *testing.T) {
+	var conns atomic.Int32
+	resumed := make(chan map[string]any, 1)
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		n := conns.Add(1)
+		readFakeMsg(conn) // hello
+		readFakeMsg(conn) // connect_server
+		_ = conn.WriteJSON(map[string]any{"type": "snapshot", "self_id": "u1"})
+		if n == 1 {
+			_ = conn.WriteJSON(map[string]any{
+				"type":       "message_history",
+				"channel_id": "3",
+				"messages":   []map[string]any{{"msg_id": 4}, {"msg_id": 7}},
+			})
+			_ = conn.WriteJSON(map[string]any{
+				"type":       "text_message",
+				"user":       map[string]any{"id": "u2", "username": "bob"},
+				"channel_id": "5",
+				"msg_id":     9,
+				"message":    "hi",
+			})
+			time.Sleep(50 * time.Millisecond)
+			return // drop the first connection
+		}
+		for {
+			var msg map[string]any
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			if msg["type"] == "resume" {
+				resumed <- msg["seqs"].(map[string]any)
+			}
+		}
+	})
+
+	tr := NewTransport()
+	tr.SetReconnect(3, 10*time.Millisecond)
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	select {
+	case seqs := <-resumed:
+		if seqs["3"] != float64(7) || seqs["5"] != float64(9) || len(seqs) != 2 {
+			t.Errorf("resume seqs = %v, want 3:7 and 5:9", seqs)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no resume sent after reconnect")
+	}
+}
+
+func TestIncomingAudioDTXSilenceIsNotLoss(t *testing.T) {
+	tr := NewTransport()
+	tr.myChannel.Store(1)
+	tr.userChannels.Store(uint16(2), int64(1))
+
+	// A talk spurt, a pause where the sender sent nothing, then more
+	// speech. Sequence numbers stay contiguous across the pause.
+	for seq := uint16(10); seq < 15; seq++ {
+		tr.handleIncomingAudio(2, seq, []byte{1, 2, 3})
+	}
+	time.Sleep(150 * time.Millisecond)
+	for seq := uint16(15); seq < 20; seq++ {
+		tr.handleIncomingAudio(2, seq, []byte{1, 2, 3})
+	}
+	if lost := tr.peerStats[2].lost; lost != 0 {
+		t.Fatalf("lost = %d after a DTX pause, want 0", lost)
+	}
+
+	// A real gap in the sequence is still counted.
+	tr.handleIncomingAudio(2, 22, []byte{1, 2, 3})
+	if lost := tr.peerStats[2].lost; lost != 2 {
+		t.Fatalf("lost = %d after skipping two sequence numbers, want 2", lost)
+	}
+}
+
+func TestPeerMetricsSplitLossPerSender(t *testing.T) {
+	tr := NewTransport()
+	tr.myChannel.Store(1)
+	tr.userChannels.Store(uint16(2), int64(1))
+	tr.userChannels.Store(uint16(3), int64(1))
+
+	// Peer 2 is clean; peer 3 drops three of four packets.
+	for seq := uint16(1); seq <= 10; seq++ {
+		tr.handleIncomingAudio(2, seq, []byte{1, 2, 3})
+	}
+	tr.handleIncomingAudio(3, 1, []byte{1, 2, 3})
+	tr.handleIncomingAudio(3, 5, []byte{1, 2, 3})
+
+	m := tr.GetMetrics()
+	if want := 3.0 / 13; m.PacketLoss != want {
+		t.Errorf("aggregate loss = %v, want %v", m.PacketLoss, want)
+	}
+	peers := tr.GetPeerMetrics()
+	if len(peers) != 2 {
+		t.Fatalf("peer metrics = %+v, want two peers", peers)
+	}
+	if got := peers[2].PacketLoss; got != 0 {
+		t.Errorf("peer 2 loss = %v, want 0", got)
+	}
+	if got := peers[3]; got.UserID != 3 || got.PacketLoss != 0.75 {
+		t.Errorf("peer 3 = %+v, want loss 0.75", got)
+	}
+
+	// Reading per-peer metrics does not reset the aggregate's counters.
+	tr.handleIncomingAudio(3, 7, []byte{1, 2, 3})
+	tr.GetPeerMetrics()
+	if m := tr.GetMetrics(); m.PacketLoss != 0.5 {
+		t.Errorf("next interval loss = %v, want 0.5", m.PacketLoss)
+	}
+}
+
+func TestRecordPeerRTT(t *testing.T) {
+	tr := NewTransport()
+	tr.peers[2] = &peerState{id: 2}
+	now := time.Unix(1_700_000_000, 0)
+
+	// The peer saw our sender report 150 ms ago and held it for 50 ms.
+	tr.recordPeerRTT(2, []rtcp.ReceptionReport{{
+		LastSenderReport: ntpCompact(now.Add(-150 * time.Millisecond)),
+		Delay:            65536 / 20,
+	}}, now)
+	rtt := tr.GetPeerMetrics()[2].RTTMs
+	if rtt < 99 || rtt > 101 {
+		t.Errorf("rtt = %.2f ms, want about 100", rtt)
+	}
+
+	// Reports without an LSR, and from peers we have closed, are ignored.
+	tr.recordPeerRTT(2, []rtcp.ReceptionReport{{}}, now)
+	if got := tr.GetPeerMetrics()[2].RTTMs; got != rtt {
+		t.Errorf("rtt = %.2f after a report without LSR, want %.2f", got, rtt)
+	}
+	tr.recordPeerRTT(3, []rtcp.ReceptionReport{{LastSenderReport: ntpCompact(now), Delay: 0}}, now)
+	if _, ok := tr.GetPeerMetrics()[3]; ok {
+		t.Error("unknown peer should not get metrics")
+	}
+}
+
+func TestSetWhisperTargetRequiresSharedChannel(t *testing.T) {
+	tr := NewTransport()
+	tr.userChannels.Store(uint16(2), int64(1))
+	tr.userChannels.Store(uint16(3), int64(2))
+	tr.userChannels.Store(uint16(4), int64(0))
+
+	if err := tr.SetWhisperTarget(2); err == nil {
+		t.Fatal("whisper allowed while not in voice")
+	}
+
+	tr.myChannel.Store(1)
+	if err := tr.SetWhisperTarget(3); err == nil {
+		t.Fatal("whisper allowed to a user in another channel")
+	}
+	if err := tr.SetWhisperTarget(4); err == nil {
+		t.Fatal("whisper allowed to a user not in any channel")
+	}
+	if err := tr.SetWhisperTarget(0); err == nil {
+		t.Fatal("whisper allowed with no target")
+	}
+	if tr.WhisperTarget() != 0 {
+		t.Fatalf("rejected whispers left target %d", tr.WhisperTarget())
+	}
+
+	if err := tr.SetWhisperTarget(2); err != nil {
+		t.Fatalf("whisper to channel mate: %v", err)
+	}
+	if tr.WhisperTarget() != 2 {
+		t.Fatalf("target = %d, want 2", tr.WhisperTarget())
+	}
+	tr.ClearWhisper()
+	if tr.WhisperTarget() != 0 {
+		t.Fatalf("target = %d after ClearWhisper, want 0", tr.WhisperTarget())
+	}
+}
+
+func TestMuteUserServerAndUserMuted(t *testing.T) {
+	requests := make(chan map[string]any, 2)
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{
+			"type":    "snapshot",
+			"self_id": "u1",
+			"users": []map[string]any{
+				{"id": "u1", "username": "alice"},
+				{"id": "u2", "username": "bob"},
+			},
+		})
+		for {
+			msg := readFakeMsg(conn)
+			if msg == nil {
+				return
+			}
+			if msg["type"] == "mute_user" {
+				requests <- msg
+				_ = conn.WriteJSON(map[string]any{
+					"type":    "user_muted",
+					"user_id": msg["user_id"],
+					"muted":   msg["muted"] != false,
+				})
+			}
+		}
+	})
+
+	type mute struct {
+		id    uint16
+		muted bool
+	}
+	received := make(chan mute, 2)
+	tr := NewTransport()
+	tr.SetOnUserMuted(func(id uint16, muted bool) {
+		received <- mute{id, muted}
+	})
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	bob := tr.localUserID("u2")
+	if err := tr.MuteUserServer(bob, 60); err != nil {
+		t.Fatalf("mute: %v", err)
+	}
+	if req := <-requests; req["user_id"] != "u2" || req["duration_s"] != float64(60) {
+		t.Errorf("unexpected mute_user request: %v", req)
+	}
+	if err := tr.UnmuteUserServer(bob); err != nil {
+		t.Fatalf("unmute: %v", err)
+	}
+	if req := <-requests; req["muted"] != false {
+		t.Errorf("unexpected unmute request: %v", req)
+	}
+	for _, want := range []bool{true, false} {
+		select {
+		case got := <-received:
+			if got.id != bob || got.muted != want {
+				t.Errorf("onUserMuted got %+v, want muted=%v for %d", got, want, bob)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("onUserMuted was not called")
+		}
+	}
+}
+
+func TestSendSpeakingStartsAndStops(t *testing.T) {
+	requests := make(chan map[string]any, 4)
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{
+			"type":    "snapshot",
+			"self_id": "u1",
+			"users": []map[string]any{
+				{"id": "u1", "username": "alice"},
+				{"id": "u2", "username": "bob"},
+			},
+		})
+		for {
+			msg := readFakeMsg(conn)
+			if msg == nil {
+				return
+			}
+			if msg["type"] == "speaking" {
+				requests <- msg
+				_ = conn.WriteJSON(map[string]any{"type": "speaking", "user_id": "u2", "speaking": msg["speaking"]})
+			}
+		}
+	})
+
+	received := make(chan bool, 2)
+	tr := NewTransport()
+	tr.SetOnSpeaking(func(id uint16, speaking bool) {
+		if id == tr.localUserID("u2") {
+			received <- speaking
+		}
+	})
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+
+	// Repeated calls within the hangover send a single start.
+	for i := 0; i < 3; i++ {
+		if err := tr.SendSpeaking(); err != nil {
+			t.Fatalf("send speaking: %v", err)
+		}
+	}
+	for _, want := range []bool{true, false} {
+		select {
+		case req := <-requests:
+			if req["speaking"] != want {
+				t.Errorf("speaking request %v, want speaking=%v", req, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no speaking=%v request", want)
+		}
+		select {
+		case got := <-received:
+			if got != want {
+				t.Errorf("onSpeaking got %v, want %v", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("onSpeaking was not called")
+		}
+	}
+	select {
+	case req := <-requests:
+		t.Errorf("unexpected extra request: %v", req)
+	default:
+	}
+}
+
+func TestResolveSoundURL(t *testing.T) {
+	for _, tc := range []struct{ base, in, want string }{
+		{"http://host:8080", "/api/blobs/chime", "http://host:8080/api/blobs/chime"},
+		{"http://host:8080", "https://cdn.example/a.wav", "https://cdn.example/a.wav"},
+		{"http://host:8080", "", ""},
+		{"", "/api/blobs/chime", ""},
+	} {
+		if got := resolveSoundURL(tc.base, tc.in); got != tc.want {
+			t.Errorf("resolveSoundURL(%q, %q) = %q, want %q", tc.base, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestMessagePageIsDeliveredOldestFirst(t *testing.T) {
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{"type": "snapshot", "self_id": "u1", "users": []map[string]any{{"id": "u1", "username": "alice"}}})
+		req := readFakeMsg(conn)
+		for req != nil && req["type"] != "get_messages_before" {
+			req = readFakeMsg(conn)
+		}
+		if req["channel_id"] != "3" || req["before"] != float64(10) || req["limit"] != float64(3) {
+			t.Errorf("unexpected request: %v", req)
+		}
+		_ = conn.WriteJSON(map[string]any{
+			"type":       "message_history",
+			"channel_id": "3",
+			"before":     10,
+			"messages": []map[string]any{
+				{"msg_id": 9, "username": "bob", "message": "c"},
+				{"msg_id": 8, "username": "bob", "message": "b"},
+				{"msg_id": 7, "username": "bob", "message": "a"},
+			},
+		})
+		for { // block until the client disconnects
+			if readFakeMsg(conn) == nil {
+				return
+			}
+		}
+	})
+
+	pages := make(chan []ChatHistoryMessage, 1)
+	tr := NewTransport()
+	tr.SetOnMessageHistory(func(_ int64, msgs []ChatHistoryMessage) { pages <- msgs })
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+	if err := tr.RequestMessagesBefore(3, 10, 3); err != nil {
+		t.Fatalf("request page: %v", err)
+	}
+
+	select {
+	case msgs := <-pages:
+		var ids []int64
+		for _, m := range msgs {
+			ids = append(ids, m.MsgID)
+		}
+		if !slices.Equal(ids, []int64{7, 8, 9}) {
+			t.Errorf("page order = %v, want [7 8 9]", ids)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message_history delivered")
+	}
+}
+
+func TestBanListAndUnban(t *testing.T) {
+	addr := startFakeServer(t, func(conn *fakeConn) {
+		readFakeMsg(conn) // hello
+		_ = conn.WriteJSON(map[string]any{"type": "snapshot", "self_id": "u1", "users": []map[string]any{{"id": "u1", "username": "alice"}}})
+		req := readFakeMsg(conn)
+		for req != nil && req["type"] != "unban" {
+			req = readFakeMsg(conn)
+		}
+		if req["ban_id"] != float64(4) {
+			t.Errorf("unexpected unban: %v", req)
+		}
+		_ = conn.WriteJSON(map[string]any{
+			"type": "ban_list",
+			"bans": []map[string]any{
+				{"id": 5, "ip": "10.0.0.2", "username": "eve", "ts": 1000, "expires_at": 2000},
+			},
+		})
+		for { // block until the client disconnects
+			if readFakeMsg(conn) == nil {
+				return
+			}
+		}
+	})
+
+	lists := make(chan []BanInfo, 1)
+	tr := NewTransport()
+	tr.SetOnBanList(func(bans []BanInfo) { lists <- bans })
+	if err := tr.Connect(context.Background(), addr, "alice"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer tr.Disconnect()
+	if err := tr.Unban(4); err != nil {
+		t.Fatalf("unban: %v", err)
+	}
+
+	select {
+	case bans := <-lists:
+		want := BanInfo{ID: 5, IP: "10.0.0.2", Username: "eve", TS: 1000, ExpiresAt: 2000}
+		if len(bans) != 1 || bans[0] != want {
+			t.Errorf("ban_list = %+v, want [%+v]", bans, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no ban_list delivered")
+	}
+}
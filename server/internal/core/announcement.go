@@ -0,0 +1,80 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"bken/server/internal/protocol"
+)
+
+// MaxAnnouncementLength bounds the server announcement text in bytes.
+const MaxAnnouncementLength = 2000
+
+// SetAnnouncement replaces the server announcement on behalf of actorID,
+// who must be an admin or the owner (otherwise ErrNotPermitted). Empty text
+// clears it. The returned announcement message is ready to broadcast.
+func (r *ChannelState) SetAnnouncement(actorID, text string) (protocol.Message, error) {
+	text = strings.TrimSpace(text)
+	if len(text) > MaxAnnouncementLength {
+		return protocol.Message{}, fmt.Errorf("announcement must be at most %d bytes", MaxAnnouncementLength)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	actor, ok := r.users[actorID]
+	if !ok {
+		return protocol.Message{}, fmt.Errorf("user not found")
+	}
+	if RoleLevel(r.roleLocked(actor)) < RoleLevel(RoleAdmin) {
+		return protocol.Message{}, ErrNotPermitted
+	}
+	r.announcement = text
+	r.announcementBy = ""
+	if text != "" {
+		r.announcementBy = actor.username
+	}
+	slog.Info("announcement set", "user_id", actorID, "len", len(text))
+	return r.announcementMsgLocked(), nil
+}
+
+// Announcement returns the current announcement message, and false when
+// there is none.
+func (r *ChannelState) Announcement() (protocol.Message, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.announcement == "" {
+		return protocol.Message{}, false
+	}
+	return r.announcementMsgLocked(), true
+}
+
+func (r *ChannelState) announcementMsgLocked() protocol.Message {
+	return protocol.Message{
+		Type:     protocol.TypeAnnouncement,
+		Message:  r.announcement,
+		PostedBy: r.announcementBy,
+	}
+}
+
+// SetMOTD sets the message of the day, which each user is sent once in
+// their snapshot when they connect. Unlike the announcement it is set by
+// the operator and never broadcast. Empty text turns it off.
+func (r *ChannelState) SetMOTD(text string) error {
+	text = strings.TrimSpace(text)
+	if len(text) > MaxAnnouncementLength {
+		return fmt.Errorf("motd must be at most %d bytes", MaxAnnouncementLength)
+	}
+	r.mu.Lock()
+	r.motd = text
+	r.mu.Unlock()
+	slog.Info("motd set", "len", len(text))
+	return nil
+}
+
+// MOTD returns the message of the day, or "" when there is none.
+func (r *ChannelState) MOTD() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.motd
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSetAnnouncementRequiresAdmin(t *testing.T) {
+	r := NewChannelState("")
+	owner, _, _ := r.Add("alice", 8)
+	admin, _, _ := r.Add("bob", 8)
+	user, _, _ := r.Add("carol", 8)
+	if err := r.SetRole(admin.UserID, RoleAdmin); err != nil {
+		t.Fatalf("set role: %v", err)
+	}
+
+	if _, err := r.SetAnnouncement(user.UserID, "hello"); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("expected ErrNotPermitted for a plain user, got %v", err)
+	}
+	if _, ok := r.Announcement(); ok {
+		t.Fatal("rejected announcement should not be stored")
+	}
+
+	msg, err := r.SetAnnouncement(admin.UserID, "  maintenance at noon  ")
+	if err != nil {
+		t.Fatalf("admin set: %v", err)
+	}
+	if msg.Message != "maintenance at noon" || msg.PostedBy != "bob" {
+		t.Fatalf("unexpected announcement %+v", msg)
+	}
+	if got, ok := r.Announcement(); !ok || got.Message != msg.Message || got.PostedBy != msg.PostedBy {
+		t.Fatalf("Announcement() = %+v, %v", got, ok)
+	}
+
+	if _, err := r.SetAnnouncement(owner.UserID, strings.Repeat("x", MaxAnnouncementLength+1)); err == nil {
+		t.Fatal("expected error for an overlong announcement")
+	}
+
+	msg, err = r.SetAnnouncement(owner.UserID, "")
+	if err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	if msg.Message != "" || msg.PostedBy != "" {
+		t.Fatalf("clear should broadcast an empty announcement, got %+v", msg)
+	}
+	if _, ok := r.Announcement(); ok {
+		t.Fatal("announcement should be cleared")
+	}
+}
+
+func TestSetMOTD(t *testing.T) {
+	r := NewChannelState("")
+	if got := r.MOTD(); got != "" {
+		t.Fatalf("default motd = %q, want empty", got)
+	}
+	if err := r.SetMOTD("  be kind  "); err != nil {
+		t.Fatalf("set motd: %v", err)
+	}
+	if got := r.MOTD(); got != "be kind" {
+		t.Fatalf("motd = %q, want %q", got, "be kind")
+	}
+	if err := r.SetMOTD(strings.Repeat("x", MaxAnnouncementLength+1)); err == nil {
+		t.Fatal("expected error for an overlong motd")
+	}
+	if got := r.MOTD(); got != "be kind" {
+		t.Fatalf("rejected motd replaced the old one: %q", got)
+	}
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"bken/server/internal/protocol"
+)
+
+// ErrNotPermitted is returned by moderation actions the actor's role does
+// not allow.
+var ErrNotPermitted = errors.New("not permitted")
+
+// SetRemoteIP records the address a user connected from, which is what a
+// ban is recorded against.
+func (r *ChannelState) SetRemoteIP(userID, ip string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.users[userID]; ok {
+		u.remoteIP = ip
+	}
+}
+
+// Banned describes a user removed by Ban.
+type Banned struct {
+	User      protocol.User
+	IP        string
+	ActorName string
+}
+
+// CheckBan reports whether actorID may ban targetID and describes the
+// target. The actor must be an admin or the owner and must outrank the
+// target; otherwise ErrNotPermitted is returned. Callers persist the ban
+// with the result before calling Ban, so a kicked client cannot reconnect
+// ahead of the record.
+func (r *ChannelState) CheckBan(actorID, targetID string) (Banned, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	actor, target, err := r.banPartiesLocked(actorID, targetID)
+	if err != nil {
+		return Banned{}, err
+	}
+	return Banned{User: toProtocolUser(target), IP: target.remoteIP, ActorName: actor.username}, nil
+}
+
+// Ban disconnects targetID on behalf of actorID, re-checking the same rules
+// as CheckBan. The target is sent kicked with reason, its send channel is
+// closed (which ends its websocket), and user_left is broadcast.
+func (r *ChannelState) Ban(actorID, targetID, reason string) (Banned, error) {
+	r.mu.Lock()
+	actor, target, err := r.banPartiesLocked(actorID, targetID)
+	if err != nil {
+		r.mu.Unlock()
+		return Banned{}, err
+	}
+	delete(r.users, targetID)
+	r.mu.Unlock()
+
+	r.bans.Add(1)
+	slog.Info("user banned", "user_id", targetID, "username", target.username, "ip", target.remoteIP, "by", actorID, "reason", reason)
+	left := r.evict(target, reason)
+	return Banned{User: left, IP: target.remoteIP, ActorName: actor.username}, nil
+}
+
+// Kick disconnects targetID on behalf of actorID without banning them, so
+// they may reconnect. The same role rules as Ban apply. The target is sent
+// kicked with reason and user_left is broadcast.
+func (r *ChannelState) Kick(actorID, targetID, reason string) (Banned, error) {
+	r.mu.Lock()
+	actor, target, err := r.banPartiesLocked(actorID, targetID)
+	if err != nil {
+		r.mu.Unlock()
+		return Banned{}, err
+	}
+	delete(r.users, targetID)
+	r.mu.Unlock()
+
+	slog.Info("user kicked", "user_id", targetID, "username", target.username, "by", actorID, "reason", reason)
+	left := r.evict(target, reason)
+	return Banned{User: left, IP: target.remoteIP, ActorName: actor.username}, nil
+}
+
+// evict sends a removed user kicked with reason, closes its send channel
+// (which ends its websocket) and broadcasts user_left. The caller has
+// already deleted the user from r.users.
+func (r *ChannelState) evict(target *userState, reason string) protocol.User {
+	r.deliver(target.send, protocol.Message{Type: protocol.TypeKicked, Message: reason})
+	close(target.send)
+	left := toProtocolUser(target)
+	r.Broadcast(protocol.Message{Type: protocol.TypeUserLeft, User: &left}, "")
+	return left
+}
+
+// banPartiesLocked looks up both sides of a ban or kick and applies the
+// role rules.
+// Caller holds r.mu.
+func (r *ChannelState) banPartiesLocked(actorID, targetID string) (*userState, *userState, error) {
+	return r.moderationPartiesLocked(actorID, targetID, RoleAdmin)
+}
+
+// moderationPartiesLocked looks up both sides of a moderation action. The
+// actor must hold at least minRole and outrank the target; otherwise
+// ErrNotPermitted is returned. Caller holds r.mu.
+func (r *ChannelState) moderationPartiesLocked(actorID, targetID, minRole string) (*userState, *userState, error) {
+	actor, ok := r.users[actorID]
+	if !ok {
+		return nil, nil, fmt.Errorf("user not found")
+	}
+	target, ok := r.users[targetID]
+	if !ok {
+		return nil, nil, fmt.Errorf("user %s is not connected", targetID)
+	}
+	actorLevel := RoleLevel(r.roleLocked(actor))
+	if actorLevel < RoleLevel(minRole) || actorLevel <= RoleLevel(r.roleLocked(target)) {
+		return nil, nil, ErrNotPermitted
+	}
+	return actor, target, nil
+}
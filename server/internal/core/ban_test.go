@@ -0,0 +1,115 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"bken/server/internal/protocol"
+)
+
+func TestBanRequiresAdminAboveTarget(t *testing.T) {
+	r := NewChannelState("")
+	add := func(name string) *Session {
+		s, _, err := r.Add(name, 8)
+		if err != nil {
+			t.Fatalf("add %s: %v", name, err)
+		}
+		return s
+	}
+	owner := add("owner")
+	admin := add("admin")
+	mod := add("mod")
+	user := add("user")
+	if err := r.SetRole(admin.UserID, RoleAdmin); err != nil {
+		t.Fatalf("set admin: %v", err)
+	}
+	if err := r.SetRole(mod.UserID, RoleModerator); err != nil {
+		t.Fatalf("set moderator: %v", err)
+	}
+	r.SetRemoteIP(user.UserID, "10.0.0.9")
+
+	for _, tc := range []struct{ actor, target *Session }{
+		{mod, user},    // moderators cannot ban
+		{admin, owner}, // nobody outranks the owner
+		{admin, admin}, // nor themselves
+		{user, mod},    // regular users cannot ban
+	} {
+		if _, err := r.CheckBan(tc.actor.UserID, tc.target.UserID); !errors.Is(err, ErrNotPermitted) {
+			t.Errorf("%s banning %s: expected ErrNotPermitted, got %v", tc.actor.Username, tc.target.Username, err)
+		}
+		if _, err := r.Ban(tc.actor.UserID, tc.target.UserID, ""); !errors.Is(err, ErrNotPermitted) {
+			t.Errorf("%s banning %s: expected ErrNotPermitted, got %v", tc.actor.Username, tc.target.Username, err)
+		}
+	}
+
+	info, err := r.CheckBan(admin.UserID, user.UserID)
+	if err != nil {
+		t.Fatalf("check ban: %v", err)
+	}
+	if info.IP != "10.0.0.9" || info.ActorName != "admin" || info.User.ID != user.UserID {
+		t.Fatalf("unexpected ban info: %+v", info)
+	}
+
+	if _, err := r.Ban(admin.UserID, user.UserID, "spam"); err != nil {
+		t.Fatalf("ban: %v", err)
+	}
+	kicked, ok := <-user.Send
+	if !ok || kicked.Type != protocol.TypeKicked || kicked.Message != "spam" {
+		t.Fatalf("expected kicked message, got %+v (ok=%v)", kicked, ok)
+	}
+	if _, ok := <-user.Send; ok {
+		t.Fatal("expected the banned user's send channel to be closed")
+	}
+	if _, ok := r.User(user.UserID); ok {
+		t.Fatal("banned user should be removed")
+	}
+	if got := r.Counters().Bans; got != 1 {
+		t.Fatalf("expected 1 ban counted, got %d", got)
+	}
+}
+
+func TestKickRequiresAdminAboveTargetAndIsNotABan(t *testing.T) {
+	r := NewChannelState("")
+	add := func(name string) *Session {
+		s, _, err := r.Add(name, 8)
+		if err != nil {
+			t.Fatalf("add %s: %v", name, err)
+		}
+		return s
+	}
+	add("owner")
+	admin := add("admin")
+	mod := add("mod")
+	user := add("user")
+	if err := r.SetRole(admin.UserID, RoleAdmin); err != nil {
+		t.Fatalf("set admin: %v", err)
+	}
+	if err := r.SetRole(mod.UserID, RoleModerator); err != nil {
+		t.Fatalf("set moderator: %v", err)
+	}
+
+	if _, err := r.Kick(mod.UserID, user.UserID, ""); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("moderator kicking: expected ErrNotPermitted, got %v", err)
+	}
+
+	info, err := r.Kick(admin.UserID, user.UserID, "take a break")
+	if err != nil {
+		t.Fatalf("kick: %v", err)
+	}
+	if info.ActorName != "admin" || info.User.ID != user.UserID {
+		t.Fatalf("unexpected kick info: %+v", info)
+	}
+	kicked, ok := <-user.Send
+	if !ok || kicked.Type != protocol.TypeKicked || kicked.Message != "take a break" {
+		t.Fatalf("expected kicked message, got %+v (ok=%v)", kicked, ok)
+	}
+	if _, ok := <-user.Send; ok {
+		t.Fatal("expected the kicked user's send channel to be closed")
+	}
+	if _, ok := r.User(user.UserID); ok {
+		t.Fatal("kicked user should be removed")
+	}
+	if got := r.Counters().Bans; got != 0 {
+		t.Fatalf("a kick must not count as a ban, got %d", got)
+	}
+}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// byteRateWindow is the window SetPerClientByteRate counts over.
+const byteRateWindow = time.Second
+
+// outMeter counts the bytes written to one client and tracks the current
+// window for SetPerClientByteRate. Each client has its own, so pacing one
+// client's writes never takes the ChannelState lock.
+type outMeter struct {
+	total atomic.Uint64
+
+	mu          sync.Mutex
+	window      time.Time
+	windowBytes int
+}
+
+// SetPerClientByteRate caps how many payload bytes per second are written
+// to any one client, so a burst of traffic to one session cannot hog the
+// server's uplink. Messages over the cap wait for the next window; while
+// they wait the client's send queue fills, and further messages are
+// skipped as for any slow client. Zero (the default) disables the cap.
+func (r *ChannelState) SetPerClientByteRate(bytesPerSec int) error {
+	if bytesPerSec < 0 {
+		return fmt.Errorf("per-client byte rate must not be negative")
+	}
+	r.clientByteRate.Store(int64(bytesPerSec))
+	return nil
+}
+
+// PerClientByteRate returns the per-client byte rate cap (0 = none).
+func (r *ChannelState) PerClientByteRate() int {
+	return int(r.clientByteRate.Load())
+}
+
+// ThrottleBytesOut records that n bytes are about to be written to the
+// session's client and returns how long the writer must wait first to stay
+// within the per-client byte rate. A message is never held back for being
+// larger than the cap on its own, only for not fitting in what is left of
+// the window.
+func (s *Session) ThrottleBytesOut(n int) time.Duration {
+	s.out.total.Add(uint64(n))
+	limit := int(s.state.clientByteRate.Load())
+	if limit <= 0 {
+		return 0
+	}
+
+	m := s.out
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := s.state.now()
+	if now.Sub(m.window) >= byteRateWindow {
+		m.window, m.windowBytes = now, 0
+	}
+	if m.windowBytes == 0 || m.windowBytes+n <= limit {
+		m.windowBytes += n
+		return 0
+	}
+	// The message opens the next window once this one ends.
+	next := m.window.Add(byteRateWindow)
+	m.window, m.windowBytes = next, n
+	slog.Debug("client byte rate exceeded", "user_id", s.UserID, "limit_bytes_per_sec", limit)
+	return next.Sub(now)
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPerClientByteRateHoldsBackUntilWindowResets(t *testing.T) {
+	r := NewChannelState("")
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+	if err := r.SetPerClientByteRate(100); err != nil {
+		t.Fatalf("set byte rate: %v", err)
+	}
+	alice, _, _ := r.Add("alice", 8)
+	bob, _, _ := r.Add("bob", 8)
+
+	if wait := alice.ThrottleBytesOut(60); wait != 0 {
+		t.Fatalf("first message waited %v", wait)
+	}
+	now = now.Add(200 * time.Millisecond)
+	if wait := alice.ThrottleBytesOut(60); wait != 800*time.Millisecond {
+		t.Fatalf("message over the cap waited %v, want until the window resets", wait)
+	}
+	if wait := bob.ThrottleBytesOut(60); wait != 0 {
+		t.Fatalf("another client waited %v", wait)
+	}
+
+	// The held message opened the next window, which has 40 bytes left.
+	now = time.Unix(1001, 0)
+	if wait := alice.ThrottleBytesOut(40); wait != 0 {
+		t.Fatalf("message within the next window waited %v", wait)
+	}
+	if wait := alice.ThrottleBytesOut(1); wait != time.Second {
+		t.Fatalf("message over the cap waited %v, want a full window", wait)
+	}
+
+	// A message larger than the cap is sent on its own in a fresh window.
+	now = time.Unix(1010, 0)
+	if wait := bob.ThrottleBytesOut(500); wait != 0 {
+		t.Fatalf("oversized message waited %v", wait)
+	}
+
+	infos := r.ClientInfos()
+	totals := map[string]uint64{}
+	for _, c := range infos {
+		totals[c.UserID] = c.BytesOut
+	}
+	if totals[alice.UserID] != 161 || totals[bob.UserID] != 560 {
+		t.Fatalf("bytes out = %v, want alice 161 and bob 560", totals)
+	}
+}
+
+func TestPerClientByteRateDisabledByDefault(t *testing.T) {
+	r := NewChannelState("")
+	alice, _, _ := r.Add("alice", 8)
+	for range 100 {
+		if wait := alice.ThrottleBytesOut(1 << 20); wait != 0 {
+			t.Fatalf("waited %v with no cap", wait)
+		}
+	}
+	if err := r.SetPerClientByteRate(-1); err == nil {
+		t.Fatal("expected error for a negative rate")
+	}
+}
+
+func TestThrottleBytesOutDoesNotTakeStateLock(t *testing.T) {
+	r := NewChannelState("")
+	if err := r.SetPerClientByteRate(100); err != nil {
+		t.Fatalf("set byte rate: %v", err)
+	}
+	alice, _, _ := r.Add("alice", 8)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		alice.ThrottleBytesOut(10)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ThrottleBytesOut blocked on the ChannelState lock")
+	}
+}
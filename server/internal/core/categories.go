@@ -0,0 +1,76 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"bken/server/internal/protocol"
+)
+
+// CreateCategory adds a named channel category to a server and returns the
+// updated category list.
+func (r *ChannelState) CreateCategory(serverID, name string) ([]protocol.Category, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("category name is required")
+	}
+	serverID = strings.TrimSpace(serverID)
+	if serverID == "" {
+		return nil, fmt.Errorf("server_id is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := r.nextCatID.Add(1)
+	r.categories[serverID] = append(r.categories[serverID], protocol.Category{ID: id, Name: name})
+	out := make([]protocol.Category, len(r.categories[serverID]))
+	copy(out, r.categories[serverID])
+
+	slog.Info("category created", "server_id", serverID, "category_id", id, "name", name)
+	return out, nil
+}
+
+// AssignChannelCategory moves a channel into a category and returns the
+// updated channel list. categoryID = 0 makes the channel uncategorized.
+func (r *ChannelState) AssignChannelCategory(serverID string, channelID, categoryID int64) ([]protocol.Channel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var categoryName string
+	if categoryID != 0 {
+		found := false
+		for _, c := range r.categories[serverID] {
+			if c.ID == categoryID {
+				categoryName, found = c.Name, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("category not found")
+		}
+	}
+
+	chs := r.channels[serverID]
+	for i := range chs {
+		if chs[i].ID == channelID {
+			chs[i].CategoryID = categoryID
+			chs[i].CategoryName = categoryName
+			out := make([]protocol.Channel, len(chs))
+			copy(out, chs)
+			slog.Info("channel category set", "server_id", serverID, "channel_id", channelID, "category_id", categoryID)
+			return out, nil
+		}
+	}
+	return nil, fmt.Errorf("channel not found")
+}
+
+// Categories returns the channel categories for a server.
+func (r *ChannelState) Categories(serverID string) []protocol.Category {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]protocol.Category, len(r.categories[serverID]))
+	copy(out, r.categories[serverID])
+	return out
+}
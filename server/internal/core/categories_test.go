@@ -0,0 +1,49 @@
+package core
+
+import "testing"
+
+func TestCategoryLifecycle(t *testing.T) {
+	r := NewChannelState("")
+	chs, err := r.CreateChannel("srv-1", "lobby")
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	chID := chs[0].ID
+
+	if _, err := r.CreateCategory("srv-1", "  "); err == nil {
+		t.Fatal("expected error for empty category name")
+	}
+	cats, err := r.CreateCategory("srv-1", " Voice ")
+	if err != nil {
+		t.Fatalf("create category: %v", err)
+	}
+	if len(cats) != 1 || cats[0].Name != "Voice" {
+		t.Fatalf("expected one trimmed category, got %#v", cats)
+	}
+	if got := r.Categories("srv-2"); len(got) != 0 {
+		t.Fatalf("categories leaked to another server: %#v", got)
+	}
+
+	chs, err = r.AssignChannelCategory("srv-1", chID, cats[0].ID)
+	if err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	if chs[0].CategoryID != cats[0].ID || chs[0].CategoryName != "Voice" {
+		t.Fatalf("channel not moved into category: %#v", chs[0])
+	}
+
+	chs, err = r.AssignChannelCategory("srv-1", chID, 0)
+	if err != nil {
+		t.Fatalf("uncategorize: %v", err)
+	}
+	if chs[0].CategoryID != 0 || chs[0].CategoryName != "" {
+		t.Fatalf("expected uncategorized channel, got %#v", chs[0])
+	}
+
+	if _, err := r.AssignChannelCategory("srv-1", chID, cats[0].ID+1); err == nil {
+		t.Error("expected error for unknown category")
+	}
+	if _, err := r.AssignChannelCategory("srv-2", chID, 0); err == nil {
+		t.Error("expected error for channel on another server")
+	}
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"bken/server/internal/protocol"
+)
+
+// validMinRole reports whether role may be used as a channel permission
+// floor. Empty means the channel is open to everyone.
+func validMinRole(role string) bool {
+	switch role {
+	case "", RoleUser, RoleModerator, RoleAdmin, RoleOwner:
+		return true
+	}
+	return false
+}
+
+// SetChannelPerms sets the minimum roles needed to speak and to chat in a
+// channel. Users already in the channel's voice who no longer meet the
+// speak floor are muted; they are returned so the caller can broadcast
+// their new state along with the updated channel list.
+func (r *ChannelState) SetChannelPerms(serverID string, channelID int64, minSpeak, minChat string) ([]protocol.Channel, []protocol.User, error) {
+	if !validMinRole(minSpeak) {
+		return nil, nil, fmt.Errorf("invalid role %q", minSpeak)
+	}
+	if !validMinRole(minChat) {
+		return nil, nil, fmt.Errorf("invalid role %q", minChat)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	chs := r.channels[serverID]
+	idx := -1
+	for i := range chs {
+		if chs[i].ID == channelID {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return nil, nil, fmt.Errorf("channel not found")
+	}
+	chs[idx].MinRoleToSpeak = minSpeak
+	chs[idx].MinRoleToChat = minChat
+
+	wireID := strconv.FormatInt(channelID, 10)
+	var muted []protocol.User
+	for _, u := range r.users {
+		if u.voice == nil || u.voice.ServerID != serverID || u.voice.ChannelID != wireID || u.muted {
+			continue
+		}
+		if !r.meetsLocked(u, minSpeak) {
+			u.muted = true
+			muted = append(muted, toProtocolUser(u))
+		}
+	}
+
+	out := make([]protocol.Channel, len(chs))
+	copy(out, chs)
+	slog.Info("channel perms set", "server_id", serverID, "channel_id", channelID, "min_speak", minSpeak, "min_chat", minChat, "muted", len(muted))
+	return out, muted, nil
+}
+
+// SetChannelRecordRole sets the minimum role needed to record a channel's
+// voice and returns the updated list. Empty lets everyone record.
+func (r *ChannelState) SetChannelRecordRole(serverID string, channelID int64, role string) ([]protocol.Channel, error) {
+	if !validMinRole(role) {
+		return nil, fmt.Errorf("invalid role %q", role)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	chs := r.channels[serverID]
+	for i := range chs {
+		if chs[i].ID == channelID {
+			chs[i].MinRoleToRecord = role
+			out := make([]protocol.Channel, len(chs))
+			copy(out, chs)
+			slog.Info("channel record role set", "server_id", serverID, "channel_id", channelID, "min_record", role)
+			return out, nil
+		}
+	}
+	return nil, fmt.Errorf("channel not found")
+}
+
+// CanRecord reports whether userID may record their current voice channel,
+// and otherwise the role required. Users not in voice may record, as there
+// is no one else to hear.
+func (r *ChannelState) CanRecord(userID string) (bool, string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	u, ok := r.users[userID]
+	if !ok || u.voice == nil {
+		return true, ""
+	}
+	ch, ok := r.channelLocked(u.voice.ServerID, u.voice.ChannelID)
+	if !ok || r.meetsLocked(u, ch.MinRoleToRecord) {
+		return true, ""
+	}
+	return false, ch.MinRoleToRecord
+}
+
+// CanChat reports whether userID may send text to a channel. Channels that
+// are unknown or have no chat floor are open to everyone.
+func (r *ChannelState) CanChat(userID, serverID, channelID string) (bool, string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	u, ok := r.users[userID]
+	if !ok {
+		return false, ""
+	}
+	ch, ok := r.channelLocked(serverID, channelID)
+	if !ok || r.meetsLocked(u, ch.MinRoleToChat) {
+		return true, ""
+	}
+	return false, ch.MinRoleToChat
+}
+
+// CanSpeak reports whether userID may transmit in their current voice
+// channel, and otherwise the role required. Users not in voice may speak.
+func (r *ChannelState) CanSpeak(userID string) (bool, string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	u, ok := r.users[userID]
+	if !ok || u.voice == nil {
+		return true, ""
+	}
+	return r.canSpeakLocked(u)
+}
+
+func (r *ChannelState) canSpeakLocked(u *userState) (bool, string) {
+	ch, ok := r.channelLocked(u.voice.ServerID, u.voice.ChannelID)
+	if !ok || r.meetsLocked(u, ch.MinRoleToSpeak) {
+		return true, ""
+	}
+	return false, ch.MinRoleToSpeak
+}
+
+// meetsLocked reports whether u's role is at least minRole. Caller holds r.mu.
+func (r *ChannelState) meetsLocked(u *userState, minRole string) bool {
+	return minRole == "" || RoleLevel(r.roleLocked(u)) >= RoleLevel(minRole)
+}
+
+// channelLocked finds a channel by its wire ID. Caller holds r.mu.
+func (r *ChannelState) channelLocked(serverID, channelID string) (protocol.Channel, bool) {
+	id, err := strconv.ParseInt(channelID, 10, 64)
+	if err != nil {
+		return protocol.Channel{}, false
+	}
+	for _, ch := range r.channels[serverID] {
+		if ch.ID == id {
+			return ch, true
+		}
+	}
+	return protocol.Channel{}, false
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestChannelPermsRoleBoundaries(t *testing.T) {
+	levels := []string{RoleUser, RoleModerator, RoleAdmin, RoleOwner}
+	for _, minRole := range levels {
+		t.Run(minRole, func(t *testing.T) {
+			r := NewChannelState("")
+			ids := make(map[string]string, len(levels))
+			// The first user to join owns the server, so add from the top down.
+			for i := len(levels) - 1; i >= 0; i-- {
+				role := levels[i]
+				s, _, _ := r.Add(role, 8)
+				if _, _, err := r.ConnectServer(s.UserID, "srv-1"); err != nil {
+					t.Fatalf("connect: %v", err)
+				}
+				if role != RoleUser && role != RoleOwner {
+					if err := r.SetRole(s.UserID, role); err != nil {
+						t.Fatalf("set role: %v", err)
+					}
+				}
+				ids[role] = s.UserID
+			}
+			chs, _ := r.CreateChannel("srv-1", "stage")
+			chID := chs[0].ID
+			wireID := strconv.FormatInt(chID, 10)
+			if _, _, err := r.SetChannelPerms("srv-1", chID, minRole, minRole); err != nil {
+				t.Fatalf("set perms: %v", err)
+			}
+
+			for _, role := range levels {
+				want := RoleLevel(role) >= RoleLevel(minRole)
+				if ok, _ := r.CanChat(ids[role], "srv-1", wireID); ok != want {
+					t.Errorf("%s CanChat = %v, want %v", role, ok, want)
+				}
+				u, _, err := r.JoinVoice(ids[role], "srv-1", wireID)
+				if err != nil {
+					t.Fatalf("join voice: %v", err)
+				}
+				if ok, _ := r.CanSpeak(ids[role]); ok != want {
+					t.Errorf("%s CanSpeak = %v, want %v", role, ok, want)
+				}
+				if u.Voice.Muted == want {
+					t.Errorf("%s joined muted = %v, want %v", role, u.Voice.Muted, !want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetChannelPermsMutesVoiceUsersBelowFloor(t *testing.T) {
+	r := NewChannelState("")
+	owner, _, _ := r.Add("owner", 8)
+	bob, _, _ := r.Add("bob", 8)
+	chs, _ := r.CreateChannel("srv-1", "stage")
+	wireID := strconv.FormatInt(chs[0].ID, 10)
+	for _, id := range []string{owner.UserID, bob.UserID} {
+		if _, _, err := r.ConnectServer(id, "srv-1"); err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		if _, _, err := r.JoinVoice(id, "srv-1", wireID); err != nil {
+			t.Fatalf("join voice: %v", err)
+		}
+	}
+
+	out, muted, err := r.SetChannelPerms("srv-1", chs[0].ID, RoleModerator, "")
+	if err != nil {
+		t.Fatalf("set perms: %v", err)
+	}
+	if out[0].MinRoleToSpeak != RoleModerator || out[0].MinRoleToChat != "" {
+		t.Fatalf("unexpected channel perms: %+v", out[0])
+	}
+	if len(muted) != 1 || muted[0].ID != bob.UserID || !muted[0].Voice.Muted {
+		t.Fatalf("expected only bob muted, got %+v", muted)
+	}
+	if ok, minRole := r.CanSpeak(bob.UserID); ok || minRole != RoleModerator {
+		t.Fatalf("bob CanSpeak = %v, %q", ok, minRole)
+	}
+	// Chat stays open because only the speak floor was set.
+	if ok, _ := r.CanChat(bob.UserID, "srv-1", wireID); !ok {
+		t.Fatal("bob should still be able to chat")
+	}
+
+	// Clearing the floor lets bob speak again.
+	if _, _, err := r.SetChannelPerms("srv-1", chs[0].ID, "", ""); err != nil {
+		t.Fatalf("clear perms: %v", err)
+	}
+	if ok, _ := r.CanSpeak(bob.UserID); !ok {
+		t.Fatal("bob should be able to speak after perms are cleared")
+	}
+}
+
+func TestSetChannelPermsRejectsBadInput(t *testing.T) {
+	r := NewChannelState("")
+	chs, _ := r.CreateChannel("srv-1", "stage")
+	if _, _, err := r.SetChannelPerms("srv-1", chs[0].ID, "superuser", ""); err == nil {
+		t.Fatal("expected error for unknown speak role")
+	}
+	if _, _, err := r.SetChannelPerms("srv-1", chs[0].ID, "", "superuser"); err == nil {
+		t.Fatal("expected error for unknown chat role")
+	}
+	if _, _, err := r.SetChannelPerms("srv-1", 999, RoleUser, ""); err == nil {
+		t.Fatal("expected error for unknown channel")
+	}
+}
+
+func TestCanRecordFollowsChannelFloor(t *testing.T) {
+	r := NewChannelState("")
+	owner, _, _ := r.Add("owner", 8)
+	mod, _, _ := r.Add("mod", 8)
+	for _, id := range []string{owner.UserID, mod.UserID} {
+		if _, _, err := r.ConnectServer(id, "srv-1"); err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+	}
+	if err := r.SetRole(mod.UserID, RoleModerator); err != nil {
+		t.Fatalf("set role: %v", err)
+	}
+	if ok, _ := r.CanRecord(mod.UserID); !ok {
+		t.Fatal("users not in voice should be able to record")
+	}
+
+	chs, _ := r.CreateChannel("srv-1", "stage")
+	if chs[len(chs)-1].MinRoleToRecord != RoleOwner {
+		t.Fatalf("new channel record role = %q, want %q", chs[len(chs)-1].MinRoleToRecord, RoleOwner)
+	}
+	chID := chs[len(chs)-1].ID
+	for _, id := range []string{owner.UserID, mod.UserID} {
+		if _, _, err := r.JoinVoice(id, "srv-1", strconv.FormatInt(chID, 10)); err != nil {
+			t.Fatalf("join voice: %v", err)
+		}
+	}
+	if ok, minRole := r.CanRecord(mod.UserID); ok || minRole != RoleOwner {
+		t.Fatalf("moderator CanRecord in owner channel = %v, %q", ok, minRole)
+	}
+	if ok, _ := r.CanRecord(owner.UserID); !ok {
+		t.Fatal("owner should be able to record")
+	}
+
+	if _, err := r.SetChannelRecordRole("srv-1", chID, RoleModerator); err != nil {
+		t.Fatalf("set record role: %v", err)
+	}
+	if ok, _ := r.CanRecord(mod.UserID); !ok {
+		t.Fatal("moderator should be able to record in a moderator channel")
+	}
+
+	if _, err := r.SetChannelRecordRole("srv-1", chID, "superuser"); err == nil {
+		t.Fatal("expected error for unknown role")
+	}
+	if _, err := r.SetChannelRecordRole("srv-1", 999, RoleUser); err == nil {
+		t.Fatal("expected error for unknown channel")
+	}
+}
@@ -1,8 +1,10 @@
 package core
 
 import (
+	"crypto/rand"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -15,10 +17,45 @@ import (
 // SendTimeout bounds how long a write to one subscriber may block.
 const SendTimeout = 50 * time.Millisecond
 
+// Username collision policies applied by Add when the requested name is
+// already held by a connected user.
+const (
+	// UsernamePolicyAllow admits duplicates unchanged.
+	UsernamePolicyAllow = "allow"
+	// UsernamePolicyReplace disconnects the existing session(s) with the name.
+	UsernamePolicyReplace = "replace"
+	// UsernamePolicyReject refuses the new session. Names differing only
+	// in case count as taken.
+	UsernamePolicyReject = "reject"
+	// UsernamePolicySuffix admits the new session as "name(N)" with the
+	// lowest free N starting at 2, comparing names case-insensitively.
+	UsernamePolicySuffix = "suffix"
+)
+
+// DefaultMaxUploadBytes is the upload size limit used until
+// SetMaxUploadBytes is called. Clients assume it when a server does not
+// advertise a limit.
+const DefaultMaxUploadBytes int64 = 10 * 1024 * 1024
+
+// DefaultMaxMessageLength is the chat message length limit, in bytes, used
+// until SetMaxMessageLength is called. Clients assume it when a server does
+// not advertise a limit.
+const DefaultMaxMessageLength = 500
+
 // Session represents one connected websocket session.
 type Session struct {
 	UserID string
-	Send   chan protocol.Message
+	// Username is the name actually assigned, which may differ from the
+	// requested one under UsernamePolicySuffix.
+	Username string
+	// Token authenticates REST calls made on behalf of this session; see
+	// UserByToken.
+	Token string
+	Send  chan protocol.Message
+
+	// out and state back ThrottleBytesOut.
+	out   *outMeter
+	state *ChannelState
 }
 
 type userState struct {
@@ -29,6 +66,54 @@ type userState struct {
 	send      chan protocol.Message
 	muted     bool
 	deafened  bool
+	client    protocol.ClientInfo
+	remoteIP  string
+	token     string
+	// lastJoin is when the user last joined a voice channel; see
+	// SetChannelSwitchCooldown.
+	lastJoin time.Time
+	// recordingIn is the "server/channel" of the voice channel the user
+	// is recording locally, and consentIn the one whose recordings they
+	// have accepted. Both are cleared whenever the user's voice channel
+	// changes. See StartRecording and ConsentToRecording.
+	recordingIn string
+	consentIn   string
+	// lastVoice is when the user last joined voice or reported voice
+	// activity; see SetIdleTimeout.
+	lastVoice time.Time
+	// lastSoundboard is when the user last triggered a soundboard clip;
+	// see PlaySoundboard.
+	lastSoundboard time.Time
+	// typingIn and lastTyping are the "server/channel" the user last typed
+	// in and when; see MarkTyping.
+	typingIn   string
+	lastTyping time.Time
+	// lastChat is when the user last sent a message, by "server/channel";
+	// see CheckSlowMode.
+	lastChat map[string]time.Time
+	// serverMuted is set by a moderator's MuteUser and keeps the user muted
+	// in voice until serverMuteUntil, or indefinitely when that is zero.
+	serverMuted     bool
+	serverMuteUntil time.Time
+	// selfMuted is whether the user had muted themselves when the server
+	// mute began; it is restored when the mute ends.
+	selfMuted bool
+	// out counts and paces what is written to the user's websocket; see
+	// Session.ThrottleBytesOut.
+	out *outMeter
+	// role is the assigned role; "" means RoleUser. The owner is tracked
+	// separately in ChannelState.ownerID.
+	role string
+	// status is the presence set with SetStatus; "" means online.
+	status string
+	// speakingIn is the "server/channel" of the voice channel the user is
+	// speaking in, or ""; lastSpeaking is their last speaking start. See
+	// SetSpeaking.
+	speakingIn   string
+	lastSpeaking time.Time
+	// reactions counts recent reaction changes by message ID; see
+	// CheckReactionRate.
+	reactions map[int64]reactionWindow
 }
 
 // ChannelState is the global in-memory presence state.
@@ -40,7 +125,57 @@ type ChannelState struct {
 	nextID     atomic.Uint64
 	channels   map[string][]protocol.Channel // serverID → channels
 	nextChID   atomic.Int64
+	categories map[string][]protocol.Category // serverID → categories
+	nextCatID  atomic.Int64
 	serverName string
+
+	usernamePolicy string        // guarded by mu
+	ownerID        string        // guarded by mu; "" only while empty
+	switchCooldown time.Duration // guarded by mu
+	idleTimeout    time.Duration // guarded by mu
+	maxUploadBytes int64         // guarded by mu
+	maxMessageLen  int           // guarded by mu; see SetMaxMessageLength
+	announcement   string        // guarded by mu; see SetAnnouncement
+	announcementBy string        // guarded by mu; username that posted it
+	motd           string        // guarded by mu; see SetMOTD
+	joinSoundURL   string        // guarded by mu; see SetJoinSounds
+	leaveSoundURL  string        // guarded by mu; see SetJoinSounds
+	allowedEmoji   []string      // guarded by mu; see SetAllowedEmoji
+	drained        chan struct{} // guarded by mu; non-nil once Drain is called
+	connectRate    int           // guarded by mu; see SetConnectRate
+	now            func() time.Time
+
+	// clientByteRate is read by every client's writer, so it is kept out
+	// of mu; see SetPerClientByteRate.
+	clientByteRate atomic.Int64
+
+	// recordingConsent is guarded by mu; see SetRecordingConsent.
+	recordingConsent bool
+
+	// Chat word filter; see SetWordFilter. Guarded by mu.
+	wordFilter       map[string]bool
+	wordFilterAction string
+
+	// Recent connection times per IP, oldest first, and when stale IPs
+	// were last swept out. Guarded by mu.
+	connectTimes map[string][]time.Time
+	connectSweep time.Time
+
+	// ICE servers for new peer connections; see SetICEServers. Guarded by mu.
+	iceServers []protocol.ICEServer
+	channelICE map[int64][]protocol.ICEServer // per-channel overrides
+
+	// Read receipts per message, oldest first in receiptOrder so the map
+	// stays bounded; see MarkRead. Guarded by mu.
+	receipts     map[int64][]string
+	receiptOrder []int64
+
+	// Monotonic traffic counters; see Counters.
+	messagesSent    atomic.Uint64
+	messagesSkipped atomic.Uint64
+	bytesIn         atomic.Uint64
+	bytesOut        atomic.Uint64
+	bans            atomic.Uint64
 }
 
 // NewChannelState returns an empty channel state with the given server name.
@@ -49,17 +184,75 @@ func NewChannelState(serverName string) *ChannelState {
 		serverName = "bken server"
 	}
 	return &ChannelState{
-		users:      make(map[string]*userState),
-		channels:   make(map[string][]protocol.Channel),
-		serverName: serverName,
+		users:          make(map[string]*userState),
+		channels:       make(map[string][]protocol.Channel),
+		categories:     make(map[string][]protocol.Category),
+		serverName:     serverName,
+		usernamePolicy: UsernamePolicyAllow,
+		maxUploadBytes: DefaultMaxUploadBytes,
+		maxMessageLen:  DefaultMaxMessageLength,
+		now:            time.Now,
 	}
 }
 
+// SetUsernamePolicy selects how Add handles a username that is already in
+// use. It returns an error for an unknown policy.
+func (r *ChannelState) SetUsernamePolicy(policy string) error {
+	switch policy {
+	case UsernamePolicyAllow, UsernamePolicyReplace, UsernamePolicyReject, UsernamePolicySuffix:
+	default:
+		return fmt.Errorf("unknown username collision policy %q", policy)
+	}
+	r.mu.Lock()
+	r.usernamePolicy = policy
+	r.mu.Unlock()
+	return nil
+}
+
 // ServerName returns the configured server display name.
 func (r *ChannelState) ServerName() string {
 	return r.serverName
 }
 
+// SetMaxUploadBytes sets the largest file the server accepts for upload.
+// The limit is enforced by the HTTP API and advertised in the snapshot.
+func (r *ChannelState) SetMaxUploadBytes(n int64) error {
+	if n <= 0 {
+		return fmt.Errorf("max upload size must be positive")
+	}
+	r.mu.Lock()
+	r.maxUploadBytes = n
+	r.mu.Unlock()
+	return nil
+}
+
+// MaxUploadBytes returns the configured upload size limit.
+func (r *ChannelState) MaxUploadBytes() int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.maxUploadBytes
+}
+
+// SetMaxMessageLength sets the longest chat message, in bytes, that the
+// server relays. It covers channel messages, edits and DMs, and is
+// advertised in the snapshot.
+func (r *ChannelState) SetMaxMessageLength(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("max message length must be positive")
+	}
+	r.mu.Lock()
+	r.maxMessageLen = n
+	r.mu.Unlock()
+	return nil
+}
+
+// MaxMessageLength returns the configured chat message length limit.
+func (r *ChannelState) MaxMessageLength() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.maxMessageLen
+}
+
 // Add registers a new user session and returns the session plus full snapshot.
 func (r *ChannelState) Add(username string, sendBuf int) (*Session, []protocol.User, error) {
 	username = strings.TrimSpace(username)
@@ -70,41 +263,183 @@ func (r *ChannelState) Add(username string, sendBuf int) (*Session, []protocol.U
 		sendBuf = 64
 	}
 
+	r.mu.Lock()
+	if r.drained != nil {
+		r.mu.Unlock()
+		return nil, nil, fmt.Errorf("server is shutting down")
+	}
+	requested := username
+	var replaced []*userState
+	switch r.usernamePolicy {
+	case UsernamePolicyReject:
+		if r.usernameTakenLocked(username, "") {
+			r.mu.Unlock()
+			return nil, nil, fmt.Errorf("username %q is already in use", username)
+		}
+	case UsernamePolicySuffix:
+		for n := 2; r.usernameTakenLocked(username, ""); n++ {
+			username = fmt.Sprintf("%s(%d)", requested, n)
+		}
+	case UsernamePolicyReplace:
+		for uid, existing := range r.users {
+			if strings.EqualFold(existing.username, username) {
+				delete(r.users, uid)
+				replaced = append(replaced, existing)
+			}
+		}
+	}
+
 	id := fmt.Sprintf("u%d", r.nextID.Add(1))
 	u := &userState{
 		id:        id,
 		username:  username,
 		connected: make(map[string]struct{}),
 		send:      make(chan protocol.Message, sendBuf),
+		token:     rand.Text(),
+		out:       &outMeter{},
 	}
-
-	r.mu.Lock()
 	r.users[id] = u
+	// The first user owns the server. A session that replaces the owner
+	// under UsernamePolicyReplace is the same person, so it keeps ownership.
+	ownerReplaced := false
+	if _, ok := r.users[r.ownerID]; !ok {
+		ownerReplaced = r.ownerID != ""
+		r.ownerID = id
+	}
 	snapshot := r.snapshotLocked()
 	count := len(r.users)
 	r.mu.Unlock()
 
-	slog.Info("user added", "user_id", id, "username", username, "total_users", count)
-	return &Session{UserID: id, Send: u.send}, snapshot, nil
+	for _, old := range replaced {
+		r.evictReplaced(old)
+	}
+	if ownerReplaced {
+		r.announceOwner(id)
+	}
+
+	slog.Info("user added", "user_id", id, "username", username, "requested", requested, "total_users", count)
+	return &Session{UserID: id, Username: username, Token: u.token, Send: u.send, out: u.out, state: r}, snapshot, nil
 }
 
-// Remove unregisters a user session.
-func (r *ChannelState) Remove(userID string) (protocol.User, bool) {
+// usernameTakenLocked reports whether a user other than exceptID holds
+// username, ignoring case.
+func (r *ChannelState) usernameTakenLocked(username, exceptID string) bool {
+	for _, u := range r.users {
+		if u.id != exceptID && strings.EqualFold(u.username, username) {
+			return true
+		}
+	}
+	return false
+}
+
+// RenameUser changes userID's username and reports whether it changed, so
+// the caller knows to broadcast user_renamed. The collision policy applies
+// as in Add, except that a rename never displaces another session: under
+// UsernamePolicyReplace a name held by someone else is refused, as under
+// UsernamePolicyReject.
+func (r *ChannelState) RenameUser(userID, username string) (protocol.User, bool, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return protocol.User{}, false, fmt.Errorf("username is required")
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	u, ok := r.users[userID]
+	if !ok {
+		return protocol.User{}, false, fmt.Errorf("user not found")
+	}
+	requested := username
+	switch r.usernamePolicy {
+	case UsernamePolicyReject, UsernamePolicyReplace:
+		if r.usernameTakenLocked(username, userID) {
+			return protocol.User{}, false, fmt.Errorf("username %q is already in use", username)
+		}
+	case UsernamePolicySuffix:
+		for n := 2; r.usernameTakenLocked(username, userID); n++ {
+			username = fmt.Sprintf("%s(%d)", requested, n)
+		}
+	}
+	if u.username == username {
+		return toProtocolUser(u), false, nil
+	}
+	u.username = username
+	slog.Info("user renamed", "user_id", userID, "username", username, "requested", requested)
+	return toProtocolUser(u), true, nil
+}
 
+// evictReplaced tells a session displaced by UsernamePolicyReplace why it is
+// going away, closes its send channel (which ends its websocket), and
+// announces its departure. The user must already be removed from r.users.
+func (r *ChannelState) evictReplaced(u *userState) {
+	r.deliver(u.send, protocol.Message{Type: protocol.TypeError, Error: "signed in from another session"})
+	close(u.send)
+	left := toProtocolUser(u)
+	slog.Info("user replaced", "user_id", u.id, "username", u.username)
+	r.Broadcast(protocol.Message{Type: protocol.TypeUserLeft, User: &left}, "")
+}
+
+// Remove unregisters a user session. If the owner leaves, ownership passes
+// to the lowest remaining user ID and owner_changed is broadcast.
+func (r *ChannelState) Remove(userID string) (protocol.User, bool) {
+	r.mu.Lock()
 	u, ok := r.users[userID]
 	if !ok {
+		r.mu.Unlock()
 		return protocol.User{}, false
 	}
 	hadVoice := u.voice != nil
 	delete(r.users, userID)
 	close(u.send)
+	newOwner := ""
+	if userID == r.ownerID {
+		newOwner = r.transferOwnerLocked()
+	}
+	remaining := len(r.users)
+	r.mu.Unlock()
 
-	slog.Info("user removed", "user_id", userID, "username", u.username, "had_voice", hadVoice, "remaining_users", len(r.users))
+	slog.Info("user removed", "user_id", userID, "username", u.username, "had_voice", hadVoice, "remaining_users", remaining)
+	if newOwner != "" {
+		r.announceOwner(newOwner)
+	}
 	return toProtocolUser(u), true
 }
 
+// SetClientInfo records the build details a user's client reported in hello.
+// Fields are truncated to keep a misbehaving client from bloating state.
+func (r *ChannelState) SetClientInfo(userID string, info protocol.ClientInfo) {
+	info.Commit = truncate(strings.TrimSpace(info.Commit), maxClientInfoLen)
+	info.OS = truncate(strings.TrimSpace(info.OS), maxClientInfoLen)
+	info.Arch = truncate(strings.TrimSpace(info.Arch), maxClientInfoLen)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.users[userID]; ok {
+		u.client = info
+	}
+}
+
+// UserClient pairs a connected user with the client build they reported.
+type UserClient struct {
+	UserID   string              `json:"user_id"`
+	Username string              `json:"username"`
+	Client   protocol.ClientInfo `json:"client"`
+	BytesOut uint64              `json:"bytes_out"`
+}
+
+// ClientInfos returns the reported client build and bytes written of every
+// connected user, ordered by user ID.
+func (r *ChannelState) ClientInfos() []UserClient {
+	r.mu.RLock()
+	out := make([]UserClient, 0, len(r.users))
+	for _, u := range r.users {
+		out = append(out, UserClient{UserID: u.id, Username: u.username, Client: u.client, BytesOut: u.out.total.Load()})
+	}
+	r.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return userSeq(out[i].UserID) < userSeq(out[j].UserID) })
+	return out
+}
+
 // ClientCount returns active websocket session count.
 func (r *ChannelState) ClientCount() int {
 	r.mu.RLock()
@@ -161,7 +496,7 @@ func (r *ChannelState) ConnectServer(userID, serverID string) (protocol.User, bo
 	// server that has no channels yet.
 	if len(r.channels[serverID]) == 0 {
 		id := r.nextChID.Add(1)
-		r.channels[serverID] = []protocol.Channel{{ID: id, Name: "General"}}
+		r.channels[serverID] = []protocol.Channel{{ID: id, Name: "General", MinRoleToRecord: RoleOwner}}
 		slog.Info("default channel created", "server_id", serverID, "channel_id", id)
 	}
 
@@ -196,6 +531,7 @@ func (r *ChannelState) DisconnectServer(userID, serverID string) (protocol.User,
 		u.voice = nil
 		u.muted = false
 		u.deafened = false
+		u.recordingIn, u.consentIn = "", ""
 	}
 
 	slog.Debug("server disconnected", "user_id", userID, "server_id", serverID, "voice_cleared", oldVoice != nil)
@@ -222,12 +558,44 @@ func (r *ChannelState) JoinVoice(userID, serverID, channelID string) (protocol.U
 		return protocol.User{}, nil, fmt.Errorf("user is not connected to server")
 	}
 
+	// Rejoining the channel we are already in is not a switch.
+	rejoin := u.voice != nil && u.voice.ServerID == serverID && u.voice.ChannelID == channelID
+	now := r.now()
+	if !rejoin {
+		if err := r.checkChannelLockLocked(u, serverID, channelID); err != nil {
+			return protocol.User{}, nil, err
+		}
+		if err := r.checkSwitchCooldownLocked(u, now); err != nil {
+			return protocol.User{}, nil, err
+		}
+	}
+
 	var oldVoice *protocol.VoiceState
 	if u.voice != nil {
 		v := *u.voice
 		oldVoice = &v
 	}
 	u.voice = &protocol.VoiceState{ServerID: serverID, ChannelID: channelID}
+	u.recordingIn, u.consentIn = "", ""
+	if oldVoice != nil && oldVoice.ServerID == serverID {
+		// Monitored channels carry over a switch within the server, less
+		// the one now joined.
+		u.voice.Monitoring = slices.DeleteFunc(slices.Clone(oldVoice.Monitoring), func(id string) bool { return id == channelID })
+	}
+	// Joining a channel while it is recorded without the user's consent
+	// leaves them muted; see SetRecordingConsent.
+	if r.awaitingConsentLocked(u) {
+		u.muted = true
+	}
+	u.lastVoice = now
+	// Joining a channel the user may not speak in, or while muted by a
+	// moderator, leaves them muted; see SetChannelPerms and MuteUser.
+	if ok, _ := r.canSpeakLocked(u); !ok || u.serverMuted {
+		u.muted = true
+	}
+	if !rejoin {
+		u.lastJoin = now
+	}
 
 	slog.Info("voice joined", "user_id", userID, "server_id", serverID, "channel_id", channelID, "prev_server", oldVoice)
 	return toProtocolUser(u), oldVoice, nil
@@ -249,6 +617,7 @@ func (r *ChannelState) DisconnectVoice(userID string) (protocol.User, *protocol.
 	u.voice = nil
 	u.muted = false
 	u.deafened = false
+	u.recordingIn, u.consentIn = "", ""
 
 	slog.Info("voice disconnected", "user_id", userID, "was_server", v.ServerID, "was_channel", v.ChannelID)
 	return toProtocolUser(u), &v, true
@@ -287,6 +656,28 @@ func (r *ChannelState) CanSendText(userID, serverID string) bool {
 	return connected
 }
 
+// SharesServer reports whether two users are both connected to at least one
+// common server.
+func (r *ChannelState) SharesServer(userID, otherID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.users[userID]
+	if !ok {
+		return false
+	}
+	other, ok := r.users[otherID]
+	if !ok {
+		return false
+	}
+	for sid := range u.connected {
+		if _, ok := other.connected[sid]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 // CreateChannel adds a named channel to a server and returns the updated list.
 func (r *ChannelState) CreateChannel(serverID, name string) ([]protocol.Channel, error) {
 	name = strings.TrimSpace(name)
@@ -302,7 +693,7 @@ func (r *ChannelState) CreateChannel(serverID, name string) ([]protocol.Channel,
 	defer r.mu.Unlock()
 
 	id := r.nextChID.Add(1)
-	r.channels[serverID] = append(r.channels[serverID], protocol.Channel{ID: id, Name: name})
+	r.channels[serverID] = append(r.channels[serverID], protocol.Channel{ID: id, Name: name, MinRoleToRecord: RoleOwner})
 	out := make([]protocol.Channel, len(r.channels[serverID]))
 	copy(out, r.channels[serverID])
 
@@ -333,6 +724,35 @@ func (r *ChannelState) RenameChannel(serverID string, channelID int64, name stri
 	return nil, fmt.Errorf("channel not found")
 }
 
+// Bounds for a channel bitrate cap, matching the Opus encoder's range.
+const (
+	MinChannelBitrateKbps = 6
+	MaxChannelBitrateKbps = 510
+)
+
+// SetChannelBitrate caps the Opus bitrate clients use in a channel and
+// returns the updated list. kbps = 0 removes the cap.
+func (r *ChannelState) SetChannelBitrate(serverID string, channelID int64, kbps int) ([]protocol.Channel, error) {
+	if kbps != 0 && (kbps < MinChannelBitrateKbps || kbps > MaxChannelBitrateKbps) {
+		return nil, fmt.Errorf("max_bitrate_kbps must be 0 or between %d and %d", MinChannelBitrateKbps, MaxChannelBitrateKbps)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	chs := r.channels[serverID]
+	for i := range chs {
+		if chs[i].ID == channelID {
+			chs[i].MaxBitrateKbps = kbps
+			out := make([]protocol.Channel, len(chs))
+			copy(out, chs)
+			slog.Info("channel bitrate set", "server_id", serverID, "channel_id", channelID, "max_bitrate_kbps", kbps)
+			return out, nil
+		}
+	}
+	return nil, fmt.Errorf("channel not found")
+}
+
 // DeleteChannel removes a channel and returns the updated list.
 func (r *ChannelState) DeleteChannel(serverID string, channelID int64) ([]protocol.Channel, error) {
 	r.mu.Lock()
@@ -342,6 +762,7 @@ func (r *ChannelState) DeleteChannel(serverID string, channelID int64) ([]protoc
 	for i := range chs {
 		if chs[i].ID == channelID {
 			r.channels[serverID] = append(chs[:i], chs[i+1:]...)
+			delete(r.channelICE, channelID)
 			out := make([]protocol.Channel, len(r.channels[serverID]))
 			copy(out, r.channels[serverID])
 			slog.Info("channel deleted", "server_id", serverID, "channel_id", channelID, "remaining_channels", len(out))
@@ -396,7 +817,7 @@ func (r *ChannelState) Broadcast(msg protocol.Message, exceptUserID string) {
 
 	sent := 0
 	for _, ch := range targets {
-		if trySend(ch, msg) {
+		if r.deliver(ch, msg) {
 			sent++
 		}
 	}
@@ -425,13 +846,37 @@ func (r *ChannelState) BroadcastToServer(serverID string, msg protocol.Message,
 
 	sent := 0
 	for _, ch := range targets {
-		if trySend(ch, msg) {
+		if r.deliver(ch, msg) {
 			sent++
 		}
 	}
 	slog.Debug("broadcast_to_server", "type", msg.Type, "server_id", serverID, "recipients", sent, "total", len(targets))
 }
 
+// BroadcastToVoiceChannel sends a message to users in voice in one channel.
+func (r *ChannelState) BroadcastToVoiceChannel(serverID, channelID string, msg protocol.Message, exceptUserID string) {
+	r.mu.RLock()
+	var targets []chan protocol.Message
+	for id, u := range r.users {
+		if exceptUserID != "" && id == exceptUserID {
+			continue
+		}
+		if u.voice == nil || u.voice.ServerID != serverID || u.voice.ChannelID != channelID {
+			continue
+		}
+		targets = append(targets, u.send)
+	}
+	r.mu.RUnlock()
+
+	sent := 0
+	for _, ch := range targets {
+		if r.deliver(ch, msg) {
+			sent++
+		}
+	}
+	slog.Debug("broadcast_to_voice_channel", "type", msg.Type, "server_id", serverID, "channel_id", channelID, "recipients", sent, "total", len(targets))
+}
+
 // SendTo sends one message to one user.
 func (r *ChannelState) SendTo(userID string, msg protocol.Message) bool {
 	r.mu.RLock()
@@ -440,7 +885,17 @@ func (r *ChannelState) SendTo(userID string, msg protocol.Message) bool {
 	if !ok {
 		return false
 	}
-	return trySend(u.send, msg)
+	return r.deliver(u.send, msg)
+}
+
+// maxClientInfoLen bounds each reported client-info field.
+const maxClientInfoLen = 64
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
 }
 
 func toProtocolUser(u *userState) protocol.User {
@@ -454,11 +909,13 @@ func toProtocolUser(u *userState) protocol.User {
 		ID:               u.id,
 		Username:         u.username,
 		ConnectedServers: servers,
+		Status:           u.status,
 	}
 	if u.voice != nil {
 		v := *u.voice
 		v.Muted = u.muted
 		v.Deafened = u.deafened
+		v.Monitoring = slices.Clone(v.Monitoring)
 		out.Voice = &v
 	}
 	return out
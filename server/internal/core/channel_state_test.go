@@ -1,6 +1,8 @@
 package core
 
 import (
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -202,6 +204,35 @@ func TestCreateChannelLifecycle(t *testing.T) {
 	}
 }
 
+func TestSetChannelBitrate(t *testing.T) {
+	r := NewChannelState("")
+	chs, err := r.CreateChannel("srv-1", "music")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	id := chs[0].ID
+
+	chs, err = r.SetChannelBitrate("srv-1", id, 128)
+	if err != nil {
+		t.Fatalf("set bitrate: %v", err)
+	}
+	if chs[0].MaxBitrateKbps != 128 {
+		t.Fatalf("expected 128 kbps cap, got %d", chs[0].MaxBitrateKbps)
+	}
+	if chs, err = r.SetChannelBitrate("srv-1", id, 0); err != nil || chs[0].MaxBitrateKbps != 0 {
+		t.Fatalf("expected cap cleared, got %#v, %v", chs, err)
+	}
+
+	for _, kbps := range []int{-1, MinChannelBitrateKbps - 1, MaxChannelBitrateKbps + 1} {
+		if _, err := r.SetChannelBitrate("srv-1", id, kbps); err == nil {
+			t.Errorf("expected error for %d kbps", kbps)
+		}
+	}
+	if _, err := r.SetChannelBitrate("srv-1", id+1, 64); err == nil {
+		t.Error("expected error for unknown channel")
+	}
+}
+
 func TestCreateChannelValidation(t *testing.T) {
 	r := NewChannelState("")
 	if _, err := r.CreateChannel("srv-1", ""); err == nil {
@@ -383,6 +414,248 @@ func TestConnectServerSeedsDefaultChannel(t *testing.T) {
 	}
 }
 
+func TestUsernamePolicyAllowKeepsDuplicates(t *testing.T) {
+	r := NewChannelState("")
+	first, _, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add first: %v", err)
+	}
+	second, _, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add second: %v", err)
+	}
+	if second.Username != "alice" || r.ClientCount() != 2 {
+		t.Fatalf("expected two sessions named alice, got %q with %d clients", second.Username, r.ClientCount())
+	}
+	assertNoRecv(t, first.Send)
+}
+
+func TestUsernamePolicyReject(t *testing.T) {
+	r := NewChannelState("")
+	if err := r.SetUsernamePolicy(UsernamePolicyReject); err != nil {
+		t.Fatalf("set policy: %v", err)
+	}
+	if _, _, err := r.Add("alice", 8); err != nil {
+		t.Fatalf("add first: %v", err)
+	}
+	if _, _, err := r.Add("alice", 8); err == nil {
+		t.Fatal("expected duplicate username to be rejected")
+	}
+	if _, _, err := r.Add("bob", 8); err != nil {
+		t.Fatalf("add bob: %v", err)
+	}
+	if r.ClientCount() != 2 {
+		t.Fatalf("expected 2 clients, got %d", r.ClientCount())
+	}
+}
+
+func TestUsernamePolicySuffixFindsLowestFree(t *testing.T) {
+	r := NewChannelState("")
+	if err := r.SetUsernamePolicy(UsernamePolicySuffix); err != nil {
+		t.Fatalf("set policy: %v", err)
+	}
+	if _, _, err := r.Add("alice", 8); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	second, _, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add second: %v", err)
+	}
+	if second.Username != "alice(2)" {
+		t.Fatalf("expected alice(2), got %q", second.Username)
+	}
+	third, snapshot, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add third: %v", err)
+	}
+	if third.Username != "alice(3)" {
+		t.Fatalf("expected alice(3), got %q", third.Username)
+	}
+	u, ok := findUser(snapshot, third.UserID)
+	if !ok || u.Username != "alice(3)" {
+		t.Fatalf("snapshot should carry assigned name, got %#v", u)
+	}
+
+	// Freeing alice(2) makes it the lowest available suffix again.
+	r.Remove(second.UserID)
+	fourth, _, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add fourth: %v", err)
+	}
+	if fourth.Username != "alice(2)" {
+		t.Fatalf("expected reuse of alice(2), got %q", fourth.Username)
+	}
+}
+
+func TestUsernamePolicySuffixIgnoresCase(t *testing.T) {
+	r := NewChannelState("")
+	if err := r.SetUsernamePolicy(UsernamePolicySuffix); err != nil {
+		t.Fatalf("set policy: %v", err)
+	}
+	var got []string
+	for _, name := range []string{"alice", "Alice", "ALICE"} {
+		s, _, err := r.Add(name, 8)
+		if err != nil {
+			t.Fatalf("add %q: %v", name, err)
+		}
+		got = append(got, s.Username)
+	}
+	if want := []string{"alice", "Alice(2)", "ALICE(3)"}; !slices.Equal(got, want) {
+		t.Fatalf("assigned names = %v, want %v", got, want)
+	}
+}
+
+func TestUsernamePolicyReplaceKeepsLatestOfThree(t *testing.T) {
+	r := NewChannelState("")
+	if err := r.SetUsernamePolicy(UsernamePolicyReplace); err != nil {
+		t.Fatalf("set policy: %v", err)
+	}
+	var last *Session
+	for range 3 {
+		s, _, err := r.Add("alice", 8)
+		if err != nil {
+			t.Fatalf("add: %v", err)
+		}
+		last = s
+	}
+	if r.ClientCount() != 1 {
+		t.Fatalf("expected 1 client after three joins, got %d", r.ClientCount())
+	}
+	if u, ok := r.User(last.UserID); !ok || u.Username != "alice" {
+		t.Fatalf("expected latest session to hold alice, got %#v", u)
+	}
+}
+
+func TestUsernamePolicyReplaceEvictsExisting(t *testing.T) {
+	r := NewChannelState("")
+	if err := r.SetUsernamePolicy(UsernamePolicyReplace); err != nil {
+		t.Fatalf("set policy: %v", err)
+	}
+	old, _, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	bob, _, err := r.Add("bob", 8)
+	if err != nil {
+		t.Fatalf("add bob: %v", err)
+	}
+
+	replacement, _, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add replacement: %v", err)
+	}
+	if r.ClientCount() != 2 {
+		t.Fatalf("expected 2 clients after replace, got %d", r.ClientCount())
+	}
+	if _, ok := r.User(old.UserID); ok {
+		t.Fatal("expected old session to be removed")
+	}
+
+	assertRecvType(t, old.Send, protocol.TypeError)
+	if _, ok := <-old.Send; ok {
+		t.Fatal("expected old session's send channel to be closed")
+	}
+	assertRecvType(t, bob.Send, protocol.TypeUserLeft)
+	assertRecvType(t, replacement.Send, protocol.TypeUserLeft)
+
+	if _, ok := r.Remove(old.UserID); ok {
+		t.Fatal("removing a replaced session should be a no-op")
+	}
+}
+
+func TestUsernamePolicyReplaceIgnoresCase(t *testing.T) {
+	r := NewChannelState("")
+	if err := r.SetUsernamePolicy(UsernamePolicyReplace); err != nil {
+		t.Fatalf("set policy: %v", err)
+	}
+	old, _, err := r.Add("Alice", 8)
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if _, _, err := r.Add("alice", 8); err != nil {
+		t.Fatalf("add replacement: %v", err)
+	}
+	if _, ok := r.User(old.UserID); ok {
+		t.Fatal("expected a name differing only in case to replace the old session")
+	}
+}
+
+func TestRenameUserFollowsPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		policy string
+		want   string // "" means the rename is refused
+	}{
+		{UsernamePolicyAllow, "Bob"},
+		{UsernamePolicyReject, ""},
+		{UsernamePolicyReplace, ""},
+		{UsernamePolicySuffix, "Bob(2)"},
+	} {
+		t.Run(tc.policy, func(t *testing.T) {
+			r := NewChannelState("")
+			if err := r.SetUsernamePolicy(tc.policy); err != nil {
+				t.Fatalf("set policy: %v", err)
+			}
+			alice, _, err := r.Add("alice", 8)
+			if err != nil {
+				t.Fatalf("add alice: %v", err)
+			}
+			bob, _, err := r.Add("bob", 8)
+			if err != nil {
+				t.Fatalf("add bob: %v", err)
+			}
+
+			u, changed, err := r.RenameUser(alice.UserID, " Bob ")
+			if tc.want == "" {
+				if err == nil {
+					t.Fatalf("expected rename onto a taken name to fail, got %+v", u)
+				}
+			} else if err != nil || !changed || u.Username != tc.want {
+				t.Fatalf("rename: %+v, changed=%v, err=%v", u, changed, err)
+			}
+			if _, ok := r.User(bob.UserID); !ok {
+				t.Fatal("a rename must never displace another session")
+			}
+		})
+	}
+}
+
+func TestRenameUserToOwnNameInOtherCase(t *testing.T) {
+	r := NewChannelState("")
+	if err := r.SetUsernamePolicy(UsernamePolicyReject); err != nil {
+		t.Fatalf("set policy: %v", err)
+	}
+	s, _, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if _, changed, _ := r.RenameUser(s.UserID, "alice"); changed {
+		t.Error("renaming to the same name should not report a change")
+	}
+	u, changed, err := r.RenameUser(s.UserID, "Alice")
+	if err != nil || !changed || u.Username != "Alice" {
+		t.Fatalf("rename: %+v, changed=%v, err=%v", u, changed, err)
+	}
+	if _, _, err := r.RenameUser(s.UserID, "  "); err == nil {
+		t.Error("expected an error for an empty username")
+	}
+}
+
+func TestSetUsernamePolicyRejectsUnknown(t *testing.T) {
+	r := NewChannelState("")
+	if err := r.SetUsernamePolicy("kick-everyone"); err == nil {
+		t.Fatal("expected error for unknown policy")
+	}
+}
+
+func findUser(users []protocol.User, id string) (protocol.User, bool) {
+	for _, u := range users {
+		if u.ID == id {
+			return u, true
+		}
+	}
+	return protocol.User{}, false
+}
+
 func assertRecvType(t *testing.T, ch <-chan protocol.Message, typ string) {
 	t.Helper()
 	select {
@@ -403,3 +676,90 @@ func assertNoRecv(t *testing.T, ch <-chan protocol.Message) {
 	case <-time.After(100 * time.Millisecond):
 	}
 }
+
+func TestSetClientInfoTruncatesFields(t *testing.T) {
+	r := NewChannelState("")
+	s, _, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	r.SetClientInfo(s.UserID, protocol.ClientInfo{Commit: strings.Repeat("a", 200), OS: " linux ", Arch: "arm64"})
+
+	infos := r.ClientInfos()
+	if len(infos) != 1 {
+		t.Fatalf("expected 1 client, got %d", len(infos))
+	}
+	got := infos[0].Client
+	if len(got.Commit) != maxClientInfoLen {
+		t.Errorf("commit length = %d, want %d", len(got.Commit), maxClientInfoLen)
+	}
+	if got.OS != "linux" || got.Arch != "arm64" {
+		t.Errorf("unexpected client info: %+v", got)
+	}
+}
+
+func TestSharesServer(t *testing.T) {
+	r := NewChannelState("")
+	alice, _, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add alice: %v", err)
+	}
+	bob, _, err := r.Add("bob", 8)
+	if err != nil {
+		t.Fatalf("add bob: %v", err)
+	}
+
+	if r.SharesServer(alice.UserID, bob.UserID) {
+		t.Fatal("users connected nowhere should not share a server")
+	}
+	if _, _, err := r.ConnectServer(alice.UserID, "srv-1"); err != nil {
+		t.Fatalf("connect alice: %v", err)
+	}
+	if _, _, err := r.ConnectServer(bob.UserID, "srv-2"); err != nil {
+		t.Fatalf("connect bob: %v", err)
+	}
+	if r.SharesServer(alice.UserID, bob.UserID) {
+		t.Fatal("users on different servers should not share a server")
+	}
+	if _, _, err := r.ConnectServer(bob.UserID, "srv-1"); err != nil {
+		t.Fatalf("connect bob to srv-1: %v", err)
+	}
+	if !r.SharesServer(alice.UserID, bob.UserID) {
+		t.Fatal("expected alice and bob to share srv-1")
+	}
+	if r.SharesServer(alice.UserID, "u999") {
+		t.Fatal("unknown user should not share a server")
+	}
+}
+
+func TestSetMaxUploadBytes(t *testing.T) {
+	r := NewChannelState("")
+	if got := r.MaxUploadBytes(); got != DefaultMaxUploadBytes {
+		t.Fatalf("expected default %d, got %d", DefaultMaxUploadBytes, got)
+	}
+	if err := r.SetMaxUploadBytes(0); err == nil {
+		t.Fatal("expected error for zero limit")
+	}
+	if err := r.SetMaxUploadBytes(5 << 20); err != nil {
+		t.Fatalf("set max upload: %v", err)
+	}
+	if got := r.MaxUploadBytes(); got != 5<<20 {
+		t.Fatalf("expected 5 MiB, got %d", got)
+	}
+}
+
+func TestSetMaxMessageLength(t *testing.T) {
+	r := NewChannelState("")
+	if got := r.MaxMessageLength(); got != DefaultMaxMessageLength {
+		t.Fatalf("expected default %d, got %d", DefaultMaxMessageLength, got)
+	}
+	if err := r.SetMaxMessageLength(-1); err == nil {
+		t.Fatal("expected error for negative limit")
+	}
+	if err := r.SetMaxMessageLength(2000); err != nil {
+		t.Fatalf("set max message length: %v", err)
+	}
+	if got := r.MaxMessageLength(); got != 2000 {
+		t.Fatalf("expected 2000, got %d", got)
+	}
+}
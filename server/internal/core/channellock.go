@@ -0,0 +1,43 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"bken/server/internal/protocol"
+)
+
+// ErrChannelLocked is returned by JoinVoice when a user below moderator
+// tries to join a locked channel.
+var ErrChannelLocked = errors.New("channel locked")
+
+// SetChannelLock locks or unlocks a voice channel and returns the updated
+// list. Nobody below moderator may join a locked channel; users already
+// in it stay.
+func (r *ChannelState) SetChannelLock(serverID string, channelID int64, locked bool) ([]protocol.Channel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	chs := r.channels[serverID]
+	for i := range chs {
+		if chs[i].ID == channelID {
+			chs[i].Locked = locked
+			out := make([]protocol.Channel, len(chs))
+			copy(out, chs)
+			slog.Info("channel lock set", "server_id", serverID, "channel_id", channelID, "locked", locked)
+			return out, nil
+		}
+	}
+	return nil, fmt.Errorf("channel not found")
+}
+
+// checkChannelLockLocked returns ErrChannelLocked if u may not join a
+// channel because it is locked. Caller holds r.mu.
+func (r *ChannelState) checkChannelLockLocked(u *userState, serverID, channelID string) error {
+	ch, ok := r.channelLocked(serverID, channelID)
+	if !ok || !ch.Locked || r.meetsLocked(u, RoleModerator) {
+		return nil
+	}
+	return ErrChannelLocked
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestLockedChannelRejectsNewJoins(t *testing.T) {
+	r := NewChannelState("")
+	inside, _, _ := r.Add("inside", 8)
+	user, _, _ := r.Add("user", 8)
+	mod, _, _ := r.Add("mod", 8)
+	if err := r.SetRole(mod.UserID, RoleModerator); err != nil {
+		t.Fatalf("set moderator: %v", err)
+	}
+	chs, _ := r.CreateChannel("srv-1", "general")
+	id := chs[0].ID
+	channelID := strconv.FormatInt(id, 10)
+	for _, s := range []*Session{inside, user, mod} {
+		if _, _, err := r.ConnectServer(s.UserID, "srv-1"); err != nil {
+			t.Fatalf("connect %s: %v", s.Username, err)
+		}
+	}
+	if _, _, err := r.JoinVoice(inside.UserID, "srv-1", channelID); err != nil {
+		t.Fatalf("join before lock: %v", err)
+	}
+
+	chs, err := r.SetChannelLock("srv-1", id, true)
+	if err != nil || !chs[0].Locked {
+		t.Fatalf("lock channel: %v, %+v", err, chs)
+	}
+	if _, _, err := r.JoinVoice(user.UserID, "srv-1", channelID); !errors.Is(err, ErrChannelLocked) {
+		t.Fatalf("user join: err = %v, want ErrChannelLocked", err)
+	}
+
+	// Moderators still get in, and whoever was already inside stays and
+	// may rejoin.
+	if _, _, err := r.JoinVoice(mod.UserID, "srv-1", channelID); err != nil {
+		t.Fatalf("moderator join: %v", err)
+	}
+	if _, _, err := r.JoinVoice(inside.UserID, "srv-1", channelID); err != nil {
+		t.Fatalf("rejoin: %v", err)
+	}
+
+	if _, err := r.SetChannelLock("srv-1", id, false); err != nil {
+		t.Fatalf("unlock channel: %v", err)
+	}
+	if _, _, err := r.JoinVoice(user.UserID, "srv-1", channelID); err != nil {
+		t.Fatalf("join after unlock: %v", err)
+	}
+	if _, err := r.SetChannelLock("srv-1", id+1, true); err == nil {
+		t.Error("expected an error for an unknown channel")
+	}
+}
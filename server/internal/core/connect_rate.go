@@ -0,0 +1,71 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// connectRateWindow is the sliding window SetConnectRate counts over.
+const connectRateWindow = time.Minute
+
+// SetConnectRate limits how many new connections one IP may open per
+// minute, to blunt reconnect storms and scanners. Zero (the default)
+// disables the limit.
+func (r *ChannelState) SetConnectRate(perMinute int) error {
+	if perMinute < 0 {
+		return fmt.Errorf("connect rate must not be negative")
+	}
+	r.mu.Lock()
+	r.connectRate = perMinute
+	if perMinute == 0 {
+		r.connectTimes = nil
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+// ConnectRate returns the per-IP connection limit per minute (0 = none).
+func (r *ChannelState) ConnectRate() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.connectRate
+}
+
+// allowConnectLocked records a connection attempt from ip at now and
+// reports whether it is within the connect rate. Rejected attempts are not
+// recorded, so a client that backs off is let back in once its earlier
+// connections leave the window. Caller holds r.mu for writing.
+func (r *ChannelState) allowConnectLocked(ip string, now time.Time) bool {
+	if r.connectRate <= 0 {
+		return true
+	}
+	if r.connectTimes == nil {
+		r.connectTimes = make(map[string][]time.Time)
+	}
+	cutoff := now.Add(-connectRateWindow)
+	// IPs that stop connecting would otherwise stay in the map forever;
+	// sweep them out once per window.
+	if now.Sub(r.connectSweep) >= connectRateWindow {
+		for addr, times := range r.connectTimes {
+			if !times[len(times)-1].After(cutoff) {
+				delete(r.connectTimes, addr)
+			}
+		}
+		r.connectSweep = now
+	}
+
+	times := r.connectTimes[ip]
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	times = times[i:]
+	if len(times) >= r.connectRate {
+		r.connectTimes[ip] = times
+		slog.Warn("connection rate exceeded", "ip", ip, "limit_per_minute", r.connectRate)
+		return false
+	}
+	r.connectTimes[ip] = append(times, now)
+	return true
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConnectRateRejectsSixthConnectionInWindow(t *testing.T) {
+	r := NewChannelState("")
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+	if err := r.SetConnectRate(5); err != nil {
+		t.Fatalf("set connect rate: %v", err)
+	}
+
+	for i := range 5 {
+		if !r.CanConnect("10.0.0.1") {
+			t.Fatalf("connection %d rejected, want allowed", i+1)
+		}
+		now = now.Add(time.Second)
+	}
+	if r.CanConnect("10.0.0.1") {
+		t.Fatal("6th connection within a minute allowed, want rejected")
+	}
+	if !r.CanConnect("10.0.0.2") {
+		t.Fatal("connection from another IP rejected")
+	}
+
+	// The first connection leaves the window a minute after it was made.
+	now = time.Unix(1000, 0).Add(connectRateWindow + time.Millisecond)
+	if !r.CanConnect("10.0.0.1") {
+		t.Fatal("connection rejected after the window slid past the first one")
+	}
+	if r.CanConnect("10.0.0.1") {
+		t.Fatal("connection allowed with the window full again")
+	}
+}
+
+func TestConnectRateSweepsIdleIPs(t *testing.T) {
+	r := NewChannelState("")
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+	if err := r.SetConnectRate(5); err != nil {
+		t.Fatalf("set connect rate: %v", err)
+	}
+	r.CanConnect("10.0.0.1")
+	now = now.Add(2 * connectRateWindow)
+	r.CanConnect("10.0.0.2")
+	if _, ok := r.connectTimes["10.0.0.1"]; ok {
+		t.Fatal("idle IP was not swept out")
+	}
+}
+
+func TestSetConnectRateRejectsNegative(t *testing.T) {
+	r := NewChannelState("")
+	if err := r.SetConnectRate(-1); err == nil {
+		t.Fatal("expected an error for a negative rate")
+	}
+	if !r.CanConnect("10.0.0.1") {
+		t.Fatal("expected connections to be unlimited by default")
+	}
+}
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// CooldownError is returned by JoinVoice when the user switched voice
+// channels too recently.
+type CooldownError struct {
+	Remaining time.Duration
+}
+
+func (e *CooldownError) Error() string {
+	secs := int((e.Remaining + time.Second - 1) / time.Second)
+	return fmt.Sprintf("switching channels too quickly; try again in %ds", secs)
+}
+
+// SetChannelSwitchCooldown sets the minimum time between a user's voice
+// channel joins. Zero (the default) disables the cooldown.
+func (r *ChannelState) SetChannelSwitchCooldown(d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("channel switch cooldown must not be negative")
+	}
+	r.mu.Lock()
+	r.switchCooldown = d
+	r.mu.Unlock()
+	return nil
+}
+
+// ChannelSwitchCooldown returns the configured channel switch cooldown.
+func (r *ChannelState) ChannelSwitchCooldown() time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.switchCooldown
+}
+
+// checkSwitchCooldownLocked returns a *CooldownError if u joined a voice
+// channel less than the configured cooldown before now. Caller holds r.mu.
+func (r *ChannelState) checkSwitchCooldownLocked(u *userState, now time.Time) error {
+	if r.switchCooldown <= 0 || u.lastJoin.IsZero() {
+		return nil
+	}
+	if remaining := u.lastJoin.Add(r.switchCooldown).Sub(now); remaining > 0 {
+		return &CooldownError{Remaining: remaining}
+	}
+	return nil
+}
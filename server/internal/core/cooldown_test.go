@@ -0,0 +1,80 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChannelSwitchCooldown(t *testing.T) {
+	r := NewChannelState("")
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+	if err := r.SetChannelSwitchCooldown(5 * time.Second); err != nil {
+		t.Fatalf("set cooldown: %v", err)
+	}
+
+	s, _, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if _, _, err := r.ConnectServer(s.UserID, "srv-1"); err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+	if _, _, err := r.JoinVoice(s.UserID, "srv-1", "chan-a"); err != nil {
+		t.Fatalf("first join should not be limited: %v", err)
+	}
+
+	// Rejoining the same channel is not a switch.
+	now = now.Add(time.Second)
+	if _, _, err := r.JoinVoice(s.UserID, "srv-1", "chan-a"); err != nil {
+		t.Fatalf("rejoin same channel: %v", err)
+	}
+
+	_, _, err = r.JoinVoice(s.UserID, "srv-1", "chan-b")
+	var cooldown *CooldownError
+	if !errors.As(err, &cooldown) {
+		t.Fatalf("expected CooldownError, got %v", err)
+	}
+	if cooldown.Remaining != 4*time.Second {
+		t.Fatalf("expected 4s remaining, got %v", cooldown.Remaining)
+	}
+	r.DisconnectVoice(s.UserID)
+
+	// Leaving and joining elsewhere is still limited.
+	if _, _, err := r.JoinVoice(s.UserID, "srv-1", "chan-b"); !errors.As(err, &cooldown) {
+		t.Fatalf("expected CooldownError after leave, got %v", err)
+	}
+
+	now = now.Add(4 * time.Second)
+	u2, _, err := r.JoinVoice(s.UserID, "srv-1", "chan-b")
+	if err != nil {
+		t.Fatalf("join at cooldown boundary: %v", err)
+	}
+	if u2.Voice == nil || u2.Voice.ChannelID != "chan-b" {
+		t.Fatalf("expected voice in chan-b, got %+v", u2.Voice)
+	}
+}
+
+func TestChannelSwitchCooldownDisabledByDefault(t *testing.T) {
+	r := NewChannelState("")
+	s, _, _ := r.Add("alice", 8)
+	if _, _, err := r.ConnectServer(s.UserID, "srv-1"); err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+	for _, ch := range []string{"a", "b", "c"} {
+		if _, _, err := r.JoinVoice(s.UserID, "srv-1", ch); err != nil {
+			t.Fatalf("join %s: %v", ch, err)
+		}
+	}
+	if err := r.SetChannelSwitchCooldown(-time.Second); err == nil {
+		t.Fatal("expected error for negative cooldown")
+	}
+}
+
+func TestCooldownErrorRoundsUp(t *testing.T) {
+	err := &CooldownError{Remaining: 1500 * time.Millisecond}
+	if got, want := err.Error(), "switching channels too quickly; try again in 2s"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
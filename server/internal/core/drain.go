@@ -0,0 +1,64 @@
+package core
+
+import (
+	"log/slog"
+	"time"
+
+	"bken/server/internal/protocol"
+)
+
+// Drain starts a graceful shutdown. Every connected user is sent
+// server_shutdown carrying the grace window, new sessions are refused (see
+// CanConnect), and once grace has passed the sessions still open are
+// closed. The returned channel is closed when that has happened. Calling
+// Drain again returns the same channel and does not restart the timer.
+func (r *ChannelState) Drain(grace time.Duration) <-chan struct{} {
+	r.mu.Lock()
+	if r.drained != nil {
+		done := r.drained
+		r.mu.Unlock()
+		return done
+	}
+	done := make(chan struct{})
+	r.drained = done
+	count := len(r.users)
+	r.mu.Unlock()
+
+	slog.Info("draining", "grace", grace, "users", count)
+	r.Broadcast(protocol.Message{Type: protocol.TypeServerShutdown, GraceMs: grace.Milliseconds()}, "")
+	time.AfterFunc(grace, func() {
+		r.closeAll()
+		close(done)
+	})
+	return done
+}
+
+// CanConnect reports whether a new session from ip is accepted. It turns
+// false once Drain has been called, and for an ip that has used up its
+// connect rate (see SetConnectRate).
+func (r *ChannelState) CanConnect(ip string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.drained == nil && r.allowConnectLocked(ip, r.now())
+}
+
+// Draining reports whether Drain has been called.
+func (r *ChannelState) Draining() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.drained != nil
+}
+
+// closeAll removes every remaining user and closes its send channel, which
+// ends its websocket.
+func (r *ChannelState) closeAll() {
+	r.mu.Lock()
+	users := r.users
+	r.users = make(map[string]*userState)
+	r.mu.Unlock()
+
+	for _, u := range users {
+		close(u.send)
+	}
+	slog.Info("drain complete", "closed", len(users))
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"bken/server/internal/protocol"
+)
+
+func TestDrainWarnsRefusesThenCloses(t *testing.T) {
+	r := NewChannelState("")
+	alice, _, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if !r.CanConnect("10.0.0.1") {
+		t.Fatal("expected CanConnect before Drain")
+	}
+
+	done := r.Drain(50 * time.Millisecond)
+	warn, ok := <-alice.Send
+	if !ok || warn.Type != protocol.TypeServerShutdown || warn.GraceMs != 50 {
+		t.Fatalf("expected server_shutdown with grace_ms 50, got %+v (ok=%v)", warn, ok)
+	}
+	if r.CanConnect("10.0.0.1") {
+		t.Fatal("expected CanConnect to be false while draining")
+	}
+	if _, _, err := r.Add("bob", 8); err == nil {
+		t.Fatal("expected Add to be refused while draining")
+	}
+	if again := r.Drain(time.Hour); again != done {
+		t.Fatal("expected a second Drain to return the first drain's channel")
+	}
+	if r.ClientCount() != 1 {
+		t.Fatalf("expected alice to stay connected during the grace window, got %d clients", r.ClientCount())
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("drain did not finish after the grace window")
+	}
+	if _, ok := <-alice.Send; ok {
+		t.Fatal("expected alice's send channel to be closed after the grace window")
+	}
+	if r.ClientCount() != 0 {
+		t.Fatalf("expected no clients after drain, got %d", r.ClientCount())
+	}
+}
@@ -0,0 +1,219 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"unicode/utf8"
+)
+
+// MaxEmojiBytes bounds a reaction's encoded size. The longest standard
+// sequences (multi-person ZWJ families, subdivision flags) are well under it.
+const MaxEmojiBytes = 64
+
+// MaxAllowedEmoji bounds the reaction allow-list sent in every snapshot.
+const MaxAllowedEmoji = 200
+
+// ErrInvalidEmoji is returned for reactions that are not a single emoji.
+var ErrInvalidEmoji = errors.New("reaction must be a single emoji")
+
+// ErrEmojiNotAllowed is returned for reactions outside the server's
+// allow-list.
+var ErrEmojiNotAllowed = errors.New("that emoji is not allowed on this server")
+
+const (
+	runeVS15       = '\uFE0E' // text presentation selector
+	runeVS16       = '\uFE0F' // emoji presentation selector
+	runeZWJ        = '\u200D' // zero-width joiner
+	runeKeycap     = '\u20E3' // combining enclosing keycap
+	runeBlackFlag  = '\U0001F3F4'
+	runeTagFirst   = '\U000E0020'
+	runeTagLast    = '\U000E007E'
+	runeTagCancel  = '\U000E007F'
+	runeRegionalA  = '\U0001F1E6'
+	runeRegionalZ  = '\U0001F1FF'
+	runeSkinLight  = '\U0001F3FB'
+	runeSkinDark   = '\U0001F3FF'
+	runeSupplStart = '\U0001F000'
+)
+
+// NormalizeEmoji checks that s is exactly one emoji — a pictograph with
+// optional skin tone, a ZWJ sequence of those, a keycap, a regional-indicator
+// flag, or a subdivision flag — and returns it in canonical form.
+//
+// Variation selectors are canonicalised so visually identical reactions
+// compare equal: they are dropped after pictographs that already default to
+// emoji presentation ("👍️" → "👍"), and VS16 is ensured after text-default
+// symbols and keycaps ("❤" → "❤️").
+func NormalizeEmoji(s string) (string, error) {
+	if s == "" || len(s) > MaxEmojiBytes || !utf8.ValidString(s) {
+		return "", ErrInvalidEmoji
+	}
+	runes := []rune(s)
+
+	switch {
+	case isRegionalIndicator(runes[0]):
+		if len(runes) == 2 && isRegionalIndicator(runes[1]) {
+			return s, nil
+		}
+		return "", ErrInvalidEmoji
+
+	case isKeycapBase(runes[0]):
+		rest := runes[1:]
+		if len(rest) > 0 && rest[0] == runeVS16 {
+			rest = rest[1:]
+		}
+		if len(rest) == 1 && rest[0] == runeKeycap {
+			return string([]rune{runes[0], runeVS16, runeKeycap}), nil
+		}
+		return "", ErrInvalidEmoji
+
+	case runes[0] == runeBlackFlag && len(runes) > 1 && isTag(runes[1]):
+		for _, r := range runes[1 : len(runes)-1] {
+			if !isTag(r) {
+				return "", ErrInvalidEmoji
+			}
+		}
+		if runes[len(runes)-1] != runeTagCancel {
+			return "", ErrInvalidEmoji
+		}
+		return s, nil
+	}
+
+	out := make([]rune, 0, len(runes)+2)
+	for i := 0; ; {
+		base := runes[i]
+		if !isPictographic(base) {
+			return "", ErrInvalidEmoji
+		}
+		i++
+		if i < len(runes) && (runes[i] == runeVS15 || runes[i] == runeVS16) {
+			i++
+		}
+		out = append(out, base)
+		switch {
+		case i < len(runes) && isSkinTone(runes[i]):
+			out = append(out, runes[i])
+			i++
+		case isTextDefault(base):
+			out = append(out, runeVS16)
+		}
+
+		if i == len(runes) {
+			break
+		}
+		// Anything further must join another pictograph.
+		if runes[i] != runeZWJ || i+1 == len(runes) {
+			return "", ErrInvalidEmoji
+		}
+		out = append(out, runeZWJ)
+		i++
+	}
+	return string(out), nil
+}
+
+func isRegionalIndicator(r rune) bool { return r >= runeRegionalA && r <= runeRegionalZ }
+func isSkinTone(r rune) bool          { return r >= runeSkinLight && r <= runeSkinDark }
+func isTag(r rune) bool               { return r >= runeTagFirst && r <= runeTagLast }
+func isKeycapBase(r rune) bool        { return (r >= '0' && r <= '9') || r == '#' || r == '*' }
+
+// pictographicRanges approximates Unicode's Extended_Pictographic property:
+// the scattered legacy symbols in the BMP plus the supplementary emoji blocks.
+var pictographicRanges = [][2]rune{
+	{0x00A9, 0x00A9}, {0x00AE, 0x00AE}, {0x203C, 0x203C}, {0x2049, 0x2049},
+	{0x2122, 0x2122}, {0x2139, 0x2139}, {0x2194, 0x2199}, {0x21A9, 0x21AA},
+	{0x231A, 0x231B}, {0x2328, 0x2328}, {0x23CF, 0x23CF}, {0x23E9, 0x23F3},
+	{0x23F8, 0x23FA}, {0x24C2, 0x24C2}, {0x25AA, 0x25AB}, {0x25B6, 0x25B6},
+	{0x25C0, 0x25C0}, {0x25FB, 0x25FE}, {0x2600, 0x27BF}, {0x2934, 0x2935},
+	{0x2B05, 0x2B07}, {0x2B1B, 0x2B1C}, {0x2B50, 0x2B50}, {0x2B55, 0x2B55},
+	{0x3030, 0x3030}, {0x303D, 0x303D}, {0x3297, 0x3297}, {0x3299, 0x3299},
+	{0x1F000, 0x1F1E5}, {0x1F200, 0x1F3FA}, {0x1F400, 0x1FAFF},
+}
+
+func isPictographic(r rune) bool {
+	return inRanges(r, pictographicRanges)
+}
+
+// textDefaultRanges lists the supplementary-plane pictographs that render as
+// text unless followed by VS16 (Extended_Pictographic without
+// Emoji_Presentation). Every BMP pictograph is treated as text-default.
+var textDefaultRanges = [][2]rune{
+	{0x1F170, 0x1F171}, {0x1F17E, 0x1F17F}, {0x1F202, 0x1F202}, {0x1F237, 0x1F237},
+	{0x1F321, 0x1F321}, {0x1F324, 0x1F32C}, {0x1F336, 0x1F336}, {0x1F37D, 0x1F37D},
+	{0x1F396, 0x1F397}, {0x1F399, 0x1F39B}, {0x1F39E, 0x1F39F}, {0x1F3CB, 0x1F3CE},
+	{0x1F3D4, 0x1F3DF}, {0x1F3F3, 0x1F3F3}, {0x1F3F5, 0x1F3F5}, {0x1F3F7, 0x1F3F7},
+	{0x1F43F, 0x1F43F}, {0x1F441, 0x1F441}, {0x1F4FD, 0x1F4FD}, {0x1F549, 0x1F54A},
+	{0x1F56F, 0x1F570}, {0x1F573, 0x1F579}, {0x1F587, 0x1F587}, {0x1F58A, 0x1F58D},
+	{0x1F590, 0x1F590}, {0x1F5A5, 0x1F5A5}, {0x1F5A8, 0x1F5A8}, {0x1F5B1, 0x1F5B2},
+	{0x1F5BC, 0x1F5BC}, {0x1F5C2, 0x1F5C4}, {0x1F5D1, 0x1F5D3}, {0x1F5DC, 0x1F5DE},
+	{0x1F5E1, 0x1F5E1}, {0x1F5E3, 0x1F5E3}, {0x1F5E8, 0x1F5E8}, {0x1F5EF, 0x1F5EF},
+	{0x1F5F3, 0x1F5F3}, {0x1F5FA, 0x1F5FA}, {0x1F6CB, 0x1F6CB}, {0x1F6CD, 0x1F6CF},
+	{0x1F6E0, 0x1F6E5}, {0x1F6E9, 0x1F6E9}, {0x1F6F0, 0x1F6F0}, {0x1F6F3, 0x1F6F3},
+}
+
+func isTextDefault(r rune) bool {
+	if r < runeSupplStart {
+		return true
+	}
+	return inRanges(r, textDefaultRanges)
+}
+
+func inRanges(r rune, ranges [][2]rune) bool {
+	for _, rg := range ranges {
+		if r >= rg[0] && r <= rg[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// SetAllowedEmoji restricts reactions to the given emoji, normalised and
+// with duplicates removed, in the order given. An empty list allows any
+// emoji.
+func (r *ChannelState) SetAllowedEmoji(list []string) error {
+	if len(list) > MaxAllowedEmoji {
+		return fmt.Errorf("allowed emoji list must have at most %d entries", MaxAllowedEmoji)
+	}
+	allowed := make([]string, 0, len(list))
+	for _, e := range list {
+		n, err := NormalizeEmoji(e)
+		if err != nil {
+			return fmt.Errorf("allowed emoji %q: %w", e, err)
+		}
+		if !slices.Contains(allowed, n) {
+			allowed = append(allowed, n)
+		}
+	}
+	r.mu.Lock()
+	r.allowedEmoji = allowed
+	r.mu.Unlock()
+	slog.Info("allowed emoji set", "count", len(allowed))
+	return nil
+}
+
+// AllowedEmoji returns the reaction allow-list, or nil when any emoji is
+// allowed.
+func (r *ChannelState) AllowedEmoji() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.allowedEmoji) == 0 {
+		return nil
+	}
+	return slices.Clone(r.allowedEmoji)
+}
+
+// CheckReactionEmoji normalises emoji and checks it against the allow-list,
+// returning ErrInvalidEmoji or ErrEmojiNotAllowed if it cannot be used.
+func (r *ChannelState) CheckReactionEmoji(emoji string) (string, error) {
+	n, err := NormalizeEmoji(emoji)
+	if err != nil {
+		return "", err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.allowedEmoji) > 0 && !slices.Contains(r.allowedEmoji, n) {
+		return "", ErrEmojiNotAllowed
+	}
+	return n, nil
+}
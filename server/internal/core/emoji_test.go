@@ -0,0 +1,110 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeEmojiAccepts(t *testing.T) {
+	cases := []struct {
+		name, in, want string
+	}{
+		{"simple", "👍", "👍"},
+		{"emoji default drops VS16", "👍️", "👍"},
+		{"text default gains VS16", "❤", "❤️"},
+		{"text default keeps VS16", "❤️", "❤️"},
+		{"VS15 normalised", "❤︎", "❤️"},
+		{"supplementary text default", "🏳", "🏳️"},
+		{"skin tone", "👍🏽", "👍🏽"},
+		{"text default with skin tone", "☝️🏻", "☝🏻"},
+		{"ZWJ family", "👨‍👩‍👧", "👨‍👩‍👧"},
+		{"ZWJ rainbow flag", "🏳‍🌈", "🏳️‍🌈"},
+		{"keycap", "1⃣", "1️⃣"},
+		{"keycap qualified", "#️⃣", "#️⃣"},
+		{"country flag", "🇳🇿", "🇳🇿"},
+		{"subdivision flag", "🏴\U000E0067\U000E0062\U000E0073\U000E0063\U000E0074\U000E007F", "🏴\U000E0067\U000E0062\U000E0073\U000E0063\U000E0074\U000E007F"},
+	}
+	for _, c := range cases {
+		got, err := NormalizeEmoji(c.in)
+		if err != nil {
+			t.Errorf("%s: NormalizeEmoji(%q) error: %v", c.name, c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: NormalizeEmoji(%q) = %q, want %q", c.name, c.in, got, c.want)
+		}
+	}
+}
+
+func TestNormalizeEmojiRejects(t *testing.T) {
+	cases := []struct {
+		name, in string
+	}{
+		{"empty", ""},
+		{"text", "lol"},
+		{"emoji plus text", "👍x"},
+		{"two emoji", "👍👍"},
+		{"lone skin tone", "🏽"},
+		{"lone regional indicator", "🇳"},
+		{"three regional indicators", "🇳🇿🇳"},
+		{"dangling ZWJ", "👨‍"},
+		{"digit without keycap", "1"},
+		{"unterminated tag sequence", "🏴\U000E0067\U000E0062"},
+		{"invalid utf-8", "\xff"},
+		{"too long", strings.Repeat("👨‍", 10) + "👨"},
+	}
+	for _, c := range cases {
+		if got, err := NormalizeEmoji(c.in); err == nil {
+			t.Errorf("%s: NormalizeEmoji(%q) = %q, want error", c.name, c.in, got)
+		}
+	}
+}
+
+func TestNormalizeEmojiDeduplicatesVariants(t *testing.T) {
+	a, errA := NormalizeEmoji("👍")
+	b, errB := NormalizeEmoji("👍️")
+	if errA != nil || errB != nil {
+		t.Fatalf("unexpected errors: %v, %v", errA, errB)
+	}
+	if a != b {
+		t.Errorf("variants normalised differently: %q vs %q", a, b)
+	}
+}
+
+func TestAllowedEmoji(t *testing.T) {
+	r := NewChannelState("")
+	if got, err := r.CheckReactionEmoji("🔥"); err != nil || got != "🔥" {
+		t.Fatalf("empty allow-list: CheckReactionEmoji = %q, %v; want any emoji allowed", got, err)
+	}
+
+	if err := r.SetAllowedEmoji([]string{"👍️", "❤", "👍"}); err != nil {
+		t.Fatalf("set allowed emoji: %v", err)
+	}
+	if got := r.AllowedEmoji(); len(got) != 2 || got[0] != "👍" || got[1] != "❤️" {
+		t.Fatalf("AllowedEmoji = %q, want normalised and deduplicated", got)
+	}
+	if got, err := r.CheckReactionEmoji("❤️"); err != nil || got != "❤️" {
+		t.Errorf("allowed emoji: got %q, %v", got, err)
+	}
+	if _, err := r.CheckReactionEmoji("🔥"); !errors.Is(err, ErrEmojiNotAllowed) {
+		t.Errorf("denied emoji: err = %v, want ErrEmojiNotAllowed", err)
+	}
+	if _, err := r.CheckReactionEmoji("nope"); !errors.Is(err, ErrInvalidEmoji) {
+		t.Errorf("invalid emoji: err = %v, want ErrInvalidEmoji", err)
+	}
+
+	if err := r.SetAllowedEmoji([]string{"👍", "not emoji"}); err == nil {
+		t.Error("expected an error for an invalid allow-list entry")
+	}
+	if got := r.AllowedEmoji(); len(got) != 2 {
+		t.Errorf("a rejected list should leave the old one, got %q", got)
+	}
+
+	if err := r.SetAllowedEmoji(nil); err != nil {
+		t.Fatalf("clear allowed emoji: %v", err)
+	}
+	if got := r.AllowedEmoji(); got != nil {
+		t.Errorf("AllowedEmoji after clearing = %q, want nil", got)
+	}
+}
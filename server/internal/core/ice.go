@@ -0,0 +1,86 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"bken/server/internal/protocol"
+)
+
+// SetICEServers sets the STUN/TURN servers clients use for peer connections
+// in channels without an override. An empty list leaves clients on their
+// built-in default.
+func (r *ChannelState) SetICEServers(servers []protocol.ICEServer) error {
+	if err := validateICEServers(servers); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.iceServers = cloneICEServers(servers)
+	r.mu.Unlock()
+	slog.Info("ice servers updated", "count", len(servers))
+	return nil
+}
+
+// SetChannelICEServers overrides the ICE servers for one voice channel, for
+// example to point a region's channels at a nearby TURN relay. An empty list
+// removes the override so the channel falls back to SetICEServers.
+func (r *ChannelState) SetChannelICEServers(channelID int64, servers []protocol.ICEServer) error {
+	if err := validateICEServers(servers); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(servers) == 0 {
+		delete(r.channelICE, channelID)
+	} else {
+		if r.channelICE == nil {
+			r.channelICE = make(map[int64][]protocol.ICEServer)
+		}
+		r.channelICE[channelID] = cloneICEServers(servers)
+	}
+	slog.Info("channel ice servers updated", "channel_id", channelID, "count", len(servers))
+	return nil
+}
+
+// ICEServers returns the effective ICE servers for a channel: its override
+// if it has one, otherwise the server-wide list. Channel 0 always gets the
+// server-wide list.
+func (r *ChannelState) ICEServers(channelID int64) []protocol.ICEServer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if servers, ok := r.channelICE[channelID]; ok {
+		return cloneICEServers(servers)
+	}
+	return cloneICEServers(r.iceServers)
+}
+
+func validateICEServers(servers []protocol.ICEServer) error {
+	for i, s := range servers {
+		if len(s.URLs) == 0 {
+			return fmt.Errorf("ice server %d has no urls", i)
+		}
+		for _, u := range s.URLs {
+			scheme, _, _ := strings.Cut(u, ":")
+			switch scheme {
+			case "stun", "stuns", "turn", "turns":
+			default:
+				return fmt.Errorf("ice server url %q must use stun, stuns, turn or turns", u)
+			}
+		}
+	}
+	return nil
+}
+
+func cloneICEServers(servers []protocol.ICEServer) []protocol.ICEServer {
+	if len(servers) == 0 {
+		return nil
+	}
+	out := make([]protocol.ICEServer, len(servers))
+	for i, s := range servers {
+		out[i] = s
+		out[i].URLs = slices.Clone(s.URLs)
+	}
+	return out
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"testing"
+
+	"bken/server/internal/protocol"
+)
+
+func TestChannelICEServersOverrideGlobal(t *testing.T) {
+	r := NewChannelState("")
+	global := []protocol.ICEServer{{URLs: []string{"stun:stun.example.com:3478"}}}
+	eu := []protocol.ICEServer{{URLs: []string{"turn:eu.example.com:3478"}, Username: "u", Credential: "p"}}
+
+	if got := r.ICEServers(1); got != nil {
+		t.Fatalf("no servers configured, got %+v", got)
+	}
+	if err := r.SetICEServers(global); err != nil {
+		t.Fatalf("set ice servers: %v", err)
+	}
+	if err := r.SetChannelICEServers(2, eu); err != nil {
+		t.Fatalf("set channel ice servers: %v", err)
+	}
+
+	if got := r.ICEServers(1); len(got) != 1 || got[0].URLs[0] != "stun:stun.example.com:3478" {
+		t.Errorf("channel without override = %+v, want the global list", got)
+	}
+	if got := r.ICEServers(2); len(got) != 1 || got[0].URLs[0] != "turn:eu.example.com:3478" || got[0].Credential != "p" {
+		t.Errorf("channel with override = %+v, want the override", got)
+	}
+
+	// The returned list is a copy.
+	r.ICEServers(2)[0].URLs[0] = "stun:changed"
+	if got := r.ICEServers(2); got[0].URLs[0] != "turn:eu.example.com:3478" {
+		t.Errorf("override was modified through a returned slice: %+v", got)
+	}
+
+	// Clearing the override falls back to the global list.
+	if err := r.SetChannelICEServers(2, nil); err != nil {
+		t.Fatalf("clear channel ice servers: %v", err)
+	}
+	if got := r.ICEServers(2); len(got) != 1 || got[0].URLs[0] != "stun:stun.example.com:3478" {
+		t.Errorf("after clearing the override = %+v, want the global list", got)
+	}
+}
+
+func TestSetICEServersRejectsBadURLs(t *testing.T) {
+	r := NewChannelState("")
+	for _, servers := range [][]protocol.ICEServer{
+		{{}},
+		{{URLs: []string{"http://example.com"}}},
+	} {
+		if err := r.SetChannelICEServers(1, servers); err == nil {
+			t.Errorf("SetChannelICEServers(%+v) succeeded, want an error", servers)
+		}
+	}
+}
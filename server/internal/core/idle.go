@@ -0,0 +1,79 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"bken/server/internal/protocol"
+)
+
+// SetIdleTimeout sets how long a user may sit in a voice channel without
+// reporting voice activity before CheckIdleClients moves them out of voice.
+// Zero (the default) disables idle detection.
+func (r *ChannelState) SetIdleTimeout(d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("idle timeout must not be negative")
+	}
+	r.mu.Lock()
+	r.idleTimeout = d
+	r.mu.Unlock()
+	return nil
+}
+
+// MarkVoiceActivity records that a user in voice is transmitting, resetting
+// their idle timer. Voice travels peer to peer, so clients report activity
+// over the control channel instead of the server observing packets.
+func (r *ChannelState) MarkVoiceActivity(userID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.users[userID]; ok && u.voice != nil {
+		u.lastVoice = r.now()
+	}
+}
+
+// CheckIdleClients disconnects from voice every user whose last voice
+// activity is older than the idle timeout, notifying the user and the rest
+// of the server with a user_state; a user who was recording is also
+// announced as stopped to the channel they left. Users not in voice are
+// never affected. It returns the IDs of the users that were moved.
+func (r *ChannelState) CheckIdleClients() []string {
+	type move struct {
+		user      protocol.User
+		voice     protocol.VoiceState
+		recording bool
+	}
+
+	r.mu.Lock()
+	if r.idleTimeout <= 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	cutoff := r.now().Add(-r.idleTimeout)
+	var moves []move
+	for id, u := range r.users {
+		if u.voice == nil || !u.lastVoice.Before(cutoff) {
+			continue
+		}
+		voice, recording := *u.voice, recordingLocked(u)
+		u.voice = nil
+		u.muted = false
+		u.deafened = false
+		u.recordingIn, u.consentIn = "", ""
+		slog.Info("voice idle timeout", "user_id", id, "was_server", voice.ServerID, "timeout", r.idleTimeout)
+		moves = append(moves, move{user: toProtocolUser(u), voice: voice, recording: recording})
+	}
+	r.mu.Unlock()
+
+	ids := make([]string, 0, len(moves))
+	for _, m := range moves {
+		user := m.user
+		r.SendTo(user.ID, protocol.Message{Type: protocol.TypeUserState, User: &user})
+		r.BroadcastToServer(m.voice.ServerID, protocol.Message{Type: protocol.TypeUserState, User: &user}, user.ID)
+		if m.recording {
+			r.BroadcastToVoiceChannel(m.voice.ServerID, m.voice.ChannelID, protocol.Message{Type: protocol.TypeRecordingStopped, UserID: user.ID}, user.ID)
+		}
+		ids = append(ids, user.ID)
+	}
+	return ids
+}
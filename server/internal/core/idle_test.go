@@ -0,0 +1,99 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"bken/server/internal/protocol"
+)
+
+func TestCheckIdleClients(t *testing.T) {
+	r := NewChannelState("")
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+	if err := r.SetIdleTimeout(time.Minute); err != nil {
+		t.Fatalf("set idle timeout: %v", err)
+	}
+
+	talker, _, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add alice: %v", err)
+	}
+	idle, _, err := r.Add("bob", 8)
+	if err != nil {
+		t.Fatalf("add bob: %v", err)
+	}
+	lobby, _, err := r.Add("carol", 8)
+	if err != nil {
+		t.Fatalf("add carol: %v", err)
+	}
+	for _, s := range []*Session{talker, idle, lobby} {
+		if _, _, err := r.ConnectServer(s.UserID, "srv-1"); err != nil {
+			t.Fatalf("connect server: %v", err)
+		}
+	}
+	for _, s := range []*Session{talker, idle} {
+		if _, _, err := r.JoinVoice(s.UserID, "srv-1", "chan-a"); err != nil {
+			t.Fatalf("join voice: %v", err)
+		}
+	}
+
+	now = now.Add(45 * time.Second)
+	r.MarkVoiceActivity(talker.UserID)
+	if moved := r.CheckIdleClients(); len(moved) != 0 {
+		t.Fatalf("expected nobody idle yet, got %v", moved)
+	}
+
+	now = now.Add(30 * time.Second)
+	moved := r.CheckIdleClients()
+	if len(moved) != 1 || moved[0] != idle.UserID {
+		t.Fatalf("expected only %s moved, got %v", idle.UserID, moved)
+	}
+	if u, _ := r.User(idle.UserID); u.Voice != nil {
+		t.Fatalf("idle user should have left voice, got %+v", u.Voice)
+	}
+	if u, _ := r.User(talker.UserID); u.Voice == nil {
+		t.Fatal("active user should still be in voice")
+	}
+
+	// The idle user and the other server member both see the move.
+	for _, s := range []*Session{idle, lobby} {
+		select {
+		case msg := <-s.Send:
+			if msg.Type != protocol.TypeUserState || msg.User == nil || msg.User.ID != idle.UserID || msg.User.Voice != nil {
+				t.Fatalf("unexpected message for %s: %+v", s.UserID, msg)
+			}
+		default:
+			t.Fatalf("expected user_state for %s", s.UserID)
+		}
+	}
+}
+
+func TestCheckIdleClientsDisabledByDefault(t *testing.T) {
+	r := NewChannelState("")
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+
+	s, _, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if _, _, err := r.ConnectServer(s.UserID, "srv-1"); err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+	if _, _, err := r.JoinVoice(s.UserID, "srv-1", "chan-a"); err != nil {
+		t.Fatalf("join voice: %v", err)
+	}
+
+	now = now.Add(24 * time.Hour)
+	if moved := r.CheckIdleClients(); len(moved) != 0 {
+		t.Fatalf("expected no moves with idle timeout disabled, got %v", moved)
+	}
+}
+
+func TestSetIdleTimeoutRejectsNegative(t *testing.T) {
+	r := NewChannelState("")
+	if err := r.SetIdleTimeout(-time.Second); err == nil {
+		t.Fatal("expected error for negative idle timeout")
+	}
+}
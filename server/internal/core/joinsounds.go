@@ -0,0 +1,47 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// maxSoundURLLength bounds a join or leave sound URL.
+const maxSoundURLLength = 2048
+
+// SetJoinSounds sets the sounds clients play when someone joins or leaves,
+// sent to each user in their snapshot. Each is an absolute http(s) URL or a
+// path such as /api/blobs/<id> on this server's REST API; empty leaves the
+// client's bundled sound in place.
+func (r *ChannelState) SetJoinSounds(joinURL, leaveURL string) error {
+	joinURL, leaveURL = strings.TrimSpace(joinURL), strings.TrimSpace(leaveURL)
+	for _, u := range []string{joinURL, leaveURL} {
+		if err := validSoundURL(u); err != nil {
+			return err
+		}
+	}
+	r.mu.Lock()
+	r.joinSoundURL = joinURL
+	r.leaveSoundURL = leaveURL
+	r.mu.Unlock()
+	slog.Info("join sounds set", "join", joinURL, "leave", leaveURL)
+	return nil
+}
+
+// JoinSounds returns the join and leave sound URLs; "" means the bundled
+// sound.
+func (r *ChannelState) JoinSounds() (joinURL, leaveURL string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.joinSoundURL, r.leaveSoundURL
+}
+
+func validSoundURL(u string) error {
+	if len(u) > maxSoundURLLength {
+		return fmt.Errorf("sound url must be at most %d bytes", maxSoundURLLength)
+	}
+	if u == "" || strings.HasPrefix(u, "/") || strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+		return nil
+	}
+	return fmt.Errorf("sound url %q must be an http(s) URL or start with /", u)
+}
@@ -0,0 +1,27 @@
+package core
+
+import "testing"
+
+func TestSetJoinSounds(t *testing.T) {
+	r := NewChannelState("")
+	if err := r.SetJoinSounds(" /api/blobs/chime ", "https://cdn.example/leave.wav"); err != nil {
+		t.Fatalf("set join sounds: %v", err)
+	}
+	join, leave := r.JoinSounds()
+	if join != "/api/blobs/chime" || leave != "https://cdn.example/leave.wav" {
+		t.Fatalf("unexpected sounds %q, %q", join, leave)
+	}
+
+	if err := r.SetJoinSounds("ftp://example/chime.wav", ""); err == nil {
+		t.Fatal("expected an error for a non-http URL")
+	}
+	if join, _ := r.JoinSounds(); join != "/api/blobs/chime" {
+		t.Fatalf("rejected update changed the join sound to %q", join)
+	}
+	if err := r.SetJoinSounds("", ""); err != nil {
+		t.Fatalf("clear join sounds: %v", err)
+	}
+	if join, leave := r.JoinSounds(); join != "" || leave != "" {
+		t.Fatalf("expected sounds cleared, got %q, %q", join, leave)
+	}
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Mentioned returns the IDs, sorted, of users connected to serverID whose
+// "@username" appears in text. The sender is never included, and users who
+// are not connected to the server are skipped.
+func (r *ChannelState) Mentioned(serverID, senderID, text string) []string {
+	serverID = strings.TrimSpace(serverID)
+	if serverID == "" || !strings.Contains(text, "@") {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var ids []string
+	for id, u := range r.users {
+		if id == senderID {
+			continue
+		}
+		if _, ok := u.connected[serverID]; !ok {
+			continue
+		}
+		if mentions(text, u.username) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// mentions reports whether text contains "@username" as a whole word, so
+// "@bob" does not match a user named "bo".
+func mentions(text, username string) bool {
+	tag := "@" + username
+	for i := 0; ; {
+		j := strings.Index(text[i:], tag)
+		if j < 0 {
+			return false
+		}
+		end := i + j + len(tag)
+		next, _ := utf8.DecodeRuneInString(text[end:])
+		if end == len(text) || !(unicode.IsLetter(next) || unicode.IsDigit(next) || next == '_') {
+			return true
+		}
+		i += j + 1
+	}
+}
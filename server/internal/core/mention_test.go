@@ -0,0 +1,42 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMentioned(t *testing.T) {
+	r := NewChannelState("")
+	alice, _, _ := r.Add("alice", 8)
+	bob, _, _ := r.Add("bob", 8)
+	bo, _, _ := r.Add("bo", 8)
+	r.Add("carol", 8) // never connects to srv-1
+	for _, s := range []*Session{alice, bob, bo} {
+		if _, _, err := r.ConnectServer(s.UserID, "srv-1"); err != nil {
+			t.Fatalf("connect server: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"no mentions", "hello everyone", nil},
+		{"one mention", "hey @bob, look", []string{bob.UserID}},
+		{"whole word only", "@bobby is not bob", nil},
+		{"prefix user", "@bo and @bob", []string{bob.UserID, bo.UserID}},
+		{"self mention ignored", "note to @alice", nil},
+		{"not on server skipped", "@carol are you there", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.Mentioned("srv-1", alice.UserID, tt.text)
+			want := slices.Clone(tt.want)
+			slices.Sort(want)
+			if !slices.Equal(got, want) {
+				t.Errorf("Mentioned(%q) = %v, want %v", tt.text, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"sort"
+	"strconv"
+
+	"bken/server/internal/protocol"
+)
+
+// Counters is a snapshot of monotonic traffic totals since process start.
+// They only ever increase, which is what Prometheus-style scrapers expect.
+type Counters struct {
+	MessagesSent    uint64 // control messages queued to a subscriber
+	MessagesSkipped uint64 // control messages dropped because a subscriber's queue was full
+	BytesIn         uint64 // websocket payload bytes received from clients
+	BytesOut        uint64 // websocket payload bytes written to clients
+	Bans            uint64 // users banned and disconnected
+}
+
+// VoiceOccupancy is the number of users in one voice channel.
+type VoiceOccupancy struct {
+	ServerID  string
+	ChannelID string
+	Users     int
+}
+
+// Counters returns the current traffic totals.
+func (r *ChannelState) Counters() Counters {
+	return Counters{
+		MessagesSent:    r.messagesSent.Load(),
+		MessagesSkipped: r.messagesSkipped.Load(),
+		BytesIn:         r.bytesIn.Load(),
+		BytesOut:        r.bytesOut.Load(),
+		Bans:            r.bans.Load(),
+	}
+}
+
+// AddBytesIn records n payload bytes read from a client.
+func (r *ChannelState) AddBytesIn(n int) {
+	r.bytesIn.Add(uint64(n))
+}
+
+// AddBytesOut records n payload bytes written to a client.
+func (r *ChannelState) AddBytesOut(n int) {
+	r.bytesOut.Add(uint64(n))
+}
+
+// VoiceOccupancy returns per-channel voice user counts, ordered by server
+// then channel. Every known channel is listed, so an emptied channel reports
+// zero instead of disappearing from the scrape.
+func (r *ChannelState) VoiceOccupancy() []VoiceOccupancy {
+	type key struct{ server, channel string }
+	counts := make(map[key]int)
+
+	r.mu.RLock()
+	for serverID, chs := range r.channels {
+		for _, ch := range chs {
+			counts[key{serverID, strconv.FormatInt(ch.ID, 10)}] = 0
+		}
+	}
+	for _, u := range r.users {
+		if u.voice != nil {
+			counts[key{u.voice.ServerID, u.voice.ChannelID}]++
+		}
+	}
+	r.mu.RUnlock()
+
+	out := make([]VoiceOccupancy, 0, len(counts))
+	for k, n := range counts {
+		out = append(out, VoiceOccupancy{ServerID: k.server, ChannelID: k.channel, Users: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ServerID != out[j].ServerID {
+			return out[i].ServerID < out[j].ServerID
+		}
+		return out[i].ChannelID < out[j].ChannelID
+	})
+	return out
+}
+
+// deliver queues msg on ch and counts the outcome.
+func (r *ChannelState) deliver(ch chan protocol.Message, msg protocol.Message) bool {
+	if trySend(ch, msg) {
+		r.messagesSent.Add(1)
+		return true
+	}
+	r.messagesSkipped.Add(1)
+	return false
+}
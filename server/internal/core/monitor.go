@@ -0,0 +1,86 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+
+	"bken/server/internal/protocol"
+)
+
+// MaxMonitoredChannels bounds how many channels one user monitors at once.
+const MaxMonitoredChannels = 4
+
+// MonitorChannel lets a moderator in voice also listen to channelID on the
+// same server without leaving their own channel. Voice is peer to peer, so
+// the server only records the membership: it is published in the user's
+// voice state, and members of the monitored channel send their audio to
+// the monitor too. A user recording their channel may not monitor another;
+// see StartRecording. It returns the updated user to broadcast.
+func (r *ChannelState) MonitorChannel(userID, channelID string) (protocol.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[userID]
+	if !ok {
+		return protocol.User{}, fmt.Errorf("user not found")
+	}
+	if !r.meetsLocked(u, RoleModerator) {
+		return protocol.User{}, ErrNotPermitted
+	}
+	if u.voice == nil {
+		return protocol.User{}, fmt.Errorf("join a voice channel to monitor another")
+	}
+	if _, ok := r.channelLocked(u.voice.ServerID, channelID); !ok {
+		return protocol.User{}, fmt.Errorf("channel not found")
+	}
+	if channelID == u.voice.ChannelID {
+		return protocol.User{}, fmt.Errorf("you are already in that channel")
+	}
+	if slices.Contains(u.voice.Monitoring, channelID) {
+		return toProtocolUser(u), nil
+	}
+	if recordingLocked(u) {
+		return protocol.User{}, fmt.Errorf("stop recording before monitoring another channel")
+	}
+	if len(u.voice.Monitoring) >= MaxMonitoredChannels {
+		return protocol.User{}, fmt.Errorf("at most %d channels can be monitored at once", MaxMonitoredChannels)
+	}
+	u.voice.Monitoring = append(slices.Clone(u.voice.Monitoring), channelID)
+	slog.Info("channel monitored", "user_id", userID, "server_id", u.voice.ServerID, "channel_id", channelID)
+	return toProtocolUser(u), nil
+}
+
+// UnmonitorChannel stops userID listening to channelID. Monitoring also
+// ends when the user leaves voice or changes server.
+func (r *ChannelState) UnmonitorChannel(userID, channelID string) (protocol.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[userID]
+	if !ok {
+		return protocol.User{}, fmt.Errorf("user not found")
+	}
+	if u.voice == nil || !slices.Contains(u.voice.Monitoring, channelID) {
+		return protocol.User{}, fmt.Errorf("not monitoring that channel")
+	}
+	u.voice.Monitoring = slices.DeleteFunc(slices.Clone(u.voice.Monitoring), func(id string) bool { return id == channelID })
+	slog.Info("channel unmonitored", "user_id", userID, "server_id", u.voice.ServerID, "channel_id", channelID)
+	return toProtocolUser(u), nil
+}
+
+// Monitors returns the IDs of users monitoring a channel, sorted.
+func (r *ChannelState) Monitors(serverID, channelID string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []string
+	for _, u := range r.users {
+		if u.voice != nil && u.voice.ServerID == serverID && slices.Contains(u.voice.Monitoring, channelID) {
+			out = append(out, u.id)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
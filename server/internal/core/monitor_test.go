@@ -0,0 +1,82 @@
+package core
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestMonitorChannelMembership(t *testing.T) {
+	r := NewChannelState("")
+	owner, _, _ := r.Add("owner", 8)
+	mod, _, _ := r.Add("mod", 8)
+	user, _, _ := r.Add("user", 8)
+	if err := r.SetRole(mod.UserID, RoleModerator); err != nil {
+		t.Fatalf("set moderator: %v", err)
+	}
+	for _, s := range []*Session{owner, mod, user} {
+		if _, _, err := r.ConnectServer(s.UserID, "srv-1"); err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+	}
+	chs, _ := r.CreateChannel("srv-1", "second")
+	lobby := strconv.FormatInt(chs[0].ID, 10)
+	second := strconv.FormatInt(chs[1].ID, 10)
+	for _, s := range []*Session{mod, user} {
+		if _, _, err := r.JoinVoice(s.UserID, "srv-1", lobby); err != nil {
+			t.Fatalf("join voice: %v", err)
+		}
+	}
+
+	if _, err := r.MonitorChannel(user.UserID, second); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("plain user: err = %v, want ErrNotPermitted", err)
+	}
+	if _, err := r.MonitorChannel(owner.UserID, second); err == nil {
+		t.Fatal("expected an error monitoring from outside voice")
+	}
+	if _, err := r.MonitorChannel(mod.UserID, lobby); err == nil {
+		t.Fatal("expected an error monitoring the channel already joined")
+	}
+	if _, err := r.MonitorChannel(mod.UserID, "999"); err == nil {
+		t.Fatal("expected an error for an unknown channel")
+	}
+
+	u, err := r.MonitorChannel(mod.UserID, second)
+	if err != nil {
+		t.Fatalf("monitor: %v", err)
+	}
+	if u.Voice == nil || u.Voice.ChannelID != lobby || !slices.Equal(u.Voice.Monitoring, []string{second}) {
+		t.Fatalf("unexpected voice state: %+v", u.Voice)
+	}
+	if got := r.Monitors("srv-1", second); !slices.Equal(got, []string{mod.UserID}) {
+		t.Fatalf("Monitors = %v, want [%s]", got, mod.UserID)
+	}
+
+	// Joining the monitored channel ends monitoring it.
+	u, _, err = r.JoinVoice(mod.UserID, "srv-1", second)
+	if err != nil {
+		t.Fatalf("switch channel: %v", err)
+	}
+	if len(u.Voice.Monitoring) != 0 || len(r.Monitors("srv-1", second)) != 0 {
+		t.Fatalf("monitoring should end on joining the channel: %+v", u.Voice)
+	}
+
+	// Leaving voice ends it too.
+	if _, err := r.MonitorChannel(mod.UserID, lobby); err != nil {
+		t.Fatalf("monitor lobby: %v", err)
+	}
+	if _, _, err := r.JoinVoice(mod.UserID, "srv-1", lobby); err != nil {
+		t.Fatalf("switch back: %v", err)
+	}
+	if _, err := r.MonitorChannel(mod.UserID, second); err != nil {
+		t.Fatalf("monitor second: %v", err)
+	}
+	r.DisconnectVoice(mod.UserID)
+	if got := r.Monitors("srv-1", second); len(got) != 0 {
+		t.Fatalf("monitors after leaving voice = %v", got)
+	}
+	if _, err := r.UnmonitorChannel(mod.UserID, second); err == nil {
+		t.Fatal("expected an error unmonitoring after leaving voice")
+	}
+}
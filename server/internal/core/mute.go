@@ -0,0 +1,124 @@
+package core
+
+import (
+	"log/slog"
+	"time"
+
+	"bken/server/internal/protocol"
+)
+
+// MuteChange describes a server mute set or lifted by MuteUser, UnmuteUser
+// or CheckMuteExpiry.
+type MuteChange struct {
+	User      protocol.User
+	ActorName string
+	Muted     bool
+	// Duration is how long a new mute lasts; zero means until lifted.
+	Duration time.Duration
+}
+
+// MuteUser mutes targetID in voice on behalf of actorID for d, or until
+// UnmuteUser when d is zero. The actor must be a moderator or above and
+// must outrank the target; otherwise ErrNotPermitted is returned. While the
+// mute lasts the user joins voice muted and may not unmute themselves.
+// Voice is peer to peer, so clients stop transmitting when they see their
+// own muted flag.
+func (r *ChannelState) MuteUser(actorID, targetID string, d time.Duration) (MuteChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	actor, target, err := r.moderationPartiesLocked(actorID, targetID, RoleModerator)
+	if err != nil {
+		return MuteChange{}, err
+	}
+	if !target.serverMuted {
+		target.selfMuted = target.voice != nil && target.muted
+	}
+	target.serverMuted = true
+	target.serverMuteUntil = time.Time{}
+	if d > 0 {
+		target.serverMuteUntil = r.now().Add(d)
+	}
+	if target.voice != nil {
+		target.muted = true
+	}
+	slog.Info("user muted", "user_id", targetID, "username", target.username, "by", actorID, "duration", d)
+	return MuteChange{User: toProtocolUser(target), ActorName: actor.username, Muted: true, Duration: d}, nil
+}
+
+// UnmuteUser lifts a server mute on behalf of actorID under the same rules
+// as MuteUser.
+func (r *ChannelState) UnmuteUser(actorID, targetID string) (MuteChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	actor, target, err := r.moderationPartiesLocked(actorID, targetID, RoleModerator)
+	if err != nil {
+		return MuteChange{}, err
+	}
+	r.liftMuteLocked(target)
+	slog.Info("user unmuted", "user_id", targetID, "username", target.username, "by", actorID)
+	return MuteChange{User: toProtocolUser(target), ActorName: actor.username}, nil
+}
+
+// ServerMuted reports whether userID is muted by a moderator.
+func (r *ChannelState) ServerMuted(userID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	u, ok := r.users[userID]
+	return ok && u.serverMuted
+}
+
+// CheckMuteExpiry lifts every server mute whose time is up, broadcasting
+// user_muted and, for users in voice, their unmuted user_state. It returns
+// the users that were unmuted.
+func (r *ChannelState) CheckMuteExpiry() []protocol.User {
+	r.mu.Lock()
+	now := r.now()
+	var lifted []protocol.User
+	for id, u := range r.users {
+		if !u.serverMuted || u.serverMuteUntil.IsZero() || u.serverMuteUntil.After(now) {
+			continue
+		}
+		r.liftMuteLocked(u)
+		slog.Info("user mute expired", "user_id", id)
+		lifted = append(lifted, toProtocolUser(u))
+	}
+	r.mu.Unlock()
+
+	for i := range lifted {
+		r.AnnounceMute(MuteChange{User: lifted[i]})
+	}
+	return lifted
+}
+
+// AnnounceMute broadcasts user_muted for c, followed by the user's voice
+// state when they are in voice.
+func (r *ChannelState) AnnounceMute(c MuteChange) {
+	user := c.User
+	muted := c.Muted
+	r.Broadcast(protocol.Message{
+		Type:      protocol.TypeUserMuted,
+		UserID:    user.ID,
+		Muted:     &muted,
+		DurationS: int64(c.Duration / time.Second),
+	}, "")
+	if user.Voice != nil {
+		r.BroadcastToServer(user.Voice.ServerID, protocol.Message{Type: protocol.TypeUserState, User: &user}, "")
+	}
+}
+
+// liftMuteLocked clears u's server mute and, if their channel lets them
+// speak and they are not waiting to consent to a recording, puts their own
+// mute back as it was before. Caller holds r.mu.
+func (r *ChannelState) liftMuteLocked(u *userState) {
+	if !u.serverMuted {
+		return
+	}
+	u.serverMuted = false
+	u.serverMuteUntil = time.Time{}
+	if u.voice != nil {
+		if ok, _ := r.canSpeakLocked(u); ok && !r.awaitingConsentLocked(u) {
+			u.muted = u.selfMuted
+		}
+	}
+	u.selfMuted = false
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"bken/server/internal/protocol"
+)
+
+func TestMuteUserExpiresAndAutoUnmutes(t *testing.T) {
+	r := NewChannelState("")
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+
+	owner, _, _ := r.Add("owner", 8)
+	mod, _, _ := r.Add("mod", 8)
+	user, _, _ := r.Add("user", 64)
+	if err := r.SetRole(mod.UserID, RoleModerator); err != nil {
+		t.Fatalf("set moderator: %v", err)
+	}
+	if _, _, err := r.ConnectServer(user.UserID, "srv-1"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if _, _, err := r.JoinVoice(user.UserID, "srv-1", "chan-a"); err != nil {
+		t.Fatalf("join voice: %v", err)
+	}
+
+	for _, tc := range []struct{ actor, target *Session }{
+		{user, mod},  // regular users cannot mute
+		{mod, owner}, // nobody outranks the owner
+		{mod, mod},   // nor themselves
+	} {
+		if _, err := r.MuteUser(tc.actor.UserID, tc.target.UserID, 0); !errors.Is(err, ErrNotPermitted) {
+			t.Errorf("%s muting %s: expected ErrNotPermitted, got %v", tc.actor.Username, tc.target.Username, err)
+		}
+	}
+
+	change, err := r.MuteUser(mod.UserID, user.UserID, 10*time.Second)
+	if err != nil {
+		t.Fatalf("mute: %v", err)
+	}
+	if !change.Muted || change.ActorName != "mod" || change.User.Voice == nil || !change.User.Voice.Muted {
+		t.Fatalf("unexpected mute change: %+v", change)
+	}
+	if !r.ServerMuted(user.UserID) {
+		t.Fatal("expected the user to be server muted")
+	}
+
+	// Rejoining voice does not shake off the mute.
+	r.DisconnectVoice(user.UserID)
+	u, _, err := r.JoinVoice(user.UserID, "srv-1", "chan-a")
+	if err != nil || !u.Voice.Muted {
+		t.Fatalf("rejoined unmuted: %+v, %v", u.Voice, err)
+	}
+
+	now = now.Add(9 * time.Second)
+	if lifted := r.CheckMuteExpiry(); len(lifted) != 0 {
+		t.Fatalf("mute lifted early: %+v", lifted)
+	}
+	now = now.Add(time.Second)
+	lifted := r.CheckMuteExpiry()
+	if len(lifted) != 1 || lifted[0].ID != user.UserID || lifted[0].Voice.Muted {
+		t.Fatalf("expected the user unmuted on expiry, got %+v", lifted)
+	}
+	if r.ServerMuted(user.UserID) {
+		t.Fatal("mute should have expired")
+	}
+	for {
+		msg := <-user.Send
+		if msg.Type == protocol.TypeUserMuted {
+			if msg.UserID != user.UserID || msg.Muted == nil || *msg.Muted {
+				t.Fatalf("unexpected user_muted: %+v", msg)
+			}
+			break
+		}
+	}
+}
+
+func TestIndefiniteMuteLastsUntilUnmuted(t *testing.T) {
+	r := NewChannelState("")
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+
+	owner, _, _ := r.Add("owner", 8)
+	user, _, _ := r.Add("user", 8)
+	if _, err := r.MuteUser(owner.UserID, user.UserID, 0); err != nil {
+		t.Fatalf("mute: %v", err)
+	}
+	now = now.Add(365 * 24 * time.Hour)
+	if lifted := r.CheckMuteExpiry(); len(lifted) != 0 {
+		t.Fatalf("indefinite mute expired: %+v", lifted)
+	}
+	if _, err := r.UnmuteUser(owner.UserID, user.UserID); err != nil {
+		t.Fatalf("unmute: %v", err)
+	}
+	if r.ServerMuted(user.UserID) {
+		t.Fatal("expected the mute to be lifted")
+	}
+}
+
+func TestLiftedMuteRestoresSelfMute(t *testing.T) {
+	r := NewChannelState("")
+	owner, _, _ := r.Add("owner", 8)
+	user, _, _ := r.Add("user", 8)
+	if _, _, err := r.ConnectServer(user.UserID, "srv-1"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if _, _, err := r.JoinVoice(user.UserID, "srv-1", "chan-a"); err != nil {
+		t.Fatalf("join voice: %v", err)
+	}
+
+	for _, selfMuted := range []bool{true, false} {
+		r.SetVoiceFlags(user.UserID, selfMuted, false)
+		if _, err := r.MuteUser(owner.UserID, user.UserID, 0); err != nil {
+			t.Fatalf("mute: %v", err)
+		}
+		// Muting again does not lose what the user had chosen.
+		if _, err := r.MuteUser(owner.UserID, user.UserID, time.Minute); err != nil {
+			t.Fatalf("mute again: %v", err)
+		}
+		change, err := r.UnmuteUser(owner.UserID, user.UserID)
+		if err != nil {
+			t.Fatalf("unmute: %v", err)
+		}
+		if change.User.Voice.Muted != selfMuted {
+			t.Errorf("self-muted %v before the mute, got muted %v after it", selfMuted, change.User.Voice.Muted)
+		}
+	}
+}
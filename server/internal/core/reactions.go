@@ -0,0 +1,67 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// ReactionBurst is how many reaction changes one user may make on one
+// message per ReactionWindow. It leaves room to react with several emoji
+// at once while stopping a client from toggling a reaction in a loop.
+const (
+	ReactionBurst  = 10
+	ReactionWindow = 5 * time.Second
+)
+
+// ReactionRateError is returned by CheckReactionRate when the user has used
+// up their reaction changes on a message for the current window.
+type ReactionRateError struct {
+	Remaining time.Duration
+}
+
+func (e *ReactionRateError) Error() string {
+	secs := int((e.Remaining + time.Second - 1) / time.Second)
+	return fmt.Sprintf("reacting too quickly; try again in %ds", secs)
+}
+
+// reactionWindow counts one user's reaction changes on one message since
+// start.
+type reactionWindow struct {
+	start time.Time
+	count int
+}
+
+// CheckReactionRate records a reaction change by userID on msgID, or
+// returns a *ReactionRateError if the user already made ReactionBurst
+// changes on that message within ReactionWindow. Other messages have their
+// own budget, so reacting across a channel is unaffected.
+func (r *ChannelState) CheckReactionRate(userID string, msgID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[userID]
+	if !ok {
+		return fmt.Errorf("user not found")
+	}
+
+	now := r.now()
+	w := u.reactions[msgID]
+	if now.Sub(w.start) >= ReactionWindow {
+		// Expired windows on other messages go too, so the map only holds
+		// messages reacted to recently.
+		for id, old := range u.reactions {
+			if now.Sub(old.start) >= ReactionWindow {
+				delete(u.reactions, id)
+			}
+		}
+		w = reactionWindow{start: now}
+	}
+	if w.count >= ReactionBurst {
+		return &ReactionRateError{Remaining: w.start.Add(ReactionWindow).Sub(now)}
+	}
+	w.count++
+	if u.reactions == nil {
+		u.reactions = make(map[int64]reactionWindow)
+	}
+	u.reactions[msgID] = w
+	return nil
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckReactionRate(t *testing.T) {
+	r := NewChannelState("")
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+	s, _, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	for i := 0; i < ReactionBurst; i++ {
+		if err := r.CheckReactionRate(s.UserID, 7); err != nil {
+			t.Fatalf("change %d: %v", i+1, err)
+		}
+	}
+	now = now.Add(time.Second)
+	err = r.CheckReactionRate(s.UserID, 7)
+	var limited *ReactionRateError
+	if !errors.As(err, &limited) {
+		t.Fatalf("expected *ReactionRateError past the burst, got %v", err)
+	}
+	if limited.Remaining != ReactionWindow-time.Second {
+		t.Fatalf("remaining = %v, want %v", limited.Remaining, ReactionWindow-time.Second)
+	}
+
+	// Another message has its own budget.
+	if err := r.CheckReactionRate(s.UserID, 8); err != nil {
+		t.Fatalf("other message: %v", err)
+	}
+
+	// The budget refills once the window has passed.
+	now = now.Add(ReactionWindow)
+	if err := r.CheckReactionRate(s.UserID, 7); err != nil {
+		t.Fatalf("after the window: %v", err)
+	}
+	if len(r.users[s.UserID].reactions) != 1 {
+		t.Fatalf("expired windows should be pruned, have %d", len(r.users[s.UserID].reactions))
+	}
+}
@@ -0,0 +1,34 @@
+package core
+
+import "slices"
+
+// MaxReadReceiptMessages bounds how many messages keep read receipts. When
+// a new message would exceed it, the receipts of the message that was first
+// read longest ago are dropped.
+const MaxReadReceiptMessages = 1000
+
+// MarkRead records that userID has read msgID. It returns the message's
+// readers, in the order they first read it, and whether userID was new to
+// that set; each reader counts once per message.
+func (r *ChannelState) MarkRead(msgID int64, userID string) ([]string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	readers, tracked := r.receipts[msgID]
+	if slices.Contains(readers, userID) {
+		return slices.Clone(readers), false
+	}
+	if !tracked {
+		if r.receipts == nil {
+			r.receipts = make(map[int64][]string)
+		}
+		if len(r.receiptOrder) >= MaxReadReceiptMessages {
+			delete(r.receipts, r.receiptOrder[0])
+			r.receiptOrder = r.receiptOrder[1:]
+		}
+		r.receiptOrder = append(r.receiptOrder, msgID)
+	}
+	readers = append(readers, userID)
+	r.receipts[msgID] = readers
+	return slices.Clone(readers), true
+}
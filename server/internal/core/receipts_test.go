@@ -0,0 +1,37 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMarkReadCountsEachReaderOnce(t *testing.T) {
+	r := NewChannelState("")
+
+	if readers, ok := r.MarkRead(7, "u1"); !ok || !slices.Equal(readers, []string{"u1"}) {
+		t.Fatalf("first read = %v, %v", readers, ok)
+	}
+	if readers, ok := r.MarkRead(7, "u1"); ok || !slices.Equal(readers, []string{"u1"}) {
+		t.Fatalf("repeat read = %v, %v; want no change", readers, ok)
+	}
+	if readers, ok := r.MarkRead(7, "u2"); !ok || !slices.Equal(readers, []string{"u1", "u2"}) {
+		t.Fatalf("second reader = %v, %v", readers, ok)
+	}
+}
+
+func TestMarkReadIsBounded(t *testing.T) {
+	r := NewChannelState("")
+	for id := int64(1); id <= MaxReadReceiptMessages+1; id++ {
+		r.MarkRead(id, "u1")
+	}
+	if len(r.receipts) != MaxReadReceiptMessages {
+		t.Fatalf("tracking %d messages, want %d", len(r.receipts), MaxReadReceiptMessages)
+	}
+	// The oldest message was evicted, so reading it again starts afresh.
+	if _, ok := r.receipts[1]; ok {
+		t.Fatal("oldest message should have been evicted")
+	}
+	if readers, ok := r.MarkRead(MaxReadReceiptMessages+1, "u1"); ok || len(readers) != 1 {
+		t.Fatalf("newest message lost its receipts: %v, %v", readers, ok)
+	}
+}
@@ -0,0 +1,146 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"bken/server/internal/protocol"
+)
+
+// SetRecordingConsent selects whether members of a voice channel must
+// accept before they are heard while someone in it records. Recordings are
+// made locally from the audio a client receives, so a member who has not
+// accepted is kept muted: nothing they say reaches the recorder. Members
+// who decline leave voice.
+func (r *ChannelState) SetRecordingConsent(required bool) {
+	r.mu.Lock()
+	r.recordingConsent = required
+	r.mu.Unlock()
+}
+
+// RecordingConsentRequired reports whether SetRecordingConsent is on.
+func (r *ChannelState) RecordingConsentRequired() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.recordingConsent
+}
+
+// StartRecording records that userID is recording their voice channel and
+// returns that channel, or nil when they are not in voice. When consent is
+// required, members of the channel who have not accepted are muted and
+// returned so the caller can broadcast their new state. A user monitoring
+// other channels may not record: the members of those channels would be
+// recorded without being told.
+func (r *ChannelState) StartRecording(userID string) (*protocol.VoiceState, []protocol.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[userID]
+	if !ok || u.voice == nil {
+		return nil, nil, nil
+	}
+	if len(u.voice.Monitoring) > 0 {
+		return nil, nil, fmt.Errorf("stop monitoring other channels before recording")
+	}
+	key := voiceKey(u.voice)
+	u.recordingIn = key
+	voice := *u.voice
+	slog.Info("recording started", "user_id", userID, "server_id", voice.ServerID, "channel_id", voice.ChannelID)
+
+	var muted []protocol.User
+	if r.recordingConsent {
+		for id, other := range r.users {
+			if id == userID || other.voice == nil || voiceKey(other.voice) != key || other.consentIn == key || other.muted {
+				continue
+			}
+			other.muted = true
+			muted = append(muted, toProtocolUser(other))
+		}
+		sort.Slice(muted, func(i, j int) bool { return userSeq(muted[i].ID) < userSeq(muted[j].ID) })
+	}
+	return &voice, muted, nil
+}
+
+// StopRecording records that userID stopped recording. It returns the
+// voice channel to tell, and false if they were not recording one.
+func (r *ChannelState) StopRecording(userID string) (protocol.VoiceState, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[userID]
+	if !ok || !recordingLocked(u) {
+		return protocol.VoiceState{}, false
+	}
+	u.recordingIn = ""
+	slog.Info("recording stopped", "user_id", userID)
+	return *u.voice, true
+}
+
+// Recorders returns the users recording a voice channel, in ID order.
+func (r *ChannelState) Recorders(serverID, channelID string) []string {
+	key := serverID + "/" + channelID
+	r.mu.RLock()
+	var ids []string
+	for id, u := range r.users {
+		if recordingLocked(u) && u.recordingIn == key {
+			ids = append(ids, id)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(ids, func(i, j int) bool { return userSeq(ids[i]) < userSeq(ids[j]) })
+	return ids
+}
+
+// ConsentToRecording records that userID accepts recordings of their
+// current voice channel. The user stays muted until they unmute.
+func (r *ChannelState) ConsentToRecording(userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[userID]
+	if !ok {
+		return fmt.Errorf("user not found")
+	}
+	if u.voice == nil {
+		return fmt.Errorf("user is not in voice")
+	}
+	u.consentIn = voiceKey(u.voice)
+	slog.Info("recording consent given", "user_id", userID, "server_id", u.voice.ServerID, "channel_id", u.voice.ChannelID)
+	return nil
+}
+
+// AwaitingConsent reports whether userID must accept recording before
+// they may unmute.
+func (r *ChannelState) AwaitingConsent(userID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	u, ok := r.users[userID]
+	return ok && r.awaitingConsentLocked(u)
+}
+
+// awaitingConsentLocked reports whether consent is required, someone else
+// is recording u's voice channel, and u has not accepted. Caller holds r.mu.
+func (r *ChannelState) awaitingConsentLocked(u *userState) bool {
+	if !r.recordingConsent || u.voice == nil {
+		return false
+	}
+	key := voiceKey(u.voice)
+	if u.consentIn == key {
+		return false
+	}
+	for id, other := range r.users {
+		if id != u.id && recordingLocked(other) && other.recordingIn == key {
+			return true
+		}
+	}
+	return false
+}
+
+// recordingLocked reports whether u is recording the voice channel they
+// are in. Caller holds r.mu.
+func recordingLocked(u *userState) bool {
+	return u.voice != nil && u.recordingIn == voiceKey(u.voice)
+}
+
+// voiceKey is the "server/channel" a voice state is in.
+func voiceKey(v *protocol.VoiceState) string {
+	return v.ServerID + "/" + v.ChannelID
+}
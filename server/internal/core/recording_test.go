@@ -0,0 +1,218 @@
+package core
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRecordingConsentMutesUntilAccepted(t *testing.T) {
+	r := NewChannelState("")
+	r.SetRecordingConsent(true)
+
+	rec, _, _ := r.Add("rec", 8)
+	bob, _, _ := r.Add("bob", 8)
+	carol, _, _ := r.Add("carol", 8)
+	for _, s := range []*Session{rec, bob, carol} {
+		if _, _, err := r.ConnectServer(s.UserID, "srv-1"); err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+	}
+	for _, s := range []*Session{rec, bob} {
+		if _, _, err := r.JoinVoice(s.UserID, "srv-1", "chan-a"); err != nil {
+			t.Fatalf("join voice: %v", err)
+		}
+	}
+	if _, _, err := r.JoinVoice(carol.UserID, "srv-1", "chan-b"); err != nil {
+		t.Fatalf("join voice: %v", err)
+	}
+
+	voice, muted, _ := r.StartRecording(rec.UserID)
+	if voice == nil || voice.ChannelID != "chan-a" {
+		t.Fatalf("unexpected recording channel: %+v", voice)
+	}
+	if len(muted) != 1 || muted[0].ID != bob.UserID || !muted[0].Voice.Muted {
+		t.Fatalf("expected only bob muted, got %+v", muted)
+	}
+	if got := r.Recorders("srv-1", "chan-a"); len(got) != 1 || got[0] != rec.UserID {
+		t.Fatalf("unexpected recorders: %v", got)
+	}
+	if r.AwaitingConsent(rec.UserID) || r.AwaitingConsent(carol.UserID) {
+		t.Fatal("only members of a channel someone else records need consent")
+	}
+	if !r.AwaitingConsent(bob.UserID) {
+		t.Fatal("expected bob to need consent")
+	}
+
+	// Someone joining mid-recording starts muted.
+	u, _, err := r.JoinVoice(carol.UserID, "srv-1", "chan-a")
+	if err != nil || !u.Voice.Muted {
+		t.Fatalf("joined a recorded channel unmuted: %+v, %v", u.Voice, err)
+	}
+
+	if err := r.ConsentToRecording(bob.UserID); err != nil {
+		t.Fatalf("consent: %v", err)
+	}
+	if r.AwaitingConsent(bob.UserID) {
+		t.Fatal("consent should clear the wait")
+	}
+
+	// Consent is for one channel; moving away and back asks again.
+	if _, _, err := r.JoinVoice(bob.UserID, "srv-1", "chan-b"); err != nil {
+		t.Fatalf("join voice: %v", err)
+	}
+	if _, _, err := r.JoinVoice(bob.UserID, "srv-1", "chan-a"); err != nil {
+		t.Fatalf("join voice: %v", err)
+	}
+	if !r.AwaitingConsent(bob.UserID) {
+		t.Fatal("consent should not follow bob to another channel")
+	}
+
+	if _, ok := r.StopRecording(rec.UserID); !ok {
+		t.Fatal("expected stop to report the recording")
+	}
+	if r.AwaitingConsent(bob.UserID) || len(r.Recorders("srv-1", "chan-a")) != 0 {
+		t.Fatal("stopping should end the wait")
+	}
+	if _, ok := r.StopRecording(rec.UserID); ok {
+		t.Fatal("second stop should be a no-op")
+	}
+}
+
+func TestRecordingEndsWhenRecorderLeavesVoice(t *testing.T) {
+	r := NewChannelState("")
+	r.SetRecordingConsent(true)
+
+	rec, _, _ := r.Add("rec", 8)
+	bob, _, _ := r.Add("bob", 8)
+	for _, s := range []*Session{rec, bob} {
+		if _, _, err := r.ConnectServer(s.UserID, "srv-1"); err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		if _, _, err := r.JoinVoice(s.UserID, "srv-1", "chan-a"); err != nil {
+			t.Fatalf("join voice: %v", err)
+		}
+	}
+	r.StartRecording(rec.UserID)
+	r.DisconnectVoice(rec.UserID)
+	if r.AwaitingConsent(bob.UserID) {
+		t.Fatal("a recorder who left voice should not hold others muted")
+	}
+
+	// Coming back does not resume the recording until asked again.
+	if _, _, err := r.JoinVoice(rec.UserID, "srv-1", "chan-a"); err != nil {
+		t.Fatalf("join voice: %v", err)
+	}
+	if r.AwaitingConsent(bob.UserID) {
+		t.Fatal("recording resumed without start_recording")
+	}
+}
+
+func TestRecordingWithoutConsentModeMutesNobody(t *testing.T) {
+	r := NewChannelState("")
+	rec, _, _ := r.Add("rec", 8)
+	bob, _, _ := r.Add("bob", 8)
+	for _, s := range []*Session{rec, bob} {
+		if _, _, err := r.ConnectServer(s.UserID, "srv-1"); err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		if _, _, err := r.JoinVoice(s.UserID, "srv-1", "chan-a"); err != nil {
+			t.Fatalf("join voice: %v", err)
+		}
+	}
+	if voice, muted, _ := r.StartRecording(rec.UserID); voice == nil || len(muted) != 0 {
+		t.Fatalf("unexpected start: %+v, %+v", voice, muted)
+	}
+	if r.AwaitingConsent(bob.UserID) {
+		t.Fatal("consent is off")
+	}
+	if err := r.ConsentToRecording(rec.UserID); err != nil {
+		t.Fatalf("consent: %v", err)
+	}
+	r.DisconnectVoice(rec.UserID)
+	if err := r.ConsentToRecording(rec.UserID); err == nil {
+		t.Fatal("expected consent outside voice to fail")
+	}
+}
+
+func TestMuteExpiryKeepsConsentMute(t *testing.T) {
+	r := NewChannelState("")
+	r.SetRecordingConsent(true)
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+
+	rec, _, _ := r.Add("rec", 8)
+	mod, _, _ := r.Add("mod", 8)
+	bob, _, _ := r.Add("bob", 8)
+	if err := r.SetRole(mod.UserID, RoleModerator); err != nil {
+		t.Fatalf("set moderator: %v", err)
+	}
+	for _, s := range []*Session{rec, bob} {
+		if _, _, err := r.ConnectServer(s.UserID, "srv-1"); err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		if _, _, err := r.JoinVoice(s.UserID, "srv-1", "chan-a"); err != nil {
+			t.Fatalf("join voice: %v", err)
+		}
+	}
+	if _, err := r.MuteUser(mod.UserID, bob.UserID, 10*time.Second); err != nil {
+		t.Fatalf("mute: %v", err)
+	}
+	if voice, _, _ := r.StartRecording(rec.UserID); voice == nil {
+		t.Fatal("expected the recording to start")
+	}
+
+	now = now.Add(10 * time.Second)
+	lifted := r.CheckMuteExpiry()
+	if len(lifted) != 1 || !lifted[0].Voice.Muted {
+		t.Fatalf("expiry unmuted bob before consent: %+v", lifted)
+	}
+
+	// A moderator lifting the mute is no different.
+	if _, err := r.MuteUser(mod.UserID, bob.UserID, 0); err != nil {
+		t.Fatalf("mute: %v", err)
+	}
+	change, err := r.UnmuteUser(mod.UserID, bob.UserID)
+	if err != nil || !change.User.Voice.Muted {
+		t.Fatalf("unmute cleared the consent mute: %+v, %v", change.User.Voice, err)
+	}
+}
+
+func TestRecordingAndMonitoringExcludeEachOther(t *testing.T) {
+	r := NewChannelState("")
+	r.Add("owner", 8)
+	mod, _, _ := r.Add("mod", 8)
+	if err := r.SetRole(mod.UserID, RoleModerator); err != nil {
+		t.Fatalf("set moderator: %v", err)
+	}
+	if _, _, err := r.ConnectServer(mod.UserID, "srv-1"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	chs, _ := r.CreateChannel("srv-1", "second")
+	lobby := strconv.FormatInt(chs[0].ID, 10)
+	second := strconv.FormatInt(chs[1].ID, 10)
+	if _, _, err := r.JoinVoice(mod.UserID, "srv-1", lobby); err != nil {
+		t.Fatalf("join voice: %v", err)
+	}
+
+	// The monitored channel's members would be recorded without notice.
+	if _, err := r.MonitorChannel(mod.UserID, second); err != nil {
+		t.Fatalf("monitor: %v", err)
+	}
+	if voice, _, err := r.StartRecording(mod.UserID); err == nil || voice != nil {
+		t.Fatalf("recorded while monitoring: %+v, %v", voice, err)
+	}
+	if len(r.Recorders("srv-1", lobby)) != 0 {
+		t.Fatal("a refused recording should not be listed")
+	}
+
+	if _, err := r.UnmonitorChannel(mod.UserID, second); err != nil {
+		t.Fatalf("unmonitor: %v", err)
+	}
+	if _, _, err := r.StartRecording(mod.UserID); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if _, err := r.MonitorChannel(mod.UserID, second); err == nil {
+		t.Fatal("expected monitoring to be refused while recording")
+	}
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"bken/server/internal/protocol"
+)
+
+// MaxMessageTTLSeconds is the longest message lifetime a channel may have.
+const MaxMessageTTLSeconds = 365 * 24 * 60 * 60
+
+// ChannelTTL is a channel whose messages expire; see MessageTTLs.
+type ChannelTTL struct {
+	ServerID  string
+	ChannelID string // wire ID, as stored with messages
+	TTL       time.Duration
+}
+
+// SetChannelTTL sets how long messages in a channel are kept and returns
+// the updated list. seconds = 0 keeps them forever.
+func (r *ChannelState) SetChannelTTL(serverID string, channelID int64, seconds int) ([]protocol.Channel, error) {
+	if seconds < 0 || seconds > MaxMessageTTLSeconds {
+		return nil, fmt.Errorf("message_ttl_seconds must be between 0 and %d", MaxMessageTTLSeconds)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	chs := r.channels[serverID]
+	for i := range chs {
+		if chs[i].ID == channelID {
+			chs[i].MessageTTLSeconds = seconds
+			out := make([]protocol.Channel, len(chs))
+			copy(out, chs)
+			slog.Info("channel message ttl set", "server_id", serverID, "channel_id", channelID, "seconds", seconds)
+			return out, nil
+		}
+	}
+	return nil, fmt.Errorf("channel not found")
+}
+
+// MessageTTLs returns every channel with a message TTL, for the expiry
+// sweep.
+func (r *ChannelState) MessageTTLs() []ChannelTTL {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []ChannelTTL
+	for serverID, chs := range r.channels {
+		for _, ch := range chs {
+			if ch.MessageTTLSeconds <= 0 {
+				continue
+			}
+			out = append(out, ChannelTTL{
+				ServerID:  serverID,
+				ChannelID: strconv.FormatInt(ch.ID, 10),
+				TTL:       time.Duration(ch.MessageTTLSeconds) * time.Second,
+			})
+		}
+	}
+	return out
+}
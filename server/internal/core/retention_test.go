@@ -0,0 +1,39 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetChannelTTL(t *testing.T) {
+	r := NewChannelState("")
+	chs, _ := r.CreateChannel("srv-1", "general")
+	chs, _ = r.CreateChannel("srv-1", "ephemeral")
+	id := chs[1].ID
+
+	chs, err := r.SetChannelTTL("srv-1", id, 3600)
+	if err != nil || chs[1].MessageTTLSeconds != 3600 {
+		t.Fatalf("set ttl: %v, %+v", err, chs)
+	}
+	for _, secs := range []int{-1, MaxMessageTTLSeconds + 1} {
+		if _, err := r.SetChannelTTL("srv-1", id, secs); err == nil {
+			t.Errorf("expected an error for %d seconds", secs)
+		}
+	}
+	if _, err := r.SetChannelTTL("srv-1", id+1, 10); err == nil {
+		t.Error("expected an error for an unknown channel")
+	}
+
+	ttls := r.MessageTTLs()
+	if len(ttls) != 1 || ttls[0].ServerID != "srv-1" || ttls[0].TTL != time.Hour {
+		t.Fatalf("unexpected ttls: %+v", ttls)
+	}
+
+	// 0 keeps messages forever, so the channel drops out of the sweep.
+	if _, err := r.SetChannelTTL("srv-1", id, 0); err != nil {
+		t.Fatalf("clear ttl: %v", err)
+	}
+	if ttls := r.MessageTTLs(); len(ttls) != 0 {
+		t.Fatalf("expected no ttls, got %+v", ttls)
+	}
+}
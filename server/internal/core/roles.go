@@ -0,0 +1,204 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	"bken/server/internal/protocol"
+)
+
+// Roles, from least to most privileged. Every session starts as RoleUser;
+// the first user to connect owns the server.
+const (
+	RoleUser      = "USER"
+	RoleModerator = "MODERATOR"
+	RoleAdmin     = "ADMIN"
+	RoleOwner     = "OWNER"
+)
+
+// RoleLevel ranks a role for permission checks; unknown roles rank lowest.
+func RoleLevel(role string) int {
+	switch role {
+	case RoleModerator:
+		return 1
+	case RoleAdmin:
+		return 2
+	case RoleOwner:
+		return 3
+	}
+	return 0
+}
+
+// Permissions is the authoritative owner and role assignment snapshot.
+type Permissions struct {
+	OwnerID string
+	// CoOwners lists the admins, who share the owner's moderation powers.
+	CoOwners []string
+	// Roles maps every connected user ID to its role.
+	Roles map[string]string
+}
+
+// OwnerID returns the current owner's user ID, or "" with nobody connected.
+func (r *ChannelState) OwnerID() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.ownerID
+}
+
+// Role returns userID's role, or "" if the user is not connected.
+func (r *ChannelState) Role(userID string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	u, ok := r.users[userID]
+	if !ok {
+		return ""
+	}
+	return r.roleLocked(u)
+}
+
+func (r *ChannelState) roleLocked(u *userState) string {
+	if u.id == r.ownerID {
+		return RoleOwner
+	}
+	if u.role == "" {
+		return RoleUser
+	}
+	return u.role
+}
+
+// SetRole assigns a non-owner role to userID. Ownership changes hands only
+// through owner transfer, so RoleOwner is rejected here. Callers are
+// responsible for checking that the actor may grant the role.
+func (r *ChannelState) SetRole(userID, role string) error {
+	switch role {
+	case RoleUser, RoleModerator, RoleAdmin:
+	default:
+		return fmt.Errorf("invalid role %q", role)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[userID]
+	if !ok {
+		return fmt.Errorf("user not found")
+	}
+	if userID == r.ownerID {
+		return fmt.Errorf("cannot change the owner's role")
+	}
+	u.role = role
+	slog.Info("role set", "user_id", userID, "role", role)
+	return nil
+}
+
+// Permissions returns the current owner and every connected user's role.
+func (r *ChannelState) Permissions() Permissions {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p := Permissions{
+		OwnerID:  r.ownerID,
+		CoOwners: []string{},
+		Roles:    make(map[string]string, len(r.users)),
+	}
+	for id, u := range r.users {
+		role := r.roleLocked(u)
+		p.Roles[id] = role
+		if role == RoleAdmin {
+			p.CoOwners = append(p.CoOwners, id)
+		}
+	}
+	sort.Slice(p.CoOwners, func(i, j int) bool { return userSeq(p.CoOwners[i]) < userSeq(p.CoOwners[j]) })
+	return p
+}
+
+// transferOwnerLocked hands ownership to the longest-connected remaining
+// user (lowest ID) after the owner leaves. It returns the new owner, or ""
+// if nobody is left. Caller holds r.mu for writing.
+func (r *ChannelState) transferOwnerLocked() string {
+	r.ownerID = ""
+	for id := range r.users {
+		if r.ownerID == "" || userSeq(id) < userSeq(r.ownerID) {
+			r.ownerID = id
+		}
+	}
+	if u, ok := r.users[r.ownerID]; ok {
+		u.role = ""
+	}
+	return r.ownerID
+}
+
+// OwnerTransfer describes an ownership handoff made by TransferOwner.
+type OwnerTransfer struct {
+	From protocol.User
+	To   protocol.User
+}
+
+// TransferOwner hands ownership from actorID to targetID and broadcasts
+// owner_changed. Only the owner may transfer (otherwise ErrNotPermitted),
+// and the target must be another connected user. The previous owner
+// becomes a regular user.
+func (r *ChannelState) TransferOwner(actorID, targetID string) (OwnerTransfer, error) {
+	r.mu.Lock()
+	actor, ok := r.users[actorID]
+	if !ok {
+		r.mu.Unlock()
+		return OwnerTransfer{}, fmt.Errorf("user not found")
+	}
+	if actorID != r.ownerID {
+		r.mu.Unlock()
+		return OwnerTransfer{}, ErrNotPermitted
+	}
+	target, ok := r.users[targetID]
+	if !ok {
+		r.mu.Unlock()
+		return OwnerTransfer{}, fmt.Errorf("user %s is not connected", targetID)
+	}
+	if targetID == actorID {
+		r.mu.Unlock()
+		return OwnerTransfer{}, fmt.Errorf("you already own the server")
+	}
+	r.ownerID = targetID
+	target.role = ""
+	actor.role = ""
+	out := OwnerTransfer{From: toProtocolUser(actor), To: toProtocolUser(target)}
+	r.mu.Unlock()
+
+	slog.Info("owner transferred", "from", actorID, "to", targetID)
+	r.announceOwner(targetID)
+	return out, nil
+}
+
+// BroadcastToRole sends msg to every connected user whose role is at least
+// minRole, for feeds such as the audit log that only staff may see.
+func (r *ChannelState) BroadcastToRole(minRole string, msg protocol.Message) {
+	floor := RoleLevel(minRole)
+	r.mu.RLock()
+	var targets []chan protocol.Message
+	for _, u := range r.users {
+		if RoleLevel(r.roleLocked(u)) >= floor {
+			targets = append(targets, u.send)
+		}
+	}
+	r.mu.RUnlock()
+
+	sent := 0
+	for _, ch := range targets {
+		if r.deliver(ch, msg) {
+			sent++
+		}
+	}
+	slog.Debug("broadcast_to_role", "type", msg.Type, "min_role", minRole, "recipients", sent, "total", len(targets))
+}
+
+// announceOwner tells everyone who owns the server now.
+func (r *ChannelState) announceOwner(ownerID string) {
+	slog.Info("owner changed", "owner_id", ownerID)
+	r.Broadcast(protocol.Message{Type: protocol.TypeOwnerChanged, OwnerID: ownerID}, "")
+}
+
+// userSeq extracts the sequence number from a "u<N>" user ID.
+func userSeq(id string) uint64 {
+	n, _ := strconv.ParseUint(strings.TrimPrefix(id, "u"), 10, 64)
+	return n
+}
@@ -0,0 +1,207 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"bken/server/internal/protocol"
+)
+
+func TestFirstUserOwnsServer(t *testing.T) {
+	r := NewChannelState("")
+	if r.OwnerID() != "" {
+		t.Fatalf("empty state should have no owner, got %q", r.OwnerID())
+	}
+	alice, _, _ := r.Add("alice", 8)
+	bob, _, _ := r.Add("bob", 8)
+	if r.OwnerID() != alice.UserID {
+		t.Fatalf("owner = %q, want %q", r.OwnerID(), alice.UserID)
+	}
+	if got := r.Role(alice.UserID); got != RoleOwner {
+		t.Fatalf("alice role = %q, want %q", got, RoleOwner)
+	}
+	if got := r.Role(bob.UserID); got != RoleUser {
+		t.Fatalf("bob role = %q, want %q", got, RoleUser)
+	}
+	if got := r.Role("u999"); got != "" {
+		t.Fatalf("unknown user role = %q, want empty", got)
+	}
+}
+
+func TestOwnerLeavingTransfersToLowestID(t *testing.T) {
+	r := NewChannelState("")
+	alice, _, _ := r.Add("alice", 8)
+	bob, _, _ := r.Add("bob", 8)
+	carol, _, _ := r.Add("carol", 8)
+	if err := r.SetRole(bob.UserID, RoleAdmin); err != nil {
+		t.Fatalf("set role: %v", err)
+	}
+
+	r.Remove(alice.UserID)
+	if r.OwnerID() != bob.UserID {
+		t.Fatalf("owner = %q, want %q", r.OwnerID(), bob.UserID)
+	}
+	// The promoted admin is now simply the owner.
+	if got := r.Role(bob.UserID); got != RoleOwner {
+		t.Fatalf("bob role = %q, want %q", got, RoleOwner)
+	}
+	msg := <-carol.Send
+	if msg.Type != protocol.TypeOwnerChanged || msg.OwnerID != bob.UserID {
+		t.Fatalf("expected owner_changed to %s, got %+v", bob.UserID, msg)
+	}
+
+	r.Remove(bob.UserID)
+	r.Remove(carol.UserID)
+	if r.OwnerID() != "" {
+		t.Fatalf("owner should be cleared when everyone leaves, got %q", r.OwnerID())
+	}
+	dave, _, _ := r.Add("dave", 8)
+	if r.OwnerID() != dave.UserID {
+		t.Fatalf("next user should own the server, got %q", r.OwnerID())
+	}
+}
+
+func TestReplacedOwnerKeepsOwnership(t *testing.T) {
+	r := NewChannelState("")
+	if err := r.SetUsernamePolicy(UsernamePolicyReplace); err != nil {
+		t.Fatalf("set policy: %v", err)
+	}
+	r.Add("alice", 8)
+	r.Add("bob", 8)
+	again, _, _ := r.Add("alice", 8)
+	if r.OwnerID() != again.UserID {
+		t.Fatalf("owner = %q, want the replacing session %q", r.OwnerID(), again.UserID)
+	}
+}
+
+func TestSetRoleValidation(t *testing.T) {
+	r := NewChannelState("")
+	alice, _, _ := r.Add("alice", 8)
+	bob, _, _ := r.Add("bob", 8)
+	if err := r.SetRole(bob.UserID, RoleOwner); err == nil {
+		t.Fatal("granting OWNER through SetRole should fail")
+	}
+	if err := r.SetRole(bob.UserID, "SUPERUSER"); err == nil {
+		t.Fatal("unknown role should fail")
+	}
+	if err := r.SetRole(alice.UserID, RoleUser); err == nil {
+		t.Fatal("demoting the owner should fail")
+	}
+	if err := r.SetRole("u999", RoleModerator); err == nil {
+		t.Fatal("unknown user should fail")
+	}
+	if err := r.SetRole(bob.UserID, RoleModerator); err != nil {
+		t.Fatalf("set moderator: %v", err)
+	}
+	if got := r.Role(bob.UserID); got != RoleModerator {
+		t.Fatalf("bob role = %q, want %q", got, RoleModerator)
+	}
+}
+
+func TestPermissionsSnapshot(t *testing.T) {
+	r := NewChannelState("")
+	alice, _, _ := r.Add("alice", 8)
+	bob, _, _ := r.Add("bob", 8)
+	carol, _, _ := r.Add("carol", 8)
+	_ = r.SetRole(carol.UserID, RoleAdmin)
+	_ = r.SetRole(bob.UserID, RoleModerator)
+
+	p := r.Permissions()
+	if p.OwnerID != alice.UserID {
+		t.Fatalf("owner = %q, want %q", p.OwnerID, alice.UserID)
+	}
+	want := map[string]string{alice.UserID: RoleOwner, bob.UserID: RoleModerator, carol.UserID: RoleAdmin}
+	if len(p.Roles) != len(want) {
+		t.Fatalf("roles = %v, want %v", p.Roles, want)
+	}
+	for id, role := range want {
+		if p.Roles[id] != role {
+			t.Fatalf("roles[%s] = %q, want %q", id, p.Roles[id], role)
+		}
+	}
+	if len(p.CoOwners) != 1 || p.CoOwners[0] != carol.UserID {
+		t.Fatalf("co-owners = %v, want [%s]", p.CoOwners, carol.UserID)
+	}
+}
+
+func TestRoleLevelOrdering(t *testing.T) {
+	order := []string{RoleUser, RoleModerator, RoleAdmin, RoleOwner}
+	for i := 1; i < len(order); i++ {
+		if RoleLevel(order[i]) <= RoleLevel(order[i-1]) {
+			t.Fatalf("%s should outrank %s", order[i], order[i-1])
+		}
+	}
+	if RoleLevel("bogus") != RoleLevel(RoleUser) {
+		t.Fatal("unknown roles should rank as USER")
+	}
+}
+
+func TestTransferOwnerRequiresOwnerAndConnectedTarget(t *testing.T) {
+	r := NewChannelState("")
+	alice, _, _ := r.Add("alice", 8)
+	bob, _, _ := r.Add("bob", 8)
+	carol, _, _ := r.Add("carol", 8)
+	if err := r.SetRole(bob.UserID, RoleAdmin); err != nil {
+		t.Fatalf("set role: %v", err)
+	}
+
+	// Not even an admin may take ownership.
+	if _, err := r.TransferOwner(bob.UserID, bob.UserID); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("admin transferring: expected ErrNotPermitted, got %v", err)
+	}
+	if _, err := r.TransferOwner(carol.UserID, bob.UserID); !errors.Is(err, ErrNotPermitted) {
+		t.Fatalf("user transferring: expected ErrNotPermitted, got %v", err)
+	}
+	if _, err := r.TransferOwner(alice.UserID, "u999"); err == nil {
+		t.Fatal("expected an error for a target that is not connected")
+	}
+	if r.OwnerID() != alice.UserID {
+		t.Fatalf("owner = %q after rejected transfers, want %q", r.OwnerID(), alice.UserID)
+	}
+
+	transfer, err := r.TransferOwner(alice.UserID, bob.UserID)
+	if err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	if transfer.From.ID != alice.UserID || transfer.To.Username != "bob" {
+		t.Fatalf("unexpected transfer: %+v", transfer)
+	}
+	if got := r.Role(bob.UserID); got != RoleOwner {
+		t.Fatalf("bob role = %q, want %q", got, RoleOwner)
+	}
+	if got := r.Role(alice.UserID); got != RoleUser {
+		t.Fatalf("alice role = %q, want %q", got, RoleUser)
+	}
+	msg := <-carol.Send
+	if msg.Type != protocol.TypeOwnerChanged || msg.OwnerID != bob.UserID {
+		t.Fatalf("expected owner_changed to %s, got %+v", bob.UserID, msg)
+	}
+}
+
+func TestBroadcastToRoleSkipsLowerRoles(t *testing.T) {
+	r := NewChannelState("")
+	alice, _, _ := r.Add("alice", 8)
+	bob, _, _ := r.Add("bob", 8)
+	carol, _, _ := r.Add("carol", 8)
+	if err := r.SetRole(bob.UserID, RoleAdmin); err != nil {
+		t.Fatalf("set role: %v", err)
+	}
+
+	r.BroadcastToRole(RoleAdmin, protocol.Message{Type: protocol.TypeAuditEntry})
+
+	for _, s := range []*Session{alice, bob} {
+		select {
+		case msg := <-s.Send:
+			if msg.Type != protocol.TypeAuditEntry {
+				t.Fatalf("%s got %q, want audit_entry", s.UserID, msg.Type)
+			}
+		default:
+			t.Fatalf("%s should have received the broadcast", s.UserID)
+		}
+	}
+	select {
+	case msg := <-carol.Send:
+		t.Fatalf("regular user received %+v", msg)
+	default:
+	}
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"bken/server/internal/protocol"
+)
+
+// MaxSlowModeSeconds is the longest slow mode a channel may have.
+const MaxSlowModeSeconds = 6 * 60 * 60
+
+// SlowModeError is returned by CheckSlowMode when the user sent a message
+// to the channel less than its slow mode interval ago.
+type SlowModeError struct {
+	Remaining time.Duration
+}
+
+func (e *SlowModeError) Error() string {
+	secs := int((e.Remaining + time.Second - 1) / time.Second)
+	return fmt.Sprintf("slow mode: wait %d seconds", secs)
+}
+
+// SetSlowMode sets the minimum time in seconds between one user's messages
+// in a channel and returns the updated list. seconds = 0 turns slow mode off.
+func (r *ChannelState) SetSlowMode(serverID string, channelID int64, seconds int) ([]protocol.Channel, error) {
+	if seconds < 0 || seconds > MaxSlowModeSeconds {
+		return nil, fmt.Errorf("slow_mode_seconds must be between 0 and %d", MaxSlowModeSeconds)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	chs := r.channels[serverID]
+	for i := range chs {
+		if chs[i].ID == channelID {
+			chs[i].SlowModeSeconds = seconds
+			out := make([]protocol.Channel, len(chs))
+			copy(out, chs)
+			slog.Info("channel slow mode set", "server_id", serverID, "channel_id", channelID, "seconds", seconds)
+			return out, nil
+		}
+	}
+	return nil, fmt.Errorf("channel not found")
+}
+
+// CheckSlowMode records that userID is sending a message to a channel, or
+// returns a *SlowModeError if the channel's slow mode interval has not
+// passed since their last one there. Admins and the owner are exempt, as
+// are channels that are unknown or have slow mode off.
+func (r *ChannelState) CheckSlowMode(userID, serverID, channelID string) error {
+	serverID = strings.TrimSpace(serverID)
+	channelID = strings.TrimSpace(channelID)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[userID]
+	if !ok {
+		return nil
+	}
+	ch, ok := r.channelLocked(serverID, channelID)
+	if !ok || ch.SlowModeSeconds <= 0 || r.meetsLocked(u, RoleAdmin) {
+		return nil
+	}
+
+	now := r.now()
+	key := serverID + "/" + channelID
+	interval := time.Duration(ch.SlowModeSeconds) * time.Second
+	if last, ok := u.lastChat[key]; ok {
+		if remaining := last.Add(interval).Sub(now); remaining > 0 {
+			return &SlowModeError{Remaining: remaining}
+		}
+	}
+	if u.lastChat == nil {
+		u.lastChat = make(map[string]time.Time)
+	}
+	u.lastChat[key] = now
+	return nil
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSetSlowMode(t *testing.T) {
+	r := NewChannelState("")
+	chs, _ := r.CreateChannel("srv-1", "general")
+	id := chs[0].ID
+
+	chs, err := r.SetSlowMode("srv-1", id, 30)
+	if err != nil || chs[0].SlowModeSeconds != 30 {
+		t.Fatalf("set slow mode: %v, %+v", err, chs)
+	}
+	for _, secs := range []int{-1, MaxSlowModeSeconds + 1} {
+		if _, err := r.SetSlowMode("srv-1", id, secs); err == nil {
+			t.Errorf("expected an error for %d seconds", secs)
+		}
+	}
+	if _, err := r.SetSlowMode("srv-1", id+1, 10); err == nil {
+		t.Error("expected an error for an unknown channel")
+	}
+}
+
+func TestCheckSlowModeExemptsAdmins(t *testing.T) {
+	r := NewChannelState("")
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+
+	owner, _, _ := r.Add("owner", 8)
+	admin, _, _ := r.Add("admin", 8)
+	mod, _, _ := r.Add("mod", 8)
+	user, _, _ := r.Add("user", 8)
+	if err := r.SetRole(admin.UserID, RoleAdmin); err != nil {
+		t.Fatalf("set admin: %v", err)
+	}
+	if err := r.SetRole(mod.UserID, RoleModerator); err != nil {
+		t.Fatalf("set moderator: %v", err)
+	}
+	r.CreateChannel("srv-1", "general")
+	chs, _ := r.CreateChannel("srv-1", "random")
+	general := strconv.FormatInt(chs[0].ID, 10)
+	random := strconv.FormatInt(chs[1].ID, 10)
+	if _, err := r.SetSlowMode("srv-1", chs[0].ID, 10); err != nil {
+		t.Fatalf("set slow mode: %v", err)
+	}
+
+	// Admins and the owner post as often as they like.
+	for _, s := range []*Session{owner, admin} {
+		for range 3 {
+			if err := r.CheckSlowMode(s.UserID, "srv-1", general); err != nil {
+				t.Fatalf("%s: %v", s.Username, err)
+			}
+		}
+	}
+
+	for _, s := range []*Session{mod, user} {
+		if err := r.CheckSlowMode(s.UserID, "srv-1", general); err != nil {
+			t.Fatalf("%s first message: %v", s.Username, err)
+		}
+	}
+	now = now.Add(4 * time.Second)
+	err := r.CheckSlowMode(user.UserID, "srv-1", general)
+	var slow *SlowModeError
+	if !errors.As(err, &slow) || slow.Remaining != 6*time.Second {
+		t.Fatalf("expected 6s remaining, got %v", err)
+	}
+	if err.Error() != "slow mode: wait 6 seconds" {
+		t.Errorf("unexpected message %q", err)
+	}
+	if err := r.CheckSlowMode(mod.UserID, "srv-1", general); err == nil {
+		t.Error("moderators are not exempt")
+	}
+	// Other channels are unaffected.
+	if err := r.CheckSlowMode(user.UserID, "srv-1", random); err != nil {
+		t.Fatalf("channel without slow mode: %v", err)
+	}
+
+	// A rejected message does not restart the interval.
+	now = now.Add(6 * time.Second)
+	if err := r.CheckSlowMode(user.UserID, "srv-1", general); err != nil {
+		t.Fatalf("after the interval: %v", err)
+	}
+
+	if _, err := r.SetSlowMode("srv-1", chs[0].ID, 0); err != nil {
+		t.Fatalf("turn off slow mode: %v", err)
+	}
+	if err := r.CheckSlowMode(user.UserID, "srv-1", general); err != nil {
+		t.Fatalf("slow mode off: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"bken/server/internal/protocol"
+)
+
+// SoundboardInterval is the minimum time between a user's soundboard
+// triggers, so one client cannot flood a channel with clips.
+const SoundboardInterval = 3 * time.Second
+
+// soundboardClips is the set of clip IDs bundled with the client. Clients
+// only play clips they ship, so anything else is rejected up front.
+var soundboardClips = map[string]struct{}{
+	"airhorn":      {},
+	"applause":     {},
+	"drumroll":     {},
+	"rimshot":      {},
+	"sad_trombone": {},
+	"tada":         {},
+}
+
+// ValidSoundboardClip reports whether clipID is a known soundboard clip.
+func ValidSoundboardClip(clipID string) bool {
+	_, ok := soundboardClips[clipID]
+	return ok
+}
+
+// SoundboardRateError is returned by PlaySoundboard when the user triggered
+// a clip less than SoundboardInterval ago.
+type SoundboardRateError struct {
+	Remaining time.Duration
+}
+
+func (e *SoundboardRateError) Error() string {
+	secs := int((e.Remaining + time.Second - 1) / time.Second)
+	return fmt.Sprintf("soundboard used too quickly; try again in %ds", secs)
+}
+
+// PlaySoundboard validates a soundboard trigger from userID and sends a
+// soundboard message to everyone in the user's voice channel, the sender
+// included, so all clients play the clip together. The user must be in
+// voice and allowed to speak there.
+func (r *ChannelState) PlaySoundboard(userID, clipID string) error {
+	if !ValidSoundboardClip(clipID) {
+		return fmt.Errorf("unknown soundboard clip %q", clipID)
+	}
+
+	r.mu.Lock()
+	u, ok := r.users[userID]
+	if !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("user not found")
+	}
+	if u.voice == nil {
+		r.mu.Unlock()
+		return fmt.Errorf("join a voice channel to use the soundboard")
+	}
+	if ok, minRole := r.canSpeakLocked(u); !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("you need the %s role to use the soundboard in this channel", minRole)
+	}
+	now := r.now()
+	if !u.lastSoundboard.IsZero() {
+		if remaining := u.lastSoundboard.Add(SoundboardInterval).Sub(now); remaining > 0 {
+			r.mu.Unlock()
+			return &SoundboardRateError{Remaining: remaining}
+		}
+	}
+	u.lastSoundboard = now
+
+	voice := *u.voice
+	msg := protocol.Message{
+		Type:      protocol.TypeSoundboard,
+		UserID:    userID,
+		ServerID:  voice.ServerID,
+		ChannelID: voice.ChannelID,
+		ClipID:    clipID,
+	}
+	var targets []chan protocol.Message
+	for _, other := range r.users {
+		if other.voice != nil && other.voice.ServerID == voice.ServerID && other.voice.ChannelID == voice.ChannelID {
+			targets = append(targets, other.send)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, ch := range targets {
+		r.deliver(ch, msg)
+	}
+	slog.Debug("soundboard", "user_id", userID, "clip_id", clipID, "server_id", voice.ServerID, "channel_id", voice.ChannelID, "recipients", len(targets))
+	return nil
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"bken/server/internal/protocol"
+)
+
+func TestPlaySoundboardReachesVoiceChannelOnly(t *testing.T) {
+	r := NewChannelState("")
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+
+	add := func(name, channelID string) *Session {
+		s, _, err := r.Add(name, 8)
+		if err != nil {
+			t.Fatalf("add %s: %v", name, err)
+		}
+		if _, _, err := r.ConnectServer(s.UserID, "srv-1"); err != nil {
+			t.Fatalf("connect %s: %v", name, err)
+		}
+		if channelID != "" {
+			if _, _, err := r.JoinVoice(s.UserID, "srv-1", channelID); err != nil {
+				t.Fatalf("join %s: %v", name, err)
+			}
+		}
+		return s
+	}
+	alice := add("alice", "chan-a")
+	bob := add("bob", "chan-a")
+	carol := add("carol", "chan-b")
+	dave := add("dave", "")
+
+	if err := r.PlaySoundboard(alice.UserID, "airhorn"); err != nil {
+		t.Fatalf("play: %v", err)
+	}
+	for _, s := range []*Session{alice, bob} {
+		select {
+		case msg := <-s.Send:
+			if msg.Type != protocol.TypeSoundboard || msg.ClipID != "airhorn" || msg.UserID != alice.UserID {
+				t.Fatalf("%s: unexpected message %+v", s.Username, msg)
+			}
+		default:
+			t.Fatalf("%s did not receive the soundboard message", s.Username)
+		}
+	}
+	for _, s := range []*Session{carol, dave} {
+		select {
+		case msg := <-s.Send:
+			t.Fatalf("%s should not receive %+v", s.Username, msg)
+		default:
+		}
+	}
+
+	if err := r.PlaySoundboard(dave.UserID, "airhorn"); err == nil {
+		t.Fatal("expected error outside voice")
+	}
+	if err := r.PlaySoundboard(bob.UserID, "not-a-clip"); err == nil {
+		t.Fatal("expected error for unknown clip")
+	}
+}
+
+func TestPlaySoundboardRateLimit(t *testing.T) {
+	r := NewChannelState("")
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+
+	s, _, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if _, _, err := r.ConnectServer(s.UserID, "srv-1"); err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+	if _, _, err := r.JoinVoice(s.UserID, "srv-1", "chan-a"); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+
+	if err := r.PlaySoundboard(s.UserID, "tada"); err != nil {
+		t.Fatalf("first trigger should not be limited: %v", err)
+	}
+	now = now.Add(time.Second)
+	err = r.PlaySoundboard(s.UserID, "rimshot")
+	var limited *SoundboardRateError
+	if !errors.As(err, &limited) {
+		t.Fatalf("expected SoundboardRateError, got %v", err)
+	}
+	if limited.Remaining != SoundboardInterval-time.Second {
+		t.Fatalf("expected %v remaining, got %v", SoundboardInterval-time.Second, limited.Remaining)
+	}
+
+	// A rejected clip is not a trigger and does not extend the limit.
+	now = now.Add(SoundboardInterval - time.Second)
+	if err := r.PlaySoundboard(s.UserID, "rimshot"); err != nil {
+		t.Fatalf("trigger after interval: %v", err)
+	}
+}
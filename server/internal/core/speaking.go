@@ -0,0 +1,48 @@
+package core
+
+import (
+	"time"
+
+	"bken/server/internal/protocol"
+)
+
+// SpeakingDebounce is the minimum time between one user's speaking starts.
+// Clients hold a start for a short hangover after their mic falls quiet, so
+// starts closer together than this are flapping and are dropped.
+const SpeakingDebounce = 250 * time.Millisecond
+
+// SetSpeaking records whether userID is transmitting voice and reports
+// whether a speaking broadcast is due, returning the voice channel to send
+// it to. It is not when the user is not in voice, the state is unchanged,
+// or a start comes within SpeakingDebounce of the last one. Users who are
+// muted cannot start speaking. A start also counts as voice activity for
+// the idle timeout.
+func (r *ChannelState) SetSpeaking(userID string, speaking bool) (protocol.VoiceState, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[userID]
+	if !ok || u.voice == nil {
+		return protocol.VoiceState{}, false
+	}
+
+	// Speaking state belongs to one voice channel; after a move or a
+	// rejoin the user starts out silent.
+	key := u.voice.ServerID + "/" + u.voice.ChannelID
+	current := u.speakingIn == key
+	if speaking == current {
+		return protocol.VoiceState{}, false
+	}
+	if !speaking {
+		u.speakingIn = ""
+		return *u.voice, true
+	}
+
+	now := r.now()
+	if u.muted || now.Sub(u.lastSpeaking) < SpeakingDebounce {
+		return protocol.VoiceState{}, false
+	}
+	u.speakingIn = key
+	u.lastSpeaking = now
+	u.lastVoice = now
+	return *u.voice, true
+}
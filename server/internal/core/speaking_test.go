@@ -0,0 +1,76 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetSpeaking(t *testing.T) {
+	r := NewChannelState("")
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+
+	s, _, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if _, _, err := r.ConnectServer(s.UserID, "srv-1"); err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+	if _, ok := r.SetSpeaking(s.UserID, true); ok {
+		t.Fatal("speaking outside voice should be ignored")
+	}
+	if _, _, err := r.JoinVoice(s.UserID, "srv-1", "chan-a"); err != nil {
+		t.Fatalf("join voice: %v", err)
+	}
+
+	voice, ok := r.SetSpeaking(s.UserID, true)
+	if !ok || voice.ServerID != "srv-1" || voice.ChannelID != "chan-a" {
+		t.Fatalf("first start should broadcast to chan-a, got ok=%v voice=%+v", ok, voice)
+	}
+	if _, ok := r.SetSpeaking(s.UserID, true); ok {
+		t.Fatal("a repeated start should not broadcast")
+	}
+	if _, ok := r.SetSpeaking(s.UserID, false); !ok {
+		t.Fatal("stop should broadcast")
+	}
+	if _, ok := r.SetSpeaking(s.UserID, false); ok {
+		t.Fatal("a repeated stop should not broadcast")
+	}
+	if _, ok := r.SetSpeaking(s.UserID, true); ok {
+		t.Fatal("a start within the debounce should be dropped")
+	}
+	now = now.Add(SpeakingDebounce)
+	if _, ok := r.SetSpeaking(s.UserID, true); !ok {
+		t.Fatal("a start after the debounce should broadcast")
+	}
+
+	// Moving channels starts the user out silent.
+	now = now.Add(time.Minute)
+	if _, _, err := r.JoinVoice(s.UserID, "srv-1", "chan-b"); err != nil {
+		t.Fatalf("switch voice: %v", err)
+	}
+	if _, ok := r.SetSpeaking(s.UserID, false); ok {
+		t.Fatal("stop after a move should not broadcast")
+	}
+	if voice, ok := r.SetSpeaking(s.UserID, true); !ok || voice.ChannelID != "chan-b" {
+		t.Fatalf("start after a move should broadcast to chan-b, got ok=%v voice=%+v", ok, voice)
+	}
+}
+
+func TestSetSpeakingIgnoresMutedUsers(t *testing.T) {
+	r := NewChannelState("")
+	s, _, _ := r.Add("alice", 8)
+	if _, _, err := r.ConnectServer(s.UserID, "srv-1"); err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+	if _, _, err := r.JoinVoice(s.UserID, "srv-1", "chan-a"); err != nil {
+		t.Fatalf("join voice: %v", err)
+	}
+	if _, changed := r.SetVoiceFlags(s.UserID, true, false); !changed {
+		t.Fatal("expected mute to change the voice flags")
+	}
+	if _, ok := r.SetSpeaking(s.UserID, true); ok {
+		t.Fatal("a muted user should not start speaking")
+	}
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"bken/server/internal/protocol"
+)
+
+// Presence statuses a user may set. Online is the default and is sent as
+// an empty status.
+const (
+	StatusOnline = "online"
+	StatusAway   = "away"
+	StatusDND    = "dnd"
+)
+
+// SetStatus sets userID's presence status and reports whether it changed,
+// so the caller knows to broadcast user_status. Unknown statuses are
+// rejected.
+func (r *ChannelState) SetStatus(userID, status string) (protocol.User, bool, error) {
+	status = strings.ToLower(strings.TrimSpace(status))
+	switch status {
+	case StatusOnline:
+		status = ""
+	case StatusAway, StatusDND:
+	default:
+		return protocol.User{}, false, fmt.Errorf("status must be one of %s, %s or %s", StatusOnline, StatusAway, StatusDND)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[userID]
+	if !ok {
+		return protocol.User{}, false, fmt.Errorf("user not found")
+	}
+	if u.status == status {
+		return toProtocolUser(u), false, nil
+	}
+	u.status = status
+	slog.Debug("status set", "user_id", userID, "status", status)
+	return toProtocolUser(u), true, nil
+}
@@ -0,0 +1,31 @@
+package core
+
+import "testing"
+
+func TestSetStatus(t *testing.T) {
+	r := NewChannelState("")
+	s, _, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	u, changed, err := r.SetStatus(s.UserID, " DND ")
+	if err != nil || !changed || u.Status != StatusDND {
+		t.Fatalf("set dnd: %+v, changed=%v, err=%v", u, changed, err)
+	}
+	if _, changed, _ := r.SetStatus(s.UserID, StatusDND); changed {
+		t.Error("setting the same status again should not report a change")
+	}
+	if _, _, err := r.SetStatus(s.UserID, "invisible"); err == nil {
+		t.Error("expected an error for an unknown status")
+	}
+	if got, _ := r.User(s.UserID); got.Status != StatusDND {
+		t.Errorf("rejected status changed it to %q", got.Status)
+	}
+
+	// Online is the default and travels as an empty status.
+	u, changed, err = r.SetStatus(s.UserID, StatusOnline)
+	if err != nil || !changed || u.Status != "" {
+		t.Fatalf("set online: %+v, changed=%v, err=%v", u, changed, err)
+	}
+}
@@ -0,0 +1,22 @@
+package core
+
+import "crypto/subtle"
+
+// UserByToken returns the ID of the connected user whose session token is
+// token. Tokens are issued by Add and sent to the client in its snapshot so
+// the REST API can act on behalf of a live websocket session; they stop
+// working as soon as the session is removed.
+func (r *ChannelState) UserByToken(token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for id, u := range r.users {
+		if subtle.ConstantTimeCompare([]byte(u.token), []byte(token)) == 1 {
+			return id, true
+		}
+	}
+	return "", false
+}
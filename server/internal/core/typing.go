@@ -0,0 +1,47 @@
+package core
+
+import (
+	"strings"
+	"time"
+
+	"bken/server/internal/protocol"
+)
+
+// TypingDebounce is the minimum time between typing broadcasts for one user
+// in one channel. Clients re-send while the user keeps typing, so anything
+// more frequent would only repeat an indicator that is already showing.
+const TypingDebounce = 3 * time.Second
+
+// MarkTyping records that userID is typing in a text channel and reports
+// whether a user_typing broadcast is due. It is not when the user is not
+// connected to the server, may not chat in the channel, or already typed
+// in the same channel within TypingDebounce.
+func (r *ChannelState) MarkTyping(userID, serverID, channelID string) (protocol.User, bool) {
+	serverID = strings.TrimSpace(serverID)
+	channelID = strings.TrimSpace(channelID)
+	if serverID == "" || channelID == "" {
+		return protocol.User{}, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[userID]
+	if !ok {
+		return protocol.User{}, false
+	}
+	if _, connected := u.connected[serverID]; !connected {
+		return protocol.User{}, false
+	}
+	if ch, ok := r.channelLocked(serverID, channelID); ok && !r.meetsLocked(u, ch.MinRoleToChat) {
+		return protocol.User{}, false
+	}
+
+	now := r.now()
+	key := serverID + "/" + channelID
+	if u.typingIn == key && now.Sub(u.lastTyping) < TypingDebounce {
+		return protocol.User{}, false
+	}
+	u.typingIn = key
+	u.lastTyping = now
+	return toProtocolUser(u), true
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMarkTypingDebounce(t *testing.T) {
+	r := NewChannelState("")
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+
+	s, _, err := r.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if _, ok := r.MarkTyping(s.UserID, "srv-1", "1"); ok {
+		t.Fatal("typing should be ignored before connecting to the server")
+	}
+	if _, _, err := r.ConnectServer(s.UserID, "srv-1"); err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+
+	u, ok := r.MarkTyping(s.UserID, "srv-1", "1")
+	if !ok || u.Username != "alice" {
+		t.Fatalf("first keystroke should broadcast, got ok=%v user=%+v", ok, u)
+	}
+	now = now.Add(time.Second)
+	if _, ok := r.MarkTyping(s.UserID, "srv-1", "1"); ok {
+		t.Fatal("repeat within the debounce should not broadcast")
+	}
+	// Moving to another channel is news straight away.
+	if _, ok := r.MarkTyping(s.UserID, "srv-1", "2"); !ok {
+		t.Fatal("typing in a new channel should broadcast")
+	}
+	now = now.Add(TypingDebounce)
+	if _, ok := r.MarkTyping(s.UserID, "srv-1", "2"); !ok {
+		t.Fatal("typing after the debounce should broadcast again")
+	}
+}
+
+func TestMarkTypingRespectsChatRole(t *testing.T) {
+	r := NewChannelState("")
+	owner, _, _ := r.Add("owner", 8)
+	bob, _, _ := r.Add("bob", 8)
+	for _, s := range []*Session{owner, bob} {
+		if _, _, err := r.ConnectServer(s.UserID, "srv-1"); err != nil {
+			t.Fatalf("connect server: %v", err)
+		}
+	}
+	chID := r.Channels("srv-1")[0].ID
+	if _, _, err := r.SetChannelPerms("srv-1", chID, "", RoleModerator); err != nil {
+		t.Fatalf("set perms: %v", err)
+	}
+	if _, ok := r.MarkTyping(bob.UserID, "srv-1", "1"); ok {
+		t.Fatal("a user who may not chat should not show as typing")
+	}
+	if _, ok := r.MarkTyping(owner.UserID, "srv-1", "1"); !ok {
+		t.Fatal("the owner may chat and should show as typing")
+	}
+}
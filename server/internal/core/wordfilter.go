@@ -0,0 +1,110 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Word filter actions.
+const (
+	// WordFilterBlock rejects a message containing a filtered word.
+	WordFilterBlock = "block"
+	// WordFilterMask replaces each filtered word with asterisks.
+	WordFilterMask = "mask"
+)
+
+// MaxFilteredWords bounds the word filter list.
+const MaxFilteredWords = 500
+
+// ErrMessageFiltered is returned by FilterMessage when the word filter
+// blocks a message.
+var ErrMessageFiltered = errors.New("message contains a filtered word")
+
+// SetWordFilter sets the words filtered out of chat and what happens to a
+// message containing one: WordFilterBlock rejects it, WordFilterMask stars
+// the word out. Matching ignores case and only whole words match, so
+// filtering "ass" leaves "class" alone. Words must be made of letters and
+// digits only. An empty list turns the filter off.
+func (r *ChannelState) SetWordFilter(words []string, action string) error {
+	if action != WordFilterBlock && action != WordFilterMask {
+		return fmt.Errorf("word filter action must be %q or %q", WordFilterBlock, WordFilterMask)
+	}
+	if len(words) > MaxFilteredWords {
+		return fmt.Errorf("word filter must have at most %d words", MaxFilteredWords)
+	}
+	filter := make(map[string]bool, len(words))
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if w == "" || strings.IndexFunc(w, isFilterSeparator) >= 0 {
+			return fmt.Errorf("filtered word %q must be a single word of letters and digits", w)
+		}
+		filter[w] = true
+	}
+	r.mu.Lock()
+	r.wordFilter = filter
+	r.wordFilterAction = action
+	r.mu.Unlock()
+	slog.Info("word filter set", "words", len(filter), "action", action)
+	return nil
+}
+
+// WordFilter returns the filtered words, sorted, and the filter action.
+func (r *ChannelState) WordFilter() ([]string, string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	words := make([]string, 0, len(r.wordFilter))
+	for w := range r.wordFilter {
+		words = append(words, w)
+	}
+	slices.Sort(words)
+	return words, r.wordFilterAction
+}
+
+// FilterMessage applies the word filter to message. It returns message
+// with any filtered words masked, or ErrMessageFiltered if the filter
+// blocks it.
+func (r *ChannelState) FilterMessage(message string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.wordFilter) == 0 {
+		return message, nil
+	}
+
+	var b strings.Builder
+	for len(message) > 0 {
+		// Copy separators through, then look at the next word.
+		i := strings.IndexFunc(message, func(c rune) bool { return !isFilterSeparator(c) })
+		if i < 0 {
+			b.WriteString(message)
+			break
+		}
+		b.WriteString(message[:i])
+		message = message[i:]
+		end := strings.IndexFunc(message, isFilterSeparator)
+		if end < 0 {
+			end = len(message)
+		}
+		word := message[:end]
+		message = message[end:]
+
+		if !r.wordFilter[strings.ToLower(word)] {
+			b.WriteString(word)
+			continue
+		}
+		if r.wordFilterAction == WordFilterBlock {
+			return "", ErrMessageFiltered
+		}
+		b.WriteString(strings.Repeat("*", utf8.RuneCountInString(word)))
+	}
+	return b.String(), nil
+}
+
+// isFilterSeparator reports whether c separates words for the word filter.
+func isFilterSeparator(c rune) bool {
+	return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestWordFilterMask(t *testing.T) {
+	r := NewChannelState("")
+	if err := r.SetWordFilter([]string{"Darn", " heck "}, WordFilterMask); err != nil {
+		t.Fatalf("set word filter: %v", err)
+	}
+	if words, action := r.WordFilter(); !slices.Equal(words, []string{"darn", "heck"}) || action != WordFilterMask {
+		t.Fatalf("WordFilter() = %v, %q", words, action)
+	}
+
+	for in, want := range map[string]string{
+		"darn it":             "**** it",
+		"DARN! What the Heck": "****! What the ****",
+		"heck.":               "****.",
+		"darnit, heckling":    "darnit, heckling",
+		"Scunthorpe":          "Scunthorpe",
+		"dárn":                "dárn",
+		"":                    "",
+	} {
+		got, err := r.FilterMessage(in)
+		if err != nil || got != want {
+			t.Errorf("FilterMessage(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestWordFilterBlock(t *testing.T) {
+	r := NewChannelState("")
+	if err := r.SetWordFilter([]string{"darn"}, WordFilterBlock); err != nil {
+		t.Fatalf("set word filter: %v", err)
+	}
+	if _, err := r.FilterMessage("well, DARN."); !errors.Is(err, ErrMessageFiltered) {
+		t.Errorf("filtered word: err = %v, want ErrMessageFiltered", err)
+	}
+	if got, err := r.FilterMessage("darned if I know"); err != nil || got != "darned if I know" {
+		t.Errorf("longer word: %q, %v", got, err)
+	}
+
+	// An empty list turns the filter off.
+	if err := r.SetWordFilter(nil, WordFilterBlock); err != nil {
+		t.Fatalf("clear word filter: %v", err)
+	}
+	if got, err := r.FilterMessage("darn"); err != nil || got != "darn" {
+		t.Errorf("after clearing: %q, %v", got, err)
+	}
+}
+
+func TestSetWordFilterRejectsBadInput(t *testing.T) {
+	r := NewChannelState("")
+	if err := r.SetWordFilter([]string{"darn"}, "drop"); err == nil {
+		t.Error("expected an error for an unknown action")
+	}
+	for _, w := range []string{"", "two words", "d-arn"} {
+		if err := r.SetWordFilter([]string{w}, WordFilterMask); err == nil {
+			t.Errorf("expected an error for %q", w)
+		}
+	}
+	if err := r.SetWordFilter(make([]string, MaxFilteredWords+1), WordFilterMask); err == nil {
+		t.Error("expected an error for too many words")
+	}
+}
@@ -117,3 +117,67 @@ func TestBlobUploadAndDownload(t *testing.T) {
 }
 
 const echoHeaderContentType = "Content-Type"
+
+func TestBlobUploadRejectsOversizedFile(t *testing.T) {
+	t.Parallel()
+
+	temp := t.TempDir()
+	st, err := store.Open(filepath.Join(temp, "bken.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = st.Close()
+	})
+	blobStore, err := blob.NewStore(filepath.Join(temp, "blobs"), st)
+	if err != nil {
+		t.Fatalf("create blob store: %v", err)
+	}
+
+	channelState := core.NewChannelState("")
+	if err := channelState.SetMaxUploadBytes(1024); err != nil {
+		t.Fatalf("set max upload: %v", err)
+	}
+	api := New(channelState, st, blobStore)
+	ts := httptest.NewServer(api.Echo())
+	t.Cleanup(ts.Close)
+
+	upload := func(size int) int {
+		t.Helper()
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		filePart, err := writer.CreateFormFile("file", "test.bin")
+		if err != nil {
+			t.Fatalf("create form file: %v", err)
+		}
+		if _, err := filePart.Write(bytes.Repeat([]byte("x"), size)); err != nil {
+			t.Fatalf("write multipart bytes: %v", err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("close multipart writer: %v", err)
+		}
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/api/blobs", &body)
+		if err != nil {
+			t.Fatalf("new upload request: %v", err)
+		}
+		req.Header.Set(echoHeaderContentType, writer.FormDataContentType())
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("upload request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := upload(1024); code != http.StatusCreated {
+		t.Fatalf("expected %d at the limit, got %d", http.StatusCreated, code)
+	}
+	// Just over the limit is caught by the file size check.
+	if code := upload(1025); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected %d just over the limit, got %d", http.StatusRequestEntityTooLarge, code)
+	}
+	// Far over the limit is cut off while reading the body.
+	if code := upload(1024 + 2*multipartOverhead); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected %d for a huge body, got %d", http.StatusRequestEntityTooLarge, code)
+	}
+}
@@ -0,0 +1,232 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"bken/server/internal/store"
+
+	"github.com/labstack/echo/v4"
+)
+
+// TranscriptEntry is one message in a JSON channel export. Deleted messages
+// keep their place and sender but not their text or attachment.
+type TranscriptEntry struct {
+	ID       int64  `json:"id"`
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	Message  string `json:"message,omitempty"`
+	TS       int64  `json:"ts"`
+	FileID   string `json:"file_id,omitempty"`
+	FileName string `json:"file_name,omitempty"`
+	ReplyTo  int64  `json:"reply_to,omitempty"`
+	Edited   bool   `json:"edited,omitempty"`
+	Deleted  bool   `json:"deleted,omitempty"`
+}
+
+// transcriptWriter renders a channel export one message at a time.
+type transcriptWriter interface {
+	write(m store.TranscriptMessage) error
+	close() error
+}
+
+// handleExportChannel streams every message stored in a channel of the
+// caller's server, oldest first, as a JSON array (format=json, the
+// default) or a Markdown document (format=md). It authenticates like
+// handleSearchMessages.
+func (s *Server) handleExportChannel(c echo.Context) error {
+	if s.store == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "message history not available")
+	}
+	userID, serverID, err := s.sessionUser(c)
+	if err != nil {
+		return err
+	}
+
+	channelID := strings.TrimSpace(c.Param("id"))
+	format := c.QueryParam("format")
+	if format == "" {
+		format = "json"
+	}
+	resp := c.Response()
+	var (
+		w           transcriptWriter
+		contentType string
+	)
+	switch format {
+	case "json":
+		w, contentType = &jsonTranscript{w: resp}, echo.MIMEApplicationJSONCharsetUTF8
+	case "md":
+		w, contentType = newMarkdownTranscript(resp, channelID), "text/markdown; charset=utf-8"
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "format must be json or md")
+	}
+
+	resp.Header().Set(echo.HeaderContentType, contentType)
+	resp.Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{
+		"filename": fmt.Sprintf("channel-%s.%s", channelID, format),
+	}))
+	resp.WriteHeader(http.StatusOK)
+
+	count := 0
+	err = s.store.ChannelTranscript(c.Request().Context(), serverID, channelID, func(m store.TranscriptMessage) error {
+		count++
+		return w.write(m)
+	})
+	if err == nil {
+		err = w.close()
+	}
+	if err != nil {
+		// The status has gone out already; a cut-off body is all the
+		// client can be told.
+		slog.Error("export channel", "user_id", userID, "server_id", serverID, "channel_id", channelID, "err", err)
+		return nil
+	}
+	slog.Info("channel exported", "user_id", userID, "server_id", serverID, "channel_id", channelID, "format", format, "count", count)
+	return nil
+}
+
+// jsonTranscript writes a JSON array of TranscriptEntry.
+type jsonTranscript struct {
+	w       io.Writer
+	started bool
+}
+
+func (t *jsonTranscript) write(m store.TranscriptMessage) error {
+	data, err := json.Marshal(TranscriptEntry{
+		ID:       m.ID,
+		UserID:   m.UserID,
+		Username: m.Username,
+		Message:  m.Message,
+		TS:       m.TS,
+		FileID:   m.FileID,
+		FileName: m.FileName,
+		ReplyTo:  m.ReplyTo,
+		Edited:   m.Edited,
+		Deleted:  m.Deleted,
+	})
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if !t.started {
+		sep, t.started = "[\n", true
+	}
+	_, err = fmt.Fprintf(t.w, "%s%s", sep, data)
+	return err
+}
+
+func (t *jsonTranscript) close() error {
+	if !t.started {
+		_, err := io.WriteString(t.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(t.w, "\n]\n")
+	return err
+}
+
+// quoteExcerptRunes bounds the quoted text shown above a reply.
+const quoteExcerptRunes = 80
+
+// markdownTranscript writes a channel export as Markdown. Replies quote the
+// start of the message they answer, and edited and deleted messages are
+// marked as such.
+type markdownTranscript struct {
+	w         io.Writer
+	channelID string
+	started   bool
+	written   bool
+}
+
+func newMarkdownTranscript(w io.Writer, channelID string) *markdownTranscript {
+	return &markdownTranscript{w: w, channelID: channelID}
+}
+
+func (t *markdownTranscript) header() error {
+	if t.started {
+		return nil
+	}
+	t.started = true
+	_, err := fmt.Fprintf(t.w, "# Channel %s transcript\n\n", t.channelID)
+	return err
+}
+
+func (t *markdownTranscript) write(m store.TranscriptMessage) error {
+	if err := t.header(); err != nil {
+		return err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** · %s", m.Username, time.UnixMilli(m.TS).UTC().Format("2006-01-02 15:04:05 UTC"))
+	switch {
+	case m.Deleted:
+		b.WriteString(" · deleted")
+	case m.Edited:
+		b.WriteString(" · edited")
+	}
+	b.WriteString("\n\n")
+
+	if m.ReplyTo > 0 {
+		fmt.Fprintf(&b, "> %s\n\n", quote(m.Parent))
+	}
+
+	if m.Deleted {
+		b.WriteString("_This message was deleted._\n\n")
+	} else {
+		if m.Message != "" {
+			b.WriteString(m.Message)
+			b.WriteString("\n\n")
+		}
+		if m.FileID != "" {
+			fmt.Fprintf(&b, "Attachment: [%s](/api/blobs/%s)\n\n", m.FileName, m.FileID)
+		}
+	}
+	t.written = true
+	_, err := io.WriteString(t.w, b.String())
+	return err
+}
+
+// quote returns the line quoting a reply's parent.
+func quote(p *store.TranscriptParent) string {
+	switch {
+	case p == nil:
+		return "_Reply to a message that is not in this transcript._"
+	case p.Deleted:
+		return fmt.Sprintf("**%s:** _deleted message_", p.Username)
+	}
+	text := excerpt(p.Message)
+	if text == "" {
+		text = "_" + p.FileName + "_"
+	}
+	return fmt.Sprintf("**%s:** %s", p.Username, text)
+}
+
+func (t *markdownTranscript) close() error {
+	if err := t.header(); err != nil {
+		return err
+	}
+	if !t.written {
+		_, err := io.WriteString(t.w, "_No messages._\n")
+		return err
+	}
+	return nil
+}
+
+// excerpt returns the first line of message, cut to quoteExcerptRunes.
+func excerpt(message string) string {
+	line, _, cut := strings.Cut(message, "\n")
+	if utf8.RuneCountInString(line) > quoteExcerptRunes {
+		line = string([]rune(line)[:quoteExcerptRunes])
+		cut = true
+	}
+	if cut {
+		line += "…"
+	}
+	return line
+}
@@ -0,0 +1,151 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bken/server/internal/core"
+	"bken/server/internal/store"
+)
+
+// startExportServer serves a store holding a short conversation in channel
+// 1 of srv-1 and returns its URL and a session token for srv-1.
+func startExportServer(t *testing.T) (string, string) {
+	t.Helper()
+	st, err := store.Open(filepath.Join(t.TempDir(), "bken.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	ctx := context.Background()
+	first, err := st.InsertMessage(ctx, "srv-1", "1", "u1", "alice", "anyone up for a game?", 1_700_000_000_000, "", "", 0, 0)
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	reply, err := st.InsertMessage(ctx, "srv-1", "1", "u2", "bob", "sure, in 5", 1_700_000_060_000, "", "", 0, first)
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if _, err := st.InsertMessage(ctx, "srv-1", "1", "u3", "carol", "spam", 1_700_000_120_000, "", "", 0, 0); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if _, err := st.InsertMessage(ctx, "srv-2", "1", "u9", "mallory", "other server", 1_700_000_000_000, "", "", 0, 0); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if ok, err := st.EditMessage(ctx, "srv-1", reply, "sure, in 10", 1_700_000_090_000); err != nil || !ok {
+		t.Fatalf("edit: ok=%v err=%v", ok, err)
+	}
+	if _, err := st.PurgeMessages(ctx, "srv-1", "1", 1); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+
+	channelState := core.NewChannelState("")
+	alice, _, err := channelState.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add alice: %v", err)
+	}
+	if _, _, err := channelState.ConnectServer(alice.UserID, "srv-1"); err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+
+	ts := httptest.NewServer(New(channelState, st).Echo())
+	t.Cleanup(ts.Close)
+	return ts.URL, alice.Token
+}
+
+func exportChannel(t *testing.T, url, token, format string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url+"/api/channels/1/export?format="+format, nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET export: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return resp, string(body)
+}
+
+func TestExportChannelJSON(t *testing.T) {
+	t.Parallel()
+	url, token := startExportServer(t)
+
+	if resp, _ := exportChannel(t, url, "", "json"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", resp.StatusCode)
+	}
+	if resp, _ := exportChannel(t, url, token, "pdf"); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown format, got %d", resp.StatusCode)
+	}
+
+	resp, body := exportChannel(t, url, token, "json")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	if cd := resp.Header.Get("Content-Disposition"); !strings.Contains(cd, "channel-1.json") {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	var entries []TranscriptEntry
+	if err := json.Unmarshal([]byte(body), &entries); err != nil {
+		t.Fatalf("decode export: %v\n%s", err, body)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected the 3 messages of srv-1, got %+v", entries)
+	}
+	if e := entries[0]; e.Username != "alice" || e.Message != "anyone up for a game?" || e.TS != 1_700_000_000_000 || e.Edited || e.Deleted {
+		t.Errorf("unexpected first entry: %+v", e)
+	}
+	if e := entries[1]; e.ReplyTo != entries[0].ID || e.Message != "sure, in 10" || !e.Edited {
+		t.Errorf("expected bob's edited reply, got %+v", e)
+	}
+	if e := entries[2]; !e.Deleted || e.Message != "" || e.Username != "carol" {
+		t.Errorf("expected carol's deleted message without text, got %+v", e)
+	}
+}
+
+func TestExportChannelMarkdown(t *testing.T) {
+	t.Parallel()
+	url, token := startExportServer(t)
+
+	resp, body := exportChannel(t, url, token, "md")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/markdown") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	want := `# Channel 1 transcript
+
+**alice** · 2023-11-14 22:13:20 UTC
+
+anyone up for a game?
+
+**bob** · 2023-11-14 22:14:20 UTC · edited
+
+> **alice:** anyone up for a game?
+
+sure, in 10
+
+**carol** · 2023-11-14 22:15:20 UTC · deleted
+
+_This message was deleted._
+
+`
+	if body != want {
+		t.Errorf("markdown export:\n%s\nwant:\n%s", body, want)
+	}
+}
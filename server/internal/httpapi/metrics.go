@@ -0,0 +1,110 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"bken/server/internal/core"
+	"bken/server/internal/store"
+)
+
+// MetricsHandler serves channelState's metrics in the Prometheus text
+// exposition format (version 0.0.4). The active ban count comes from st and
+// is left out when st is nil.
+func MetricsHandler(channelState *core.ChannelState, st *store.Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(r.Context(), w, channelState, st)
+	})
+}
+
+func writeMetrics(ctx context.Context, w io.Writer, channelState *core.ChannelState, st *store.Store) {
+	counters := channelState.Counters()
+
+	writeMetric(w, "bken_clients", "gauge", "Connected websocket clients.")
+	fmt.Fprintf(w, "bken_clients %d\n", channelState.ClientCount())
+
+	writeMetric(w, "bken_messages_total", "counter", "Control messages delivered to clients.")
+	fmt.Fprintf(w, "bken_messages_total %d\n", counters.MessagesSent)
+
+	writeMetric(w, "bken_skipped_messages_total", "counter", "Control messages dropped because a client's send queue was full.")
+	fmt.Fprintf(w, "bken_skipped_messages_total %d\n", counters.MessagesSkipped)
+
+	writeMetric(w, "bken_bytes_total", "counter", "Websocket payload bytes by direction.")
+	fmt.Fprintf(w, "bken_bytes_total{direction=\"in\"} %d\n", counters.BytesIn)
+	fmt.Fprintf(w, "bken_bytes_total{direction=\"out\"} %d\n", counters.BytesOut)
+
+	writeMetric(w, "bken_bans_total", "counter", "Users banned and disconnected.")
+	fmt.Fprintf(w, "bken_bans_total %d\n", counters.Bans)
+
+	if st != nil {
+		if n, err := st.CountBans(ctx, time.Now()); err != nil {
+			slog.Warn("metrics: count bans", "err", err)
+		} else {
+			writeMetric(w, "bken_active_bans", "gauge", "Bans that have not expired.")
+			fmt.Fprintf(w, "bken_active_bans %d\n", n)
+		}
+	}
+
+	writeMetric(w, "bken_voice_channel_users", "gauge", "Users in each voice channel.")
+	for _, occ := range channelState.VoiceOccupancy() {
+		fmt.Fprintf(w, "bken_voice_channel_users{server_id=\"%s\",channel_id=\"%s\"} %d\n",
+			escapeLabel(occ.ServerID), escapeLabel(occ.ChannelID), occ.Users)
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	writeMetric(w, "go_goroutines", "gauge", "Number of goroutines that currently exist.")
+	fmt.Fprintf(w, "go_goroutines %d\n", runtime.NumGoroutine())
+
+	writeMetric(w, "go_memstats_heap_alloc_bytes", "gauge", "Heap bytes allocated and still in use.")
+	fmt.Fprintf(w, "go_memstats_heap_alloc_bytes %d\n", mem.HeapAlloc)
+}
+
+func writeMetric(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes a label value per the exposition format.
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+// RunMetrics serves /metrics on addr until ctx is cancelled. It runs on its
+// own listener so the scrape endpoint can stay off the public port.
+func RunMetrics(ctx context.Context, addr string, channelState *core.ChannelState, st *store.Store) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", MetricsHandler(channelState, st))
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	errCh := make(chan error, 1)
+	go func() {
+		err := srv.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+			return
+		}
+		errCh <- nil
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutCtx)
+		slog.Info("metrics server stopped")
+		return nil
+	}
+}
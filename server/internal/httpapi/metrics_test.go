@@ -0,0 +1,152 @@
+package httpapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"bken/server/internal/core"
+	"bken/server/internal/protocol"
+	"bken/server/internal/store"
+)
+
+func scrape(t *testing.T, channelState *core.ChannelState) string {
+	t.Helper()
+	ts := httptest.NewServer(MetricsHandler(channelState, nil))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from /metrics, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return string(body)
+}
+
+func TestMetricsExposition(t *testing.T) {
+	channelState := core.NewChannelState("")
+	alice, _, err := channelState.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add alice: %v", err)
+	}
+	if _, _, err := channelState.Add("bob", 8); err != nil {
+		t.Fatalf("add bob: %v", err)
+	}
+	if _, _, err := channelState.ConnectServer(alice.UserID, "srv-1"); err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+	if _, _, err := channelState.JoinVoice(alice.UserID, "srv-1", "chan-a"); err != nil {
+		t.Fatalf("join voice: %v", err)
+	}
+	channelState.SendTo(alice.UserID, protocol.Message{Type: protocol.TypePong})
+	channelState.AddBytesIn(10)
+	channelState.AddBytesOut(25)
+
+	body := scrape(t, channelState)
+	for _, want := range []string{
+		"# TYPE bken_clients gauge\nbken_clients 2\n",
+		"# TYPE bken_messages_total counter\nbken_messages_total 1\n",
+		"bken_skipped_messages_total 0\n",
+		"# TYPE bken_bans_total counter\nbken_bans_total 0\n",
+		`bken_bytes_total{direction="in"} 10` + "\n",
+		`bken_bytes_total{direction="out"} 25` + "\n",
+		`bken_voice_channel_users{server_id="srv-1",channel_id="chan-a"} 1` + "\n",
+		"# TYPE go_goroutines gauge\n",
+		"# TYPE go_memstats_heap_alloc_bytes gauge\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsCountersAreMonotonic(t *testing.T) {
+	channelState := core.NewChannelState("")
+	channelState.AddBytesIn(5)
+	_ = scrape(t, channelState)
+	channelState.AddBytesIn(5)
+
+	body := scrape(t, channelState)
+	if !strings.Contains(body, `bken_bytes_total{direction="in"} 10`) {
+		t.Errorf("scrape reset the counter:\n%s", body)
+	}
+}
+
+func TestMetricsListEmptyChannels(t *testing.T) {
+	channelState := core.NewChannelState("")
+	alice, _, _ := channelState.Add("alice", 8)
+	if _, _, err := channelState.ConnectServer(alice.UserID, "srv-1"); err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+	chID := strconv.FormatInt(channelState.Channels("srv-1")[0].ID, 10)
+
+	body := scrape(t, channelState)
+	want := `bken_voice_channel_users{server_id="srv-1",channel_id="` + chID + `"} 0` + "\n"
+	if !strings.Contains(body, want) {
+		t.Errorf("metrics missing %q\n%s", want, body)
+	}
+}
+
+func TestAPIServerMetricsAreOptional(t *testing.T) {
+	st, err := store.Open(filepath.Join(t.TempDir(), "bken.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	ctx := context.Background()
+	if err := st.RecordBan(ctx, store.Ban{IP: "192.0.2.1"}); err != nil {
+		t.Fatalf("record ban: %v", err)
+	}
+	if err := st.RecordBan(ctx, store.Ban{IP: "192.0.2.2", ExpiresAt: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatalf("record expired ban: %v", err)
+	}
+
+	api := New(core.NewChannelState(""), st)
+	ts := httptest.NewServer(api.Echo())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 before EnableMetrics, got %d", resp.StatusCode)
+	}
+
+	api.EnableMetrics()
+	resp, err = http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "bken_clients 0\n") {
+		t.Fatalf("unexpected /metrics response %d:\n%s", resp.StatusCode, body)
+	}
+	if want := "# TYPE bken_active_bans gauge\nbken_active_bans 1\n"; !strings.Contains(string(body), want) {
+		t.Errorf("metrics missing %q\n%s", want, body)
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	if got := escapeLabel("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Errorf("escapeLabel = %q", got)
+	}
+}
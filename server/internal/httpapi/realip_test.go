@@ -0,0 +1,122 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"bken/server/internal/core"
+	"bken/server/internal/protocol"
+	"bken/server/internal/store"
+
+	"github.com/gorilla/websocket"
+)
+
+// helloVia opens a websocket to ts with the given X-Forwarded-For header
+// and returns the first reply to hello.
+func helloVia(t *testing.T, ts *httptest.Server, xff string) protocol.Message {
+	t.Helper()
+	header := http.Header{}
+	if xff != "" {
+		header.Set("X-Forwarded-For", xff)
+	}
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", header)
+	if err != nil {
+		t.Fatalf("dial ws: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.WriteJSON(protocol.Message{Type: protocol.TypeHello, Username: "mallory"}); err != nil {
+		t.Fatalf("write hello: %v", err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg protocol.Message
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("read reply: %v", err)
+	}
+	return msg
+}
+
+func TestSpoofedForwardedForDoesNotBypassBan(t *testing.T) {
+	st, err := store.Open(filepath.Join(t.TempDir(), "bken.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.RecordBan(context.Background(), store.Ban{IP: "127.0.0.1", Reason: "spam", BannedBy: "owner"}); err != nil {
+		t.Fatalf("record ban: %v", err)
+	}
+
+	api := New(core.NewChannelState(""), st)
+	ts := httptest.NewServer(api.Echo())
+	defer ts.Close()
+
+	for _, xff := range []string{"", "203.0.113.9", "203.0.113.9, 127.0.0.1"} {
+		msg := helloVia(t, ts, xff)
+		if msg.Type != protocol.TypeError || !strings.Contains(msg.Error, "banned") {
+			t.Fatalf("X-Forwarded-For %q: expected a ban rejection, got %+v", xff, msg)
+		}
+	}
+}
+
+func TestTrustedProxyForwardedFor(t *testing.T) {
+	st, err := store.Open(filepath.Join(t.TempDir(), "bken.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.RecordBan(context.Background(), store.Ban{IP: "203.0.113.9", Reason: "spam", BannedBy: "owner"}); err != nil {
+		t.Fatalf("record ban: %v", err)
+	}
+
+	api := New(core.NewChannelState(""), st)
+	if err := api.SetTrustedProxies([]string{"not-a-cidr"}); err == nil {
+		t.Fatal("expected an error for a bad CIDR")
+	}
+	if err := api.SetTrustedProxies([]string{"127.0.0.1/32"}); err != nil {
+		t.Fatalf("set trusted proxies: %v", err)
+	}
+	ts := httptest.NewServer(api.Echo())
+	defer ts.Close()
+
+	// Through the trusted proxy, the forwarded address is the client's.
+	if msg := helloVia(t, ts, "203.0.113.9"); msg.Type != protocol.TypeError || !strings.Contains(msg.Error, "banned") {
+		t.Fatalf("expected the forwarded address to be banned, got %+v", msg)
+	}
+	if msg := helloVia(t, ts, "198.51.100.7"); msg.Type != protocol.TypeSnapshot {
+		t.Fatalf("expected another forwarded address to get in, got %+v", msg)
+	}
+}
+
+func TestSpoofedForwardedForDoesNotBypassConnectRate(t *testing.T) {
+	channelState := core.NewChannelState("")
+	if err := channelState.SetConnectRate(1); err != nil {
+		t.Fatalf("set connect rate: %v", err)
+	}
+	api := New(channelState, nil)
+	ts := httptest.NewServer(api.Echo())
+	defer ts.Close()
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+	for i, xff := range []string{"203.0.113.1", "203.0.113.2"} {
+		header := http.Header{"X-Forwarded-For": {xff}}
+		conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+		if i == 0 {
+			if err != nil {
+				t.Fatalf("first dial: %v", err)
+			}
+			conn.Close()
+			continue
+		}
+		if err == nil {
+			conn.Close()
+			t.Fatal("expected a fresh X-Forwarded-For to be throttled")
+		}
+		if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("expected 429, got %v (%v)", resp, err)
+		}
+	}
+}
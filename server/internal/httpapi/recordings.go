@@ -0,0 +1,165 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bken/server/internal/recording"
+	"bken/server/internal/store"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxRecordingUploadBytes bounds a recording upload: about 90 minutes of
+// one speaker's 48 kHz mono audio.
+const maxRecordingUploadBytes int64 = 512 << 20
+
+// RecordingResponse describes a finished recording.
+type RecordingResponse struct {
+	ID         string `json:"id"`
+	ChannelID  string `json:"channel_id"`
+	UserID     string `json:"user_id"`
+	DurationMS int64  `json:"duration_ms"`
+	SizeBytes  int64  `json:"size_bytes"`
+	CreatedAt  string `json:"created_at"`
+}
+
+func recordingResponse(info store.RecordingInfo) RecordingResponse {
+	return RecordingResponse{
+		ID:         info.ID,
+		ChannelID:  info.ChannelID,
+		UserID:     info.UserID,
+		DurationMS: info.Duration.Milliseconds(),
+		SizeBytes:  info.SizeBytes,
+		CreatedAt:  info.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// EnableRecordings serves recording uploads and exports from recs.
+func (s *Server) EnableRecordings(recs *recording.Store) {
+	s.recordings = recs
+	s.echo.POST("/api/recordings", s.handleRecordingUpload)
+	s.echo.GET("/api/recordings", s.handleListRecordings)
+	s.echo.GET("/api/recordings/:id", s.handleRecordingDownload)
+}
+
+// handleRecordingUpload mixes a finished recording into one WAV. The
+// recorder's client decodes each speaker's Opus frames and posts them as
+// "track" WAV parts, each with a matching "offset_ms" (when that speaker's
+// first frame arrived) and "user_id" field, plus the "channel_id" recorded.
+func (s *Server) handleRecordingUpload(c echo.Context) error {
+	userID, serverID, err := s.sessionUser(c)
+	if err != nil {
+		return err
+	}
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, maxRecordingUploadBytes)
+	form, err := c.MultipartForm()
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return uploadTooLarge(maxRecordingUploadBytes)
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, "multipart form is required")
+	}
+	defer form.RemoveAll()
+
+	channelID := strings.TrimSpace(c.FormValue("channel_id"))
+	if channelID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "channel_id is required")
+	}
+	parts := form.File["track"]
+	offsets := form.Value["offset_ms"]
+	speakers := form.Value["user_id"]
+	if len(parts) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "at least one track is required")
+	}
+	if len(offsets) != len(parts) || len(speakers) != len(parts) {
+		return echo.NewHTTPError(http.StatusBadRequest, "each track needs an offset_ms and a user_id")
+	}
+
+	tracks := make([]recording.Track, len(parts))
+	for i, part := range parts {
+		offsetMS, err := strconv.ParseInt(offsets[i], 10, 64)
+		if err != nil || offsetMS < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "offset_ms must be a non-negative integer")
+		}
+		f, err := part.Open()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("open track: %v", err))
+		}
+		defer f.Close()
+		tracks[i] = recording.Track{
+			UserID: speakers[i],
+			Offset: time.Duration(offsetMS) * time.Millisecond,
+			WAV:    f,
+		}
+	}
+
+	info, err := s.recordings.Finish(c.Request().Context(), recording.FinishInput{
+		ServerID:  serverID,
+		ChannelID: channelID,
+		UserID:    userID,
+		Tracks:    tracks,
+	})
+	if errors.Is(err, recording.ErrBadTrack) {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		slog.Error("recording upload failed", "user_id", userID, "channel_id", channelID, "err", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "store recording failed")
+	}
+	return c.JSON(http.StatusCreated, recordingResponse(info))
+}
+
+// handleListRecordings lists the recordings of the caller's server.
+func (s *Server) handleListRecordings(c echo.Context) error {
+	_, serverID, err := s.sessionUser(c)
+	if err != nil {
+		return err
+	}
+	infos, err := s.recordings.Recordings(c.Request().Context(), serverID)
+	if err != nil {
+		slog.Error("list recordings", "server_id", serverID, "err", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "list recordings failed")
+	}
+	out := make([]RecordingResponse, 0, len(infos))
+	for _, info := range infos {
+		out = append(out, recordingResponse(info))
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+// handleRecordingDownload serves a recording's WAV to members of the server
+// it was made on.
+func (s *Server) handleRecordingDownload(c echo.Context) error {
+	_, serverID, err := s.sessionUser(c)
+	if err != nil {
+		return err
+	}
+	id := strings.TrimSpace(c.Param("id"))
+	result, err := s.recordings.Open(c.Request().Context(), id)
+	if errors.Is(err, store.ErrRecordingNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "recording not found")
+	}
+	if err != nil {
+		slog.Error("recording download error", "recording_id", id, "err", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "open recording failed")
+	}
+	defer result.File.Close()
+	if result.Info.ServerID != serverID {
+		return echo.NewHTTPError(http.StatusNotFound, "recording not found")
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "audio/wav")
+	c.Response().Header().Set(echo.HeaderContentLength, strconv.FormatInt(result.Info.SizeBytes, 10))
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="recording-%s.wav"`, id))
+	c.Response().WriteHeader(http.StatusOK)
+	_, copyErr := io.Copy(c.Response().Writer, result.File)
+	return copyErr
+}
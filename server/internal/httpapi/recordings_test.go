@@ -0,0 +1,137 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"bken/server/internal/core"
+	"bken/server/internal/recording"
+	"bken/server/internal/store"
+)
+
+// testWAV returns n silent samples in the recording format.
+func testWAV(n int) []byte {
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	_ = binary.Write(&b, binary.LittleEndian, uint32(36+2*n))
+	b.WriteString("WAVEfmt ")
+	for _, v := range []any{uint32(16), uint16(1), uint16(1), uint32(48000), uint32(96000), uint16(2), uint16(16)} {
+		_ = binary.Write(&b, binary.LittleEndian, v)
+	}
+	b.WriteString("data")
+	_ = binary.Write(&b, binary.LittleEndian, uint32(2*n))
+	b.Write(make([]byte, 2*n))
+	return b.Bytes()
+}
+
+func TestRecordingUploadListAndExport(t *testing.T) {
+	t.Parallel()
+
+	temp := t.TempDir()
+	st, err := store.Open(filepath.Join(temp, "bken.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	recs, err := recording.NewStore(filepath.Join(temp, "recordings"), st)
+	if err != nil {
+		t.Fatalf("create recording store: %v", err)
+	}
+
+	channelState := core.NewChannelState("")
+	alice, _, _ := channelState.Add("alice", 8)
+	mallory, _, _ := channelState.Add("mallory", 8)
+	if _, _, err := channelState.ConnectServer(alice.UserID, "srv-1"); err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+	if _, _, err := channelState.ConnectServer(mallory.UserID, "srv-2"); err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+
+	api := New(channelState, st)
+	api.EnableRecordings(recs)
+	ts := httptest.NewServer(api.Echo())
+	t.Cleanup(ts.Close)
+
+	do := func(method, path, token string, body io.Reader, contentType string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL+path, body)
+		if err != nil {
+			t.Fatalf("new request: %v", err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("channel_id", "1")
+	for _, tr := range []struct{ user, offset string }{{alice.UserID, "0"}, {"u9", "500"}} {
+		part, err := mw.CreateFormFile("track", tr.user+".wav")
+		if err != nil {
+			t.Fatalf("create form file: %v", err)
+		}
+		part.Write(testWAV(48000))
+		_ = mw.WriteField("offset_ms", tr.offset)
+		_ = mw.WriteField("user_id", tr.user)
+	}
+	mw.Close()
+
+	if resp := do(http.MethodPost, "/api/recordings", "", bytes.NewReader(body.Bytes()), mw.FormDataContentType()); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", resp.StatusCode)
+	}
+	resp := do(http.MethodPost, "/api/recordings", alice.Token, &body, mw.FormDataContentType())
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(resp.Body)
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, msg)
+	}
+	var created RecordingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if created.DurationMS != 1500 || created.UserID != alice.UserID || created.ChannelID != "1" {
+		t.Fatalf("unexpected recording: %+v", created)
+	}
+
+	var list []RecordingResponse
+	if err := json.NewDecoder(do(http.MethodGet, "/api/recordings", alice.Token, nil, "").Body).Decode(&list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != created.ID {
+		t.Fatalf("unexpected list: %+v", list)
+	}
+
+	resp = do(http.MethodGet, "/api/recordings/"+created.ID, alice.Token, nil, "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "audio/wav" {
+		t.Fatalf("unexpected export: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	data, _ := io.ReadAll(resp.Body)
+	if int64(len(data)) != created.SizeBytes || string(data[:4]) != "RIFF" {
+		t.Fatalf("unexpected export body of %d bytes", len(data))
+	}
+
+	// Recordings stay with the server they were made on.
+	if resp := do(http.MethodGet, "/api/recordings/"+created.ID, mallory.Token, nil, ""); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 from another server, got %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodGet, "/api/recordings/nope", alice.Token, nil, ""); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown id, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,102 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"bken/server/internal/core"
+	"bken/server/internal/store"
+)
+
+func TestSearchMessagesRequiresSessionAndPages(t *testing.T) {
+	t.Parallel()
+
+	st, err := store.Open(filepath.Join(t.TempDir(), "bken.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	ctx := context.Background()
+	var ids []int64
+	for i := range 3 {
+		id, err := st.InsertMessage(ctx, "srv-1", "1", "u9", "bob", fmt.Sprintf("release %d", i), int64(1000+i), "", "", 0, 0)
+		if err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if _, err := st.InsertMessage(ctx, "srv-2", "1", "u9", "bob", "release elsewhere", 2000, "", "", 0, 0); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	channelState := core.NewChannelState("")
+	alice, _, err := channelState.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add alice: %v", err)
+	}
+	if _, _, err := channelState.ConnectServer(alice.UserID, "srv-1"); err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+
+	api := New(channelState, st)
+	ts := httptest.NewServer(api.Echo())
+	t.Cleanup(ts.Close)
+
+	search := func(token, query string) (int, []SearchResult) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/api/channels/1/search?"+query, nil)
+		if err != nil {
+			t.Fatalf("new request: %v", err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET search: %v", err)
+		}
+		defer resp.Body.Close()
+		var results []SearchResult
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+				t.Fatalf("decode results: %v", err)
+			}
+		}
+		return resp.StatusCode, results
+	}
+
+	if code, _ := search("", "q=release"); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", code)
+	}
+	if code, _ := search("not-a-token", "q=release"); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for an unknown token, got %d", code)
+	}
+	if code, _ := search(alice.Token, "q=release&before=abc"); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad cursor, got %d", code)
+	}
+
+	code, page := search(alice.Token, "q=release&limit=2")
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(page) != 2 || page[0].ID != ids[2] || page[1].ID != ids[1] {
+		t.Fatalf("expected the two newest matches from srv-1, got %+v", page)
+	}
+
+	code, page = search(alice.Token, fmt.Sprintf("q=release&limit=2&before=%d", page[1].ID))
+	if code != http.StatusOK || len(page) != 1 || page[0].ID != ids[0] {
+		t.Fatalf("expected the oldest match on page 2, got %d %+v", code, page)
+	}
+
+	// The token dies with the session.
+	channelState.Remove(alice.UserID)
+	if code, _ := search(alice.Token, "q=release"); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 after the session ended, got %d", code)
+	}
+}
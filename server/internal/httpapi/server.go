@@ -2,10 +2,12 @@ package httpapi
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -14,6 +16,7 @@ import (
 	"bken/server/internal/blob"
 	"bken/server/internal/core"
 	"bken/server/internal/protocol"
+	"bken/server/internal/recording"
 	"bken/server/internal/store"
 	"bken/server/internal/ws"
 
@@ -27,6 +30,8 @@ type Server struct {
 	channelState *core.ChannelState
 	store        *store.Store
 	blobs        *blob.Store
+	recordings   *recording.Store
+	tlsConfig    *tls.Config
 }
 
 // New constructs an Echo app with websocket + REST routes.
@@ -34,6 +39,9 @@ func New(channelState *core.ChannelState, st *store.Store, blobs ...*blob.Store)
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
+	// Client IPs back bans and the connect rate limit, so never take them
+	// from headers the client controls; see SetTrustedProxies.
+	e.IPExtractor = echo.ExtractIPDirect()
 	e.Use(middleware.Recover())
 	e.Use(requestLogger())
 
@@ -90,6 +98,9 @@ func (s *Server) Echo() *echo.Echo {
 func (s *Server) registerRoutes() {
 	s.echo.GET("/health", s.handleHealth)
 	s.echo.GET("/api/state", s.handleState)
+	s.echo.GET("/api/stats", s.handleStats)
+	s.echo.GET("/api/channels/:id/search", s.handleSearchMessages)
+	s.echo.GET("/api/channels/:id/export", s.handleExportChannel)
 	if s.blobs != nil {
 		s.echo.POST("/api/blobs", s.handleBlobUpload)
 		s.echo.POST("/api/upload", s.handleBlobUpload) // Backward-compatible alias.
@@ -99,11 +110,55 @@ func (s *Server) registerRoutes() {
 	ws.NewHandler(s.channelState, s.store).Register(s.echo)
 }
 
+// EnableMetrics serves the Prometheus /metrics endpoint on the API
+// listener. It is off by default so public servers don't expose it.
+func (s *Server) EnableMetrics() {
+	s.echo.GET("/metrics", echo.WrapHandler(MetricsHandler(s.channelState, s.store)))
+}
+
+// SetTrustedProxies takes client IPs from X-Forwarded-For when the
+// request comes through one of the proxies in cidrs, for servers behind a
+// reverse proxy. Every other peer's own address is used as-is. An empty
+// list goes back to ignoring the header.
+func (s *Server) SetTrustedProxies(cidrs []string) error {
+	if len(cidrs) == 0 {
+		s.echo.IPExtractor = echo.ExtractIPDirect()
+		return nil
+	}
+	opts := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return fmt.Errorf("trusted proxy %q: %w", cidr, err)
+		}
+		opts = append(opts, echo.TrustIPRange(ipNet))
+	}
+	s.echo.IPExtractor = echo.ExtractIPFromXFFHeader(opts...)
+	return nil
+}
+
+// SetTLSConfig makes Run serve HTTPS with cfg; nil serves plain HTTP.
+func (s *Server) SetTLSConfig(cfg *tls.Config) {
+	s.tlsConfig = cfg
+}
+
 // Run starts Echo and blocks until ctx cancellation or startup failure.
 func (s *Server) Run(ctx context.Context, addr string) error {
 	errCh := make(chan error, 1)
 	go func() {
-		err := s.echo.Start(addr)
+		var err error
+		if s.tlsConfig != nil {
+			srv := s.echo.TLSServer
+			srv.Addr = addr
+			srv.TLSConfig = s.tlsConfig
+			err = s.echo.StartServer(srv)
+		} else {
+			err = s.echo.Start(addr)
+		}
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			errCh <- err
 			return
@@ -152,6 +207,154 @@ func (s *Server) handleState(c echo.Context) error {
 	})
 }
 
+type statsResponse struct {
+	Clients int `json:"clients"`
+	// ClientVersions counts connected clients by reported build commit;
+	// clients that sent no build info are counted under "unknown".
+	ClientVersions map[string]int `json:"client_versions"`
+	// Users lists each client's build and bytes written. It is only sent
+	// to admins and the owner.
+	Users []core.UserClient `json:"users,omitempty"`
+}
+
+// handleStats reports connected client builds. Anyone may read the counts;
+// the per-user detail needs the bearer session_token of an admin or the
+// owner.
+func (s *Server) handleStats(c echo.Context) error {
+	admin := false
+	if c.Request().Header.Get(echo.HeaderAuthorization) != "" {
+		userID, err := s.tokenUser(c)
+		if err != nil {
+			return err
+		}
+		if core.RoleLevel(s.channelState.Role(userID)) < core.RoleLevel(core.RoleAdmin) {
+			return echo.NewHTTPError(http.StatusForbidden, "per-user stats are for admins and the owner")
+		}
+		admin = true
+	}
+
+	users := s.channelState.ClientInfos()
+	versions := make(map[string]int)
+	for _, u := range users {
+		commit := u.Client.Commit
+		if commit == "" {
+			commit = "unknown"
+		}
+		versions[commit]++
+	}
+	resp := statsResponse{
+		Clients:        len(users),
+		ClientVersions: versions,
+	}
+	if admin {
+		resp.Users = users
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// Search page sizes for GET /api/channels/:id/search.
+const (
+	defaultSearchLimit = 25
+	maxSearchLimit     = 100
+)
+
+// SearchResult is one message matched by GET /api/channels/:id/search.
+type SearchResult struct {
+	ID        int64  `json:"id"`
+	ChannelID string `json:"channel_id"`
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	Message   string `json:"message"`
+	TS        int64  `json:"ts"`
+	FileID    string `json:"file_id,omitempty"`
+	FileName  string `json:"file_name,omitempty"`
+	ReplyTo   int64  `json:"reply_to,omitempty"`
+}
+
+// sessionUser authenticates a REST caller by the session_token from its
+// websocket snapshot and returns the caller and the server it is on.
+func (s *Server) sessionUser(c echo.Context) (userID, serverID string, err error) {
+	userID, err = s.tokenUser(c)
+	if err != nil {
+		return "", "", err
+	}
+	serverID, err = s.channelState.UserServer(userID)
+	if err != nil {
+		return "", "", echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+	return userID, serverID, nil
+}
+
+// tokenUser authenticates a REST call by the bearer session_token alone,
+// for calls that do not need the caller to be connected to a server.
+func (s *Server) tokenUser(c echo.Context) (string, error) {
+	token, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if !ok {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, "bearer session token is required")
+	}
+	userID, ok := s.channelState.UserByToken(strings.TrimSpace(token))
+	if !ok {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, "invalid session token")
+	}
+	return userID, nil
+}
+
+// handleSearchMessages searches a channel of the caller's server, newest
+// first. The caller authenticates with the session_token from its
+// websocket snapshot; pass the last result's ID as before to page back.
+func (s *Server) handleSearchMessages(c echo.Context) error {
+	if s.store == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "message history not available")
+	}
+	userID, serverID, err := s.sessionUser(c)
+	if err != nil {
+		return err
+	}
+
+	channelID := strings.TrimSpace(c.Param("id"))
+	query := strings.TrimSpace(c.QueryParam("q"))
+	if query == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "q is required")
+	}
+	var before int64
+	if v := c.QueryParam("before"); v != "" {
+		before, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || before <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "before must be a positive message id")
+		}
+	}
+	limit := defaultSearchLimit
+	if v := c.QueryParam("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "limit must be a positive integer")
+		}
+		limit = min(limit, maxSearchLimit)
+	}
+
+	rows, err := s.store.SearchMessages(c.Request().Context(), serverID, channelID, query, before, limit)
+	if err != nil {
+		slog.Error("search messages", "user_id", userID, "server_id", serverID, "channel_id", channelID, "err", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "search failed")
+	}
+	results := make([]SearchResult, 0, len(rows))
+	for _, m := range rows {
+		results = append(results, SearchResult{
+			ID:        m.ID,
+			ChannelID: m.ChannelID,
+			UserID:    m.UserID,
+			Username:  m.Username,
+			Message:   m.Message,
+			TS:        m.TS,
+			FileID:    m.FileID,
+			FileName:  m.FileName,
+			ReplyTo:   m.ReplyTo,
+		})
+	}
+	slog.Debug("search messages", "user_id", userID, "server_id", serverID, "channel_id", channelID, "count", len(results))
+	return c.JSON(http.StatusOK, results)
+}
+
 type blobUploadResponse struct {
 	ID           string `json:"id"`
 	Kind         string `json:"kind"`
@@ -166,10 +369,22 @@ func (s *Server) handleBlobUpload(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusServiceUnavailable, "blob storage is not configured")
 	}
 
+	limit := s.channelState.MaxUploadBytes()
+	// Bound the whole body so an oversized upload is cut off before it is
+	// spooled to disk; the slack covers multipart headers.
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, limit+multipartOverhead)
+
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return uploadTooLarge(limit)
+		}
 		return echo.NewHTTPError(http.StatusBadRequest, "multipart file field \"file\" is required")
 	}
+	if fileHeader.Size > limit {
+		return uploadTooLarge(limit)
+	}
 
 	src, err := fileHeader.Open()
 	if err != nil {
@@ -202,6 +417,14 @@ func (s *Server) handleBlobUpload(c echo.Context) error {
 	})
 }
 
+// multipartOverhead is the allowance for multipart framing and form fields
+// on top of the file itself when bounding an upload body.
+const multipartOverhead = 64 * 1024
+
+func uploadTooLarge(limit int64) error {
+	return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("file exceeds the %d byte upload limit", limit))
+}
+
 func (s *Server) handleBlobDownload(c echo.Context) error {
 	if s.blobs == nil {
 		return echo.NewHTTPError(http.StatusServiceUnavailable, "blob storage is not configured")
@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"bken/server/internal/core"
+	"bken/server/internal/protocol"
 )
 
 func TestHealthAndState(t *testing.T) {
@@ -64,3 +66,79 @@ func TestHealthAndState(t *testing.T) {
 		t.Fatalf("expected voice presence in state, got %#v", state.Users[0])
 	}
 }
+
+func TestStatsReportsClientBuilds(t *testing.T) {
+	channelState := core.NewChannelState("")
+	alice, _, err := channelState.Add("alice", 8)
+	if err != nil {
+		t.Fatalf("add alice: %v", err)
+	}
+	bob, _, err := channelState.Add("bob", 8)
+	if err != nil {
+		t.Fatalf("add bob: %v", err)
+	}
+	// Enough users to reach u10, which must sort after u2.
+	for i := 3; i <= 10; i++ {
+		if _, _, err := channelState.Add("user"+strconv.Itoa(i), 8); err != nil {
+			t.Fatalf("add user %d: %v", i, err)
+		}
+	}
+	channelState.SetClientInfo(alice.UserID, protocol.ClientInfo{Commit: "abc123", OS: "linux", Arch: "amd64"})
+	channelState.SetClientInfo(bob.UserID, protocol.ClientInfo{Commit: "abc123", OS: "windows", Arch: "amd64"})
+
+	api := New(channelState, nil)
+	ts := httptest.NewServer(api.Echo())
+	defer ts.Close()
+
+	getStats := func(token string, wantStatus int) statsResponse {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/api/stats", nil)
+		if err != nil {
+			t.Fatalf("new request: %v", err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /api/stats: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != wantStatus {
+			t.Fatalf("expected %d from /api/stats, got %d", wantStatus, resp.StatusCode)
+		}
+		var stats statsResponse
+		if wantStatus == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+				t.Fatalf("decode stats: %v", err)
+			}
+		}
+		return stats
+	}
+
+	stats := getStats("", http.StatusOK)
+	if stats.Clients != 10 {
+		t.Fatalf("expected 10 clients, got %d", stats.Clients)
+	}
+	if stats.ClientVersions["abc123"] != 2 || stats.ClientVersions["unknown"] != 8 {
+		t.Fatalf("unexpected client versions: %#v", stats.ClientVersions)
+	}
+	if len(stats.Users) != 0 {
+		t.Fatalf("anonymous stats must not list users, got %#v", stats.Users)
+	}
+
+	getStats(bob.Token, http.StatusForbidden)
+	getStats("not-a-token", http.StatusUnauthorized)
+
+	// alice joined first, so she owns the server.
+	stats = getStats(alice.Token, http.StatusOK)
+	if len(stats.Users) != 10 {
+		t.Fatalf("expected 10 users for the owner, got %d", len(stats.Users))
+	}
+	if stats.Users[0].Username != "alice" || stats.Users[0].Client.OS != "linux" {
+		t.Fatalf("unexpected first user: %#v", stats.Users[0])
+	}
+	if stats.Users[1].UserID != "u2" || stats.Users[9].UserID != "u10" {
+		t.Fatalf("users not ordered by numeric ID: %s, %s", stats.Users[1].UserID, stats.Users[9].UserID)
+	}
+}
@@ -0,0 +1,281 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Control codecs a client can ask for with the proto field of hello.
+const (
+	ProtoJSON   = "json"
+	ProtoBinary = "binary"
+)
+
+// maxBinaryDepth bounds nesting when decoding a binary frame, so a hostile
+// peer cannot exhaust the stack.
+const maxBinaryDepth = 32
+
+var errBinaryTruncated = errors.New("binary message truncated")
+
+// JSONToBinary re-encodes a JSON control message as MessagePack, the
+// payload of a binary websocket frame once ProtoBinary is negotiated. The
+// websocket frame carries the length, and strings, arrays and maps inside
+// are length-prefixed by MessagePack itself. Going through JSON keeps the
+// field names and tags of Message the single source of truth.
+func JSONToBinary(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(data))
+	return appendBinary(out, v)
+}
+
+// BinaryToJSON decodes a MessagePack control message back into JSON, so the
+// read loop can unmarshal it exactly like a text frame.
+func BinaryToJSON(data []byte) ([]byte, error) {
+	v, rest, err := readBinary(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("binary message has %d trailing bytes", len(rest))
+	}
+	return json.Marshal(v)
+}
+
+func appendBinary(b []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if v {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return appendBinaryInt(b, i), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, 0xcb)
+		return binary.BigEndian.AppendUint64(b, math.Float64bits(f)), nil
+	case string:
+		return appendBinaryString(b, v), nil
+	case []any:
+		b = appendBinaryHeader(b, len(v), 0x90, 16, 0xdc)
+		for _, e := range v {
+			var err error
+			if b, err = appendBinary(b, e); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]any:
+		b = appendBinaryHeader(b, len(v), 0x80, 16, 0xde)
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			b = appendBinaryString(b, k)
+			var err error
+			if b, err = appendBinary(b, v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("cannot encode %T", v)
+	}
+}
+
+func appendBinaryInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i < 128:
+		return append(b, byte(i))
+	case i < 0 && i >= -32:
+		return append(b, byte(i))
+	case i >= 0 && i <= math.MaxUint8:
+		return append(b, 0xcc, byte(i))
+	case i >= 0 && i <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(i))
+	case i >= 0 && i <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(i))
+	case i >= math.MinInt8 && i < 0:
+		return append(b, 0xd0, byte(i))
+	case i >= math.MinInt16 && i < 0:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(i))
+	case i >= math.MinInt32 && i < 0:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(i))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+	}
+}
+
+func appendBinaryString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+// appendBinaryHeader writes an array or map header: the fix form below
+// fixMax, else the 16-bit form at code, else the 32-bit form at code+1.
+func appendBinaryHeader(b []byte, n int, fix byte, fixMax int, code byte) []byte {
+	switch {
+	case n < fixMax:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, code), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, code+1), uint32(n))
+	}
+}
+
+func readBinary(b []byte, depth int) (any, []byte, error) {
+	if depth > maxBinaryDepth {
+		return nil, nil, errors.New("binary message nested too deeply")
+	}
+	if len(b) == 0 {
+		return nil, nil, errBinaryTruncated
+	}
+	c, b := b[0], b[1:]
+	switch {
+	case c <= 0x7f:
+		return int64(c), b, nil
+	case c >= 0xe0:
+		return int64(int8(c)), b, nil
+	case c&0xf0 == 0x80:
+		return readBinaryMap(b, int(c&0x0f), depth)
+	case c&0xf0 == 0x90:
+		return readBinaryArray(b, int(c&0x0f), depth)
+	case c&0xe0 == 0xa0:
+		return readBinaryString(b, int(c&0x1f))
+	}
+	switch c {
+	case 0xc0:
+		return nil, b, nil
+	case 0xc2:
+		return false, b, nil
+	case 0xc3:
+		return true, b, nil
+	case 0xca:
+		u, b, err := readBinaryUint(b, 4)
+		return float64(math.Float32frombits(uint32(u))), b, err
+	case 0xcb:
+		u, b, err := readBinaryUint(b, 8)
+		return math.Float64frombits(u), b, err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, b, err := readBinaryUint(b, 1<<(c-0xcc))
+		return u, b, err
+	case 0xd0:
+		u, b, err := readBinaryUint(b, 1)
+		return int64(int8(u)), b, err
+	case 0xd1:
+		u, b, err := readBinaryUint(b, 2)
+		return int64(int16(u)), b, err
+	case 0xd2:
+		u, b, err := readBinaryUint(b, 4)
+		return int64(int32(u)), b, err
+	case 0xd3:
+		u, b, err := readBinaryUint(b, 8)
+		return int64(u), b, err
+	case 0xd9, 0xda, 0xdb:
+		n, b, err := readBinaryUint(b, 1<<(c-0xd9))
+		if err != nil {
+			return nil, nil, err
+		}
+		return readBinaryString(b, int(n))
+	case 0xdc, 0xdd:
+		n, b, err := readBinaryUint(b, 2<<(c-0xdc))
+		if err != nil {
+			return nil, nil, err
+		}
+		return readBinaryArray(b, int(n), depth)
+	case 0xde, 0xdf:
+		n, b, err := readBinaryUint(b, 2<<(c-0xde))
+		if err != nil {
+			return nil, nil, err
+		}
+		return readBinaryMap(b, int(n), depth)
+	}
+	return nil, nil, fmt.Errorf("unsupported binary type 0x%02x", c)
+}
+
+func readBinaryUint(b []byte, size int) (uint64, []byte, error) {
+	if len(b) < size {
+		return 0, nil, errBinaryTruncated
+	}
+	var u uint64
+	for _, x := range b[:size] {
+		u = u<<8 | uint64(x)
+	}
+	return u, b[size:], nil
+}
+
+func readBinaryString(b []byte, n int) (any, []byte, error) {
+	if n > len(b) {
+		return nil, nil, errBinaryTruncated
+	}
+	return string(b[:n]), b[n:], nil
+}
+
+func readBinaryArray(b []byte, n int, depth int) (any, []byte, error) {
+	// Every element takes at least a byte, which also caps the allocation.
+	if n > len(b) {
+		return nil, nil, errBinaryTruncated
+	}
+	arr := make([]any, 0, n)
+	for range n {
+		var v any
+		var err error
+		if v, b, err = readBinary(b, depth+1); err != nil {
+			return nil, nil, err
+		}
+		arr = append(arr, v)
+	}
+	return arr, b, nil
+}
+
+func readBinaryMap(b []byte, n int, depth int) (any, []byte, error) {
+	if 2*n > len(b) {
+		return nil, nil, errBinaryTruncated
+	}
+	m := make(map[string]any, n)
+	for range n {
+		k, rest, err := readBinary(b, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("binary map key is %T, want string", k)
+		}
+		var v any
+		if v, b, err = readBinary(rest, depth+1); err != nil {
+			return nil, nil, err
+		}
+		m[key] = v
+	}
+	return m, b, nil
+}
@@ -0,0 +1,75 @@
+package protocol
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	muted := true
+	in := Message{
+		Type:         TypeSnapshot,
+		SelfID:       "5d2c",
+		ChannelID:    "12",
+		TS:           1700000000123,
+		MsgID:        -7,
+		Message:      strings.Repeat("x", 300),
+		Muted:        &muted,
+		Users:        []User{{ID: "a", Username: "alice"}},
+		Roles:        map[string]string{"a": "OWNER"},
+		AllowedEmoji: []string{"👍", "🎉"},
+		RetryAfterMs: 70000,
+	}
+	js, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	bin, err := JSONToBinary(js)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	back, err := BinaryToJSON(bin)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	var out Message
+	if err := json.Unmarshal(back, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("round trip changed message:\nin  %+v\nout %+v", in, out)
+	}
+}
+
+func TestBinaryPingIsSmallerThanJSON(t *testing.T) {
+	js, err := json.Marshal(Message{Type: TypePing, TS: 1700000000123})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	bin, err := JSONToBinary(js)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	t.Logf("ping: json %d bytes, binary %d bytes (%.0f%% smaller)", len(js), len(bin), 100*(1-float64(len(bin))/float64(len(js))))
+	if len(bin) >= len(js)*3/4 {
+		t.Fatalf("expected binary ping (%d bytes) to be at least 25%% smaller than json (%d bytes)", len(bin), len(js))
+	}
+}
+
+func TestBinaryToJSONRejectsMalformed(t *testing.T) {
+	cases := map[string][]byte{
+		"empty":          {},
+		"truncated str":  {0x81, 0xa4, 't', 'y'},
+		"trailing bytes": {0xc0, 0xc0},
+		"non-string key": {0x81, 0x01, 0x02},
+		"huge array":     {0xdd, 0xff, 0xff, 0xff, 0xff},
+		"unsupported":    {0xc4, 0x01, 0x00},
+	}
+	for name, data := range cases {
+		if _, err := BinaryToJSON(data); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -6,6 +6,11 @@ import (
 	"strings"
 )
 
+// ProtocolVersion is the major version of the websocket protocol. It is
+// bumped whenever a change would make old clients misbehave against a new
+// server (or vice versa); additive changes keep the same version.
+const ProtocolVersion = 1
+
 // Message types used by the websocket protocol.
 const (
 	TypeHello                 = "hello"
@@ -20,6 +25,9 @@ const (
 	TypeDisconnectVoiceLegacy = "disconnect_voice"
 	TypeSendText              = "send_text"
 	TypeTextMessage           = "text_message"
+	TypeDM                    = "dm"
+	TypeVoiceActivity         = "voice_activity"
+	TypeSpeaking              = "speaking"
 	TypePing                  = "ping"
 	TypePong                  = "pong"
 	TypeError                 = "error"
@@ -29,7 +37,16 @@ const (
 	TypeChannelList           = "channel_list"
 	TypeGetChannels           = "get_channels"
 	TypeGetMessages           = "get_messages"
+	TypeGetMessagesBefore     = "get_messages_before"
 	TypeMessageHistory        = "message_history"
+	TypeGetThread             = "get_thread"
+	TypeThread                = "thread"
+	TypePurgeMessages         = "purge_messages"
+	TypeMessageDeleted        = "message_deleted"
+	TypeEditMessage           = "edit_message"
+	TypeMessageEdited         = "message_edited"
+	TypeGetEditHistory        = "get_edit_history"
+	TypeEditHistory           = "edit_history"
 	TypeGetServerInfo         = "get_server_info"
 	TypeServerInfo            = "server_info"
 	TypeSetVoiceState         = "set_voice_state"
@@ -37,6 +54,63 @@ const (
 	TypeRemoveReaction        = "remove_reaction"
 	TypeReactionAdded         = "reaction_added"
 	TypeReactionRemoved       = "reaction_removed"
+	TypeReactionUpdate        = "reaction_update"
+	TypeStartRecording        = "start_recording"
+	TypeStopRecording         = "stop_recording"
+	TypeRecordingStarted      = "recording_started"
+	TypeRecordingStopped      = "recording_stopped"
+	TypeRecordingConsent      = "recording_consent"
+	TypeOwnerChanged          = "owner_changed"
+	TypeTransferOwner         = "transfer_owner"
+	TypeGetPermissions        = "get_permissions"
+	TypeSetRole               = "set_role"
+	TypeRoleChanged           = "role_changed"
+	TypeSetChannelPerms       = "set_channel_perms"
+	TypeSetChannelBitrate     = "set_channel_bitrate"
+	TypeSetSlowMode           = "set_slow_mode"
+	TypeSetChannelTTL         = "set_channel_ttl"
+	TypeSetChannelRecordRole  = "set_channel_record_role"
+	TypePermissions           = "permissions"
+	TypeVersionMismatch       = "version_mismatch"
+	TypeSoundboard            = "soundboard"
+	TypeBanUser               = "ban_user"
+	TypeKick                  = "kick"
+	TypeGetAuditLog           = "get_audit_log"
+	TypeAuditLog              = "audit_log"
+	TypeAuditEntry            = "audit_entry"
+	TypeKicked                = "kicked"
+	TypeMuteUser              = "mute_user"
+	TypeUserMuted             = "user_muted"
+	TypeSetStatus             = "set_status"
+	TypeUserStatus            = "user_status"
+	TypeRenameUser            = "rename_user"
+	TypeUserRenamed           = "user_renamed"
+	TypeTyping                = "typing"
+	TypeUserTyping            = "user_typing"
+	TypeSetAnnouncement       = "set_announcement"
+	TypeAnnouncement          = "announcement"
+	TypeMention               = "mention"
+	TypeICEUpdate             = "ice_update"
+	TypeReadReceipt           = "read_receipt"
+	TypeMessageRead           = "message_read"
+	TypeCreateCategory        = "create_category"
+	TypeAssignChannelCategory = "assign_channel_category"
+	TypeResume                = "resume"
+	TypeServerShutdown        = "server_shutdown"
+	TypeSetWordFilter         = "set_word_filter"
+	TypeWordFilter            = "word_filter"
+	TypeMonitorChannel        = "monitor_channel"
+	TypeUnmonitorChannel      = "unmonitor_channel"
+	TypeGetBans               = "get_bans"
+	TypeBanList               = "ban_list"
+	TypeUnban                 = "unban"
+	TypePinMessage            = "pin_message"
+	TypeUnpinMessage          = "unpin_message"
+	TypeMessagePinned         = "message_pinned"
+	TypeMessageUnpinned       = "message_unpinned"
+	TypeGetPinned             = "get_pinned"
+	TypePinnedList            = "pinned_list"
+	TypeSetChannelLock        = "set_channel_lock"
 )
 
 // Message is the JSON control envelope exchanged over websocket.
@@ -57,11 +131,172 @@ type Message struct {
 	Messages   []TextMessage `json:"messages,omitempty"`
 	Muted      *bool         `json:"muted,omitempty"`
 	Deafened   *bool         `json:"deafened,omitempty"`
+	Speaking   *bool         `json:"speaking,omitempty"`
 	Emoji      string        `json:"emoji,omitempty"`
 	UserID     string        `json:"user_id,omitempty"`
 	FileID     string        `json:"file_id,omitempty"`
 	FileName   string        `json:"file_name,omitempty"`
 	FileSize   int64         `json:"file_size,omitempty"`
+	// Consent carries recording_consent: true accepts recordings of the
+	// sender's voice channel, false declines and leaves voice.
+	Consent *bool `json:"consent,omitempty"`
+	// ConsentRequired marks a recording_started whose channel members must
+	// send recording_consent before they can unmute.
+	ConsentRequired bool `json:"consent_required,omitempty"`
+	// ProtocolVersion is sent by clients in hello and by the server in
+	// snapshot and version_mismatch. Zero means the peer predates versioning.
+	ProtocolVersion int `json:"protocol_version,omitempty"`
+	// ClientInfo is the client's build details, sent once in hello.
+	ClientInfo *ClientInfo `json:"client_info,omitempty"`
+	// Proto is the control codec a client asks for in hello, echoed in
+	// snapshot when the server agrees. Empty means ProtoJSON.
+	Proto string `json:"proto,omitempty"`
+	// OwnerID is the server owner, sent in snapshot, owner_changed and
+	// permissions.
+	OwnerID string `json:"owner_id,omitempty"`
+	// CoOwners and Roles make up the permissions reply. Roles maps user
+	// ID to role for every connected user.
+	CoOwners []string          `json:"co_owners,omitempty"`
+	Roles    map[string]string `json:"roles,omitempty"`
+	// Role carries set_role and role_changed for UserID.
+	Role string `json:"role,omitempty"`
+	// Reactions is the full reaction set for MsgID in reaction_update.
+	Reactions []ReactionInfo `json:"reactions,omitempty"`
+	// RetryAfterMs accompanies an error for a request that was rate
+	// limited and may be retried after this many milliseconds.
+	RetryAfterMs int64 `json:"retry_after_ms,omitempty"`
+	// GraceMs carries server_shutdown: how long until the server closes
+	// the remaining connections.
+	GraceMs int64 `json:"grace_ms,omitempty"`
+	// MinRoleToSpeak and MinRoleToChat carry set_channel_perms.
+	MinRoleToSpeak string `json:"min_role_to_speak,omitempty"`
+	MinRoleToChat  string `json:"min_role_to_chat,omitempty"`
+	// MinRoleToRecord carries set_channel_record_role.
+	MinRoleToRecord string `json:"min_role_to_record,omitempty"`
+	// MaxBitrateKbps carries set_channel_bitrate; 0 removes the cap.
+	MaxBitrateKbps int `json:"max_bitrate_kbps,omitempty"`
+	// SlowModeSeconds carries set_slow_mode; 0 turns slow mode off.
+	SlowModeSeconds int `json:"slow_mode_seconds,omitempty"`
+	// Locked carries set_channel_lock; false unlocks the channel.
+	Locked bool `json:"locked,omitempty"`
+	// MessageTTLSeconds carries set_channel_ttl; 0 keeps messages forever.
+	MessageTTLSeconds int `json:"message_ttl_seconds,omitempty"`
+	// CategoryID carries assign_channel_category; 0 uncategorizes the
+	// channel.
+	CategoryID int64 `json:"category_id,omitempty"`
+	// Categories accompanies Channels in channel_list.
+	Categories []Category `json:"categories,omitempty"`
+	// Seqs carries resume: the highest msg_id the client has seen, keyed
+	// by channel ID.
+	Seqs map[string]int64 `json:"seqs,omitempty"`
+	// Before and Limit carry get_messages_before: up to Limit messages
+	// with IDs below Before, or the newest when Before is 0. Before is
+	// echoed in the message_history page, which is then newest first.
+	Before int64 `json:"before,omitempty"`
+	Limit  int   `json:"limit,omitempty"`
+	// Count is how many of the newest messages purge_messages deletes.
+	Count int `json:"count,omitempty"`
+	// Status carries set_status: "online", "away" or "dnd".
+	Status string `json:"status,omitempty"`
+	// MOTD is the operator's message of the day, sent in snapshot.
+	MOTD string `json:"motd,omitempty"`
+	// AllowedEmoji is the server's reaction allow-list, sent in snapshot;
+	// empty means any emoji may be used.
+	AllowedEmoji []string `json:"allowed_emoji,omitempty"`
+	// MaxUploadBytes is the server's file upload limit, sent in snapshot.
+	MaxUploadBytes int64 `json:"max_upload_bytes,omitempty"`
+	// MaxMessageLength is the server's chat message limit in bytes, sent
+	// in snapshot.
+	MaxMessageLength int `json:"max_message_length,omitempty"`
+	// JoinSoundURL and LeaveSoundURL are the server's own join and leave
+	// sounds, sent in snapshot; empty means the client's bundled sound.
+	// A path is relative to the server's REST API.
+	JoinSoundURL  string `json:"join_sound_url,omitempty"`
+	LeaveSoundURL string `json:"leave_sound_url,omitempty"`
+	// SessionToken is sent in snapshot and authenticates REST calls made
+	// on behalf of this session (Authorization: Bearer <token>).
+	SessionToken string `json:"session_token,omitempty"`
+	// ReplyTo is the message a send_text or text_message replies to.
+	ReplyTo int64 `json:"reply_to,omitempty"`
+	// PostedBy is the username that set the announcement.
+	PostedBy string `json:"posted_by,omitempty"`
+	// Mentions lists the IDs of connected users a text_message mentions.
+	Mentions []string `json:"mentions,omitempty"`
+	// ICEServers is the STUN/TURN list for new peer connections, sent in
+	// snapshot and, for the joined voice channel, in ice_update.
+	ICEServers []ICEServer `json:"ice_servers,omitempty"`
+	// Readers lists the users who have read MsgID, in message_read.
+	Readers []string `json:"readers,omitempty"`
+	// ClipID names the soundboard clip to play.
+	ClipID string `json:"clip_id,omitempty"`
+	// Reason and DurationS carry ban_user; a zero duration bans for good.
+	// mute_user and user_muted use DurationS and Muted the same way.
+	Reason    string `json:"reason,omitempty"`
+	DurationS int64  `json:"duration_s,omitempty"`
+	// Edits carries edit_history: MsgID's earlier versions, oldest first.
+	Edits []MessageEdit `json:"edits,omitempty"`
+	// Audit carries audit_log, newest first, and the single new entry of
+	// an audit_entry. Only admins and the owner ever receive either.
+	Audit []AuditEntry `json:"audit,omitempty"`
+	// Words and FilterAction carry set_word_filter and the owner's
+	// word_filter confirmation: the words filtered from chat, and "block"
+	// or "mask". An empty list turns the filter off.
+	Words        []string `json:"words,omitempty"`
+	FilterAction string   `json:"filter_action,omitempty"`
+	// Bans carries ban_list, newest first. BanID names the ban to lift
+	// in unban.
+	Bans  []BanEntry `json:"bans,omitempty"`
+	BanID int64      `json:"ban_id,omitempty"`
+	// Pins carries pinned_list: the channel's pinned messages, most
+	// recently pinned first.
+	Pins []PinnedMessage `json:"pins,omitempty"`
+}
+
+// PinnedMessage is one pinned message in pinned_list. PinnedBy is the
+// user ID that pinned it and PinnedAt when, in Unix milliseconds.
+type PinnedMessage struct {
+	TextMessage
+	PinnedBy string `json:"pinned_by"`
+	PinnedAt int64  `json:"pinned_at"`
+}
+
+// BanEntry is one active ban in ban_list. TS and ExpiresAt are in Unix
+// milliseconds; ExpiresAt is 0 for a permanent ban.
+type BanEntry struct {
+	ID        int64  `json:"id"`
+	IP        string `json:"ip"`
+	Username  string `json:"username,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	BannedBy  string `json:"banned_by,omitempty"`
+	TS        int64  `json:"ts"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+}
+
+// AuditEntry is one moderation action from the server's audit log. TS is
+// in Unix milliseconds.
+type AuditEntry struct {
+	ActorID    string `json:"actor_id,omitempty"`
+	ActorName  string `json:"actor_name,omitempty"`
+	Action     string `json:"action"`
+	TargetID   string `json:"target_id,omitempty"`
+	TargetName string `json:"target_name,omitempty"`
+	Details    string `json:"details,omitempty"`
+	TS         int64  `json:"ts"`
+}
+
+// MessageEdit is an earlier version of an edited message and when it was
+// replaced, in Unix milliseconds.
+type MessageEdit struct {
+	Message string `json:"message"`
+	TS      int64  `json:"ts"`
+}
+
+// ClientInfo describes the build a client is running, for support and
+// compatibility tracking. It is never broadcast to other users.
+type ClientInfo struct {
+	Commit string `json:"commit,omitempty"`
+	OS     string `json:"os,omitempty"`
+	Arch   string `json:"arch,omitempty"`
 }
 
 // TextMessage is a persisted chat message returned in history queries.
@@ -75,6 +310,7 @@ type TextMessage struct {
 	FileID    string         `json:"file_id,omitempty"`
 	FileName  string         `json:"file_name,omitempty"`
 	FileSize  int64          `json:"file_size,omitempty"`
+	ReplyTo   int64          `json:"reply_to,omitempty"`
 	Reactions []ReactionInfo `json:"reactions,omitempty"`
 }
 
@@ -122,6 +358,42 @@ type Channel struct {
 	ID       int64  `json:"id"`
 	Name     string `json:"name"`
 	MaxUsers int    `json:"max_users,omitempty"`
+	// MinRoleToSpeak and MinRoleToChat are the lowest roles allowed to
+	// transmit voice and send text here; empty means everyone.
+	MinRoleToSpeak string `json:"min_role_to_speak,omitempty"`
+	MinRoleToChat  string `json:"min_role_to_chat,omitempty"`
+	// MinRoleToRecord is the lowest role allowed to record the channel's
+	// voice; new channels start at OWNER.
+	MinRoleToRecord string `json:"min_role_to_record,omitempty"`
+	// MaxBitrateKbps caps the Opus bitrate clients send in this channel;
+	// 0 means no cap.
+	MaxBitrateKbps int `json:"max_bitrate_kbps,omitempty"`
+	// SlowModeSeconds is the minimum time between one user's messages
+	// here; 0 means off. Admins and the owner are exempt.
+	SlowModeSeconds int `json:"slow_mode_seconds,omitempty"`
+	// MessageTTLSeconds is how long messages here are kept before they
+	// are deleted; 0 means forever.
+	MessageTTLSeconds int `json:"message_ttl_seconds,omitempty"`
+	// CategoryID and CategoryName place the channel under a category;
+	// 0 and "" mean uncategorized.
+	CategoryID   int64  `json:"category_id,omitempty"`
+	CategoryName string `json:"category_name,omitempty"`
+	// Locked channels take no new voice joins from users below
+	// moderator; see set_channel_lock.
+	Locked bool `json:"locked,omitempty"`
+}
+
+// Category groups channels within a server.
+type Category struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// ICEServer describes a STUN or TURN server for WebRTC peer connections.
+type ICEServer struct {
+	URLs       []string `json:"urls"`
+	Username   string   `json:"username,omitempty"`
+	Credential string   `json:"credential,omitempty"`
 }
 
 // User is the authoritative presence payload for one user.
@@ -130,6 +402,8 @@ type User struct {
 	Username         string      `json:"username"`
 	ConnectedServers []string    `json:"connected_servers,omitempty"`
 	Voice            *VoiceState `json:"voice,omitempty"`
+	// Status is "away" or "dnd"; empty means online.
+	Status string `json:"status,omitempty"`
 }
 
 // VoiceState is the global voice presence for a user.
@@ -139,4 +413,8 @@ type VoiceState struct {
 	ChannelID string `json:"channel_id"`
 	Muted     bool   `json:"muted,omitempty"`
 	Deafened  bool   `json:"deafened,omitempty"`
+	// Monitoring lists other channels on ServerID the user also listens
+	// to; see monitor_channel. Members of those channels send their voice
+	// to the user as well.
+	Monitoring []string `json:"monitoring,omitempty"`
 }
@@ -0,0 +1,163 @@
+// Package recording mixes voice channel recordings into WAV files on disk,
+// with their metadata in sqlite.
+package recording
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"bken/server/internal/store"
+)
+
+// Store coordinates recording files on disk with metadata in sqlite.
+type Store struct {
+	rootDir string
+	meta    *store.Store
+}
+
+// FinishInput is a recording to mix down and store.
+type FinishInput struct {
+	ServerID  string
+	ChannelID string
+	UserID    string
+	Tracks    []Track
+}
+
+// OpenResult is a recording's metadata and its opened WAV file.
+type OpenResult struct {
+	Info store.RecordingInfo
+	File *os.File
+}
+
+// NewStore creates a recording store rooted at rootDir.
+func NewStore(rootDir string, meta *store.Store) (*Store, error) {
+	rootDir = strings.TrimSpace(rootDir)
+	if rootDir == "" {
+		return nil, fmt.Errorf("recording directory is required")
+	}
+	if meta == nil {
+		return nil, fmt.Errorf("sqlite metadata store is required")
+	}
+	if err := os.MkdirAll(rootDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create recording directory: %w", err)
+	}
+	slog.Debug("recording store initialized", "dir", rootDir)
+	return &Store{rootDir: rootDir, meta: meta}, nil
+}
+
+// Finish mixes the input's tracks into one WAV and records it. The file is
+// written under a temporary name, flushed, synced and closed before it is
+// renamed into place, so a listed recording is always complete.
+func (s *Store) Finish(ctx context.Context, in FinishInput) (store.RecordingInfo, error) {
+	if len(in.Tracks) == 0 {
+		return store.RecordingInfo{}, fmt.Errorf("recording has no tracks")
+	}
+	id, err := newID()
+	if err != nil {
+		return store.RecordingInfo{}, fmt.Errorf("generate recording id: %w", err)
+	}
+
+	f, err := os.CreateTemp(s.rootDir, ".recording-write-*")
+	if err != nil {
+		return store.RecordingInfo{}, fmt.Errorf("create temp recording file: %w", err)
+	}
+	tempPath := f.Name()
+	samples, size, err := writeWAV(f, in.Tracks)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return store.RecordingInfo{}, fmt.Errorf("write recording: %w", err)
+	}
+
+	path := filepath.Join(s.rootDir, id+".wav")
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return store.RecordingInfo{}, fmt.Errorf("move recording into place: %w", err)
+	}
+
+	info := store.RecordingInfo{
+		ID:        id,
+		ServerID:  in.ServerID,
+		ChannelID: in.ChannelID,
+		UserID:    in.UserID,
+		Duration:  time.Duration(samples) * time.Second / SampleRate,
+		Path:      path,
+		SizeBytes: size,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.meta.CreateRecording(ctx, info); err != nil {
+		_ = os.Remove(path)
+		return store.RecordingInfo{}, fmt.Errorf("persist recording metadata: %w", err)
+	}
+
+	slog.Info("recording stored", "recording_id", id, "server_id", in.ServerID, "channel_id", in.ChannelID,
+		"tracks", len(in.Tracks), "duration", info.Duration, "size", size)
+	return info, nil
+}
+
+// writeWAV mixes tracks into f and returns the sample count and file size.
+// The header's sizes are only known once mixing ends, so it is written
+// twice.
+func writeWAV(f *os.File, tracks []Track) (samples, size int64, err error) {
+	w := bufio.NewWriter(f)
+	if err := writeHeader(w, 0); err != nil {
+		return 0, 0, err
+	}
+	if samples, err = Mix(w, tracks); err != nil {
+		return 0, 0, err
+	}
+	if err := w.Flush(); err != nil {
+		return 0, 0, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+	if err := writeHeader(f, samples); err != nil {
+		return 0, 0, err
+	}
+	if err := f.Sync(); err != nil {
+		return 0, 0, err
+	}
+	return samples, headerBytes + samples*bitsPerSample/8, nil
+}
+
+// Recordings lists a server's recordings, newest first.
+func (s *Store) Recordings(ctx context.Context, serverID string) ([]store.RecordingInfo, error) {
+	return s.meta.Recordings(ctx, serverID)
+}
+
+// Open looks up a recording and opens its WAV file.
+func (s *Store) Open(ctx context.Context, id string) (OpenResult, error) {
+	info, err := s.meta.RecordingByID(ctx, id)
+	if err != nil {
+		return OpenResult{}, err
+	}
+	f, err := os.Open(info.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			slog.Warn("recording file missing", "recording_id", id, "path", info.Path)
+		}
+		return OpenResult{}, fmt.Errorf("open recording file: %w", err)
+	}
+	return OpenResult{Info: info, File: f}, nil
+}
+
+// newID returns a random hex ID; it names the file on disk too.
+func newID() (string, error) {
+	var raw [16]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", raw), nil
+}
@@ -0,0 +1,103 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bken/server/internal/store"
+)
+
+func TestFinishWritesPlayableWAV(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	meta, err := store.Open(filepath.Join(dir, "bken.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = meta.Close() })
+	recs, err := NewStore(filepath.Join(dir, "recordings"), meta)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	ctx := context.Background()
+	info, err := recs.Finish(ctx, FinishInput{
+		ServerID:  "srv-1",
+		ChannelID: "1",
+		UserID:    "u1",
+		Tracks: []Track{
+			{UserID: "u1", WAV: wav(t, make([]int16, SampleRate/2)...)},
+			{UserID: "u2", Offset: time.Second, WAV: wav(t, make([]int16, SampleRate/2)...)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	if info.Duration != 1500*time.Millisecond {
+		t.Fatalf("expected 1.5s, got %v", info.Duration)
+	}
+	if filepath.Dir(info.Path) != filepath.Join(dir, "recordings") {
+		t.Fatalf("recording written outside the recordings dir: %s", info.Path)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(info.Path))
+	if len(entries) != 1 {
+		t.Fatalf("expected only the finished file, got %d entries", len(entries))
+	}
+
+	opened, err := recs.Open(ctx, info.ID)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer opened.File.Close()
+	data, err := io.ReadAll(opened.File)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if int64(len(data)) != info.SizeBytes {
+		t.Fatalf("file is %d bytes, metadata says %d", len(data), info.SizeBytes)
+	}
+	dataBytes, err := readHeader(bytes.NewReader(data))
+	if err != nil || dataBytes != int64(len(data)-headerBytes) {
+		t.Fatalf("bad header: %d data bytes, %v", dataBytes, err)
+	}
+
+	list, err := recs.Recordings(ctx, "srv-1")
+	if err != nil || len(list) != 1 || list[0].ID != info.ID {
+		t.Fatalf("unexpected listing: %+v, %v", list, err)
+	}
+	if list, _ := recs.Recordings(ctx, "srv-2"); len(list) != 0 {
+		t.Fatalf("listing leaked across servers: %+v", list)
+	}
+}
+
+func TestFinishLeavesNothingOnBadTrack(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	meta, err := store.Open(filepath.Join(dir, "bken.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = meta.Close() })
+	recs, err := NewStore(filepath.Join(dir, "recordings"), meta)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	if _, err := recs.Finish(context.Background(), FinishInput{
+		ServerID: "srv-1",
+		Tracks:   []Track{{WAV: bytes.NewReader([]byte("nope"))}},
+	}); err == nil {
+		t.Fatal("expected an error")
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "recordings"))
+	if len(entries) != 0 {
+		t.Fatalf("expected the temp file removed, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,170 @@
+package recording
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Recordings are 16-bit mono PCM WAV at 48 kHz, the rate clients decode
+// Opus at.
+const (
+	SampleRate    = 48000
+	bitsPerSample = 16
+	headerBytes   = 44
+	// mixSamples is how much audio is mixed per pass (100 ms), which bounds
+	// memory regardless of the recording's length.
+	mixSamples = SampleRate / 10
+	// maxSamples is the most a WAV's 32-bit sizes can describe (about 12 h).
+	maxSamples = (math.MaxUint32 - 36) / (bitsPerSample / 8)
+)
+
+// ErrBadTrack is returned by Mix for a track that is not a WAV in the
+// recording format or ends early.
+var ErrBadTrack = errors.New("invalid track")
+
+// Track is one speaker's decoded audio, as a WAV in the recording format.
+// Offset is when the speaker's first frame arrived after the recording
+// started; it keeps concurrent speakers time-aligned in the mix.
+type Track struct {
+	UserID string
+	Offset time.Duration
+	WAV    io.Reader
+}
+
+// track is a Track whose header has been read.
+type track struct {
+	r          *bufio.Reader
+	start, end int64 // sample span in the mix
+}
+
+// Mix sums tracks into a single WAV body written to w, without the header,
+// and returns the number of samples written. Tracks are read sequentially,
+// so they may be streams; samples past 16 bits are clipped.
+func Mix(w io.Writer, tracks []Track) (int64, error) {
+	ts := make([]track, 0, len(tracks))
+	var total int64
+	for i, t := range tracks {
+		if t.Offset < 0 {
+			return 0, fmt.Errorf("%w %d: offset must not be negative", ErrBadTrack, i)
+		}
+		r := bufio.NewReader(t.WAV)
+		dataBytes, err := readHeader(r)
+		if err != nil {
+			return 0, fmt.Errorf("%w %d: %v", ErrBadTrack, i, err)
+		}
+		start := int64(t.Offset) * SampleRate / int64(time.Second)
+		tr := track{r: r, start: start, end: start + dataBytes/2}
+		total = max(total, tr.end)
+		ts = append(ts, tr)
+	}
+	if total > maxSamples {
+		return 0, fmt.Errorf("%w: recording is too long for a wav file", ErrBadTrack)
+	}
+
+	sum := make([]int32, mixSamples)
+	raw := make([]byte, 2*mixSamples)
+	for pos := int64(0); pos < total; pos += mixSamples {
+		n := min(mixSamples, total-pos)
+		clear(sum[:n])
+		for i := range ts {
+			lo, hi := max(pos, ts[i].start), min(pos+n, ts[i].end)
+			if lo >= hi {
+				continue
+			}
+			b := raw[:2*(hi-lo)]
+			if _, err := io.ReadFull(ts[i].r, b); err != nil {
+				return 0, fmt.Errorf("%w %d: read samples: %v", ErrBadTrack, i, err)
+			}
+			for j := range hi - lo {
+				sum[lo-pos+j] += int32(int16(binary.LittleEndian.Uint16(b[2*j:])))
+			}
+		}
+		for j, s := range sum[:n] {
+			s = max(math.MinInt16, min(math.MaxInt16, s))
+			binary.LittleEndian.PutUint16(raw[2*j:], uint16(int16(s)))
+		}
+		if _, err := w.Write(raw[:2*n]); err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+// readHeader reads a WAV header up to the start of the samples, checks that
+// it is in the recording format, and returns the size of the sample data.
+func readHeader(r io.Reader) (int64, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return 0, fmt.Errorf("read wav header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return 0, fmt.Errorf("not a wav file")
+	}
+	var sawFormat bool
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return 0, fmt.Errorf("read wav chunk: %w", err)
+		}
+		size := int64(binary.LittleEndian.Uint32(chunk[4:]))
+		switch string(chunk[:4]) {
+		case "fmt ":
+			if size < 16 {
+				return 0, fmt.Errorf("short wav format chunk")
+			}
+			var f [16]byte
+			if _, err := io.ReadFull(r, f[:]); err != nil {
+				return 0, fmt.Errorf("read wav format: %w", err)
+			}
+			format := binary.LittleEndian.Uint16(f[0:])
+			channels := binary.LittleEndian.Uint16(f[2:])
+			rate := binary.LittleEndian.Uint32(f[4:])
+			bits := binary.LittleEndian.Uint16(f[14:])
+			if format != 1 || channels != 1 || rate != SampleRate || bits != bitsPerSample {
+				return 0, fmt.Errorf("wav must be %d Hz mono %d-bit PCM", SampleRate, bitsPerSample)
+			}
+			if _, err := io.CopyN(io.Discard, r, size-16+size%2); err != nil {
+				return 0, fmt.Errorf("read wav format: %w", err)
+			}
+			sawFormat = true
+		case "data":
+			if !sawFormat {
+				return 0, fmt.Errorf("wav data before format")
+			}
+			if size%2 != 0 {
+				return 0, fmt.Errorf("wav data is not whole samples")
+			}
+			return size, nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return 0, fmt.Errorf("skip wav chunk: %w", err)
+			}
+		}
+	}
+}
+
+// writeHeader writes the WAV header for samples of mixed audio.
+func writeHeader(w io.Writer, samples int64) error {
+	const blockAlign = bitsPerSample / 8
+	dataBytes := samples * blockAlign
+	h := make([]byte, 0, headerBytes)
+	h = append(h, "RIFF"...)
+	h = binary.LittleEndian.AppendUint32(h, uint32(36+dataBytes))
+	h = append(h, "WAVEfmt "...)
+	h = binary.LittleEndian.AppendUint32(h, 16)
+	h = binary.LittleEndian.AppendUint16(h, 1) // PCM
+	h = binary.LittleEndian.AppendUint16(h, 1) // mono
+	h = binary.LittleEndian.AppendUint32(h, SampleRate)
+	h = binary.LittleEndian.AppendUint32(h, SampleRate*blockAlign)
+	h = binary.LittleEndian.AppendUint16(h, blockAlign)
+	h = binary.LittleEndian.AppendUint16(h, bitsPerSample)
+	h = append(h, "data"...)
+	h = binary.LittleEndian.AppendUint32(h, uint32(dataBytes))
+	_, err := w.Write(h)
+	return err
+}
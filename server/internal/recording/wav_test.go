@@ -0,0 +1,89 @@
+package recording
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// wav encodes samples in the recording format.
+func wav(t *testing.T, samples ...int16) *bytes.Buffer {
+	t.Helper()
+	var b bytes.Buffer
+	if err := writeHeader(&b, int64(len(samples))); err != nil {
+		t.Fatalf("write header: %v", err)
+	}
+	if err := binary.Write(&b, binary.LittleEndian, samples); err != nil {
+		t.Fatalf("write samples: %v", err)
+	}
+	return &b
+}
+
+func decode(t *testing.T, b []byte) []int16 {
+	t.Helper()
+	out := make([]int16, len(b)/2)
+	if err := binary.Read(bytes.NewReader(b), binary.LittleEndian, out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return out
+}
+
+func TestMixAlignsTracksByOffset(t *testing.T) {
+	// One sample is 1/48 ms, so a 1 ms offset is 48 samples.
+	a := make([]int16, 100)
+	b := make([]int16, 100)
+	for i := range a {
+		a[i], b[i] = 100, 10
+	}
+	var out bytes.Buffer
+	n, err := Mix(&out, []Track{
+		{UserID: "u1", WAV: wav(t, a...)},
+		{UserID: "u2", Offset: time.Millisecond, WAV: wav(t, b...)},
+	})
+	if err != nil {
+		t.Fatalf("mix: %v", err)
+	}
+	if n != 148 {
+		t.Fatalf("expected 148 samples, got %d", n)
+	}
+	got := decode(t, out.Bytes())
+	if got[0] != 100 || got[47] != 100 || got[48] != 110 || got[99] != 110 || got[100] != 10 || got[147] != 10 {
+		t.Fatalf("tracks misaligned: %v", got)
+	}
+}
+
+func TestMixClipsAndSpansChunks(t *testing.T) {
+	loud := make([]int16, mixSamples+10)
+	for i := range loud {
+		loud[i] = math.MaxInt16
+	}
+	var out bytes.Buffer
+	if _, err := Mix(&out, []Track{{WAV: wav(t, loud...)}, {WAV: wav(t, loud...)}}); err != nil {
+		t.Fatalf("mix: %v", err)
+	}
+	got := decode(t, out.Bytes())
+	if len(got) != len(loud) || got[0] != math.MaxInt16 || got[len(got)-1] != math.MaxInt16 {
+		t.Fatalf("unexpected mix of %d samples, ends %d/%d", len(got), got[0], got[len(got)-1])
+	}
+}
+
+func TestMixRejectsBadTracks(t *testing.T) {
+	short := wav(t, 1, 2, 3)
+	short.Truncate(short.Len() - 2)
+
+	stereo := wav(t, 1, 2)
+	binary.LittleEndian.PutUint16(stereo.Bytes()[22:], 2)
+
+	for name, r := range map[string]*bytes.Buffer{
+		"not wav":   bytes.NewBufferString("hello, this is not a wav file"),
+		"truncated": short,
+		"stereo":    stereo,
+	} {
+		if _, err := Mix(&bytes.Buffer{}, []Track{{WAV: r}}); !errors.Is(err, ErrBadTrack) {
+			t.Errorf("%s: expected ErrBadTrack, got %v", name, err)
+		}
+	}
+}
@@ -2,10 +2,13 @@ package store
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
@@ -17,6 +20,9 @@ import (
 // ErrBlobNotFound is returned when no blob metadata exists for an ID.
 var ErrBlobNotFound = errors.New("blob metadata not found")
 
+// ErrRecordingNotFound is returned when no recording exists for an ID.
+var ErrRecordingNotFound = errors.New("recording not found")
+
 // BlobMetadata stores metadata about a binary blob on disk.
 type BlobMetadata struct {
 	ID           string
@@ -31,6 +37,10 @@ type BlobMetadata struct {
 // Store persists server state in SQLite.
 type Store struct {
 	db *sql.DB
+	// bootID is stamped on every message this process inserts. User IDs
+	// restart at u1 on each boot, so a UserID alone does not identify the
+	// author of a message written by an earlier process.
+	bootID string
 }
 
 // Open opens (or creates) a SQLite database and runs migrations.
@@ -49,7 +59,7 @@ func Open(path string) (*Store, error) {
 		return nil, fmt.Errorf("open sqlite database: %w", err)
 	}
 
-	st := &Store{db: db}
+	st := &Store{db: db, bootID: rand.Text()}
 	if err := st.migrate(context.Background()); err != nil {
 		_ = db.Close()
 		return nil, err
@@ -103,6 +113,60 @@ CREATE TABLE IF NOT EXISTS reactions (
 	UNIQUE(msg_id, user_id, emoji)
 );
 CREATE INDEX IF NOT EXISTS idx_reactions_msg ON reactions(msg_id);
+
+CREATE TABLE IF NOT EXISTS bans (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	ip TEXT NOT NULL,
+	username TEXT NOT NULL,
+	reason TEXT NOT NULL,
+	banned_by TEXT NOT NULL,
+	created_at_unix_ms INTEGER NOT NULL,
+	expires_at_unix_ms INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_bans_ip ON bans(ip);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	actor_id TEXT NOT NULL,
+	actor_name TEXT NOT NULL,
+	action TEXT NOT NULL,
+	target_id TEXT NOT NULL,
+	target_name TEXT NOT NULL,
+	details TEXT NOT NULL,
+	created_at_unix_ms INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at_unix_ms);
+
+CREATE TABLE IF NOT EXISTS recordings (
+	id TEXT PRIMARY KEY,
+	server_id TEXT NOT NULL,
+	channel_id TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	duration_ms INTEGER NOT NULL CHECK(duration_ms >= 0),
+	path TEXT NOT NULL,
+	size_bytes INTEGER NOT NULL CHECK(size_bytes >= 0),
+	created_at_unix_ms INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_recordings_server ON recordings(server_id, created_at_unix_ms);
+
+CREATE TABLE IF NOT EXISTS message_edits (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	msg_id INTEGER NOT NULL,
+	message TEXT NOT NULL,
+	edited_at_unix_ms INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_message_edits_msg ON message_edits(msg_id, id);
+
+CREATE TABLE IF NOT EXISTS pins (
+	msg_id INTEGER PRIMARY KEY,
+	pinned_by TEXT NOT NULL,
+	pinned_at_unix_ms INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS settings (
+	key TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
 `
 
 	if _, err := s.db.ExecContext(ctx, schema); err != nil {
@@ -114,6 +178,9 @@ CREATE INDEX IF NOT EXISTS idx_reactions_msg ON reactions(msg_id);
 		`ALTER TABLE messages ADD COLUMN file_id TEXT NOT NULL DEFAULT ''`,
 		`ALTER TABLE messages ADD COLUMN file_name TEXT NOT NULL DEFAULT ''`,
 		`ALTER TABLE messages ADD COLUMN file_size INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE messages ADD COLUMN reply_to INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE messages ADD COLUMN deleted INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE messages ADD COLUMN boot_id TEXT NOT NULL DEFAULT ''`,
 	} {
 		_, _ = s.db.ExecContext(ctx, stmt)
 	}
@@ -181,12 +248,37 @@ type MessageRow struct {
 	FileID    string
 	FileName  string
 	FileSize  int64
+	// ReplyTo is the ID of the message this one replies to, or 0.
+	ReplyTo int64
+	// BootID identifies the server process that stored the message.
+	BootID string
+}
+
+// BootID returns the identifier stamped on messages inserted by this process.
+func (s *Store) BootID() string {
+	return s.bootID
 }
 
-// InsertMessage persists a chat message and returns the assigned ID.
-func (s *Store) InsertMessage(ctx context.Context, serverID, channelID, userID, username, message string, ts int64, fileID, fileName string, fileSize int64) (int64, error) {
-	const q = `INSERT INTO messages (server_id, channel_id, user_id, username, message, ts, file_id, file_name, file_size) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	result, err := s.db.ExecContext(ctx, q, serverID, channelID, userID, username, message, ts, fileID, fileName, fileSize)
+// messageColumns is the column list scanned by scanMessage.
+const messageColumns = `id, server_id, channel_id, user_id, username, message, ts, file_id, file_name, file_size, reply_to, boot_id`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMessage(row rowScanner) (MessageRow, error) {
+	var m MessageRow
+	err := row.Scan(&m.ID, &m.ServerID, &m.ChannelID, &m.UserID, &m.Username, &m.Message, &m.TS, &m.FileID, &m.FileName, &m.FileSize, &m.ReplyTo, &m.BootID)
+	return m, err
+}
+
+// InsertMessage persists a chat message and returns the assigned ID. replyTo
+// is the ID of the message being replied to, or 0. IDs come from the
+// messages table's AUTOINCREMENT key, so they keep rising across restarts
+// and are never reused, which clients rely on when they cache messages.
+func (s *Store) InsertMessage(ctx context.Context, serverID, channelID, userID, username, message string, ts int64, fileID, fileName string, fileSize, replyTo int64) (int64, error) {
+	const q = `INSERT INTO messages (server_id, channel_id, user_id, username, message, ts, file_id, file_name, file_size, reply_to, boot_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := s.db.ExecContext(ctx, q, serverID, channelID, userID, username, message, ts, fileID, fileName, fileSize, replyTo, s.bootID)
 	if err != nil {
 		return 0, fmt.Errorf("insert message: %w", err)
 	}
@@ -201,9 +293,9 @@ func (s *Store) GetMessages(ctx context.Context, serverID, channelID string, lim
 		limit = 50
 	}
 	const q = `
-SELECT id, server_id, channel_id, user_id, username, message, ts, file_id, file_name, file_size
+SELECT ` + messageColumns + `
 FROM messages
-WHERE server_id = ? AND channel_id = ?
+WHERE server_id = ? AND channel_id = ? AND deleted = 0
 ORDER BY ts DESC, id DESC
 LIMIT ?
 `
@@ -215,8 +307,8 @@ LIMIT ?
 
 	var msgs []MessageRow
 	for rows.Next() {
-		var m MessageRow
-		if err := rows.Scan(&m.ID, &m.ServerID, &m.ChannelID, &m.UserID, &m.Username, &m.Message, &m.TS, &m.FileID, &m.FileName, &m.FileSize); err != nil {
+		m, err := scanMessage(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan message: %w", err)
 		}
 		msgs = append(msgs, m)
@@ -229,6 +321,536 @@ LIMIT ?
 	return msgs, rows.Err()
 }
 
+// GetMessagesBefore returns up to limit messages in a channel with an ID
+// below before, newest first; a non-positive before starts from the newest.
+// The ID of the last message returned is the cursor for the next page.
+func (s *Store) GetMessagesBefore(ctx context.Context, serverID, channelID string, before int64, limit int) ([]MessageRow, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if before <= 0 {
+		before = math.MaxInt64
+	}
+	const q = `
+SELECT ` + messageColumns + `
+FROM messages
+WHERE server_id = ? AND channel_id = ? AND id < ? AND deleted = 0
+ORDER BY id DESC
+LIMIT ?
+`
+	rows, err := s.db.QueryContext(ctx, q, serverID, channelID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query messages before: %w", err)
+	}
+	defer rows.Close()
+
+	var msgs []MessageRow
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan message: %w", err)
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, rows.Err()
+}
+
+// GetMessagesSince returns up to limit messages in a channel with an ID
+// greater than afterID, oldest first. A reconnecting client uses it to
+// fetch only what it missed.
+func (s *Store) GetMessagesSince(ctx context.Context, serverID, channelID string, afterID int64, limit int) ([]MessageRow, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	const q = `
+SELECT ` + messageColumns + `
+FROM messages
+WHERE server_id = ? AND channel_id = ? AND id > ? AND deleted = 0
+ORDER BY id ASC
+LIMIT ?
+`
+	rows, err := s.db.QueryContext(ctx, q, serverID, channelID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query messages since: %w", err)
+	}
+	defer rows.Close()
+
+	var msgs []MessageRow
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan message: %w", err)
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, rows.Err()
+}
+
+// SearchMessages returns messages in a channel whose text contains query
+// (case-insensitive for ASCII), newest first. When before is positive only
+// messages with a smaller ID are returned, so the ID of the last result is
+// the cursor for the next page.
+func (s *Store) SearchMessages(ctx context.Context, serverID, channelID, query string, before int64, limit int) ([]MessageRow, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if before <= 0 {
+		before = math.MaxInt64
+	}
+	const q = `
+SELECT ` + messageColumns + `
+FROM messages
+WHERE server_id = ? AND channel_id = ? AND deleted = 0 AND id < ? AND message LIKE ? ESCAPE '\'
+ORDER BY id DESC
+LIMIT ?
+`
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+	rows, err := s.db.QueryContext(ctx, q, serverID, channelID, before, pattern, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search messages: %w", err)
+	}
+	defer rows.Close()
+
+	var msgs []MessageRow
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan message: %w", err)
+		}
+		msgs = append(msgs, m)
+	}
+	slog.Debug("messages searched", "server_id", serverID, "channel_id", channelID, "before", before, "count", len(msgs))
+	return msgs, rows.Err()
+}
+
+// likeEscaper escapes LIKE wildcards so a search query matches literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// GetMessage returns one message by ID within a server. ok is false when no
+// such message exists there or it was deleted.
+func (s *Store) GetMessage(ctx context.Context, serverID string, msgID int64) (MessageRow, bool, error) {
+	const q = `SELECT ` + messageColumns + ` FROM messages WHERE id = ? AND server_id = ? AND deleted = 0`
+	m, err := scanMessage(s.db.QueryRowContext(ctx, q, msgID, serverID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return MessageRow{}, false, nil
+	}
+	if err != nil {
+		return MessageRow{}, false, fmt.Errorf("query message: %w", err)
+	}
+	return m, true, nil
+}
+
+// GetReplyThread returns the chain of messages that msgID replies to,
+// ordered from the thread root down to msgID itself. Only the maxDepth
+// messages nearest msgID are returned; the walk also stops at a missing
+// parent or at a message already visited, so corrupt reply links cannot
+// loop forever.
+func (s *Store) GetReplyThread(ctx context.Context, serverID string, msgID int64, maxDepth int) ([]MessageRow, error) {
+	var chain []MessageRow
+	seen := make(map[int64]struct{})
+	for id := msgID; id > 0 && len(chain) < maxDepth; {
+		if _, dup := seen[id]; dup {
+			slog.Warn("reply thread cycle", "msg_id", msgID, "at", id)
+			break
+		}
+		seen[id] = struct{}{}
+		m, ok, err := s.GetMessage(ctx, serverID, id)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		chain = append(chain, m)
+		id = m.ReplyTo
+	}
+	// Reverse to root-first order.
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	slog.Debug("reply thread loaded", "server_id", serverID, "msg_id", msgID, "count", len(chain))
+	return chain, nil
+}
+
+// PurgeMessages marks the newest n messages in a channel that are not
+// already deleted as deleted and returns their IDs, newest first. Deleted
+// messages are left out of history, search and threads.
+func (s *Store) PurgeMessages(ctx context.Context, serverID, channelID string, n int) ([]int64, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin purge: %w", err)
+	}
+	defer tx.Rollback()
+
+	const sel = `
+SELECT id FROM messages
+WHERE server_id = ? AND channel_id = ? AND deleted = 0
+ORDER BY ts DESC, id DESC
+LIMIT ?
+`
+	rows, err := tx.QueryContext(ctx, sel, serverID, channelID, n)
+	if err != nil {
+		return nil, fmt.Errorf("query messages to purge: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan message id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query messages to purge: %w", err)
+	}
+
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, `UPDATE messages SET deleted = 1 WHERE id = ?`, id); err != nil {
+			return nil, fmt.Errorf("mark message deleted: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit purge: %w", err)
+	}
+	slog.Info("messages purged", "server_id", serverID, "channel_id", channelID, "count", len(ids))
+	return ids, nil
+}
+
+// ExpireMessages marks every message in a channel sent before the Unix
+// millisecond time before as deleted and returns their IDs, oldest first.
+// Messages already deleted are skipped, so repeated sweeps report each
+// message once.
+func (s *Store) ExpireMessages(ctx context.Context, serverID, channelID string, before int64) ([]int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin expire: %w", err)
+	}
+	defer tx.Rollback()
+
+	const sel = `
+SELECT id FROM messages
+WHERE server_id = ? AND channel_id = ? AND deleted = 0 AND ts < ?
+ORDER BY ts ASC, id ASC
+`
+	rows, err := tx.QueryContext(ctx, sel, serverID, channelID, before)
+	if err != nil {
+		return nil, fmt.Errorf("query expired messages: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan message id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query expired messages: %w", err)
+	}
+
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, `UPDATE messages SET deleted = 1 WHERE id = ?`, id); err != nil {
+			return nil, fmt.Errorf("mark message deleted: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit expire: %w", err)
+	}
+	if len(ids) > 0 {
+		slog.Info("messages expired", "server_id", serverID, "channel_id", channelID, "count", len(ids))
+	}
+	return ids, nil
+}
+
+// MaxEditHistory is how many earlier versions EditMessage keeps per
+// message; older ones are dropped.
+const MaxEditHistory = 20
+
+// MessageEdit is an earlier version of an edited message: its text and
+// when it was replaced, in Unix milliseconds.
+type MessageEdit struct {
+	Message  string
+	EditedAt int64
+}
+
+// EditMessage replaces the text of a message that is not deleted and keeps
+// the text it had before in its edit history, which is capped at
+// MaxEditHistory entries. ok is false when no such message exists in the
+// server.
+func (s *Store) EditMessage(ctx context.Context, serverID string, msgID int64, message string, editedAt int64) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("begin edit: %w", err)
+	}
+	defer tx.Rollback()
+
+	var prior string
+	err = tx.QueryRowContext(ctx, `SELECT message FROM messages WHERE id = ? AND server_id = ? AND deleted = 0`, msgID, serverID).Scan(&prior)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("query message to edit: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO message_edits (msg_id, message, edited_at_unix_ms) VALUES (?, ?, ?)`, msgID, prior, editedAt); err != nil {
+		return false, fmt.Errorf("insert message edit: %w", err)
+	}
+	const prune = `
+DELETE FROM message_edits
+WHERE msg_id = ? AND id NOT IN (
+	SELECT id FROM message_edits WHERE msg_id = ? ORDER BY id DESC LIMIT ?
+)
+`
+	if _, err := tx.ExecContext(ctx, prune, msgID, msgID, MaxEditHistory); err != nil {
+		return false, fmt.Errorf("prune message edits: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE messages SET message = ? WHERE id = ?`, message, msgID); err != nil {
+		return false, fmt.Errorf("update message: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("commit edit: %w", err)
+	}
+	slog.Debug("message edited", "msg_id", msgID, "server_id", serverID)
+	return true, nil
+}
+
+// GetEditHistory returns the earlier versions of a message, oldest first.
+func (s *Store) GetEditHistory(ctx context.Context, msgID int64) ([]MessageEdit, error) {
+	const q = `SELECT message, edited_at_unix_ms FROM message_edits WHERE msg_id = ? ORDER BY id ASC`
+	rows, err := s.db.QueryContext(ctx, q, msgID)
+	if err != nil {
+		return nil, fmt.Errorf("query edit history: %w", err)
+	}
+	defer rows.Close()
+	var out []MessageEdit
+	for rows.Next() {
+		var e MessageEdit
+		if err := rows.Scan(&e.Message, &e.EditedAt); err != nil {
+			return nil, fmt.Errorf("scan message edit: %w", err)
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// TranscriptMessage is one message in a channel transcript. Deleted
+// messages are included, without their text.
+type TranscriptMessage struct {
+	MessageRow
+	Edited  bool
+	Deleted bool
+	// Parent is the message this one replies to, or nil when it is not a
+	// reply or its parent is not in the same channel.
+	Parent *TranscriptParent
+}
+
+// TranscriptParent is the message a transcript message replies to, as much
+// of it as a quote needs. A deleted parent has no text.
+type TranscriptParent struct {
+	Username string
+	Message  string
+	FileName string
+	Deleted  bool
+}
+
+// transcriptPageSize is how many messages ChannelTranscript reads per query.
+const transcriptPageSize = 500
+
+// ChannelTranscript calls fn for every message stored in a channel, oldest
+// first, stopping at the first error fn returns. Messages are read a page
+// at a time by ID, and each page's rows are closed before fn sees them, so
+// a slow reader never holds a query open and a long channel is never held
+// in memory at once.
+func (s *Store) ChannelTranscript(ctx context.Context, serverID, channelID string, fn func(TranscriptMessage) error) error {
+	return s.channelTranscript(ctx, serverID, channelID, transcriptPageSize, fn)
+}
+
+func (s *Store) channelTranscript(ctx context.Context, serverID, channelID string, pageSize int, fn func(TranscriptMessage) error) error {
+	var after int64
+	for {
+		page, err := s.transcriptPage(ctx, serverID, channelID, after, pageSize)
+		if err != nil {
+			return err
+		}
+		for _, m := range page {
+			if err := fn(m); err != nil {
+				return err
+			}
+		}
+		if len(page) < pageSize {
+			return nil
+		}
+		after = page[len(page)-1].ID
+	}
+}
+
+// transcriptPage returns up to limit messages of a channel with IDs above
+// after, oldest first, each with the parent it replies to.
+func (s *Store) transcriptPage(ctx context.Context, serverID, channelID string, after int64, limit int) ([]TranscriptMessage, error) {
+	const q = `
+SELECT m.id, m.server_id, m.channel_id, m.user_id, m.username, m.message, m.ts, m.file_id, m.file_name, m.file_size, m.reply_to, m.boot_id, m.deleted,
+	EXISTS (SELECT 1 FROM message_edits WHERE message_edits.msg_id = m.id),
+	p.username, p.message, p.file_name, p.deleted
+FROM messages m
+LEFT JOIN messages p ON p.id = m.reply_to AND p.server_id = m.server_id AND p.channel_id = m.channel_id
+WHERE m.server_id = ? AND m.channel_id = ? AND m.id > ?
+ORDER BY m.id ASC
+LIMIT ?
+`
+	rows, err := s.db.QueryContext(ctx, q, serverID, channelID, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query transcript: %w", err)
+	}
+	defer rows.Close()
+
+	var page []TranscriptMessage
+	for rows.Next() {
+		var (
+			m                                      TranscriptMessage
+			parentName, parentText, parentFileName sql.NullString
+			parentDeleted                          sql.NullBool
+		)
+		err := rows.Scan(&m.ID, &m.ServerID, &m.ChannelID, &m.UserID, &m.Username, &m.Message, &m.TS, &m.FileID, &m.FileName, &m.FileSize, &m.ReplyTo, &m.BootID, &m.Deleted, &m.Edited,
+			&parentName, &parentText, &parentFileName, &parentDeleted)
+		if err != nil {
+			return nil, fmt.Errorf("scan transcript message: %w", err)
+		}
+		if m.Deleted {
+			m.Message = ""
+			m.FileID, m.FileName, m.FileSize = "", "", 0
+		}
+		if parentName.Valid {
+			m.Parent = &TranscriptParent{Username: parentName.String, Deleted: parentDeleted.Bool}
+			if !m.Parent.Deleted {
+				m.Parent.Message, m.Parent.FileName = parentText.String, parentFileName.String
+			}
+		}
+		page = append(page, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read transcript: %w", err)
+	}
+	return page, nil
+}
+
+// MaxPinnedPerChannel is how many messages may be pinned in one channel.
+const MaxPinnedPerChannel = 50
+
+// ErrTooManyPins is returned by PinMessage when the message's channel
+// already has MaxPinnedPerChannel pins.
+var ErrTooManyPins = fmt.Errorf("a channel can have at most %d pinned messages", MaxPinnedPerChannel)
+
+// PinnedMessage is a pinned message, who pinned it and when, in Unix
+// milliseconds.
+type PinnedMessage struct {
+	MessageRow
+	PinnedBy string
+	PinnedAt int64
+}
+
+// PinMessage pins a message that is not deleted, recording pinnedBy as the
+// user who pinned it. Pinning a message that is already pinned changes
+// nothing. ok is false when no such message exists in the server; the
+// message is returned otherwise so callers know its channel.
+func (s *Store) PinMessage(ctx context.Context, serverID string, msgID int64, pinnedBy string, pinnedAt int64) (MessageRow, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return MessageRow{}, false, fmt.Errorf("begin pin: %w", err)
+	}
+	defer tx.Rollback()
+
+	const sel = `SELECT ` + messageColumns + ` FROM messages WHERE id = ? AND server_id = ? AND deleted = 0`
+	m, err := scanMessage(tx.QueryRowContext(ctx, sel, msgID, serverID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return MessageRow{}, false, nil
+	}
+	if err != nil {
+		return MessageRow{}, false, fmt.Errorf("query message to pin: %w", err)
+	}
+
+	var pinned bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM pins WHERE msg_id = ?)`, msgID).Scan(&pinned); err != nil {
+		return MessageRow{}, false, fmt.Errorf("query pin: %w", err)
+	}
+	if pinned {
+		return m, true, nil
+	}
+
+	const count = `
+SELECT COUNT(*) FROM pins JOIN messages ON messages.id = pins.msg_id
+WHERE server_id = ? AND channel_id = ? AND deleted = 0
+`
+	var n int
+	if err := tx.QueryRowContext(ctx, count, serverID, m.ChannelID).Scan(&n); err != nil {
+		return MessageRow{}, false, fmt.Errorf("count pins: %w", err)
+	}
+	if n >= MaxPinnedPerChannel {
+		return MessageRow{}, false, ErrTooManyPins
+	}
+
+	const ins = `INSERT INTO pins (msg_id, pinned_by, pinned_at_unix_ms) VALUES (?, ?, ?)`
+	if _, err := tx.ExecContext(ctx, ins, msgID, pinnedBy, pinnedAt); err != nil {
+		return MessageRow{}, false, fmt.Errorf("insert pin: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return MessageRow{}, false, fmt.Errorf("commit pin: %w", err)
+	}
+	slog.Debug("message pinned", "server_id", serverID, "channel_id", m.ChannelID, "msg_id", msgID, "pinned_by", pinnedBy)
+	return m, true, nil
+}
+
+// UnpinMessage unpins a message in the server. ok is false when the
+// message is not pinned there.
+func (s *Store) UnpinMessage(ctx context.Context, serverID string, msgID int64) (MessageRow, bool, error) {
+	m, ok, err := s.GetMessage(ctx, serverID, msgID)
+	if err != nil || !ok {
+		return MessageRow{}, false, err
+	}
+	res, err := s.db.ExecContext(ctx, `DELETE FROM pins WHERE msg_id = ?`, msgID)
+	if err != nil {
+		return MessageRow{}, false, fmt.Errorf("delete pin: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return MessageRow{}, false, nil
+	}
+	slog.Debug("message unpinned", "server_id", serverID, "channel_id", m.ChannelID, "msg_id", msgID)
+	return m, true, nil
+}
+
+// GetPinnedMessages returns the messages pinned in a channel, most
+// recently pinned first. Pins on deleted messages are left out.
+func (s *Store) GetPinnedMessages(ctx context.Context, serverID, channelID string) ([]PinnedMessage, error) {
+	const q = `
+SELECT ` + messageColumns + `, pinned_by, pinned_at_unix_ms
+FROM messages JOIN pins ON pins.msg_id = messages.id
+WHERE server_id = ? AND channel_id = ? AND deleted = 0
+ORDER BY pinned_at_unix_ms DESC, msg_id DESC
+`
+	rows, err := s.db.QueryContext(ctx, q, serverID, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("query pinned messages: %w", err)
+	}
+	defer rows.Close()
+
+	var pins []PinnedMessage
+	for rows.Next() {
+		var p PinnedMessage
+		m := &p.MessageRow
+		if err := rows.Scan(&m.ID, &m.ServerID, &m.ChannelID, &m.UserID, &m.Username, &m.Message, &m.TS, &m.FileID, &m.FileName, &m.FileSize, &m.ReplyTo, &m.BootID, &p.PinnedBy, &p.PinnedAt); err != nil {
+			return nil, fmt.Errorf("scan pinned message: %w", err)
+		}
+		pins = append(pins, p)
+	}
+	return pins, rows.Err()
+}
+
 // ReactionRow is a single reaction record.
 type ReactionRow struct {
 	MsgID  int64
@@ -323,3 +945,329 @@ WHERE id = ?
 	slog.Debug("blob loaded", "blob_id", id, "size", meta.SizeBytes)
 	return meta, nil
 }
+
+// Ban is a persisted ban on the IP address a user connected from.
+type Ban struct {
+	// ID is assigned by RecordBan and identifies the ban to RemoveBan.
+	ID       int64
+	IP       string
+	Username string
+	Reason   string
+	BannedBy string
+	// ExpiresAt is when the ban lapses; zero means it never does.
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// RecordBan persists a ban.
+func (s *Store) RecordBan(ctx context.Context, b Ban) error {
+	if strings.TrimSpace(b.IP) == "" {
+		return fmt.Errorf("ban ip is required")
+	}
+	if b.CreatedAt.IsZero() {
+		b.CreatedAt = time.Now()
+	}
+	var expires int64
+	if !b.ExpiresAt.IsZero() {
+		expires = b.ExpiresAt.UnixMilli()
+	}
+	const q = `INSERT INTO bans (ip, username, reason, banned_by, created_at_unix_ms, expires_at_unix_ms) VALUES (?, ?, ?, ?, ?, ?)`
+	if _, err := s.db.ExecContext(ctx, q, b.IP, b.Username, b.Reason, b.BannedBy, b.CreatedAt.UnixMilli(), expires); err != nil {
+		return fmt.Errorf("insert ban: %w", err)
+	}
+	slog.Info("ban recorded", "ip", b.IP, "username", b.Username, "banned_by", b.BannedBy, "expires_at", b.ExpiresAt)
+	return nil
+}
+
+// ActiveBan returns the most recent ban on ip that has not expired at now.
+// ok is false when the address is not banned.
+func (s *Store) ActiveBan(ctx context.Context, ip string, now time.Time) (Ban, bool, error) {
+	const q = `
+SELECT ` + banColumns + `
+FROM bans
+WHERE ip = ? AND (expires_at_unix_ms = 0 OR expires_at_unix_ms > ?)
+ORDER BY id DESC
+LIMIT 1
+`
+	b, err := scanBan(s.db.QueryRowContext(ctx, q, ip, now.UnixMilli()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Ban{}, false, nil
+	}
+	if err != nil {
+		return Ban{}, false, fmt.Errorf("query ban: %w", err)
+	}
+	return b, true, nil
+}
+
+// GetBans returns every ban that has not expired at now, newest first.
+func (s *Store) GetBans(ctx context.Context, now time.Time) ([]Ban, error) {
+	const q = `
+SELECT ` + banColumns + `
+FROM bans
+WHERE expires_at_unix_ms = 0 OR expires_at_unix_ms > ?
+ORDER BY id DESC
+`
+	rows, err := s.db.QueryContext(ctx, q, now.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("query bans: %w", err)
+	}
+	defer rows.Close()
+
+	var bans []Ban
+	for rows.Next() {
+		b, err := scanBan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan ban: %w", err)
+		}
+		bans = append(bans, b)
+	}
+	return bans, rows.Err()
+}
+
+// CountBans returns how many bans have not expired at now.
+func (s *Store) CountBans(ctx context.Context, now time.Time) (int, error) {
+	const q = `SELECT COUNT(*) FROM bans WHERE expires_at_unix_ms = 0 OR expires_at_unix_ms > ?`
+	var n int
+	if err := s.db.QueryRowContext(ctx, q, now.UnixMilli()).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count bans: %w", err)
+	}
+	return n, nil
+}
+
+// RemoveBan lifts the ban with the given ID and returns it. ok is false
+// when there is no such ban.
+func (s *Store) RemoveBan(ctx context.Context, id int64) (Ban, bool, error) {
+	q := `DELETE FROM bans WHERE id = ? RETURNING ` + banColumns
+	b, err := scanBan(s.db.QueryRowContext(ctx, q, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Ban{}, false, nil
+	}
+	if err != nil {
+		return Ban{}, false, fmt.Errorf("delete ban: %w", err)
+	}
+	slog.Info("ban removed", "ban_id", id, "ip", b.IP, "username", b.Username)
+	return b, true, nil
+}
+
+const banColumns = `id, ip, username, reason, banned_by, created_at_unix_ms, expires_at_unix_ms`
+
+func scanBan(row rowScanner) (Ban, error) {
+	var (
+		b                Ban
+		created, expires int64
+	)
+	if err := row.Scan(&b.ID, &b.IP, &b.Username, &b.Reason, &b.BannedBy, &created, &expires); err != nil {
+		return Ban{}, err
+	}
+	b.CreatedAt = time.UnixMilli(created).UTC()
+	if expires != 0 {
+		b.ExpiresAt = time.UnixMilli(expires).UTC()
+	}
+	return b, nil
+}
+
+// AuditEntry is one moderation action in the audit log.
+type AuditEntry struct {
+	ActorID    string
+	ActorName  string
+	Action     string
+	TargetID   string
+	TargetName string
+	Details    string
+	CreatedAt  time.Time
+}
+
+// RecordAudit appends an entry to the audit log.
+func (s *Store) RecordAudit(ctx context.Context, e AuditEntry) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	const q = `INSERT INTO audit_log (actor_id, actor_name, action, target_id, target_name, details, created_at_unix_ms) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	if _, err := s.db.ExecContext(ctx, q, e.ActorID, e.ActorName, e.Action, e.TargetID, e.TargetName, e.Details, e.CreatedAt.UnixMilli()); err != nil {
+		return fmt.Errorf("insert audit entry: %w", err)
+	}
+	return nil
+}
+
+// AuditLog returns the most recent audit log entries, newest first.
+func (s *Store) AuditLog(ctx context.Context, limit int) ([]AuditEntry, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	const q = `
+SELECT actor_id, actor_name, action, target_id, target_name, details, created_at_unix_ms
+FROM audit_log
+ORDER BY id DESC
+LIMIT ?
+`
+	rows, err := s.db.QueryContext(ctx, q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var (
+			e  AuditEntry
+			ts int64
+		)
+		if err := rows.Scan(&e.ActorID, &e.ActorName, &e.Action, &e.TargetID, &e.TargetName, &e.Details, &ts); err != nil {
+			return nil, fmt.Errorf("scan audit entry: %w", err)
+		}
+		e.CreatedAt = time.UnixMilli(ts).UTC()
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// RecordingInfo describes a finished voice channel recording on disk.
+type RecordingInfo struct {
+	ID        string
+	ServerID  string
+	ChannelID string
+	// UserID is the user who recorded the channel.
+	UserID    string
+	Duration  time.Duration
+	Path      string
+	SizeBytes int64
+	CreatedAt time.Time
+}
+
+// recordingColumns is the column list scanned by scanRecording.
+const recordingColumns = `id, server_id, channel_id, user_id, duration_ms, path, size_bytes, created_at_unix_ms`
+
+func scanRecording(row rowScanner) (RecordingInfo, error) {
+	var (
+		r                     RecordingInfo
+		durationMS, createdMS int64
+	)
+	if err := row.Scan(&r.ID, &r.ServerID, &r.ChannelID, &r.UserID, &durationMS, &r.Path, &r.SizeBytes, &createdMS); err != nil {
+		return RecordingInfo{}, err
+	}
+	r.Duration = time.Duration(durationMS) * time.Millisecond
+	r.CreatedAt = time.UnixMilli(createdMS).UTC()
+	return r, nil
+}
+
+// CreateRecording persists a recording's metadata.
+func (s *Store) CreateRecording(ctx context.Context, r RecordingInfo) error {
+	if strings.TrimSpace(r.ID) == "" {
+		return fmt.Errorf("recording id is required")
+	}
+	if strings.TrimSpace(r.Path) == "" {
+		return fmt.Errorf("recording path is required")
+	}
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now().UTC()
+	}
+	const q = `INSERT INTO recordings (` + recordingColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	if _, err := s.db.ExecContext(ctx, q, r.ID, r.ServerID, r.ChannelID, r.UserID, r.Duration.Milliseconds(), r.Path, r.SizeBytes, r.CreatedAt.UnixMilli()); err != nil {
+		return fmt.Errorf("insert recording: %w", err)
+	}
+	slog.Debug("recording metadata created", "recording_id", r.ID, "duration", r.Duration)
+	return nil
+}
+
+// RecordingByID returns one recording's metadata.
+func (s *Store) RecordingByID(ctx context.Context, id string) (RecordingInfo, error) {
+	const q = `SELECT ` + recordingColumns + ` FROM recordings WHERE id = ?`
+	r, err := scanRecording(s.db.QueryRowContext(ctx, q, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return RecordingInfo{}, ErrRecordingNotFound
+	}
+	if err != nil {
+		return RecordingInfo{}, fmt.Errorf("query recording: %w", err)
+	}
+	return r, nil
+}
+
+// Recordings returns a server's recordings, newest first.
+func (s *Store) Recordings(ctx context.Context, serverID string) ([]RecordingInfo, error) {
+	const q = `SELECT ` + recordingColumns + ` FROM recordings WHERE server_id = ? ORDER BY created_at_unix_ms DESC, id`
+	rows, err := s.db.QueryContext(ctx, q, serverID)
+	if err != nil {
+		return nil, fmt.Errorf("query recordings: %w", err)
+	}
+	defer rows.Close()
+
+	var out []RecordingInfo
+	for rows.Next() {
+		r, err := scanRecording(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan recording: %w", err)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// settingWordFilter is the settings key the chat word filter is saved
+// under, as JSON.
+const settingWordFilter = "word_filter"
+
+// WordFilter is the chat word filter as saved by SetWordFilter.
+type WordFilter struct {
+	Words  []string `json:"words"`
+	Action string   `json:"action"`
+}
+
+// SetWordFilter saves the chat word filter, replacing any saved before.
+func (s *Store) SetWordFilter(ctx context.Context, f WordFilter) error {
+	value, err := json.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("encode word filter: %w", err)
+	}
+	if err := s.saveSetting(ctx, settingWordFilter, string(value)); err != nil {
+		return fmt.Errorf("save word filter: %w", err)
+	}
+	return nil
+}
+
+// LoadWordFilter returns the saved chat word filter. ok is false when none
+// has been saved.
+func (s *Store) LoadWordFilter(ctx context.Context) (f WordFilter, ok bool, err error) {
+	value, ok, err := s.loadSetting(ctx, settingWordFilter)
+	if err != nil || !ok {
+		return WordFilter{}, false, err
+	}
+	if err := json.Unmarshal([]byte(value), &f); err != nil {
+		return WordFilter{}, false, fmt.Errorf("decode word filter: %w", err)
+	}
+	return f, true, nil
+}
+
+// settingMOTD is the settings key the message of the day is saved under.
+const settingMOTD = "motd"
+
+// SetMOTD saves the message of the day; "" saves that there is none.
+func (s *Store) SetMOTD(ctx context.Context, text string) error {
+	if err := s.saveSetting(ctx, settingMOTD, text); err != nil {
+		return fmt.Errorf("save motd: %w", err)
+	}
+	return nil
+}
+
+// LoadMOTD returns the saved message of the day. ok is false when none has
+// been saved.
+func (s *Store) LoadMOTD(ctx context.Context) (string, bool, error) {
+	return s.loadSetting(ctx, settingMOTD)
+}
+
+func (s *Store) saveSetting(ctx context.Context, key, value string) error {
+	const q = `INSERT INTO settings (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value`
+	_, err := s.db.ExecContext(ctx, q, key, value)
+	return err
+}
+
+func (s *Store) loadSetting(ctx context.Context, key string) (string, bool, error) {
+	var value string
+	err := s.db.QueryRowContext(ctx, `SELECT value FROM settings WHERE key = ?`, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("query setting %s: %w", key, err)
+	}
+	return value, true, nil
+}
@@ -2,6 +2,8 @@ package store
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"path/filepath"
 	"testing"
 	"time"
@@ -61,7 +63,7 @@ func TestInsertAndGetMessages(t *testing.T) {
 	t.Cleanup(func() { _ = st.Close() })
 
 	ctx := context.Background()
-	id, err := st.InsertMessage(ctx, "srv1", "ch1", "u1", "Alice", "hello", 1000, "", "", 0)
+	id, err := st.InsertMessage(ctx, "srv1", "ch1", "u1", "Alice", "hello", 1000, "", "", 0, 0)
 	if err != nil {
 		t.Fatalf("insert message: %v", err)
 	}
@@ -81,6 +83,146 @@ func TestInsertAndGetMessages(t *testing.T) {
 	}
 }
 
+func TestGetMessagesSince(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "bken.db")
+	st, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	ctx := context.Background()
+	var ids []int64
+	for i, text := range []string{"one", "two", "three"} {
+		id, err := st.InsertMessage(ctx, "srv1", "ch1", "u1", "Alice", text, int64(1000+i), "", "", 0, 0)
+		if err != nil {
+			t.Fatalf("insert %q: %v", text, err)
+		}
+		ids = append(ids, id)
+	}
+	if _, err := st.InsertMessage(ctx, "srv1", "ch2", "u1", "Alice", "elsewhere", 2000, "", "", 0, 0); err != nil {
+		t.Fatalf("insert other channel: %v", err)
+	}
+
+	rows, err := st.GetMessagesSince(ctx, "srv1", "ch1", ids[0], 50)
+	if err != nil {
+		t.Fatalf("get messages since: %v", err)
+	}
+	if len(rows) != 2 || rows[0].ID != ids[1] || rows[1].ID != ids[2] {
+		t.Fatalf("expected messages after %d oldest first, got %+v", ids[0], rows)
+	}
+
+	rows, err = st.GetMessagesSince(ctx, "srv1", "ch1", ids[2], 50)
+	if err != nil {
+		t.Fatalf("get messages since newest: %v", err)
+	}
+	if len(rows) != 0 {
+		t.Fatalf("expected nothing after the newest message, got %+v", rows)
+	}
+}
+
+func TestMessagesSurviveReopen(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "bken.db")
+	st, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	ctx := context.Background()
+	for i, msg := range []string{"first", "second", "third"} {
+		if _, err := st.InsertMessage(ctx, "srv1", "ch1", "u1", "Alice", msg, int64(1000+i), "", "", 0, 0); err != nil {
+			t.Fatalf("insert message: %v", err)
+		}
+	}
+	if err := st.Close(); err != nil {
+		t.Fatalf("close store: %v", err)
+	}
+
+	st, err = Open(dbPath)
+	if err != nil {
+		t.Fatalf("reopen sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	rows, err := st.GetMessages(ctx, "srv1", "ch1", 2)
+	if err != nil {
+		t.Fatalf("get messages: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Message != "second" || rows[1].Message != "third" {
+		t.Fatalf("expected the two most recent messages oldest first, got %+v", rows)
+	}
+}
+
+func TestMessageIDsKeepRisingAcrossReopen(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "bken.db")
+	st, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	ctx := context.Background()
+	var maxID int64
+	for i, msg := range []string{"first", "second", "third"} {
+		id, err := st.InsertMessage(ctx, "srv1", "ch1", "u1", "Alice", msg, int64(1000+i), "", "", 0, 0)
+		if err != nil {
+			t.Fatalf("insert message: %v", err)
+		}
+		maxID = max(maxID, id)
+	}
+	// Deleting the newest messages must not free their IDs either.
+	if _, err := st.PurgeMessages(ctx, "srv1", "ch1", 2); err != nil {
+		t.Fatalf("purge messages: %v", err)
+	}
+	if err := st.Close(); err != nil {
+		t.Fatalf("close store: %v", err)
+	}
+
+	st, err = Open(dbPath)
+	if err != nil {
+		t.Fatalf("reopen sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	id, err := st.InsertMessage(ctx, "srv1", "ch2", "u2", "Bob", "after restart", 2000, "", "", 0, 0)
+	if err != nil {
+		t.Fatalf("insert message: %v", err)
+	}
+	if id <= maxID {
+		t.Fatalf("message ID %d after reopen, want above %d", id, maxID)
+	}
+}
+
+func TestFileMessageRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "bken.db")
+	st, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	ctx := context.Background()
+	if _, err := st.InsertMessage(ctx, "srv1", "ch1", "u1", "Alice", "", 1000, "blob-1", "notes.txt", 2048, 0); err != nil {
+		t.Fatalf("insert file message: %v", err)
+	}
+
+	rows, err := st.GetMessages(ctx, "srv1", "ch1", 10)
+	if err != nil {
+		t.Fatalf("get messages: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(rows))
+	}
+	if rows[0].FileID != "blob-1" || rows[0].FileName != "notes.txt" || rows[0].FileSize != 2048 {
+		t.Fatalf("file fields not persisted: %+v", rows[0])
+	}
+}
+
 func TestAddAndRemoveReaction(t *testing.T) {
 	t.Parallel()
 
@@ -94,7 +236,7 @@ func TestAddAndRemoveReaction(t *testing.T) {
 	ctx := context.Background()
 
 	// Insert a message to react to.
-	msgID, err := st.InsertMessage(ctx, "srv1", "ch1", "u1", "Alice", "hi", 1000, "", "", 0)
+	msgID, err := st.InsertMessage(ctx, "srv1", "ch1", "u1", "Alice", "hi", 1000, "", "", 0, 0)
 	if err != nil {
 		t.Fatalf("insert message: %v", err)
 	}
@@ -160,3 +302,633 @@ func TestGetReactionsForMessagesEmpty(t *testing.T) {
 		t.Fatalf("expected nil map, got %v", rxMap)
 	}
 }
+
+func TestGetReplyThread(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "bken.db")
+	st, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	ctx := context.Background()
+
+	var ids []int64
+	var parent int64
+	for i, text := range []string{"root", "first reply", "second reply"} {
+		id, err := st.InsertMessage(ctx, "srv1", "ch1", "u1", "Alice", text, int64(1000+i), "", "", 0, parent)
+		if err != nil {
+			t.Fatalf("insert %q: %v", text, err)
+		}
+		ids = append(ids, id)
+		parent = id
+	}
+
+	thread, err := st.GetReplyThread(ctx, "srv1", ids[2], 50)
+	if err != nil {
+		t.Fatalf("get thread: %v", err)
+	}
+	if len(thread) != 3 || thread[0].Message != "root" || thread[2].Message != "second reply" {
+		t.Fatalf("expected root-first thread of 3, got %+v", thread)
+	}
+	if thread[1].ReplyTo != ids[0] {
+		t.Fatalf("expected reply_to %d, got %d", ids[0], thread[1].ReplyTo)
+	}
+
+	// The depth cap keeps the messages nearest the requested one.
+	thread, err = st.GetReplyThread(ctx, "srv1", ids[2], 2)
+	if err != nil {
+		t.Fatalf("get capped thread: %v", err)
+	}
+	if len(thread) != 2 || thread[0].ID != ids[1] {
+		t.Fatalf("expected capped thread starting at %d, got %+v", ids[1], thread)
+	}
+
+	// Messages from another server are not reachable.
+	if thread, err := st.GetReplyThread(ctx, "srv2", ids[2], 50); err != nil || len(thread) != 0 {
+		t.Fatalf("expected empty thread for other server, got %+v, %v", thread, err)
+	}
+
+	// A reply loop terminates instead of hanging.
+	if _, err := st.db.ExecContext(ctx, `UPDATE messages SET reply_to = ? WHERE id = ?`, ids[2], ids[0]); err != nil {
+		t.Fatalf("create cycle: %v", err)
+	}
+	thread, err = st.GetReplyThread(ctx, "srv1", ids[2], 50)
+	if err != nil {
+		t.Fatalf("get cyclic thread: %v", err)
+	}
+	if len(thread) != 3 {
+		t.Fatalf("expected cycle to stop after 3 messages, got %d", len(thread))
+	}
+}
+
+func TestSearchMessagesPagesWithBefore(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "bken.db")
+	st, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	ctx := context.Background()
+	insert := func(serverID, channelID, text string) int64 {
+		id, err := st.InsertMessage(ctx, serverID, channelID, "u1", "Alice", text, 1000, "", "", 0, 0)
+		if err != nil {
+			t.Fatalf("insert %q: %v", text, err)
+		}
+		return id
+	}
+	var ids []int64
+	for _, text := range []string{"deploy one", "unrelated", "Deploy two", "deploy three", "deploy four"} {
+		ids = append(ids, insert("srv1", "ch1", text))
+	}
+	insert("srv1", "ch2", "deploy elsewhere")
+	insert("srv2", "ch1", "deploy on another server")
+
+	page, err := st.SearchMessages(ctx, "srv1", "ch1", "deploy", 0, 2)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(page) != 2 || page[0].ID != ids[4] || page[1].ID != ids[3] {
+		t.Fatalf("expected newest two matches, got %+v", page)
+	}
+
+	page, err = st.SearchMessages(ctx, "srv1", "ch1", "deploy", page[1].ID, 2)
+	if err != nil {
+		t.Fatalf("search page 2: %v", err)
+	}
+	if len(page) != 2 || page[0].ID != ids[2] || page[1].ID != ids[0] {
+		t.Fatalf("expected case-insensitive second page skipping non-matches, got %+v", page)
+	}
+
+	page, err = st.SearchMessages(ctx, "srv1", "ch1", "deploy", page[1].ID, 2)
+	if err != nil {
+		t.Fatalf("search page 3: %v", err)
+	}
+	if len(page) != 0 {
+		t.Fatalf("expected no results past the oldest match, got %+v", page)
+	}
+
+	// LIKE wildcards in the query match literally.
+	insert("srv1", "ch1", "100% done")
+	if page, err := st.SearchMessages(ctx, "srv1", "ch1", "0%", 0, 10); err != nil || len(page) != 1 {
+		t.Fatalf("expected one literal %% match, got %+v, %v", page, err)
+	}
+	if page, err := st.SearchMessages(ctx, "srv1", "ch1", "_", 0, 10); err != nil || len(page) != 0 {
+		t.Fatalf("expected underscore to match literally, got %+v, %v", page, err)
+	}
+}
+
+func TestPurgeMessagesMarksNewestDeleted(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "bken.db")
+	st, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	ctx := context.Background()
+	var ids []int64
+	for i, text := range []string{"keep", "spam one", "spam two", "spam three"} {
+		id, err := st.InsertMessage(ctx, "srv1", "ch1", "u1", "Alice", text, int64(1000+i), "", "", 0, 0)
+		if err != nil {
+			t.Fatalf("insert %q: %v", text, err)
+		}
+		ids = append(ids, id)
+	}
+	other, err := st.InsertMessage(ctx, "srv1", "ch2", "u1", "Alice", "elsewhere", 2000, "", "", 0, 0)
+	if err != nil {
+		t.Fatalf("insert other channel: %v", err)
+	}
+
+	purged, err := st.PurgeMessages(ctx, "srv1", "ch1", 2)
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if len(purged) != 2 || purged[0] != ids[3] || purged[1] != ids[2] {
+		t.Fatalf("expected newest two purged, got %v", purged)
+	}
+
+	// Already deleted messages are skipped by the next purge.
+	purged, err = st.PurgeMessages(ctx, "srv1", "ch1", 1)
+	if err != nil {
+		t.Fatalf("second purge: %v", err)
+	}
+	if len(purged) != 1 || purged[0] != ids[1] {
+		t.Fatalf("expected %d purged next, got %v", ids[1], purged)
+	}
+
+	msgs, err := st.GetMessages(ctx, "srv1", "ch1", 50)
+	if err != nil {
+		t.Fatalf("get messages: %v", err)
+	}
+	if len(msgs) != 1 || msgs[0].ID != ids[0] {
+		t.Fatalf("expected only the kept message in history, got %+v", msgs)
+	}
+	if _, ok, err := st.GetMessage(ctx, "srv1", ids[3]); err != nil || ok {
+		t.Fatalf("expected purged message to be gone, got ok=%v err=%v", ok, err)
+	}
+	if _, ok, err := st.GetMessage(ctx, "srv1", other); err != nil || !ok {
+		t.Fatalf("expected other channel untouched, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestExpireMessagesSkipsDeleted(t *testing.T) {
+	t.Parallel()
+
+	st, err := Open(filepath.Join(t.TempDir(), "bken.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	ctx := context.Background()
+	var ids []int64
+	for i, text := range []string{"old", "purged one", "purged two"} {
+		id, err := st.InsertMessage(ctx, "srv1", "ch1", "u1", "Alice", text, int64(1000*(i+1)), "", "", 0, 0)
+		if err != nil {
+			t.Fatalf("insert %q: %v", text, err)
+		}
+		ids = append(ids, id)
+	}
+	if _, err := st.InsertMessage(ctx, "srv1", "ch2", "u1", "Alice", "elsewhere", 1000, "", "", 0, 0); err != nil {
+		t.Fatalf("insert other channel: %v", err)
+	}
+	// Delete two messages up front; expiry must not report them again.
+	if _, err := st.PurgeMessages(ctx, "srv1", "ch1", 2); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if _, err := st.InsertMessage(ctx, "srv1", "ch1", "u1", "Alice", "newest", 4000, "", "", 0, 0); err != nil {
+		t.Fatalf("insert newest: %v", err)
+	}
+
+	expired, err := st.ExpireMessages(ctx, "srv1", "ch1", 5000)
+	if err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	if len(expired) != 2 || expired[0] != ids[0] {
+		t.Fatalf("expected the old and newest messages expired, got %v", expired)
+	}
+
+	expired, err = st.ExpireMessages(ctx, "srv1", "ch1", 5000)
+	if err != nil || len(expired) != 0 {
+		t.Fatalf("expected nothing left to expire, got %v, %v", expired, err)
+	}
+	msgs, err := st.GetMessages(ctx, "srv1", "ch2", 50)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("expected other channel untouched, got %+v, %v", msgs, err)
+	}
+}
+
+func TestChannelTranscriptIncludesDeletedAndEdited(t *testing.T) {
+	t.Parallel()
+
+	st, err := Open(filepath.Join(t.TempDir(), "bken.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	ctx := context.Background()
+	var ids []int64
+	for i, text := range []string{"first", "typo", "spam"} {
+		id, err := st.InsertMessage(ctx, "srv1", "ch1", "u1", "Alice", text, int64(1000+i), "", "", 0, 0)
+		if err != nil {
+			t.Fatalf("insert %q: %v", text, err)
+		}
+		ids = append(ids, id)
+	}
+	if _, err := st.InsertMessage(ctx, "srv1", "ch2", "u1", "Alice", "elsewhere", 2000, "", "", 0, 0); err != nil {
+		t.Fatalf("insert other channel: %v", err)
+	}
+	if ok, err := st.EditMessage(ctx, "srv1", ids[1], "fixed", 3000); err != nil || !ok {
+		t.Fatalf("edit: ok=%v err=%v", ok, err)
+	}
+	if _, err := st.PurgeMessages(ctx, "srv1", "ch1", 1); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+
+	var got []TranscriptMessage
+	if err := st.ChannelTranscript(ctx, "srv1", "ch1", func(m TranscriptMessage) error {
+		got = append(got, m)
+		return nil
+	}); err != nil {
+		t.Fatalf("transcript: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 messages, got %+v", got)
+	}
+	if got[0].Message != "first" || got[0].Edited || got[0].Deleted {
+		t.Errorf("unexpected first message: %+v", got[0])
+	}
+	if got[1].Message != "fixed" || !got[1].Edited {
+		t.Errorf("expected the edited message, got %+v", got[1])
+	}
+	if got[2].ID != ids[2] || !got[2].Deleted || got[2].Message != "" {
+		t.Errorf("expected the deleted message without its text, got %+v", got[2])
+	}
+}
+
+func TestChannelTranscriptPagesByID(t *testing.T) {
+	t.Parallel()
+
+	st, err := Open(filepath.Join(t.TempDir(), "bken.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	ctx := context.Background()
+	elsewhere, err := st.InsertMessage(ctx, "srv1", "ch2", "u1", "Alice", "elsewhere", 500, "", "", 0, 0)
+	if err != nil {
+		t.Fatalf("insert other channel: %v", err)
+	}
+	first, err := st.InsertMessage(ctx, "srv1", "ch1", "u1", "Alice", "first", 1000, "", "", 0, 0)
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	want := []int64{first}
+	for i, replyTo := range []int64{first, elsewhere, 0, first} {
+		id, err := st.InsertMessage(ctx, "srv1", "ch1", "u2", "Bob", fmt.Sprintf("m%d", i), int64(2000+i), "", "", 0, replyTo)
+		if err != nil {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+		want = append(want, id)
+	}
+
+	var got []TranscriptMessage
+	if err := st.channelTranscript(ctx, "srv1", "ch1", 2, func(m TranscriptMessage) error {
+		got = append(got, m)
+		return nil
+	}); err != nil {
+		t.Fatalf("transcript: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d messages across pages, got %d", len(want), len(got))
+	}
+	for i, m := range got {
+		if m.ID != want[i] {
+			t.Fatalf("message %d: id %d, want %d", i, m.ID, want[i])
+		}
+	}
+	if p := got[1].Parent; p == nil || p.Username != "Alice" || p.Message != "first" {
+		t.Errorf("expected the reply to carry its parent, got %+v", p)
+	}
+	if got[2].Parent != nil {
+		t.Errorf("a parent in another channel should not be quoted, got %+v", got[2].Parent)
+	}
+	if got[0].Parent != nil || got[3].Parent != nil {
+		t.Error("messages that are not replies should have no parent")
+	}
+}
+
+func TestEditMessageKeepsBoundedHistory(t *testing.T) {
+	t.Parallel()
+
+	st, err := Open(filepath.Join(t.TempDir(), "bken.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	ctx := context.Background()
+	id, err := st.InsertMessage(ctx, "srv1", "ch1", "u1", "Alice", "v0", 1000, "", "", 0, 0)
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if ok, err := st.EditMessage(ctx, "srv2", id, "wrong server", 1500); err != nil || ok {
+		t.Fatalf("expected an edit from another server to miss, got %v, %v", ok, err)
+	}
+	for i := 1; i <= MaxEditHistory+2; i++ {
+		if ok, err := st.EditMessage(ctx, "srv1", id, fmt.Sprintf("v%d", i), int64(1000+i)); err != nil || !ok {
+			t.Fatalf("edit %d: %v, %v", i, ok, err)
+		}
+	}
+
+	msg, found, err := st.GetMessage(ctx, "srv1", id)
+	if err != nil || !found || msg.Message != fmt.Sprintf("v%d", MaxEditHistory+2) {
+		t.Fatalf("expected the latest text, got %+v, %v, %v", msg, found, err)
+	}
+	history, err := st.GetEditHistory(ctx, id)
+	if err != nil {
+		t.Fatalf("edit history: %v", err)
+	}
+	if len(history) != MaxEditHistory {
+		t.Fatalf("expected %d history entries, got %d", MaxEditHistory, len(history))
+	}
+	// The two oldest versions were dropped; v2 was replaced at 1003.
+	if history[0].Message != "v2" || history[0].EditedAt != 1003 {
+		t.Fatalf("expected oldest kept entry v2 at 1003, got %+v", history[0])
+	}
+	if last := history[len(history)-1]; last.Message != fmt.Sprintf("v%d", MaxEditHistory+1) {
+		t.Fatalf("expected newest entry to be the previous text, got %+v", last)
+	}
+}
+
+func TestPinMessagesEnforcesLimit(t *testing.T) {
+	t.Parallel()
+
+	st, err := Open(filepath.Join(t.TempDir(), "bken.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	ctx := context.Background()
+	var ids []int64
+	for i := 0; i <= MaxPinnedPerChannel; i++ {
+		id, err := st.InsertMessage(ctx, "srv1", "ch1", "u1", "Alice", fmt.Sprintf("m%d", i), int64(1000+i), "", "", 0, 0)
+		if err != nil {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+		ids = append(ids, id)
+	}
+	if _, ok, err := st.PinMessage(ctx, "srv2", ids[0], "u2", 2000); err != nil || ok {
+		t.Fatalf("expected a pin from another server to miss, got %v, %v", ok, err)
+	}
+	for i, id := range ids[:MaxPinnedPerChannel] {
+		m, ok, err := st.PinMessage(ctx, "srv1", id, "u2", int64(2000+i))
+		if err != nil || !ok || m.ChannelID != "ch1" {
+			t.Fatalf("pin %d: %+v, %v, %v", i, m, ok, err)
+		}
+	}
+	// Re-pinning is a no-op, even at the limit.
+	if _, ok, err := st.PinMessage(ctx, "srv1", ids[0], "u3", 3000); err != nil || !ok {
+		t.Fatalf("re-pin: %v, %v", ok, err)
+	}
+	if _, _, err := st.PinMessage(ctx, "srv1", ids[MaxPinnedPerChannel], "u2", 3000); !errors.Is(err, ErrTooManyPins) {
+		t.Fatalf("expected ErrTooManyPins, got %v", err)
+	}
+
+	pins, err := st.GetPinnedMessages(ctx, "srv1", "ch1")
+	if err != nil {
+		t.Fatalf("get pinned: %v", err)
+	}
+	if len(pins) != MaxPinnedPerChannel {
+		t.Fatalf("expected %d pins, got %d", MaxPinnedPerChannel, len(pins))
+	}
+	if first := pins[0]; first.ID != ids[MaxPinnedPerChannel-1] || first.PinnedBy != "u2" || first.PinnedAt != int64(2000+MaxPinnedPerChannel-1) {
+		t.Errorf("expected the most recent pin first, got %+v", first)
+	}
+
+	// Unpinning frees a slot.
+	if _, ok, err := st.UnpinMessage(ctx, "srv1", ids[0]); err != nil || !ok {
+		t.Fatalf("unpin: %v, %v", ok, err)
+	}
+	if _, ok, err := st.UnpinMessage(ctx, "srv1", ids[0]); err != nil || ok {
+		t.Fatalf("expected a second unpin to miss, got %v, %v", ok, err)
+	}
+	if _, ok, err := st.PinMessage(ctx, "srv1", ids[MaxPinnedPerChannel], "u2", 4000); err != nil || !ok {
+		t.Fatalf("pin after unpin: %v, %v", ok, err)
+	}
+
+	// Deleted messages drop out of the list and the count.
+	if _, err := st.PurgeMessages(ctx, "srv1", "ch1", 1); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if pins, err := st.GetPinnedMessages(ctx, "srv1", "ch1"); err != nil || len(pins) != MaxPinnedPerChannel-1 {
+		t.Fatalf("expected %d pins after purge, got %d, %v", MaxPinnedPerChannel-1, len(pins), err)
+	}
+}
+
+func TestBanLookupHonoursExpiry(t *testing.T) {
+	t.Parallel()
+
+	st, err := Open(filepath.Join(t.TempDir(), "bken.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = st.Close()
+	})
+	ctx := context.Background()
+	now := time.UnixMilli(1_700_000_000_000)
+
+	if err := st.RecordBan(ctx, Ban{IP: "10.0.0.1", Username: "mallory", Reason: "spam", BannedBy: "alice", CreatedAt: now}); err != nil {
+		t.Fatalf("record permanent ban: %v", err)
+	}
+	if err := st.RecordBan(ctx, Ban{IP: "10.0.0.2", Username: "eve", BannedBy: "alice", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("record timed ban: %v", err)
+	}
+	if err := st.RecordBan(ctx, Ban{Username: "nobody"}); err == nil {
+		t.Fatal("expected error for a ban without an ip")
+	}
+
+	b, ok, err := st.ActiveBan(ctx, "10.0.0.1", now.Add(365*24*time.Hour))
+	if err != nil || !ok {
+		t.Fatalf("permanent ban: ok=%v err=%v", ok, err)
+	}
+	if b.Username != "mallory" || b.Reason != "spam" || !b.ExpiresAt.IsZero() {
+		t.Fatalf("unexpected ban: %#v", b)
+	}
+
+	if _, ok, err := st.ActiveBan(ctx, "10.0.0.2", now.Add(30*time.Minute)); err != nil || !ok {
+		t.Fatalf("timed ban before expiry: ok=%v err=%v", ok, err)
+	}
+	if _, ok, err := st.ActiveBan(ctx, "10.0.0.2", now.Add(time.Hour)); err != nil || ok {
+		t.Fatalf("timed ban at expiry: ok=%v err=%v", ok, err)
+	}
+	if _, ok, err := st.ActiveBan(ctx, "10.0.0.3", now); err != nil || ok {
+		t.Fatalf("unbanned ip: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestGetBansAndRemoveBan(t *testing.T) {
+	t.Parallel()
+
+	st, err := Open(filepath.Join(t.TempDir(), "bken.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = st.Close()
+	})
+	ctx := context.Background()
+	now := time.UnixMilli(1_700_000_000_000)
+
+	for _, b := range []Ban{
+		{IP: "10.0.0.1", Username: "mallory", CreatedAt: now},
+		{IP: "10.0.0.2", Username: "eve", CreatedAt: now, ExpiresAt: now.Add(time.Minute)},
+		{IP: "10.0.0.3", Username: "trent", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+	} {
+		if err := st.RecordBan(ctx, b); err != nil {
+			t.Fatalf("record ban: %v", err)
+		}
+	}
+
+	// eve's ban has run out by then and is left out.
+	bans, err := st.GetBans(ctx, now.Add(30*time.Minute))
+	if err != nil {
+		t.Fatalf("get bans: %v", err)
+	}
+	if len(bans) != 2 || bans[0].Username != "trent" || bans[1].Username != "mallory" || bans[0].ID == 0 {
+		t.Fatalf("unexpected bans: %#v", bans)
+	}
+
+	removed, ok, err := st.RemoveBan(ctx, bans[1].ID)
+	if err != nil || !ok || removed.IP != "10.0.0.1" {
+		t.Fatalf("remove ban: %#v ok=%v err=%v", removed, ok, err)
+	}
+	if _, ok, err := st.ActiveBan(ctx, "10.0.0.1", now); err != nil || ok {
+		t.Fatalf("removed ban still active: ok=%v err=%v", ok, err)
+	}
+	if _, ok, err := st.RemoveBan(ctx, bans[1].ID); err != nil || ok {
+		t.Fatalf("removing twice: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestAuditLogNewestFirst(t *testing.T) {
+	t.Parallel()
+
+	st, err := Open(filepath.Join(t.TempDir(), "bken.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = st.Close()
+	})
+	ctx := context.Background()
+
+	for _, target := range []string{"u2", "u3"} {
+		if err := st.RecordAudit(ctx, AuditEntry{ActorID: "u1", ActorName: "alice", Action: "ban", TargetID: target}); err != nil {
+			t.Fatalf("record audit: %v", err)
+		}
+	}
+	entries, err := st.AuditLog(ctx, 10)
+	if err != nil {
+		t.Fatalf("audit log: %v", err)
+	}
+	if len(entries) != 2 || entries[0].TargetID != "u3" || entries[1].TargetID != "u2" {
+		t.Fatalf("unexpected audit log: %#v", entries)
+	}
+	if entries[0].CreatedAt.IsZero() {
+		t.Fatal("expected created_at to default to now")
+	}
+}
+
+func TestWordFilterRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "bken.db")
+	st, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, ok, err := st.LoadWordFilter(ctx); err != nil || ok {
+		t.Fatalf("fresh store: ok=%v, err=%v", ok, err)
+	}
+	if err := st.SetWordFilter(ctx, WordFilter{Words: []string{"darn"}, Action: "block"}); err != nil {
+		t.Fatalf("save word filter: %v", err)
+	}
+	if err := st.SetWordFilter(ctx, WordFilter{Words: []string{"darn", "heck"}, Action: "mask"}); err != nil {
+		t.Fatalf("replace word filter: %v", err)
+	}
+	_ = st.Close()
+
+	st, err = Open(dbPath)
+	if err != nil {
+		t.Fatalf("reopen sqlite store: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = st.Close()
+	})
+	f, ok, err := st.LoadWordFilter(ctx)
+	if err != nil || !ok {
+		t.Fatalf("load word filter: ok=%v, err=%v", ok, err)
+	}
+	if len(f.Words) != 2 || f.Words[1] != "heck" || f.Action != "mask" {
+		t.Errorf("loaded %+v, want the replacement", f)
+	}
+}
+
+func TestMOTDRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "bken.db")
+	st, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, ok, err := st.LoadMOTD(ctx); err != nil || ok {
+		t.Fatalf("fresh store: ok=%v, err=%v", ok, err)
+	}
+	if err := st.SetMOTD(ctx, "welcome"); err != nil {
+		t.Fatalf("save motd: %v", err)
+	}
+	if err := st.SetMOTD(ctx, "maintenance tonight"); err != nil {
+		t.Fatalf("replace motd: %v", err)
+	}
+	_ = st.Close()
+
+	st, err = Open(dbPath)
+	if err != nil {
+		t.Fatalf("reopen sqlite store: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = st.Close()
+	})
+	motd, ok, err := st.LoadMOTD(ctx)
+	if err != nil || !ok {
+		t.Fatalf("load motd: ok=%v, err=%v", ok, err)
+	}
+	if motd != "maintenance tonight" {
+		t.Errorf("loaded %q, want the replacement", motd)
+	}
+
+	if err := st.SetMOTD(ctx, ""); err != nil {
+		t.Fatalf("clear motd: %v", err)
+	}
+	if motd, ok, err := st.LoadMOTD(ctx); err != nil || !ok || motd != "" {
+		t.Errorf("cleared motd: %q ok=%v err=%v, want a saved empty MOTD", motd, ok, err)
+	}
+}
@@ -2,9 +2,13 @@ package ws
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -19,22 +23,57 @@ import (
 
 const writeTimeout = 5 * time.Second
 
+// messageHistoryLimit is how many persisted messages get_messages returns,
+// so a client reconnecting after a server restart still sees recent context.
+const messageHistoryLimit = 200
+
+// maxMessagePage caps how many messages one get_messages_before returns;
+// defaultMessagePage is used when the client gives no limit.
+const (
+	maxMessagePage     = 100
+	defaultMessagePage = 50
+)
+
+// maxThreadDepth caps how many messages get_thread walks back through.
+const maxThreadDepth = 50
+
+// maxPurgeMessages caps how many messages one purge_messages deletes.
+const maxPurgeMessages = 100
+
+// auditLogLimit is how many of the newest audit entries get_audit_log
+// returns.
+const auditLogLimit = 100
+
+// maxReasonLength caps the reason given with a kick or ban.
+const maxReasonLength = 500
+
+// maxBanDurationS is the longest timed ban or mute, ten years; longer ones
+// should use 0, which never lapses.
+const maxBanDurationS = 10 * 365 * 24 * 60 * 60
+
 // Handler owns websocket transport for the backend.
 type Handler struct {
 	channelState *core.ChannelState
 	store        *store.Store
 	upgrader     websocket.Upgrader
+	// reactions batches reaction fan-out; nil without a store, in which
+	// case reaction changes are broadcast individually.
+	reactions *reactionBatcher
 }
 
 // NewHandler creates a websocket handler bound to channelState.
 func NewHandler(channelState *core.ChannelState, st *store.Store) *Handler {
-	return &Handler{
+	h := &Handler{
 		channelState: channelState,
 		store:        st,
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(_ *http.Request) bool { return true },
 		},
 	}
+	if st != nil {
+		h.reactions = newReactionBatcher(reactionBatchInterval, h.flushReactions)
+	}
+	return h
 }
 
 // Register binds websocket routes on an Echo router.
@@ -47,6 +86,19 @@ func (h *Handler) HandleWebSocket(c echo.Context) error {
 	remoteAddr := c.RealIP()
 	slog.Debug("ws upgrade request", "remote", remoteAddr)
 
+	if !h.channelState.CanConnect(remoteAddr) {
+		if h.channelState.Draining() {
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "server is shutting down")
+		}
+		if h.store != nil {
+			h.recordAudit(c.Request().Context(), store.AuditEntry{
+				Action:  "connect_throttled",
+				Details: fmt.Sprintf("ip=%s limit_per_minute=%d", remoteAddr, h.channelState.ConnectRate()),
+			})
+		}
+		return echo.NewHTTPError(http.StatusTooManyRequests, "too many connections; try again later")
+	}
+
 	conn, err := h.upgrader.Upgrade(c.Response(), c.Request(), nil)
 	if err != nil {
 		slog.Error("ws upgrade failed", "remote", remoteAddr, "err", err)
@@ -73,7 +125,31 @@ func (h *Handler) serveConn(conn *websocket.Conn, remoteAddr string) {
 		return
 	}
 
-	slog.Debug("ws hello received", "remote", remoteAddr, "username", hello.Username)
+	slog.Debug("ws hello received", "remote", remoteAddr, "username", hello.Username, "protocol_version", hello.ProtocolVersion, "proto", hello.Proto)
+
+	// Clients that predate versioning send no version; let them in rather
+	// than lock out every existing install.
+	if hello.ProtocolVersion != 0 && hello.ProtocolVersion != protocol.ProtocolVersion {
+		slog.Warn("ws protocol version mismatch", "remote", remoteAddr, "username", hello.Username, "client_version", hello.ProtocolVersion, "server_version", protocol.ProtocolVersion)
+		_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		_ = conn.WriteJSON(protocol.Message{
+			Type:            protocol.TypeVersionMismatch,
+			ProtocolVersion: protocol.ProtocolVersion,
+			Error:           fmt.Sprintf("server requires protocol version %d, client speaks %d", protocol.ProtocolVersion, hello.ProtocolVersion),
+		})
+		return
+	}
+
+	if h.store != nil {
+		ban, banned, err := h.store.ActiveBan(context.Background(), remoteAddr, time.Now())
+		if err != nil {
+			slog.Error("ban lookup failed", "remote", remoteAddr, "err", err)
+		} else if banned {
+			slog.Info("ws banned client rejected", "remote", remoteAddr, "username", hello.Username)
+			h.writeDirectError(conn, banMessage(ban))
+			return
+		}
+	}
 
 	session, snapshot, err := h.channelState.Add(hello.Username, 64)
 	if err != nil {
@@ -82,9 +158,26 @@ func (h *Handler) serveConn(conn *websocket.Conn, remoteAddr string) {
 		return
 	}
 
-	slog.Info("ws connected", "user_id", session.UserID, "username", hello.Username, "remote", remoteAddr)
+	h.channelState.SetRemoteIP(session.UserID, remoteAddr)
+
+	var client protocol.ClientInfo
+	if hello.ClientInfo != nil {
+		client = *hello.ClientInfo
+		h.channelState.SetClientInfo(session.UserID, client)
+	}
+	slog.Info("ws connected", "user_id", session.UserID, "username", session.Username, "remote", remoteAddr, "client_commit", client.Commit, "client_os", client.OS)
+
+	// Once the client asks for the binary codec every message we send is a
+	// MessagePack binary frame. Inbound frames are decoded by their opcode,
+	// so a client may switch over as soon as it sees the snapshot.
+	binaryProto := hello.Proto == protocol.ProtoBinary
+	proto := ""
+	if binaryProto {
+		proto = protocol.ProtoBinary
+	}
 
 	defer func() {
+		h.stopRecording(session.UserID)
 		if removed, ok := h.channelState.Remove(session.UserID); ok {
 			slog.Info("ws disconnected", "user_id", session.UserID, "username", removed.Username, "remote", remoteAddr)
 			h.channelState.Broadcast(protocol.Message{Type: protocol.TypeUserLeft, User: &removed}, session.UserID)
@@ -93,34 +186,82 @@ func (h *Handler) serveConn(conn *websocket.Conn, remoteAddr string) {
 
 	go func() {
 		for out := range session.Send {
+			data, err := json.Marshal(out)
+			if err != nil {
+				slog.Error("ws marshal error", "user_id", session.UserID, "type", out.Type, "err", err)
+				continue
+			}
+			frameType := websocket.TextMessage
+			if binaryProto {
+				if data, err = protocol.JSONToBinary(data); err != nil {
+					slog.Error("ws binary encode error", "user_id", session.UserID, "type", out.Type, "err", err)
+					continue
+				}
+				frameType = websocket.BinaryMessage
+			}
+			if wait := session.ThrottleBytesOut(len(data)); wait > 0 {
+				time.Sleep(wait)
+			}
 			_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
-			if err := conn.WriteJSON(out); err != nil {
+			if err := conn.WriteMessage(frameType, data); err != nil {
 				slog.Debug("ws write error", "user_id", session.UserID, "type", out.Type, "err", err)
 				return
 			}
+			h.channelState.AddBytesOut(len(data))
 		}
 		slog.Debug("ws send channel closed", "user_id", session.UserID)
+		// The send channel is closed on Remove, or early when the session is
+		// displaced by another login; either way the socket is done.
+		_ = conn.Close()
 	}()
 
+	joinSound, leaveSound := h.channelState.JoinSounds()
 	h.channelState.SendTo(session.UserID, protocol.Message{
-		Type:   protocol.TypeSnapshot,
-		SelfID: session.UserID,
-		Users:  snapshot,
+		Type:             protocol.TypeSnapshot,
+		SelfID:           session.UserID,
+		Username:         session.Username,
+		Users:            snapshot,
+		OwnerID:          h.channelState.OwnerID(),
+		ProtocolVersion:  protocol.ProtocolVersion,
+		Proto:            proto,
+		MaxUploadBytes:   h.channelState.MaxUploadBytes(),
+		MaxMessageLength: h.channelState.MaxMessageLength(),
+		MOTD:             h.channelState.MOTD(),
+		AllowedEmoji:     h.channelState.AllowedEmoji(),
+		SessionToken:     session.Token,
+		ICEServers:       h.channelState.ICEServers(0),
+		JoinSoundURL:     joinSound,
+		LeaveSoundURL:    leaveSound,
 	})
 	slog.Debug("ws snapshot sent", "user_id", session.UserID, "user_count", len(snapshot))
+	if msg, ok := h.channelState.Announcement(); ok {
+		h.channelState.SendTo(session.UserID, msg)
+	}
 
 	if joined, ok := h.channelState.User(session.UserID); ok {
 		h.channelState.Broadcast(protocol.Message{Type: protocol.TypeUserJoined, User: &joined}, session.UserID)
 	}
 
 	for {
-		var in protocol.Message
-		if err := conn.ReadJSON(&in); err != nil {
+		frameType, data, err := conn.ReadMessage()
+		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
 				slog.Debug("ws unexpected close", "user_id", session.UserID, "err", err)
 			}
 			return
 		}
+		h.channelState.AddBytesIn(len(data))
+		if frameType == websocket.BinaryMessage {
+			if data, err = protocol.BinaryToJSON(data); err != nil {
+				slog.Debug("ws bad binary message", "user_id", session.UserID, "err", err)
+				return
+			}
+		}
+		var in protocol.Message
+		if err := json.Unmarshal(data, &in); err != nil {
+			slog.Debug("ws bad message", "user_id", session.UserID, "err", err)
+			return
+		}
 		slog.Debug("ws recv", "user_id", session.UserID, "type", in.Type, "server_id", in.ServerID, "channel_id", in.ChannelID)
 		h.handleInbound(session.UserID, in)
 	}
@@ -144,6 +285,7 @@ func (h *Handler) handleInbound(userID string, in protocol.Message) {
 		}
 
 	case protocol.TypeDisconnectServer:
+		h.stopRecording(userID)
 		user, changed, _, err := h.channelState.DisconnectServer(userID, in.ServerID)
 		if err != nil {
 			slog.Debug("disconnect_server error", "user_id", userID, "server_id", in.ServerID, "err", err)
@@ -156,19 +298,37 @@ func (h *Handler) handleInbound(userID string, in protocol.Message) {
 		}
 
 	case protocol.TypeJoinVoice:
+		h.stopRecording(userID)
 		user, oldVoice, err := h.channelState.JoinVoice(userID, in.ServerID, in.ChannelID)
 		if err != nil {
 			slog.Debug("join_voice error", "user_id", userID, "server_id", in.ServerID, "channel_id", in.ChannelID, "err", err)
+			var cooldown *core.CooldownError
+			if errors.As(err, &cooldown) {
+				h.channelState.SendTo(userID, protocol.Message{Type: protocol.TypeError, Error: err.Error(), RetryAfterMs: cooldown.Remaining.Milliseconds()})
+				return
+			}
 			h.sendError(userID, err.Error())
 			return
 		}
+		// The joiner learns the channel's ICE servers before anyone starts
+		// negotiating with it, so its new peers use the right relays.
+		chID, _ := parseChannelID(in.ChannelID)
+		h.channelState.SendTo(userID, protocol.Message{Type: protocol.TypeICEUpdate, ChannelID: in.ChannelID, ICEServers: h.channelState.ICEServers(chID)})
 		h.channelState.SendTo(userID, protocol.Message{Type: protocol.TypeUserState, User: &user})
 		if oldVoice != nil && oldVoice.ServerID != in.ServerID {
 			h.channelState.BroadcastToServer(oldVoice.ServerID, protocol.Message{Type: protocol.TypeUserState, User: &user}, userID)
 		}
 		h.channelState.BroadcastToServer(in.ServerID, protocol.Message{Type: protocol.TypeUserState, User: &user}, userID)
+		// Anyone already recording the channel is announced to the joiner.
+		consent := h.channelState.RecordingConsentRequired()
+		for _, recorder := range h.channelState.Recorders(in.ServerID, in.ChannelID) {
+			if recorder != userID {
+				h.channelState.SendTo(userID, protocol.Message{Type: protocol.TypeRecordingStarted, UserID: recorder, ConsentRequired: consent})
+			}
+		}
 
 	case protocol.TypeDisconnectVoice, protocol.TypeDisconnectVoiceLegacy:
+		h.stopRecording(userID)
 		user, oldVoice, _ := h.channelState.DisconnectVoice(userID)
 		h.channelState.SendTo(userID, protocol.Message{Type: protocol.TypeUserState, User: &user})
 		if oldVoice != nil {
@@ -184,39 +344,126 @@ func (h *Handler) handleInbound(userID string, in protocol.Message) {
 			h.sendError(userID, "message or file is required")
 			return
 		}
+		if h.messageTooLong(userID, in.Message) {
+			return
+		}
 		if !h.channelState.CanSendText(userID, in.ServerID) {
 			slog.Debug("send_text denied", "user_id", userID, "server_id", in.ServerID)
 			h.sendError(userID, "user is not connected to server")
 			return
 		}
+		if ok, minRole := h.channelState.CanChat(userID, in.ServerID, in.ChannelID); !ok {
+			h.sendError(userID, fmt.Sprintf("you need the %s role to chat in this channel", minRole))
+			return
+		}
 		user, ok := h.channelState.User(userID)
 		if !ok {
 			h.sendError(userID, "user not found")
 			return
 		}
+		if in.ReplyTo < 0 {
+			h.sendError(userID, "invalid reply_to")
+			return
+		}
+		if in.ReplyTo > 0 && h.store != nil {
+			if _, found, err := h.store.GetMessage(context.Background(), in.ServerID, in.ReplyTo); err != nil || !found {
+				if err != nil {
+					slog.Error("load reply target", "user_id", userID, "reply_to", in.ReplyTo, "err", err)
+				}
+				h.sendError(userID, "reply target not found")
+				return
+			}
+		}
+		if err := h.channelState.CheckSlowMode(userID, in.ServerID, in.ChannelID); err != nil {
+			var slow *core.SlowModeError
+			if errors.As(err, &slow) {
+				h.channelState.SendTo(userID, protocol.Message{Type: protocol.TypeError, Error: err.Error(), RetryAfterMs: slow.Remaining.Milliseconds()})
+				return
+			}
+			h.sendError(userID, err.Error())
+			return
+		}
+		text, ok := h.filterMessage(userID, in.Message)
+		if !ok {
+			return
+		}
 		ts := time.Now().UnixMilli()
 		var msgID int64
 		if h.store != nil {
-			id, err := h.store.InsertMessage(context.Background(), in.ServerID, in.ChannelID, userID, user.Username, in.Message, ts, in.FileID, in.FileName, in.FileSize)
+			id, err := h.store.InsertMessage(context.Background(), in.ServerID, in.ChannelID, userID, user.Username, text, ts, in.FileID, in.FileName, in.FileSize, in.ReplyTo)
 			if err != nil {
 				slog.Error("persist message", "user_id", userID, "err", err)
 			} else {
 				msgID = id
 			}
 		}
-		slog.Debug("send_text", "user_id", userID, "server_id", in.ServerID, "channel_id", in.ChannelID, "msg_id", msgID, "len", len(in.Message))
+		mentioned := h.channelState.Mentioned(in.ServerID, userID, text)
+		slog.Debug("send_text", "user_id", userID, "server_id", in.ServerID, "channel_id", in.ChannelID, "msg_id", msgID, "len", len(text), "mentions", len(mentioned))
 		h.channelState.BroadcastToServer(in.ServerID, protocol.Message{
 			Type:      protocol.TypeTextMessage,
 			ServerID:  in.ServerID,
 			ChannelID: in.ChannelID,
-			Message:   in.Message,
+			Message:   text,
 			MsgID:     msgID,
 			TS:        ts,
 			User:      &user,
 			FileID:    in.FileID,
 			FileName:  in.FileName,
 			FileSize:  in.FileSize,
+			ReplyTo:   in.ReplyTo,
+			Mentions:  mentioned,
 		}, "")
+		// Mentioned users also get a direct nudge so they notice even
+		// when they are looking at another channel.
+		for _, id := range mentioned {
+			h.channelState.SendTo(id, protocol.Message{
+				Type:      protocol.TypeMention,
+				ServerID:  in.ServerID,
+				ChannelID: in.ChannelID,
+				MsgID:     msgID,
+				User:      &user,
+			})
+		}
+
+	case protocol.TypeDM:
+		targetID := strings.TrimSpace(in.UserID)
+		if targetID == "" || targetID == userID {
+			h.sendError(userID, "a recipient other than yourself is required")
+			return
+		}
+		if strings.TrimSpace(in.Message) == "" {
+			h.sendError(userID, "message is required")
+			return
+		}
+		if h.messageTooLong(userID, in.Message) {
+			return
+		}
+		// Only users sharing a server can reach each other; anyone else is
+		// reported as unknown so DMs can't probe other servers' users.
+		if !h.channelState.SharesServer(userID, targetID) {
+			slog.Debug("dm dropped: unknown target", "user_id", userID, "target_id", targetID)
+			h.sendError(userID, "user not found")
+			return
+		}
+		sender, ok := h.channelState.User(userID)
+		if !ok {
+			h.sendError(userID, "user not found")
+			return
+		}
+		text, ok := h.filterMessage(userID, in.Message)
+		if !ok {
+			return
+		}
+		dm := protocol.Message{
+			Type:    protocol.TypeDM,
+			User:    &sender,
+			UserID:  targetID,
+			Message: text,
+			TS:      time.Now().UnixMilli(),
+		}
+		slog.Debug("dm", "user_id", userID, "target_id", targetID, "len", len(text))
+		h.channelState.SendTo(targetID, dm)
+		h.channelState.SendTo(userID, dm)
 
 	case protocol.TypeCreateChannel:
 		if strings.TrimSpace(in.Message) == "" {
@@ -233,10 +480,7 @@ func (h *Handler) handleInbound(userID string, in protocol.Message) {
 			h.sendError(userID, err.Error())
 			return
 		}
-		h.channelState.BroadcastToServer(serverID, protocol.Message{
-			Type:     protocol.TypeChannelList,
-			Channels: channels,
-		}, "")
+		h.channelState.BroadcastToServer(serverID, h.channelList(serverID, channels), "")
 
 	case protocol.TypeRenameChannel:
 		if strings.TrimSpace(in.ChannelID) == "" || strings.TrimSpace(in.Message) == "" {
@@ -258,10 +502,7 @@ func (h *Handler) handleInbound(userID string, in protocol.Message) {
 			h.sendError(userID, err.Error())
 			return
 		}
-		h.channelState.BroadcastToServer(serverID, protocol.Message{
-			Type:     protocol.TypeChannelList,
-			Channels: channels,
-		}, "")
+		h.channelState.BroadcastToServer(serverID, h.channelList(serverID, channels), "")
 
 	case protocol.TypeDeleteChannel:
 		if strings.TrimSpace(in.ChannelID) == "" {
@@ -283,27 +524,44 @@ func (h *Handler) handleInbound(userID string, in protocol.Message) {
 			h.sendError(userID, err.Error())
 			return
 		}
-		h.channelState.BroadcastToServer(serverID, protocol.Message{
-			Type:     protocol.TypeChannelList,
-			Channels: channels,
-		}, "")
+		h.channelState.BroadcastToServer(serverID, h.channelList(serverID, channels), "")
 
-	case protocol.TypeGetChannels:
+	case protocol.TypeSetChannelPerms:
+		if h.channelState.Role(userID) != core.RoleOwner {
+			h.sendError(userID, "only the owner can change channel permissions")
+			return
+		}
+		if strings.TrimSpace(in.ChannelID) == "" {
+			h.sendError(userID, "channel_id is required")
+			return
+		}
 		serverID, err := h.channelState.UserServer(userID)
 		if err != nil {
 			h.sendError(userID, err.Error())
 			return
 		}
-		channels := h.channelState.Channels(serverID)
-		slog.Debug("get_channels", "user_id", userID, "server_id", serverID, "count", len(channels))
-		h.channelState.SendTo(userID, protocol.Message{
-			Type:     protocol.TypeChannelList,
-			Channels: channels,
-		})
+		chID, err := parseChannelID(in.ChannelID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		channels, muted, err := h.channelState.SetChannelPerms(serverID, chID, in.MinRoleToSpeak, in.MinRoleToChat)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		h.channelState.BroadcastToServer(serverID, h.channelList(serverID, channels), "")
+		for i := range muted {
+			h.channelState.BroadcastToServer(serverID, protocol.Message{Type: protocol.TypeUserState, User: &muted[i]}, "")
+		}
 
-	case protocol.TypeAddReaction:
-		if in.MsgID <= 0 || strings.TrimSpace(in.Emoji) == "" {
-			h.sendError(userID, "msg_id and emoji are required")
+	case protocol.TypeSetChannelBitrate:
+		if h.channelState.Role(userID) != core.RoleOwner {
+			h.sendError(userID, "only the owner can change the channel bitrate")
+			return
+		}
+		if strings.TrimSpace(in.ChannelID) == "" {
+			h.sendError(userID, "channel_id is required")
 			return
 		}
 		serverID, err := h.channelState.UserServer(userID)
@@ -311,21 +569,25 @@ func (h *Handler) handleInbound(userID string, in protocol.Message) {
 			h.sendError(userID, err.Error())
 			return
 		}
-		if h.store != nil {
-			if err := h.store.AddReaction(context.Background(), in.MsgID, userID, in.Emoji); err != nil {
-				slog.Error("add reaction", "user_id", userID, "msg_id", in.MsgID, "err", err)
-			}
+		chID, err := parseChannelID(in.ChannelID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
 		}
-		h.channelState.BroadcastToServer(serverID, protocol.Message{
-			Type:   protocol.TypeReactionAdded,
-			MsgID:  in.MsgID,
-			Emoji:  in.Emoji,
-			UserID: userID,
-		}, "")
+		channels, err := h.channelState.SetChannelBitrate(serverID, chID, in.MaxBitrateKbps)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		h.channelState.BroadcastToServer(serverID, h.channelList(serverID, channels), "")
 
-	case protocol.TypeRemoveReaction:
-		if in.MsgID <= 0 || strings.TrimSpace(in.Emoji) == "" {
-			h.sendError(userID, "msg_id and emoji are required")
+	case protocol.TypeSetSlowMode:
+		if core.RoleLevel(h.channelState.Role(userID)) < core.RoleLevel(core.RoleAdmin) {
+			h.sendError(userID, "only admins and the owner can change slow mode")
+			return
+		}
+		if strings.TrimSpace(in.ChannelID) == "" {
+			h.sendError(userID, "channel_id is required")
 			return
 		}
 		serverID, err := h.channelState.UserServer(userID)
@@ -333,21 +595,21 @@ func (h *Handler) handleInbound(userID string, in protocol.Message) {
 			h.sendError(userID, err.Error())
 			return
 		}
-		if h.store != nil {
-			if err := h.store.RemoveReaction(context.Background(), in.MsgID, userID, in.Emoji); err != nil {
-				slog.Error("remove reaction", "user_id", userID, "msg_id", in.MsgID, "err", err)
-			}
+		chID, err := parseChannelID(in.ChannelID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
 		}
-		h.channelState.BroadcastToServer(serverID, protocol.Message{
-			Type:   protocol.TypeReactionRemoved,
-			MsgID:  in.MsgID,
-			Emoji:  in.Emoji,
-			UserID: userID,
-		}, "")
+		channels, err := h.channelState.SetSlowMode(serverID, chID, in.SlowModeSeconds)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		h.channelState.BroadcastToServer(serverID, h.channelList(serverID, channels), "")
 
-	case protocol.TypeGetMessages:
-		if h.store == nil {
-			h.sendError(userID, "message history not available")
+	case protocol.TypeSetChannelLock:
+		if core.RoleLevel(h.channelState.Role(userID)) < core.RoleLevel(core.RoleModerator) {
+			h.sendError(userID, "only moderators and above can lock channels")
 			return
 		}
 		if strings.TrimSpace(in.ChannelID) == "" {
@@ -359,93 +621,1213 @@ func (h *Handler) handleInbound(userID string, in protocol.Message) {
 			h.sendError(userID, err.Error())
 			return
 		}
-		rows, err := h.store.GetMessages(context.Background(), serverID, in.ChannelID, 50)
+		chID, err := parseChannelID(in.ChannelID)
 		if err != nil {
-			h.sendError(userID, "failed to load messages")
-			slog.Error("get messages", "user_id", userID, "server_id", serverID, "channel_id", in.ChannelID, "err", err)
+			h.sendError(userID, err.Error())
 			return
 		}
-		msgs := make([]protocol.TextMessage, len(rows))
-		msgIDs := make([]int64, len(rows))
-		for i, r := range rows {
-			msgs[i] = protocol.TextMessage{
-				MsgID:     r.ID,
-				UserID:    r.UserID,
-				Username:  r.Username,
-				ChannelID: r.ChannelID,
-				Message:   r.Message,
-				TS:        r.TS,
-				FileID:    r.FileID,
-				FileName:  r.FileName,
-				FileSize:  r.FileSize,
-			}
-			msgIDs[i] = r.ID
+		channels, err := h.channelState.SetChannelLock(serverID, chID, in.Locked)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
 		}
-		// Attach reactions to messages.
-		if len(msgIDs) > 0 {
-			reactionMap, err := h.store.GetReactionsForMessages(context.Background(), msgIDs)
-			if err != nil {
-				slog.Error("get reactions for messages", "err", err)
-			} else {
-				for i := range msgs {
-					rxRows := reactionMap[msgs[i].MsgID]
-					if len(rxRows) == 0 {
-						continue
-					}
-					// Group by emoji.
-					emojiMap := make(map[string][]string)
-					var order []string
-					for _, rx := range rxRows {
-						if _, seen := emojiMap[rx.Emoji]; !seen {
-							order = append(order, rx.Emoji)
-						}
-						emojiMap[rx.Emoji] = append(emojiMap[rx.Emoji], rx.UserID)
-					}
-					for _, emoji := range order {
-						uids := emojiMap[emoji]
-						msgs[i].Reactions = append(msgs[i].Reactions, protocol.ReactionInfo{
-							Emoji:   emoji,
-							UserIDs: uids,
-							Count:   len(uids),
-						})
-					}
-				}
+		h.channelState.BroadcastToServer(serverID, h.channelList(serverID, channels), "")
+
+	case protocol.TypeSetWordFilter:
+		if h.channelState.Role(userID) != core.RoleOwner {
+			h.sendError(userID, "only the owner can change the word filter")
+			return
+		}
+		if err := h.channelState.SetWordFilter(in.Words, in.FilterAction); err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		words, action := h.channelState.WordFilter()
+		if h.store != nil {
+			if err := h.store.SetWordFilter(context.Background(), store.WordFilter{Words: words, Action: action}); err != nil {
+				slog.Error("persist word filter", "user_id", userID, "err", err)
 			}
 		}
-		slog.Debug("get_messages", "user_id", userID, "server_id", serverID, "channel_id", in.ChannelID, "count", len(msgs))
-		h.channelState.SendTo(userID, protocol.Message{
-			Type:      protocol.TypeMessageHistory,
-			ChannelID: in.ChannelID,
-			Messages:  msgs,
-		})
+		h.channelState.SendTo(userID, protocol.Message{Type: protocol.TypeWordFilter, Words: words, FilterAction: action})
 
-	case protocol.TypeSetVoiceState:
-		muted := in.Muted != nil && *in.Muted
-		deafened := in.Deafened != nil && *in.Deafened
-		user, changed := h.channelState.SetVoiceFlags(userID, muted, deafened)
-		if changed {
-			h.channelState.SendTo(userID, protocol.Message{Type: protocol.TypeUserState, User: &user})
-			if user.Voice != nil {
-				h.channelState.BroadcastToServer(user.Voice.ServerID, protocol.Message{Type: protocol.TypeUserState, User: &user}, userID)
-			}
+	case protocol.TypeSetChannelTTL:
+		if h.channelState.Role(userID) != core.RoleOwner {
+			h.sendError(userID, "only the owner can change message retention")
+			return
 		}
+		if strings.TrimSpace(in.ChannelID) == "" {
+			h.sendError(userID, "channel_id is required")
+			return
+		}
+		serverID, err := h.channelState.UserServer(userID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		chID, err := parseChannelID(in.ChannelID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		channels, err := h.channelState.SetChannelTTL(serverID, chID, in.MessageTTLSeconds)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		h.channelState.BroadcastToServer(serverID, h.channelList(serverID, channels), "")
 
-	case protocol.TypeGetServerInfo:
-		slog.Debug("get_server_info", "user_id", userID)
-		h.channelState.SendTo(userID, protocol.Message{
-			Type:       protocol.TypeServerInfo,
-			ServerName: h.channelState.ServerName(),
-		})
+	case protocol.TypeSetChannelRecordRole:
+		if h.channelState.Role(userID) != core.RoleOwner {
+			h.sendError(userID, "only the owner can change who may record")
+			return
+		}
+		if strings.TrimSpace(in.ChannelID) == "" {
+			h.sendError(userID, "channel_id is required")
+			return
+		}
+		serverID, err := h.channelState.UserServer(userID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		chID, err := parseChannelID(in.ChannelID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		channels, err := h.channelState.SetChannelRecordRole(serverID, chID, in.MinRoleToRecord)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		h.channelState.BroadcastToServer(serverID, h.channelList(serverID, channels), "")
 
-	default:
-		slog.Warn("ws unknown message type", "user_id", userID, "type", in.Type)
-		h.sendError(userID, "unsupported message type")
-	}
-}
+	case protocol.TypeStartRecording:
+		// Recordings are made locally; the client asks when it starts or
+		// joins voice while recording, and stops if told to.
+		if ok, minRole := h.channelState.CanRecord(userID); !ok {
+			h.sendError(userID, fmt.Sprintf("you need the %s role to record in this channel", minRole))
+			h.channelState.SendTo(userID, protocol.Message{Type: protocol.TypeStopRecording})
+			return
+		}
+		voice, muted, err := h.channelState.StartRecording(userID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			h.channelState.SendTo(userID, protocol.Message{Type: protocol.TypeStopRecording})
+			return
+		}
+		if voice == nil {
+			return
+		}
+		h.channelState.BroadcastToVoiceChannel(voice.ServerID, voice.ChannelID, protocol.Message{
+			Type:            protocol.TypeRecordingStarted,
+			UserID:          userID,
+			ConsentRequired: h.channelState.RecordingConsentRequired(),
+		}, userID)
+		for _, user := range muted {
+			h.channelState.SendTo(user.ID, protocol.Message{Type: protocol.TypeUserState, User: &user})
+			h.channelState.BroadcastToServer(voice.ServerID, protocol.Message{Type: protocol.TypeUserState, User: &user}, user.ID)
+		}
 
-func (h *Handler) sendError(userID, errMsg string) {
-	slog.Debug("ws sending error", "user_id", userID, "error", errMsg)
-	h.channelState.SendTo(userID, protocol.Message{Type: protocol.TypeError, Error: errMsg})
+	case protocol.TypeStopRecording:
+		h.stopRecording(userID)
+
+	case protocol.TypeRecordingConsent:
+		if in.Consent == nil {
+			h.sendError(userID, "consent is required")
+			return
+		}
+		if *in.Consent {
+			if err := h.channelState.ConsentToRecording(userID); err != nil {
+				h.sendError(userID, err.Error())
+			}
+			return
+		}
+		// Declining leaves voice, as for disconnect_voice.
+		user, oldVoice, _ := h.channelState.DisconnectVoice(userID)
+		h.channelState.SendTo(userID, protocol.Message{Type: protocol.TypeUserState, User: &user})
+		if oldVoice != nil {
+			h.channelState.BroadcastToServer(oldVoice.ServerID, protocol.Message{Type: protocol.TypeUserState, User: &user}, userID)
+		}
+
+	case protocol.TypeCreateCategory:
+		if h.channelState.Role(userID) != core.RoleOwner {
+			h.sendError(userID, "only the owner can create categories")
+			return
+		}
+		serverID, err := h.channelState.UserServer(userID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		if _, err := h.channelState.CreateCategory(serverID, in.Message); err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		h.channelState.BroadcastToServer(serverID, h.channelList(serverID, h.channelState.Channels(serverID)), "")
+
+	case protocol.TypeAssignChannelCategory:
+		if h.channelState.Role(userID) != core.RoleOwner {
+			h.sendError(userID, "only the owner can change channel categories")
+			return
+		}
+		if strings.TrimSpace(in.ChannelID) == "" {
+			h.sendError(userID, "channel_id is required")
+			return
+		}
+		serverID, err := h.channelState.UserServer(userID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		chID, err := parseChannelID(in.ChannelID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		channels, err := h.channelState.AssignChannelCategory(serverID, chID, in.CategoryID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		h.channelState.BroadcastToServer(serverID, h.channelList(serverID, channels), "")
+
+	case protocol.TypeSetAnnouncement:
+		msg, err := h.channelState.SetAnnouncement(userID, in.Message)
+		if errors.Is(err, core.ErrNotPermitted) {
+			h.sendError(userID, "only admins and the owner can set the announcement")
+			return
+		}
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		h.channelState.Broadcast(msg, "")
+
+	case protocol.TypeBanUser:
+		if in.DurationS < 0 || in.DurationS > maxBanDurationS {
+			h.sendError(userID, fmt.Sprintf("duration_s must be between 0 and %d", maxBanDurationS))
+			return
+		}
+		reason := strings.TrimSpace(in.Reason)
+		if len(reason) > maxReasonLength {
+			h.sendError(userID, fmt.Sprintf("reason must not exceed %d characters", maxReasonLength))
+			return
+		}
+		target, err := h.channelState.CheckBan(userID, in.UserID)
+		if errors.Is(err, core.ErrNotPermitted) {
+			slog.Warn("ban_user ignored: not permitted", "user_id", userID, "target", in.UserID)
+			return
+		}
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		if h.store != nil {
+			// Record the ban before the kick so the client cannot slip
+			// back in while it is being written.
+			ctx := context.Background()
+			now := time.Now()
+			ban := store.Ban{
+				IP:        target.IP,
+				Username:  target.User.Username,
+				Reason:    reason,
+				BannedBy:  target.ActorName,
+				CreatedAt: now,
+			}
+			if in.DurationS > 0 {
+				ban.ExpiresAt = now.Add(time.Duration(in.DurationS) * time.Second)
+			}
+			if err := h.store.RecordBan(ctx, ban); err != nil {
+				slog.Error("record ban", "user_id", userID, "target", in.UserID, "err", err)
+			}
+			h.recordAudit(ctx, store.AuditEntry{
+				ActorID:    userID,
+				ActorName:  target.ActorName,
+				Action:     "ban",
+				TargetID:   target.User.ID,
+				TargetName: target.User.Username,
+				Details:    fmt.Sprintf("ip=%s duration_s=%d reason=%q", target.IP, in.DurationS, reason),
+				CreatedAt:  now,
+			})
+		}
+		if _, err := h.channelState.Ban(userID, in.UserID, reason); err != nil {
+			h.sendError(userID, err.Error())
+		}
+
+	case protocol.TypeKick:
+		reason := strings.TrimSpace(in.Reason)
+		if len(reason) > maxReasonLength {
+			h.sendError(userID, fmt.Sprintf("reason must not exceed %d characters", maxReasonLength))
+			return
+		}
+		kicked, err := h.channelState.Kick(userID, in.UserID, reason)
+		if errors.Is(err, core.ErrNotPermitted) {
+			slog.Warn("kick ignored: not permitted", "user_id", userID, "target", in.UserID)
+			return
+		}
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		if h.store != nil {
+			h.recordAudit(context.Background(), store.AuditEntry{
+				ActorID:    userID,
+				ActorName:  kicked.ActorName,
+				Action:     "kick",
+				TargetID:   kicked.User.ID,
+				TargetName: kicked.User.Username,
+				Details:    fmt.Sprintf("reason=%q", reason),
+				CreatedAt:  time.Now(),
+			})
+		}
+
+	case protocol.TypeMuteUser:
+		if in.DurationS < 0 || in.DurationS > maxBanDurationS {
+			h.sendError(userID, fmt.Sprintf("duration_s must be between 0 and %d", maxBanDurationS))
+			return
+		}
+		var (
+			change core.MuteChange
+			err    error
+		)
+		action, details := "unmute_user", ""
+		if in.Muted != nil && !*in.Muted {
+			change, err = h.channelState.UnmuteUser(userID, in.UserID)
+		} else {
+			action, details = "mute_user", fmt.Sprintf("duration_s=%d", in.DurationS)
+			change, err = h.channelState.MuteUser(userID, in.UserID, time.Duration(in.DurationS)*time.Second)
+		}
+		if errors.Is(err, core.ErrNotPermitted) {
+			h.sendError(userID, "only moderators and above can mute users they outrank")
+			return
+		}
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		h.channelState.AnnounceMute(change)
+		if h.store != nil {
+			h.recordAudit(context.Background(), store.AuditEntry{
+				ActorID:    userID,
+				ActorName:  change.ActorName,
+				Action:     action,
+				TargetID:   change.User.ID,
+				TargetName: change.User.Username,
+				Details:    details,
+				CreatedAt:  time.Now(),
+			})
+		}
+
+	case protocol.TypeTransferOwner:
+		transfer, err := h.channelState.TransferOwner(userID, in.UserID)
+		if errors.Is(err, core.ErrNotPermitted) {
+			h.sendError(userID, "only the owner can transfer ownership")
+			return
+		}
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		if h.store != nil {
+			h.recordAudit(context.Background(), store.AuditEntry{
+				ActorID:    userID,
+				ActorName:  transfer.From.Username,
+				Action:     "transfer_owner",
+				TargetID:   transfer.To.ID,
+				TargetName: transfer.To.Username,
+				CreatedAt:  time.Now(),
+			})
+		}
+
+	case protocol.TypeGetChannels:
+		serverID, err := h.channelState.UserServer(userID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		channels := h.channelState.Channels(serverID)
+		slog.Debug("get_channels", "user_id", userID, "server_id", serverID, "count", len(channels))
+		h.channelState.SendTo(userID, h.channelList(serverID, channels))
+
+	case protocol.TypeAddReaction:
+		if in.MsgID <= 0 || strings.TrimSpace(in.Emoji) == "" {
+			h.sendError(userID, "msg_id and emoji are required")
+			return
+		}
+		// Only adding is checked against the allow-list, so reactions
+		// placed before it changed can still be taken back.
+		emoji, err := h.channelState.CheckReactionEmoji(in.Emoji)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		serverID, err := h.channelState.UserServer(userID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		if !h.allowReaction(userID, in.MsgID) {
+			return
+		}
+		change := protocol.Message{
+			Type:   protocol.TypeReactionAdded,
+			MsgID:  in.MsgID,
+			Emoji:  emoji,
+			UserID: userID,
+		}
+		if h.store == nil {
+			h.channelState.BroadcastToServer(serverID, change, "")
+			return
+		}
+		if err := h.store.AddReaction(context.Background(), in.MsgID, userID, emoji); err != nil {
+			slog.Error("add reaction", "user_id", userID, "msg_id", in.MsgID, "err", err)
+			h.sendError(userID, "failed to save reaction")
+			return
+		}
+		// The reacting user sees their change at once; everyone else gets
+		// the coalesced reaction_update.
+		h.channelState.SendTo(userID, change)
+		h.reactions.mark(serverID, in.MsgID)
+
+	case protocol.TypeRemoveReaction:
+		if in.MsgID <= 0 || strings.TrimSpace(in.Emoji) == "" {
+			h.sendError(userID, "msg_id and emoji are required")
+			return
+		}
+		emoji, err := core.NormalizeEmoji(in.Emoji)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		serverID, err := h.channelState.UserServer(userID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		if !h.allowReaction(userID, in.MsgID) {
+			return
+		}
+		change := protocol.Message{
+			Type:   protocol.TypeReactionRemoved,
+			MsgID:  in.MsgID,
+			Emoji:  emoji,
+			UserID: userID,
+		}
+		if h.store == nil {
+			h.channelState.BroadcastToServer(serverID, change, "")
+			return
+		}
+		if err := h.store.RemoveReaction(context.Background(), in.MsgID, userID, emoji); err != nil {
+			slog.Error("remove reaction", "user_id", userID, "msg_id", in.MsgID, "err", err)
+			h.sendError(userID, "failed to remove reaction")
+			return
+		}
+		// The reacting user sees their change at once; everyone else gets
+		// the coalesced reaction_update.
+		h.channelState.SendTo(userID, change)
+		h.reactions.mark(serverID, in.MsgID)
+
+	case protocol.TypeReadReceipt:
+		if in.MsgID <= 0 {
+			h.sendError(userID, "msg_id is required")
+			return
+		}
+		if h.store == nil {
+			h.sendError(userID, "message history not available")
+			return
+		}
+		serverID, err := h.channelState.UserServer(userID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		row, found, err := h.store.GetMessage(context.Background(), serverID, in.MsgID)
+		if err != nil {
+			slog.Error("load read receipt target", "user_id", userID, "msg_id", in.MsgID, "err", err)
+			h.sendError(userID, "failed to load message")
+			return
+		}
+		if !found {
+			h.sendError(userID, "message not found")
+			return
+		}
+		// Authors have read their own messages; repeats change nothing.
+		if h.isAuthor(row, userID) {
+			return
+		}
+		readers, changed := h.channelState.MarkRead(in.MsgID, userID)
+		if !changed {
+			return
+		}
+		h.channelState.BroadcastToServer(serverID, protocol.Message{
+			Type:      protocol.TypeMessageRead,
+			MsgID:     in.MsgID,
+			ChannelID: row.ChannelID,
+			Readers:   readers,
+		}, "")
+
+	case protocol.TypeGetMessages:
+		if h.store == nil {
+			h.sendError(userID, "message history not available")
+			return
+		}
+		if strings.TrimSpace(in.ChannelID) == "" {
+			h.sendError(userID, "channel_id is required")
+			return
+		}
+		serverID, err := h.channelState.UserServer(userID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		rows, err := h.store.GetMessages(context.Background(), serverID, in.ChannelID, messageHistoryLimit)
+		if err != nil {
+			h.sendError(userID, "failed to load messages")
+			slog.Error("get messages", "user_id", userID, "server_id", serverID, "channel_id", in.ChannelID, "err", err)
+			return
+		}
+		msgs := h.textMessages(rows)
+		slog.Debug("get_messages", "user_id", userID, "server_id", serverID, "channel_id", in.ChannelID, "count", len(msgs))
+		h.channelState.SendTo(userID, protocol.Message{
+			Type:      protocol.TypeMessageHistory,
+			ChannelID: in.ChannelID,
+			Messages:  msgs,
+		})
+
+	case protocol.TypeGetMessagesBefore:
+		if h.store == nil {
+			h.sendError(userID, "message history not available")
+			return
+		}
+		if strings.TrimSpace(in.ChannelID) == "" {
+			h.sendError(userID, "channel_id is required")
+			return
+		}
+		if in.Before < 0 {
+			h.sendError(userID, "invalid before")
+			return
+		}
+		serverID, err := h.channelState.UserServer(userID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		limit := in.Limit
+		if limit <= 0 {
+			limit = defaultMessagePage
+		}
+		limit = min(limit, maxMessagePage)
+		rows, err := h.store.GetMessagesBefore(context.Background(), serverID, in.ChannelID, in.Before, limit)
+		if err != nil {
+			h.sendError(userID, "failed to load messages")
+			slog.Error("get messages before", "user_id", userID, "server_id", serverID, "channel_id", in.ChannelID, "before", in.Before, "err", err)
+			return
+		}
+		msgs := h.textMessages(rows)
+		slog.Debug("get_messages_before", "user_id", userID, "server_id", serverID, "channel_id", in.ChannelID, "before", in.Before, "count", len(msgs))
+		h.channelState.SendTo(userID, protocol.Message{
+			Type:      protocol.TypeMessageHistory,
+			ChannelID: in.ChannelID,
+			Messages:  msgs,
+			Before:    in.Before,
+		})
+
+	case protocol.TypeResume:
+		// Without a store there is nothing to replay; a resume is a hint,
+		// so it is not worth an error.
+		if h.store == nil {
+			return
+		}
+		serverID, err := h.channelState.UserServer(userID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		channelIDs := slices.Sorted(maps.Keys(in.Seqs))
+		for _, channelID := range channelIDs {
+			seq := in.Seqs[channelID]
+			if seq <= 0 {
+				continue
+			}
+			rows, err := h.store.GetMessagesSince(context.Background(), serverID, channelID, seq, messageHistoryLimit)
+			if err != nil {
+				slog.Error("resume messages", "user_id", userID, "server_id", serverID, "channel_id", channelID, "err", err)
+				continue
+			}
+			slog.Debug("resume", "user_id", userID, "server_id", serverID, "channel_id", channelID, "after", seq, "count", len(rows))
+			for _, r := range rows {
+				h.channelState.SendTo(userID, replayedMessage(serverID, r))
+			}
+		}
+
+	case protocol.TypePurgeMessages:
+		if core.RoleLevel(h.channelState.Role(userID)) < core.RoleLevel(core.RoleModerator) {
+			h.sendError(userID, "only moderators and above can purge messages")
+			return
+		}
+		if h.store == nil {
+			h.sendError(userID, "message history not available")
+			return
+		}
+		if strings.TrimSpace(in.ChannelID) == "" {
+			h.sendError(userID, "channel_id is required")
+			return
+		}
+		if in.Count < 1 || in.Count > maxPurgeMessages {
+			h.sendError(userID, fmt.Sprintf("count must be between 1 and %d", maxPurgeMessages))
+			return
+		}
+		serverID, err := h.channelState.UserServer(userID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		ids, err := h.store.PurgeMessages(context.Background(), serverID, in.ChannelID, in.Count)
+		if err != nil {
+			h.sendError(userID, "failed to purge messages")
+			slog.Error("purge messages", "user_id", userID, "server_id", serverID, "channel_id", in.ChannelID, "err", err)
+			return
+		}
+		for _, id := range ids {
+			h.channelState.BroadcastToServer(serverID, protocol.Message{Type: protocol.TypeMessageDeleted, MsgID: id}, "")
+		}
+		actor, _ := h.channelState.User(userID)
+		h.recordAudit(context.Background(), store.AuditEntry{
+			ActorID:   userID,
+			ActorName: actor.Username,
+			Action:    "purge_messages",
+			TargetID:  in.ChannelID,
+			Details:   fmt.Sprintf("count=%d", len(ids)),
+			CreatedAt: time.Now(),
+		})
+
+	case protocol.TypeEditMessage:
+		if h.store == nil {
+			h.sendError(userID, "message history not available")
+			return
+		}
+		if in.MsgID <= 0 {
+			h.sendError(userID, "msg_id is required")
+			return
+		}
+		if strings.TrimSpace(in.Message) == "" {
+			h.sendError(userID, "message is required")
+			return
+		}
+		if h.messageTooLong(userID, in.Message) {
+			return
+		}
+		serverID, err := h.channelState.UserServer(userID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		ctx := context.Background()
+		msg, found, err := h.store.GetMessage(ctx, serverID, in.MsgID)
+		if err != nil {
+			h.sendError(userID, "failed to load message")
+			slog.Error("load message to edit", "user_id", userID, "msg_id", in.MsgID, "err", err)
+			return
+		}
+		if !found {
+			h.sendError(userID, "message not found")
+			return
+		}
+		if !h.isAuthor(msg, userID) {
+			h.sendError(userID, "only the sender can edit a message")
+			return
+		}
+		text, ok := h.filterMessage(userID, in.Message)
+		if !ok {
+			return
+		}
+		ts := time.Now().UnixMilli()
+		if found, err := h.store.EditMessage(ctx, serverID, in.MsgID, text, ts); err != nil || !found {
+			if err != nil {
+				slog.Error("edit message", "user_id", userID, "msg_id", in.MsgID, "err", err)
+			}
+			h.sendError(userID, "failed to edit message")
+			return
+		}
+		slog.Debug("edit_message", "user_id", userID, "server_id", serverID, "msg_id", in.MsgID, "len", len(text))
+		h.channelState.BroadcastToServer(serverID, protocol.Message{
+			Type:      protocol.TypeMessageEdited,
+			ChannelID: msg.ChannelID,
+			MsgID:     in.MsgID,
+			Message:   text,
+			TS:        ts,
+		}, "")
+
+	case protocol.TypeGetEditHistory:
+		if h.store == nil {
+			h.sendError(userID, "message history not available")
+			return
+		}
+		if in.MsgID <= 0 {
+			h.sendError(userID, "msg_id is required")
+			return
+		}
+		serverID, err := h.channelState.UserServer(userID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		ctx := context.Background()
+		msg, found, err := h.store.GetMessage(ctx, serverID, in.MsgID)
+		if err != nil {
+			h.sendError(userID, "failed to load message")
+			slog.Error("load message for edit history", "user_id", userID, "msg_id", in.MsgID, "err", err)
+			return
+		}
+		if !found {
+			h.sendError(userID, "message not found")
+			return
+		}
+		if !h.isAuthor(msg, userID) && h.channelState.Role(userID) != core.RoleOwner {
+			h.sendError(userID, "only the sender or the server owner can see edit history")
+			return
+		}
+		rows, err := h.store.GetEditHistory(ctx, in.MsgID)
+		if err != nil {
+			h.sendError(userID, "failed to load edit history")
+			slog.Error("get edit history", "user_id", userID, "msg_id", in.MsgID, "err", err)
+			return
+		}
+		edits := make([]protocol.MessageEdit, 0, len(rows))
+		for _, e := range rows {
+			edits = append(edits, protocol.MessageEdit{Message: e.Message, TS: e.EditedAt})
+		}
+		h.channelState.SendTo(userID, protocol.Message{
+			Type:  protocol.TypeEditHistory,
+			MsgID: in.MsgID,
+			Edits: edits,
+		})
+
+	case protocol.TypePinMessage, protocol.TypeUnpinMessage:
+		if core.RoleLevel(h.channelState.Role(userID)) < core.RoleLevel(core.RoleModerator) {
+			h.sendError(userID, "only moderators and above can pin messages")
+			return
+		}
+		if h.store == nil {
+			h.sendError(userID, "message history not available")
+			return
+		}
+		if in.MsgID <= 0 {
+			h.sendError(userID, "msg_id is required")
+			return
+		}
+		serverID, err := h.channelState.UserServer(userID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		ctx := context.Background()
+		pin := in.Type == protocol.TypePinMessage
+		var (
+			msg   store.MessageRow
+			found bool
+		)
+		if pin {
+			msg, found, err = h.store.PinMessage(ctx, serverID, in.MsgID, userID, time.Now().UnixMilli())
+		} else {
+			msg, found, err = h.store.UnpinMessage(ctx, serverID, in.MsgID)
+		}
+		if errors.Is(err, store.ErrTooManyPins) {
+			h.sendError(userID, err.Error())
+			return
+		}
+		if err != nil {
+			h.sendError(userID, "failed to update pin")
+			slog.Error("update pin", "user_id", userID, "msg_id", in.MsgID, "pin", pin, "err", err)
+			return
+		}
+		if !found {
+			if pin {
+				h.sendError(userID, "message not found")
+			} else {
+				h.sendError(userID, "message is not pinned")
+			}
+			return
+		}
+		out := protocol.Message{Type: protocol.TypeMessageUnpinned, ChannelID: msg.ChannelID, MsgID: in.MsgID, UserID: userID}
+		if pin {
+			out.Type = protocol.TypeMessagePinned
+		}
+		slog.Debug(in.Type, "user_id", userID, "server_id", serverID, "channel_id", msg.ChannelID, "msg_id", in.MsgID)
+		h.channelState.BroadcastToServer(serverID, out, "")
+
+	case protocol.TypeGetPinned:
+		if h.store == nil {
+			h.sendError(userID, "message history not available")
+			return
+		}
+		if strings.TrimSpace(in.ChannelID) == "" {
+			h.sendError(userID, "channel_id is required")
+			return
+		}
+		serverID, err := h.channelState.UserServer(userID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		rows, err := h.store.GetPinnedMessages(context.Background(), serverID, in.ChannelID)
+		if err != nil {
+			h.sendError(userID, "failed to load pinned messages")
+			slog.Error("get pinned messages", "user_id", userID, "server_id", serverID, "channel_id", in.ChannelID, "err", err)
+			return
+		}
+		msgRows := make([]store.MessageRow, len(rows))
+		for i, r := range rows {
+			msgRows[i] = r.MessageRow
+		}
+		msgs := h.textMessages(msgRows)
+		pins := make([]protocol.PinnedMessage, len(rows))
+		for i, r := range rows {
+			pins[i] = protocol.PinnedMessage{TextMessage: msgs[i], PinnedBy: r.PinnedBy, PinnedAt: r.PinnedAt}
+		}
+		h.channelState.SendTo(userID, protocol.Message{
+			Type:      protocol.TypePinnedList,
+			ChannelID: in.ChannelID,
+			Pins:      pins,
+		})
+
+	case protocol.TypeGetAuditLog:
+		if core.RoleLevel(h.channelState.Role(userID)) < core.RoleLevel(core.RoleAdmin) {
+			slog.Warn("get_audit_log ignored: not permitted", "user_id", userID)
+			return
+		}
+		if h.store == nil {
+			h.sendError(userID, "audit log not available")
+			return
+		}
+		rows, err := h.store.AuditLog(context.Background(), auditLogLimit)
+		if err != nil {
+			h.sendError(userID, "failed to load audit log")
+			slog.Error("get audit log", "user_id", userID, "err", err)
+			return
+		}
+		entries := make([]protocol.AuditEntry, 0, len(rows))
+		for _, e := range rows {
+			entries = append(entries, auditEntry(e))
+		}
+		h.channelState.SendTo(userID, protocol.Message{Type: protocol.TypeAuditLog, Audit: entries})
+
+	case protocol.TypeGetBans:
+		if core.RoleLevel(h.channelState.Role(userID)) < core.RoleLevel(core.RoleAdmin) {
+			slog.Warn("get_bans ignored: not permitted", "user_id", userID)
+			return
+		}
+		if h.store == nil {
+			h.sendError(userID, "bans not available")
+			return
+		}
+		h.sendBanList(userID)
+
+	case protocol.TypeUnban:
+		if core.RoleLevel(h.channelState.Role(userID)) < core.RoleLevel(core.RoleAdmin) {
+			slog.Warn("unban ignored: not permitted", "user_id", userID, "ban_id", in.BanID)
+			return
+		}
+		if h.store == nil {
+			h.sendError(userID, "bans not available")
+			return
+		}
+		if in.BanID <= 0 {
+			h.sendError(userID, "ban_id is required")
+			return
+		}
+		ctx := context.Background()
+		ban, ok, err := h.store.RemoveBan(ctx, in.BanID)
+		if err != nil {
+			h.sendError(userID, "failed to remove ban")
+			slog.Error("remove ban", "user_id", userID, "ban_id", in.BanID, "err", err)
+			return
+		}
+		if !ok {
+			h.sendError(userID, "ban not found")
+			return
+		}
+		actor, _ := h.channelState.User(userID)
+		h.recordAudit(ctx, store.AuditEntry{
+			ActorID:    userID,
+			ActorName:  actor.Username,
+			Action:     "unban",
+			TargetName: ban.Username,
+			Details:    fmt.Sprintf("ip=%s ban_id=%d", ban.IP, ban.ID),
+			CreatedAt:  time.Now(),
+		})
+		h.sendBanList(userID)
+
+	case protocol.TypeGetThread:
+		if h.store == nil {
+			h.sendError(userID, "message history not available")
+			return
+		}
+		if in.MsgID <= 0 {
+			h.sendError(userID, "msg_id is required")
+			return
+		}
+		serverID, err := h.channelState.UserServer(userID)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		rows, err := h.store.GetReplyThread(context.Background(), serverID, in.MsgID, maxThreadDepth)
+		if err != nil {
+			h.sendError(userID, "failed to load thread")
+			slog.Error("get thread", "user_id", userID, "server_id", serverID, "msg_id", in.MsgID, "err", err)
+			return
+		}
+		if len(rows) == 0 {
+			h.sendError(userID, "message not found")
+			return
+		}
+		msgs := h.textMessages(rows)
+		slog.Debug("get_thread", "user_id", userID, "server_id", serverID, "msg_id", in.MsgID, "count", len(msgs))
+		h.channelState.SendTo(userID, protocol.Message{
+			Type:     protocol.TypeThread,
+			MsgID:    in.MsgID,
+			Messages: msgs,
+		})
+
+	case protocol.TypeSetVoiceState:
+		muted := in.Muted != nil && *in.Muted
+		deafened := in.Deafened != nil && *in.Deafened
+		if !muted && h.channelState.ServerMuted(userID) {
+			h.sendError(userID, "you have been muted by a moderator")
+			muted = true
+		}
+		if !muted {
+			if ok, minRole := h.channelState.CanSpeak(userID); !ok {
+				h.sendError(userID, fmt.Sprintf("you need the %s role to speak in this channel", minRole))
+				muted = true
+			}
+		}
+		if !muted && h.channelState.AwaitingConsent(userID) {
+			h.sendError(userID, "accept the recording in this channel to speak")
+			muted = true
+		}
+		user, changed := h.channelState.SetVoiceFlags(userID, muted, deafened)
+		if changed {
+			h.channelState.SendTo(userID, protocol.Message{Type: protocol.TypeUserState, User: &user})
+			if user.Voice != nil {
+				h.channelState.BroadcastToServer(user.Voice.ServerID, protocol.Message{Type: protocol.TypeUserState, User: &user}, userID)
+			}
+		}
+
+	case protocol.TypeMonitorChannel, protocol.TypeUnmonitorChannel:
+		if strings.TrimSpace(in.ChannelID) == "" {
+			h.sendError(userID, "channel_id is required")
+			return
+		}
+		var (
+			user protocol.User
+			err  error
+		)
+		if in.Type == protocol.TypeMonitorChannel {
+			user, err = h.channelState.MonitorChannel(userID, in.ChannelID)
+		} else {
+			user, err = h.channelState.UnmonitorChannel(userID, in.ChannelID)
+		}
+		if errors.Is(err, core.ErrNotPermitted) {
+			h.sendError(userID, "only moderators and above can monitor other channels")
+			return
+		}
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		slog.Debug(in.Type, "user_id", userID, "channel_id", in.ChannelID)
+		h.channelState.BroadcastToServer(user.Voice.ServerID, protocol.Message{Type: protocol.TypeUserState, User: &user}, "")
+
+	case protocol.TypeSetStatus:
+		user, changed, err := h.channelState.SetStatus(userID, in.Status)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		if changed {
+			h.channelState.Broadcast(protocol.Message{Type: protocol.TypeUserStatus, User: &user}, "")
+		}
+
+	case protocol.TypeRenameUser:
+		user, changed, err := h.channelState.RenameUser(userID, in.Username)
+		if err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		if changed {
+			h.channelState.Broadcast(protocol.Message{Type: protocol.TypeUserRenamed, User: &user}, "")
+		}
+
+	case protocol.TypeVoiceActivity:
+		h.channelState.MarkVoiceActivity(userID)
+
+	case protocol.TypeSpeaking:
+		speaking := in.Speaking != nil && *in.Speaking
+		voice, ok := h.channelState.SetSpeaking(userID, speaking)
+		if !ok {
+			return
+		}
+		h.channelState.BroadcastToVoiceChannel(voice.ServerID, voice.ChannelID, protocol.Message{
+			Type:     protocol.TypeSpeaking,
+			UserID:   userID,
+			Speaking: &speaking,
+		}, userID)
+
+	case protocol.TypeTyping:
+		user, ok := h.channelState.MarkTyping(userID, in.ServerID, in.ChannelID)
+		if !ok {
+			return
+		}
+		// Everyone on the server gets it; clients only show it while
+		// viewing that channel.
+		h.channelState.BroadcastToServer(in.ServerID, protocol.Message{
+			Type:      protocol.TypeUserTyping,
+			User:      &user,
+			ServerID:  in.ServerID,
+			ChannelID: in.ChannelID,
+		}, userID)
+
+	case protocol.TypeSoundboard:
+		if err := h.channelState.PlaySoundboard(userID, strings.TrimSpace(in.ClipID)); err != nil {
+			var limited *core.SoundboardRateError
+			if errors.As(err, &limited) {
+				h.channelState.SendTo(userID, protocol.Message{Type: protocol.TypeError, Error: err.Error(), RetryAfterMs: limited.Remaining.Milliseconds()})
+				return
+			}
+			h.sendError(userID, err.Error())
+		}
+
+	case protocol.TypeSetRole:
+		if h.channelState.Role(userID) != core.RoleOwner {
+			h.sendError(userID, "only the owner can assign roles")
+			return
+		}
+		role := strings.ToUpper(strings.TrimSpace(in.Role))
+		if err := h.channelState.SetRole(in.UserID, role); err != nil {
+			h.sendError(userID, err.Error())
+			return
+		}
+		h.channelState.Broadcast(protocol.Message{Type: protocol.TypeRoleChanged, UserID: in.UserID, Role: role}, "")
+		if h.store != nil {
+			actor, _ := h.channelState.User(userID)
+			target, _ := h.channelState.User(in.UserID)
+			h.recordAudit(context.Background(), store.AuditEntry{
+				ActorID:    userID,
+				ActorName:  actor.Username,
+				Action:     "set_role",
+				TargetID:   in.UserID,
+				TargetName: target.Username,
+				Details:    "role=" + role,
+				CreatedAt:  time.Now(),
+			})
+		}
+
+	case protocol.TypeGetPermissions:
+		perms := h.channelState.Permissions()
+		reply := protocol.Message{
+			Type:    protocol.TypePermissions,
+			OwnerID: perms.OwnerID,
+			Roles:   perms.Roles,
+		}
+		// Everyone may see roles; who else holds admin powers is for admins.
+		if core.RoleLevel(h.channelState.Role(userID)) >= core.RoleLevel(core.RoleAdmin) {
+			reply.CoOwners = perms.CoOwners
+		}
+		h.channelState.SendTo(userID, reply)
+
+	case protocol.TypeGetServerInfo:
+		slog.Debug("get_server_info", "user_id", userID)
+		h.channelState.SendTo(userID, protocol.Message{
+			Type:       protocol.TypeServerInfo,
+			ServerName: h.channelState.ServerName(),
+		})
+
+	default:
+		slog.Warn("ws unknown message type", "user_id", userID, "type", in.Type)
+		h.sendError(userID, "unsupported message type")
+	}
+}
+
+// stopRecording tells userID's voice channel that they no longer record
+// it. It is called when they stop and before they leave the channel.
+func (h *Handler) stopRecording(userID string) {
+	voice, ok := h.channelState.StopRecording(userID)
+	if !ok {
+		return
+	}
+	h.channelState.BroadcastToVoiceChannel(voice.ServerID, voice.ChannelID, protocol.Message{
+		Type:   protocol.TypeRecordingStopped,
+		UserID: userID,
+	}, userID)
+}
+
+// textMessages converts persisted rows to wire messages with their
+// reactions attached.
+func (h *Handler) textMessages(rows []store.MessageRow) []protocol.TextMessage {
+	msgs := make([]protocol.TextMessage, len(rows))
+	msgIDs := make([]int64, len(rows))
+	for i, r := range rows {
+		msgs[i] = protocol.TextMessage{
+			MsgID:     r.ID,
+			UserID:    r.UserID,
+			Username:  r.Username,
+			ChannelID: r.ChannelID,
+			Message:   r.Message,
+			TS:        r.TS,
+			FileID:    r.FileID,
+			FileName:  r.FileName,
+			FileSize:  r.FileSize,
+			ReplyTo:   r.ReplyTo,
+		}
+		msgIDs[i] = r.ID
+	}
+	if len(msgIDs) == 0 {
+		return msgs
+	}
+	reactionMap, err := h.store.GetReactionsForMessages(context.Background(), msgIDs)
+	if err != nil {
+		slog.Error("get reactions for messages", "err", err)
+		return msgs
+	}
+	for i := range msgs {
+		if rxRows := reactionMap[msgs[i].MsgID]; len(rxRows) > 0 {
+			msgs[i].Reactions = groupReactions(rxRows)
+		}
+	}
+	return msgs
+}
+
+// replayedMessage rebuilds the text_message originally broadcast for a
+// persisted row. Mentions are left out so a replay does not notify again.
+func replayedMessage(serverID string, r store.MessageRow) protocol.Message {
+	return protocol.Message{
+		Type:      protocol.TypeTextMessage,
+		ServerID:  serverID,
+		ChannelID: r.ChannelID,
+		Message:   r.Message,
+		MsgID:     r.ID,
+		TS:        r.TS,
+		User:      &protocol.User{ID: r.UserID, Username: r.Username},
+		FileID:    r.FileID,
+		FileName:  r.FileName,
+		FileSize:  r.FileSize,
+		ReplyTo:   r.ReplyTo,
+	}
+}
+
+// isAuthor reports whether userID wrote row. User IDs restart at u1 when the
+// server restarts, so messages stored by an earlier process have no author
+// among the connected users.
+func (h *Handler) isAuthor(row store.MessageRow, userID string) bool {
+	return row.UserID == userID && row.BootID == h.store.BootID()
+}
+
+// recordAudit persists a moderation action and streams it to connected
+// admins and the owner as audit_entry.
+func (h *Handler) recordAudit(ctx context.Context, e store.AuditEntry) {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	if err := h.store.RecordAudit(ctx, e); err != nil {
+		slog.Error("record audit entry", "action", e.Action, "err", err)
+		return
+	}
+	h.channelState.BroadcastToRole(core.RoleAdmin, protocol.Message{
+		Type:  protocol.TypeAuditEntry,
+		Audit: []protocol.AuditEntry{auditEntry(e)},
+	})
+}
+
+func auditEntry(e store.AuditEntry) protocol.AuditEntry {
+	return protocol.AuditEntry{
+		ActorID:    e.ActorID,
+		ActorName:  e.ActorName,
+		Action:     e.Action,
+		TargetID:   e.TargetID,
+		TargetName: e.TargetName,
+		Details:    e.Details,
+		TS:         e.CreatedAt.UnixMilli(),
+	}
+}
+
+// sendBanList sends userID the active bans as a ban_list.
+func (h *Handler) sendBanList(userID string) {
+	bans, err := h.store.GetBans(context.Background(), time.Now())
+	if err != nil {
+		h.sendError(userID, "failed to load bans")
+		slog.Error("get bans", "user_id", userID, "err", err)
+		return
+	}
+	entries := make([]protocol.BanEntry, 0, len(bans))
+	for _, b := range bans {
+		e := protocol.BanEntry{
+			ID:       b.ID,
+			IP:       b.IP,
+			Username: b.Username,
+			Reason:   b.Reason,
+			BannedBy: b.BannedBy,
+			TS:       b.CreatedAt.UnixMilli(),
+		}
+		if !b.ExpiresAt.IsZero() {
+			e.ExpiresAt = b.ExpiresAt.UnixMilli()
+		}
+		entries = append(entries, e)
+	}
+	h.channelState.SendTo(userID, protocol.Message{Type: protocol.TypeBanList, Bans: entries})
+}
+
+// messageTooLong reports whether message exceeds the server's chat length
+// limit, telling userID so when it does.
+func (h *Handler) messageTooLong(userID, message string) bool {
+	limit := h.channelState.MaxMessageLength()
+	if len(message) <= limit {
+		return false
+	}
+	h.sendError(userID, fmt.Sprintf("message must not exceed %d characters", limit))
+	return true
+}
+
+// filterMessage applies the word filter to a chat message or DM from userID.
+// ok is false when the filter blocks it, in which case userID has been
+// told why.
+func (h *Handler) filterMessage(userID, message string) (string, bool) {
+	text, err := h.channelState.FilterMessage(message)
+	if err != nil {
+		slog.Debug("message blocked by word filter", "user_id", userID)
+		h.sendError(userID, err.Error())
+		return "", false
+	}
+	return text, true
+}
+
+func (h *Handler) sendError(userID, errMsg string) {
+	slog.Debug("ws sending error", "user_id", userID, "error", errMsg)
+	h.channelState.SendTo(userID, protocol.Message{Type: protocol.TypeError, Error: errMsg})
+}
+
+// banMessage is the error shown to a banned client trying to connect.
+func banMessage(b store.Ban) string {
+	msg := "you are banned from this server"
+	if !b.ExpiresAt.IsZero() {
+		msg += " until " + b.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	if b.Reason != "" {
+		msg += ": " + b.Reason
+	}
+	return msg
+}
+
+// channelList builds a channel_list for serverID, carrying its categories
+// so clients can group the channels.
+func (h *Handler) channelList(serverID string, channels []protocol.Channel) protocol.Message {
+	return protocol.Message{
+		Type:       protocol.TypeChannelList,
+		Channels:   channels,
+		Categories: h.channelState.Categories(serverID),
+	}
 }
 
 func parseChannelID(s string) (int64, error) {
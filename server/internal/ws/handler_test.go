@@ -1,15 +1,22 @@
 package ws
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"net"
+	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"bken/server/internal/core"
 	"bken/server/internal/protocol"
+	"bken/server/internal/store"
 
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
@@ -112,6 +119,350 @@ func TestCreateChannelRequiresServerConnection(t *testing.T) {
 	})
 }
 
+func TestHelloVersionMismatchRejected(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	conn, _, err := websocket.DefaultDialer.Dial(baseURL+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dial ws: %v", err)
+	}
+	defer conn.Close()
+
+	writeMsg(t, conn, protocol.Message{Type: protocol.TypeHello, Username: "alice", ProtocolVersion: protocol.ProtocolVersion + 1})
+	msg := readUntil(t, conn, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeVersionMismatch
+	})
+	if msg.ProtocolVersion != protocol.ProtocolVersion {
+		t.Fatalf("expected required version %d, got %d", protocol.ProtocolVersion, msg.ProtocolVersion)
+	}
+
+	// The server closes the connection after the mismatch notice.
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Fatal("expected connection to be closed after version_mismatch")
+	}
+}
+
+func TestDrainWarnsClientsAndRefusesNewConnections(t *testing.T) {
+	channelState := core.NewChannelState("")
+	e := echo.New()
+	NewHandler(channelState, nil).Register(e)
+	httpServer := httptest.NewServer(e)
+	defer httpServer.Close()
+	baseURL := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+
+	channelState.Drain(time.Minute)
+	msg := readUntil(t, alice, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeServerShutdown
+	})
+	if msg.GraceMs != time.Minute.Milliseconds() {
+		t.Fatalf("expected grace_ms %d, got %d", time.Minute.Milliseconds(), msg.GraceMs)
+	}
+
+	conn, resp, err := websocket.DefaultDialer.Dial(baseURL+"/ws", nil)
+	if err == nil {
+		conn.Close()
+		t.Fatal("expected the upgrade to be refused while draining")
+	}
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 while draining, got %v", resp)
+	}
+}
+
+func TestConnectRateRefusesWithTooManyRequests(t *testing.T) {
+	channelState := core.NewChannelState("")
+	if err := channelState.SetConnectRate(1); err != nil {
+		t.Fatalf("set connect rate: %v", err)
+	}
+	e := echo.New()
+	NewHandler(channelState, nil).Register(e)
+	httpServer := httptest.NewServer(e)
+	defer httpServer.Close()
+	baseURL := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+
+	conn, resp, err := websocket.DefaultDialer.Dial(baseURL+"/ws", nil)
+	if err == nil {
+		conn.Close()
+		t.Fatal("expected the second connection in a minute to be refused")
+	}
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 over the connect rate, got %v", resp)
+	}
+}
+
+func TestHelloMatchingVersionReceivesSnapshotVersion(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	conn, _, err := websocket.DefaultDialer.Dial(baseURL+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dial ws: %v", err)
+	}
+	defer conn.Close()
+
+	writeMsg(t, conn, protocol.Message{Type: protocol.TypeHello, Username: "alice", ProtocolVersion: protocol.ProtocolVersion})
+	snap := readUntil(t, conn, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeSnapshot
+	})
+	if snap.ProtocolVersion != protocol.ProtocolVersion {
+		t.Fatalf("expected snapshot protocol_version %d, got %d", protocol.ProtocolVersion, snap.ProtocolVersion)
+	}
+}
+
+func TestHelloBinaryProtoSwitchesToBinaryFrames(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	conn, _, err := websocket.DefaultDialer.Dial(baseURL+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dial ws: %v", err)
+	}
+	defer conn.Close()
+
+	readBinaryMsg := func() protocol.Message {
+		t.Helper()
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		frameType, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if frameType != websocket.BinaryMessage {
+			t.Fatalf("expected binary frame, got type %d: %s", frameType, data)
+		}
+		js, err := protocol.BinaryToJSON(data)
+		if err != nil {
+			t.Fatalf("decode binary: %v", err)
+		}
+		var msg protocol.Message
+		if err := json.Unmarshal(js, &msg); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		return msg
+	}
+
+	writeMsg(t, conn, protocol.Message{Type: protocol.TypeHello, Username: "alice", Proto: protocol.ProtoBinary})
+	snap := readBinaryMsg()
+	if snap.Type != protocol.TypeSnapshot || snap.Proto != protocol.ProtoBinary {
+		t.Fatalf("expected binary snapshot acknowledging proto, got %+v", snap)
+	}
+
+	// The server reads binary frames too.
+	js, _ := json.Marshal(protocol.Message{Type: protocol.TypePing, TS: 42})
+	data, err := protocol.JSONToBinary(js)
+	if err != nil {
+		t.Fatalf("encode ping: %v", err)
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(2 * time.Second))
+	if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+		t.Fatalf("write ping: %v", err)
+	}
+	for {
+		msg := readBinaryMsg()
+		if msg.Type == protocol.TypePong {
+			if msg.TS != 42 {
+				t.Fatalf("expected pong ts 42, got %d", msg.TS)
+			}
+			break
+		}
+	}
+}
+
+func TestHelloWithoutProtoStaysJSON(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	conn, snap := connectClient(t, baseURL, "alice")
+	defer conn.Close()
+	if snap.Proto != "" {
+		t.Fatalf("expected no proto in snapshot, got %q", snap.Proto)
+	}
+}
+
+func TestRecordingConsentGatesSpeaking(t *testing.T) {
+	channelState := core.NewChannelState("")
+	channelState.SetRecordingConsent(true)
+	e := echo.New()
+	NewHandler(channelState, nil).Register(e)
+	httpServer := httptest.NewServer(e)
+	defer httpServer.Close()
+	baseURL := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+
+	alice, snap := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, bobSnap := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+	aliceID := findUserID(t, snap.Users, "alice")
+	bobID := findUserID(t, bobSnap.Users, "bob")
+
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+	}
+	for _, c := range []struct {
+		conn *websocket.Conn
+		id   string
+	}{{alice, aliceID}, {bob, bobID}} {
+		writeMsg(t, c.conn, protocol.Message{Type: protocol.TypeJoinVoice, ServerID: "srv-1", ChannelID: "chan-a"})
+		readUntil(t, c.conn, func(m protocol.Message) bool {
+			return m.Type == protocol.TypeUserState && m.User != nil && m.User.ID == c.id && m.User.Voice != nil
+		})
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeStartRecording})
+	started := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeRecordingStarted })
+	if started.UserID != aliceID || !started.ConsentRequired {
+		t.Fatalf("unexpected recording_started: %+v", started)
+	}
+	readUntil(t, bob, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeUserState && m.User != nil && m.User.Voice != nil && m.User.Voice.Muted
+	})
+
+	// Unmuting waits for consent.
+	unmute := false
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeSetVoiceState, Muted: &unmute})
+	errMsg := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if !strings.Contains(errMsg.Error, "recording") {
+		t.Fatalf("unexpected error: %+v", errMsg)
+	}
+
+	accept := true
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeRecordingConsent, Consent: &accept})
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeSetVoiceState, Muted: &unmute})
+	readUntil(t, bob, func(m protocol.Message) bool {
+		if m.Type == protocol.TypeError {
+			t.Fatalf("unmute after consent failed: %+v", m)
+		}
+		return m.Type == protocol.TypeUserState && m.User != nil && m.User.Voice != nil && !m.User.Voice.Muted
+	})
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeStopRecording})
+	stopped := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeRecordingStopped })
+	if stopped.UserID != aliceID {
+		t.Fatalf("unexpected recording_stopped: %+v", stopped)
+	}
+}
+
+func TestDecliningRecordingLeavesVoice(t *testing.T) {
+	channelState := core.NewChannelState("")
+	channelState.SetRecordingConsent(true)
+	e := echo.New()
+	NewHandler(channelState, nil).Register(e)
+	httpServer := httptest.NewServer(e)
+	defer httpServer.Close()
+	baseURL := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+
+	alice, snap := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	aliceID := findUserID(t, snap.Users, "alice")
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeJoinVoice, ServerID: "srv-1", ChannelID: "chan-a"})
+	readUntil(t, alice, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeUserState && m.User != nil && m.User.Voice != nil
+	})
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeStartRecording})
+
+	// Someone joining mid-recording hears about it and starts muted.
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeJoinVoice, ServerID: "srv-1", ChannelID: "chan-a"})
+	joined := readUntil(t, bob, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeUserState && m.User != nil && m.User.ID != aliceID && m.User.Voice != nil
+	})
+	if !joined.User.Voice.Muted {
+		t.Fatalf("expected bob to join muted, got %+v", joined.User.Voice)
+	}
+	started := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeRecordingStarted })
+	if started.UserID != aliceID {
+		t.Fatalf("unexpected recording_started: %+v", started)
+	}
+
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeRecordingConsent})
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+
+	decline := false
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeRecordingConsent, Consent: &decline})
+	readUntil(t, bob, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeUserState && m.User != nil && m.User.Voice == nil
+	})
+}
+
+func TestAddReactionRejectsNonEmoji(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+	readUntil(t, alice, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeUserState
+	})
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeAddReaction, MsgID: 1, Emoji: "not an emoji"})
+	msg := readUntil(t, alice, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeError || m.Type == protocol.TypeReactionAdded
+	})
+	if msg.Type != protocol.TypeError {
+		t.Fatalf("expected error for non-emoji reaction, got %s", msg.Type)
+	}
+}
+
+func TestAddReactionBroadcastsNormalizedEmoji(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+	readUntil(t, alice, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeUserState
+	})
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeAddReaction, MsgID: 1, Emoji: "👍\uFE0F"})
+	msg := readUntil(t, alice, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeReactionAdded
+	})
+	if msg.Emoji != "👍" {
+		t.Fatalf("expected normalized emoji %q, got %q", "👍", msg.Emoji)
+	}
+}
+
+func TestSendTextRelaysFileAttachment(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, conn, func(m protocol.Message) bool {
+			return m.Type == protocol.TypeUserState
+		})
+	}
+
+	// A file-only message (no text) must be accepted and relayed intact.
+	writeMsg(t, alice, protocol.Message{
+		Type:      protocol.TypeSendText,
+		ServerID:  "srv-1",
+		ChannelID: "1",
+		FileID:    "blob-1",
+		FileName:  "notes.txt",
+		FileSize:  2048,
+	})
+	msg := readUntil(t, bob, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeTextMessage
+	})
+	if msg.FileID != "blob-1" || msg.FileName != "notes.txt" || msg.FileSize != 2048 {
+		t.Fatalf("file fields not relayed: %+v", msg)
+	}
+	if msg.User == nil || msg.User.Username != "alice" {
+		t.Fatalf("expected sender alice, got %+v", msg.User)
+	}
+}
+
 func startTestServer(t *testing.T) (*httptest.Server, string) {
 	t.Helper()
 
@@ -125,6 +476,46 @@ func startTestServer(t *testing.T) (*httptest.Server, string) {
 	return httpServer, wsURL
 }
 
+// startTestServerWithStore is startTestServer backed by a temporary SQLite
+// store, for features that need persistence.
+func startTestServerWithStore(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+
+	st, err := store.Open(filepath.Join(t.TempDir(), "bken.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	channelState := core.NewChannelState("")
+	e := echo.New()
+	NewHandler(channelState, st).Register(e)
+	httpServer := httptest.NewServer(e)
+	t.Cleanup(httpServer.Close)
+
+	wsURL := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+	return httpServer, wsURL
+}
+
+// startTestServerWithAuditStore is startTestServerWithStore for tests that
+// inspect the store afterwards.
+func startTestServerWithAuditStore(t *testing.T) (*store.Store, string) {
+	t.Helper()
+
+	st, err := store.Open(filepath.Join(t.TempDir(), "bken.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	e := echo.New()
+	NewHandler(core.NewChannelState(""), st).Register(e)
+	httpServer := httptest.NewServer(e)
+	t.Cleanup(httpServer.Close)
+
+	return st, "ws" + strings.TrimPrefix(httpServer.URL, "http")
+}
+
 func connectClient(t *testing.T, baseWSURL, username string) (*websocket.Conn, protocol.Message) {
 	t.Helper()
 
@@ -195,3 +586,1728 @@ func hasServer(u *protocol.User, serverID string) bool {
 	}
 	return false
 }
+
+func TestSnapshotCarriesMOTD(t *testing.T) {
+	channelState := core.NewChannelState("")
+	e := echo.New()
+	NewHandler(channelState, nil).Register(e)
+	httpServer := httptest.NewServer(e)
+	defer httpServer.Close()
+	wsURL := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+
+	alice, snap := connectClient(t, wsURL, "alice")
+	alice.Close()
+	if snap.MOTD != "" {
+		t.Fatalf("snapshot motd = %q with none set", snap.MOTD)
+	}
+
+	if err := channelState.SetMOTD("read the rules in #welcome"); err != nil {
+		t.Fatalf("set motd: %v", err)
+	}
+	bob, snap := connectClient(t, wsURL, "bob")
+	defer bob.Close()
+	if snap.MOTD != "read the rules in #welcome" {
+		t.Fatalf("snapshot motd = %q", snap.MOTD)
+	}
+}
+
+func TestSnapshotCarriesJoinSounds(t *testing.T) {
+	channelState := core.NewChannelState("")
+	if err := channelState.SetJoinSounds("/api/blobs/chime", ""); err != nil {
+		t.Fatalf("set join sounds: %v", err)
+	}
+	e := echo.New()
+	NewHandler(channelState, nil).Register(e)
+	httpServer := httptest.NewServer(e)
+	defer httpServer.Close()
+
+	alice, snap := connectClient(t, "ws"+strings.TrimPrefix(httpServer.URL, "http"), "alice")
+	defer alice.Close()
+	if snap.JoinSoundURL != "/api/blobs/chime" || snap.LeaveSoundURL != "" {
+		t.Fatalf("unexpected snapshot sounds %q, %q", snap.JoinSoundURL, snap.LeaveSoundURL)
+	}
+}
+
+func TestMaxMessageLengthIsConfigurable(t *testing.T) {
+	channelState := core.NewChannelState("")
+	e := echo.New()
+	NewHandler(channelState, nil).Register(e)
+	httpServer := httptest.NewServer(e)
+	defer httpServer.Close()
+	wsURL := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+
+	alice, snap := connectClient(t, wsURL, "alice")
+	defer alice.Close()
+	if snap.MaxMessageLength != core.DefaultMaxMessageLength {
+		t.Fatalf("snapshot max_message_length = %d, want %d", snap.MaxMessageLength, core.DefaultMaxMessageLength)
+	}
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+	readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+
+	long := strings.Repeat("x", 1500)
+	send := protocol.Message{Type: protocol.TypeSendText, ServerID: "srv-1", ChannelID: "1", Message: long}
+	writeMsg(t, alice, send)
+	rejected := readUntil(t, alice, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeError || m.Type == protocol.TypeTextMessage
+	})
+	if rejected.Type != protocol.TypeError || !strings.Contains(rejected.Error, "500") {
+		t.Fatalf("default limit should reject 1500 characters, got %+v", rejected)
+	}
+
+	if err := channelState.SetMaxMessageLength(2000); err != nil {
+		t.Fatalf("set max message length: %v", err)
+	}
+	bob, snap := connectClient(t, wsURL, "bob")
+	defer bob.Close()
+	if snap.MaxMessageLength != 2000 {
+		t.Fatalf("snapshot max_message_length = %d, want 2000", snap.MaxMessageLength)
+	}
+	writeMsg(t, alice, send)
+	relayed := readUntil(t, alice, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeError || m.Type == protocol.TypeTextMessage
+	})
+	if relayed.Type != protocol.TypeTextMessage || relayed.Message != long {
+		t.Fatalf("1500 characters should be relayed under a 2000 limit, got %+v", relayed.Type)
+	}
+}
+
+func TestReactionAllowList(t *testing.T) {
+	channelState := core.NewChannelState("")
+	if err := channelState.SetAllowedEmoji([]string{"👍", "🎉"}); err != nil {
+		t.Fatalf("set allowed emoji: %v", err)
+	}
+	e := echo.New()
+	NewHandler(channelState, nil).Register(e)
+	httpServer := httptest.NewServer(e)
+	defer httpServer.Close()
+
+	alice, snap := connectClient(t, "ws"+strings.TrimPrefix(httpServer.URL, "http"), "alice")
+	defer alice.Close()
+	if len(snap.AllowedEmoji) != 2 || snap.AllowedEmoji[0] != "👍" || snap.AllowedEmoji[1] != "🎉" {
+		t.Fatalf("snapshot allowed emoji = %q", snap.AllowedEmoji)
+	}
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+	readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeAddReaction, MsgID: 1, Emoji: "🔥"})
+	msg := readUntil(t, alice, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeError || m.Type == protocol.TypeReactionAdded
+	})
+	if msg.Type != protocol.TypeError || msg.Error != core.ErrEmojiNotAllowed.Error() {
+		t.Fatalf("expected a not-allowed error, got %+v", msg)
+	}
+
+	// Allowed emoji still go through, including unnormalised spellings.
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeAddReaction, MsgID: 1, Emoji: "👍\uFE0F"})
+	msg = readUntil(t, alice, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeError || m.Type == protocol.TypeReactionAdded
+	})
+	if msg.Type != protocol.TypeReactionAdded || msg.Emoji != "👍" {
+		t.Fatalf("expected the allowed reaction, got %+v", msg)
+	}
+}
+
+func TestJoinVoiceSendsChannelICEServers(t *testing.T) {
+	channelState := core.NewChannelState("")
+	global := []protocol.ICEServer{{URLs: []string{"stun:stun.example.com:3478"}}}
+	if err := channelState.SetICEServers(global); err != nil {
+		t.Fatalf("set ice servers: %v", err)
+	}
+	e := echo.New()
+	NewHandler(channelState, nil).Register(e)
+	httpServer := httptest.NewServer(e)
+	defer httpServer.Close()
+
+	alice, snap := connectClient(t, "ws"+strings.TrimPrefix(httpServer.URL, "http"), "alice")
+	defer alice.Close()
+	if len(snap.ICEServers) != 1 || snap.ICEServers[0].URLs[0] != "stun:stun.example.com:3478" {
+		t.Fatalf("snapshot ice servers = %+v, want the global list", snap.ICEServers)
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeCreateChannel, ServerID: "srv-1", Message: "EU"})
+	list := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList && len(m.Channels) == 2 })
+	general, eu := list.Channels[0].ID, list.Channels[1].ID
+	if err := channelState.SetChannelICEServers(eu, []protocol.ICEServer{{URLs: []string{"turn:eu.example.com:3478"}}}); err != nil {
+		t.Fatalf("set channel ice servers: %v", err)
+	}
+
+	for _, tc := range []struct {
+		channelID int64
+		want      string
+	}{
+		{eu, "turn:eu.example.com:3478"},
+		{general, "stun:stun.example.com:3478"},
+	} {
+		writeMsg(t, alice, protocol.Message{Type: protocol.TypeJoinVoice, ServerID: "srv-1", ChannelID: strconv.FormatInt(tc.channelID, 10)})
+		got := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeICEUpdate })
+		if len(got.ICEServers) != 1 || got.ICEServers[0].URLs[0] != tc.want {
+			t.Errorf("ice_update for channel %d = %+v, want %s", tc.channelID, got.ICEServers, tc.want)
+		}
+	}
+}
+
+func TestJoinVoiceCooldownReportsRetryAfter(t *testing.T) {
+	channelState := core.NewChannelState("")
+	if err := channelState.SetChannelSwitchCooldown(time.Minute); err != nil {
+		t.Fatalf("set cooldown: %v", err)
+	}
+	e := echo.New()
+	NewHandler(channelState, nil).Register(e)
+	httpServer := httptest.NewServer(e)
+	defer httpServer.Close()
+
+	alice, snap := connectClient(t, "ws"+strings.TrimPrefix(httpServer.URL, "http"), "alice")
+	defer alice.Close()
+	aliceID := findUserID(t, snap.Users, "alice")
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeJoinVoice, ServerID: "srv-1", ChannelID: "chan-a"})
+	readUntil(t, alice, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeUserState && m.User != nil && m.User.ID == aliceID && m.User.Voice != nil
+	})
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeJoinVoice, ServerID: "srv-1", ChannelID: "chan-b"})
+	msg := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if msg.RetryAfterMs <= 0 || msg.RetryAfterMs > time.Minute.Milliseconds() {
+		t.Fatalf("expected retry_after_ms within the cooldown, got %d", msg.RetryAfterMs)
+	}
+	if !strings.Contains(msg.Error, "try again") {
+		t.Fatalf("unexpected error text: %q", msg.Error)
+	}
+}
+
+func TestReactionBurstIsCoalesced(t *testing.T) {
+	_, baseURL := startTestServerWithStore(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+	}
+
+	emojis := []string{"👍", "🎉", "🔥", "😂", "👀"}
+	for _, e := range emojis {
+		writeMsg(t, bob, protocol.Message{Type: protocol.TypeAddReaction, MsgID: 7, Emoji: e})
+	}
+
+	// The reacting user gets immediate per-change feedback.
+	for range emojis {
+		readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeReactionAdded })
+	}
+
+	// Everyone else sees the burst as aggregated updates, never one per change.
+	var updates, individual int
+	var last protocol.Message
+	deadline := time.Now().Add(4 * reactionBatchInterval)
+	for time.Now().Before(deadline) {
+		_ = alice.SetReadDeadline(deadline)
+		var msg protocol.Message
+		if err := alice.ReadJSON(&msg); err != nil {
+			break
+		}
+		switch msg.Type {
+		case protocol.TypeReactionUpdate:
+			updates++
+			last = msg
+		case protocol.TypeReactionAdded:
+			individual++
+		}
+	}
+	if individual != 0 {
+		t.Fatalf("expected no per-change broadcasts to alice, got %d", individual)
+	}
+	if updates == 0 || updates >= len(emojis) {
+		t.Fatalf("expected coalesced updates (1..%d), got %d", len(emojis)-1, updates)
+	}
+	if last.MsgID != 7 || len(last.Reactions) != len(emojis) {
+		t.Fatalf("final update should carry all %d reactions on msg 7, got %+v", len(emojis), last)
+	}
+	for i, rx := range last.Reactions {
+		if rx.Emoji != emojis[i] || rx.Count != 1 || len(rx.UserIDs) != 1 {
+			t.Fatalf("reaction %d = %+v", i, rx)
+		}
+	}
+}
+
+func TestReactionTogglesAreRateLimited(t *testing.T) {
+	_, baseURL := startTestServerWithStore(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+	}
+
+	for i := 0; i < 100; i++ {
+		typ := protocol.TypeAddReaction
+		if i%2 == 1 {
+			typ = protocol.TypeRemoveReaction
+		}
+		writeMsg(t, bob, protocol.Message{Type: typ, MsgID: 7, Emoji: "👍"})
+	}
+
+	msg := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if msg.RetryAfterMs <= 0 || !strings.Contains(msg.Error, "try again") {
+		t.Fatalf("expected a rate limit error with retry_after_ms, got %+v", msg)
+	}
+
+	// Everyone else sees the toggling as one or two updates carrying the
+	// final state: the last accepted change was a removal.
+	var broadcasts int
+	var last protocol.Message
+	deadline := time.Now().Add(4 * reactionBatchInterval)
+	for time.Now().Before(deadline) {
+		_ = alice.SetReadDeadline(deadline)
+		var m protocol.Message
+		if err := alice.ReadJSON(&m); err != nil {
+			break
+		}
+		switch m.Type {
+		case protocol.TypeReactionUpdate:
+			last = m
+			broadcasts++
+		case protocol.TypeReactionAdded, protocol.TypeReactionRemoved:
+			broadcasts++
+		}
+	}
+	if broadcasts == 0 || broadcasts > 2 {
+		t.Fatalf("expected at most a couple of broadcasts for 100 toggles, got %d", broadcasts)
+	}
+	if last.MsgID != 7 || len(last.Reactions) != 0 {
+		t.Fatalf("final update should show no reactions on msg 7, got %+v", last)
+	}
+}
+
+func TestReadReceiptCountsEachReaderOnce(t *testing.T) {
+	_, baseURL := startTestServerWithStore(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, bobSnap := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSendText, ServerID: "srv-1", ChannelID: "1", Message: "seen?"})
+	sent := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeTextMessage })
+
+	// The author's own receipt is ignored, and bob counts once however
+	// often he reports it.
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeReadReceipt, MsgID: sent.MsgID})
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeReadReceipt, MsgID: sent.MsgID})
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeReadReceipt, MsgID: sent.MsgID})
+	got := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeMessageRead })
+	if got.MsgID != sent.MsgID || got.ChannelID != "1" || len(got.Readers) != 1 || got.Readers[0] != bobSnap.SelfID {
+		t.Fatalf("unexpected message_read: %+v", got)
+	}
+	// Bob's next message arrives after anything the repeat would have sent.
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeSendText, ServerID: "srv-1", ChannelID: "1", Message: "yes"})
+	readUntil(t, alice, func(m protocol.Message) bool {
+		if m.Type == protocol.TypeMessageRead {
+			t.Fatalf("repeat receipt was broadcast again: %+v", m)
+		}
+		return m.Type == protocol.TypeTextMessage
+	})
+}
+
+func TestGetPermissionsReportsOwnerAndRoles(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, aliceSnap := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	aliceID := aliceSnap.SelfID
+	if aliceSnap.OwnerID != aliceID {
+		t.Fatalf("snapshot owner_id = %q, want %q", aliceSnap.OwnerID, aliceID)
+	}
+	bob, bobSnap := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+	if bobSnap.OwnerID != aliceID {
+		t.Fatalf("bob's snapshot owner_id = %q, want %q", bobSnap.OwnerID, aliceID)
+	}
+
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeGetPermissions})
+	perms := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypePermissions })
+	if perms.OwnerID != aliceID {
+		t.Fatalf("permissions owner_id = %q, want %q", perms.OwnerID, aliceID)
+	}
+	if perms.Roles[aliceID] != core.RoleOwner || perms.Roles[bobSnap.SelfID] != core.RoleUser {
+		t.Fatalf("unexpected roles: %v", perms.Roles)
+	}
+
+	// The owner leaving hands ownership to bob.
+	alice.Close()
+	changed := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeOwnerChanged })
+	if changed.OwnerID != bobSnap.SelfID {
+		t.Fatalf("owner_changed owner_id = %q, want %q", changed.OwnerID, bobSnap.SelfID)
+	}
+}
+
+func TestSetRoleRequiresOwnerAndBroadcasts(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, aliceSnap := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, bobSnap := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+	carol, carolSnap := connectClient(t, baseURL, "carol")
+	defer carol.Close()
+	bobID := bobSnap.SelfID
+
+	// Only the owner assigns roles.
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeSetRole, UserID: carolSnap.SelfID, Role: core.RoleAdmin})
+	errMsg := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if !strings.Contains(errMsg.Error, "only the owner") {
+		t.Fatalf("unexpected error: %q", errMsg.Error)
+	}
+
+	// Ownership is not a role, and the owner's own role is fixed.
+	for _, bad := range []protocol.Message{
+		{Type: protocol.TypeSetRole, UserID: bobID, Role: core.RoleOwner},
+		{Type: protocol.TypeSetRole, UserID: bobID, Role: "SUPERUSER"},
+		{Type: protocol.TypeSetRole, UserID: aliceSnap.SelfID, Role: core.RoleUser},
+	} {
+		writeMsg(t, alice, bad)
+		readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSetRole, UserID: bobID, Role: "moderator"})
+	changed := readUntil(t, carol, func(m protocol.Message) bool { return m.Type == protocol.TypeRoleChanged })
+	if changed.UserID != bobID || changed.Role != core.RoleModerator {
+		t.Fatalf("unexpected role_changed: %+v", changed)
+	}
+	writeMsg(t, carol, protocol.Message{Type: protocol.TypeGetPermissions})
+	perms := readUntil(t, carol, func(m protocol.Message) bool { return m.Type == protocol.TypePermissions })
+	if perms.Roles[bobID] != core.RoleModerator {
+		t.Fatalf("unexpected roles: %v", perms.Roles)
+	}
+
+	// A moderator passes moderator gates, such as locking a channel.
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeGetChannels})
+	list := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList })
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeSetChannelLock, ChannelID: strconv.FormatInt(list.Channels[0].ID, 10), Locked: true})
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList && m.Channels[0].Locked })
+
+	// Admins show up as co-owners.
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSetRole, UserID: bobID, Role: core.RoleAdmin})
+	readUntil(t, carol, func(m protocol.Message) bool { return m.Type == protocol.TypeRoleChanged && m.Role == core.RoleAdmin })
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeGetPermissions})
+	perms = readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypePermissions })
+	if len(perms.CoOwners) != 1 || perms.CoOwners[0] != bobID {
+		t.Fatalf("unexpected co_owners: %v", perms.CoOwners)
+	}
+}
+
+func TestDMReachesOnlySenderAndRecipient(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, bobSnap := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+	carol, _ := connectClient(t, baseURL, "carol")
+	defer carol.Close()
+
+	for _, conn := range []*websocket.Conn{alice, bob, carol} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, conn, func(m protocol.Message) bool {
+			return m.Type == protocol.TypeUserState
+		})
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeDM, UserID: bobSnap.SelfID, Message: "psst"})
+	for _, conn := range []*websocket.Conn{bob, alice} {
+		dm := readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeDM })
+		if dm.Message != "psst" || dm.UserID != bobSnap.SelfID {
+			t.Fatalf("unexpected dm: %+v", dm)
+		}
+		if dm.User == nil || dm.User.Username != "alice" {
+			t.Fatalf("expected sender alice, got %+v", dm.User)
+		}
+	}
+
+	// A channel message sent afterwards must reach carol with no DM before it.
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSendText, ServerID: "srv-1", ChannelID: "1", Message: "hi all"})
+	readUntil(t, carol, func(m protocol.Message) bool {
+		if m.Type == protocol.TypeDM {
+			t.Fatalf("carol received a dm meant for bob: %+v", m)
+		}
+		return m.Type == protocol.TypeTextMessage
+	})
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeDM, UserID: "u999", Message: "anyone?"})
+	errMsg := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if errMsg.Error != "user not found" {
+		t.Fatalf("unexpected error for unknown target: %q", errMsg.Error)
+	}
+}
+
+func TestEditMessageThenFetchHistory(t *testing.T) {
+	_, baseURL := startTestServerWithStore(t)
+
+	// alice connects first and owns the server.
+	conns := map[string]*websocket.Conn{}
+	for _, name := range []string{"alice", "bob", "carol"} {
+		conn, _ := connectClient(t, baseURL, name)
+		defer conn.Close()
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+		conns[name] = conn
+	}
+	alice, bob, carol := conns["alice"], conns["bob"], conns["carol"]
+
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeSendText, ServerID: "srv-1", ChannelID: "1", Message: "helo"})
+	sent := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeTextMessage })
+
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeEditMessage, MsgID: sent.MsgID, Message: "hello"})
+	for _, conn := range []*websocket.Conn{bob, carol} {
+		edited := readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeMessageEdited })
+		if edited.MsgID != sent.MsgID || edited.Message != "hello" || edited.ChannelID != "1" {
+			t.Fatalf("unexpected message_edited: %+v", edited)
+		}
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeEditMessage, MsgID: sent.MsgID, Message: "hijacked"})
+	errMsg := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if errMsg.Error != "only the sender can edit a message" {
+		t.Fatalf("unexpected error editing another user's message: %q", errMsg.Error)
+	}
+
+	for _, conn := range []*websocket.Conn{bob, alice} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeGetEditHistory, MsgID: sent.MsgID})
+		history := readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeEditHistory })
+		if history.MsgID != sent.MsgID || len(history.Edits) != 1 || history.Edits[0].Message != "helo" || history.Edits[0].TS == 0 {
+			t.Fatalf("unexpected edit_history: %+v", history)
+		}
+	}
+
+	writeMsg(t, carol, protocol.Message{Type: protocol.TypeGetEditHistory, MsgID: sent.MsgID})
+	errMsg = readUntil(t, carol, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if errMsg.Error != "only the sender or the server owner can see edit history" {
+		t.Fatalf("unexpected error for a bystander: %q", errMsg.Error)
+	}
+}
+
+func TestEditRejectsMessageFromEarlierBoot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bken.db")
+	prev, err := store.Open(path)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	// u1 in the previous process was someone else.
+	msgID, err := prev.InsertMessage(context.Background(), "srv-1", "1", "u1", "mallory", "original", time.Now().UnixMilli(), "", "", 0, 0)
+	if err != nil {
+		t.Fatalf("insert message: %v", err)
+	}
+	_ = prev.Close()
+
+	st, err := store.Open(path)
+	if err != nil {
+		t.Fatalf("reopen store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	e := echo.New()
+	NewHandler(core.NewChannelState(""), st).Register(e)
+	httpServer := httptest.NewServer(e)
+	t.Cleanup(httpServer.Close)
+
+	alice, snapshot := connectClient(t, "ws"+strings.TrimPrefix(httpServer.URL, "http"), "alice")
+	defer alice.Close()
+	if snapshot.SelfID != "u1" {
+		t.Fatalf("expected alice to reuse u1, got %q", snapshot.SelfID)
+	}
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+	readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeEditMessage, MsgID: msgID, Message: "hijacked"})
+	errMsg := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if errMsg.Error != "only the sender can edit a message" {
+		t.Fatalf("unexpected error editing a message from an earlier boot: %q", errMsg.Error)
+	}
+}
+
+func TestGetThreadReturnsReplyChain(t *testing.T) {
+	_, baseURL := startTestServerWithStore(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+	readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+
+	var replyTo int64
+	for _, text := range []string{"root", "reply", "reply to reply"} {
+		writeMsg(t, alice, protocol.Message{
+			Type:      protocol.TypeSendText,
+			ServerID:  "srv-1",
+			ChannelID: "1",
+			Message:   text,
+			ReplyTo:   replyTo,
+		})
+		msg := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeTextMessage })
+		if msg.ReplyTo != replyTo {
+			t.Fatalf("%q: expected reply_to %d, got %d", text, replyTo, msg.ReplyTo)
+		}
+		replyTo = msg.MsgID
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeGetThread, MsgID: replyTo})
+	thread := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeThread })
+	if thread.MsgID != replyTo || len(thread.Messages) != 3 {
+		t.Fatalf("expected 3-message thread for %d, got %+v", replyTo, thread)
+	}
+	if thread.Messages[0].Message != "root" || thread.Messages[2].MsgID != replyTo {
+		t.Fatalf("thread not ordered root first: %+v", thread.Messages)
+	}
+
+	// Replying to a message that does not exist is rejected.
+	writeMsg(t, alice, protocol.Message{
+		Type:      protocol.TypeSendText,
+		ServerID:  "srv-1",
+		ChannelID: "1",
+		Message:   "orphan",
+		ReplyTo:   9999,
+	})
+	errMsg := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if errMsg.Error != "reply target not found" {
+		t.Fatalf("unexpected error: %q", errMsg.Error)
+	}
+}
+
+func TestResumeReplaysOnlyMissedMessages(t *testing.T) {
+	_, baseURL := startTestServerWithStore(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+	readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+
+	var ids []int64
+	for _, text := range []string{"seen", "missed one", "missed two"} {
+		writeMsg(t, alice, protocol.Message{Type: protocol.TypeSendText, ServerID: "srv-1", ChannelID: "1", Message: text})
+		msg := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeTextMessage })
+		ids = append(ids, msg.MsgID)
+	}
+
+	// Up to date: nothing comes back before the pong.
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeResume, Seqs: map[string]int64{"1": ids[2]}})
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypePing, TS: 1})
+	readUntil(t, alice, func(m protocol.Message) bool {
+		if m.Type == protocol.TypeTextMessage {
+			t.Fatalf("up-to-date resume replayed %+v", m)
+		}
+		return m.Type == protocol.TypePong
+	})
+
+	// Behind by two: exactly the two missed messages, in order.
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeResume, Seqs: map[string]int64{"1": ids[0]}})
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypePing, TS: 2})
+	var replayed []string
+	readUntil(t, alice, func(m protocol.Message) bool {
+		if m.Type == protocol.TypeTextMessage {
+			if m.User == nil || m.User.Username != "alice" || m.ChannelID != "1" {
+				t.Fatalf("replayed message missing sender or channel: %+v", m)
+			}
+			replayed = append(replayed, m.Message)
+		}
+		return m.Type == protocol.TypePong
+	})
+	if !slices.Equal(replayed, []string{"missed one", "missed two"}) {
+		t.Fatalf("expected the two missed messages, got %q", replayed)
+	}
+}
+
+func TestSnapshotAdvertisesMaxUploadBytes(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	conn, snap := connectClient(t, baseURL, "alice")
+	defer conn.Close()
+	if snap.MaxUploadBytes != core.DefaultMaxUploadBytes {
+		t.Fatalf("expected max_upload_bytes %d, got %d", core.DefaultMaxUploadBytes, snap.MaxUploadBytes)
+	}
+}
+
+func TestSetChannelBitrateIsOwnerOnly(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+	}
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeGetChannels})
+	list := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList })
+	chID := strconv.FormatInt(list.Channels[0].ID, 10)
+
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeSetChannelBitrate, ChannelID: chID, MaxBitrateKbps: 32})
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSetChannelBitrate, ChannelID: chID, MaxBitrateKbps: 32})
+	updated := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList })
+	if updated.Channels[0].MaxBitrateKbps != 32 {
+		t.Fatalf("expected a 32 kbps cap, got %+v", updated.Channels[0])
+	}
+}
+
+func TestSetChannelTTLIsOwnerOnly(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+	}
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeGetChannels})
+	list := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList })
+	chID := strconv.FormatInt(list.Channels[0].ID, 10)
+
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeSetChannelTTL, ChannelID: chID, MessageTTLSeconds: 3600})
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSetChannelTTL, ChannelID: chID, MessageTTLSeconds: 3600})
+	updated := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList })
+	if updated.Channels[0].MessageTTLSeconds != 3600 {
+		t.Fatalf("expected a one hour ttl, got %+v", updated.Channels[0])
+	}
+}
+
+func TestStartRecordingFollowsChannelRecordRole(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+	}
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeGetChannels})
+	list := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList })
+	if list.Channels[0].MinRoleToRecord != core.RoleOwner {
+		t.Fatalf("expected recording to default to the owner, got %+v", list.Channels[0])
+	}
+	chID := strconv.FormatInt(list.Channels[0].ID, 10)
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeJoinVoice, ServerID: "srv-1", ChannelID: chID})
+	readUntil(t, bob, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeUserState && m.User != nil && m.User.Voice != nil
+	})
+
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeStartRecording})
+	errMsg := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if !strings.Contains(errMsg.Error, core.RoleOwner) {
+		t.Fatalf("expected recording to be denied, got %+v", errMsg)
+	}
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeStopRecording })
+
+	// Only the owner may change who records.
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeSetChannelRecordRole, ChannelID: chID, MinRoleToRecord: core.RoleUser})
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSetChannelRecordRole, ChannelID: chID, MinRoleToRecord: core.RoleUser})
+	updated := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList })
+	if updated.Channels[0].MinRoleToRecord != core.RoleUser {
+		t.Fatalf("unexpected record role: %+v", updated.Channels[0])
+	}
+
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeStartRecording})
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeGetChannels})
+	readUntil(t, bob, func(m protocol.Message) bool {
+		if m.Type == protocol.TypeStopRecording || m.Type == protocol.TypeError {
+			t.Fatalf("recording should be allowed, got %+v", m)
+		}
+		return m.Type == protocol.TypeChannelList
+	})
+}
+
+func TestChannelCategoriesAreOwnerOnly(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+	}
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeGetChannels})
+	list := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList })
+	chID := strconv.FormatInt(list.Channels[0].ID, 10)
+
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeCreateCategory, Message: "Games"})
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeCreateCategory, Message: "Games"})
+	created := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList })
+	if len(created.Categories) != 1 || created.Categories[0].Name != "Games" {
+		t.Fatalf("expected the new category in channel_list, got %+v", created.Categories)
+	}
+	catID := created.Categories[0].ID
+
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeAssignChannelCategory, ChannelID: chID, CategoryID: catID})
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeAssignChannelCategory, ChannelID: chID, CategoryID: catID})
+	moved := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList })
+	if moved.Channels[0].CategoryID != catID || moved.Channels[0].CategoryName != "Games" {
+		t.Fatalf("expected channel in category %d, got %+v", catID, moved.Channels[0])
+	}
+	if len(moved.Categories) != 1 {
+		t.Fatalf("expected categories with the channel list, got %+v", moved.Categories)
+	}
+}
+
+func TestSlowModeRejectsFastSendersButNotAdmins(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+	}
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeGetChannels})
+	list := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList })
+	chID := strconv.FormatInt(list.Channels[0].ID, 10)
+
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeSetSlowMode, ChannelID: chID, SlowModeSeconds: 30})
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSetSlowMode, ChannelID: chID, SlowModeSeconds: 30})
+	updated := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList })
+	if updated.Channels[0].SlowModeSeconds != 30 {
+		t.Fatalf("expected 30 s slow mode, got %+v", updated.Channels[0])
+	}
+
+	send := func(conn *websocket.Conn, text string) {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeSendText, ServerID: "srv-1", ChannelID: chID, Message: text})
+	}
+	send(bob, "first")
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeTextMessage && m.Message == "first" })
+	send(bob, "second")
+	msg := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if !strings.HasPrefix(msg.Error, "slow mode: wait ") {
+		t.Fatalf("unexpected error text: %q", msg.Error)
+	}
+	if msg.RetryAfterMs <= 0 || msg.RetryAfterMs > 30*time.Second.Milliseconds() {
+		t.Fatalf("expected retry_after_ms within the interval, got %d", msg.RetryAfterMs)
+	}
+
+	// The owner is exempt.
+	send(alice, "one")
+	send(alice, "two")
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeTextMessage && m.Message == "two" })
+}
+
+func TestLockedChannelRejectsJoinsBelowModerator(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+	}
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeGetChannels})
+	list := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList })
+	chID := strconv.FormatInt(list.Channels[0].ID, 10)
+
+	// A regular user may not lock the channel.
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeSetChannelLock, ChannelID: chID, Locked: true})
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSetChannelLock, ChannelID: chID, Locked: true})
+	updated := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList })
+	if !updated.Channels[0].Locked {
+		t.Fatalf("expected the channel to be locked, got %+v", updated.Channels[0])
+	}
+
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeJoinVoice, ServerID: "srv-1", ChannelID: chID})
+	errMsg := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if errMsg.Error != "channel locked" {
+		t.Fatalf("unexpected error text: %q", errMsg.Error)
+	}
+
+	// The owner is above the bar and joins anyway.
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeJoinVoice, ServerID: "srv-1", ChannelID: chID})
+	readUntil(t, alice, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeUserState && m.User != nil && m.User.Voice != nil
+	})
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSetChannelLock, ChannelID: chID})
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList && !m.Channels[0].Locked })
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeJoinVoice, ServerID: "srv-1", ChannelID: chID})
+	readUntil(t, bob, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeUserState && m.User != nil && m.User.Voice != nil && m.User.Username == "bob"
+	})
+}
+
+func TestAnnouncementOnConnectAndChange(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+
+	// Only admins and the owner may post.
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeSetAnnouncement, Message: "hijack"})
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSetAnnouncement, Message: "welcome to bken"})
+	got := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeAnnouncement })
+	if got.Message != "welcome to bken" || got.PostedBy != "alice" {
+		t.Fatalf("unexpected announcement broadcast: %+v", got)
+	}
+
+	// A client that connects later gets it right after the snapshot.
+	carol, _ := connectClient(t, baseURL, "carol")
+	defer carol.Close()
+	got = readUntil(t, carol, func(m protocol.Message) bool { return m.Type == protocol.TypeAnnouncement })
+	if got.Message != "welcome to bken" {
+		t.Fatalf("unexpected announcement on connect: %+v", got)
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSetAnnouncement})
+	got = readUntil(t, carol, func(m protocol.Message) bool { return m.Type == protocol.TypeAnnouncement })
+	if got.Message != "" {
+		t.Fatalf("expected the clear to be broadcast, got %+v", got)
+	}
+}
+
+func TestMentionSentToMentionedUsers(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, bobSnap := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+	}
+
+	// Self-mentions and names nobody is using are ignored.
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSendText, ServerID: "srv-1", ChannelID: "1", Message: "@alice @nobody @bob ping"})
+	got := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeMention })
+	if got.ChannelID != "1" || got.User == nil || got.User.Username != "alice" {
+		t.Fatalf("unexpected mention: %+v", got)
+	}
+	msg := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeTextMessage })
+	if len(msg.Mentions) != 1 || msg.Mentions[0] != bobSnap.SelfID {
+		t.Fatalf("text_message mentions = %v, want [%s]", msg.Mentions, bobSnap.SelfID)
+	}
+
+	// A message without mentions sends no mention to anyone.
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeSendText, ServerID: "srv-1", ChannelID: "1", Message: "hi alice"})
+	readUntil(t, alice, func(m protocol.Message) bool {
+		if m.Type == protocol.TypeMention {
+			t.Fatalf("unexpected mention: %+v", m)
+		}
+		return m.Type == protocol.TypeTextMessage
+	})
+}
+
+func TestChannelPermsRestrictChatAndVoice(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, bobSnap := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+	}
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeGetChannels})
+	list := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList })
+	chID := strconv.FormatInt(list.Channels[0].ID, 10)
+
+	// Bob is in voice before the channel is locked down.
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeJoinVoice, ServerID: "srv-1", ChannelID: chID})
+	readUntil(t, bob, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeUserState && m.User != nil && m.User.Voice != nil
+	})
+
+	// Only the owner may change channel permissions.
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeSetChannelPerms, ChannelID: chID, MinRoleToChat: core.RoleModerator})
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+
+	writeMsg(t, alice, protocol.Message{
+		Type:           protocol.TypeSetChannelPerms,
+		ChannelID:      chID,
+		MinRoleToSpeak: core.RoleModerator,
+		MinRoleToChat:  core.RoleModerator,
+	})
+	updated := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList })
+	if updated.Channels[0].MinRoleToSpeak != core.RoleModerator || updated.Channels[0].MinRoleToChat != core.RoleModerator {
+		t.Fatalf("unexpected channel perms: %+v", updated.Channels[0])
+	}
+	forced := readUntil(t, bob, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeUserState && m.User != nil && m.User.ID == bobSnap.SelfID
+	})
+	if forced.User.Voice == nil || !forced.User.Voice.Muted {
+		t.Fatalf("bob should be muted in a moderator-only channel, got %+v", forced.User.Voice)
+	}
+
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeSendText, ServerID: "srv-1", ChannelID: chID, Message: "hi"})
+	errMsg := readUntil(t, bob, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeError || m.Type == protocol.TypeTextMessage
+	})
+	if errMsg.Type != protocol.TypeError || !strings.Contains(errMsg.Error, core.RoleModerator) {
+		t.Fatalf("expected chat to be denied, got %+v", errMsg)
+	}
+
+	unmuted := false
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeSetVoiceState, Muted: &unmuted})
+	errMsg = readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if !strings.Contains(errMsg.Error, "speak") {
+		t.Fatalf("expected speak to be denied, got %q", errMsg.Error)
+	}
+
+	// The owner is above the floor and may still chat.
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSendText, ServerID: "srv-1", ChannelID: chID, Message: "announcement"})
+	readUntil(t, bob, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeTextMessage && m.Message == "announcement"
+	})
+}
+
+func TestBanUserDisconnectsAndBlocksReconnect(t *testing.T) {
+	_, baseURL := startTestServerWithStore(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+	carol, carolSnap := connectClient(t, baseURL, "carol")
+	defer carol.Close()
+	carolID := carolSnap.SelfID
+
+	// A regular user's ban request is ignored.
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeBanUser, UserID: carolID})
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypePing, TS: 1})
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypePong })
+	writeMsg(t, carol, protocol.Message{Type: protocol.TypePing, TS: 2})
+	readUntil(t, carol, func(m protocol.Message) bool { return m.Type == protocol.TypePong })
+
+	// Overlong reasons and out-of-range durations are refused.
+	for _, bad := range []protocol.Message{
+		{Type: protocol.TypeBanUser, UserID: carolID, Reason: strings.Repeat("x", maxReasonLength+1)},
+		{Type: protocol.TypeBanUser, UserID: carolID, DurationS: -1},
+		{Type: protocol.TypeBanUser, UserID: carolID, DurationS: maxBanDurationS + 1},
+	} {
+		writeMsg(t, alice, bad)
+		readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeBanUser, UserID: carolID, Reason: "spam"})
+	kicked := readUntil(t, carol, func(m protocol.Message) bool { return m.Type == protocol.TypeKicked })
+	if kicked.Message != "spam" {
+		t.Fatalf("expected kick reason %q, got %q", "spam", kicked.Message)
+	}
+	readUntil(t, bob, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeUserLeft && m.User != nil && m.User.ID == carolID
+	})
+
+	// The ban is recorded against carol's address, so she cannot come back.
+	conn, _, err := websocket.DefaultDialer.Dial(baseURL+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dial ws: %v", err)
+	}
+	defer conn.Close()
+	writeMsg(t, conn, protocol.Message{Type: protocol.TypeHello, Username: "carol2"})
+	rejected := readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if !strings.Contains(rejected.Error, "banned") || !strings.Contains(rejected.Error, "spam") {
+		t.Fatalf("unexpected rejection: %q", rejected.Error)
+	}
+}
+
+func TestTransferOwnerRequiresOwnerAndRecordsAudit(t *testing.T) {
+	st, baseURL := startTestServerWithAuditStore(t)
+
+	alice, aliceSnap := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, bobSnap := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+
+	// Only the owner may hand off ownership.
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeTransferOwner, UserID: bobSnap.SelfID})
+	rejected := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if !strings.Contains(rejected.Error, "only the owner") {
+		t.Fatalf("unexpected rejection: %q", rejected.Error)
+	}
+
+	// The target must be connected.
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeTransferOwner, UserID: "u999"})
+	rejected = readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if !strings.Contains(rejected.Error, "not connected") {
+		t.Fatalf("unexpected rejection: %q", rejected.Error)
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeTransferOwner, UserID: bobSnap.SelfID})
+	changed := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeOwnerChanged })
+	if changed.OwnerID != bobSnap.SelfID {
+		t.Fatalf("owner_changed to %q, want %q", changed.OwnerID, bobSnap.SelfID)
+	}
+
+	// Alice's messages are handled in order, so once her pong arrives the
+	// audit entry has been written.
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypePing, TS: 1})
+	readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypePong })
+	entries, err := st.AuditLog(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("audit log: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Action != "transfer_owner" || entries[0].ActorID != aliceSnap.SelfID || entries[0].TargetName != "bob" {
+		t.Fatalf("unexpected audit log: %+v", entries)
+	}
+}
+
+func TestAuditLogOnlyReachesAdmins(t *testing.T) {
+	_, baseURL := startTestServerWithAuditStore(t)
+
+	alice, aliceSnap := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+	carol, carolSnap := connectClient(t, baseURL, "carol")
+	defer carol.Close()
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeKick, UserID: carolSnap.SelfID, Reason: "spam"})
+	live := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeAuditEntry })
+	if len(live.Audit) != 1 || live.Audit[0].Action != "kick" || live.Audit[0].ActorID != aliceSnap.SelfID || live.Audit[0].TargetName != "carol" {
+		t.Fatalf("unexpected audit_entry: %+v", live.Audit)
+	}
+
+	// A regular user gets neither the live entry nor the log. Bob's
+	// messages are handled in order, so everything meant for him has
+	// arrived by the time his pong does.
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeGetAuditLog})
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypePing, TS: 1})
+	readUntil(t, bob, func(m protocol.Message) bool {
+		if m.Type == protocol.TypeAuditEntry || m.Type == protocol.TypeAuditLog || m.Type == protocol.TypeError {
+			t.Fatalf("regular user received %+v", m)
+		}
+		return m.Type == protocol.TypePong
+	})
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeGetAuditLog})
+	log := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeAuditLog })
+	if len(log.Audit) != 1 || log.Audit[0] != live.Audit[0] {
+		t.Fatalf("audit_log = %+v, want %+v", log.Audit, live.Audit)
+	}
+}
+
+func TestUnbanRemovesBanForAdminsOnly(t *testing.T) {
+	st, baseURL := startTestServerWithAuditStore(t)
+	ctx := context.Background()
+	for _, b := range []store.Ban{
+		{IP: "10.0.0.1", Username: "mallory", Reason: "spam", BannedBy: "alice", CreatedAt: time.Now()},
+		{IP: "10.0.0.2", Username: "eve", BannedBy: "alice", CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)},
+	} {
+		if err := st.RecordBan(ctx, b); err != nil {
+			t.Fatalf("record ban: %v", err)
+		}
+	}
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeGetBans})
+	list := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeBanList })
+	if len(list.Bans) != 2 || list.Bans[0].Username != "eve" || list.Bans[0].ExpiresAt == 0 || list.Bans[1].Reason != "spam" {
+		t.Fatalf("unexpected ban_list: %+v", list.Bans)
+	}
+	mallory := list.Bans[1]
+
+	// A regular user can neither list nor lift bans.
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeGetBans})
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeUnban, BanID: mallory.ID})
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypePing, TS: 1})
+	readUntil(t, bob, func(m protocol.Message) bool {
+		if m.Type == protocol.TypeBanList || m.Type == protocol.TypeError {
+			t.Fatalf("regular user received %+v", m)
+		}
+		return m.Type == protocol.TypePong
+	})
+	if _, ok, err := st.ActiveBan(ctx, mallory.IP, time.Now()); err != nil || !ok {
+		t.Fatalf("ban lifted by a regular user: ok=%v err=%v", ok, err)
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeUnban, BanID: mallory.ID})
+	list = readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeBanList })
+	if len(list.Bans) != 1 || list.Bans[0].Username != "eve" {
+		t.Fatalf("ban_list after unban: %+v", list.Bans)
+	}
+	if _, ok, err := st.ActiveBan(ctx, mallory.IP, time.Now()); err != nil || ok {
+		t.Fatalf("ban still active after unban: ok=%v err=%v", ok, err)
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeUnban, BanID: mallory.ID})
+	if e := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeError }); e.Error != "ban not found" {
+		t.Fatalf("unexpected error: %q", e.Error)
+	}
+
+	entries, err := st.AuditLog(ctx, 10)
+	if err != nil {
+		t.Fatalf("audit log: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Action != "unban" || entries[0].ActorName != "alice" || entries[0].TargetName != "mallory" {
+		t.Fatalf("unexpected audit log: %+v", entries)
+	}
+}
+
+func TestPinMessagesForModeratorsOnlyUpToLimit(t *testing.T) {
+	st, baseURL := startTestServerWithAuditStore(t)
+
+	alice, aliceSnap := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+	for _, c := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, c, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, c, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+	}
+
+	ctx := context.Background()
+	var ids []int64
+	for i := 0; i <= store.MaxPinnedPerChannel; i++ {
+		id, err := st.InsertMessage(ctx, "srv-1", "1", "u1", "carol", "m"+strconv.Itoa(i), int64(1000+i), "", "", 0, 0)
+		if err != nil {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+		ids = append(ids, id)
+	}
+
+	// A regular user may not pin, but may list the channel's pins.
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypePinMessage, MsgID: ids[0]})
+	denied := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if !strings.Contains(denied.Error, "only moderators") {
+		t.Fatalf("unexpected rejection: %q", denied.Error)
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypePinMessage, MsgID: ids[0]})
+	pinned := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeMessagePinned })
+	if pinned.MsgID != ids[0] || pinned.ChannelID != "1" || pinned.UserID != aliceSnap.SelfID {
+		t.Fatalf("unexpected message_pinned: %+v", pinned)
+	}
+
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeGetPinned, ChannelID: "1"})
+	list := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypePinnedList })
+	if len(list.Pins) != 1 || list.Pins[0].MsgID != ids[0] || list.Pins[0].Message != "m0" || list.Pins[0].PinnedBy != aliceSnap.SelfID {
+		t.Fatalf("unexpected pinned_list: %+v", list.Pins)
+	}
+
+	// Fill the channel up to the limit; one more pin is refused.
+	for i, id := range ids[1:store.MaxPinnedPerChannel] {
+		if _, ok, err := st.PinMessage(ctx, "srv-1", id, aliceSnap.SelfID, int64(2000+i)); err != nil || !ok {
+			t.Fatalf("pin %d: %v, %v", id, ok, err)
+		}
+	}
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypePinMessage, MsgID: ids[store.MaxPinnedPerChannel]})
+	full := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if full.Error != store.ErrTooManyPins.Error() {
+		t.Fatalf("unexpected error at the pin limit: %q", full.Error)
+	}
+
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeUnpinMessage, MsgID: ids[0]})
+	denied = readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if !strings.Contains(denied.Error, "only moderators") {
+		t.Fatalf("unexpected rejection: %q", denied.Error)
+	}
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeUnpinMessage, MsgID: ids[0]})
+	unpinned := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeMessageUnpinned })
+	if unpinned.MsgID != ids[0] {
+		t.Fatalf("unexpected message_unpinned: %+v", unpinned)
+	}
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeUnpinMessage, MsgID: ids[0]})
+	if e := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeError }); e.Error != "message is not pinned" {
+		t.Fatalf("unexpected error: %q", e.Error)
+	}
+}
+
+func TestSpeakingRelaysToVoiceChannel(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, aliceSnap := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+	carol, _ := connectClient(t, baseURL, "carol")
+	defer carol.Close()
+	for c, channelID := range map[*websocket.Conn]string{alice: "chan-a", bob: "chan-a", carol: "chan-b"} {
+		writeMsg(t, c, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		writeMsg(t, c, protocol.Message{Type: protocol.TypeJoinVoice, ServerID: "srv-1", ChannelID: channelID})
+	}
+	for _, c := range []*websocket.Conn{alice, bob, carol} {
+		writeMsg(t, c, protocol.Message{Type: protocol.TypePing, TS: 1})
+		readUntil(t, c, func(m protocol.Message) bool { return m.Type == protocol.TypePong })
+	}
+
+	speaking := true
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSpeaking, Speaking: &speaking})
+	started := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeSpeaking })
+	if started.UserID != aliceSnap.SelfID || started.Speaking == nil || !*started.Speaking {
+		t.Fatalf("unexpected speaking start: %+v", started)
+	}
+
+	speaking = false
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSpeaking, Speaking: &speaking})
+	stopped := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeSpeaking })
+	if stopped.Speaking == nil || *stopped.Speaking {
+		t.Fatalf("unexpected speaking stop: %+v", stopped)
+	}
+
+	// carol is in another channel and hears nothing.
+	writeMsg(t, carol, protocol.Message{Type: protocol.TypePing, TS: 2})
+	readUntil(t, carol, func(m protocol.Message) bool {
+		if m.Type == protocol.TypeSpeaking {
+			t.Fatalf("carol received speaking for another channel: %+v", m)
+		}
+		return m.Type == protocol.TypePong
+	})
+}
+
+func TestKickRelaysReasonAndRecordsAudit(t *testing.T) {
+	st, baseURL := startTestServerWithAuditStore(t)
+
+	alice, aliceSnap := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+	carol, carolSnap := connectClient(t, baseURL, "carol")
+	defer carol.Close()
+	carolID := carolSnap.SelfID
+
+	// A regular user's kick request is ignored.
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeKick, UserID: carolID})
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypePing, TS: 1})
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypePong })
+	writeMsg(t, carol, protocol.Message{Type: protocol.TypePing, TS: 2})
+	readUntil(t, carol, func(m protocol.Message) bool { return m.Type == protocol.TypePong })
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeKick, UserID: carolID, Reason: strings.Repeat("x", maxReasonLength+1)})
+	rejected := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if !strings.Contains(rejected.Error, "reason must not exceed") {
+		t.Fatalf("unexpected rejection: %q", rejected.Error)
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeKick, UserID: carolID, Reason: " cool off "})
+	kicked := readUntil(t, carol, func(m protocol.Message) bool { return m.Type == protocol.TypeKicked })
+	if kicked.Message != "cool off" {
+		t.Fatalf("kick reason = %q, want %q", kicked.Message, "cool off")
+	}
+	readUntil(t, bob, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeUserLeft && m.User != nil && m.User.ID == carolID
+	})
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypePing, TS: 3})
+	readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypePong })
+	entries, err := st.AuditLog(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("audit log: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Action != "kick" || entries[0].ActorID != aliceSnap.SelfID ||
+		entries[0].TargetName != "carol" || entries[0].Details != `reason="cool off"` {
+		t.Fatalf("unexpected audit log: %+v", entries)
+	}
+
+	// A kick is not a ban: carol can come straight back.
+	again, _ := connectClient(t, baseURL, "carol")
+	again.Close()
+}
+
+func TestPurgeMessagesDeletesNewestAndRecordsAudit(t *testing.T) {
+	st, baseURL := startTestServerWithAuditStore(t)
+
+	alice, aliceSnap := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+	for _, c := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, c, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, c, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+	}
+
+	var ids []int64
+	for _, text := range []string{"keep", "spam", "more spam"} {
+		writeMsg(t, alice, protocol.Message{Type: protocol.TypeSendText, ServerID: "srv-1", ChannelID: "1", Message: text})
+		echo := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeTextMessage })
+		ids = append(ids, echo.MsgID)
+	}
+
+	// A regular user may not purge.
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypePurgeMessages, ChannelID: "1", Count: 2})
+	denied := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if !strings.Contains(denied.Error, "only moderators") {
+		t.Fatalf("unexpected rejection: %q", denied.Error)
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypePurgeMessages, ChannelID: "1", Count: maxPurgeMessages + 1})
+	rejected := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if !strings.Contains(rejected.Error, "count must be between") {
+		t.Fatalf("unexpected rejection: %q", rejected.Error)
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypePurgeMessages, ChannelID: "1", Count: 2})
+	for _, want := range []int64{ids[2], ids[1]} {
+		deleted := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeMessageDeleted })
+		if deleted.MsgID != want {
+			t.Fatalf("message_deleted for %d, want %d", deleted.MsgID, want)
+		}
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeGetMessages, ChannelID: "1"})
+	history := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeMessageHistory })
+	if len(history.Messages) != 1 || history.Messages[0].MsgID != ids[0] {
+		t.Fatalf("expected only the kept message in history, got %+v", history.Messages)
+	}
+
+	entries, err := st.AuditLog(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("audit log: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Action != "purge_messages" || entries[0].ActorID != aliceSnap.SelfID ||
+		entries[0].TargetID != "1" || entries[0].Details != "count=2" {
+		t.Fatalf("unexpected audit log: %+v", entries)
+	}
+}
+
+func TestMuteUserForcesVoiceMuteAndRecordsAudit(t *testing.T) {
+	st, baseURL := startTestServerWithAuditStore(t)
+
+	alice, aliceSnap := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, bobSnap := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+	bobID := bobSnap.SelfID
+
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeJoinVoice, ServerID: "srv-1", ChannelID: "chan-a"})
+	readUntil(t, bob, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeUserState && m.User != nil && m.User.ID == bobID && m.User.Voice != nil
+	})
+
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeMuteUser, UserID: aliceSnap.SelfID})
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+
+	// Durations past the ban limit are refused.
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeMuteUser, UserID: bobID, DurationS: maxBanDurationS + 1})
+	readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeMuteUser, UserID: bobID, DurationS: 60})
+	muted := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeUserMuted })
+	if muted.UserID != bobID || muted.Muted == nil || !*muted.Muted || muted.DurationS != 60 {
+		t.Fatalf("unexpected user_muted: %+v", muted)
+	}
+	state := readUntil(t, bob, func(m protocol.Message) bool {
+		return m.Type == protocol.TypeUserState && m.User != nil && m.User.ID == bobID
+	})
+	if state.User.Voice == nil || !state.User.Voice.Muted {
+		t.Fatalf("expected bob muted in voice, got %+v", state.User.Voice)
+	}
+
+	unmute := false
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeSetVoiceState, Muted: &unmute})
+	rejected := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if !strings.Contains(rejected.Error, "muted by a moderator") {
+		t.Fatalf("unexpected error: %q", rejected.Error)
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeMuteUser, UserID: bobID, Muted: &unmute})
+	lifted := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeUserMuted })
+	if lifted.Muted == nil || *lifted.Muted {
+		t.Fatalf("expected the mute lifted, got %+v", lifted)
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypePing, TS: 1})
+	readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypePong })
+	entries, err := st.AuditLog(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("audit log: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Action != "unmute_user" || entries[1].Action != "mute_user" || entries[1].Details != "duration_s=60" {
+		t.Fatalf("unexpected audit log: %+v", entries)
+	}
+}
+
+func TestSetStatusBroadcastsAndShowsInSnapshot(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, aliceSnap := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSetStatus, Status: "busy"})
+	rejected := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if !strings.Contains(rejected.Error, "status must be one of") {
+		t.Fatalf("unexpected error: %q", rejected.Error)
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSetStatus, Status: "away"})
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		got := readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeUserStatus })
+		if got.User == nil || got.User.ID != aliceSnap.SelfID || got.User.Status != "away" {
+			t.Fatalf("unexpected user_status: %+v", got.User)
+		}
+	}
+
+	carol, carolSnap := connectClient(t, baseURL, "carol")
+	defer carol.Close()
+	aliceID := findUserID(t, carolSnap.Users, "alice")
+	for _, u := range carolSnap.Users {
+		if u.ID == aliceID && u.Status != "away" {
+			t.Fatalf("snapshot status = %q, want away", u.Status)
+		}
+	}
+}
+
+func TestRenameUserBroadcasts(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, aliceSnap := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeRenameUser, Username: "  "})
+	rejected := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if rejected.Error != "username is required" {
+		t.Fatalf("unexpected error: %q", rejected.Error)
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeRenameUser, Username: "alicia"})
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		got := readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeUserRenamed })
+		if got.User == nil || got.User.ID != aliceSnap.SelfID || got.User.Username != "alicia" {
+			t.Fatalf("unexpected user_renamed: %+v", got.User)
+		}
+	}
+}
+
+func TestTypingReachesOthersButNotSender(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, aliceSnap := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeTyping, ServerID: "srv-1", ChannelID: "1"})
+	typing := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeUserTyping })
+	if typing.User == nil || typing.User.ID != aliceSnap.SelfID || typing.User.Username != "alice" || typing.ChannelID != "1" {
+		t.Fatalf("unexpected user_typing: %+v", typing)
+	}
+
+	// Alice never hears her own typing: the next thing she sees is the
+	// pong for a ping sent after it.
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypePing, TS: 1})
+	got := readUntil(t, alice, func(m protocol.Message) bool {
+		return m.Type == protocol.TypePong || m.Type == protocol.TypeUserTyping
+	})
+	if got.Type != protocol.TypePong {
+		t.Fatalf("sender received its own typing: %+v", got)
+	}
+}
+
+func TestWordFilterMasksOrBlocksChat(t *testing.T) {
+	st, baseURL := startTestServerWithAuditStore(t)
+
+	alice, aliceSnap := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+	}
+	send := func(conn *websocket.Conn, text string) {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeSendText, ServerID: "srv-1", ChannelID: "1", Message: text})
+	}
+
+	// Only the owner may set the filter.
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeSetWordFilter, Words: []string{"darn"}, FilterAction: core.WordFilterMask})
+	readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSetWordFilter, Words: []string{"Darn"}, FilterAction: core.WordFilterMask})
+	ack := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeWordFilter })
+	if len(ack.Words) != 1 || ack.Words[0] != "darn" || ack.FilterAction != core.WordFilterMask {
+		t.Fatalf("unexpected word_filter: %+v", ack)
+	}
+	saved, ok, err := st.LoadWordFilter(context.Background())
+	if err != nil || !ok || saved.Action != core.WordFilterMask {
+		t.Fatalf("word filter not saved: %+v, %v, %v", saved, ok, err)
+	}
+
+	send(bob, "DARN it, darnit")
+	msg := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeTextMessage })
+	if msg.Message != "**** it, darnit" {
+		t.Fatalf("masked message = %q", msg.Message)
+	}
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeDM, UserID: aliceSnap.SelfID, Message: "darn"})
+	if dm := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeDM }); dm.Message != "****" {
+		t.Fatalf("masked dm = %q", dm.Message)
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeSetWordFilter, Words: []string{"darn"}, FilterAction: core.WordFilterBlock})
+	readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeWordFilter })
+	send(bob, "oh darn")
+	errMsg := readUntil(t, bob, func(m protocol.Message) bool {
+		if m.Type == protocol.TypeTextMessage && m.Message == "oh darn" {
+			t.Fatal("blocked message was broadcast")
+		}
+		return m.Type == protocol.TypeError
+	})
+	if errMsg.Error != core.ErrMessageFiltered.Error() {
+		t.Fatalf("unexpected error: %q", errMsg.Error)
+	}
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeDM, UserID: aliceSnap.SelfID, Message: "darn you"})
+	readUntil(t, bob, func(m protocol.Message) bool {
+		if m.Type == protocol.TypeDM {
+			t.Fatal("blocked dm was delivered")
+		}
+		return m.Type == protocol.TypeError
+	})
+	send(bob, "fine")
+	readUntil(t, alice, func(m protocol.Message) bool {
+		if (m.Type == protocol.TypeTextMessage && m.Message == "oh darn") || m.Type == protocol.TypeDM {
+			t.Fatal("blocked message was delivered")
+		}
+		return m.Type == protocol.TypeTextMessage && m.Message == "fine"
+	})
+}
+
+func TestMonitorChannelIsPublishedToTheServer(t *testing.T) {
+	_, baseURL := startTestServer(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	bob, _ := connectClient(t, baseURL, "bob")
+	defer bob.Close()
+
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+		readUntil(t, conn, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+	}
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeCreateChannel, Message: "stage"})
+	list := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeChannelList && len(m.Channels) == 2 })
+	lobby := strconv.FormatInt(list.Channels[0].ID, 10)
+	stage := strconv.FormatInt(list.Channels[1].ID, 10)
+
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		writeMsg(t, conn, protocol.Message{Type: protocol.TypeJoinVoice, ServerID: "srv-1", ChannelID: lobby})
+		readUntil(t, conn, func(m protocol.Message) bool {
+			return m.Type == protocol.TypeUserState && m.User != nil && m.User.Voice != nil
+		})
+	}
+
+	// bob is a plain user and may not monitor.
+	writeMsg(t, bob, protocol.Message{Type: protocol.TypeMonitorChannel, ChannelID: stage})
+	errMsg := readUntil(t, bob, func(m protocol.Message) bool { return m.Type == protocol.TypeError })
+	if !strings.Contains(errMsg.Error, "moderators") {
+		t.Fatalf("unexpected error: %q", errMsg.Error)
+	}
+
+	monitoring := func(m protocol.Message) bool {
+		return m.Type == protocol.TypeUserState && m.User != nil && m.User.Username == "alice" && m.User.Voice != nil
+	}
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeMonitorChannel, ChannelID: stage})
+	state := readUntil(t, bob, monitoring)
+	if state.User.Voice.ChannelID != lobby || len(state.User.Voice.Monitoring) != 1 || state.User.Voice.Monitoring[0] != stage {
+		t.Fatalf("unexpected voice state: %+v", state.User.Voice)
+	}
+
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeUnmonitorChannel, ChannelID: stage})
+	state = readUntil(t, bob, monitoring)
+	if len(state.User.Voice.Monitoring) != 0 {
+		t.Fatalf("monitoring should have ended: %+v", state.User.Voice)
+	}
+}
+
+func TestGetMessagesBeforePagesBackward(t *testing.T) {
+	_, baseURL := startTestServerWithStore(t)
+
+	alice, _ := connectClient(t, baseURL, "alice")
+	defer alice.Close()
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeConnectServer, ServerID: "srv-1"})
+	readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeUserState })
+
+	for i := 1; i <= 7; i++ {
+		text := strconv.Itoa(i)
+		writeMsg(t, alice, protocol.Message{Type: protocol.TypeSendText, ServerID: "srv-1", ChannelID: "1", Message: text})
+		readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeTextMessage && m.Message == text })
+	}
+
+	page := func(before int64) []protocol.TextMessage {
+		t.Helper()
+		writeMsg(t, alice, protocol.Message{Type: protocol.TypeGetMessagesBefore, ChannelID: "1", Before: before, Limit: 3})
+		msg := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeMessageHistory })
+		if msg.ChannelID != "1" || msg.Before != before {
+			t.Fatalf("page for before=%d came back as %+v", before, msg)
+		}
+		return msg.Messages
+	}
+	texts := func(msgs []protocol.TextMessage) []string {
+		var out []string
+		for _, m := range msgs {
+			out = append(out, m.Message)
+		}
+		return out
+	}
+
+	// Each page is newest first; its last message is the next cursor.
+	var cursor int64
+	for _, want := range [][]string{{"7", "6", "5"}, {"4", "3", "2"}, {"1"}} {
+		msgs := page(cursor)
+		if got := texts(msgs); !slices.Equal(got, want) {
+			t.Fatalf("before=%d: got %q, want %q", cursor, got, want)
+		}
+		cursor = msgs[len(msgs)-1].MsgID
+	}
+	if msgs := page(cursor); len(msgs) != 0 {
+		t.Fatalf("expected no messages before the first, got %q", texts(msgs))
+	}
+
+	// A limit over the cap is clamped rather than rejected.
+	writeMsg(t, alice, protocol.Message{Type: protocol.TypeGetMessagesBefore, ChannelID: "1", Limit: maxMessagePage + 1})
+	if msg := readUntil(t, alice, func(m protocol.Message) bool { return m.Type == protocol.TypeMessageHistory }); len(msg.Messages) != 7 {
+		t.Fatalf("expected all 7 messages, got %d", len(msg.Messages))
+	}
+}
@@ -0,0 +1,109 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"bken/server/internal/core"
+	"bken/server/internal/protocol"
+	"bken/server/internal/store"
+)
+
+// reactionBatchInterval is the minimum spacing between reaction_update
+// broadcasts for one message.
+const reactionBatchInterval = 250 * time.Millisecond
+
+// reactionBatcher coalesces reaction changes so a burst of reactions on one
+// message produces a single reaction_update fan-out per interval instead of
+// one broadcast per change.
+type reactionBatcher struct {
+	interval time.Duration
+	flush    func(serverID string, msgID int64)
+
+	mu      sync.Mutex
+	pending map[int64]struct{}
+}
+
+func newReactionBatcher(interval time.Duration, flush func(serverID string, msgID int64)) *reactionBatcher {
+	return &reactionBatcher{
+		interval: interval,
+		flush:    flush,
+		pending:  make(map[int64]struct{}),
+	}
+}
+
+// mark records that msgID's reactions changed. The first change schedules a
+// flush after the interval; further changes before then ride along with it.
+func (b *reactionBatcher) mark(serverID string, msgID int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.pending[msgID]; ok {
+		return
+	}
+	b.pending[msgID] = struct{}{}
+	time.AfterFunc(b.interval, func() {
+		// Clear before flushing so a change racing the flush schedules
+		// another update rather than being lost.
+		b.mu.Lock()
+		delete(b.pending, msgID)
+		b.mu.Unlock()
+		b.flush(serverID, msgID)
+	})
+}
+
+// allowReaction counts a reaction change by userID on msgID against the
+// per-message rate limit. When the user is over it they are told when they
+// may react again and the change is dropped.
+func (h *Handler) allowReaction(userID string, msgID int64) bool {
+	err := h.channelState.CheckReactionRate(userID, msgID)
+	if err == nil {
+		return true
+	}
+	var limited *core.ReactionRateError
+	if errors.As(err, &limited) {
+		h.channelState.SendTo(userID, protocol.Message{Type: protocol.TypeError, Error: err.Error(), RetryAfterMs: limited.Remaining.Milliseconds()})
+		return false
+	}
+	h.sendError(userID, err.Error())
+	return false
+}
+
+// flushReactions broadcasts the current reactions on msgID to serverID.
+func (h *Handler) flushReactions(serverID string, msgID int64) {
+	rows, err := h.store.GetReactionsForMessages(context.Background(), []int64{msgID})
+	if err != nil {
+		slog.Error("load reactions for update", "msg_id", msgID, "err", err)
+		return
+	}
+	h.channelState.BroadcastToServer(serverID, protocol.Message{
+		Type:      protocol.TypeReactionUpdate,
+		MsgID:     msgID,
+		Reactions: groupReactions(rows[msgID]),
+	}, "")
+}
+
+// groupReactions folds per-user reaction rows into one ReactionInfo per
+// emoji, in order of each emoji's first use.
+func groupReactions(rows []store.ReactionRow) []protocol.ReactionInfo {
+	emojiMap := make(map[string][]string)
+	var order []string
+	for _, rx := range rows {
+		if _, seen := emojiMap[rx.Emoji]; !seen {
+			order = append(order, rx.Emoji)
+		}
+		emojiMap[rx.Emoji] = append(emojiMap[rx.Emoji], rx.UserID)
+	}
+	infos := make([]protocol.ReactionInfo, 0, len(order))
+	for _, emoji := range order {
+		uids := emojiMap[emoji]
+		infos = append(infos, protocol.ReactionInfo{
+			Emoji:   emoji,
+			UserIDs: uids,
+			Count:   len(uids),
+		})
+	}
+	return infos
+}
@@ -8,10 +8,13 @@ import (
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"bken/server/internal/blob"
 	"bken/server/internal/core"
 	"bken/server/internal/httpapi"
+	"bken/server/internal/recording"
 	"bken/server/internal/store"
 )
 
@@ -22,7 +25,26 @@ func main() {
 	addr := flag.String("addr", ":8080", "Echo listen address")
 	dbPath := flag.String("db", "bken.db", "SQLite database path")
 	blobsDir := flag.String("blobs-dir", "", "Blob directory path (defaults to <db-dir>/blobs)")
+	recordingsDir := flag.String("recordings-dir", "", "Recording directory path (defaults to <db-dir>/recordings)")
 	serverName := flag.String("name", "bken server", "Server display name")
+	motd := flag.String("motd", "", "Message of the day shown to users when they connect; saved and reused when omitted (empty clears)")
+	usernamePolicy := flag.String("username-collision-policy", core.UsernamePolicyAllow, "How to handle a hello whose username is already connected: allow, replace, reject, or suffix")
+	switchCooldown := flag.Duration("channel-switch-cooldown", 0, "Minimum time between a user's voice channel switches (0 disables)")
+	voiceIdleTimeout := flag.Duration("voice-idle-timeout", 0, "Move users out of voice after this long without voice activity (0 disables)")
+	connectRate := flag.Int("connect-rate", 0, "Most new connections one IP may open per minute (0 disables)")
+	clientByteRate := flag.Int("client-byte-rate", 0, "Most websocket bytes per second written to one client; messages over it wait for the next second (0 disables)")
+	maxUploadSize := flag.Int64("max-upload-size", core.DefaultMaxUploadBytes, "Largest file upload accepted, in bytes")
+	maxMessageLength := flag.Int("max-message-length", core.DefaultMaxMessageLength, "Longest chat message, edit or DM accepted, in bytes")
+	joinSound := flag.String("join-sound-url", "", "WAV file clients play when someone joins: an http(s) URL or a path on this server such as /api/blobs/<id> (empty uses the bundled sound)")
+	leaveSound := flag.String("leave-sound-url", "", "WAV file clients play when someone leaves, like -join-sound-url")
+	configPath := flag.String("config", "", "JSON file of runtime settings applied at startup and re-read on SIGHUP: channel_switch_cooldown, max_upload_size, motd, allowed_emoji, connect_rate, client_byte_rate (disabled when empty)")
+	shutdownGrace := flag.Duration("shutdown-grace", 10*time.Second, "On the first SIGINT or SIGTERM, warn users and wait this long before closing their connections; a second signal stops at once")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate file to serve HTTPS with (requires -tls-key; plain HTTP when both are empty)")
+	tlsKey := flag.String("tls-key", "", "PEM private key file for -tls-cert")
+	trustedProxies := flag.String("trusted-proxies", "", "Comma-separated CIDRs of reverse proxies whose X-Forwarded-For header gives the client IP (empty trusts no header)")
+	metricsAddr := flag.String("metrics-addr", "", "Prometheus /metrics listen address (disabled when empty)")
+	metrics := flag.Bool("metrics", false, "Serve Prometheus /metrics on the API listener")
+	recordingConsent := flag.Bool("recording-consent", false, "While someone records a voice channel, keep its other members muted until they accept (declining leaves voice)")
 	debug := flag.Bool("debug", false, "Enable debug logging (auto-enabled for dev builds)")
 	flag.Parse()
 
@@ -58,22 +80,142 @@ func main() {
 		os.Exit(1)
 	}
 
+	recordingRoot := strings.TrimSpace(*recordingsDir)
+	if recordingRoot == "" {
+		recordingRoot = filepath.Join(filepath.Dir(*dbPath), "recordings")
+	}
+	recordingStore, err := recording.NewStore(recordingRoot, sqliteStore)
+	if err != nil {
+		slog.Error("initialize recording store", "err", err)
+		os.Exit(1)
+	}
+
 	channelState := core.NewChannelState(*serverName)
+	if err := channelState.SetUsernamePolicy(*usernamePolicy); err != nil {
+		slog.Error("invalid -username-collision-policy", "err", err)
+		os.Exit(1)
+	}
+	if err := channelState.SetChannelSwitchCooldown(*switchCooldown); err != nil {
+		slog.Error("invalid -channel-switch-cooldown", "err", err)
+		os.Exit(1)
+	}
+	channelState.SetRecordingConsent(*recordingConsent)
+	if err := channelState.SetIdleTimeout(*voiceIdleTimeout); err != nil {
+		slog.Error("invalid -voice-idle-timeout", "err", err)
+		os.Exit(1)
+	}
+	if err := channelState.SetConnectRate(*connectRate); err != nil {
+		slog.Error("invalid -connect-rate", "err", err)
+		os.Exit(1)
+	}
+	if err := channelState.SetPerClientByteRate(*clientByteRate); err != nil {
+		slog.Error("invalid -client-byte-rate", "err", err)
+		os.Exit(1)
+	}
+	if err := channelState.SetMaxUploadBytes(*maxUploadSize); err != nil {
+		slog.Error("invalid -max-upload-size", "err", err)
+		os.Exit(1)
+	}
+	if err := channelState.SetMaxMessageLength(*maxMessageLength); err != nil {
+		slog.Error("invalid -max-message-length", "err", err)
+		os.Exit(1)
+	}
+	if err := channelState.SetJoinSounds(*joinSound, *leaveSound); err != nil {
+		slog.Error("invalid -join-sound-url/-leave-sound-url", "err", err)
+		os.Exit(1)
+	}
+	if err := setupMOTD(context.Background(), channelState, sqliteStore, *motd); err != nil {
+		slog.Error("invalid -motd", "err", err)
+		os.Exit(1)
+	}
+	if f, ok, err := sqliteStore.LoadWordFilter(context.Background()); err != nil {
+		slog.Error("load word filter", "err", err)
+		os.Exit(1)
+	} else if ok {
+		if err := channelState.SetWordFilter(f.Words, f.Action); err != nil {
+			slog.Error("invalid saved word filter", "err", err)
+			os.Exit(1)
+		}
+	}
+	if *configPath != "" {
+		if err := reloadRuntimeConfig(*configPath, channelState); err != nil {
+			slog.Error("invalid -config", "err", err)
+			os.Exit(1)
+		}
+	}
 	slog.Debug("channel state initialized", "server_name", *serverName)
 
+	tlsConfig, fingerprint, err := loadTLSConfig(*tlsCert, *tlsKey)
+	if err != nil {
+		slog.Error("invalid -tls-cert/-tls-key", "err", err)
+		os.Exit(1)
+	}
+
 	server := httpapi.New(channelState, sqliteStore, blobStore)
+	server.EnableRecordings(recordingStore)
+	if *metrics {
+		server.EnableMetrics()
+	}
+	if *trustedProxies != "" {
+		if err := server.SetTrustedProxies(strings.Split(*trustedProxies, ",")); err != nil {
+			slog.Error("invalid -trusted-proxies", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	if tlsConfig != nil {
+		server.SetTLSConfig(tlsConfig)
+		slog.Info("serving TLS", "cert", *tlsCert, "sha256", fingerprint)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, os.Interrupt)
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-sigCh
-		slog.Info("received interrupt, shutting down")
+		slog.Info("received shutdown signal, draining", "grace", *shutdownGrace)
+		select {
+		case <-channelState.Drain(*shutdownGrace):
+		case <-sigCh:
+			slog.Info("received second shutdown signal, shutting down now")
+		}
 		cancel()
 	}()
 
+	// Catch SIGHUP even without -config, so a reload sent to a server that
+	// has nothing to reload is reported instead of killing it.
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	go func() {
+		for range hupCh {
+			if *configPath == "" {
+				slog.Warn("received SIGHUP, but there is no -config file to reload")
+				continue
+			}
+			slog.Info("received SIGHUP, reloading config", "path", *configPath)
+			if err := reloadRuntimeConfig(*configPath, channelState); err != nil {
+				slog.Error("reload config", "err", err)
+			}
+		}
+	}()
+
+	if *voiceIdleTimeout > 0 {
+		go runIdleChecks(ctx, channelState, *voiceIdleTimeout)
+	}
+	go runMuteExpiry(ctx, channelState)
+	go runMessageExpiry(ctx, channelState, sqliteStore)
+
+	if *metricsAddr != "" {
+		go func() {
+			slog.Info("metrics listening", "addr", *metricsAddr)
+			if err := httpapi.RunMetrics(ctx, *metricsAddr, channelState, sqliteStore); err != nil {
+				slog.Error("metrics server error", "err", err)
+			}
+		}()
+	}
+
 	slog.Info("listening", "addr", *addr)
 	if err := server.Run(ctx, *addr); err != nil {
 		slog.Error("server error", "err", err)
@@ -81,3 +223,64 @@ func main() {
 	}
 	slog.Info("server stopped")
 }
+
+// runIdleChecks periodically moves idle users out of voice until ctx is
+// cancelled. Checking at a fraction of the timeout bounds how long past the
+// deadline a user can linger.
+func runIdleChecks(ctx context.Context, channelState *core.ChannelState, timeout time.Duration) {
+	interval := timeout / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			channelState.CheckIdleClients()
+		}
+	}
+}
+
+// runMuteExpiry lifts timed moderator mutes once a second until ctx is
+// cancelled.
+func runMuteExpiry(ctx context.Context, channelState *core.ChannelState) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			channelState.CheckMuteExpiry()
+		}
+	}
+}
+
+// setupMOTD applies the message of the day. A -motd given on the command line
+// replaces and saves the stored one; otherwise the MOTD saved by an earlier
+// run is restored.
+func setupMOTD(ctx context.Context, channelState *core.ChannelState, st *store.Store, motd string) error {
+	explicit := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "motd" {
+			explicit = true
+		}
+	})
+	if !explicit {
+		saved, ok, err := st.LoadMOTD(ctx)
+		if err != nil || !ok {
+			return err
+		}
+		motd = saved
+	}
+	if err := channelState.SetMOTD(motd); err != nil {
+		return err
+	}
+	if explicit {
+		return st.SetMOTD(ctx, motd)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"bken/server/internal/core"
+)
+
+// runtimeConfig is the subset of settings that can change without a
+// restart. It is read from the -config file at startup and again on
+// SIGHUP; fields absent from the file keep their current value.
+type runtimeConfig struct {
+	ChannelSwitchCooldown *string   `json:"channel_switch_cooldown"` // a Go duration, e.g. "5s"
+	MaxUploadSize         *int64    `json:"max_upload_size"`
+	MOTD                  *string   `json:"motd"`
+	AllowedEmoji          *[]string `json:"allowed_emoji"`    // [] allows any emoji
+	ConnectRate           *int      `json:"connect_rate"`     // per IP per minute, 0 disables
+	ClientByteRate        *int      `json:"client_byte_rate"` // per client per second, 0 disables
+}
+
+// loadRuntimeConfig reads and parses the runtime config file at path.
+// Unknown fields are rejected so a typo does not silently change nothing.
+func loadRuntimeConfig(path string) (runtimeConfig, error) {
+	var cfg runtimeConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return cfg, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// applyRuntimeConfig sets each field present in cfg on channelState and
+// logs what changed. An invalid value is reported and skipped; the other
+// fields are still applied.
+func applyRuntimeConfig(channelState *core.ChannelState, cfg runtimeConfig) error {
+	var errs []error
+	if cfg.ChannelSwitchCooldown != nil {
+		d, err := time.ParseDuration(*cfg.ChannelSwitchCooldown)
+		if err == nil {
+			old := channelState.ChannelSwitchCooldown()
+			if err = channelState.SetChannelSwitchCooldown(d); err == nil && d != old {
+				slog.Info("config: channel switch cooldown changed", "from", old, "to", d)
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("channel_switch_cooldown: %w", err))
+		}
+	}
+	if cfg.MaxUploadSize != nil {
+		old := channelState.MaxUploadBytes()
+		if err := channelState.SetMaxUploadBytes(*cfg.MaxUploadSize); err != nil {
+			errs = append(errs, fmt.Errorf("max_upload_size: %w", err))
+		} else if *cfg.MaxUploadSize != old {
+			slog.Info("config: max upload size changed", "from", old, "to", *cfg.MaxUploadSize)
+		}
+	}
+	if cfg.MOTD != nil {
+		old := channelState.MOTD()
+		if err := channelState.SetMOTD(*cfg.MOTD); err != nil {
+			errs = append(errs, fmt.Errorf("motd: %w", err))
+		} else if channelState.MOTD() != old {
+			slog.Info("config: motd changed", "len", len(channelState.MOTD()))
+		}
+	}
+	if cfg.AllowedEmoji != nil {
+		if err := channelState.SetAllowedEmoji(*cfg.AllowedEmoji); err != nil {
+			errs = append(errs, fmt.Errorf("allowed_emoji: %w", err))
+		}
+	}
+	if cfg.ConnectRate != nil {
+		old := channelState.ConnectRate()
+		if err := channelState.SetConnectRate(*cfg.ConnectRate); err != nil {
+			errs = append(errs, fmt.Errorf("connect_rate: %w", err))
+		} else if *cfg.ConnectRate != old {
+			slog.Info("config: connect rate changed", "from", old, "to", *cfg.ConnectRate)
+		}
+	}
+	if cfg.ClientByteRate != nil {
+		old := channelState.PerClientByteRate()
+		if err := channelState.SetPerClientByteRate(*cfg.ClientByteRate); err != nil {
+			errs = append(errs, fmt.Errorf("client_byte_rate: %w", err))
+		} else if *cfg.ClientByteRate != old {
+			slog.Info("config: client byte rate changed", "from", old, "to", *cfg.ClientByteRate)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reloadRuntimeConfig re-reads path and applies it to channelState.
+func reloadRuntimeConfig(path string, channelState *core.ChannelState) error {
+	cfg, err := loadRuntimeConfig(path)
+	if err != nil {
+		return err
+	}
+	return applyRuntimeConfig(channelState, cfg)
+}
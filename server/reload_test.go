@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bken/server/internal/core"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bken.json")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestReloadRuntimeConfigUpdatesChannelState(t *testing.T) {
+	cs := core.NewChannelState("test")
+	if err := cs.SetMOTD("old motd"); err != nil {
+		t.Fatalf("set motd: %v", err)
+	}
+
+	path := writeConfig(t, `{"channel_switch_cooldown": "5s", "max_upload_size": 1024, "allowed_emoji": ["👍", "🎉"], "connect_rate": 30, "client_byte_rate": 65536}`)
+	if err := reloadRuntimeConfig(path, cs); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := cs.ChannelSwitchCooldown(); got != 5*time.Second {
+		t.Errorf("cooldown = %v, want 5s", got)
+	}
+	if got := cs.MaxUploadBytes(); got != 1024 {
+		t.Errorf("max upload = %d, want 1024", got)
+	}
+	if got := cs.AllowedEmoji(); len(got) != 2 {
+		t.Errorf("allowed emoji = %q, want two", got)
+	}
+	if got := cs.ConnectRate(); got != 30 {
+		t.Errorf("connect rate = %d, want 30", got)
+	}
+	if got := cs.PerClientByteRate(); got != 65536 {
+		t.Errorf("client byte rate = %d, want 65536", got)
+	}
+	if got := cs.MOTD(); got != "old motd" {
+		t.Errorf("motd = %q, want it unchanged when absent from the file", got)
+	}
+}
+
+func TestReloadRuntimeConfigSkipsInvalidValues(t *testing.T) {
+	cs := core.NewChannelState("test")
+	path := writeConfig(t, `{"channel_switch_cooldown": "soon", "max_upload_size": 0, "motd": "hello", "connect_rate": -1, "client_byte_rate": -1}`)
+	if err := reloadRuntimeConfig(path, cs); err == nil {
+		t.Fatal("expected an error for invalid values")
+	}
+	if got := cs.ChannelSwitchCooldown(); got != 0 {
+		t.Errorf("cooldown = %v, want unchanged 0", got)
+	}
+	if got := cs.MaxUploadBytes(); got != core.DefaultMaxUploadBytes {
+		t.Errorf("max upload = %d, want unchanged default", got)
+	}
+	if got := cs.ConnectRate(); got != 0 {
+		t.Errorf("connect rate = %d, want unchanged 0", got)
+	}
+	if got := cs.PerClientByteRate(); got != 0 {
+		t.Errorf("client byte rate = %d, want unchanged 0", got)
+	}
+	if got := cs.MOTD(); got != "hello" {
+		t.Errorf("motd = %q, want the valid field applied", got)
+	}
+}
+
+func TestLoadRuntimeConfigRejectsUnknownFields(t *testing.T) {
+	path := writeConfig(t, `{"rate_limit": 10}`)
+	if _, err := loadRuntimeConfig(path); err == nil {
+		t.Fatal("expected an error for an unknown field")
+	}
+}
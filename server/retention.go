@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"bken/server/internal/core"
+	"bken/server/internal/protocol"
+	"bken/server/internal/store"
+)
+
+// messageExpiryInterval is how often channels with a message TTL are swept.
+const messageExpiryInterval = 10 * time.Second
+
+// runMessageExpiry deletes messages older than their channel's TTL every
+// messageExpiryInterval until ctx is cancelled.
+func runMessageExpiry(ctx context.Context, channelState *core.ChannelState, st *store.Store) {
+	ticker := time.NewTicker(messageExpiryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expireMessages(ctx, channelState, st, time.Now())
+		}
+	}
+}
+
+// expireMessages marks every message past its channel's TTL at now as
+// deleted and broadcasts message_deleted for each. Channels with no TTL
+// keep their messages forever.
+func expireMessages(ctx context.Context, channelState *core.ChannelState, st *store.Store, now time.Time) {
+	for _, c := range channelState.MessageTTLs() {
+		ids, err := st.ExpireMessages(ctx, c.ServerID, c.ChannelID, now.Add(-c.TTL).UnixMilli())
+		if err != nil {
+			slog.Error("expire messages", "server_id", c.ServerID, "channel_id", c.ChannelID, "err", err)
+			continue
+		}
+		for _, id := range ids {
+			channelState.BroadcastToServer(c.ServerID, protocol.Message{Type: protocol.TypeMessageDeleted, MsgID: id}, "")
+		}
+	}
+}
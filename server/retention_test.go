@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"bken/server/internal/core"
+	"bken/server/internal/protocol"
+	"bken/server/internal/store"
+)
+
+func TestExpireMessagesBroadcastsDeletes(t *testing.T) {
+	st, err := store.Open(filepath.Join(t.TempDir(), "bken.db"))
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	cs := core.NewChannelState("test")
+	sess, _, err := cs.Add("alice", 16)
+	if err != nil {
+		t.Fatalf("add user: %v", err)
+	}
+	if _, _, err := cs.ConnectServer(sess.UserID, "srv-1"); err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+	chs, _ := cs.CreateChannel("srv-1", "ephemeral")
+	general := strconv.FormatInt(chs[0].ID, 10)
+	ephemeral := strconv.FormatInt(chs[1].ID, 10)
+	if _, err := cs.SetChannelTTL("srv-1", chs[1].ID, 60); err != nil {
+		t.Fatalf("set ttl: %v", err)
+	}
+
+	ctx := context.Background()
+	now := time.UnixMilli(1_000_000)
+	old := now.Add(-2 * time.Minute).UnixMilli()
+	kept, _ := st.InsertMessage(ctx, "srv-1", general, sess.UserID, "alice", "forever", old, "", "", 0, 0)
+	expired, _ := st.InsertMessage(ctx, "srv-1", ephemeral, sess.UserID, "alice", "gone", old, "", "", 0, 0)
+	fresh, _ := st.InsertMessage(ctx, "srv-1", ephemeral, sess.UserID, "alice", "fresh", now.UnixMilli(), "", "", 0, 0)
+
+	expireMessages(ctx, cs, st, now)
+	select {
+	case msg := <-sess.Send:
+		if msg.Type != protocol.TypeMessageDeleted || msg.MsgID != expired {
+			t.Fatalf("expected message_deleted for %d, got %+v", expired, msg)
+		}
+	default:
+		t.Fatal("expected a message_deleted broadcast")
+	}
+
+	// A second sweep finds nothing new to delete.
+	expireMessages(ctx, cs, st, now)
+	select {
+	case msg := <-sess.Send:
+		t.Fatalf("unexpected broadcast on second sweep: %+v", msg)
+	default:
+	}
+
+	for _, id := range []int64{kept, fresh} {
+		if _, ok, err := st.GetMessage(ctx, "srv-1", id); err != nil || !ok {
+			t.Errorf("message %d: expected kept, got ok=%v err=%v", id, ok, err)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// loadTLSConfig loads the certificate and key named by -tls-cert and
+// -tls-key. With neither set it returns nil and the server keeps serving
+// plain HTTP. The returned fingerprint is the SHA-256 of the leaf
+// certificate, for operators to compare with what clients see.
+func loadTLSConfig(certFile, keyFile string) (*tls.Config, string, error) {
+	if certFile == "" && keyFile == "" {
+		return nil, "", nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, "", errors.New("-tls-cert and -tls-key must be set together")
+	}
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("load TLS key pair %s, %s: %w", certFile, keyFile, err)
+	}
+	sum := sha256.Sum256(pair.Certificate[0])
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		MinVersion:   tls.VersionTLS12,
+	}
+	return cfg, hex.EncodeToString(sum[:]), nil
+}
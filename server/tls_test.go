@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeKeyPair writes a fresh self-signed certificate and its key as PEM
+// files and returns their paths.
+func writeKeyPair(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "bken.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+func TestLoadTLSConfigFromFiles(t *testing.T) {
+	certFile, keyFile := writeKeyPair(t)
+	cfg, fingerprint, err := loadTLSConfig(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg == nil || len(cfg.Certificates) != 1 {
+		t.Fatalf("expected one certificate, got %+v", cfg)
+	}
+	if len(fingerprint) != 64 {
+		t.Fatalf("expected a hex SHA-256 fingerprint, got %q", fingerprint)
+	}
+}
+
+func TestLoadTLSConfigWithoutFlagsServesPlain(t *testing.T) {
+	cfg, _, err := loadTLSConfig("", "")
+	if err != nil || cfg != nil {
+		t.Fatalf("expected no TLS config, got %+v, %v", cfg, err)
+	}
+}
+
+func TestLoadTLSConfigRejectsBadPairs(t *testing.T) {
+	certFile, _ := writeKeyPair(t)
+	_, otherKey := writeKeyPair(t)
+
+	if _, _, err := loadTLSConfig(certFile, ""); err == nil || !strings.Contains(err.Error(), "set together") {
+		t.Fatalf("expected an error for a cert without a key, got %v", err)
+	}
+	_, _, err := loadTLSConfig(certFile, otherKey)
+	if err == nil || !strings.Contains(err.Error(), "private key does not match public key") {
+		t.Fatalf("expected a mismatch error, got %v", err)
+	}
+}